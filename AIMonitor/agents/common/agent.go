@@ -37,6 +37,12 @@ type AgentConfig struct {
 		Level string `yaml:"level"`
 		File  string `yaml:"file"`
 	} `yaml:"logging"`
+	// Commands 平台下发命令的执行配置，默认关闭，只执行白名单内的命令
+	Commands struct {
+		Enabled  bool          `yaml:"enabled"`
+		Interval time.Duration `yaml:"interval"`
+		Allowed  []string      `yaml:"allowed"`
+	} `yaml:"commands"`
 }
 
 // AgentInfo Agent信息
@@ -163,6 +169,9 @@ func LoadConfig(configPath string) (*AgentConfig, error) {
 	if config.Metrics.Interval == 0 {
 		config.Metrics.Interval = 30 * time.Second
 	}
+	if config.Commands.Interval == 0 {
+		config.Commands.Interval = 10 * time.Second
+	}
 
 	return &config, nil
 }
@@ -194,6 +203,14 @@ func (a *Agent) Register() error {
 		return fmt.Errorf("registration failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
+	// 使用平台分配的ID，后续心跳和命令拉取都以该ID标识本Agent
+	var registered struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err == nil && registered.ID != "" {
+		a.Info.ID = registered.ID
+	}
+
 	a.Logger.Info("Agent registered successfully: %s", a.Info.ID)
 	return nil
 }
@@ -286,6 +303,10 @@ func (a *Agent) Start() error {
 	heartbeatTicker := time.NewTicker(a.Config.Agent.Interval)
 	metricsTicker := time.NewTicker(a.Config.Metrics.Interval)
 
+	if a.Config.Commands.Enabled {
+		go a.runCommandLoop()
+	}
+
 	go func() {
 		for {
 			select {
@@ -308,6 +329,21 @@ func (a *Agent) Start() error {
 	return nil
 }
 
+// runCommandLoop 按配置间隔拉取并执行平台下发的命令
+func (a *Agent) runCommandLoop() {
+	ticker := time.NewTicker(a.Config.Commands.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.Ctx.Done():
+			return
+		case <-ticker.C:
+			a.PollCommands()
+		}
+	}
+}
+
 // Stop 停止Agent
 func (a *Agent) Stop() {
 	a.Cancel()
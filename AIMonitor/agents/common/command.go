@@ -0,0 +1,166 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// maxCommandOutput 上报的命令输出上限，超出部分截断
+const maxCommandOutput = 64 * 1024
+
+// AgentCommand 平台下发的待执行命令
+type AgentCommand struct {
+	ID      string   `json:"id"`
+	AgentID string   `json:"agent_id"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Timeout int      `json:"timeout"`
+	Status  string   `json:"status"`
+}
+
+// CommandResult 命令执行结果
+type CommandResult struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// FetchCommands 拉取平台下发给本Agent的待执行命令
+func (a *Agent) FetchCommands() ([]AgentCommand, error) {
+	url := a.getAPIURL(fmt.Sprintf("/api/v1/agents/%s/commands", a.Info.ID))
+	req, err := http.NewRequestWithContext(a.Ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Config.Server.APIKey)
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commands: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("fetch commands failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []AgentCommand `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode commands: %w", err)
+	}
+	return result.Data, nil
+}
+
+// ReportCommandResult 向平台上报命令执行结果
+func (a *Agent) ReportCommandResult(commandID string, result *CommandResult) error {
+	url := a.getAPIURL(fmt.Sprintf("/api/v1/agents/%s/commands/%s/result", a.Info.ID, commandID))
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(a.Ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.Config.Server.APIKey)
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to report command result: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("report command result failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ExecuteCommand 在本机执行命令，不经过shell；命令不在白名单内时直接判定失败
+func (a *Agent) ExecuteCommand(cmd *AgentCommand) *CommandResult {
+	if !a.commandAllowed(cmd.Command) {
+		return &CommandResult{
+			Status:   "failed",
+			ExitCode: -1,
+			Output:   fmt.Sprintf("command %q is not in the agent allow list", cmd.Command),
+		}
+	}
+
+	timeout := time.Duration(cmd.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(a.Ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	c := exec.CommandContext(ctx, cmd.Command, cmd.Args...)
+	c.Stdout = &output
+	c.Stderr = &output
+
+	err := c.Run()
+	result := &CommandResult{Status: "succeeded", Output: truncateOutput(output.String())}
+	if err != nil {
+		result.Status = "failed"
+		result.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Output += fmt.Sprintf("\ncommand timed out after %s", timeout)
+		} else if result.ExitCode == -1 {
+			result.Output += "\n" + err.Error()
+		}
+	}
+	return result
+}
+
+// PollCommands 拉取并依次执行待执行命令，逐条上报结果
+func (a *Agent) PollCommands() {
+	commands, err := a.FetchCommands()
+	if err != nil {
+		a.Logger.Error("Failed to fetch commands: %v", err)
+		return
+	}
+
+	for i := range commands {
+		cmd := &commands[i]
+		a.Logger.Info("Executing command %s: %s %v", cmd.ID, cmd.Command, cmd.Args)
+		result := a.ExecuteCommand(cmd)
+		if err := a.ReportCommandResult(cmd.ID, result); err != nil {
+			a.Logger.Error("Failed to report result of command %s: %v", cmd.ID, err)
+		}
+	}
+}
+
+// commandAllowed 检查命令是否在白名单内，白名单为空时不允许执行任何命令
+func (a *Agent) commandAllowed(command string) bool {
+	for _, allowed := range a.Config.Commands.Allowed {
+		if allowed == command {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateOutput 截断过长的命令输出
+func truncateOutput(output string) string {
+	if len(output) <= maxCommandOutput {
+		return output
+	}
+	return output[:maxCommandOutput] + "\n...(truncated)"
+}
@@ -1,3 +1,16 @@
 module aimonitor-docker-agent
 
 go 1.24.4
+
+require (
+	ai-monitor v0.0.0
+	aimonitor-agents v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/google/uuid v1.6.0 // indirect
+
+replace (
+	ai-monitor => ../..
+	aimonitor-agents => ..
+)
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"ai-monitor/pkg/docker"
 	"aimonitor-agents/common"
+
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
@@ -27,7 +32,14 @@ func main() {
 	agent.Info.Name = "Docker Monitor"
 
 	// 创建Docker监控器
-	monitor := NewDockerMonitor(agent)
+	dockerConfig, err := LoadDockerConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load docker config: %v", err)
+	}
+	monitor, err := NewDockerMonitor(agent, dockerConfig)
+	if err != nil {
+		log.Fatalf("Failed to create docker monitor: %v", err)
+	}
 
 	// 启动Agent
 	if err := agent.Start(); err != nil {
@@ -49,22 +61,72 @@ func main() {
 	fmt.Println("Docker Agent stopped.")
 }
 
+// DockerConfig 配置文件中的docker段
+type DockerConfig struct {
+	Docker struct {
+		Endpoint   string        `yaml:"endpoint"`
+		APIVersion string        `yaml:"api_version"`
+		Timeout    time.Duration `yaml:"timeout"`
+		TLSVerify  bool          `yaml:"tls_verify"`
+		CertPath   string        `yaml:"cert_path"`
+		KeyPath    string        `yaml:"key_path"`
+		CAPath     string        `yaml:"ca_path"`
+	} `yaml:"docker"`
+}
+
+// LoadDockerConfig 加载Docker连接配置
+func LoadDockerConfig(configPath string) (docker.Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return docker.Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg DockerConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return docker.Config{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return docker.Config{
+		Endpoint:   cfg.Docker.Endpoint,
+		APIVersion: cfg.Docker.APIVersion,
+		Timeout:    cfg.Docker.Timeout,
+		TLSVerify:  cfg.Docker.TLSVerify,
+		CertPath:   cfg.Docker.CertPath,
+		KeyPath:    cfg.Docker.KeyPath,
+		CAPath:     cfg.Docker.CAPath,
+	}, nil
+}
+
 // DockerMonitor Docker监控器
 type DockerMonitor struct {
-	agent *common.Agent
+	agent  *common.Agent
+	client *docker.Client
+
+	// 每个运行中容器一个统计流，latest保存最近一次采样
+	mu      sync.Mutex
+	streams map[string]context.CancelFunc
+	latest  map[string]*docker.Stats
 }
 
 // NewDockerMonitor 创建Docker监控器
-func NewDockerMonitor(agent *common.Agent) *DockerMonitor {
-	return &DockerMonitor{
-		agent: agent,
+func NewDockerMonitor(agent *common.Agent, cfg docker.Config) (*DockerMonitor, error) {
+	client, err := docker.NewClient(cfg)
+	if err != nil {
+		return nil, err
 	}
+	return &DockerMonitor{
+		agent:   agent,
+		client:  client,
+		streams: make(map[string]context.CancelFunc),
+		latest:  make(map[string]*docker.Stats),
+	}, nil
 }
 
 // StartMonitoring 开始监控
 func (m *DockerMonitor) StartMonitoring() {
 	ticker := time.NewTicker(m.agent.Config.Metrics.Interval)
 	defer ticker.Stop()
+	defer m.stopStreams()
 
 	for {
 		select {
@@ -84,144 +146,201 @@ func (m *DockerMonitor) StartMonitoring() {
 // collectMetrics 收集Docker指标
 func (m *DockerMonitor) collectMetrics() map[string]interface{} {
 	metrics := make(map[string]interface{})
+	ctx := m.agent.Ctx
 
 	// Docker系统信息
-	systemInfo, err := m.getSystemInfo()
+	info, err := m.client.Info(ctx)
 	if err != nil {
 		m.agent.Logger.Error("Failed to get Docker system info: %v", err)
 	} else {
-		metrics["containers_total"] = systemInfo["containers_total"]
-		metrics["containers_running"] = systemInfo["containers_running"]
-		metrics["containers_paused"] = systemInfo["containers_paused"]
-		metrics["containers_stopped"] = systemInfo["containers_stopped"]
-		metrics["images_total"] = systemInfo["images_total"]
+		metrics["containers_total"] = info.Containers
+		metrics["containers_running"] = info.ContainersRunning
+		metrics["containers_paused"] = info.ContainersPaused
+		metrics["containers_stopped"] = info.ContainersStopped
+		metrics["images_total"] = info.Images
+		metrics["docker_version"] = info.ServerVersion
+		metrics["api_version"] = m.client.APIVersion()
+		metrics["kernel_version"] = info.KernelVersion
+		metrics["operating_system"] = info.OperatingSystem
+		metrics["architecture"] = info.Architecture
 	}
 
 	// 容器资源使用情况
-	containerStats, err := m.getContainerStats()
-	if err != nil {
+	if err := m.syncStreams(ctx); err != nil {
 		m.agent.Logger.Error("Failed to get container stats: %v", err)
 	} else {
-		metrics["total_cpu_usage_percent"] = containerStats["total_cpu_usage_percent"]
-		metrics["total_memory_usage_bytes"] = containerStats["total_memory_usage_bytes"]
-		metrics["total_memory_limit_bytes"] = containerStats["total_memory_limit_bytes"]
-		metrics["total_network_rx_bytes"] = containerStats["total_network_rx_bytes"]
-		metrics["total_network_tx_bytes"] = containerStats["total_network_tx_bytes"]
-		metrics["total_block_read_bytes"] = containerStats["total_block_read_bytes"]
-		metrics["total_block_write_bytes"] = containerStats["total_block_write_bytes"]
+		for k, v := range m.containerTotals() {
+			metrics[k] = v
+		}
 	}
 
 	// 镜像信息
-	imageInfo, err := m.getImageInfo()
-	if err != nil {
+	if err := m.collectImageInfo(ctx, metrics); err != nil {
 		m.agent.Logger.Error("Failed to get image info: %v", err)
-	} else {
-		metrics["total_image_size_bytes"] = imageInfo["total_image_size_bytes"]
-		metrics["dangling_images"] = imageInfo["dangling_images"]
 	}
 
 	// 卷信息
-	volumeInfo, err := m.getVolumeInfo()
-	if err != nil {
+	if err := m.collectVolumeInfo(ctx, metrics); err != nil {
 		m.agent.Logger.Error("Failed to get volume info: %v", err)
-	} else {
-		metrics["volumes_total"] = volumeInfo["volumes_total"]
-		metrics["volumes_size_bytes"] = volumeInfo["volumes_size_bytes"]
 	}
 
 	// 网络信息
-	networkInfo, err := m.getNetworkInfo()
-	if err != nil {
+	if err := m.collectNetworkInfo(ctx, metrics); err != nil {
 		m.agent.Logger.Error("Failed to get network info: %v", err)
-	} else {
-		metrics["networks_total"] = networkInfo["networks_total"]
-		metrics["bridge_networks"] = networkInfo["bridge_networks"]
-		metrics["overlay_networks"] = networkInfo["overlay_networks"]
 	}
 
-	// Docker守护进程信息
-	daemonInfo, err := m.getDaemonInfo()
+	return metrics
+}
+
+// syncStreams 为新启动的容器开启统计流，关闭已停止容器的统计流
+func (m *DockerMonitor) syncStreams(ctx context.Context) error {
+	containers, err := m.client.ContainerList(ctx, false)
 	if err != nil {
-		m.agent.Logger.Error("Failed to get daemon info: %v", err)
-	} else {
-		metrics["docker_version"] = daemonInfo["docker_version"]
-		metrics["api_version"] = daemonInfo["api_version"]
-		metrics["kernel_version"] = daemonInfo["kernel_version"]
-		metrics["operating_system"] = daemonInfo["operating_system"]
-		metrics["architecture"] = daemonInfo["architecture"]
+		return err
 	}
 
-	return metrics
+	running := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		running[c.ID] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, cancel := range m.streams {
+		if !running[id] {
+			cancel()
+			delete(m.streams, id)
+			delete(m.latest, id)
+		}
+	}
+	for id := range running {
+		if _, ok := m.streams[id]; ok {
+			continue
+		}
+		streamCtx, cancel := context.WithCancel(ctx)
+		m.streams[id] = cancel
+		go m.streamStats(streamCtx, id)
+	}
+	return nil
 }
 
-// getSystemInfo 获取Docker系统信息
-func (m *DockerMonitor) getSystemInfo() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取系统信息
-	// 为了简化，这里返回模拟数据
-	return map[string]interface{}{
-		"containers_total":   15,
-		"containers_running": 10,
-		"containers_paused":  1,
-		"containers_stopped": 4,
-		"images_total":       25,
-	}, nil
+// streamStats 持续接收单个容器的统计，流结束后由下一轮syncStreams重新建立
+func (m *DockerMonitor) streamStats(ctx context.Context, containerID string) {
+	err := m.client.ContainerStatsStream(ctx, containerID, func(s *docker.Stats) error {
+		m.mu.Lock()
+		m.latest[containerID] = s
+		m.mu.Unlock()
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		m.agent.Logger.Warn("Stats stream for container %s ended: %v", containerID, err)
+	}
+
+	m.mu.Lock()
+	if cancel, ok := m.streams[containerID]; ok && ctx.Err() == nil {
+		cancel()
+		delete(m.streams, containerID)
+	}
+	m.mu.Unlock()
 }
 
-// getContainerStats 获取容器统计信息
-func (m *DockerMonitor) getContainerStats() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取所有运行容器的统计信息
-	// 为了简化，这里返回模拟数据
-	return map[string]interface{}{
-		"total_cpu_usage_percent":    45.5,
-		"total_memory_usage_bytes":   2147483648, // 2GB
-		"total_memory_limit_bytes":   8589934592, // 8GB
-		"total_network_rx_bytes":     1073741824, // 1GB
-		"total_network_tx_bytes":     536870912,  // 512MB
-		"total_block_read_bytes":     2147483648, // 2GB
-		"total_block_write_bytes":    1073741824, // 1GB
-	}, nil
+// stopStreams 关闭所有统计流
+func (m *DockerMonitor) stopStreams() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, cancel := range m.streams {
+		cancel()
+		delete(m.streams, id)
+	}
 }
 
-// getImageInfo 获取镜像信息
-func (m *DockerMonitor) getImageInfo() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取镜像信息
-	// 为了简化，这里返回模拟数据
+// containerTotals 汇总所有运行中容器的最近一次采样
+func (m *DockerMonitor) containerTotals() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cpuPercent float64
+	var memUsage, memLimit, rxBytes, txBytes, readBytes, writeBytes uint64
+	for _, s := range m.latest {
+		cpuPercent += docker.CPUPercent(s)
+		memUsage += docker.MemoryUsage(s)
+		memLimit += s.MemoryStats.Limit
+		network := docker.NetworkTotals(s)
+		rxBytes += network.RxBytes
+		txBytes += network.TxBytes
+		r, w, _, _ := docker.BlockIOTotals(s)
+		readBytes += r
+		writeBytes += w
+	}
+
 	return map[string]interface{}{
-		"total_image_size_bytes": 10737418240, // 10GB
-		"dangling_images":        3,
-	}, nil
+		"total_cpu_usage_percent":  cpuPercent,
+		"total_memory_usage_bytes": memUsage,
+		"total_memory_limit_bytes": memLimit,
+		"total_network_rx_bytes":   rxBytes,
+		"total_network_tx_bytes":   txBytes,
+		"total_block_read_bytes":   readBytes,
+		"total_block_write_bytes":  writeBytes,
+	}
 }
 
-// getVolumeInfo 获取卷信息
-func (m *DockerMonitor) getVolumeInfo() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取卷信息
-	// 为了简化，这里返回模拟数据
-	return map[string]interface{}{
-		"volumes_total":      8,
-		"volumes_size_bytes": 5368709120, // 5GB
-	}, nil
+// collectImageInfo 收集镜像信息
+func (m *DockerMonitor) collectImageInfo(ctx context.Context, metrics map[string]interface{}) error {
+	images, err := m.client.ImageList(ctx, nil)
+	if err != nil {
+		return err
+	}
+	dangling, err := m.client.ImageList(ctx, map[string][]string{"dangling": {"true"}})
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, img := range images {
+		size += img.Size
+	}
+	metrics["total_image_size_bytes"] = size
+	metrics["dangling_images"] = len(dangling)
+	return nil
 }
 
-// getNetworkInfo 获取网络信息
-func (m *DockerMonitor) getNetworkInfo() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取网络信息
-	// 为了简化，这里返回模拟数据
-	return map[string]interface{}{
-		"networks_total":    5,
-		"bridge_networks":   3,
-		"overlay_networks": 2,
-	}, nil
+// collectVolumeInfo 收集卷信息，卷大小来自/system/df
+func (m *DockerMonitor) collectVolumeInfo(ctx context.Context, metrics map[string]interface{}) error {
+	usage, err := m.client.DiskUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, v := range usage.Volumes {
+		if v.UsageData != nil && v.UsageData.Size > 0 {
+			size += v.UsageData.Size
+		}
+	}
+	metrics["volumes_total"] = len(usage.Volumes)
+	metrics["volumes_size_bytes"] = size
+	return nil
 }
 
-// getDaemonInfo 获取Docker守护进程信息
-func (m *DockerMonitor) getDaemonInfo() (map[string]interface{}, error) {
-	// 这里应该使用Docker API获取守护进程信息
-	// 为了简化，这里返回模拟数据
-	return map[string]interface{}{
-		"docker_version":   "24.0.7",
-		"api_version":      "1.43",
-		"kernel_version":   "5.15.0-91-generic",
-		"operating_system": "Ubuntu 22.04.3 LTS",
-		"architecture":     "x86_64",
-	}, nil
-}
\ No newline at end of file
+// collectNetworkInfo 收集网络信息
+func (m *DockerMonitor) collectNetworkInfo(ctx context.Context, metrics map[string]interface{}) error {
+	networks, err := m.client.NetworkList(ctx)
+	if err != nil {
+		return err
+	}
+
+	var bridge, overlay int
+	for _, n := range networks {
+		switch n.Driver {
+		case "bridge":
+			bridge++
+		case "overlay":
+			overlay++
+		}
+	}
+	metrics["networks_total"] = len(networks)
+	metrics["bridge_networks"] = bridge
+	metrics["overlay_networks"] = overlay
+	return nil
+}
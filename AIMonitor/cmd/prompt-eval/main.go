@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/database"
+	"ai-monitor/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// prompt-eval 提示词模板离线评估工具：回放评估集中的历史告警，输出JSON评估报告
+//
+// 用法：prompt-eval -set <评估集名称> -template <模板ID> [-judge] [-config config] [-output report.json]
+func main() {
+	configFile := flag.String("config", "config", "配置文件名")
+	setName := flag.String("set", "", "评估集名称")
+	templateID := flag.String("template", "", "提示词模板ID")
+	judge := flag.Bool("judge", false, "使用模型对照已确认根因打分")
+	output := flag.String("output", "", "报告输出文件，默认输出到标准输出")
+	flag.Parse()
+
+	if *setName == "" || *templateID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	id, err := uuid.Parse(*templateID)
+	if err != nil {
+		logrus.Fatalf("Invalid template id: %v", err)
+	}
+
+	// 初始化配置
+	cfg, err := config.LoadWithFile(*configFile)
+	if err != nil {
+		logrus.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 初始化数据库
+	if err := database.Initialize(&cfg.Database); err != nil {
+		logrus.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	// 评估不依赖缓存
+	aiService := services.NewAIService(database.DB, nil, cfg)
+	report, err := aiService.EvaluatePromptTemplate(*setName, id, *judge)
+	if err != nil {
+		logrus.Fatalf("Failed to evaluate prompt template: %v", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logrus.Fatalf("Failed to encode report: %v", err)
+	}
+	if *output == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		logrus.Fatalf("Failed to write report: %v", err)
+	}
+}
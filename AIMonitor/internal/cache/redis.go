@@ -447,6 +447,11 @@ func AlertRulesCacheKey(targetType, targetID, metricName string) string {
 	return "alert_rules:" + targetType + ":" + targetID + ":" + metricName
 }
 
+// CapacityReportCacheKey 生成容量预测报告缓存键
+func CapacityReportCacheKey(resource string, days int, thresholdPercent float64) string {
+	return fmt.Sprintf("ai:capacity:%s:%d:%.1f", resource, days, thresholdPercent)
+}
+
 // JWTBlacklistKey 生成JWT黑名单缓存键
 func JWTBlacklistKey(tokenID string) string {
 	return "jwt_blacklist:" + tokenID
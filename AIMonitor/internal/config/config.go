@@ -104,8 +104,19 @@ type ElasticsearchConfig struct {
 
 // AIModelsConfig AI模型配置
 type AIModelsConfig struct {
-	OpenAI AIModelConfig `mapstructure:"openai"`
-	Claude AIModelConfig `mapstructure:"claude"`
+	OpenAI         AIModelConfig   `mapstructure:"openai"`
+	Claude         AIModelConfig   `mapstructure:"claude"`
+	Assistant      AssistantConfig `mapstructure:"assistant"`
+	PromptLanguage string          `mapstructure:"prompt_language"`
+}
+
+// AssistantConfig 运维助手配置
+type AssistantConfig struct {
+	MaxSteps       int           `mapstructure:"max_steps"`
+	TokenBudget    int           `mapstructure:"token_budget"`
+	HistoryLimit   int           `mapstructure:"history_limit"`
+	ToolTimeout    time.Duration `mapstructure:"tool_timeout"`
+	MaxResultChars int           `mapstructure:"max_result_chars"`
 }
 
 // AIModelConfig 单个AI模型配置
@@ -118,6 +129,9 @@ type AIModelConfig struct {
 	Timeout     time.Duration   `mapstructure:"timeout"`
 	RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
 	CostLimit   CostLimitConfig `mapstructure:"cost_limit"`
+	// OutputMode 结构化输出方式：tool_call（函数调用）、json_mode（JSON模式）、prompt（仅提示词约束）
+	OutputMode     string `mapstructure:"output_mode"`
+	RepairAttempts int    `mapstructure:"repair_attempts"`
 }
 
 // RateLimitConfig 速率限制配置
@@ -164,6 +178,57 @@ type AlertingConfig struct {
 	RepeatInterval       time.Duration       `mapstructure:"repeat_interval"`
 	MaxAlertsPerGroup    int                 `mapstructure:"max_alerts_per_group"`
 	Aggregation          AggregationConfig   `mapstructure:"aggregation"`
+	AnomalyDetection     AnomalyDetectionConfig `mapstructure:"anomaly_detection"`
+	CapacityPlanning     CapacityPlanningConfig `mapstructure:"capacity_planning"`
+	Insights             InsightsConfig         `mapstructure:"insights"`
+	Correlation          CorrelationConfig      `mapstructure:"correlation"`
+	Remediation          RemediationConfig      `mapstructure:"remediation"`
+	VersionRegression    VersionRegressionConfig `mapstructure:"version_regression"`
+	SLO                  SLOConfig              `mapstructure:"slo"`
+}
+
+// SLOConfig SLO评估配置
+type SLOConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// HistoryInterval 错误预算历史的记录间隔
+	HistoryInterval time.Duration `mapstructure:"history_interval"`
+	// HistoryRetention 错误预算历史保留时长
+	HistoryRetention time.Duration `mapstructure:"history_retention"`
+	// BurnRateWindows 新建SLO时自动生成的多窗口燃烧率告警规则
+	BurnRateWindows []BurnRateWindowConfig `mapstructure:"burn_rate_windows"`
+}
+
+// BurnRateWindowConfig 燃烧率告警窗口：长短两个窗口的燃烧率都达到阈值时触发，短窗口回落后解决
+type BurnRateWindowConfig struct {
+	LongWindow  time.Duration `mapstructure:"long_window"`
+	ShortWindow time.Duration `mapstructure:"short_window"`
+	BurnRate    float64       `mapstructure:"burn_rate"`
+	Severity    string        `mapstructure:"severity"`
+}
+
+// VersionRegressionConfig 发布版本性能回归检测配置，版本对比接口也使用其中的显著性参数
+type VersionRegressionConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// ObservationWindow 新版本首次出现后至少观察多久再评估
+	ObservationWindow time.Duration `mapstructure:"observation_window"`
+	// BaselineWindow 旧版本在新版本出现前用作基线的时间范围
+	BaselineWindow time.Duration `mapstructure:"baseline_window"`
+	// MinSamples 基线和新版本各自至少需要的请求数
+	MinSamples int64 `mapstructure:"min_samples"`
+	// SignificanceLevel 显著性水平，检验p值低于该值才认为变化显著
+	SignificanceLevel float64 `mapstructure:"significance_level"`
+	// MinLatencyChange p95或p99相对增幅达到该比例才视为延迟回归
+	MinLatencyChange float64 `mapstructure:"min_latency_change"`
+}
+
+// AnomalyDetectionConfig 异常检测告警配置
+type AnomalyDetectionConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Interval           time.Duration `mapstructure:"interval"`
+	HistoryDays        int           `mapstructure:"history_days"`
+	DefaultSensitivity float64       `mapstructure:"default_sensitivity"`
 }
 
 // AggregationConfig 聚合配置
@@ -174,6 +239,42 @@ type AggregationConfig struct {
 	GroupBy    []string      `mapstructure:"group_by"`
 }
 
+// CapacityPlanningConfig 容量预测配置
+type CapacityPlanningConfig struct {
+	Enabled     bool               `mapstructure:"enabled"`
+	Interval    time.Duration      `mapstructure:"interval"`
+	HistoryDays int                `mapstructure:"history_days"`
+	Limits      map[string]float64 `mapstructure:"limits"`
+}
+
+// InsightsConfig 智能洞察生成配置
+type InsightsConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`
+	Lookback  time.Duration `mapstructure:"lookback"`
+	MaxSeries int           `mapstructure:"max_series"`
+}
+
+// CorrelationConfig 告警关联（故障事件）配置
+type CorrelationConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	Interval            time.Duration `mapstructure:"interval"`
+	Window              time.Duration `mapstructure:"window"`
+	SimilarityThreshold float64       `mapstructure:"similarity_threshold"`
+	SettleDelay         time.Duration `mapstructure:"settle_delay"`
+}
+
+// RemediationConfig 自动修复配置，告警自动触发还需开启 feature_flags.auto_remediation
+type RemediationConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Interval          time.Duration `mapstructure:"interval"`
+	ApprovalTimeout   time.Duration `mapstructure:"approval_timeout"`
+	DefaultRateLimit  int           `mapstructure:"default_rate_limit"`
+	DefaultRateWindow time.Duration `mapstructure:"default_rate_window"`
+	CommandTimeout    time.Duration `mapstructure:"command_timeout"`
+	WebhookTimeout    time.Duration `mapstructure:"webhook_timeout"`
+}
+
 // CollectorConfig 采集器配置
 type CollectorConfig struct {
 	ScrapeInterval     time.Duration              `mapstructure:"scrape_interval"`
@@ -229,6 +330,63 @@ type MonitoringConfig struct {
 	HealthCheck HealthCheckConfig `mapstructure:"health_check"`
 	Metrics     MetricsConfig     `mapstructure:"metrics"`
 	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Docker      DockerConfig      `mapstructure:"docker"`
+	Kubernetes  KubernetesConfig  `mapstructure:"kubernetes"`
+}
+
+// DockerConfig Docker Engine API连接配置
+type DockerConfig struct {
+	// Endpoint 支持 unix:///path、tcp://host:port，为空时读取DOCKER_HOST
+	Endpoint string `mapstructure:"endpoint"`
+	// APIVersion 固定API版本，为空时自动协商
+	APIVersion string        `mapstructure:"api_version"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	TLSVerify  bool          `mapstructure:"tls_verify"`
+	CertPath   string        `mapstructure:"cert_path"`
+	KeyPath    string        `mapstructure:"key_path"`
+	CAPath     string        `mapstructure:"ca_path"`
+	// Events 订阅/events流跟踪容器生命周期
+	Events DockerEventsConfig `mapstructure:"events"`
+}
+
+// DockerEventsConfig Docker事件订阅配置
+type DockerEventsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ReconnectInterval 事件流断开后的重连间隔
+	ReconnectInterval time.Duration `mapstructure:"reconnect_interval"`
+	// ResolveInterval 检查OOM、崩溃循环告警是否恢复的间隔
+	ResolveInterval time.Duration `mapstructure:"resolve_interval"`
+	// LogLines 告警附带的容器最近日志行数
+	LogLines  int           `mapstructure:"log_lines"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// KubernetesConfig Kubernetes API连接配置
+type KubernetesConfig struct {
+	// Kubeconfig kubeconfig文件路径，为空时依次尝试集群内ServiceAccount、KUBECONFIG与~/.kube/config
+	Kubeconfig string `mapstructure:"kubeconfig"`
+	// Context kubeconfig上下文，为空时使用current-context
+	Context string `mapstructure:"context"`
+	// InCluster 强制使用Pod挂载的ServiceAccount凭据
+	InCluster bool          `mapstructure:"in_cluster"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	// ClusterName 以上连接配置注册为集群时使用的名称
+	ClusterName string `mapstructure:"cluster_name"`
+	// HealthCheckInterval 已注册集群的健康检查间隔，0表示不检查
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"`
+	// Inventory 基于list+watch的资源缓存与内置检测器
+	Inventory KubernetesInventoryConfig `mapstructure:"inventory"`
+}
+
+// KubernetesInventoryConfig Kubernetes资源缓存与事件告警配置
+type KubernetesInventoryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RetryInterval list或watch失败后的重试间隔
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+	// ClusterSyncInterval 检查集群注册表变化（新增、更新、停用、删除）的间隔
+	ClusterSyncInterval time.Duration `mapstructure:"cluster_sync_interval"`
+	// DetectInterval 内置检测器的评估间隔
+	DetectInterval time.Duration `mapstructure:"detect_interval"`
 }
 
 // HealthCheckConfig 健康检查配置
@@ -251,6 +409,95 @@ type TracingConfig struct {
 	Enabled         bool   `mapstructure:"enabled"`
 	JaegerEndpoint  string `mapstructure:"jaeger_endpoint"`
 	ServiceName     string `mapstructure:"service_name"`
+	// QueryEndpoint Jaeger Query服务地址（HTTP/JSON API），用于查询链路数据
+	QueryEndpoint   string        `mapstructure:"query_endpoint"`
+	QueryTimeout    time.Duration `mapstructure:"query_timeout"`
+	// OTLP 内置OTLP链路接收器，HTTP接收端挂载在主服务的/v1/traces
+	OTLP            OTLPReceiverConfig `mapstructure:"otlp"`
+	// Zipkin Zipkin v2 JSON接收器，挂载在主服务的/api/v2/spans
+	Zipkin          TraceReceiverConfig `mapstructure:"zipkin"`
+	// JaegerThrift Jaeger客户端Thrift over HTTP接收器，挂载在主服务的/api/traces
+	JaegerThrift    TraceReceiverConfig `mapstructure:"jaeger_thrift"`
+	// ServiceMap 基于存储跨度的服务拓扑聚合
+	ServiceMap      ServiceMapConfig   `mapstructure:"service_map"`
+	// SpanMetrics 由跨度计算RED指标
+	SpanMetrics     SpanMetricsConfig  `mapstructure:"span_metrics"`
+	// Sampling 尾部采样，按整条链路决定是否入库
+	Sampling        TailSamplingConfig   `mapstructure:"sampling"`
+	// Retention 链路数据保留策略
+	Retention       TraceRetentionConfig `mapstructure:"retention"`
+}
+
+// TailSamplingConfig 尾部采样配置。错误链路、超过延迟阈值或命中属性的链路始终保留，其余按服务保留率采样
+type TailSamplingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DecisionWait 链路首个跨度到达后等待其余跨度的时间
+	DecisionWait      time.Duration `mapstructure:"decision_wait"`
+	MaxBufferedTraces int           `mapstructure:"max_buffered_traces"`
+	// LateSpanTTL 已决策链路的结果保留时间，期间迟到的跨度沿用同一决策
+	LateSpanTTL     time.Duration `mapstructure:"late_span_ttl"`
+	DefaultKeepRate float64       `mapstructure:"default_keep_rate"`
+	// ServiceKeepRates 服务名 -> 保留率(0-1)，链路涉及多个服务时取最大值
+	ServiceKeepRates        map[string]float64          `mapstructure:"service_keep_rates"`
+	DefaultLatencyThreshold time.Duration               `mapstructure:"default_latency_threshold"`
+	LatencyThresholds       []OperationLatencyThreshold `mapstructure:"latency_thresholds"`
+	KeepAttributes          []SamplingAttributeMatch    `mapstructure:"keep_attributes"`
+}
+
+// OperationLatencyThreshold 操作延迟阈值，Service或Operation为空表示匹配任意值
+type OperationLatencyThreshold struct {
+	Service   string        `mapstructure:"service"`
+	Operation string        `mapstructure:"operation"`
+	Threshold time.Duration `mapstructure:"threshold"`
+}
+
+// SamplingAttributeMatch 属性匹配条件，Value为空表示只要求属性存在
+type SamplingAttributeMatch struct {
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
+}
+
+// TraceRetentionConfig 链路保留配置，包含错误跨度的链路按ErrorMaxAge保留
+type TraceRetentionConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`
+	MaxAge      time.Duration `mapstructure:"max_age"`
+	ErrorMaxAge time.Duration `mapstructure:"error_max_age"`
+	BatchSize   int           `mapstructure:"batch_size"`
+}
+
+// SpanMetricsConfig 跨度指标配置
+type SpanMetricsConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	Retention     time.Duration `mapstructure:"retention"`
+}
+
+// ServiceMapConfig 服务拓扑聚合配置，Windows中第一个窗口为默认窗口并用于历史快照
+type ServiceMapConfig struct {
+	Enabled          bool            `mapstructure:"enabled"`
+	Interval         time.Duration   `mapstructure:"interval"`
+	Windows          []time.Duration `mapstructure:"windows"`
+	SnapshotInterval time.Duration   `mapstructure:"snapshot_interval"`
+	HistoryRetention time.Duration   `mapstructure:"history_retention"`
+	MaxSpans         int             `mapstructure:"max_spans"`
+}
+
+// OTLPReceiverConfig OTLP接收器配置
+type OTLPReceiverConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	GRPCAddr    string `mapstructure:"grpc_addr"`
+	MaxBodySize int64  `mapstructure:"max_body_size"`
+	// RequireAuth 要求携带API Key（Authorization: Bearer <key>）
+	RequireAuth bool   `mapstructure:"require_auth"`
+}
+
+// TraceReceiverConfig HTTP链路接收器配置
+type TraceReceiverConfig struct {
+	Enabled     bool  `mapstructure:"enabled"`
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+	// RequireAuth 要求携带API Key（Authorization: Bearer <key>）
+	RequireAuth bool `mapstructure:"require_auth"`
 }
 
 // CacheConfig 缓存配置
@@ -364,6 +611,127 @@ func setDefaults() {
 	viper.SetDefault("jwt.refresh_token_expiry", "168h")
 	viper.SetDefault("jwt.issuer", "ai-monitor")
 
+	// Docker连接默认值
+	viper.SetDefault("monitoring.docker.endpoint", "unix:///var/run/docker.sock")
+	viper.SetDefault("monitoring.docker.timeout", "10s")
+	viper.SetDefault("monitoring.docker.events.enabled", false)
+	viper.SetDefault("monitoring.docker.events.reconnect_interval", "10s")
+	viper.SetDefault("monitoring.docker.events.resolve_interval", "1m")
+	viper.SetDefault("monitoring.docker.events.log_lines", 50)
+	viper.SetDefault("monitoring.docker.events.retention", "720h")
+
+	// Kubernetes连接默认值
+	viper.SetDefault("monitoring.kubernetes.in_cluster", false)
+	viper.SetDefault("monitoring.kubernetes.timeout", "15s")
+	viper.SetDefault("monitoring.kubernetes.cluster_name", "default")
+	viper.SetDefault("monitoring.kubernetes.health_check_interval", "1m")
+	viper.SetDefault("monitoring.kubernetes.inventory.enabled", false)
+	viper.SetDefault("monitoring.kubernetes.inventory.retry_interval", "5s")
+	viper.SetDefault("monitoring.kubernetes.inventory.cluster_sync_interval", "1m")
+	viper.SetDefault("monitoring.kubernetes.inventory.detect_interval", "30s")
+
+	// 链路查询默认值
+	viper.SetDefault("monitoring.tracing.query_endpoint", "http://localhost:16686")
+	viper.SetDefault("monitoring.tracing.query_timeout", "10s")
+	viper.SetDefault("monitoring.tracing.otlp.enabled", false)
+	viper.SetDefault("monitoring.tracing.otlp.grpc_addr", ":4317")
+	viper.SetDefault("monitoring.tracing.otlp.max_body_size", 8<<20)
+	viper.SetDefault("monitoring.tracing.otlp.require_auth", true)
+	viper.SetDefault("monitoring.tracing.zipkin.enabled", false)
+	viper.SetDefault("monitoring.tracing.zipkin.max_body_size", 8<<20)
+	viper.SetDefault("monitoring.tracing.zipkin.require_auth", true)
+	viper.SetDefault("monitoring.tracing.jaeger_thrift.enabled", false)
+	viper.SetDefault("monitoring.tracing.jaeger_thrift.max_body_size", 8<<20)
+	viper.SetDefault("monitoring.tracing.jaeger_thrift.require_auth", true)
+	viper.SetDefault("monitoring.tracing.service_map.enabled", true)
+	viper.SetDefault("monitoring.tracing.service_map.interval", "1m")
+	viper.SetDefault("monitoring.tracing.service_map.windows", []string{"15m", "5m", "1h"})
+	viper.SetDefault("monitoring.tracing.service_map.snapshot_interval", "10m")
+	viper.SetDefault("monitoring.tracing.service_map.history_retention", "168h")
+	viper.SetDefault("monitoring.tracing.service_map.max_spans", 200000)
+	viper.SetDefault("monitoring.tracing.span_metrics.enabled", true)
+	viper.SetDefault("monitoring.tracing.span_metrics.flush_interval", "1m")
+	viper.SetDefault("monitoring.tracing.span_metrics.retention", "720h")
+	viper.SetDefault("monitoring.tracing.sampling.enabled", false)
+	viper.SetDefault("monitoring.tracing.sampling.decision_wait", "10s")
+	viper.SetDefault("monitoring.tracing.sampling.max_buffered_traces", 50000)
+	viper.SetDefault("monitoring.tracing.sampling.late_span_ttl", "5m")
+	viper.SetDefault("monitoring.tracing.sampling.default_keep_rate", 0.1)
+	viper.SetDefault("monitoring.tracing.sampling.default_latency_threshold", "2s")
+	viper.SetDefault("monitoring.tracing.retention.enabled", true)
+	viper.SetDefault("monitoring.tracing.retention.interval", "1h")
+	viper.SetDefault("monitoring.tracing.retention.max_age", "72h")
+	viper.SetDefault("monitoring.tracing.retention.error_max_age", "336h")
+	viper.SetDefault("monitoring.tracing.retention.batch_size", 5000)
+
+	// 提示词模板默认语言
+	viper.SetDefault("ai_models.prompt_language", "zh")
+
+	// 结构化输出默认值
+	viper.SetDefault("ai_models.openai.output_mode", "tool_call")
+	viper.SetDefault("ai_models.openai.repair_attempts", 1)
+
+	// 运维助手默认值
+	viper.SetDefault("ai_models.assistant.max_steps", 6)
+	viper.SetDefault("ai_models.assistant.token_budget", 20000)
+	viper.SetDefault("ai_models.assistant.history_limit", 20)
+	viper.SetDefault("ai_models.assistant.tool_timeout", "30s")
+	viper.SetDefault("ai_models.assistant.max_result_chars", 6000)
+
+	// 版本回归检测默认值
+	viper.SetDefault("alerting.version_regression.enabled", true)
+	viper.SetDefault("alerting.version_regression.interval", "5m")
+	viper.SetDefault("alerting.version_regression.observation_window", "30m")
+	viper.SetDefault("alerting.version_regression.baseline_window", "24h")
+	viper.SetDefault("alerting.version_regression.min_samples", 100)
+	viper.SetDefault("alerting.version_regression.significance_level", 0.01)
+	viper.SetDefault("alerting.version_regression.min_latency_change", 0.1)
+
+	// SLO评估默认值，燃烧率窗口未配置时使用内置的多窗口组合
+	viper.SetDefault("alerting.slo.enabled", true)
+	viper.SetDefault("alerting.slo.interval", "1m")
+	viper.SetDefault("alerting.slo.history_interval", "10m")
+	viper.SetDefault("alerting.slo.history_retention", "2160h")
+
+	// 异常检测告警默认值
+	viper.SetDefault("alerting.anomaly_detection.enabled", true)
+	viper.SetDefault("alerting.anomaly_detection.interval", "5m")
+	viper.SetDefault("alerting.anomaly_detection.history_days", 28)
+	viper.SetDefault("alerting.anomaly_detection.default_sensitivity", 3.5)
+
+	// 容量预测默认值
+	viper.SetDefault("alerting.capacity_planning.enabled", true)
+	viper.SetDefault("alerting.capacity_planning.interval", "15m")
+	viper.SetDefault("alerting.capacity_planning.history_days", 14)
+	viper.SetDefault("alerting.capacity_planning.limits", map[string]float64{
+		"disk":            100,
+		"memory":          100,
+		"connection_pool": 100,
+		"kafka_lag":       100000,
+	})
+
+	// 智能洞察默认值
+	viper.SetDefault("alerting.insights.enabled", true)
+	viper.SetDefault("alerting.insights.interval", "10m")
+	viper.SetDefault("alerting.insights.lookback", "24h")
+	viper.SetDefault("alerting.insights.max_series", 500)
+
+	// 告警关联默认值
+	viper.SetDefault("alerting.correlation.enabled", true)
+	viper.SetDefault("alerting.correlation.interval", "1m")
+	viper.SetDefault("alerting.correlation.window", "10m")
+	viper.SetDefault("alerting.correlation.similarity_threshold", 0.5)
+	viper.SetDefault("alerting.correlation.settle_delay", "2m")
+
+	// 自动修复默认值
+	viper.SetDefault("alerting.remediation.enabled", true)
+	viper.SetDefault("alerting.remediation.interval", "30s")
+	viper.SetDefault("alerting.remediation.approval_timeout", "30m")
+	viper.SetDefault("alerting.remediation.default_rate_limit", 3)
+	viper.SetDefault("alerting.remediation.default_rate_window", "1h")
+	viper.SetDefault("alerting.remediation.command_timeout", "5m")
+	viper.SetDefault("alerting.remediation.webhook_timeout", "10s")
+
 	// 日志默认值
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
@@ -425,4 +793,9 @@ func (c *DatabaseConfig) GetDSN() string {
 // GetRedisAddr 获取Redis地址
 func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
-}
\ No newline at end of file
+}
+
+// ReceiversEnabled 是否启用了任一内置链路接收器，启用后链路查询改用本地存储
+func (c *TracingConfig) ReceiversEnabled() bool {
+	return c.OTLP.Enabled || c.Zipkin.Enabled || c.JaegerThrift.Enabled
+}
@@ -130,6 +130,30 @@ func Migrate() error {
 		&models.Dashboard{},
 		&models.NotificationChannel{},
 		&models.APIKey{},
+		&models.AlertEvent{},
+		&models.AIInsight{},
+		&models.Incident{},
+		&models.IncidentAlert{},
+		&models.IncidentEvent{},
+		&models.RemediationPlaybook{},
+		&models.RemediationExecution{},
+		&models.AgentCommand{},
+		&models.AssistantConversation{},
+		&models.AssistantMessage{},
+		&models.AIAnalysisFeedback{},
+		&models.IncidentMemory{},
+		&models.PromptTemplate{},
+		&models.PromptEvalSet{},
+		&models.APMService{},
+		&models.APMTrace{},
+		&models.ServiceTopologySnapshot{},
+		&models.APMSpanMetric{},
+		&models.APMSpanTag{},
+		&models.ServiceDeployment{},
+		&models.SLO{},
+		&models.SLOSnapshot{},
+		&models.ContainerEvent{},
+		&models.KubernetesCluster{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -223,6 +247,44 @@ func createIndexes() error {
 	DB.Exec("CREATE INDEX IF NOT EXISTS idx_notification_channels_enabled ON notification_channels(enabled)")
 	DB.Exec("CREATE INDEX IF NOT EXISTS idx_notification_channels_created_by ON notification_channels(created_by)")
 
+	// 告警事件表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_alert_events_alert_created_at ON alert_events(alert_id, created_at)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_alert_events_type_created_at ON alert_events(type, created_at)")
+
+	// AI洞察表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_ai_insights_batch_rank ON ai_insights(batch_id, insight_rank)")
+
+	// 故障事件表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_incidents_status_last_alert_at ON incidents(status, last_alert_at)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_incident_events_incident_created_at ON incident_events(incident_id, created_at)")
+
+	// 自动修复表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_remediation_executions_playbook_target_started ON remediation_executions(playbook_id, target_id, started_at)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_agent_commands_agent_status ON agent_commands(agent_id, status)")
+
+	// 运维助手表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_assistant_conversations_user_active ON assistant_conversations(user_id, last_active)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_assistant_messages_conversation_created ON assistant_messages(conversation_id, created_at)")
+
+	// AI分析反馈表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_ai_analysis_feedback_analysis_user ON ai_analysis_feedback(analysis_id, user_id)")
+
+	// 链路跨度表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_traces_service_start ON apm_traces(service_name, start_time)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_traces_operation_start ON apm_traces(operation_name, start_time)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_traces_status_start ON apm_traces(status, start_time)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_span_tags_key_value ON apm_span_tags(tag_key, tag_value, start_time)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_span_metrics_service_bucket ON apm_span_metrics(service_name, bucket_start)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_span_metrics_service_version ON apm_span_metrics(service_name, service_version, bucket_start)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_apm_span_metrics_service_end ON apm_span_metrics(service_name, bucket_end)")
+
+	// SLO表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_metric_data_metric_timestamp ON metric_data(metric, timestamp)")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_slo_snapshots_slo_timestamp ON slo_snapshots(slo_id, timestamp)")
+
+	// 容器事件表索引
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_container_events_container_timestamp ON container_events(container_id, timestamp)")
+
 	return nil
 }
 
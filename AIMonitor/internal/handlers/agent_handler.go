@@ -45,6 +45,15 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 		return
 	}
 
+	// 通过API Key注册的Agent与该Key绑定，后续命令通道按此校验
+	if authType, _ := c.Get("auth_type"); authType == "api_key" {
+		if apiKeyID, ok := c.Get("api_key_id"); ok {
+			if id, ok := apiKeyID.(uuid.UUID); ok {
+				req.APIKeyID = &id
+			}
+		}
+	}
+
 	agent, err := h.agentService.CreateAgent(&req)
 	if err != nil {
 		if err.Error() == "agent name already exists" {
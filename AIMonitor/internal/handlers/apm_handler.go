@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"ai-monitor/internal/services"
@@ -25,17 +29,19 @@ func NewAPMHandler(apmService *services.APMService) *APMHandler {
 
 // GetTraces 获取链路追踪列表
 // @Summary 获取链路追踪列表
-// @Description 获取应用的链路追踪数据列表
+// @Description 按服务、操作、状态、耗时范围及任意跨度标签检索链路
 // @Tags APM监控
 // @Accept json
 // @Produce json
 // @Param service_name query string false "服务名称"
 // @Param operation_name query string false "操作名称"
 // @Param status query string false "状态" Enums(ok,error,timeout)
+// @Param tag query []string false "跨度标签条件，格式key:value，可重复" collectionFormat(multi)
+// @Param tags query string false "跨度标签条件，JSON对象，如{\"http.status_code\":\"500\"}"
 // @Param start_time query string false "开始时间" format(date-time)
 // @Param end_time query string false "结束时间" format(date-time)
-// @Param min_duration query int false "最小耗时(微秒)"
-// @Param max_duration query int false "最大耗时(微秒)"
+// @Param min_duration query string false "最小耗时，微秒数或时长字符串如500ms"
+// @Param max_duration query string false "最大耗时，微秒数或时长字符串如2s"
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页数量" default(20)
 // @Success 200 {object} PaginatedResponse
@@ -47,9 +53,32 @@ func (h *APMHandler) GetTraces(c *gin.Context) {
 	query := services.TraceQuery{
 		Service:   c.Query("service_name"),
 		Operation: c.Query("operation_name"),
+		Status:    c.Query("status"),
 		Tags:      make(map[string]string),
 	}
 
+	// 解析标签条件
+	if tagsJSON := c.Query("tags"); tagsJSON != "" {
+		if err := json.Unmarshal([]byte(tagsJSON), &query.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid tags",
+				Message: "tags must be a JSON object of string values",
+			})
+			return
+		}
+	}
+	for _, tag := range c.QueryArray("tag") {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid tag",
+				Message: fmt.Sprintf("tag %q must be in key:value format", tag),
+			})
+			return
+		}
+		query.Tags[key] = value
+	}
+
 	// 解析时间范围
 	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
 		if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
@@ -63,16 +92,8 @@ func (h *APMHandler) GetTraces(c *gin.Context) {
 	}
 
 	// 解析耗时范围
-	if minDurationStr := c.Query("min_duration"); minDurationStr != "" {
-		if minDuration, err := strconv.ParseInt(minDurationStr, 10, 64); err == nil {
-			query.MinDuration = time.Duration(minDuration) * time.Microsecond
-		}
-	}
-	if maxDurationStr := c.Query("max_duration"); maxDurationStr != "" {
-		if maxDuration, err := strconv.ParseInt(maxDurationStr, 10, 64); err == nil {
-			query.MaxDuration = time.Duration(maxDuration) * time.Microsecond
-		}
-	}
+	query.MinDuration = parseTraceDuration(c.Query("min_duration"))
+	query.MaxDuration = parseTraceDuration(c.Query("max_duration"))
 
 	// 解析分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -87,6 +108,7 @@ func (h *APMHandler) GetTraces(c *gin.Context) {
 
 	// 设置分页限制
 	query.Limit = pageSize
+	query.Offset = (page - 1) * pageSize
 
 	traces, err := h.apmService.GetTraces(query)
 	if err != nil {
@@ -97,23 +119,43 @@ func (h *APMHandler) GetTraces(c *gin.Context) {
 		return
 	}
 
-	// 简化响应，因为实际的分页逻辑需要在服务层实现
+	// Jaeger不返回总数，满页时提示还有下一页
+	total := query.Offset + len(traces)
+	pages := page
+	if len(traces) == pageSize {
+		total++
+		pages++
+	}
 	response := PaginatedResponse{
 		Data: traces,
 		Pagination: PaginationInfo{
 			Page:     page,
 			PageSize: pageSize,
-			Total:    len(traces),
-			Pages:    1,
+			Total:    total,
+			Pages:    pages,
 		},
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// parseTraceDuration 解析耗时参数，纯数字按微秒，否则按时长字符串解析
+func parseTraceDuration(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if micros, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(micros) * time.Microsecond
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return 0
+}
+
 // GetTraceDetail 获取链路追踪详情
 // @Summary 获取链路追踪详情
-// @Description 获取指定链路追踪的详细信息
+// @Description 获取指定链路追踪的详细信息，包含关键路径、跨度自身耗时、服务耗时分布及N+1查询检测
 // @Tags APM监控
 // @Accept json
 // @Produce json
@@ -135,7 +177,7 @@ func (h *APMHandler) GetTraceDetail(c *gin.Context) {
 
 	traceDetail, err := h.apmService.GetTraceDetail(traceID)
 	if err != nil {
-		if err.Error() == "trace not found" {
+		if errors.Is(err, services.ErrTraceNotFound) {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "Not Found",
 				Message: err.Error(),
@@ -154,38 +196,89 @@ func (h *APMHandler) GetTraceDetail(c *gin.Context) {
 
 // GetServiceTopology 获取服务拓扑图
 // @Summary 获取服务拓扑图
-// @Description 获取应用服务间的调用拓扑关系
+// @Description 获取由链路跨度聚合的服务调用拓扑，可按滑动窗口、时间范围或历史时间点查询
 // @Tags APM监控
 // @Accept json
 // @Produce json
-// @Param start_time query string false "开始时间" format(date-time)
+// @Param window query string false "聚合窗口，如5m、15m、1h，默认使用配置的第一个窗口"
+// @Param start_time query string false "开始时间，与end_time同时指定时按该范围实时聚合" format(date-time)
 // @Param end_time query string false "结束时间" format(date-time)
-// @Param service_name query string false "服务名称过滤"
-// @Success 200 {object} services.ServiceTopology
+// @Param at query string false "历史时间点，返回该时间点之前最近的拓扑快照" format(date-time)
+// @Param service_name query string false "只返回该服务的直接上下游"
+// @Success 200 {object} services.ServiceMap
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /api/v1/apm/topology [get]
+// @Router /api/v1/apm/service-map [get]
 func (h *APMHandler) GetServiceTopology(c *gin.Context) {
-	// 解析时间范围
-	var startTime, endTime *time.Time
-	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
-		if t, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
-			startTime = &t
+	serviceName := c.Query("service_name")
+
+	// 历史快照
+	if atStr := c.Query("at"); atStr != "" {
+		at, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid at time",
+			})
+			return
+		}
+		topology, _, err := h.apmService.GetServiceMapAt(at)
+		if err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "Not Found",
+				Message: err.Error(),
+			})
+			return
+		}
+		if serviceName != "" {
+			topology = services.FilterServiceMap(topology, serviceName)
 		}
+		c.JSON(http.StatusOK, topology)
+		return
 	}
-	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
-		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
-			endTime = &t
+
+	// 指定时间范围
+	startStr, endStr := c.Query("start_time"), c.Query("end_time")
+	if startStr != "" && endStr != "" {
+		startTime, err1 := time.Parse(time.RFC3339, startStr)
+		endTime, err2 := time.Parse(time.RFC3339, endStr)
+		if err1 != nil || err2 != nil || !endTime.After(startTime) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid time range",
+			})
+			return
+		}
+		topology, err := h.apmService.BuildServiceMap(c.Request.Context(), startTime, endTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if serviceName != "" {
+			topology = services.FilterServiceMap(topology, serviceName)
 		}
+		c.JSON(http.StatusOK, topology)
+		return
 	}
 
-	_ = c.Query("service_name")
-	// 暂时不使用时间范围参数
-	_ = startTime
-	_ = endTime
+	var window time.Duration
+	if windowStr := c.Query("window"); windowStr != "" {
+		d, err := time.ParseDuration(windowStr)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid window",
+			})
+			return
+		}
+		window = d
+	}
 
 	// 获取服务拓扑图
-	topology, err := h.apmService.GetServiceMap()
+	topology, err := h.apmService.GetServiceMapForWindow(window, serviceName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Internal Server Error",
@@ -197,6 +290,233 @@ func (h *APMHandler) GetServiceTopology(c *gin.Context) {
 	c.JSON(http.StatusOK, topology)
 }
 
+// GetServiceMapHistory 获取服务拓扑历史快照列表
+// @Summary 获取服务拓扑历史快照
+// @Description 列出时间范围内保存的服务拓扑快照，默认最近24小时
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Param start_time query string false "开始时间" format(date-time)
+// @Param end_time query string false "结束时间" format(date-time)
+// @Param limit query int false "返回数量" default(100)
+// @Success 200 {object} []services.TopologySnapshotInfo
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/apm/service-map/history [get]
+func (h *APMHandler) GetServiceMapHistory(c *gin.Context) {
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour)
+	if startStr := c.Query("start_time"); startStr != "" {
+		t, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid start time",
+			})
+			return
+		}
+		startTime = t
+	}
+	if endStr := c.Query("end_time"); endStr != "" {
+		t, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid end time",
+			})
+			return
+		}
+		endTime = t
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	snapshots, err := h.apmService.ListTopologySnapshots(startTime, endTime, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// DiffServiceMap 对比两个时间点的服务拓扑
+// @Summary 对比服务拓扑
+// @Description 对比两个时间点之前最近的拓扑快照，返回新增、消失的节点和调用关系，以及错误率、p95延迟、吞吐量显著变化的调用
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Param before query string true "对比基准时间，如发布开始时间" format(date-time)
+// @Param after query string true "对比时间，如发布完成后" format(date-time)
+// @Success 200 {object} services.ServiceMapDiff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/apm/service-map/diff [get]
+func (h *APMHandler) DiffServiceMap(c *gin.Context) {
+	before, err1 := time.Parse(time.RFC3339, c.Query("before"))
+	after, err2 := time.Parse(time.RFC3339, c.Query("after"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Bad Request",
+			Message: "before and after must be RFC3339 times",
+		})
+		return
+	}
+
+	diff, err := h.apmService.DiffServiceMap(before, after)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "topology snapshot not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// GetServiceVersions 获取服务按版本划分的延迟分布
+// @Summary 获取服务版本延迟分布
+// @Description 按service.version统计服务各操作的请求数、错误率、延迟分位数和直方图
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Param name path string true "服务名称"
+// @Param start_time query string false "开始时间，默认7天前" format(date-time)
+// @Param end_time query string false "结束时间，默认当前时间" format(date-time)
+// @Success 200 {object} services.VersionDistributions
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/apm/services/{name}/versions [get]
+func (h *APMHandler) GetServiceVersions(c *gin.Context) {
+	endTime := time.Now()
+	startTime := endTime.Add(-7 * 24 * time.Hour)
+	var err error
+	if startTime, err = parseOptionalTime(c.Query("start_time"), startTime); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid start time",
+		})
+		return
+	}
+	if endTime, err = parseOptionalTime(c.Query("end_time"), endTime); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid end time",
+		})
+		return
+	}
+
+	distributions, err := h.apmService.GetVersionDistributions(c.Request.Context(), c.Param("name"), startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, distributions)
+}
+
+// CompareServiceVersions 对比服务的两个版本或两个时间窗口
+// @Summary 对比服务版本
+// @Description 对比两个版本或两个时间窗口的p50/p95/p99和错误率，给出显著性检验结果及自身耗时增长最多的操作。
+// @Description 只指定候选版本时以其发布记录为准，基线取上一版本在发布前的数据；都不指定时对比最近一小时与前一小时
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Param name path string true "服务名称"
+// @Param baseline_version query string false "基线版本"
+// @Param candidate_version query string false "候选版本"
+// @Param baseline_start query string false "基线开始时间" format(date-time)
+// @Param baseline_end query string false "基线结束时间" format(date-time)
+// @Param candidate_start query string false "候选开始时间" format(date-time)
+// @Param candidate_end query string false "候选结束时间" format(date-time)
+// @Success 200 {object} services.VersionComparison
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/apm/services/{name}/compare [get]
+func (h *APMHandler) CompareServiceVersions(c *gin.Context) {
+	req := services.VersionCompareRequest{
+		ServiceName:      c.Param("name"),
+		BaselineVersion:  c.Query("baseline_version"),
+		CandidateVersion: c.Query("candidate_version"),
+	}
+	for param, target := range map[string]*time.Time{
+		"baseline_start":  &req.BaselineStart,
+		"baseline_end":    &req.BaselineEnd,
+		"candidate_start": &req.CandidateStart,
+		"candidate_end":   &req.CandidateEnd,
+	} {
+		t, err := parseOptionalTime(c.Query(param), time.Time{})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: fmt.Sprintf("Invalid %s", param),
+			})
+			return
+		}
+		*target = t
+	}
+
+	comparison, err := h.apmService.CompareVersions(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "invalid time range", "baseline and candidate are identical", "service name is required":
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// GetServiceDeployments 获取服务发布记录
+// @Summary 获取服务发布记录
+// @Description 列出服务各版本首次出现的时间及与上一版本对比的回归评估结果
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Param name path string true "服务名称"
+// @Param limit query int false "返回数量" default(50)
+// @Success 200 {object} []models.ServiceDeployment
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/apm/services/{name}/deployments [get]
+func (h *APMHandler) GetServiceDeployments(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	deployments, err := h.apmService.ListDeployments(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deployments)
+}
+
+// parseOptionalTime 解析RFC3339时间，为空时返回默认值
+func parseOptionalTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 // GetServices 获取服务列表
 // @Summary 获取服务列表
 // @Description 获取所有监控的服务列表
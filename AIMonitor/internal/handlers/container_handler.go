@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"ai-monitor/internal/services"
@@ -125,6 +127,7 @@ func (h *ContainerHandler) GetDockerContainerDetail(c *gin.Context) {
 // @Tags 容器监控
 // @Accept json
 // @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群，all表示全部已启用集群"
 // @Param namespace query string false "命名空间过滤"
 // @Param node_name query string false "节点名称过滤"
 // @Param status query string false "Pod状态" Enums(running,pending,succeeded,failed,unknown)
@@ -137,6 +140,7 @@ func (h *ContainerHandler) GetDockerContainerDetail(c *gin.Context) {
 // @Router /api/v1/containers/kubernetes/pods [get]
 func (h *ContainerHandler) GetKubernetesPods(c *gin.Context) {
 	// 解析查询参数
+	cluster := c.Query("cluster")
 	namespace := c.Query("namespace")
 	_ = c.Query("node_name")
 	_ = c.Query("status")
@@ -153,10 +157,12 @@ func (h *ContainerHandler) GetKubernetesPods(c *gin.Context) {
 		pageSize = 10
 	}
 
-	pods, err := h.containerService.GetKubernetesPods(namespace)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Internal Server Error",
+	pods, err := h.containerService.GetKubernetesPods(cluster, namespace)
+	warnings, ok := clusterWarnings(err)
+	if !ok {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
 			Message: err.Error(),
 		})
 		return
@@ -171,6 +177,7 @@ func (h *ContainerHandler) GetKubernetesPods(c *gin.Context) {
 			Total:    len(pods),
 			Pages:    1,
 		},
+		Warnings: warnings,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -263,6 +270,7 @@ func (h *ContainerHandler) GetContainerMonitor(c *gin.Context) {
 // @Tags 容器监控
 // @Accept json
 // @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群，all表示全部已启用集群"
 // @Param status query string false "节点状态" Enums(ready,notready,unknown)
 // @Param role query string false "节点角色" Enums(master,worker)
 // @Param page query int false "页码" default(1)
@@ -273,6 +281,7 @@ func (h *ContainerHandler) GetContainerMonitor(c *gin.Context) {
 // @Router /api/v1/containers/kubernetes/nodes [get]
 func (h *ContainerHandler) GetKubernetesNodes(c *gin.Context) {
 	// 解析查询参数
+	cluster := c.Query("cluster")
 	_ = c.Query("status")
 	_ = c.Query("role")
 
@@ -287,10 +296,12 @@ func (h *ContainerHandler) GetKubernetesNodes(c *gin.Context) {
 		pageSize = 10
 	}
 
-	nodes, err := h.containerService.GetKubernetesNodes()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Internal Server Error",
+	nodes, err := h.containerService.GetKubernetesNodes(cluster)
+	warnings, ok := clusterWarnings(err)
+	if !ok {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
 			Message: err.Error(),
 		})
 		return
@@ -306,6 +317,7 @@ func (h *ContainerHandler) GetKubernetesNodes(c *gin.Context) {
 			Total:    len(nodes),
 			Pages:    totalPages,
 		},
+		Warnings: warnings,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -317,39 +329,177 @@ func (h *ContainerHandler) GetKubernetesNodes(c *gin.Context) {
 // @Tags 容器监控
 // @Accept json
 // @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群，all表示全部已启用集群"
 // @Param status query string false "命名空间状态" Enums(active,terminating)
 // @Success 200 {object} []services.KubernetesNamespace
+// @Header 200 {string} X-Cluster-Warnings "部分集群查询失败时的说明"
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/containers/kubernetes/namespaces [get]
 func (h *ContainerHandler) GetKubernetesNamespaces(c *gin.Context) {
 	_ = c.Query("status")
 
-	namespaces, err := h.containerService.GetKubernetesNamespaces()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Internal Server Error",
+	namespaces, err := h.containerService.GetKubernetesNamespaces(c.Query("cluster"))
+	warnings, ok := clusterWarnings(err)
+	if !ok {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
 			Message: err.Error(),
 		})
 		return
 	}
 
+	// 响应体为数组，部分集群失败的说明通过响应头返回
+	if len(warnings) > 0 {
+		c.Header("X-Cluster-Warnings", strings.Join(warnings, "; "))
+	}
+
 	c.JSON(http.StatusOK, namespaces)
 }
 
+// GetKubernetesWorkloads 获取Kubernetes工作负载列表
+// @Summary 获取Kubernetes工作负载列表
+// @Description 从资源缓存获取Deployment、StatefulSet、DaemonSet与Job的副本状态，需启用monitoring.kubernetes.inventory
+// @Tags 容器监控
+// @Accept json
+// @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群，all表示全部已启用集群"
+// @Param kind query string false "工作负载类型，为空时返回全部" Enums(deployment,statefulset,daemonset,job)
+// @Param namespace query string false "命名空间过滤"
+// @Param status query string false "状态过滤" Enums(healthy,progressing,degraded,running,complete,failed)
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/containers/kubernetes/workloads [get]
+func (h *ContainerHandler) GetKubernetesWorkloads(c *gin.Context) {
+	kind := c.Query("kind")
+	switch kind {
+	case "", services.WorkloadDeployment, services.WorkloadStatefulSet, services.WorkloadDaemonSet, services.WorkloadJob:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid kind",
+			Message: "kind must be one of deployment, statefulset, daemonset, job",
+		})
+		return
+	}
+	status := c.Query("status")
+
+	workloads, err := h.containerService.GetKubernetesWorkloads(c.Query("cluster"), kind, c.Query("namespace"))
+	warnings, ok := clusterWarnings(err)
+	if !ok {
+		code := kubernetesErrorStatus(err)
+		c.JSON(code, ErrorResponse{
+			Error:   http.StatusText(code),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if status != "" {
+		filtered := []services.KubernetesWorkload{}
+		for _, workload := range workloads {
+			if workload.Status == status {
+				filtered = append(filtered, workload)
+			}
+		}
+		workloads = filtered
+	}
+
+	c.JSON(http.StatusOK, PaginatedResponse{
+		Data: workloads,
+		Pagination: PaginationInfo{
+			Page:     1,
+			PageSize: len(workloads),
+			Total:    len(workloads),
+			Pages:    1,
+		},
+		Warnings: warnings,
+	})
+}
+
+// GetKubernetesEvents 获取Kubernetes事件列表
+// @Summary 获取Kubernetes事件列表
+// @Description 从资源缓存获取集群事件，按最近发生时间倒序，需启用monitoring.kubernetes.inventory
+// @Tags 容器监控
+// @Accept json
+// @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群，all表示全部已启用集群"
+// @Param namespace query string false "命名空间过滤"
+// @Param kind query string false "关联对象类型，如Pod、Node、Deployment"
+// @Param name query string false "关联对象名称"
+// @Param type query string false "事件类型" Enums(Normal,Warning)
+// @Param limit query int false "返回数量" default(100)
+// @Success 200 {object} PaginatedResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/containers/kubernetes/events [get]
+func (h *ContainerHandler) GetKubernetesEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	events, err := h.containerService.GetKubernetesEvents(c.Query("cluster"), services.KubernetesEventFilter{
+		Namespace: c.Query("namespace"),
+		Kind:      c.Query("kind"),
+		Name:      c.Query("name"),
+		Type:      c.Query("type"),
+		Limit:     limit,
+	})
+	warnings, ok := clusterWarnings(err)
+	if !ok {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedResponse{
+		Data: events,
+		Pagination: PaginationInfo{
+			Page:     1,
+			PageSize: limit,
+			Total:    len(events),
+			Pages:    1,
+		},
+		Warnings: warnings,
+	})
+}
+
+// GetKubernetesInventoryStatus 获取Kubernetes资源缓存同步状态
+// @Summary 获取Kubernetes资源缓存状态
+// @Description 获取各集群list+watch资源缓存的同步状态、对象数量与最近错误
+// @Tags 容器监控
+// @Accept json
+// @Produce json
+// @Success 200 {object} []services.KubernetesInventoryStatus
+// @Router /api/v1/containers/kubernetes/inventory [get]
+func (h *ContainerHandler) GetKubernetesInventoryStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.containerService.GetKubernetesInventoryStatus())
+}
+
 // GetClusterMetrics 获取集群指标
 // @Summary 获取集群指标
 // @Description 获取Kubernetes集群的整体指标信息
 // @Tags 容器监控
 // @Accept json
 // @Produce json
+// @Param cluster query string false "集群名称或ID，为空时使用默认集群"
 // @Success 200 {object} services.ClusterMetrics
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/containers/kubernetes/cluster/metrics [get]
 func (h *ContainerHandler) GetClusterMetrics(c *gin.Context) {
-	metrics, err := h.containerService.GetKubernetesClusterMetrics()
+	metrics, err := h.containerService.GetKubernetesClusterMetrics(c.Query("cluster"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Internal Server Error",
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
 			Message: err.Error(),
 		})
 		return
@@ -366,6 +516,7 @@ func (h *ContainerHandler) GetClusterMetrics(c *gin.Context) {
 // @Produce json
 // @Param platform query string true "平台类型" Enums(docker,kubernetes)
 // @Param resource_type query string false "资源类型" Enums(cpu,memory,disk,network)
+// @Param cluster query string false "集群名称或ID(仅Kubernetes)，为空时使用默认集群"
 // @Param namespace query string false "命名空间(仅Kubernetes)"
 // @Param start_time query string false "开始时间" format(date-time)
 // @Param end_time query string false "结束时间" format(date-time)
@@ -399,10 +550,11 @@ func (h *ContainerHandler) GetResourceUsage(c *gin.Context) {
 		}
 	}
 
-	usage, err := h.containerService.GetResourceUsage(platform, resourceType, namespace, startTime, endTime)
+	usage, err := h.containerService.GetResourceUsage(platform, c.Query("cluster"), resourceType, namespace, startTime, endTime)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Internal Server Error",
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
 			Message: err.Error(),
 		})
 		return
@@ -440,6 +592,8 @@ func (h *ContainerHandler) CreateContainerMonitor(c *gin.Context) {
 		Name:        req.Name,
 		Image:       req.Image,
 		Platform:    req.Platform,
+		Namespace:   req.Namespace,
+		Cluster:     req.Cluster,
 		Status:      "running",
 		CreatedAt:   time.Now().Format(time.RFC3339),
 		UpdatedAt:   time.Now().Format(time.RFC3339),
@@ -522,6 +676,33 @@ func (h *ContainerHandler) DeleteContainerMonitor(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// clusterWarnings 区分跨集群查询的部分失败与整体失败
+// 部分失败时返回各集群的失败说明，ok为false表示应作为错误响应
+func clusterWarnings(err error) (warnings []string, ok bool) {
+	if err == nil {
+		return nil, true
+	}
+	var partial *services.PartialClusterError
+	if errors.As(err, &partial) {
+		return partial.Warnings(), true
+	}
+	return nil, false
+}
+
+// kubernetesErrorStatus 将集群查询错误映射为HTTP状态码
+func kubernetesErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrClusterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAllClustersUnsupported):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInventoryNotSynced):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // 请求和响应结构体
 
 // CreateContainerMonitorRequest 创建容器监控请求
@@ -531,6 +712,7 @@ type CreateContainerMonitorRequest struct {
 	Image       string            `json:"image" binding:"required" example:"nginx:1.21"`
 	Platform    string            `json:"platform" binding:"required,oneof=docker kubernetes" example:"docker"`
 	Namespace   string            `json:"namespace" example:"default"`
+	Cluster     string            `json:"cluster" example:"prod-east"`
 	Labels      map[string]string `json:"labels" example:"{\"app\":\"nginx\",\"env\":\"prod\"}"`
 	Annotations map[string]string `json:"annotations" example:"{\"description\":\"Web server\"}"`
 }
@@ -550,6 +732,7 @@ type ContainerMonitorResponse struct {
 	Image       string            `json:"image" example:"nginx:1.21"`
 	Platform    string            `json:"platform" example:"docker"`
 	Namespace   string            `json:"namespace,omitempty" example:"default"`
+	Cluster     string            `json:"cluster,omitempty" example:"prod-east"`
 	Status      string            `json:"status" example:"running"`
 	StartedAt   *string           `json:"started_at,omitempty" example:"2024-01-01T12:00:00Z"`
 	Labels      map[string]string `json:"labels,omitempty" example:"{\"app\":\"nginx\",\"env\":\"prod\"}"`
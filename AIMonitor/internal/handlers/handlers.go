@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"ai-monitor/internal/auth"
 	"ai-monitor/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +24,8 @@ type ErrorResponse struct {
 type PaginatedResponse struct {
 	Data       interface{}    `json:"data"`
 	Pagination PaginationInfo `json:"pagination"`
+	// Warnings 跨集群聚合查询时部分集群失败的说明
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // PaginationInfo 分页信息
@@ -47,6 +51,13 @@ type Handlers struct {
 	containerService  *services.ContainerService
 	agentService      *services.AgentService
 	apiKeyService     *services.APIKeyService
+	insightService    *services.InsightService
+	incidentService   *services.IncidentService
+	remediationService *services.RemediationService
+	assistantService  *services.AssistantService
+	sloService        *services.SLOService
+	containerEventService *services.ContainerEventService
+	kubernetesClusterService *services.KubernetesClusterService
 	// 新增处理器
 	middlewareHandler *MiddlewareHandler
 	apmHandler        *APMHandler
@@ -55,6 +66,7 @@ type Handlers struct {
 	configHandler     *ConfigHandler
 	apiKeyHandler     *APIKeyHandler
 	discoveryHandler  *DiscoveryHandler
+	traceIngestHandler *TraceIngestHandler
 	// 添加Services字段以便访问所有服务
 	Services          *services.Services
 }
@@ -69,6 +81,7 @@ func NewHandlers(services *services.Services) *Handlers {
 	configHandler := NewConfigHandler(services.ConfigService, services.AuditService)
 	apiKeyHandler := NewAPIKeyHandler(services.APIKeyService)
 	discoveryHandler := NewDiscoveryHandler(services.DiscoveryService)
+	traceIngestHandler := NewTraceIngestHandler(services.TraceIngestService, services.GetConfig().Monitoring.Tracing)
 
 	return &Handlers{
 		userService:         services.UserService,
@@ -84,6 +97,13 @@ func NewHandlers(services *services.Services) *Handlers {
 		containerService:  services.ContainerService,
 		agentService:      services.AgentService,
 		apiKeyService:     services.APIKeyService,
+		insightService:    services.InsightService,
+		incidentService:   services.IncidentService,
+		remediationService: services.RemediationService,
+		assistantService:  services.AssistantService,
+		sloService:        services.SLOService,
+		containerEventService: services.ContainerEvents,
+		kubernetesClusterService: services.KubernetesClusters,
 		// 新增处理器
 		middlewareHandler: middlewareHandler,
 		apmHandler:        apmHandler,
@@ -92,6 +112,7 @@ func NewHandlers(services *services.Services) *Handlers {
 		configHandler:     configHandler,
 		apiKeyHandler:     apiKeyHandler,
 		discoveryHandler:  discoveryHandler,
+		traceIngestHandler: traceIngestHandler,
 		// 添加Services字段
 		Services:          services,
 	}
@@ -1250,6 +1271,26 @@ func (h *Handlers) GetTraceDetail(c *gin.Context) {
 	h.apmHandler.GetTraceDetail(c)
 }
 
+// ExportOTLPTraces 接收OTLP/HTTP链路数据
+func (h *Handlers) ExportOTLPTraces(c *gin.Context) {
+	h.traceIngestHandler.ExportOTLPTraces(c)
+}
+
+// ExportZipkinSpans 接收Zipkin v2 JSON跨度
+func (h *Handlers) ExportZipkinSpans(c *gin.Context) {
+	h.traceIngestHandler.ExportZipkinSpans(c)
+}
+
+// ExportJaegerThrift 接收Jaeger Thrift over HTTP上报
+func (h *Handlers) ExportJaegerThrift(c *gin.Context) {
+	h.traceIngestHandler.ExportJaegerThrift(c)
+}
+
+// GetSamplingStats 获取尾部采样统计
+func (h *Handlers) GetSamplingStats(c *gin.Context) {
+	h.traceIngestHandler.GetSamplingStats(c)
+}
+
 // GetServices 获取服务列表
 func (h *Handlers) GetServices(c *gin.Context) {
 	h.apmHandler.GetServices(c)
@@ -1312,6 +1353,21 @@ func (h *Handlers) GetKubernetesNamespaces(c *gin.Context) {
 	h.containerHandler.GetKubernetesNamespaces(c)
 }
 
+// GetKubernetesWorkloads 获取Kubernetes工作负载列表
+func (h *Handlers) GetKubernetesWorkloads(c *gin.Context) {
+	h.containerHandler.GetKubernetesWorkloads(c)
+}
+
+// GetKubernetesEvents 获取Kubernetes事件列表
+func (h *Handlers) GetKubernetesEvents(c *gin.Context) {
+	h.containerHandler.GetKubernetesEvents(c)
+}
+
+// GetKubernetesInventoryStatus 获取Kubernetes资源缓存状态
+func (h *Handlers) GetKubernetesInventoryStatus(c *gin.Context) {
+	h.containerHandler.GetKubernetesInventoryStatus(c)
+}
+
 // GetClusterMetrics 获取集群指标
 func (h *Handlers) GetClusterMetrics(c *gin.Context) {
 	h.containerHandler.GetClusterMetrics(c)
@@ -2293,7 +2349,7 @@ func (h *Handlers) GetMetricValues(c *gin.Context) {
 
 // AnalyzeData 分析数据
 func (h *Handlers) AnalyzeData(c *gin.Context) {
-	var req map[string]interface{}
+	var req services.AIAnalysisRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "Invalid request body",
@@ -2301,29 +2357,38 @@ func (h *Handlers) AnalyzeData(c *gin.Context) {
 		})
 		return
 	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
 
-	// 模拟分析结果
-	analysis := map[string]interface{}{
-		"id":         uuid.New().String(),
-		"type":       req["type"],
-		"status":     "completed",
-		"result": map[string]interface{}{
-			"anomalies": []map[string]interface{}{
-				{
-					"timestamp": time.Now().Unix(),
-					"metric":    "cpu_usage",
-					"value":     95.5,
-					"severity":  "high",
-				},
-			},
-			"insights": []string{
-				"CPU使用率异常高",
-				"建议检查系统负载",
-			},
-		},
-		"created_at": time.Now(),
+	// 按分析类型分发
+	var analysis *services.AIAnalysisResponse
+	var err error
+	switch req.Type {
+	case "alert_analysis":
+		analysis, err = h.aiService.AnalyzeAlert(&req)
+	case "capacity_planning":
+		analysis, err = h.aiService.AnalyzeCapacity(&req)
+	default:
+		analysis, err = h.aiService.AnalyzePerformance(&req)
+	}
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "ai_analyze_data", "ai_analysis", "", "failure", err.Error(), map[string]interface{}{
+			"type":      req.Type,
+			"target_id": req.TargetID,
+		})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to analyze data",
+			Message: err.Error(),
+		})
+		return
 	}
 
+	h.auditService.LogAuditFromContext(c, "ai_analyze_data", "ai_analysis", analysis.ID.String(), "success", "", map[string]interface{}{
+		"type":      req.Type,
+		"target_id": req.TargetID,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"message": "数据分析成功",
@@ -2410,8 +2475,19 @@ func (h *Handlers) DeleteAnalysis(c *gin.Context) {
 }
 
 // PredictTrend 预测趋势
+// @Summary 预测指标趋势
+// @Description 基于历史指标数据使用Holt-Winters模型预测指标走势
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.ForecastRequest true "预测请求"
+// @Success 200 {object} services.ForecastResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/predict [post]
 func (h *Handlers) PredictTrend(c *gin.Context) {
-	var req map[string]interface{}
+	var req services.ForecastRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "Invalid request body",
@@ -2420,49 +2496,46 @@ func (h *Handlers) PredictTrend(c *gin.Context) {
 		return
 	}
 
-	// 模拟趋势预测
-	prediction := map[string]interface{}{
-		"metric":     req["metric"],
-		"timeframe": req["timeframe"],
-		"prediction": []map[string]interface{}{
-			{
-				"timestamp": time.Now().Add(1 * time.Hour).Unix(),
-				"value":     78.5,
-				"confidence": 0.85,
-			},
-			{
-				"timestamp": time.Now().Add(2 * time.Hour).Unix(),
-				"value":     82.1,
-				"confidence": 0.78,
-			},
-		},
+	forecast, err := h.aiService.ForecastMetric(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to predict trend",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"message": "趋势预测成功",
-		"data": prediction,
+		"data": forecast,
 	})
 }
 
 // GetInsights 获取洞察
+// @Summary 获取智能洞察
+// @Description 返回最新一批按评分排序的洞察，包括指标异常、噪声规则、抖动告警、错误率上升和容量风险
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "洞察类型" Enums(anomaly, noisy_rule, flapping_alert, error_rate, capacity)
+// @Param severity query string false "严重级别" Enums(critical, high, medium, low)
+// @Param limit query int false "返回数量" default(20)
+// @Success 200 {array} services.InsightResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/insights [get]
 func (h *Handlers) GetInsights(c *gin.Context) {
-	// 模拟洞察数据
-	insights := []map[string]interface{}{
-		{
-			"type":        "performance",
-			"title":       "CPU使用率异常",
-			"description": "过去1小时内CPU使用率持续超过90%",
-			"severity":    "high",
-			"timestamp":   time.Now().Add(-30 * time.Minute),
-		},
-		{
-			"type":        "capacity",
-			"title":       "内存使用趋势",
-			"description": "内存使用率呈上升趋势，预计2小时后达到阈值",
-			"severity":    "medium",
-			"timestamp":   time.Now().Add(-15 * time.Minute),
-		},
+	insightType := c.Query("type")
+	severity := c.Query("severity")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	insights, err := h.insightService.ListInsights(insightType, severity, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to get insights",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -2472,126 +2545,2160 @@ func (h *Handlers) GetInsights(c *gin.Context) {
 	})
 }
 
-// ===== 中间件管理相关处理器 =====
+// GetCapacityReport 获取容量预测报告
+// @Summary 获取容量预测报告
+// @Description 基于稳健回归预测磁盘、内存、连接池、Kafka积压等资源的耗尽时间，按耗尽时间排序
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param resource query string false "资源类型" Enums(disk, memory, connection_pool, kafka_lag)
+// @Param days query int false "历史天数(7-30)" default(14)
+// @Param threshold_percent query number false "容量上限百分比" default(100)
+// @Success 200 {object} services.CapacityReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/capacity [get]
+func (h *Handlers) GetCapacityReport(c *gin.Context) {
+	resource := c.Query("resource")
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "0"))
+	thresholdPercent, err := strconv.ParseFloat(c.DefaultQuery("threshold_percent", "100"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid threshold_percent",
+			Message: err.Error(),
+		})
+		return
+	}
 
-// GetMiddlewareList 获取中间件列表
-func (h *Handlers) GetMiddlewareList(c *gin.Context) {
-	// 模拟中间件数据
-	middlewares := []map[string]interface{}{
-		{
-			"name":        "auth",
-			"description": "身份验证中间件",
-			"enabled":     true,
-			"order":       1,
-		},
-		{
-			"name":        "cors",
-			"description": "跨域请求中间件",
-			"enabled":     true,
-			"order":       2,
-		},
-		{
-			"name":        "rate_limit",
-			"description": "限流中间件",
-			"enabled":     false,
-			"order":       3,
-		},
+	report, err := h.aiService.GetCapacityReport(resource, days, thresholdPercent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to get capacity report",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"message": "获取中间件列表成功",
-		"data": middlewares,
+		"message": "获取容量预测报告成功",
+		"data": report,
 	})
 }
 
-// ===== APM相关处理器 =====
+// SubmitAnalysisFeedback 评价AI分析
+// @Summary 评价AI分析
+// @Description 对分析结果点赞或点踩，并可填写修正后的根因。好评或修正的根因会作为故障记忆，用于相似告警的后续分析
+// @Tags AI分析
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "分析ID"
+// @Param request body services.AnalysisFeedbackRequest true "反馈内容"
+// @Success 200 {object} services.AnalysisFeedbackResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /ai/analyses/{id}/feedback [post]
+func (h *Handlers) SubmitAnalysisFeedback(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	analysisID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
 
-// GetOperations 获取操作列表
-func (h *Handlers) GetOperations(c *gin.Context) {
-	service := c.Query("service")
-	startTime := c.Query("start")
-	endTime := c.Query("end")
+	var req services.AnalysisFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
 
-	_ = service   // 避免未使用变量错误
-	_ = startTime // 避免未使用变量错误
-	_ = endTime   // 避免未使用变量错误
+	feedback, err := h.aiService.SubmitAnalysisFeedback(analysisID, userID, &req)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "feedback_analysis", "ai_analysis", analysisID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Failed to submit feedback",
+			Message: err.Error(),
+		})
+		return
+	}
 
-	// 模拟操作数据
-	operations := []map[string]interface{}{
-		{
-			"name":         "GET /api/users",
-			"service":      "user-service",
-			"avg_duration": 45.2,
-			"request_count": 1250,
-			"error_rate":   0.02,
-		},
-		{
-			"name":         "POST /api/orders",
-			"service":      "order-service",
-			"avg_duration": 128.7,
-			"request_count": 856,
-			"error_rate":   0.01,
-		},
+	h.auditService.LogAuditFromContext(c, "feedback_analysis", "ai_analysis", analysisID.String(), "success", "", map[string]interface{}{
+		"rating":    feedback.Rating,
+		"corrected": feedback.CorrectedRootCause != "",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "提交分析反馈成功",
+		"data": feedback,
+	})
+}
+
+// GetAnalysisFeedback 获取AI分析的反馈
+// @Summary 获取AI分析的反馈
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "分析ID"
+// @Success 200 {array} services.AnalysisFeedbackResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/analyses/{id}/feedback [get]
+func (h *Handlers) GetAnalysisFeedback(c *gin.Context) {
+	analysisID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	feedback, err := h.aiService.ListAnalysisFeedback(analysisID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"message": "获取操作列表成功",
-		"data": operations,
+		"message": "获取分析反馈成功",
+		"data": feedback,
 	})
 }
 
-// GetServiceMap 获取服务地图
-func (h *Handlers) GetServiceMap(c *gin.Context) {
-	// 模拟服务地图数据
-	serviceMap := map[string]interface{}{
-		"nodes": []map[string]interface{}{
-			{
-				"id":       "user-service",
-				"name":     "用户服务",
-				"type":     "service",
-				"status":   "healthy",
-				"requests": 1250,
-			},
-			{
-				"id":       "order-service",
-				"name":     "订单服务",
-				"type":     "service",
-				"status":   "healthy",
-				"requests": 856,
-			},
-			{
-				"id":       "database",
-				"name":     "数据库",
-				"type":     "database",
-				"status":   "healthy",
-				"requests": 2106,
-			},
-		},
-		"edges": []map[string]interface{}{
-			{
-				"source":   "user-service",
-				"target":   "database",
-				"requests": 1250,
-				"latency":  25.3,
-			},
-			{
-				"source":   "order-service",
-				"target":   "database",
-				"requests": 856,
-				"latency":  32.1,
-			},
-		},
+// GetAnalysisAccuracy 获取AI分析准确率报告
+// @Summary 获取AI分析准确率报告
+// @Description 按模型、提示词版本和分析类型统计用户反馈的准确率，用于比较提示词调整前后的效果
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "统计天数" default(30)
+// @Param analysis_type query string false "分析类型"
+// @Success 200 {object} services.AnalysisAccuracyReport
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/analyses/accuracy [get]
+func (h *Handlers) GetAnalysisAccuracy(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 {
+		days = 30
+	}
+
+	report, err := h.aiService.GetAnalysisAccuracy(time.Now().AddDate(0, 0, -days), c.Query("analysis_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"message": "获取服务地图成功",
-		"data": serviceMap,
+		"message": "获取分析准确率成功",
+		"data": report,
 	})
 }
 
+// GetPromptTemplates 获取提示词模板列表
+// @Summary 获取提示词模板列表
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param analysis_type query string false "分析类型"
+// @Param language query string false "语言"
+// @Param status query string false "状态"
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/prompt-templates [get]
+func (h *Handlers) GetPromptTemplates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	templates, total, err := h.aiService.ListPromptTemplates(page, pageSize, c.Query("analysis_type"), c.Query("language"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: templates,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreatePromptTemplate 创建提示词模板
+// @Summary 创建提示词模板
+// @Description 创建新的提示词版本（草稿），模板使用Go text/template语法，创建时会试渲染校验
+// @Tags AI分析
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreatePromptTemplateRequest true "模板信息"
+// @Success 201 {object} services.PromptTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /ai/prompt-templates [post]
+func (h *Handlers) CreatePromptTemplate(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.CreatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	tpl, err := h.aiService.CreatePromptTemplate(&req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "create_prompt_template", "prompt_template", "", "failure", err.Error(), map[string]interface{}{
+			"analysis_type": req.AnalysisType,
+			"version":       req.Version,
+		})
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create prompt template",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_prompt_template", "prompt_template", tpl.ID.String(), "success", "", map[string]interface{}{
+		"analysis_type": tpl.AnalysisType,
+		"language":      tpl.Language,
+		"version":       tpl.Version,
+	})
+
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// GetPromptTemplate 获取提示词模板详情
+// @Summary 获取提示词模板详情
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "模板ID"
+// @Success 200 {object} services.PromptTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /ai/prompt-templates/{id} [get]
+func (h *Handlers) GetPromptTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	tpl, err := h.aiService.GetPromptTemplate(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Prompt template not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// UpdatePromptTemplate 更新提示词模板
+// @Summary 更新提示词模板
+// @Description 草稿可修改模板内容；激活后只能调整流量权重或归档，内容变更需创建新版本
+// @Tags AI分析
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "模板ID"
+// @Param request body services.UpdatePromptTemplateRequest true "更新内容"
+// @Success 200 {object} services.PromptTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /ai/prompt-templates/{id} [put]
+func (h *Handlers) UpdatePromptTemplate(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	tpl, err := h.aiService.UpdatePromptTemplate(id, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_prompt_template", "prompt_template", id.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to update prompt template",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_prompt_template", "prompt_template", id.String(), "success", "", map[string]interface{}{
+		"status":         tpl.Status,
+		"traffic_weight": tpl.TrafficWeight,
+	})
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// GetPromptEvalSets 获取提示词评估集列表
+// @Summary 获取提示词评估集列表
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.PromptEvalSetResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/prompt-eval-sets [get]
+func (h *Handlers) GetPromptEvalSets(c *gin.Context) {
+	sets, err := h.aiService.ListPromptEvalSets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "获取评估集成功",
+		"data": sets,
+	})
+}
+
+// CreatePromptEvalSet 创建提示词评估集
+// @Summary 创建提示词评估集
+// @Description 保存一组历史告警，供 prompt-eval 命令离线回放评估提示词版本
+// @Tags AI分析
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreatePromptEvalSetRequest true "评估集信息"
+// @Success 201 {object} services.PromptEvalSetResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /ai/prompt-eval-sets [post]
+func (h *Handlers) CreatePromptEvalSet(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.CreatePromptEvalSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	set, err := h.aiService.CreatePromptEvalSet(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create prompt eval set",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_prompt_eval_set", "prompt_template", set.ID.String(), "success", "", map[string]interface{}{
+		"name":   set.Name,
+		"alerts": len(set.AlertIDs),
+	})
+
+	c.JSON(http.StatusCreated, set)
+}
+
+// AssistantChat 运维助手对话
+// @Summary 运维助手对话
+// @Description 以SSE流式返回对话过程：conversation、tool_call、tool_result、answer，失败时返回error事件。工具只读且受调用者权限限制
+// @Tags AI分析
+// @Accept json
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param request body services.AssistantChatRequest true "对话内容"
+// @Success 200 {object} services.AssistantAnswer
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /ai/assistant/chat [post]
+func (h *Handlers) AssistantChat(c *gin.Context) {
+	claims, ok := c.MustGet("user_claims").(*auth.UserClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid user claims",
+		})
+		return
+	}
+
+	var req services.AssistantChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	emit := func(event string, data interface{}) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	answer, err := h.assistantService.Chat(c.Request.Context(), claims, &req, emit)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "assistant_chat", "ai", "", "failure", err.Error(), nil)
+		emit("error", ErrorResponse{
+			Error:   "Assistant failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "assistant_chat", "ai", answer.ConversationID.String(), "success", "", map[string]interface{}{
+		"steps":  answer.Steps,
+		"tokens": answer.Tokens,
+	})
+}
+
+// GetAssistantConversations 获取运维助手会话列表
+// @Summary 获取运维助手会话列表
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ai/assistant/conversations [get]
+func (h *Handlers) GetAssistantConversations(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	conversations, total, err := h.assistantService.ListConversations(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: conversations,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAssistantConversation 获取运维助手会话详情
+// @Summary 获取运维助手会话详情
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} services.AssistantConversationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /ai/assistant/conversations/{id} [get]
+func (h *Handlers) GetAssistantConversation(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	conversation, err := h.assistantService.GetConversation(userID, conversationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// DeleteAssistantConversation 删除运维助手会话
+// @Summary 删除运维助手会话
+// @Tags AI分析
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /ai/assistant/conversations/{id} [delete]
+func (h *Handlers) DeleteAssistantConversation(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	if err := h.assistantService.DeleteConversation(userID, conversationID); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "delete_conversation", "ai", conversationID.String(), "success", "", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "删除会话成功",
+	})
+}
+
+// ===== 故障事件相关处理器 =====
+
+// GetIncidents 获取故障事件列表
+// @Summary 获取故障事件列表
+// @Description 获取由关联告警归并而成的故障事件
+// @Tags 故障事件
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param status query string false "状态" Enums(investigating, identified, monitoring, resolved)
+// @Param severity query string false "严重级别"
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents [get]
+func (h *Handlers) GetIncidents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	status := c.Query("status")
+	severity := c.Query("severity")
+
+	incidents, total, err := h.incidentService.ListIncidents(page, pageSize, status, severity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: incidents,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetIncident 获取故障事件详情
+// @Summary 获取故障事件详情
+// @Description 获取故障事件的成员告警以及告警、指标异常、部署和配置变更的关联时间线
+// @Tags 故障事件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Success 200 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /incidents/{id} [get]
+func (h *Handlers) GetIncident(c *gin.Context) {
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	incident, err := h.incidentService.GetIncident(incidentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// AnalyzeIncident 对故障事件进行根因分析
+// @Summary 故障事件根因分析
+// @Description 将事件的关联时间线发送给AI模型，生成一次整体根因分析
+// @Tags 故障事件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Success 200 {object} services.AIAnalysisResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents/{id}/analyze [post]
+func (h *Handlers) AnalyzeIncident(c *gin.Context) {
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	analysis, err := h.incidentService.AnalyzeIncident(incidentID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "ai_analyze_incident", "incident", incidentID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to analyze incident",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "ai_analyze_incident", "incident", incidentID.String(), "success", "", map[string]interface{}{
+		"analysis_id": analysis.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "事件根因分析成功",
+		"data": analysis,
+	})
+}
+
+// CreateIncident 创建故障事件
+// @Summary 创建故障事件
+// @Description 手动创建故障事件，可指定负责人、相关人员通知渠道和关联告警
+// @Tags 故障事件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateIncidentRequest true "事件信息"
+// @Success 201 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents [post]
+func (h *Handlers) CreateIncident(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.CreateIncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	incident, err := h.incidentService.CreateIncident(&req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "create_incident", "incident", "", "failure", err.Error(), map[string]interface{}{
+			"title": req.Title,
+		})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_incident", "incident", incident.ID.String(), "success", "", map[string]interface{}{
+		"title": incident.Title,
+	})
+
+	c.JSON(http.StatusCreated, incident)
+}
+
+// UpdateIncident 更新故障事件
+// @Summary 更新故障事件
+// @Description 更新事件标题、描述、严重级别、负责人和相关人员
+// @Tags 故障事件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Param request body services.UpdateIncidentRequest true "更新内容"
+// @Success 200 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents/{id} [put]
+func (h *Handlers) UpdateIncident(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.UpdateIncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	incident, err := h.incidentService.UpdateIncident(incidentID, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_incident", "incident", incidentID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_incident", "incident", incidentID.String(), "success", "", nil)
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// UpdateIncidentStatus 变更故障事件状态
+// @Summary 变更故障事件状态
+// @Description 变更事件状态（investigating/identified/monitoring/resolved）并通知相关人员，解决时自动生成复盘草稿
+// @Tags 故障事件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Param request body services.UpdateIncidentStatusRequest true "状态信息"
+// @Success 200 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /incidents/{id}/status [put]
+func (h *Handlers) UpdateIncidentStatus(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.UpdateIncidentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	incident, err := h.incidentService.UpdateIncidentStatus(incidentID, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_incident_status", "incident", incidentID.String(), "failure", err.Error(), map[string]interface{}{
+			"status": req.Status,
+		})
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to update incident status",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_incident_status", "incident", incidentID.String(), "success", "", map[string]interface{}{
+		"status": req.Status,
+	})
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// AddIncidentNote 添加故障事件备注
+// @Summary 添加故障事件备注
+// @Description 在事件时间线中添加一条备注
+// @Tags 故障事件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Param request body services.IncidentNoteRequest true "备注内容"
+// @Success 200 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents/{id}/notes [post]
+func (h *Handlers) AddIncidentNote(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.IncidentNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	incident, err := h.incidentService.AddIncidentNote(incidentID, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// LinkIncidentAlerts 关联告警到故障事件
+// @Summary 关联告警到故障事件
+// @Description 手动将告警关联到事件，已属于其他事件的告警会被移入
+// @Tags 故障事件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Param request body services.LinkIncidentAlertsRequest true "告警ID列表"
+// @Success 200 {object} services.IncidentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents/{id}/alerts [post]
+func (h *Handlers) LinkIncidentAlerts(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.LinkIncidentAlertsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.incidentService.LinkAlerts(incidentID, req.AlertIDs, userID); err != nil {
+		h.auditService.LogAuditFromContext(c, "link_incident_alerts", "incident", incidentID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "link_incident_alerts", "incident", incidentID.String(), "success", "", map[string]interface{}{
+		"alert_ids": req.AlertIDs,
+	})
+
+	incident, err := h.incidentService.GetIncident(incidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// GenerateIncidentPostmortem 生成故障复盘草稿
+// @Summary 生成故障复盘草稿
+// @Description 根据事件时间线、关联AI分析结果和指标生成复盘，保存为可编辑的知识库草稿
+// @Tags 故障事件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "事件ID"
+// @Success 200 {object} services.KnowledgeBaseResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /incidents/{id}/postmortem [post]
+func (h *Handlers) GenerateIncidentPostmortem(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	incidentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	postmortem, err := h.incidentService.GeneratePostmortem(incidentID, &userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "generate_postmortem", "incident", incidentID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to generate postmortem",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "generate_postmortem", "incident", incidentID.String(), "success", "", map[string]interface{}{
+		"knowledge_base_id": postmortem.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "复盘草稿生成成功",
+		"data": postmortem,
+	})
+}
+
+// ===== SLO相关处理器 =====
+
+// GetSLOs 获取SLO列表
+// @Summary 获取SLO列表
+// @Description 分页获取SLO定义，可按服务过滤
+// @Tags SLO
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param service query string false "服务名称"
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /slos [get]
+func (h *Handlers) GetSLOs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	slos, total, err := h.sloService.ListSLOs(page, pageSize, c.Query("service"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: slos,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSLO 创建SLO
+// @Summary 创建SLO
+// @Description 创建SLO并按配置的多窗口燃烧率组合自动生成告警规则
+// @Tags SLO
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateSLORequest true "SLO定义"
+// @Success 201 {object} services.SLOResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /slos [post]
+func (h *Handlers) CreateSLO(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.CreateSLORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	slo, err := h.sloService.CreateSLO(&req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "create_slo", "slo", "", "failure", err.Error(), map[string]interface{}{
+			"name": req.Name,
+		})
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create slo",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_slo", "slo", slo.ID.String(), "success", "", map[string]interface{}{
+		"name":   slo.Name,
+		"target": slo.Target,
+	})
+
+	c.JSON(http.StatusCreated, slo)
+}
+
+// GetSLOReport 获取SLO报表
+// @Summary 获取SLO报表
+// @Description 按剩余错误预算从少到多列出已启用的SLO及其触发中的燃烧率告警数
+// @Tags SLO
+// @Produce json
+// @Security BearerAuth
+// @Param service query string false "服务名称"
+// @Success 200 {object} []services.SLOReportEntry
+// @Failure 500 {object} ErrorResponse
+// @Router /slos/report [get]
+func (h *Handlers) GetSLOReport(c *gin.Context) {
+	report, err := h.sloService.GetSLOReport(c.Query("service"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSLO 获取SLO详情
+// @Summary 获取SLO详情
+// @Description 获取SLO定义及实时计算的SLI、剩余错误预算和各燃烧率规则的当前燃烧率
+// @Tags SLO
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "SLO ID"
+// @Success 200 {object} services.SLOResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /slos/{id} [get]
+func (h *Handlers) GetSLO(c *gin.Context) {
+	sloID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	slo, err := h.sloService.GetSLO(c.Request.Context(), sloID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "slo not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, slo)
+}
+
+// UpdateSLO 更新SLO
+// @Summary 更新SLO
+// @Tags SLO
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "SLO ID"
+// @Param request body services.UpdateSLORequest true "更新内容"
+// @Success 200 {object} services.SLOResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /slos/{id} [put]
+func (h *Handlers) UpdateSLO(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	sloID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.UpdateSLORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	slo, err := h.sloService.UpdateSLO(sloID, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_slo", "slo", sloID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to update slo",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_slo", "slo", sloID.String(), "success", "", map[string]interface{}{
+		"target":  slo.Target,
+		"enabled": slo.Enabled,
+	})
+
+	c.JSON(http.StatusOK, slo)
+}
+
+// DeleteSLO 删除SLO
+// @Summary 删除SLO
+// @Description 删除SLO及其燃烧率告警规则和错误预算历史
+// @Tags SLO
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "SLO ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /slos/{id} [delete]
+func (h *Handlers) DeleteSLO(c *gin.Context) {
+	sloID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	if err := h.sloService.DeleteSLO(sloID); err != nil {
+		h.auditService.LogAuditFromContext(c, "delete_slo", "slo", sloID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "delete_slo", "slo", sloID.String(), "success", "", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除SLO成功",
+	})
+}
+
+// GetSLOHistory 获取SLO错误预算历史
+// @Summary 获取SLO错误预算历史
+// @Description 获取按history_interval记录的SLI和剩余错误预算，用于绘制预算消耗曲线
+// @Tags SLO
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "SLO ID"
+// @Param start_time query string false "开始时间，默认30天前" format(date-time)
+// @Param end_time query string false "结束时间，默认当前时间" format(date-time)
+// @Success 200 {object} []services.SLOHistoryPoint
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /slos/{id}/history [get]
+func (h *Handlers) GetSLOHistory(c *gin.Context) {
+	sloID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	endTime := time.Now()
+	startTime := endTime.Add(-30 * 24 * time.Hour)
+	if startStr := c.Query("start_time"); startStr != "" {
+		if startTime, err = time.Parse(time.RFC3339, startStr); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid start time",
+			})
+			return
+		}
+	}
+	if endStr := c.Query("end_time"); endStr != "" {
+		if endTime, err = time.Parse(time.RFC3339, endStr); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid end time",
+			})
+			return
+		}
+	}
+
+	history, err := h.sloService.GetSLOHistory(sloID, startTime, endTime)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "slo not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// ===== 容器事件相关处理器 =====
+
+// GetContainerEvents 获取容器生命周期事件
+// @Summary 获取容器事件
+// @Description 分页获取Docker事件流中记录的容器start/die/oom/restart/health_status事件
+// @Tags 容器监控
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param container_id query string false "容器ID"
+// @Param action query string false "事件类型" Enums(start,die,oom,restart,health_status)
+// @Param start_time query string false "开始时间" format(date-time)
+// @Param end_time query string false "结束时间" format(date-time)
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /containers/events [get]
+func (h *Handlers) GetContainerEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var startTime, endTime *time.Time
+	if startStr := c.Query("start_time"); startStr != "" {
+		t, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid start time",
+			})
+			return
+		}
+		startTime = &t
+	}
+	if endStr := c.Query("end_time"); endStr != "" {
+		t, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid end time",
+			})
+			return
+		}
+		endTime = &t
+	}
+
+	events, total, err := h.containerEventService.ListContainerEvents(page, pageSize, c.Query("container_id"), c.Query("action"), startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: events,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ===== Kubernetes集群相关处理器 =====
+
+// GetKubernetesClusters 获取Kubernetes集群列表
+// @Summary 获取Kubernetes集群列表
+// @Description 获取已注册的Kubernetes集群及最近一次健康检查结果，凭据不会返回
+// @Tags 容器监控
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.KubernetesCluster
+// @Failure 500 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters [get]
+func (h *Handlers) GetKubernetesClusters(c *gin.Context) {
+	clusters, err := h.kubernetesClusterService.ListClusters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, clusters)
+}
+
+// CreateKubernetesCluster 注册Kubernetes集群
+// @Summary 注册Kubernetes集群
+// @Description 注册集群，凭据加密存储，注册后立即执行一次健康检查
+// @Tags 容器监控
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.KubernetesClusterRequest true "集群定义"
+// @Success 201 {object} models.KubernetesCluster
+// @Failure 400 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters [post]
+func (h *Handlers) CreateKubernetesCluster(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.KubernetesClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	cluster, err := h.kubernetesClusterService.CreateCluster(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "create_kubernetes_cluster", "kubernetes_cluster", "", "failure", err.Error(), map[string]interface{}{
+			"name": req.Name,
+		})
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create kubernetes cluster",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_kubernetes_cluster", "kubernetes_cluster", cluster.ID.String(), "success", "", map[string]interface{}{
+		"name":      cluster.Name,
+		"auth_type": cluster.AuthType,
+		"server":    cluster.Server,
+	})
+
+	c.JSON(http.StatusCreated, cluster)
+}
+
+// GetKubernetesCluster 获取Kubernetes集群详情
+// @Summary 获取Kubernetes集群详情
+// @Tags 容器监控
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "集群ID或名称"
+// @Success 200 {object} models.KubernetesCluster
+// @Failure 404 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters/{id} [get]
+func (h *Handlers) GetKubernetesCluster(c *gin.Context) {
+	cluster, err := h.kubernetesClusterService.GetCluster(c.Param("id"))
+	if err != nil {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cluster)
+}
+
+// UpdateKubernetesCluster 更新Kubernetes集群
+// @Summary 更新Kubernetes集群
+// @Description 更新集群配置，未提供凭据字段时保留原凭据
+// @Tags 容器监控
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "集群ID或名称"
+// @Param request body services.KubernetesClusterRequest true "更新内容"
+// @Success 200 {object} models.KubernetesCluster
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters/{id} [put]
+func (h *Handlers) UpdateKubernetesCluster(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	key := c.Param("id")
+
+	var req services.KubernetesClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	cluster, err := h.kubernetesClusterService.UpdateCluster(c.Request.Context(), key, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_kubernetes_cluster", "kubernetes_cluster", key, "failure", err.Error(), nil)
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrClusterNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   "Failed to update kubernetes cluster",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_kubernetes_cluster", "kubernetes_cluster", cluster.ID.String(), "success", "", map[string]interface{}{
+		"name":                cluster.Name,
+		"enabled":             cluster.Enabled,
+		"credentials_updated": req.Kubeconfig != "" || req.Token != "" || req.ClientCert != "" || req.ClientKey != "" || req.CAData != "",
+	})
+
+	c.JSON(http.StatusOK, cluster)
+}
+
+// DeleteKubernetesCluster 删除Kubernetes集群
+// @Summary 删除Kubernetes集群
+// @Description 删除集群注册信息及加密凭据
+// @Tags 容器监控
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "集群ID或名称"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters/{id} [delete]
+func (h *Handlers) DeleteKubernetesCluster(c *gin.Context) {
+	key := c.Param("id")
+
+	if err := h.kubernetesClusterService.DeleteCluster(key); err != nil {
+		h.auditService.LogAuditFromContext(c, "delete_kubernetes_cluster", "kubernetes_cluster", key, "failure", err.Error(), nil)
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "delete_kubernetes_cluster", "kubernetes_cluster", key, "success", "", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除集群成功",
+	})
+}
+
+// CheckKubernetesCluster 检查Kubernetes集群健康状态
+// @Summary 检查Kubernetes集群健康状态
+// @Description 立即检查API Server就绪状态、版本和节点数并更新集群记录
+// @Tags 容器监控
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "集群ID或名称"
+// @Success 200 {object} models.KubernetesCluster
+// @Failure 404 {object} ErrorResponse
+// @Router /containers/kubernetes/clusters/{id}/check [post]
+func (h *Handlers) CheckKubernetesCluster(c *gin.Context) {
+	cluster, err := h.kubernetesClusterService.CheckCluster(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		status := kubernetesErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cluster)
+}
+
+// ===== 自动修复相关处理器 =====
+
+// GetRemediationPlaybooks 获取修复剧本列表
+// @Summary 获取修复剧本列表
+// @Description 分页获取自动修复剧本，可按告警规则过滤
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param rule_id query string false "告警规则ID"
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /remediation/playbooks [get]
+func (h *Handlers) GetRemediationPlaybooks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	ruleID := c.Query("rule_id")
+
+	playbooks, total, err := h.remediationService.ListPlaybooks(page, pageSize, ruleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: playbooks,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateRemediationPlaybook 创建修复剧本
+// @Summary 创建修复剧本
+// @Description 创建绑定到告警规则的修复剧本，新剧本默认以演练模式运行
+// @Tags 自动修复
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreatePlaybookRequest true "剧本信息"
+// @Success 201 {object} services.PlaybookResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /remediation/playbooks [post]
+func (h *Handlers) CreateRemediationPlaybook(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req services.CreatePlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	playbook, err := h.remediationService.CreatePlaybook(&req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "create_playbook", "remediation", "", "failure", err.Error(), map[string]interface{}{
+			"name": req.Name,
+		})
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create playbook",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "create_playbook", "remediation", playbook.ID.String(), "success", "", map[string]interface{}{
+		"name":    playbook.Name,
+		"rule_id": playbook.RuleID,
+		"dry_run": playbook.DryRun,
+	})
+
+	c.JSON(http.StatusCreated, playbook)
+}
+
+// GetRemediationPlaybook 获取修复剧本详情
+// @Summary 获取修复剧本详情
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "剧本ID"
+// @Success 200 {object} services.PlaybookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /remediation/playbooks/{id} [get]
+func (h *Handlers) GetRemediationPlaybook(c *gin.Context) {
+	playbookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	playbook, err := h.remediationService.GetPlaybook(playbookID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, playbook)
+}
+
+// UpdateRemediationPlaybook 更新修复剧本
+// @Summary 更新修复剧本
+// @Tags 自动修复
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "剧本ID"
+// @Param request body services.UpdatePlaybookRequest true "更新内容"
+// @Success 200 {object} services.PlaybookResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /remediation/playbooks/{id} [put]
+func (h *Handlers) UpdateRemediationPlaybook(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	playbookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.UpdatePlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	playbook, err := h.remediationService.UpdatePlaybook(playbookID, &req, userID)
+	if err != nil {
+		h.auditService.LogAuditFromContext(c, "update_playbook", "remediation", playbookID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to update playbook",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "update_playbook", "remediation", playbookID.String(), "success", "", map[string]interface{}{
+		"enabled": playbook.Enabled,
+		"dry_run": playbook.DryRun,
+	})
+
+	c.JSON(http.StatusOK, playbook)
+}
+
+// DeleteRemediationPlaybook 删除修复剧本
+// @Summary 删除修复剧本
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "剧本ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /remediation/playbooks/{id} [delete]
+func (h *Handlers) DeleteRemediationPlaybook(c *gin.Context) {
+	playbookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	if err := h.remediationService.DeletePlaybook(playbookID); err != nil {
+		h.auditService.LogAuditFromContext(c, "delete_playbook", "remediation", playbookID.String(), "failure", err.Error(), nil)
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogAuditFromContext(c, "delete_playbook", "remediation", playbookID.String(), "success", "", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "删除修复剧本成功",
+	})
+}
+
+// ExecuteRemediationPlaybook 手动执行修复剧本
+// @Summary 手动执行修复剧本
+// @Description 针对告警或目标手动执行剧本，dry_run为true时只渲染步骤不产生副作用
+// @Tags 自动修复
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "剧本ID"
+// @Param request body services.ExecutePlaybookRequest true "执行参数"
+// @Success 200 {object} services.RemediationExecutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /remediation/playbooks/{id}/execute [post]
+func (h *Handlers) ExecuteRemediationPlaybook(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	playbookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req services.ExecutePlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	execution, err := h.remediationService.ExecutePlaybook(playbookID, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to execute playbook",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "修复剧本已开始执行",
+		"data": execution,
+	})
+}
+
+// GetRemediationExecutions 获取修复执行记录列表
+// @Summary 获取修复执行记录列表
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param playbook_id query string false "剧本ID"
+// @Param alert_id query string false "告警ID"
+// @Param status query string false "状态" Enums(running, pending_approval, succeeded, failed, rejected, rate_limited, expired)
+// @Success 200 {object} PaginatedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /remediation/executions [get]
+func (h *Handlers) GetRemediationExecutions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	executions, total, err := h.remediationService.ListExecutions(page, pageSize, c.Query("playbook_id"), c.Query("alert_id"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := PaginatedResponse{
+		Data: executions,
+		Pagination: PaginationInfo{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    int(total),
+			Pages:    (int(total) + pageSize - 1) / pageSize,
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRemediationExecution 获取修复执行记录详情
+// @Summary 获取修复执行记录详情
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "执行ID"
+// @Success 200 {object} services.RemediationExecutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /remediation/executions/{id} [get]
+func (h *Handlers) GetRemediationExecution(c *gin.Context) {
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	execution, err := h.remediationService.GetExecution(executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Not Found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
+
+// ApproveRemediationExecution 审批修复执行
+// @Summary 审批修复执行
+// @Description 批准等待审批的高风险步骤，执行从挂起的步骤继续
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "执行ID"
+// @Success 200 {object} services.RemediationExecutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /remediation/executions/{id}/approve [post]
+func (h *Handlers) ApproveRemediationExecution(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	execution, err := h.remediationService.ApproveExecution(executionID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to approve execution",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "审批修复执行成功",
+		"data": execution,
+	})
+}
+
+// RejectRemediationExecution 拒绝修复执行
+// @Summary 拒绝修复执行
+// @Tags 自动修复
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "执行ID"
+// @Success 200 {object} services.RemediationExecutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /remediation/executions/{id}/reject [post]
+func (h *Handlers) RejectRemediationExecution(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	execution, err := h.remediationService.RejectExecution(executionID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to reject execution",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "拒绝修复执行成功",
+		"data": execution,
+	})
+}
+
+// GetAgentCommands Agent拉取待执行命令
+// @Summary Agent拉取待执行命令
+// @Description Agent轮询获取下发的命令，返回的命令会被标记为已下发
+// @Tags Agent管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Agent ID"
+// @Success 200 {array} services.AgentCommandResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /agents/{id}/commands [get]
+func (h *Handlers) GetAgentCommands(c *gin.Context) {
+	agentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+
+	if !h.authorizeAgentCaller(c, agentID) {
+		return
+	}
+
+	commands, err := h.agentService.FetchPendingCommands(agentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "获取Agent命令成功",
+		"data": commands,
+	})
+}
+
+// ReportAgentCommandResult Agent上报命令执行结果
+// @Summary Agent上报命令执行结果
+// @Tags Agent管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Agent ID"
+// @Param command_id path string true "命令ID"
+// @Param request body services.AgentCommandResultRequest true "执行结果"
+// @Success 200 {object} services.AgentCommandResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /agents/{id}/commands/{command_id}/result [post]
+func (h *Handlers) ReportAgentCommandResult(c *gin.Context) {
+	agentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "ID must be a valid UUID",
+		})
+		return
+	}
+	commandID, err := uuid.Parse(c.Param("command_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid ID format",
+			Message: "Command ID must be a valid UUID",
+		})
+		return
+	}
+
+	if !h.authorizeAgentCaller(c, agentID) {
+		return
+	}
+
+	var req services.AgentCommandResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	command, err := h.agentService.ReportCommandResult(agentID, commandID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to report command result",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "上报命令结果成功",
+		"data": command,
+	})
+}
+
+// authorizeAgentCaller 校验调用方是否可访问指定Agent的命令通道：
+// API Key必须是注册该Agent时使用的Key，JWT用户必须具有admin角色
+func (h *Handlers) authorizeAgentCaller(c *gin.Context, agentID uuid.UUID) bool {
+	authType, _ := c.Get("auth_type")
+	switch authType {
+	case "api_key":
+		apiKeyID, _ := c.Get("api_key_id")
+		id, ok := apiKeyID.(uuid.UUID)
+		if !ok {
+			break
+		}
+		err := h.agentService.AuthorizeAgentAPIKey(agentID, id)
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, services.ErrAgentCredentialMismatch) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "Not Found",
+				Message: err.Error(),
+			})
+			return false
+		}
+	case "jwt":
+		if claims, ok := c.Get("user_claims"); ok {
+			if userClaims, ok := claims.(*auth.UserClaims); ok && userClaims.IsAdmin() {
+				return true
+			}
+		}
+	}
+
+	c.JSON(http.StatusForbidden, ErrorResponse{
+		Error:   "Forbidden",
+		Message: "caller is not authorized for this agent",
+	})
+	return false
+}
+
+// ===== 中间件管理相关处理器 =====
+
+// GetMiddlewareList 获取中间件列表
+func (h *Handlers) GetMiddlewareList(c *gin.Context) {
+	// 模拟中间件数据
+	middlewares := []map[string]interface{}{
+		{
+			"name":        "auth",
+			"description": "身份验证中间件",
+			"enabled":     true,
+			"order":       1,
+		},
+		{
+			"name":        "cors",
+			"description": "跨域请求中间件",
+			"enabled":     true,
+			"order":       2,
+		},
+		{
+			"name":        "rate_limit",
+			"description": "限流中间件",
+			"enabled":     false,
+			"order":       3,
+		},
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "获取中间件列表成功",
+		"data": middlewares,
+	})
+}
+
+// ===== APM相关处理器 =====
+
+// GetOperations 获取操作列表
+// @Summary 获取服务操作列表
+// @Description 从Jaeger查询服务上报过的操作名称
+// @Tags APM监控
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "服务名称"
+// @Success 200 {array} string
+// @Failure 500 {object} ErrorResponse
+// @Router /apm/services/{name}/operations [get]
+func (h *Handlers) GetOperations(c *gin.Context) {
+	service := c.Param("name")
+	if service == "" {
+		service = c.Query("service")
+	}
+
+	operations, err := h.apmService.GetOperations(service)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"message": "获取操作列表成功",
+		"data": operations,
+	})
+}
+
+// GetServiceMap 获取服务地图
+func (h *Handlers) GetServiceMap(c *gin.Context) {
+	h.apmHandler.GetServiceTopology(c)
+}
+
+// GetServiceMapHistory 获取服务拓扑历史快照
+func (h *Handlers) GetServiceMapHistory(c *gin.Context) {
+	h.apmHandler.GetServiceMapHistory(c)
+}
+
+// DiffServiceMap 对比服务拓扑
+func (h *Handlers) DiffServiceMap(c *gin.Context) {
+	h.apmHandler.DiffServiceMap(c)
+}
+
+// GetServiceVersions 获取服务版本延迟分布
+func (h *Handlers) GetServiceVersions(c *gin.Context) {
+	h.apmHandler.GetServiceVersions(c)
+}
+
+// CompareServiceVersions 对比服务版本
+func (h *Handlers) CompareServiceVersions(c *gin.Context) {
+	h.apmHandler.CompareServiceVersions(c)
+}
+
+// GetServiceDeployments 获取服务发布记录
+func (h *Handlers) GetServiceDeployments(c *gin.Context) {
+	h.apmHandler.GetServiceDeployments(c)
+}
+
 // ===== 代理管理相关处理器 =====
 
 // GetAgents 获取代理列表
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/services"
+
+	"github.com/gin-gonic/gin"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// TraceIngestHandler 链路数据接入处理器
+type TraceIngestHandler struct {
+	traceIngestService *services.TraceIngestService
+	config             config.TracingConfig
+}
+
+// NewTraceIngestHandler 创建链路数据接入处理器
+func NewTraceIngestHandler(traceIngestService *services.TraceIngestService, config config.TracingConfig) *TraceIngestHandler {
+	return &TraceIngestHandler{
+		traceIngestService: traceIngestService,
+		config:             config,
+	}
+}
+
+// errBodyTooLarge 请求体超过限制或无法读取
+var errBodyTooLarge = errors.New("request body too large or unreadable")
+
+// readBody 读取请求体，支持gzip压缩，maxBodySize同时限制压缩前后的大小
+func readBody(c *gin.Context, maxBodySize int64) ([]byte, error) {
+	var body io.Reader = c.Request.Body
+	if maxBodySize > 0 {
+		body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+	}
+	if c.GetHeader("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, errors.New("invalid gzip body")
+		}
+		defer gz.Close()
+		body = gz
+		if maxBodySize > 0 {
+			// 限制解压后的大小
+			body = io.LimitReader(gz, maxBodySize+1)
+		}
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil || (maxBodySize > 0 && int64(len(data)) > maxBodySize) {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// ExportOTLPTraces 接收OTLP/HTTP链路数据
+// @Summary 接收OTLP链路数据
+// @Description 接收OpenTelemetry SDK或Collector通过OTLP/HTTP导出的链路数据，支持protobuf和JSON编码及gzip压缩
+// @Tags APM监控
+// @Accept application/x-protobuf,json
+// @Produce application/x-protobuf,json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /v1/traces [post]
+func (h *TraceIngestHandler) ExportOTLPTraces(c *gin.Context) {
+	contentType := c.GetHeader("Content-Type")
+
+	data, err := readBody(c, h.config.OTLP.MaxBodySize)
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			h.writeStatus(c, contentType, http.StatusRequestEntityTooLarge, codes.InvalidArgument, err.Error())
+			return
+		}
+		h.writeStatus(c, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	req, err := services.DecodeOTLPTraceRequest(data, contentType)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedOTLPContentType) {
+			h.writeStatus(c, contentType, http.StatusUnsupportedMediaType, codes.InvalidArgument, err.Error())
+			return
+		}
+		h.writeStatus(c, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	resp, err := h.traceIngestService.ExportOTLPTraces(c.Request.Context(), req)
+	if err != nil {
+		// 503表示可重试，SDK会按退避策略重新发送
+		h.writeStatus(c, contentType, http.StatusServiceUnavailable, codes.Unavailable, err.Error())
+		return
+	}
+
+	out, outType, err := services.EncodeOTLPMessage(resp, contentType)
+	if err != nil {
+		h.writeStatus(c, contentType, http.StatusInternalServerError, codes.Internal, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, outType, out)
+}
+
+// ExportZipkinSpans 接收Zipkin v2 JSON跨度
+// @Summary 接收Zipkin链路数据
+// @Description 接收Zipkin reporter上报的v2 JSON跨度数组，与OTLP数据写入同一存储和服务注册表，支持gzip压缩
+// @Tags APM监控
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} services.IngestResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v2/spans [post]
+func (h *TraceIngestHandler) ExportZipkinSpans(c *gin.Context) {
+	if !strings.HasPrefix(c.GetHeader("Content-Type"), "application/json") {
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Error:   "Unsupported content type",
+			Message: "only Zipkin v2 JSON (application/json) is supported",
+		})
+		return
+	}
+	h.ingest(c, h.config.Zipkin.MaxBodySize, h.traceIngestService.IngestZipkinSpans)
+}
+
+// ExportJaegerThrift 接收Jaeger客户端Thrift over HTTP上报
+// @Summary 接收Jaeger Thrift链路数据
+// @Description 接收Jaeger客户端以Thrift二进制协议上报的Batch，与OTLP数据写入同一存储和服务注册表
+// @Tags APM监控
+// @Accept application/x-thrift
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} services.IngestResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/traces [post]
+func (h *TraceIngestHandler) ExportJaegerThrift(c *gin.Context) {
+	contentType := c.GetHeader("Content-Type")
+	if !strings.HasPrefix(contentType, services.JaegerThriftContentType) &&
+		!strings.HasPrefix(contentType, "application/vnd.apache.thrift.binary") {
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Error:   "Unsupported content type",
+			Message: "only Thrift binary (application/x-thrift) is supported",
+		})
+		return
+	}
+	h.ingest(c, h.config.JaegerThrift.MaxBodySize, h.traceIngestService.IngestJaegerThrift)
+}
+
+// ingest 读取请求体并交给对应格式的解析入库函数
+func (h *TraceIngestHandler) ingest(c *gin.Context, maxBodySize int64, ingestFn func(context.Context, []byte) (*services.IngestResult, error)) {
+	data, err := readBody(c, maxBodySize)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := ingestFn(c.Request.Context(), data)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to decode") {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid spans",
+				Message: err.Error(),
+			})
+			return
+		}
+		// 503表示可重试
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Service Unavailable",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, result)
+}
+
+// GetSamplingStats 获取尾部采样统计
+// @Summary 获取尾部采样统计
+// @Description 获取缓冲中的链路数及按原因统计的保留、丢弃链路数
+// @Tags APM监控
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.SamplingStats
+// @Failure 404 {object} ErrorResponse
+// @Router /apm/sampling/stats [get]
+func (h *TraceIngestHandler) GetSamplingStats(c *gin.Context) {
+	stats := h.traceIngestService.GetSamplingStats()
+	if stats == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Sampling disabled",
+			Message: "tail sampling is not enabled",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// writeStatus 按OTLP/HTTP规范以google.rpc.Status返回错误
+func (h *TraceIngestHandler) writeStatus(c *gin.Context, contentType string, httpStatus int, code codes.Code, message string) {
+	out, outType, err := services.EncodeOTLPMessage(&statuspb.Status{Code: int32(code), Message: message}, contentType)
+	if err != nil {
+		c.String(httpStatus, message)
+		return
+	}
+	c.Data(httpStatus, outType, out)
+}
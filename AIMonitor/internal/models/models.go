@@ -70,10 +70,16 @@ type AlertRule struct {
 	Duration    int    `json:"duration" gorm:"not null;default:300" validate:"min=60"`
 	Severity    string `json:"severity" gorm:"not null;size:20" validate:"required,oneof=critical high medium low"`
 	Enabled     bool   `json:"enabled" gorm:"default:true"`
+	Kind        string `json:"kind" gorm:"default:'threshold';size:20;index" validate:"oneof=threshold anomaly forecast regression slo event"`
+	Sensitivity float64 `json:"sensitivity" gorm:"default:0"`
+	ForecastHorizon int `json:"forecast_horizon" gorm:"default:0"`
 	Query       string `json:"query" gorm:"type:text"`
 	Labels      string `json:"labels" gorm:"type:json"`
 	Annotations string `json:"annotations" gorm:"type:json"`
 	GroupBy     string `json:"group_by" gorm:"size:255"`
+	PromptTemplateID *uuid.UUID `json:"prompt_template_id" gorm:"type:char(36)"`
+	SLOID       *uuid.UUID `json:"slo_id" gorm:"type:char(36);index"`
+	ShortWindow int    `json:"short_window" gorm:"default:0"`
 	CreatedBy   uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
 	UpdatedBy   uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
 	Alerts      []Alert   `json:"-" gorm:"foreignKey:RuleID"`
@@ -159,6 +165,13 @@ type AIAnalysisResult struct {
 	Status          string    `json:"status" gorm:"not null;size:20;index" validate:"required,oneof=pending processing completed failed"`
 	Error           string    `json:"error" gorm:"type:text"`
 	Metadata        string    `json:"metadata" gorm:"type:json"`
+	PromptVersion   string    `json:"prompt_version" gorm:"size:50;default:'v1';index"`
+	// Response 保存通过schema校验的结构化结果，模型原始输出和修复输出单独保存
+	RawResponse      string `json:"raw_response" gorm:"type:text"`
+	RepairedResponse string `json:"repaired_response" gorm:"type:text"`
+	ValidationErrors string `json:"validation_errors" gorm:"type:text"`
+	RepairAttempts   int    `json:"repair_attempts" gorm:"default:0"`
+	OutputMode       string `json:"output_mode" gorm:"size:20"`
 }
 
 // KnowledgeBase 知识库模�?
@@ -192,8 +205,10 @@ type MonitoringTarget struct {
 	Metrics     string `json:"metrics" gorm:"type:json"`
 	Status      string `json:"status" gorm:"default:'active';size:20;index" validate:"oneof=active inactive error"`
 	LastSeen    *time.Time `json:"last_seen"`
-	CreatedBy   uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
-	UpdatedBy   uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
+	// APIKeyID 注册Agent时使用的API Key，Agent命令通道只接受该Key的调用
+	APIKeyID  *uuid.UUID `json:"-" gorm:"type:char(36);index"`
+	CreatedBy uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy uuid.UUID  `json:"updated_by" gorm:"type:char(36)"`
 }
 
 // MetricData 指标数据模型
@@ -253,11 +268,13 @@ type MiddlewareMonitor struct {
 // APMTrace APM链路追踪模型
 type APMTrace struct {
 	ID           uuid.UUID `json:"id" gorm:"type:char(36);primary_key;"`
-	TraceID      string    `json:"trace_id" gorm:"not null;size:32;index" validate:"required"`
-	SpanID       string    `json:"span_id" gorm:"not null;size:16;index" validate:"required"`
+	TraceID      string    `json:"trace_id" gorm:"not null;size:32;index;uniqueIndex:idx_apm_traces_trace_span" validate:"required"`
+	SpanID       string    `json:"span_id" gorm:"not null;size:16;index;uniqueIndex:idx_apm_traces_trace_span" validate:"required"`
 	ParentSpanID string    `json:"parent_span_id" gorm:"size:16;index"`
 	OperationName string   `json:"operation_name" gorm:"not null;size:255" validate:"required"`
 	ServiceName  string    `json:"service_name" gorm:"not null;size:100;index" validate:"required"`
+	SpanKind     string    `json:"span_kind" gorm:"size:20;index"`
+	ServiceVersion string  `json:"service_version" gorm:"size:50;index"`
 	StartTime    time.Time `json:"start_time" gorm:"not null;index"`
 	EndTime      time.Time `json:"end_time" gorm:"not null;index"`
 	Duration     int64     `json:"duration" gorm:"not null;index"`
@@ -295,6 +312,7 @@ type ContainerMonitor struct {
 	Namespace     string `json:"namespace" gorm:"size:100;index"`
 	PodName       string `json:"pod_name" gorm:"size:255;index"`
 	NodeName      string `json:"node_name" gorm:"size:255;index"`
+	Cluster       string `json:"cluster" gorm:"size:100;index"` // Kubernetes集群名称，Docker容器为空
 	Status        string `json:"status" gorm:"not null;size:20;index" validate:"required,oneof=running stopped paused exited error"`
 	StartedAt     *time.Time `json:"started_at"`
 	FinishedAt    *time.Time `json:"finished_at"`
@@ -379,6 +397,327 @@ type APIKey struct {
 	UpdatedBy   uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
 }
 
+// AlertEvent 告警事件模型（告警时间线）
+type AlertEvent struct {
+	BaseModel
+	AlertID   uuid.UUID  `json:"alert_id" gorm:"type:char(36);not null;index"`
+	RuleID    uuid.UUID  `json:"rule_id" gorm:"type:char(36);not null;index"`
+	Type      string     `json:"type" gorm:"not null;size:30;index" validate:"required,oneof=firing resolved reopened acknowledged remediation"`
+	Message   string     `json:"message" gorm:"size:500"`
+	Value     float64    `json:"value"`
+	Details   string     `json:"details" gorm:"type:json"`
+	UserID    *uuid.UUID `json:"user_id" gorm:"type:char(36)"`
+}
+
+// AIInsight AI洞察模型
+type AIInsight struct {
+	BaseModel
+	BatchID     uuid.UUID `json:"batch_id" gorm:"type:char(36);not null;index"`
+	Type        string    `json:"type" gorm:"not null;size:30;index" validate:"required,oneof=anomaly noisy_rule flapping_alert error_rate capacity"`
+	Title       string    `json:"title" gorm:"not null;size:200" validate:"required"`
+	Description string    `json:"description" gorm:"type:text"`
+	Severity    string    `json:"severity" gorm:"not null;size:20;index" validate:"oneof=critical high medium low"`
+	Score       float64   `json:"score" gorm:"default:0"`
+	Rank        int       `json:"rank" gorm:"column:insight_rank;default:0"`
+	TargetID    string    `json:"target_id" gorm:"size:100;index"`
+	Evidence    string    `json:"evidence" gorm:"type:json"`
+	GeneratedAt time.Time `json:"generated_at" gorm:"not null;index"`
+}
+
+// Incident 故障事件模型（关联告警分组）
+type Incident struct {
+	BaseModel
+	Title              string     `json:"title" gorm:"not null;size:500" validate:"required"`
+	Description        string     `json:"description" gorm:"type:text"`
+	Status             string     `json:"status" gorm:"default:'investigating';size:20;index" validate:"oneof=investigating identified monitoring resolved"`
+	Severity           string     `json:"severity" gorm:"not null;size:20;index"`
+	CommanderID        *uuid.UUID `json:"commander_id" gorm:"type:char(36);index"`
+	Stakeholders       string     `json:"stakeholders" gorm:"type:json"`
+	StartedAt          time.Time  `json:"started_at" gorm:"not null;index"`
+	LastAlertAt        time.Time  `json:"last_alert_at" gorm:"not null;index"`
+	ResolvedAt         *time.Time `json:"resolved_at" gorm:"index"`
+	AlertCount         int        `json:"alert_count" gorm:"default:0"`
+	Hosts              string     `json:"hosts" gorm:"type:json"`
+	Services           string     `json:"services" gorm:"type:json"`
+	Nodes              string     `json:"nodes" gorm:"type:json"`
+	RootCause          string     `json:"root_cause" gorm:"type:text"`
+	AnalysisID         *uuid.UUID `json:"analysis_id" gorm:"type:char(36)"`
+	AnalyzedAlertCount int        `json:"analyzed_alert_count" gorm:"default:0"`
+	PostmortemID       *uuid.UUID `json:"postmortem_id" gorm:"type:char(36)"`
+	CreatedBy          *uuid.UUID `json:"created_by" gorm:"type:char(36)"`
+	Alerts             []IncidentAlert `json:"alerts,omitempty" gorm:"foreignKey:IncidentID"`
+}
+
+// IncidentAlert 故障事件与告警关联模型
+type IncidentAlert struct {
+	BaseModel
+	IncidentID uuid.UUID `json:"incident_id" gorm:"type:char(36);not null;index"`
+	AlertID    uuid.UUID `json:"alert_id" gorm:"type:char(36);not null;uniqueIndex"`
+	Alert      Alert     `json:"alert" gorm:"foreignKey:AlertID"`
+	Reason     string    `json:"reason" gorm:"not null;size:20" validate:"oneof=seed host service node labels text manual"`
+	Score      float64   `json:"score" gorm:"default:0"`
+}
+
+// IncidentEvent 故障事件时间线模型（备注、状态变更和自动事件）
+type IncidentEvent struct {
+	BaseModel
+	IncidentID uuid.UUID  `json:"incident_id" gorm:"type:char(36);not null;index"`
+	Type       string     `json:"type" gorm:"not null;size:30;index" validate:"required,oneof=created status_change note alert_linked commander_changed analysis postmortem"`
+	Message    string     `json:"message" gorm:"type:text"`
+	FromStatus string     `json:"from_status" gorm:"size:20"`
+	ToStatus   string     `json:"to_status" gorm:"size:20"`
+	UserID     *uuid.UUID `json:"user_id" gorm:"type:char(36)"`
+	Details    string     `json:"details" gorm:"type:json"`
+}
+
+// RemediationPlaybook 自动修复剧本模型
+type RemediationPlaybook struct {
+	BaseModel
+	Name        string     `json:"name" gorm:"not null;size:100" validate:"required"`
+	Description string     `json:"description" gorm:"type:text"`
+	RuleID      *uuid.UUID `json:"rule_id" gorm:"type:char(36);index"`
+	Enabled     bool       `json:"enabled" gorm:"default:true;index"`
+	DryRun      bool       `json:"dry_run" gorm:"default:true"`
+	Steps       string     `json:"steps" gorm:"type:json" validate:"required"`
+	RateLimit   int        `json:"rate_limit" gorm:"default:0"`
+	RateWindow  int        `json:"rate_window" gorm:"default:0"` // 秒
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy   uuid.UUID  `json:"updated_by" gorm:"type:char(36)"`
+}
+
+// RemediationExecution 自动修复执行记录模型
+type RemediationExecution struct {
+	BaseModel
+	PlaybookID  uuid.UUID  `json:"playbook_id" gorm:"type:char(36);not null;index"`
+	AlertID     *uuid.UUID `json:"alert_id" gorm:"type:char(36);index"`
+	TargetID    string     `json:"target_id" gorm:"size:100;index"`
+	Trigger     string     `json:"trigger" gorm:"not null;size:20" validate:"oneof=alert manual"`
+	Status      string     `json:"status" gorm:"not null;size:20;index" validate:"oneof=running pending_approval succeeded failed rejected rate_limited expired"`
+	DryRun      bool       `json:"dry_run" gorm:"default:false"`
+	CurrentStep int        `json:"current_step" gorm:"default:0"`
+	StepResults string     `json:"step_results" gorm:"type:json"`
+	Variables   string     `json:"variables" gorm:"type:json"`
+	Error       string     `json:"error" gorm:"type:text"`
+	TriggeredBy *uuid.UUID `json:"triggered_by" gorm:"type:char(36)"`
+	ApprovedBy  *uuid.UUID `json:"approved_by" gorm:"type:char(36)"`
+	ApprovedAt  *time.Time `json:"approved_at"`
+	// ApprovedStep 已审批、尚未执行的高风险步骤序号，步骤执行后清空，每个高风险步骤都需单独审批
+	ApprovedStep *int       `json:"approved_step"`
+	StartedAt    time.Time  `json:"started_at" gorm:"not null;index"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+// AgentCommand 下发给Agent执行的命令模型
+type AgentCommand struct {
+	BaseModel
+	AgentID      uuid.UUID  `json:"agent_id" gorm:"type:char(36);not null;index"`
+	ExecutionID  *uuid.UUID `json:"execution_id" gorm:"type:char(36);index"`
+	Command      string     `json:"command" gorm:"not null;size:1000" validate:"required"`
+	Args         string     `json:"args" gorm:"type:json"`
+	Timeout      int        `json:"timeout" gorm:"default:300"` // 秒
+	Status       string     `json:"status" gorm:"default:'pending';size:20;index" validate:"oneof=pending dispatched succeeded failed expired"`
+	ExitCode     int        `json:"exit_code" gorm:"default:0"`
+	Output       string     `json:"output" gorm:"type:text"`
+	DispatchedAt *time.Time `json:"dispatched_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+// AssistantConversation 运维助手会话模型
+type AssistantConversation struct {
+	BaseModel
+	UserID      uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	Title       string    `json:"title" gorm:"size:200"`
+	TotalTokens int       `json:"total_tokens" gorm:"default:0"`
+	LastActive  time.Time `json:"last_active" gorm:"index"`
+}
+
+// AssistantMessage 运维助手会话消息模型
+type AssistantMessage struct {
+	BaseModel
+	ConversationID uuid.UUID `json:"conversation_id" gorm:"type:char(36);not null;index"`
+	Role           string    `json:"role" gorm:"not null;size:20" validate:"oneof=user assistant tool"`
+	Content        string    `json:"content" gorm:"type:text"`
+	ToolCalls      string    `json:"tool_calls" gorm:"type:json"`
+	ToolCallID     string    `json:"tool_call_id" gorm:"size:100"`
+	ToolName       string    `json:"tool_name" gorm:"size:100"`
+	Evidence       string    `json:"evidence" gorm:"type:json"`
+	Tokens         int       `json:"tokens" gorm:"default:0"`
+}
+
+// AIAnalysisFeedback AI分析反馈模型
+type AIAnalysisFeedback struct {
+	BaseModel
+	AnalysisID         uuid.UUID `json:"analysis_id" gorm:"type:char(36);not null;index"`
+	UserID             uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	Rating             string    `json:"rating" gorm:"not null;size:10;index" validate:"required,oneof=up down"`
+	CorrectedRootCause string    `json:"corrected_root_cause" gorm:"type:text"`
+	Comment            string    `json:"comment" gorm:"size:1000"`
+}
+
+// IncidentMemory 已确认根因的故障记忆，用作相似告警分析的示例
+type IncidentMemory struct {
+	BaseModel
+	AnalysisID    uuid.UUID  `json:"analysis_id" gorm:"type:char(36);not null;uniqueIndex"`
+	AlertID       *uuid.UUID `json:"alert_id" gorm:"type:char(36);index"`
+	RuleID        *uuid.UUID `json:"rule_id" gorm:"type:char(36);index"`
+	Labels        string     `json:"labels" gorm:"type:json"`
+	Summary       string     `json:"summary" gorm:"size:500"`
+	RootCause     string     `json:"root_cause" gorm:"type:text"`
+	Corrected     bool       `json:"corrected" gorm:"default:false"`
+	ConfirmedBy   uuid.UUID  `json:"confirmed_by" gorm:"type:char(36);not null"`
+	UsedCount     int        `json:"used_count" gorm:"default:0"`
+}
+
+// PromptTemplate AI提示词模板模型，按分析类型和语言进行版本管理
+type PromptTemplate struct {
+	BaseModel
+	Name          string    `json:"name" gorm:"not null;size:100" validate:"required"`
+	Description   string    `json:"description" gorm:"size:500"`
+	AnalysisType  string    `json:"analysis_type" gorm:"not null;size:50;uniqueIndex:idx_prompt_templates_type_lang_version" validate:"required,oneof=alert_analysis performance_analysis"`
+	Language      string    `json:"language" gorm:"not null;size:10;uniqueIndex:idx_prompt_templates_type_lang_version" validate:"required"`
+	Version       string    `json:"version" gorm:"not null;size:50;uniqueIndex:idx_prompt_templates_type_lang_version" validate:"required"`
+	SystemPrompt  string    `json:"system_prompt" gorm:"type:text"`
+	Template      string    `json:"template" gorm:"type:text" validate:"required"`
+	Status        string    `json:"status" gorm:"default:'draft';size:20;index" validate:"oneof=draft active archived"`
+	TrafficWeight int       `json:"traffic_weight" gorm:"default:0"`
+	CreatedBy     uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy     uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
+}
+
+// PromptEvalSet 提示词离线评估使用的历史告警集合
+type PromptEvalSet struct {
+	BaseModel
+	Name        string    `json:"name" gorm:"not null;size:100;uniqueIndex" validate:"required"`
+	Description string    `json:"description" gorm:"size:500"`
+	AlertIDs    string    `json:"alert_ids" gorm:"type:json" validate:"required"`
+	CreatedBy   uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
+}
+
+// APMSpanMetric 由跨度计算的RED指标汇总，每个刷新周期按服务、操作、状态各一行，延迟单位毫秒
+type APMSpanMetric struct {
+	ID            uuid.UUID `json:"id" gorm:"type:char(36);primary_key;"`
+	BucketStart   time.Time `json:"bucket_start" gorm:"not null;index"`
+	BucketEnd     time.Time `json:"bucket_end" gorm:"not null"`
+	ServiceName   string    `json:"service_name" gorm:"not null;size:100"`
+	OperationName string    `json:"operation_name" gorm:"not null;size:255"`
+	ServiceVersion string   `json:"service_version" gorm:"size:50"`
+	Status        string    `json:"status" gorm:"not null;size:20"`
+	Count         int64     `json:"count" gorm:"not null"`
+	DurationSum   float64   `json:"duration_sum"`
+	DurationMax   float64   `json:"duration_max"`
+	Histogram     string    `json:"histogram" gorm:"type:json"`
+	Exemplars     string    `json:"exemplars" gorm:"type:json"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// APMSpanTag 跨度标签索引，每个标量标签一行，用于按任意标签检索链路；
+// (tag_key, tag_value, start_time)索引供按标签检索时直接定位时间范围内的命中跨度
+type APMSpanTag struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key;"`
+	TraceID   string    `json:"trace_id" gorm:"not null;size:32;index;uniqueIndex:idx_apm_span_tags_span_key"`
+	SpanID    string    `json:"span_id" gorm:"not null;size:16;uniqueIndex:idx_apm_span_tags_span_key"`
+	TagKey    string    `json:"tag_key" gorm:"not null;size:128;uniqueIndex:idx_apm_span_tags_span_key;index:idx_apm_span_tags_key_value_time,priority:1"`
+	TagValue  string    `json:"tag_value" gorm:"not null;size:255;index:idx_apm_span_tags_key_value_time,priority:2"`
+	StartTime time.Time `json:"start_time" gorm:"not null;index;index:idx_apm_span_tags_key_value_time,priority:3"`
+}
+
+// ServiceDeployment 服务版本发布记录，按服务首次出现新service.version的时间记录，用于发布后的性能回归检测
+type ServiceDeployment struct {
+	BaseModel
+	ServiceName     string     `json:"service_name" gorm:"not null;size:100;uniqueIndex:idx_service_deployments_service_version"`
+	Version         string     `json:"version" gorm:"not null;size:50;uniqueIndex:idx_service_deployments_service_version"`
+	PreviousVersion string     `json:"previous_version" gorm:"size:50"`
+	FirstSeen       time.Time  `json:"first_seen" gorm:"not null;index"`
+	Status          string     `json:"status" gorm:"default:'observing';size:20;index" validate:"oneof=baseline observing healthy regressed insufficient_data"`
+	EvaluatedAt     *time.Time `json:"evaluated_at"`
+	Report          string     `json:"report" gorm:"type:json"`
+}
+
+// ServiceTopologySnapshot 服务拓扑历史快照，用于对比发布前后的调用关系
+type ServiceTopologySnapshot struct {
+	BaseModel
+	CapturedAt time.Time `json:"captured_at" gorm:"not null;index"`
+	WindowSeconds int64  `json:"window_seconds" gorm:"not null"`
+	NodeCount  int       `json:"node_count"`
+	EdgeCount  int       `json:"edge_count"`
+	Topology   string    `json:"topology" gorm:"type:json"`
+}
+
+// SLO 服务等级目标模型。SLI为滚动窗口内好事件占总事件的比例，
+// 事件来自APM跨度指标（可用性或延迟）或两个计数指标，MetricKind区分按周期计数（delta）与累计计数器（counter）
+type SLO struct {
+	BaseModel
+	Name             string     `json:"name" gorm:"not null;size:100;uniqueIndex" validate:"required"`
+	Description      string     `json:"description" gorm:"size:500"`
+	SourceType       string     `json:"source_type" gorm:"not null;size:20" validate:"required,oneof=apm metric"`
+	ServiceName      string     `json:"service_name" gorm:"size:100;index"`
+	Operation        string     `json:"operation" gorm:"size:255"`
+	Indicator        string     `json:"indicator" gorm:"size:20" validate:"omitempty,oneof=availability latency"`
+	LatencyThreshold float64    `json:"latency_threshold"`
+	GoodMetric       string     `json:"good_metric" gorm:"size:100"`
+	TotalMetric      string     `json:"total_metric" gorm:"size:100"`
+	MetricKind       string     `json:"metric_kind" gorm:"size:20;default:'delta'" validate:"omitempty,oneof=delta counter"`
+	MetricTargetID   *uuid.UUID `json:"metric_target_id" gorm:"type:char(36)"`
+	Target           float64    `json:"target" gorm:"not null" validate:"required,gt=0,lt=100"`
+	WindowDays       int        `json:"window_days" gorm:"not null;default:30" validate:"min=1,max=90"`
+	Enabled          bool       `json:"enabled" gorm:"default:true"`
+	SLI              float64    `json:"sli"`
+	BudgetRemaining  float64    `json:"budget_remaining"`
+	EvaluatedAt      *time.Time `json:"evaluated_at"`
+	CreatedBy        uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy        uuid.UUID  `json:"updated_by" gorm:"type:char(36)"`
+}
+
+// SLOSnapshot SLO错误预算历史
+type SLOSnapshot struct {
+	ID              uuid.UUID `json:"id" gorm:"type:char(36);primary_key;"`
+	SLOID           uuid.UUID `json:"slo_id" gorm:"type:char(36);not null;index"`
+	Timestamp       time.Time `json:"timestamp" gorm:"not null;index"`
+	Good            float64   `json:"good"`
+	Total           float64   `json:"total"`
+	SLI             float64   `json:"sli"`
+	BudgetRemaining float64   `json:"budget_remaining"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ContainerEvent 容器生命周期事件（来自Docker /events流）
+type ContainerEvent struct {
+	ID            uuid.UUID `json:"id" gorm:"type:char(36);primary_key;"`
+	ContainerID   string    `json:"container_id" gorm:"not null;size:64;index"`
+	ContainerName string    `json:"container_name" gorm:"size:255"`
+	Image         string    `json:"image" gorm:"size:255"`
+	Platform      string    `json:"platform" gorm:"not null;size:50;default:'docker'"`
+	Action        string    `json:"action" gorm:"not null;size:50;index"` // start, die, oom, restart, health_status
+	Health        string    `json:"health" gorm:"size:20"`                // health_status事件的healthy/unhealthy
+	ExitCode      *int      `json:"exit_code"`
+	Attributes    string    `json:"attributes" gorm:"type:json"`
+	Timestamp     time.Time `json:"timestamp" gorm:"not null;index"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// KubernetesCluster 已注册的Kubernetes集群，凭据以AES-GCM加密存储
+type KubernetesCluster struct {
+	BaseModel
+	Name        string `json:"name" gorm:"not null;size:100;uniqueIndex" validate:"required"`
+	Description string `json:"description" gorm:"size:500"`
+	// AuthType config使用服务端monitoring.kubernetes配置，in_cluster使用Pod的ServiceAccount
+	AuthType              string     `json:"auth_type" gorm:"not null;size:20" validate:"required,oneof=config in_cluster kubeconfig token client_cert"`
+	Server                string     `json:"server" gorm:"size:255"`
+	Context               string     `json:"context" gorm:"size:100"`
+	InsecureSkipTLSVerify bool       `json:"insecure_skip_tls_verify" gorm:"default:false"`
+	Credentials           string     `json:"-" gorm:"type:text"`
+	Labels                string     `json:"labels" gorm:"type:json"`
+	Enabled               bool       `json:"enabled" gorm:"default:true"`
+	IsDefault             bool       `json:"is_default" gorm:"default:false"`
+	Status                string     `json:"status" gorm:"size:20;default:'unknown'"` // unknown, healthy, unhealthy
+	Version               string     `json:"version" gorm:"size:50"`
+	NodeCount             int        `json:"node_count"`
+	LastError             string     `json:"last_error" gorm:"size:1000"`
+	LastCheckedAt         *time.Time `json:"last_checked_at"`
+	CreatedBy             uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy             uuid.UUID  `json:"updated_by" gorm:"type:char(36)"`
+}
+
 // TableName 指定表名
 func (User) TableName() string                { return "users" }
 func (Role) TableName() string                { return "roles" }
@@ -402,6 +741,28 @@ func (AgentInfo) TableName() string           { return "agent_infos" }
 func (AgentDeployment) TableName() string     { return "agent_deployments" }
 func (AgentPackage) TableName() string        { return "agent_packages" }
 func (APIKey) TableName() string              { return "api_keys" }
+func (AlertEvent) TableName() string          { return "alert_events" }
+func (AIInsight) TableName() string           { return "ai_insights" }
+func (Incident) TableName() string            { return "incidents" }
+func (IncidentAlert) TableName() string       { return "incident_alerts" }
+func (IncidentEvent) TableName() string       { return "incident_events" }
+func (RemediationPlaybook) TableName() string { return "remediation_playbooks" }
+func (RemediationExecution) TableName() string { return "remediation_executions" }
+func (AgentCommand) TableName() string        { return "agent_commands" }
+func (AssistantConversation) TableName() string { return "assistant_conversations" }
+func (AssistantMessage) TableName() string    { return "assistant_messages" }
+func (AIAnalysisFeedback) TableName() string  { return "ai_analysis_feedback" }
+func (IncidentMemory) TableName() string      { return "incident_memories" }
+func (PromptTemplate) TableName() string      { return "prompt_templates" }
+func (PromptEvalSet) TableName() string       { return "prompt_eval_sets" }
+func (ServiceTopologySnapshot) TableName() string { return "service_topology_snapshots" }
+func (APMSpanMetric) TableName() string       { return "apm_span_metrics" }
+func (APMSpanTag) TableName() string          { return "apm_span_tags" }
+func (ServiceDeployment) TableName() string   { return "service_deployments" }
+func (SLO) TableName() string                 { return "slos" }
+func (SLOSnapshot) TableName() string         { return "slo_snapshots" }
+func (ContainerEvent) TableName() string      { return "container_events" }
+func (KubernetesCluster) TableName() string   { return "kubernetes_clusters" }
 
 // BeforeCreate 创建前钩�?
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -557,3 +918,136 @@ func (ak *APIKey) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func (ae *AlertEvent) BeforeCreate(tx *gorm.DB) error {
+	if ae.ID == uuid.Nil {
+		ae.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ai *AIInsight) BeforeCreate(tx *gorm.DB) error {
+	if ai.ID == uuid.Nil {
+		ai.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *Incident) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ia *IncidentAlert) BeforeCreate(tx *gorm.DB) error {
+	if ia.ID == uuid.Nil {
+		ia.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ie *IncidentEvent) BeforeCreate(tx *gorm.DB) error {
+	if ie.ID == uuid.Nil {
+		ie.ID = uuid.New()
+	}
+	return nil
+}
+
+func (rp *RemediationPlaybook) BeforeCreate(tx *gorm.DB) error {
+	if rp.ID == uuid.Nil {
+		rp.ID = uuid.New()
+	}
+	return nil
+}
+
+func (re *RemediationExecution) BeforeCreate(tx *gorm.DB) error {
+	if re.ID == uuid.Nil {
+		re.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ac *AgentCommand) BeforeCreate(tx *gorm.DB) error {
+	if ac.ID == uuid.Nil {
+		ac.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ac *AssistantConversation) BeforeCreate(tx *gorm.DB) error {
+	if ac.ID == uuid.Nil {
+		ac.ID = uuid.New()
+	}
+	return nil
+}
+
+func (am *AssistantMessage) BeforeCreate(tx *gorm.DB) error {
+	if am.ID == uuid.Nil {
+		am.ID = uuid.New()
+	}
+	return nil
+}
+
+func (af *AIAnalysisFeedback) BeforeCreate(tx *gorm.DB) error {
+	if af.ID == uuid.Nil {
+		af.ID = uuid.New()
+	}
+	return nil
+}
+
+func (im *IncidentMemory) BeforeCreate(tx *gorm.DB) error {
+	if im.ID == uuid.Nil {
+		im.ID = uuid.New()
+	}
+	return nil
+}
+
+func (pt *PromptTemplate) BeforeCreate(tx *gorm.DB) error {
+	if pt.ID == uuid.Nil {
+		pt.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ps *PromptEvalSet) BeforeCreate(tx *gorm.DB) error {
+	if ps.ID == uuid.Nil {
+		ps.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ts *ServiceTopologySnapshot) BeforeCreate(tx *gorm.DB) error {
+	if ts.ID == uuid.Nil {
+		ts.ID = uuid.New()
+	}
+	return nil
+}
+
+func (sm *APMSpanMetric) BeforeCreate(tx *gorm.DB) error {
+	if sm.ID == uuid.Nil {
+		sm.ID = uuid.New()
+	}
+	return nil
+}
+
+func (st *APMSpanTag) BeforeCreate(tx *gorm.DB) error {
+	if st.ID == uuid.Nil {
+		st.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ss *SLOSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if ss.ID == uuid.Nil {
+		ss.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ce *ContainerEvent) BeforeCreate(tx *gorm.DB) error {
+	if ce.ID == uuid.Nil {
+		ce.ID = uuid.New()
+	}
+	return nil
+}
@@ -113,6 +113,31 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 	// Prometheus指标
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// OTLP/HTTP链路接收，路径与OpenTelemetry SDK默认导出路径一致
+	if otlp := cfg.Monitoring.Tracing.OTLP; otlp.Enabled {
+		if otlp.RequireAuth {
+			r.POST("/v1/traces", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.ExportOTLPTraces)
+		} else {
+			r.POST("/v1/traces", h.ExportOTLPTraces)
+		}
+	}
+
+	// Zipkin v2与Jaeger Thrift over HTTP接收，路径与各自collector默认路径一致
+	if zipkin := cfg.Monitoring.Tracing.Zipkin; zipkin.Enabled {
+		if zipkin.RequireAuth {
+			r.POST("/api/v2/spans", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.ExportZipkinSpans)
+		} else {
+			r.POST("/api/v2/spans", h.ExportZipkinSpans)
+		}
+	}
+	if jaegerThrift := cfg.Monitoring.Tracing.JaegerThrift; jaegerThrift.Enabled {
+		if jaegerThrift.RequireAuth {
+			r.POST("/api/traces", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.ExportJaegerThrift)
+		} else {
+			r.POST("/api/traces", h.ExportJaegerThrift)
+		}
+	}
+
 	// pprof性能分析（仅在开发模式下）
 	if cfg.Server.Mode == "debug" {
 		pprof.Register(r)
@@ -174,6 +199,50 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 			alerts.POST("/rules/:id/disable", h.DisableAlertRule)
 		}
 
+		// SLO路由（需要认证）
+		slos := api.Group("/slos")
+		slos.Use(middleware.Auth())
+		{
+			slos.GET("", h.GetSLOs)
+			slos.POST("", h.CreateSLO)
+			slos.GET("/report", h.GetSLOReport)
+			slos.GET("/:id", h.GetSLO)
+			slos.PUT("/:id", h.UpdateSLO)
+			slos.DELETE("/:id", h.DeleteSLO)
+			slos.GET("/:id/history", h.GetSLOHistory)
+		}
+
+		// 故障事件路由（需要认证）
+		incidents := api.Group("/incidents")
+		incidents.Use(middleware.Auth())
+		{
+			incidents.GET("", h.GetIncidents)
+			incidents.POST("", h.CreateIncident)
+			incidents.GET("/:id", h.GetIncident)
+			incidents.PUT("/:id", h.UpdateIncident)
+			incidents.PUT("/:id/status", h.UpdateIncidentStatus)
+			incidents.POST("/:id/notes", h.AddIncidentNote)
+			incidents.POST("/:id/alerts", h.LinkIncidentAlerts)
+			incidents.POST("/:id/analyze", h.AnalyzeIncident)
+			incidents.POST("/:id/postmortem", h.GenerateIncidentPostmortem)
+		}
+
+		// 自动修复路由（需要认证）
+		remediation := api.Group("/remediation")
+		remediation.Use(middleware.Auth())
+		{
+			remediation.GET("/playbooks", h.GetRemediationPlaybooks)
+			remediation.POST("/playbooks", h.CreateRemediationPlaybook)
+			remediation.GET("/playbooks/:id", h.GetRemediationPlaybook)
+			remediation.PUT("/playbooks/:id", h.UpdateRemediationPlaybook)
+			remediation.DELETE("/playbooks/:id", h.DeleteRemediationPlaybook)
+			remediation.POST("/playbooks/:id/execute", h.ExecuteRemediationPlaybook)
+			remediation.GET("/executions", h.GetRemediationExecutions)
+			remediation.GET("/executions/:id", h.GetRemediationExecution)
+			remediation.POST("/executions/:id/approve", h.ApproveRemediationExecution)
+			remediation.POST("/executions/:id/reject", h.RejectRemediationExecution)
+		}
+
 		// 监控数据路由（需要认证）
 		monitoring := api.Group("/monitoring")
 		monitoring.Use(middleware.Auth())
@@ -212,10 +281,28 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 		{
 			ai.POST("/analyze", h.AnalyzeData)
 			ai.GET("/analyses", h.GetAnalyses)
+			ai.GET("/analyses/accuracy", h.GetAnalysisAccuracy)
 			ai.GET("/analyses/:id", h.GetAnalysis)
+			ai.POST("/analyses/:id/feedback", h.SubmitAnalysisFeedback)
+			ai.GET("/analyses/:id/feedback", h.GetAnalysisFeedback)
 			ai.DELETE("/analyses/:id", h.DeleteAnalysis)
 			ai.POST("/predict", h.PredictTrend)
 			ai.GET("/insights", h.GetInsights)
+			ai.GET("/capacity", h.GetCapacityReport)
+
+			// 提示词模板管理
+			ai.GET("/prompt-templates", h.GetPromptTemplates)
+			ai.POST("/prompt-templates", h.CreatePromptTemplate)
+			ai.GET("/prompt-templates/:id", h.GetPromptTemplate)
+			ai.PUT("/prompt-templates/:id", h.UpdatePromptTemplate)
+			ai.GET("/prompt-eval-sets", h.GetPromptEvalSets)
+			ai.POST("/prompt-eval-sets", h.CreatePromptEvalSet)
+
+			// 运维助手
+			ai.POST("/assistant/chat", h.AssistantChat)
+			ai.GET("/assistant/conversations", h.GetAssistantConversations)
+			ai.GET("/assistant/conversations/:id", h.GetAssistantConversation)
+			ai.DELETE("/assistant/conversations/:id", h.DeleteAssistantConversation)
 
 			// 知识库管理
 			ai.GET("/knowledge-base", h.GetKnowledgeBases)
@@ -247,7 +334,15 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 			apm.GET("/services", h.GetServices)
 			apm.GET("/services/:name/performance", h.GetServicePerformance)
 			apm.GET("/services/:name/operations", h.GetOperations)
+			apm.GET("/services/:name/versions", h.GetServiceVersions)
+			apm.GET("/services/:name/compare", h.CompareServiceVersions)
+			apm.GET("/services/:name/deployments", h.GetServiceDeployments)
 			apm.GET("/service-map", h.GetServiceMap)
+			apm.GET("/service-map/history", h.GetServiceMapHistory)
+			apm.GET("/service-map/diff", h.DiffServiceMap)
+			apm.GET("/traces", h.GetTraces)
+			apm.GET("/traces/:trace_id", h.GetTraceDetail)
+			apm.GET("/sampling/stats", h.GetSamplingStats)
 			apm.POST("/services", h.CreateService)
 			apm.PUT("/services/:name", h.UpdateService)
 			apm.DELETE("/services/:name", h.DeleteService)
@@ -258,9 +353,19 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 		containers.Use(middleware.Auth())
 		{
 			containers.GET("/docker", h.GetDockerContainers)
+			containers.GET("/events", h.GetContainerEvents)
 			containers.GET("/kubernetes/pods", h.GetKubernetesPods)
 			containers.GET("/kubernetes/nodes", h.GetKubernetesNodes)
 			containers.GET("/kubernetes/namespaces", h.GetKubernetesNamespaces)
+			containers.GET("/kubernetes/workloads", h.GetKubernetesWorkloads)
+			containers.GET("/kubernetes/events", h.GetKubernetesEvents)
+			containers.GET("/kubernetes/inventory", h.GetKubernetesInventoryStatus)
+			containers.GET("/kubernetes/clusters", h.GetKubernetesClusters)
+			containers.POST("/kubernetes/clusters", h.CreateKubernetesCluster)
+			containers.GET("/kubernetes/clusters/:id", h.GetKubernetesCluster)
+			containers.PUT("/kubernetes/clusters/:id", h.UpdateKubernetesCluster)
+			containers.DELETE("/kubernetes/clusters/:id", h.DeleteKubernetesCluster)
+			containers.POST("/kubernetes/clusters/:id/check", h.CheckKubernetesCluster)
 			containers.GET("/cluster/metrics", h.GetClusterMetrics)
 			containers.GET("/resource-usage", h.GetResourceUsage)
 			containers.POST("", h.CreateContainerMonitor)
@@ -278,6 +383,8 @@ func setupRoutes(r *gin.Engine, h *handlers.Handlers, cfg *config.Config) {
 			// Agent注册和心跳路由（支持API Key或JWT认证）
 			agents.POST("", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.CreateAgent)
 			agents.POST("/heartbeat", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.HandleAgentHeartbeat)
+			agents.GET("/:id/commands", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.GetAgentCommands)
+			agents.POST("/:id/commands/:command_id/result", middleware.APIKeyOrJWTAuthMiddleware(h.Services.JWTManager, db), h.ReportAgentCommandResult)
 			
 			// Agent管理路由（需要JWT认证）
 			agents.GET("", middleware.Auth(), h.GetAgents)
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AgentCommandResponse Agent命令响应
+type AgentCommandResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	AgentID      uuid.UUID  `json:"agent_id"`
+	ExecutionID  *uuid.UUID `json:"execution_id"`
+	Command      string     `json:"command"`
+	Args         []string   `json:"args"`
+	Timeout      int        `json:"timeout"`
+	Status       string     `json:"status"`
+	ExitCode     int        `json:"exit_code"`
+	Output       string     `json:"output"`
+	DispatchedAt *time.Time `json:"dispatched_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AgentCommandResultRequest Agent上报命令执行结果请求
+type AgentCommandResultRequest struct {
+	Status   string `json:"status" binding:"required,oneof=succeeded failed"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// ErrAgentCredentialMismatch 调用方凭据与Agent不匹配
+var ErrAgentCredentialMismatch = errors.New("credentials are not bound to this agent")
+
+// AuthorizeAgentAPIKey 校验API Key是否为注册该Agent时使用的Key
+func (s *AgentService) AuthorizeAgentAPIKey(agentID, apiKeyID uuid.UUID) error {
+	var target models.MonitoringTarget
+	if err := s.db.Select("id", "api_key_id").Where("id = ? AND type = 'agent'", agentID).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("agent not found")
+		}
+		return fmt.Errorf("failed to get agent: %w", err)
+	}
+	if target.APIKeyID == nil || *target.APIKeyID != apiKeyID {
+		return ErrAgentCredentialMismatch
+	}
+	return nil
+}
+
+// EnqueueCommand 为Agent排队一条待执行命令，Agent在下次拉取时获取
+func (s *AgentService) EnqueueCommand(agentID uuid.UUID, command string, args []string, timeout int, executionID *uuid.UUID) (*AgentCommandResponse, error) {
+	var count int64
+	if err := s.db.Model(&models.MonitoringTarget{}).Where("id = ? AND type = 'agent'", agentID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check agent: %w", err)
+	}
+	if count == 0 {
+		return nil, errors.New("agent not found")
+	}
+
+	argsJSON, _ := json.Marshal(args)
+	cmd := models.AgentCommand{
+		AgentID:     agentID,
+		ExecutionID: executionID,
+		Command:     command,
+		Args:        string(argsJSON),
+		Timeout:     timeout,
+		Status:      "pending",
+	}
+	if err := s.db.Create(&cmd).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue agent command: %w", err)
+	}
+
+	return s.toAgentCommandResponse(&cmd), nil
+}
+
+// FetchPendingCommands 获取Agent待执行命令并标记为已下发
+func (s *AgentService) FetchPendingCommands(agentID uuid.UUID) ([]*AgentCommandResponse, error) {
+	var commands []models.AgentCommand
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ? AND status = ?", agentID, "pending").
+			Order("created_at ASC").
+			Find(&commands).Error; err != nil {
+			return fmt.Errorf("failed to query agent commands: %w", err)
+		}
+		if len(commands) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(commands))
+		now := time.Now()
+		for i := range commands {
+			ids[i] = commands[i].ID
+			commands[i].Status = "dispatched"
+			commands[i].DispatchedAt = &now
+		}
+		if err := tx.Model(&models.AgentCommand{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":        "dispatched",
+			"dispatched_at": now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to mark agent commands dispatched: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*AgentCommandResponse, len(commands))
+	for i := range commands {
+		responses[i] = s.toAgentCommandResponse(&commands[i])
+	}
+	return responses, nil
+}
+
+// ReportCommandResult 记录Agent上报的命令执行结果
+func (s *AgentService) ReportCommandResult(agentID, commandID uuid.UUID, req *AgentCommandResultRequest) (*AgentCommandResponse, error) {
+	var cmd models.AgentCommand
+	if err := s.db.Where("id = ? AND agent_id = ?", commandID, agentID).First(&cmd).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("agent command not found")
+		}
+		return nil, fmt.Errorf("failed to get agent command: %w", err)
+	}
+	if cmd.Status != "dispatched" {
+		return nil, fmt.Errorf("agent command is %s", cmd.Status)
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&cmd).Updates(map[string]interface{}{
+		"status":       req.Status,
+		"exit_code":    req.ExitCode,
+		"output":       req.Output,
+		"completed_at": now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update agent command: %w", err)
+	}
+	cmd.Status = req.Status
+	cmd.ExitCode = req.ExitCode
+	cmd.Output = req.Output
+	cmd.CompletedAt = &now
+
+	return s.toAgentCommandResponse(&cmd), nil
+}
+
+// WaitCommand 等待命令完成，超时后将命令标记为过期
+func (s *AgentService) WaitCommand(commandID uuid.UUID, timeout time.Duration) (*AgentCommandResponse, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var cmd models.AgentCommand
+		if err := s.db.First(&cmd, commandID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get agent command: %w", err)
+		}
+		if cmd.Status == "succeeded" || cmd.Status == "failed" {
+			return s.toAgentCommandResponse(&cmd), nil
+		}
+		if time.Now().After(deadline) {
+			s.db.Model(&cmd).Where("status IN ?", []string{"pending", "dispatched"}).Update("status", "expired")
+			return nil, errors.New("agent command timed out")
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// toAgentCommandResponse 转换为Agent命令响应格式
+func (s *AgentService) toAgentCommandResponse(cmd *models.AgentCommand) *AgentCommandResponse {
+	var args []string
+	if cmd.Args != "" {
+		json.Unmarshal([]byte(cmd.Args), &args)
+	}
+
+	return &AgentCommandResponse{
+		ID:           cmd.ID,
+		AgentID:      cmd.AgentID,
+		ExecutionID:  cmd.ExecutionID,
+		Command:      cmd.Command,
+		Args:         args,
+		Timeout:      cmd.Timeout,
+		Status:       cmd.Status,
+		ExitCode:     cmd.ExitCode,
+		Output:       cmd.Output,
+		DispatchedAt: cmd.DispatchedAt,
+		CompletedAt:  cmd.CompletedAt,
+		CreatedAt:    cmd.CreatedAt,
+	}
+}
@@ -145,6 +145,8 @@ type CreateAgentRequest struct {
 	Port         int                    `json:"port"`
 	Config       map[string]interface{} `json:"config"`
 	Tags         map[string]string      `json:"tags"`
+	// APIKeyID 由处理器根据认证信息填充，不从请求体读取
+	APIKeyID *uuid.UUID `json:"-"`
 }
 
 // UpdateAgentRequest 更新Agent请求
@@ -199,6 +201,7 @@ func (s *AgentService) CreateAgent(req *CreateAgentRequest) (*Agent, error) {
 		Credentials: string(configJSON),
 		Labels:      string(tagsJSON),
 		Status:      "pending",
+		APIKeyID:    req.APIKeyID,
 	}
 
 	if err := s.db.Create(&target).Error; err != nil {
@@ -0,0 +1,564 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// capacityResource 容量资源分类规则
+type capacityResource struct {
+	Name     string
+	Keywords []string
+	Limit    float64
+	// Absolute 指标本身为绝对量（如消费积压条数），无需百分比后缀即按上限预测
+	Absolute bool
+}
+
+// defaultCapacityResources 默认容量资源分类，容量上限可通过配置覆盖
+var defaultCapacityResources = []capacityResource{
+	{Name: "disk", Keywords: []string{"disk", "filesystem", "fs_usage", "volume"}, Limit: 100},
+	{Name: "memory", Keywords: []string{"memory", "mem_"}, Limit: 100},
+	{Name: "connection_pool", Keywords: []string{"conn_pool", "connection_pool", "pool_usage", "connections"}, Limit: 100},
+	{Name: "kafka_lag", Keywords: []string{"kafka_lag", "consumer_lag", "consumergroup_lag"}, Limit: 100000, Absolute: true},
+}
+
+var (
+	// capacityPercentSuffixes 百分比类指标后缀，未单独配置上限时只预测这类指标
+	capacityPercentSuffixes = []string{"_percent", "_pct"}
+	// capacityDecliningKeywords 剩余量类指标，数值下降才意味着容量耗尽
+	capacityDecliningKeywords = []string{"free", "available", "avail", "remaining"}
+)
+
+// capacityMetric 指标的容量分类结果
+type capacityMetric struct {
+	Resource string
+	Limit    float64
+	// Declining 剩余量类指标，趋势下降到下限即耗尽
+	Declining bool
+}
+
+// threshold 按阈值百分比计算预警线与比较条件，剩余量类指标以上限的剩余比例为下限
+func (m *capacityMetric) threshold(thresholdPercent float64) (float64, string) {
+	if m.Declining {
+		return m.Limit * (1 - thresholdPercent/100), "<="
+	}
+	return m.Limit * thresholdPercent / 100, ">="
+}
+
+// CapacityForecast 单个序列的容量耗尽预测
+type CapacityForecast struct {
+	TargetID           string     `json:"target_id"`
+	TargetName         string     `json:"target_name"`
+	Resource           string     `json:"resource"`
+	MetricName         string     `json:"metric_name"`
+	CurrentValue       float64    `json:"current_value"`
+	Limit              float64    `json:"limit"`
+	SlopePerDay        float64    `json:"slope_per_day"`
+	HoursToExhaustion  *float64   `json:"hours_to_exhaustion"`
+	ExhaustionTime     *time.Time `json:"exhaustion_time"`
+	EarliestExhaustion *time.Time `json:"earliest_exhaustion"`
+	Confidence         float64    `json:"confidence"`
+	RiskLevel          string     `json:"risk_level"`
+	DataPoints         int        `json:"data_points"`
+}
+
+// CapacityReport 容量预测报告
+type CapacityReport struct {
+	GeneratedAt      time.Time           `json:"generated_at"`
+	HistoryDays      int                 `json:"history_days"`
+	ThresholdPercent float64             `json:"threshold_percent"`
+	Forecasts        []*CapacityForecast `json:"forecasts"`
+}
+
+// capacitySeriesKey 容量序列标识
+type capacitySeriesKey struct {
+	TargetID uuid.UUID
+	Metric   string
+}
+
+// robustTrend Theil-Sen稳健回归结果
+type robustTrend struct {
+	Slope     float64 // 每小时变化量
+	Intercept float64
+	Scale     float64 // 残差稳健尺度
+	Fit       float64 // 稳健拟合优度
+}
+
+// classifyCapacityMetric 根据指标名判断所属容量资源
+// 默认只预测百分比类指标；字节数、计数器等绝对量指标需在limits中按指标名配置上限
+func (s *AIService) classifyCapacityMetric(metricName string) (*capacityMetric, bool) {
+	name := strings.ToLower(metricName)
+	metricLimit, hasMetricLimit := s.config.Alerting.CapacityPlanning.Limits[name]
+	hasMetricLimit = hasMetricLimit && metricLimit > 0
+
+	for _, resource := range defaultCapacityResources {
+		if !containsAny(name, resource.Keywords) {
+			continue
+		}
+
+		metric := &capacityMetric{
+			Resource:  resource.Name,
+			Limit:     s.capacityLimit(resource),
+			Declining: containsAny(name, capacityDecliningKeywords),
+		}
+		switch {
+		case hasMetricLimit:
+			metric.Limit = metricLimit
+		case resource.Absolute:
+		case hasAnySuffix(name, capacityPercentSuffixes):
+		default:
+			// 绝对量指标没有可比较的上限，跳过
+			return nil, false
+		}
+		return metric, true
+	}
+
+	if hasMetricLimit {
+		return &capacityMetric{
+			Resource:  "custom",
+			Limit:     metricLimit,
+			Declining: containsAny(name, capacityDecliningKeywords),
+		}, true
+	}
+	return nil, false
+}
+
+// containsAny 判断字符串是否包含任一关键字
+func containsAny(value string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnySuffix 判断字符串是否以任一后缀结尾
+func hasAnySuffix(value string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(value, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// capacityLimit 获取资源容量上限，配置优先
+func (s *AIService) capacityLimit(resource capacityResource) float64 {
+	if limit, ok := s.config.Alerting.CapacityPlanning.Limits[resource.Name]; ok && limit > 0 {
+		return limit
+	}
+	return resource.Limit
+}
+
+// capacityHistoryDays 将历史窗口限制在7到30天
+func (s *AIService) capacityHistoryDays(days int) int {
+	if days <= 0 {
+		days = s.config.Alerting.CapacityPlanning.HistoryDays
+	}
+	if days < 7 {
+		days = 7
+	}
+	if days > 30 {
+		days = 30
+	}
+	return days
+}
+
+// GetCapacityReport 生成按耗尽时间排序的容量预测报告
+func (s *AIService) GetCapacityReport(resource string, days int, thresholdPercent float64) (*CapacityReport, error) {
+	days = s.capacityHistoryDays(days)
+	if thresholdPercent <= 0 || thresholdPercent > 100 {
+		thresholdPercent = 100
+	}
+
+	cacheKey := cache.CapacityReportCacheKey(resource, days, thresholdPercent)
+	if s.cacheManager != nil {
+		var cached CapacityReport
+		if err := s.cacheManager.Get(context.Background(), cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	// 仅扫描最近一天仍在上报的序列
+	var keys []capacitySeriesKey
+	if err := s.db.Model(&models.MetricData{}).
+		Distinct("target_id", "metric").
+		Where("timestamp >= ?", time.Now().Add(-24*time.Hour)).
+		Scan(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query capacity series: %w", err)
+	}
+
+	forecasts := make([]*CapacityForecast, 0)
+	for _, key := range keys {
+		metric, ok := s.classifyCapacityMetric(key.Metric)
+		if !ok || (resource != "" && resource != metric.Resource) {
+			continue
+		}
+
+		threshold, condition := metric.threshold(thresholdPercent)
+		forecast, err := s.forecastCapacity(key.TargetID.String(), key.Metric, days, threshold, condition)
+		if err != nil {
+			return nil, err
+		}
+		if forecast == nil {
+			continue
+		}
+		forecast.Resource = metric.Resource
+		forecasts = append(forecasts, forecast)
+	}
+
+	s.fillCapacityTargetNames(forecasts)
+	sortCapacityForecasts(forecasts)
+
+	report := &CapacityReport{
+		GeneratedAt:      time.Now(),
+		HistoryDays:      days,
+		ThresholdPercent: thresholdPercent,
+		Forecasts:        forecasts,
+	}
+
+	if s.cacheManager != nil {
+		s.cacheManager.Set(context.Background(), cacheKey, report, 10*time.Minute)
+	}
+
+	return report, nil
+}
+
+// AnalyzeCapacity 容量规划分析
+func (s *AIService) AnalyzeCapacity(req *AIAnalysisRequest) (*AIAnalysisResponse, error) {
+	if req.TargetID == "" || req.MetricName == "" {
+		return nil, errors.New("target_id and metric_name are required for capacity planning")
+	}
+
+	metric, ok := s.classifyCapacityMetric(req.MetricName)
+	if !ok {
+		if req.Threshold <= 0 {
+			return nil, errors.New("threshold is required for non-capacity metrics")
+		}
+		metric = &capacityMetric{}
+	}
+	limit, condition := metric.threshold(100)
+	if req.Threshold > 0 {
+		limit = req.Threshold
+	}
+	if req.Condition != "" {
+		condition = req.Condition
+	}
+	resource := metric.Resource
+
+	forecast, err := s.forecastCapacity(req.TargetID, req.MetricName, s.capacityHistoryDays(0), limit, condition)
+	if err != nil {
+		return nil, err
+	}
+	if forecast == nil {
+		return nil, errors.New("insufficient history for capacity planning")
+	}
+	forecast.Resource = resource
+
+	metadata := map[string]interface{}{
+		"target_type":       req.TargetType,
+		"target_id":         req.TargetID,
+		"metric_name":       req.MetricName,
+		"capacity_forecast": forecast,
+	}
+
+	analysis := models.AIAnalysisResult{
+		AnalysisType: "capacity_planning",
+		Model:        "theil-sen",
+		Confidence:   forecast.Confidence,
+		Status:       "completed",
+	}
+	summary := describeCapacityForecast(forecast)
+	analysis.Response = summary
+
+	// 配置了AI模型时，由模型给出扩容建议
+	var parsed *ParsedAIResponse
+	if s.openaiClient != nil {
+		prompt := s.buildCapacityAnalysisContext(req, forecast)
+		analysis.Model = s.config.AIModels.OpenAI.Model
+		analysis.Prompt = prompt
+		structured, err := s.callStructured("", prompt, "capacity_planning")
+		if err != nil {
+			if errors.Is(err, ErrInvalidStructuredOutput) {
+				s.recordFailedAnalysis(&analysis, structured, err)
+			}
+			return nil, fmt.Errorf("failed to call AI model: %w", err)
+		}
+		parsed = structured.Parsed
+		analysis.Response = structured.Validated
+		applyStructuredResult(&analysis, structured)
+	} else {
+		parsed = &ParsedAIResponse{
+			RootCause:       summary,
+			Recommendations: []string{"根据预测耗尽时间提前规划扩容或清理"},
+			SeverityLevel:   forecast.RiskLevel,
+			ConfidenceScore: forecast.Confidence,
+		}
+	}
+
+	metadataJSON, _ := json.Marshal(metadata)
+	analysis.Metadata = string(metadataJSON)
+	if err := s.db.Create(&analysis).Error; err != nil {
+		return nil, fmt.Errorf("failed to save analysis result: %w", err)
+	}
+
+	return &AIAnalysisResponse{
+		ID:              analysis.ID,
+		Type:            analysis.AnalysisType,
+		TargetType:      req.TargetType,
+		TargetID:        req.TargetID,
+		AnalysisResult:  analysis.Response,
+		RootCause:       parsed.RootCause,
+		Recommendations: parsed.Recommendations,
+		SeverityLevel:   parsed.SeverityLevel,
+		ConfidenceScore: analysis.Confidence,
+		Tags:            req.Tags,
+		Metadata:        metadata,
+		CreatedAt:       analysis.CreatedAt,
+	}, nil
+}
+
+// forecastCapacity 对单个序列做稳健回归并计算到达阈值的时间，数据不足时返回nil
+func (s *AIService) forecastCapacity(targetID, metricName string, days int, threshold float64, condition string) (*CapacityForecast, error) {
+	series, err := s.getHistoricalMetrics("", targetID, metricName, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical data: %w", err)
+	}
+	if len(series.Values) < minBaselineSamples {
+		return nil, nil
+	}
+
+	trend := theilSen(series)
+	last := len(series.Values) - 1
+	lastX := float64(last) * series.Step.Hours()
+	current := series.Values[last]
+	fitted := trend.Intercept + trend.Slope*lastX
+
+	forecast := &CapacityForecast{
+		TargetID:     targetID,
+		MetricName:   metricName,
+		CurrentValue: current,
+		Limit:        threshold,
+		SlopePerDay:  trend.Slope * 24,
+		Confidence:   trend.Fit,
+		DataPoints:   len(series.Values),
+	}
+
+	lastTime := series.TimestampAt(last)
+	if hours, ok := hoursToThreshold(fitted, trend.Slope, threshold, condition); ok {
+		// 当前值已越线时直接视为耗尽
+		if crossesThreshold(current, threshold, condition) {
+			hours = 0
+		}
+		exhaustion := lastTime.Add(time.Duration(hours * float64(time.Hour)))
+		forecast.HoursToExhaustion = &hours
+		forecast.ExhaustionTime = &exhaustion
+	} else if crossesThreshold(current, threshold, condition) {
+		hours := 0.0
+		forecast.HoursToExhaustion = &hours
+		forecast.ExhaustionTime = &lastTime
+	}
+
+	// 以残差上沿估计最早耗尽时间
+	band := 2 * trend.Scale
+	if condition == "<" || condition == "<=" {
+		band = -band
+	}
+	if hours, ok := hoursToThreshold(fitted+band, trend.Slope, threshold, condition); ok {
+		earliest := lastTime.Add(time.Duration(hours * float64(time.Hour)))
+		forecast.EarliestExhaustion = &earliest
+	}
+
+	forecast.RiskLevel = capacityRiskLevel(forecast.HoursToExhaustion)
+	return forecast, nil
+}
+
+// theilSen 以成对斜率中位数估计趋势，对离群点不敏感
+func theilSen(series *HistoricalSeries) *robustTrend {
+	data := series.Values
+	n := len(data)
+	stepHours := series.Step.Hours()
+
+	// 数据点较多时抽样以控制成对斜率数量
+	stride := 1
+	if n > 400 {
+		stride = n / 400
+	}
+
+	slopes := make([]float64, 0, (n/stride)*(n/stride)/2)
+	for i := 0; i < n; i += stride {
+		for j := i + stride; j < n; j += stride {
+			slopes = append(slopes, (data[j]-data[i])/(float64(j-i)*stepHours))
+		}
+	}
+	slope := calculateMedian(slopes)
+
+	intercepts := make([]float64, n)
+	for i, v := range data {
+		intercepts[i] = v - slope*float64(i)*stepHours
+	}
+	intercept := calculateMedian(intercepts)
+
+	residuals := make([]float64, n)
+	for i, v := range data {
+		residuals[i] = v - (intercept + slope*float64(i)*stepHours)
+	}
+	scale := madScaleFactor * calculateMAD(residuals, calculateMedian(residuals))
+
+	// 稳健拟合优度：1 - (残差MAD / 原始MAD)^2
+	fit := 0.0
+	if dataScale := madScaleFactor * calculateMAD(data, calculateMedian(data)); dataScale > 0 {
+		fit = math.Max(0, math.Min(1, 1-(scale/dataScale)*(scale/dataScale)))
+	}
+
+	return &robustTrend{Slope: slope, Intercept: intercept, Scale: scale, Fit: fit}
+}
+
+// hoursToThreshold 按线性趋势计算到达阈值所需小时数，趋势背离阈值时返回false
+func hoursToThreshold(current, slopePerHour, threshold float64, condition string) (float64, bool) {
+	if crossesThreshold(current, threshold, condition) {
+		return 0, true
+	}
+	if slopePerHour == 0 {
+		return 0, false
+	}
+	hours := (threshold - current) / slopePerHour
+	if hours < 0 {
+		return 0, false
+	}
+	return hours, true
+}
+
+// crossesThreshold 判断数值是否已越过阈值
+func crossesThreshold(value, threshold float64, condition string) bool {
+	switch condition {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	default:
+		return value >= threshold
+	}
+}
+
+// capacityRiskLevel 根据耗尽时间评估风险级别
+func capacityRiskLevel(hours *float64) string {
+	switch {
+	case hours == nil:
+		return "low"
+	case *hours <= 24:
+		return "critical"
+	case *hours <= 72:
+		return "high"
+	case *hours <= 168:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// sortCapacityForecasts 按耗尽时间升序排序，无耗尽风险的排在最后
+func sortCapacityForecasts(forecasts []*CapacityForecast) {
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		a, b := forecasts[i].HoursToExhaustion, forecasts[j].HoursToExhaustion
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+}
+
+// fillCapacityTargetNames 批量填充监控目标名称
+func (s *AIService) fillCapacityTargetNames(forecasts []*CapacityForecast) {
+	if len(forecasts) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(forecasts))
+	for _, f := range forecasts {
+		ids = append(ids, f.TargetID)
+	}
+
+	var targets []models.MonitoringTarget
+	if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&targets).Error; err != nil {
+		return
+	}
+
+	names := make(map[string]string, len(targets))
+	for _, t := range targets {
+		names[t.ID.String()] = t.Name
+	}
+	for _, f := range forecasts {
+		f.TargetName = names[f.TargetID]
+	}
+}
+
+// describeCapacityForecast 生成容量预测摘要
+func describeCapacityForecast(f *CapacityForecast) string {
+	if f.HoursToExhaustion == nil {
+		return fmt.Sprintf("%s 当前值 %.2f，日均变化 %.2f，预测期内不会达到上限 %.2f",
+			f.MetricName, f.CurrentValue, f.SlopePerDay, f.Limit)
+	}
+	return fmt.Sprintf("%s 当前值 %.2f，日均变化 %.2f，预计 %.1f 小时后（%s）达到上限 %.2f",
+		f.MetricName, f.CurrentValue, f.SlopePerDay, *f.HoursToExhaustion,
+		f.ExhaustionTime.Format("2006-01-02 15:04"), f.Limit)
+}
+
+// buildCapacityAnalysisContext 构建容量规划分析上下文
+func (s *AIService) buildCapacityAnalysisContext(req *AIAnalysisRequest, f *CapacityForecast) string {
+	exhaustion := "预测期内不会耗尽"
+	if f.ExhaustionTime != nil {
+		exhaustion = f.ExhaustionTime.Format("2006-01-02 15:04:05")
+	}
+
+	return fmt.Sprintf(`
+请基于以下容量预测结果进行容量规划分析：
+
+=== 容量预测 ===
+- 目标类型：%s
+- 目标ID：%s
+- 指标名称：%s
+- 资源类型：%s
+- 当前值：%.2f
+- 容量上限：%.2f
+- 日均增长：%.4f
+- 预计耗尽时间：%s
+- 风险级别：%s
+- 拟合优度：%.2f（样本点数：%d）
+
+=== 请提供以下分析 ===
+1. 增长原因分析
+2. 耗尽后的业务影响评估
+3. 扩容、清理或限流建议
+4. 风险级别（critical/high/medium/low）
+5. 分析置信度评分（0-1之间的小数）
+
+请以JSON格式返回分析结果，格式如下：
+{
+  "root_cause": "增长原因分析",
+  "impact_assessment": "业务影响评估",
+  "recommendations": ["建议1", "建议2"],
+  "prevention_measures": "长期容量管理建议",
+  "severity_level": "风险级别",
+  "confidence_score": 0.85
+}
+`,
+		req.TargetType, req.TargetID, f.MetricName, f.Resource,
+		f.CurrentValue, f.Limit, f.SlopePerDay, exhaustion,
+		f.RiskLevel, f.Confidence, f.DataPoints,
+	)
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultPromptVersion 未指定提示词版本时记录的版本号
+const defaultPromptVersion = "v1"
+
+// AnalysisFeedbackRequest AI分析反馈请求
+type AnalysisFeedbackRequest struct {
+	Rating             string `json:"rating" binding:"required,oneof=up down"`
+	CorrectedRootCause string `json:"corrected_root_cause" binding:"max=5000"`
+	Comment            string `json:"comment" binding:"max=1000"`
+}
+
+// AnalysisFeedbackResponse AI分析反馈响应
+type AnalysisFeedbackResponse struct {
+	ID                 uuid.UUID `json:"id"`
+	AnalysisID         uuid.UUID `json:"analysis_id"`
+	UserID             uuid.UUID `json:"user_id"`
+	Rating             string    `json:"rating"`
+	CorrectedRootCause string    `json:"corrected_root_cause"`
+	Comment            string    `json:"comment"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// AnalysisAccuracyReport 按模型和提示词版本统计的分析准确率
+type AnalysisAccuracyReport struct {
+	Since time.Time                `json:"since"`
+	Items []*AnalysisAccuracyEntry `json:"items"`
+}
+
+// AnalysisAccuracyEntry 单个模型/提示词版本的准确率
+type AnalysisAccuracyEntry struct {
+	Model         string  `json:"model"`
+	PromptVersion string  `json:"prompt_version"`
+	AnalysisType  string  `json:"analysis_type"`
+	Analyses      int64   `json:"analyses"`
+	Rated         int64   `json:"rated"`
+	Up            int64   `json:"up"`
+	Down          int64   `json:"down"`
+	Corrected     int64   `json:"corrected"`
+	Accuracy      float64 `json:"accuracy"`      // up / rated
+	FeedbackRate  float64 `json:"feedback_rate"` // rated / analyses
+}
+
+// SubmitAnalysisFeedback 提交或更新对分析的评价，每个用户对同一分析保留一条反馈
+func (s *AIService) SubmitAnalysisFeedback(analysisID, userID uuid.UUID, req *AnalysisFeedbackRequest) (*AnalysisFeedbackResponse, error) {
+	var analysis models.AIAnalysisResult
+	if err := s.db.First(&analysis, analysisID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("analysis not found")
+		}
+		return nil, fmt.Errorf("failed to get analysis: %w", err)
+	}
+
+	var feedback models.AIAnalysisFeedback
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("analysis_id = ? AND user_id = ?", analysisID, userID).First(&feedback).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get feedback: %w", err)
+		}
+
+		feedback.AnalysisID = analysisID
+		feedback.UserID = userID
+		feedback.Rating = req.Rating
+		feedback.CorrectedRootCause = strings.TrimSpace(req.CorrectedRootCause)
+		feedback.Comment = req.Comment
+		if err := tx.Save(&feedback).Error; err != nil {
+			return fmt.Errorf("failed to save feedback: %w", err)
+		}
+
+		return s.syncIncidentMemory(tx, &analysis, &feedback)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toFeedbackResponse(&feedback), nil
+}
+
+// ListAnalysisFeedback 获取分析的全部反馈
+func (s *AIService) ListAnalysisFeedback(analysisID uuid.UUID) ([]*AnalysisFeedbackResponse, error) {
+	var feedbacks []models.AIAnalysisFeedback
+	if err := s.db.Where("analysis_id = ?", analysisID).Order("created_at DESC").Find(&feedbacks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list feedback: %w", err)
+	}
+
+	responses := make([]*AnalysisFeedbackResponse, len(feedbacks))
+	for i := range feedbacks {
+		responses[i] = toFeedbackResponse(&feedbacks[i])
+	}
+	return responses, nil
+}
+
+// GetAnalysisAccuracy 统计各模型和提示词版本的分析准确率，用于判断提示词调整是否变差
+func (s *AIService) GetAnalysisAccuracy(since time.Time, analysisType string) (*AnalysisAccuracyReport, error) {
+	query := s.db.Table("ai_analysis_results r").
+		Select(`r.model, COALESCE(r.prompt_version, ?) AS prompt_version, r.analysis_type,
+			COUNT(DISTINCT r.id) AS analyses,
+			COUNT(DISTINCT CASE WHEN f.id IS NOT NULL THEN r.id END) AS rated,
+			COUNT(DISTINCT CASE WHEN f.rating = 'up' THEN f.id END) AS up,
+			COUNT(DISTINCT CASE WHEN f.rating = 'down' THEN f.id END) AS down,
+			COUNT(DISTINCT CASE WHEN f.corrected_root_cause <> '' THEN f.id END) AS corrected`, defaultPromptVersion).
+		Joins("LEFT JOIN ai_analysis_feedback f ON f.analysis_id = r.id AND f.deleted_at IS NULL").
+		Where("r.deleted_at IS NULL AND r.created_at >= ?", since)
+	if analysisType != "" {
+		query = query.Where("r.analysis_type = ?", analysisType)
+	}
+
+	var items []*AnalysisAccuracyEntry
+	if err := query.Group("r.model, r.prompt_version, r.analysis_type").Scan(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute analysis accuracy: %w", err)
+	}
+
+	for _, item := range items {
+		// 同一分析可能有多人评价，准确率按反馈条数计算
+		if total := item.Up + item.Down; total > 0 {
+			item.Accuracy = float64(item.Up) / float64(total)
+		}
+		if item.Analyses > 0 {
+			item.FeedbackRate = float64(item.Rated) / float64(item.Analyses)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Model != items[j].Model {
+			return items[i].Model < items[j].Model
+		}
+		return items[i].PromptVersion < items[j].PromptVersion
+	})
+
+	return &AnalysisAccuracyReport{Since: since, Items: items}, nil
+}
+
+// syncIncidentMemory 根据反馈维护故障记忆：好评或修正根因视为确认，差评且无修正则撤销
+func (s *AIService) syncIncidentMemory(tx *gorm.DB, analysis *models.AIAnalysisResult, feedback *models.AIAnalysisFeedback) error {
+	rootCause := feedback.CorrectedRootCause
+	if rootCause == "" && feedback.Rating == "up" {
+		if parsed, err := s.parseAIResponse(analysis.Response); err == nil {
+			rootCause = parsed.RootCause
+		}
+	}
+
+	var memory models.IncidentMemory
+	err := tx.Where("analysis_id = ?", analysis.ID).First(&memory).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get incident memory: %w", err)
+	}
+	exists := err == nil
+
+	if rootCause == "" {
+		if exists {
+			// analysis_id 为唯一索引，需物理删除以便之后重新确认
+			if err := tx.Unscoped().Delete(&memory).Error; err != nil {
+				return fmt.Errorf("failed to delete incident memory: %w", err)
+			}
+		}
+		return nil
+	}
+
+	memory.AnalysisID = analysis.ID
+	memory.RootCause = rootCause
+	memory.Corrected = feedback.CorrectedRootCause != ""
+	memory.ConfirmedBy = feedback.UserID
+
+	if analysis.AlertID != uuid.Nil {
+		var alert models.Alert
+		if err := tx.First(&alert, analysis.AlertID).Error; err == nil {
+			memory.AlertID = &alert.ID
+			memory.RuleID = &alert.RuleID
+			memory.Summary = alert.Summary
+			memory.Labels = jsonStringList(alertLabelSet(alert.Labels))
+		}
+	}
+
+	if err := tx.Save(&memory).Error; err != nil {
+		return fmt.Errorf("failed to save incident memory: %w", err)
+	}
+	return nil
+}
+
+// incidentMemoryExamples 检索相似告警（同规则、同标签、相似摘要）的已确认根因作为few-shot示例
+func (s *AIService) incidentMemoryExamples(req *AIAnalysisRequest) string {
+	var ruleID uuid.UUID
+	labels := map[string]bool{}
+	tokens := map[string]bool{}
+
+	if req.AlertID != uuid.Nil {
+		var alert models.Alert
+		if err := s.db.First(&alert, req.AlertID).Error; err == nil {
+			ruleID = alert.RuleID
+			labels = alertLabelSet(alert.Labels)
+			tokens = tokenizeSummary(alert.Summary)
+		}
+	}
+	if ruleID == uuid.Nil {
+		ruleID = req.RuleID
+	}
+	for key, value := range req.Tags {
+		if value != nil {
+			labels[key+"="+fmt.Sprint(value)] = true
+		}
+	}
+
+	var candidates []models.IncidentMemory
+	query := s.db.Model(&models.IncidentMemory{}).Order("updated_at DESC").Limit(200)
+	if ruleID != uuid.Nil {
+		query = query.Where("rule_id = ? OR updated_at >= ?", ruleID, time.Now().AddDate(0, 0, -90))
+	} else {
+		query = query.Where("updated_at >= ?", time.Now().AddDate(0, 0, -90))
+	}
+	if err := query.Find(&candidates).Error; err != nil || len(candidates) == 0 {
+		return ""
+	}
+
+	type scored struct {
+		memory *models.IncidentMemory
+		score  float64
+	}
+	matches := make([]scored, 0)
+	for i := range candidates {
+		memory := &candidates[i]
+		if req.AlertID != uuid.Nil && memory.AlertID != nil && *memory.AlertID == req.AlertID {
+			continue
+		}
+
+		score := 0.3*jaccard(labels, jsonStringSet(memory.Labels)) + 0.3*jaccard(tokens, tokenizeSummary(memory.Summary))
+		if ruleID != uuid.Nil && memory.RuleID != nil && *memory.RuleID == ruleID {
+			score += 0.4
+		}
+		if score >= 0.3 {
+			matches = append(matches, scored{memory, score})
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+
+	var b strings.Builder
+	b.WriteString("=== 相似告警的已确认根因（供参考，请结合本次数据判断是否适用）===\n")
+	ids := make([]uuid.UUID, len(matches))
+	for i, match := range matches {
+		ids[i] = match.memory.ID
+		b.WriteString(fmt.Sprintf("示例%d（相似度 %.2f）\n", i+1, match.score))
+		if match.memory.Summary != "" {
+			b.WriteString(fmt.Sprintf("- 告警：%s\n", match.memory.Summary))
+		}
+		if labels := jsonStrings(match.memory.Labels); len(labels) > 0 {
+			b.WriteString(fmt.Sprintf("- 标签：%s\n", strings.Join(labels, ", ")))
+		}
+		b.WriteString(fmt.Sprintf("- 确认根因：%s\n", match.memory.RootCause))
+	}
+	s.db.Model(&models.IncidentMemory{}).Where("id IN ?", ids).UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+
+	return b.String()
+}
+
+// alertLabelSet 将告警标签转换为 key=value 集合，忽略对同类告警都相同的指标名和规则类型
+func alertLabelSet(raw string) map[string]bool {
+	set := map[string]bool{}
+	var labels map[string]interface{}
+	if raw == "" || json.Unmarshal([]byte(raw), &labels) != nil {
+		return set
+	}
+	for key, value := range labels {
+		if value == nil || key == "metric" || key == "kind" {
+			continue
+		}
+		set[key+"="+fmt.Sprint(value)] = true
+	}
+	return set
+}
+
+// toFeedbackResponse 转换为反馈响应格式
+func toFeedbackResponse(feedback *models.AIAnalysisFeedback) *AnalysisFeedbackResponse {
+	return &AnalysisFeedbackResponse{
+		ID:                 feedback.ID,
+		AnalysisID:         feedback.AnalysisID,
+		UserID:             feedback.UserID,
+		Rating:             feedback.Rating,
+		CorrectedRootCause: feedback.CorrectedRootCause,
+		Comment:            feedback.Comment,
+		CreatedAt:          feedback.CreatedAt,
+		UpdatedAt:          feedback.UpdatedAt,
+	}
+}
@@ -0,0 +1,760 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// historicalStep 历史数据对齐步长
+	historicalStep = time.Hour
+	// seasonalityACFThreshold 自相关系数超过该值才认为存在季节性
+	seasonalityACFThreshold = 0.3
+	// robustZScoreThreshold 稳健Z分数的默认异常阈值（Iglewicz-Hoaglin建议值）
+	robustZScoreThreshold = 3.5
+	// maxAnomalyScore 离散度为0时的异常评分上限
+	maxAnomalyScore = 10.0
+	// madScaleFactor MAD到标准差的换算系数
+	madScaleFactor = 1.4826
+	// predictionIntervalZ 95%预测区间对应的Z值
+	predictionIntervalZ = 1.96
+)
+
+// HistoricalSeries 按固定步长对齐的历史指标序列
+type HistoricalSeries struct {
+	Start  time.Time     `json:"start"`
+	Step   time.Duration `json:"step"`
+	Values []float64     `json:"values"`
+}
+
+// TimestampAt 返回第i个数据点的时间
+func (hs *HistoricalSeries) TimestampAt(i int) time.Time {
+	return hs.Start.Add(time.Duration(i) * hs.Step)
+}
+
+// ForecastRequest 指标预测请求
+type ForecastRequest struct {
+	TargetType   string  `json:"target_type"`
+	TargetID     string  `json:"target_id" binding:"required"`
+	MetricName   string  `json:"metric_name" binding:"required"`
+	HistoryDays  int     `json:"history_days"`
+	Horizon      int     `json:"horizon"`
+	Threshold    float64 `json:"threshold"`
+	Condition    string  `json:"condition"`
+	CurrentValue float64 `json:"current_value"`
+}
+
+// ForecastResponse 指标预测响应
+type ForecastResponse struct {
+	ID         uuid.UUID               `json:"id"`
+	TargetType string                  `json:"target_type"`
+	TargetID   string                  `json:"target_id"`
+	MetricName string                  `json:"metric_name"`
+	Prediction *TrendPredictionResult  `json:"prediction"`
+	Anomaly    *AnomalyDetectionResult `json:"anomaly,omitempty"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+// ForecastMetric 基于真实历史数据预测指标走势并持久化结果
+func (s *AIService) ForecastMetric(req *ForecastRequest) (*ForecastResponse, error) {
+	if req.HistoryDays <= 0 {
+		req.HistoryDays = 7
+	}
+	if req.Horizon <= 0 {
+		req.Horizon = 6
+	}
+
+	series, err := s.getHistoricalMetrics(req.TargetType, req.TargetID, req.MetricName, req.HistoryDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical data: %w", err)
+	}
+
+	currentValue := req.CurrentValue
+	if currentValue == 0 && len(series.Values) > 0 {
+		currentValue = series.Values[len(series.Values)-1]
+	}
+
+	prediction := s.forecastSeries(series, req.Horizon, currentValue, req.Threshold, req.Condition)
+	anomaly := s.scoreAnomaly(series, currentValue, time.Now(), robustZScoreThreshold)
+
+	predictionJSON, _ := json.Marshal(prediction)
+	metadata := map[string]interface{}{
+		"target_type":       req.TargetType,
+		"target_id":         req.TargetID,
+		"metric_name":       req.MetricName,
+		"current_value":     currentValue,
+		"threshold":         req.Threshold,
+		"condition":         req.Condition,
+		"history_days":      req.HistoryDays,
+		"anomaly_detection": anomaly,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	analysis := models.AIAnalysisResult{
+		AnalysisType: "trend_analysis",
+		Model:        prediction.Method,
+		Response:     string(predictionJSON),
+		Confidence:   prediction.Accuracy,
+		Status:       "completed",
+		Metadata:     string(metadataJSON),
+	}
+	if err := s.db.Create(&analysis).Error; err != nil {
+		return nil, fmt.Errorf("failed to save forecast result: %w", err)
+	}
+
+	return &ForecastResponse{
+		ID:         analysis.ID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		MetricName: req.MetricName,
+		Prediction: prediction,
+		Anomaly:    anomaly,
+		CreatedAt:  analysis.CreatedAt,
+	}, nil
+}
+
+// errMonitoringTargetNotFound 目标ID或名称无法解析为监控目标
+var errMonitoringTargetNotFound = errors.New("monitoring target not found")
+
+// resolveTargetID 将目标ID或目标名称解析为监控目标UUID
+func (s *AIService) resolveTargetID(targetType, targetID string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(targetID); err == nil {
+		return id, nil
+	}
+
+	var target models.MonitoringTarget
+	query := s.db.Where("name = ?", targetID)
+	if targetType != "" {
+		query = query.Where("type = ?", targetType)
+	}
+	if err := query.First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, errMonitoringTargetNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to get monitoring target: %w", err)
+	}
+	return target.ID, nil
+}
+
+// getHistoricalMetrics 从指标存储中获取按小时对齐的历史数据
+// 目标无法解析时视为没有历史数据，返回空序列，由调用方按数据不足处理
+func (s *AIService) getHistoricalMetrics(targetType, targetID, metricName string, days int) (*HistoricalSeries, error) {
+	id, err := s.resolveTargetID(targetType, targetID)
+	if err != nil {
+		if errors.Is(err, errMonitoringTargetNotFound) {
+			return &HistoricalSeries{Step: historicalStep}, nil
+		}
+		return nil, err
+	}
+
+	var rows []models.MetricData
+	since := time.Now().AddDate(0, 0, -days)
+	if err := s.db.Select("value", "timestamp").
+		Where("target_id = ? AND metric = ? AND timestamp >= ?", id, metricName, since).
+		Order("timestamp ASC").
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query metric data: %w", err)
+	}
+
+	return alignSeries(rows, historicalStep), nil
+}
+
+// alignSeries 将不规则采样点按步长分桶求均值，并线性插值填补空桶
+func alignSeries(rows []models.MetricData, step time.Duration) *HistoricalSeries {
+	series := &HistoricalSeries{Step: step}
+	if len(rows) == 0 {
+		return series
+	}
+
+	start := rows[0].Timestamp.Truncate(step)
+	end := rows[len(rows)-1].Timestamp.Truncate(step)
+	size := int(end.Sub(start)/step) + 1
+
+	sums := make([]float64, size)
+	counts := make([]int, size)
+	for _, row := range rows {
+		idx := int(row.Timestamp.Truncate(step).Sub(start) / step)
+		if idx < 0 || idx >= size {
+			continue
+		}
+		sums[idx] += row.Value
+		counts[idx]++
+	}
+
+	values := make([]float64, size)
+	last := -1
+	for i := 0; i < size; i++ {
+		if counts[i] == 0 {
+			continue
+		}
+		values[i] = sums[i] / float64(counts[i])
+		// 填补上一个有效点与当前点之间的空桶
+		if last >= 0 && i-last > 1 {
+			for j := last + 1; j < i; j++ {
+				ratio := float64(j-last) / float64(i-last)
+				values[j] = values[last] + (values[i]-values[last])*ratio
+			}
+		}
+		last = i
+	}
+
+	series.Start = start
+	series.Values = values
+	return series
+}
+
+// detectSeasonality 基于自相关函数检测季节周期，返回周期长度及其自相关系数
+func (s *AIService) detectSeasonality(data []float64) (int, float64) {
+	n := len(data)
+	if n < 8 {
+		return 0, 0
+	}
+
+	mean := s.calculateMean(data)
+	variance := 0.0
+	for _, v := range data {
+		variance += (v - mean) * (v - mean)
+	}
+	if variance == 0 {
+		return 0, 0
+	}
+
+	// 至少需要两个完整周期
+	maxLag := n / 2
+	acf := make([]float64, maxLag+2)
+	for lag := 1; lag <= maxLag+1 && lag < n; lag++ {
+		sum := 0.0
+		for i := 0; i+lag < n; i++ {
+			sum += (data[i] - mean) * (data[i+lag] - mean)
+		}
+		acf[lag] = sum / variance
+	}
+
+	bestLag, bestACF := 0, 0.0
+	for lag := 2; lag <= maxLag; lag++ {
+		// 只考虑自相关函数的局部峰值
+		if acf[lag] < acf[lag-1] || acf[lag] < acf[lag+1] {
+			continue
+		}
+		if acf[lag] > bestACF {
+			bestLag, bestACF = lag, acf[lag]
+		}
+	}
+
+	if bestACF < seasonalityACFThreshold {
+		return 0, bestACF
+	}
+	return bestLag, bestACF
+}
+
+// holtWintersModel 加法Holt-Winters模型
+type holtWintersModel struct {
+	alpha  float64
+	beta   float64
+	gamma  float64
+	period int
+	level  float64
+	trend  float64
+	season []float64
+	sse    float64
+	mae    float64
+	n      int
+	length int
+}
+
+// fitHoltWinters 使用给定平滑参数拟合模型，period为0时退化为Holt线性趋势模型
+func fitHoltWinters(data []float64, period int, alpha, beta, gamma float64) *holtWintersModel {
+	m := &holtWintersModel{alpha: alpha, beta: beta, gamma: gamma, period: period, length: len(data)}
+
+	start := 1
+	if period > 0 {
+		first, second := 0.0, 0.0
+		for i := 0; i < period; i++ {
+			first += data[i]
+			second += data[period+i]
+		}
+		first /= float64(period)
+		second /= float64(period)
+
+		m.level = first
+		m.trend = (second - first) / float64(period)
+		m.season = make([]float64, period)
+		for i := 0; i < period; i++ {
+			m.season[i] = data[i] - first
+		}
+		start = period
+	} else {
+		m.level = data[0]
+		m.trend = data[1] - data[0]
+	}
+
+	absErr := 0.0
+	for t := start; t < len(data); t++ {
+		seasonal := 0.0
+		if period > 0 {
+			seasonal = m.season[t%period]
+		}
+
+		forecast := m.level + m.trend + seasonal
+		err := data[t] - forecast
+		m.sse += err * err
+		absErr += math.Abs(err)
+		m.n++
+
+		prevLevel := m.level
+		m.level = alpha*(data[t]-seasonal) + (1-alpha)*(m.level+m.trend)
+		m.trend = beta*(m.level-prevLevel) + (1-beta)*m.trend
+		if period > 0 {
+			m.season[t%period] = gamma*(data[t]-m.level) + (1-gamma)*seasonal
+		}
+	}
+	if m.n > 0 {
+		m.mae = absErr / float64(m.n)
+	}
+
+	return m
+}
+
+// bestHoltWinters 网格搜索使一步预测误差平方和最小的平滑参数
+func bestHoltWinters(data []float64, period int) *holtWintersModel {
+	alphas := []float64{0.1, 0.3, 0.5, 0.7, 0.9}
+	betas := []float64{0.01, 0.05, 0.1, 0.2}
+	gammas := []float64{0}
+	if period > 0 {
+		gammas = []float64{0.05, 0.1, 0.3, 0.5}
+	}
+
+	var best *holtWintersModel
+	for _, alpha := range alphas {
+		for _, beta := range betas {
+			for _, gamma := range gammas {
+				m := fitHoltWinters(data, period, alpha, beta, gamma)
+				if best == nil || m.sse < best.sse {
+					best = m
+				}
+			}
+		}
+	}
+	return best
+}
+
+// forecast 预测第h步的值（h从1开始）
+func (m *holtWintersModel) forecast(h int) float64 {
+	value := m.level + float64(h)*m.trend
+	if m.period > 0 {
+		value += m.season[(m.length-1+h)%m.period]
+	}
+	return value
+}
+
+// intervalWidth 第h步预测区间半宽
+func (m *holtWintersModel) intervalWidth(h int) float64 {
+	if m.n == 0 {
+		return 0
+	}
+	sigma := math.Sqrt(m.sse / float64(m.n))
+
+	variance := 1.0
+	for j := 1; j < h; j++ {
+		c := m.alpha * (1 + float64(j)*m.beta)
+		if m.period > 0 && j%m.period == 0 {
+			c += m.gamma
+		}
+		variance += c * c
+	}
+	return predictionIntervalZ * sigma * math.Sqrt(variance)
+}
+
+// predictTrend 趋势预测分析
+func (s *AIService) predictTrend(req *AIAnalysisRequest) (*TrendPredictionResult, error) {
+	series, err := s.getHistoricalMetrics(req.TargetType, req.TargetID, req.MetricName, 7) // 获取7天历史数据
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical data: %w", err)
+	}
+
+	return s.forecastSeries(series, 6, req.CurrentValue, req.Threshold, req.Condition), nil
+}
+
+// forecastSeries 对对齐后的序列进行Holt-Winters预测
+func (s *AIService) forecastSeries(series *HistoricalSeries, horizon int, currentValue, threshold float64, condition string) *TrendPredictionResult {
+	timeHorizon := (time.Duration(horizon) * series.Step).String()
+
+	if len(series.Values) < 5 {
+		// 数据不足，返回基础预测结果
+		return &TrendPredictionResult{
+			TrendDirection:  "stable",
+			PredictedValues: []float64{currentValue},
+			RiskLevel:       "low",
+			TimeHorizon:     timeHorizon,
+			Accuracy:        0.5,
+			Method:          "insufficient_data",
+			DataPoints:      len(series.Values),
+		}
+	}
+
+	period, strength := s.detectSeasonality(series.Values)
+	if period > 0 && len(series.Values) < 2*period+1 {
+		period = 0
+	}
+
+	model := bestHoltWinters(series.Values, period)
+	method := "holt_linear"
+	if period > 0 {
+		method = "holt_winters_additive"
+	}
+
+	lastTime := series.TimestampAt(len(series.Values) - 1)
+	predicted := make([]float64, horizon)
+	lower := make([]float64, horizon)
+	upper := make([]float64, horizon)
+	timestamps := make([]time.Time, horizon)
+	for h := 1; h <= horizon; h++ {
+		value := model.forecast(h)
+		width := model.intervalWidth(h)
+		predicted[h-1] = value
+		lower[h-1] = value - width
+		upper[h-1] = value + width
+		timestamps[h-1] = lastTime.Add(time.Duration(h) * series.Step)
+	}
+
+	// 以一步预测误差衡量趋势变化是否显著
+	sigma := 0.0
+	if model.n > 0 {
+		sigma = math.Sqrt(model.sse / float64(model.n))
+	}
+	change := model.trend * float64(horizon)
+	trendDirection := "stable"
+	if math.Abs(change) > sigma {
+		if change > 0 {
+			trendDirection = "increasing"
+		} else {
+			trendDirection = "decreasing"
+		}
+	}
+
+	// 预测准确度：1 - 平均绝对误差 / 平均绝对值
+	accuracy := 0.5
+	if meanAbs := meanAbsolute(series.Values); meanAbs > 0 {
+		accuracy = math.Max(0, math.Min(0.99, 1-model.mae/meanAbs))
+	}
+
+	return &TrendPredictionResult{
+		TrendDirection:     trendDirection,
+		PredictedValues:    predicted,
+		ConfidenceInterval: []float64{lower[horizon-1], upper[horizon-1]},
+		LowerBounds:        lower,
+		UpperBounds:        upper,
+		Timestamps:         timestamps,
+		Seasonality:        period > 0,
+		SeasonalPeriod:     period,
+		SeasonalStrength:   strength,
+		RiskLevel:          evaluateTrendRisk(predicted, upper, lower, threshold, condition, change, sigma),
+		TimeHorizon:        timeHorizon,
+		Accuracy:           accuracy,
+		Method:             method,
+		DataPoints:         len(series.Values),
+	}
+}
+
+// evaluateTrendRisk 根据预测值及预测区间是否越过阈值评估风险级别
+func evaluateTrendRisk(predicted, upper, lower []float64, threshold float64, condition string, change, sigma float64) string {
+	if threshold != 0 {
+		crosses := func(values []float64) bool {
+			for _, v := range values {
+				switch condition {
+				case "<", "<=", "lt", "lte":
+					if v <= threshold {
+						return true
+					}
+				default:
+					if v >= threshold {
+						return true
+					}
+				}
+			}
+			return false
+		}
+
+		bound := upper
+		if condition == "<" || condition == "<=" || condition == "lt" || condition == "lte" {
+			bound = lower
+		}
+		if crosses(predicted) {
+			return "high"
+		}
+		if crosses(bound) {
+			return "medium"
+		}
+		return "low"
+	}
+
+	if sigma > 0 && math.Abs(change) > 3*sigma {
+		return "high"
+	}
+	if sigma > 0 && math.Abs(change) > 1.5*sigma {
+		return "medium"
+	}
+	return "low"
+}
+
+// detectAnomaly 异常检测算法
+func (s *AIService) detectAnomaly(req *AIAnalysisRequest) (*AnomalyDetectionResult, error) {
+	series, err := s.getHistoricalMetrics(req.TargetType, req.TargetID, req.MetricName, 30) // 获取30天历史数据
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical data: %w", err)
+	}
+
+	// 根据严重级别动态调整阈值
+	threshold := robustZScoreThreshold
+	if req.Severity == "critical" {
+		threshold = 3.0 // 更敏感的检测
+	} else if req.Severity == "low" {
+		threshold = 4.0 // 较不敏感的检测
+	}
+
+	at := req.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	return s.scoreAnomaly(series, req.CurrentValue, at, threshold), nil
+}
+
+// scoreAnomaly 使用中位数/MAD及季节分解残差对当前值进行稳健异常评分
+func (s *AIService) scoreAnomaly(series *HistoricalSeries, value float64, at time.Time, threshold float64) *AnomalyDetectionResult {
+	data := series.Values
+	if len(data) < 10 {
+		// 数据不足，返回基础检测结果
+		return &AnomalyDetectionResult{
+			IsAnomaly:      false,
+			AnomalyScore:   0.0,
+			Threshold:      threshold,
+			DeviationLevel: "normal",
+			Confidence:     0.5,
+			Method:         "insufficient_data",
+			DataPoints:     len(data),
+		}
+	}
+
+	mean := s.calculateMean(data)
+	stdDev := s.calculateStdDev(data, mean)
+	median := calculateMedian(data)
+	mad := calculateMAD(data, median)
+
+	method := "median_mad"
+	expected := median
+	score := robustScore(value, median, madScaleFactor*mad, stdDev)
+
+	period, _ := s.detectSeasonality(data)
+	if period > 0 && len(data) >= 2*period {
+		seasonalExpected, residualScale := seasonalExpectation(series, period, at)
+		expected = seasonalExpected
+		score = robustScore(value, seasonalExpected, residualScale, stdDev)
+		method = "seasonal_residual"
+	}
+
+	// 确定偏差级别
+	deviationLevel := "normal"
+	if score > threshold {
+		if score > threshold*2 {
+			deviationLevel = "severe"
+		} else if score > threshold*1.5 {
+			deviationLevel = "high"
+		} else {
+			deviationLevel = "moderate"
+		}
+	}
+
+	// 计算置信度
+	confidence := math.Min(0.95, float64(len(data))/200.0+0.5)
+
+	return &AnomalyDetectionResult{
+		IsAnomaly:        score > threshold,
+		AnomalyScore:     score,
+		Threshold:        threshold,
+		DeviationLevel:   deviationLevel,
+		HistoricalMean:   mean,
+		HistoricalStdDev: stdDev,
+		HistoricalMedian: median,
+		HistoricalMAD:    mad,
+		ExpectedValue:    expected,
+		SeasonalPeriod:   period,
+		Method:           method,
+		DataPoints:       len(data),
+		Confidence:       confidence,
+	}
+}
+
+// seasonalExpectation 经典加法季节分解，返回指定时刻的期望值及残差尺度
+func seasonalExpectation(series *HistoricalSeries, period int, at time.Time) (float64, float64) {
+	data := series.Values
+	n := len(data)
+
+	// 中心移动平均估计趋势，边缘使用最近的有效值
+	trend := make([]float64, n)
+	half := period / 2
+	firstValid, lastValid := -1, -1
+	for i := half; i+half < n; i++ {
+		lo, hi := i-half, i+half
+		if period%2 == 0 {
+			hi--
+		}
+		sum := 0.0
+		for j := lo; j <= hi; j++ {
+			sum += data[j]
+		}
+		trend[i] = sum / float64(hi-lo+1)
+		if firstValid < 0 {
+			firstValid = i
+		}
+		lastValid = i
+	}
+	for i := 0; i < firstValid; i++ {
+		trend[i] = trend[firstValid]
+	}
+	for i := lastValid + 1; i < n; i++ {
+		trend[i] = trend[lastValid]
+	}
+
+	// 各相位去趋势值的中位数作为季节项
+	phases := make([][]float64, period)
+	for i := 0; i < n; i++ {
+		phases[i%period] = append(phases[i%period], data[i]-trend[i])
+	}
+	seasonal := make([]float64, period)
+	seasonalMean := 0.0
+	for k := range phases {
+		seasonal[k] = calculateMedian(phases[k])
+		seasonalMean += seasonal[k]
+	}
+	seasonalMean /= float64(period)
+	for k := range seasonal {
+		seasonal[k] -= seasonalMean
+	}
+
+	residuals := make([]float64, n)
+	for i := 0; i < n; i++ {
+		residuals[i] = data[i] - trend[i] - seasonal[i%period]
+	}
+	residualMedian := calculateMedian(residuals)
+	residualScale := madScaleFactor * calculateMAD(residuals, residualMedian)
+
+	offset := int(at.Sub(series.Start) / series.Step)
+	phase := ((offset % period) + period) % period
+
+	return trend[n-1] + seasonal[phase] + residualMedian, residualScale
+}
+
+// robustScore 计算稳健偏差分数，尺度为0时退化为标准差
+func robustScore(value, center, scale, fallback float64) float64 {
+	if scale <= 0 {
+		scale = fallback
+	}
+	if scale <= 0 {
+		if value == center {
+			return 0
+		}
+		return maxAnomalyScore
+	}
+	return math.Abs(value-center) / scale
+}
+
+func calculateMedian(data []float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(data))
+	copy(sorted, data)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func calculateMAD(data []float64, median float64) float64 {
+	deviations := make([]float64, len(data))
+	for i, v := range data {
+		deviations[i] = math.Abs(v - median)
+	}
+	return calculateMedian(deviations)
+}
+
+func meanAbsolute(data []float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range data {
+		sum += math.Abs(v)
+	}
+	return sum / float64(len(data))
+}
+
+// SeriesBaseline 序列基线
+type SeriesBaseline struct {
+	Expected float64 `json:"expected"`
+	Scale    float64 `json:"scale"`
+	Mode     string  `json:"mode"`
+	Samples  int     `json:"samples"`
+}
+
+// FitSeasonalBaseline 按星期几+小时拟合季节基线，历史不足时依次退化为按小时和全局基线
+func (s *AIService) FitSeasonalBaseline(series *HistoricalSeries, at time.Time) *SeriesBaseline {
+	data := series.Values
+	if len(data) == 0 {
+		return &SeriesBaseline{Mode: "none"}
+	}
+
+	weeklyKey := func(t time.Time) int { return int(t.Weekday())*24 + t.Hour() }
+	dailyKey := func(t time.Time) int { return t.Hour() }
+	globalKey := func(t time.Time) int { return 0 }
+
+	// 至少3周数据才能区分星期几，至少3天数据才能区分小时
+	span := time.Duration(len(data)) * series.Step
+	mode, keyOf := "global", globalKey
+	if span >= 21*24*time.Hour {
+		mode, keyOf = "weekly", weeklyKey
+	} else if span >= 3*24*time.Hour {
+		mode, keyOf = "daily", dailyKey
+	}
+
+	buckets := make(map[int][]float64)
+	for i, v := range data {
+		key := keyOf(series.TimestampAt(i))
+		buckets[key] = append(buckets[key], v)
+	}
+	medians := make(map[int]float64, len(buckets))
+	for key, values := range buckets {
+		medians[key] = calculateMedian(values)
+	}
+
+	residuals := make([]float64, len(data))
+	for i, v := range data {
+		residuals[i] = v - medians[keyOf(series.TimestampAt(i))]
+	}
+
+	expected, ok := medians[keyOf(at)]
+	samples := len(buckets[keyOf(at)])
+	if !ok || samples < 3 {
+		expected, samples, mode = calculateMedian(data), len(data), "global"
+	}
+
+	return &SeriesBaseline{
+		Expected: expected,
+		Scale:    madScaleFactor * calculateMAD(residuals, calculateMedian(residuals)),
+		Mode:     mode,
+		Samples:  samples,
+	}
+}
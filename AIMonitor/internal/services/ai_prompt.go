@@ -0,0 +1,706 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+// defaultSystemPrompt 未配置模板系统提示词时使用的默认系统提示词
+const defaultSystemPrompt = "你是一个专业的系统监控和运维专家，具有丰富的故障诊断和性能优化经验。请提供准确、实用的分析和建议。"
+
+// defaultPromptLanguage 未配置提示词语言时的默认语言
+const defaultPromptLanguage = "zh"
+
+// PromptData 提示词模板渲染数据
+type PromptData struct {
+	Req         *AIAnalysisRequest
+	Time        string
+	Anomaly     *AnomalyDetectionResult
+	Prediction  *TrendPredictionResult
+	Remediation string
+	Context     string
+	Knowledge   string
+	Examples    string
+}
+
+// CreatePromptTemplateRequest 创建提示词模板请求
+type CreatePromptTemplateRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	AnalysisType  string `json:"analysis_type" binding:"required,oneof=alert_analysis performance_analysis"`
+	Language      string `json:"language" binding:"required"`
+	Version       string `json:"version" binding:"required"`
+	SystemPrompt  string `json:"system_prompt"`
+	Template      string `json:"template" binding:"required"`
+	TrafficWeight int    `json:"traffic_weight" binding:"min=0,max=100"`
+}
+
+// UpdatePromptTemplateRequest 更新提示词模板请求，模板内容仅草稿状态可修改
+type UpdatePromptTemplateRequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	SystemPrompt  string `json:"system_prompt"`
+	Template      string `json:"template"`
+	Status        string `json:"status" binding:"omitempty,oneof=draft active archived"`
+	TrafficWeight *int   `json:"traffic_weight" binding:"omitempty,min=0,max=100"`
+}
+
+// PromptTemplateResponse 提示词模板响应
+type PromptTemplateResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	AnalysisType  string    `json:"analysis_type"`
+	Language      string    `json:"language"`
+	Version       string    `json:"version"`
+	SystemPrompt  string    `json:"system_prompt"`
+	Template      string    `json:"template"`
+	Status        string    `json:"status"`
+	TrafficWeight int       `json:"traffic_weight"`
+	CreatedBy     uuid.UUID `json:"created_by"`
+	UpdatedBy     uuid.UUID `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreatePromptEvalSetRequest 创建提示词评估集请求
+type CreatePromptEvalSetRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	AlertIDs    []uuid.UUID `json:"alert_ids" binding:"required,min=1"`
+}
+
+// PromptEvalSetResponse 提示词评估集响应
+type PromptEvalSetResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	AlertIDs    []uuid.UUID `json:"alert_ids"`
+	CreatedBy   uuid.UUID   `json:"created_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// builtinPromptTemplates 内置v1提示词模板，按 分析类型/语言 索引
+var builtinPromptTemplates = map[string]models.PromptTemplate{
+	"alert_analysis/zh": {
+		Name:         "告警深度分析",
+		AnalysisType: "alert_analysis",
+		Language:     "zh",
+		SystemPrompt: defaultSystemPrompt,
+		Template: `
+你是一个专业的AI驱动系统监控和运维专家，请基于以下综合信息进行深度分析：
+
+=== 基础告警信息 ===
+- 目标类型：{{.Req.TargetType}}
+- 目标ID：{{.Req.TargetID}}
+- 指标名称：{{.Req.MetricName}}
+- 当前值：{{printf "%.2f" .Req.CurrentValue}}
+- 阈值：{{printf "%.2f" .Req.Threshold}}
+- 条件：{{.Req.Condition}}
+- 严重级别：{{.Req.Severity}}
+- 时间：{{.Time}}
+{{with .Anomaly}}
+=== AI异常检测结果 ===
+- 异常状态：{{.IsAnomaly}}
+- 异常评分：{{printf "%.2f" .AnomalyScore}}
+- 检测阈值：{{printf "%.2f" .Threshold}}
+- 偏差级别：{{.DeviationLevel}}
+- 历史均值：{{printf "%.2f" .HistoricalMean}}
+- 历史标准差：{{printf "%.2f" .HistoricalStdDev}}
+- 历史中位数：{{printf "%.2f" .HistoricalMedian}}
+- 中位数绝对偏差(MAD)：{{printf "%.2f" .HistoricalMAD}}
+- 期望值：{{printf "%.2f" .ExpectedValue}}
+- 检测方法：{{.Method}}（样本点数：{{.DataPoints}}）
+- 检测置信度：{{printf "%.2f" .Confidence}}
+{{end}}{{with .Prediction}}
+=== AI趋势预测分析 ===
+- 趋势方向：{{.TrendDirection}}
+- 风险级别：{{.RiskLevel}}
+- 预测准确度：{{printf "%.2f" .Accuracy}}
+- 时间范围：{{.TimeHorizon}}
+- 季节性模式：{{.Seasonality}}（周期：{{.SeasonalPeriod}}个数据点）
+- 预测方法：{{.Method}}
+{{if .Timestamps}}
+=== 预测值（95%预测区间） ===
+{{range $i, $ts := .Timestamps}}- {{$ts.Format "2006-01-02 15:04"}}: {{printf "%.2f" (index $.Prediction.PredictedValues $i)}} [{{printf "%.2f" (index $.Prediction.LowerBounds $i)}}, {{printf "%.2f" (index $.Prediction.UpperBounds $i)}}]
+{{end}}{{end}}{{end}}{{if .Req.Tags}}
+=== 标签信息 ===
+{{range $k, $v := .Req.Tags}}- {{$k}}: {{$v}}
+{{end}}{{end}}{{if .Remediation}}
+=== 自动修复执行记录 ===
+{{.Remediation}}{{end}}{{if .Context}}
+=== 附加上下文 ===
+{{.Context}}
+{{end}}
+
+=== 请提供以下深度分析 ===
+1. 智能根因分析（结合异常检测和趋势预测结果）
+2. 多维度影响评估（业务影响、技术影响、用户体验影响）
+3. 分层解决方案（紧急处理、中期优化、长期预防）
+4. 预测性维护建议（基于趋势分析）
+5. 智能告警优化建议
+6. 综合风险评估（critical/high/medium/low）
+7. AI分析置信度评分（0-1之间的小数）
+
+请以JSON格式返回分析结果，格式如下：
+{
+  "root_cause": "智能根因分析结果",
+  "impact_assessment": "多维度影响评估",
+  "recommendations": ["紧急处理方案", "中期优化方案", "长期预防方案"],
+  "prevention_measures": "预测性维护建议",
+  "severity_level": "综合风险级别",
+  "confidence_score": 0.85
+}
+{{if .Knowledge}}
+
+相关知识库信息:
+{{.Knowledge}}{{end}}{{if .Examples}}
+
+{{.Examples}}{{end}}`,
+	},
+	"alert_analysis/en": {
+		Name:         "Alert deep analysis",
+		AnalysisType: "alert_analysis",
+		Language:     "en",
+		SystemPrompt: "You are an experienced SRE specialising in incident diagnosis and performance tuning. Give accurate, actionable analysis and advice.",
+		Template: `
+You are an AI-driven monitoring and operations expert. Perform an in-depth analysis based on the information below.
+
+=== Alert ===
+- Target type: {{.Req.TargetType}}
+- Target ID: {{.Req.TargetID}}
+- Metric: {{.Req.MetricName}}
+- Current value: {{printf "%.2f" .Req.CurrentValue}}
+- Threshold: {{printf "%.2f" .Req.Threshold}}
+- Condition: {{.Req.Condition}}
+- Severity: {{.Req.Severity}}
+- Time: {{.Time}}
+{{with .Anomaly}}
+=== Anomaly detection ===
+- Anomalous: {{.IsAnomaly}}
+- Anomaly score: {{printf "%.2f" .AnomalyScore}}
+- Detection threshold: {{printf "%.2f" .Threshold}}
+- Deviation level: {{.DeviationLevel}}
+- Historical mean: {{printf "%.2f" .HistoricalMean}}
+- Historical std dev: {{printf "%.2f" .HistoricalStdDev}}
+- Historical median: {{printf "%.2f" .HistoricalMedian}}
+- Median absolute deviation (MAD): {{printf "%.2f" .HistoricalMAD}}
+- Expected value: {{printf "%.2f" .ExpectedValue}}
+- Method: {{.Method}} ({{.DataPoints}} samples)
+- Confidence: {{printf "%.2f" .Confidence}}
+{{end}}{{with .Prediction}}
+=== Trend prediction ===
+- Direction: {{.TrendDirection}}
+- Risk level: {{.RiskLevel}}
+- Accuracy: {{printf "%.2f" .Accuracy}}
+- Horizon: {{.TimeHorizon}}
+- Seasonality: {{.Seasonality}} (period: {{.SeasonalPeriod}} points)
+- Method: {{.Method}}
+{{if .Timestamps}}
+=== Forecast (95% prediction interval) ===
+{{range $i, $ts := .Timestamps}}- {{$ts.Format "2006-01-02 15:04"}}: {{printf "%.2f" (index $.Prediction.PredictedValues $i)}} [{{printf "%.2f" (index $.Prediction.LowerBounds $i)}}, {{printf "%.2f" (index $.Prediction.UpperBounds $i)}}]
+{{end}}{{end}}{{end}}{{if .Req.Tags}}
+=== Labels ===
+{{range $k, $v := .Req.Tags}}- {{$k}}: {{$v}}
+{{end}}{{end}}{{if .Remediation}}
+=== Remediation history ===
+{{.Remediation}}{{end}}{{if .Context}}
+=== Additional context ===
+{{.Context}}
+{{end}}
+
+=== Please provide ===
+1. Root cause analysis (using the anomaly and trend results)
+2. Impact assessment (business, technical, user experience)
+3. Layered remediation (immediate, mid-term, long-term)
+4. Predictive maintenance advice
+5. Alert tuning advice
+6. Overall risk level (critical/high/medium/low)
+7. Confidence score (a decimal between 0 and 1)
+
+Reply with JSON only, in this format:
+{
+  "root_cause": "root cause",
+  "impact_assessment": "impact assessment",
+  "recommendations": ["immediate action", "mid-term action", "long-term action"],
+  "prevention_measures": "prevention measures",
+  "severity_level": "risk level",
+  "confidence_score": 0.85
+}
+{{if .Knowledge}}
+
+Related knowledge base entries:
+{{.Knowledge}}{{end}}{{if .Examples}}
+
+{{.Examples}}{{end}}`,
+	},
+	"performance_analysis/zh": {
+		Name:         "性能分析",
+		AnalysisType: "performance_analysis",
+		Language:     "zh",
+		SystemPrompt: defaultSystemPrompt,
+		Template: `
+你是一个专业的系统性能分析专家，请分析以下性能数据并提供优化建议：
+
+性能数据：
+- 目标类型：{{.Req.TargetType}}
+- 目标ID：{{.Req.TargetID}}
+- 指标名称：{{.Req.MetricName}}
+- 当前值：{{printf "%.2f" .Req.CurrentValue}}
+- 时间：{{.Time}}
+{{if .Req.Context}}
+上下文信息：
+{{range $k, $v := .Req.Context}}- {{$k}}: {{$v}}
+{{end}}{{end}}
+
+请提供以下分析：
+1. 性能状态评估
+2. 瓶颈识别
+3. 优化建议（至少3个具体的优化方案）
+4. 容量规划建议
+5. 风险评估
+6. 置信度评分（0-1之间的小数）
+
+请以JSON格式返回分析结果。
+`,
+	},
+	"performance_analysis/en": {
+		Name:         "Performance analysis",
+		AnalysisType: "performance_analysis",
+		Language:     "en",
+		SystemPrompt: "You are an experienced SRE specialising in incident diagnosis and performance tuning. Give accurate, actionable analysis and advice.",
+		Template: `
+You are a system performance expert. Analyse the performance data below and suggest optimisations.
+
+Performance data:
+- Target type: {{.Req.TargetType}}
+- Target ID: {{.Req.TargetID}}
+- Metric: {{.Req.MetricName}}
+- Current value: {{printf "%.2f" .Req.CurrentValue}}
+- Time: {{.Time}}
+{{if .Req.Context}}
+Context:
+{{range $k, $v := .Req.Context}}- {{$k}}: {{$v}}
+{{end}}{{end}}
+
+Please provide:
+1. Performance assessment
+2. Bottleneck identification
+3. Optimisation advice (at least 3 concrete options)
+4. Capacity planning advice
+5. Risk assessment
+6. Confidence score (a decimal between 0 and 1)
+
+Reply with JSON only, using the keys root_cause, impact_assessment, recommendations, prevention_measures, severity_level and confidence_score.
+`,
+	},
+}
+
+// EnsureDefaultPromptTemplates 确保内置v1模板已写入数据库，已存在（含已归档）的版本不会被覆盖
+func (s *AIService) EnsureDefaultPromptTemplates() error {
+	for _, builtin := range builtinPromptTemplates {
+		var count int64
+		if err := s.db.Unscoped().Model(&models.PromptTemplate{}).
+			Where("analysis_type = ? AND language = ? AND version = ?", builtin.AnalysisType, builtin.Language, defaultPromptVersion).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check prompt template: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		tpl := builtin
+		tpl.Version = defaultPromptVersion
+		tpl.Description = "内置模板"
+		tpl.Status = "active"
+		tpl.TrafficWeight = 100
+		if err := s.db.Create(&tpl).Error; err != nil {
+			return fmt.Errorf("failed to seed prompt template: %w", err)
+		}
+	}
+	return nil
+}
+
+// selectPromptTemplate 选择本次分析使用的提示词模板：
+// 规则指定的模板优先，否则在该语言的活跃版本间按流量权重分配；
+// 同一告警按stickyKey哈希固定到同一版本，便于对比评价
+func (s *AIService) selectPromptTemplate(analysisType, language string, ruleID uuid.UUID, stickyKey string) *models.PromptTemplate {
+	if ruleID != uuid.Nil {
+		var rule models.AlertRule
+		if err := s.db.Select("id", "prompt_template_id").First(&rule, ruleID).Error; err == nil && rule.PromptTemplateID != nil {
+			var pinned models.PromptTemplate
+			if err := s.db.Where("id = ? AND analysis_type = ? AND status <> ?", *rule.PromptTemplateID, analysisType, "archived").
+				First(&pinned).Error; err == nil {
+				return &pinned
+			}
+		}
+	}
+
+	if language == "" {
+		language = s.config.AIModels.PromptLanguage
+	}
+	if language == "" {
+		language = defaultPromptLanguage
+	}
+
+	languages := []string{language}
+	if language != defaultPromptLanguage {
+		languages = append(languages, defaultPromptLanguage)
+	}
+	for _, lang := range languages {
+		var candidates []models.PromptTemplate
+		if err := s.db.Where("analysis_type = ? AND language = ? AND status = ? AND traffic_weight > 0", analysisType, lang, "active").
+			Order("version ASC").
+			Find(&candidates).Error; err != nil || len(candidates) == 0 {
+			continue
+		}
+		return pickWeightedTemplate(candidates, stickyKey)
+	}
+
+	return builtinPromptTemplate(analysisType, language)
+}
+
+// pickWeightedTemplate 按流量权重选择模板
+func pickWeightedTemplate(candidates []models.PromptTemplate, stickyKey string) *models.PromptTemplate {
+	total := 0
+	for _, c := range candidates {
+		total += c.TrafficWeight
+	}
+
+	var n int
+	if stickyKey != "" {
+		h := fnv.New32a()
+		h.Write([]byte(stickyKey))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.Intn(total)
+	}
+
+	for i := range candidates {
+		n -= candidates[i].TrafficWeight
+		if n < 0 {
+			return &candidates[i]
+		}
+	}
+	return &candidates[len(candidates)-1]
+}
+
+// builtinPromptTemplate 获取内置模板，找不到对应语言时回退到默认语言
+func builtinPromptTemplate(analysisType, language string) *models.PromptTemplate {
+	tpl, ok := builtinPromptTemplates[analysisType+"/"+language]
+	if !ok {
+		tpl = builtinPromptTemplates[analysisType+"/"+defaultPromptLanguage]
+	}
+	tpl.Version = defaultPromptVersion
+	return &tpl
+}
+
+// renderPrompt 渲染提示词模板
+func renderPrompt(tpl *models.PromptTemplate, data *PromptData) (string, error) {
+	t, err := template.New(tpl.AnalysisType).Option("missingkey=zero").Parse(tpl.Template)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// validatePromptTemplate 使用示例数据试渲染模板，提前发现语法和字段错误
+func validatePromptTemplate(tpl *models.PromptTemplate) error {
+	now := time.Now()
+	data := &PromptData{
+		Req: &AIAnalysisRequest{
+			Type:         tpl.AnalysisType,
+			TargetType:   "host",
+			TargetID:     "sample",
+			MetricName:   "cpu_usage",
+			CurrentValue: 95,
+			Threshold:    80,
+			Condition:    ">",
+			Severity:     "high",
+			Tags:         map[string]interface{}{"env": "prod"},
+			Timestamp:    now,
+			Context:      map[string]interface{}{"sample": true},
+		},
+		Time:    now.Format("2006-01-02 15:04:05"),
+		Anomaly: &AnomalyDetectionResult{},
+		Prediction: &TrendPredictionResult{
+			PredictedValues: []float64{1},
+			LowerBounds:     []float64{0},
+			UpperBounds:     []float64{2},
+			Timestamps:      []time.Time{now},
+		},
+		Remediation: "sample",
+		Context:     "{}",
+		Knowledge:   "sample",
+		Examples:    "sample",
+	}
+	_, err := renderPrompt(tpl, data)
+	return err
+}
+
+// chatModel 获取对话模型名称
+func (s *AIService) chatModel() string {
+	if model := s.config.AIModels.OpenAI.Model; model != "" {
+		return model
+	}
+	return openai.GPT3Dot5Turbo
+}
+
+// callModel 使用指定系统提示词调用模型，返回内容、消耗token数和耗时
+func (s *AIService) callModel(systemPrompt, prompt string) (string, int, time.Duration, error) {
+	if s.openaiClient == nil {
+		return "", 0, 0, errors.New("AI service not configured")
+	}
+	if systemPrompt == "" {
+		systemPrompt = defaultSystemPrompt
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model: s.chatModel(),
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+		MaxTokens:   s.config.AIModels.OpenAI.MaxTokens,
+		Temperature: float32(s.config.AIModels.OpenAI.Temperature),
+	}
+
+	start := time.Now()
+	resp, err := s.openaiClient.CreateChatCompletion(context.Background(), req)
+	latency := time.Since(start)
+	if err != nil {
+		return "", 0, latency, fmt.Errorf("OpenAI API call failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", resp.Usage.TotalTokens, latency, errors.New("no response from OpenAI")
+	}
+
+	return resp.Choices[0].Message.Content, resp.Usage.TotalTokens, latency, nil
+}
+
+// CreatePromptTemplate 创建提示词模板（草稿状态）
+func (s *AIService) CreatePromptTemplate(req *CreatePromptTemplateRequest, createdBy uuid.UUID) (*PromptTemplateResponse, error) {
+	tpl := models.PromptTemplate{
+		Name:          req.Name,
+		Description:   req.Description,
+		AnalysisType:  req.AnalysisType,
+		Language:      strings.ToLower(req.Language),
+		Version:       req.Version,
+		SystemPrompt:  req.SystemPrompt,
+		Template:      req.Template,
+		Status:        "draft",
+		TrafficWeight: req.TrafficWeight,
+		CreatedBy:     createdBy,
+		UpdatedBy:     createdBy,
+	}
+	if err := validatePromptTemplate(&tpl); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Unscoped().Model(&models.PromptTemplate{}).
+		Where("analysis_type = ? AND language = ? AND version = ?", tpl.AnalysisType, tpl.Language, tpl.Version).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check prompt template: %w", err)
+	}
+	if count > 0 {
+		return nil, errors.New("prompt template version already exists")
+	}
+
+	if err := s.db.Create(&tpl).Error; err != nil {
+		return nil, fmt.Errorf("failed to create prompt template: %w", err)
+	}
+
+	return s.toPromptTemplateResponse(&tpl), nil
+}
+
+// GetPromptTemplate 获取提示词模板
+func (s *AIService) GetPromptTemplate(id uuid.UUID) (*PromptTemplateResponse, error) {
+	var tpl models.PromptTemplate
+	if err := s.db.First(&tpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prompt template not found")
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	return s.toPromptTemplateResponse(&tpl), nil
+}
+
+// ListPromptTemplates 获取提示词模板列表
+func (s *AIService) ListPromptTemplates(page, pageSize int, analysisType, language, status string) ([]*PromptTemplateResponse, int64, error) {
+	query := s.db.Model(&models.PromptTemplate{})
+	if analysisType != "" {
+		query = query.Where("analysis_type = ?", analysisType)
+	}
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count prompt templates: %w", err)
+	}
+
+	var templates []models.PromptTemplate
+	offset := (page - 1) * pageSize
+	if err := query.Order("analysis_type ASC, language ASC, created_at DESC").
+		Offset(offset).Limit(pageSize).Find(&templates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+
+	responses := make([]*PromptTemplateResponse, len(templates))
+	for i := range templates {
+		responses[i] = s.toPromptTemplateResponse(&templates[i])
+	}
+	return responses, total, nil
+}
+
+// UpdatePromptTemplate 更新提示词模板：模板内容仅草稿可改，已归档的版本不可再修改
+func (s *AIService) UpdatePromptTemplate(id uuid.UUID, req *UpdatePromptTemplateRequest, updatedBy uuid.UUID) (*PromptTemplateResponse, error) {
+	var tpl models.PromptTemplate
+	if err := s.db.First(&tpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prompt template not found")
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	if tpl.Status == "archived" {
+		return nil, errors.New("archived prompt template cannot be modified")
+	}
+
+	updates := map[string]interface{}{"updated_by": updatedBy}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.SystemPrompt != "" || req.Template != "" {
+		if tpl.Status != "draft" {
+			return nil, errors.New("only draft prompt template content can be modified, create a new version instead")
+		}
+		if req.SystemPrompt != "" {
+			tpl.SystemPrompt = req.SystemPrompt
+			updates["system_prompt"] = req.SystemPrompt
+		}
+		if req.Template != "" {
+			tpl.Template = req.Template
+			updates["template"] = req.Template
+			if err := validatePromptTemplate(&tpl); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Status != "" && req.Status != tpl.Status {
+		if req.Status == "draft" {
+			return nil, errors.New("prompt template cannot return to draft")
+		}
+		updates["status"] = req.Status
+	}
+	if req.TrafficWeight != nil {
+		updates["traffic_weight"] = *req.TrafficWeight
+	}
+
+	if err := s.db.Model(&tpl).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update prompt template: %w", err)
+	}
+
+	return s.GetPromptTemplate(id)
+}
+
+// CreatePromptEvalSet 保存一组历史告警作为离线评估集
+func (s *AIService) CreatePromptEvalSet(req *CreatePromptEvalSetRequest, createdBy uuid.UUID) (*PromptEvalSetResponse, error) {
+	var count int64
+	if err := s.db.Model(&models.Alert{}).Where("id IN ?", req.AlertIDs).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check alerts: %w", err)
+	}
+	if int(count) != len(req.AlertIDs) {
+		return nil, errors.New("some alerts not found")
+	}
+
+	alertIDsJSON, _ := json.Marshal(req.AlertIDs)
+	set := models.PromptEvalSet{
+		Name:        req.Name,
+		Description: req.Description,
+		AlertIDs:    string(alertIDsJSON),
+		CreatedBy:   createdBy,
+	}
+	if err := s.db.Create(&set).Error; err != nil {
+		return nil, fmt.Errorf("failed to create prompt eval set: %w", err)
+	}
+
+	return s.toPromptEvalSetResponse(&set), nil
+}
+
+// ListPromptEvalSets 获取提示词评估集列表
+func (s *AIService) ListPromptEvalSets() ([]*PromptEvalSetResponse, error) {
+	var sets []models.PromptEvalSet
+	if err := s.db.Order("created_at DESC").Find(&sets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list prompt eval sets: %w", err)
+	}
+
+	responses := make([]*PromptEvalSetResponse, len(sets))
+	for i := range sets {
+		responses[i] = s.toPromptEvalSetResponse(&sets[i])
+	}
+	return responses, nil
+}
+
+// toPromptTemplateResponse 转换为提示词模板响应格式
+func (s *AIService) toPromptTemplateResponse(tpl *models.PromptTemplate) *PromptTemplateResponse {
+	return &PromptTemplateResponse{
+		ID:            tpl.ID,
+		Name:          tpl.Name,
+		Description:   tpl.Description,
+		AnalysisType:  tpl.AnalysisType,
+		Language:      tpl.Language,
+		Version:       tpl.Version,
+		SystemPrompt:  tpl.SystemPrompt,
+		Template:      tpl.Template,
+		Status:        tpl.Status,
+		TrafficWeight: tpl.TrafficWeight,
+		CreatedBy:     tpl.CreatedBy,
+		UpdatedBy:     tpl.UpdatedBy,
+		CreatedAt:     tpl.CreatedAt,
+		UpdatedAt:     tpl.UpdatedAt,
+	}
+}
+
+// toPromptEvalSetResponse 转换为评估集响应格式
+func (s *AIService) toPromptEvalSetResponse(set *models.PromptEvalSet) *PromptEvalSetResponse {
+	var alertIDs []uuid.UUID
+	if set.AlertIDs != "" {
+		json.Unmarshal([]byte(set.AlertIDs), &alertIDs)
+	}
+
+	return &PromptEvalSetResponse{
+		ID:          set.ID,
+		Name:        set.Name,
+		Description: set.Description,
+		AlertIDs:    alertIDs,
+		CreatedBy:   set.CreatedBy,
+		CreatedAt:   set.CreatedAt,
+	}
+}
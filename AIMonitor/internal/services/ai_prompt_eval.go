@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PromptEvalSample 单条告警的评估结果
+type PromptEvalSample struct {
+	AlertID    uuid.UUID `json:"alert_id"`
+	ParseOK    bool      `json:"parse_ok"`
+	Repaired   bool      `json:"repaired"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Tokens     int       `json:"tokens"`
+	RootCause  string    `json:"root_cause,omitempty"`
+	JudgeScore *float64  `json:"judge_score,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// PromptEvalReport 提示词模板离线评估报告
+type PromptEvalReport struct {
+	SetName          string              `json:"set_name"`
+	TemplateID       uuid.UUID           `json:"template_id"`
+	AnalysisType     string              `json:"analysis_type"`
+	Language         string              `json:"language"`
+	Version          string              `json:"version"`
+	Samples          int                 `json:"samples"`
+	ParseSuccess     int                 `json:"parse_success"`
+	ParseSuccessRate float64             `json:"parse_success_rate"`
+	RepairSuccess    int                 `json:"repair_success"`
+	AvgLatencyMs     float64             `json:"avg_latency_ms"`
+	P95LatencyMs     int64               `json:"p95_latency_ms"`
+	TotalTokens      int                 `json:"total_tokens"`
+	AvgTokens        float64             `json:"avg_tokens"`
+	JudgedSamples    int                 `json:"judged_samples"`
+	AvgJudgeScore    float64             `json:"avg_judge_score"`
+	RaterFeedback    int                 `json:"rater_feedback"`
+	RaterAccuracy    float64             `json:"rater_accuracy"`
+	Results          []*PromptEvalSample `json:"results"`
+	EvaluatedAt      time.Time           `json:"evaluated_at"`
+}
+
+// judgeScorePattern 从裁判模型回复中提取分数
+var judgeScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// EvaluatePromptTemplate 使用评估集中的历史告警回放指定模板，统计schema校验通过率、耗时、token和评分。
+// 评估不写入分析结果，也不注入故障记忆示例，避免已确认根因泄漏到被评估的提示词中
+func (s *AIService) EvaluatePromptTemplate(setName string, templateID uuid.UUID, judge bool) (*PromptEvalReport, error) {
+	if s.openaiClient == nil {
+		return nil, errors.New("AI service not configured")
+	}
+
+	var set models.PromptEvalSet
+	if err := s.db.Where("name = ?", setName).First(&set).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prompt eval set not found")
+		}
+		return nil, fmt.Errorf("failed to get prompt eval set: %w", err)
+	}
+	var alertIDs []uuid.UUID
+	if err := json.Unmarshal([]byte(set.AlertIDs), &alertIDs); err != nil {
+		return nil, fmt.Errorf("failed to parse eval set alerts: %w", err)
+	}
+
+	var tpl models.PromptTemplate
+	if err := s.db.First(&tpl, templateID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prompt template not found")
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	report := &PromptEvalReport{
+		SetName:      set.Name,
+		TemplateID:   tpl.ID,
+		AnalysisType: tpl.AnalysisType,
+		Language:     tpl.Language,
+		Version:      tpl.Version,
+		EvaluatedAt:  time.Now(),
+	}
+
+	var latencies []int64
+	var judgeTotal float64
+	for _, alertID := range alertIDs {
+		sample := s.evaluateAlert(&tpl, alertID, judge)
+		report.Results = append(report.Results, sample)
+		report.Samples++
+		if sample.ParseOK {
+			report.ParseSuccess++
+		}
+		if sample.Repaired {
+			report.RepairSuccess++
+		}
+		if sample.LatencyMs > 0 {
+			latencies = append(latencies, sample.LatencyMs)
+		}
+		report.TotalTokens += sample.Tokens
+		if sample.JudgeScore != nil {
+			report.JudgedSamples++
+			judgeTotal += *sample.JudgeScore
+		}
+	}
+
+	if report.Samples > 0 {
+		report.ParseSuccessRate = float64(report.ParseSuccess) / float64(report.Samples)
+		report.AvgTokens = float64(report.TotalTokens) / float64(report.Samples)
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		var sum int64
+		for _, l := range latencies {
+			sum += l
+		}
+		report.AvgLatencyMs = float64(sum) / float64(len(latencies))
+		report.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1]
+	}
+	if report.JudgedSamples > 0 {
+		report.AvgJudgeScore = judgeTotal / float64(report.JudgedSamples)
+	}
+
+	// 线上人工评价：同分析类型、同版本的历史反馈
+	var rater struct {
+		Up   int
+		Down int
+	}
+	s.db.Table("ai_analysis_feedback f").
+		Select("COUNT(CASE WHEN f.rating = 'up' THEN 1 END) AS up, COUNT(CASE WHEN f.rating = 'down' THEN 1 END) AS down").
+		Joins("JOIN ai_analysis_results r ON r.id = f.analysis_id AND r.deleted_at IS NULL").
+		Where("f.deleted_at IS NULL AND r.analysis_type = ? AND r.prompt_version = ?", tpl.AnalysisType, tpl.Version).
+		Scan(&rater)
+	report.RaterFeedback = rater.Up + rater.Down
+	if report.RaterFeedback > 0 {
+		report.RaterAccuracy = float64(rater.Up) / float64(report.RaterFeedback)
+	}
+
+	return report, nil
+}
+
+// evaluateAlert 回放单条告警
+func (s *AIService) evaluateAlert(tpl *models.PromptTemplate, alertID uuid.UUID, judge bool) *PromptEvalSample {
+	sample := &PromptEvalSample{AlertID: alertID}
+
+	var alert models.Alert
+	if err := s.db.Preload("Rule").First(&alert, alertID).Error; err != nil {
+		sample.Error = fmt.Sprintf("failed to get alert: %v", err)
+		return sample
+	}
+
+	labels := map[string]interface{}{}
+	if alert.Labels != "" {
+		json.Unmarshal([]byte(alert.Labels), &labels)
+	}
+	req := &AIAnalysisRequest{
+		Type:         tpl.AnalysisType,
+		AlertID:      alert.ID,
+		RuleID:       alert.RuleID,
+		TargetType:   paramString(labels, "target_type"),
+		TargetID:     paramString(labels, "target_id"),
+		MetricName:   alert.Rule.Metric,
+		CurrentValue: alert.Value,
+		Threshold:    alert.Rule.Threshold,
+		Condition:    alert.Rule.Condition,
+		Severity:     alert.Severity,
+		Tags:         labels,
+		Timestamp:    alert.StartsAt,
+		Language:     tpl.Language,
+	}
+
+	var prompt string
+	var err error
+	if tpl.AnalysisType == "performance_analysis" {
+		prompt, err = s.buildPerformanceAnalysisContext(tpl, req)
+	} else {
+		anomaly, aerr := s.detectAnomaly(req)
+		if aerr != nil {
+			sample.Error = fmt.Sprintf("anomaly detection failed: %v", aerr)
+			return sample
+		}
+		prediction, perr := s.predictTrend(req)
+		if perr != nil {
+			sample.Error = fmt.Sprintf("trend prediction failed: %v", perr)
+			return sample
+		}
+		knowledge, _ := s.getRelevantKnowledge(req.MetricName, req.Severity)
+		prompt, err = s.buildEnhancedAnalysisContext(tpl, req, anomaly, prediction, knowledge, "")
+	}
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+
+	// 首次输出即通过校验计为解析成功，经修复才通过的单独统计
+	structured, err := s.callStructured(tpl.SystemPrompt, prompt, tpl.AnalysisType)
+	if structured != nil {
+		sample.LatencyMs = structured.Latency.Milliseconds()
+		sample.Tokens = structured.Tokens
+	}
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	parsed := structured.Parsed
+	sample.ParseOK = structured.RepairAttempts == 0
+	sample.Repaired = structured.RepairAttempts > 0
+	sample.RootCause = parsed.RootCause
+
+	if judge {
+		if score, err := s.judgeRootCause(alert.ID, parsed.RootCause); err == nil && score != nil {
+			sample.JudgeScore = score
+		}
+	}
+	return sample
+}
+
+// judgeRootCause 以故障记忆中人工确认的根因为参考，由模型对候选根因打分（归一化到0-1）。
+// 告警没有确认根因时返回nil
+func (s *AIService) judgeRootCause(alertID uuid.UUID, candidate string) (*float64, error) {
+	var memory models.IncidentMemory
+	if err := s.db.Where("alert_id = ?", alertID).Order("updated_at DESC").First(&memory).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get incident memory: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`请评估候选根因与参考根因的一致程度，0分表示完全无关，10分表示本质相同。
+只返回一个0到10之间的数字。
+
+参考根因：%s
+
+候选根因：%s
+`, memory.RootCause, candidate)
+
+	content, _, _, err := s.callModel("你是一个严格的故障复盘评审专家。", prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	match := judgeScorePattern.FindString(content)
+	if match == "" {
+		return nil, errors.New("judge returned no score")
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse judge score: %w", err)
+	}
+	if score > 10 {
+		score = 10
+	}
+	score /= 10
+	return &score, nil
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"math/rand"
 	"strings"
 	"time"
 
@@ -65,6 +64,7 @@ type AIAnalysisRequest struct {
 	Tags         map[string]interface{} `json:"tags"`
 	Timestamp    time.Time              `json:"timestamp"`
 	Context      map[string]interface{} `json:"context,omitempty"`
+	Language     string                 `json:"language,omitempty"`
 }
 
 // PerformanceAnalysisRequest 性能分析请求
@@ -127,9 +127,9 @@ func (s *AIService) AnalyzeAlert(req *AIAnalysisRequest) (*AIAnalysisResponse, e
 		return nil, errors.New("AI service not configured")
 	}
 
-	// 检查缓存
+	// 检查缓存（携带附加上下文的请求需要重新分析）
 	cacheKey := s.generateCacheKey("alert_analysis", req)
-	if s.cacheManager != nil {
+	if s.cacheManager != nil && req.Context == nil {
 		var cached string
 		if err := s.cacheManager.Get(context.Background(), cacheKey, &cached); err == nil {
 			var response AIAnalysisResponse
@@ -151,35 +151,45 @@ func (s *AIService) AnalyzeAlert(req *AIAnalysisRequest) (*AIAnalysisResponse, e
 		return nil, fmt.Errorf("trend prediction failed: %w", err)
 	}
 
-	// 构建增强的分析上下文
-	context_str := s.buildEnhancedAnalysisContext(req, anomalyScore, prediction)
+	// 选择提示词模板（规则指定或按流量权重分配）
+	tpl := s.selectPromptTemplate("alert_analysis", req.Language, req.RuleID, stickyPromptKey(req))
 
 	// 查询相关知识库
-	knowledgeContext, err := s.getRelevantKnowledge(req.MetricName, req.Severity)
-	if err == nil && knowledgeContext != "" {
-		context_str += "\n\n相关知识库信息:\n" + knowledgeContext
-	}
+	knowledgeContext, _ := s.getRelevantKnowledge(req.MetricName, req.Severity)
 
-	// 调用AI模型
-	analysisResult, err := s.callOpenAI(context_str, "alert_analysis")
+	// 构建增强的分析上下文，相似告警的已确认根因作为参考示例
+	context_str, err := s.buildEnhancedAnalysisContext(tpl, req, anomalyScore, prediction, knowledgeContext, s.incidentMemoryExamples(req))
 	if err != nil {
-		return nil, fmt.Errorf("failed to call AI model: %w", err)
+		return nil, err
 	}
 
-	// 解析AI响应
-	parsedResult, err := s.parseAIResponse(analysisResult)
+	// 调用AI模型获取结构化结果（校验失败时自动修复）
+	structured, err := s.callStructured(tpl.SystemPrompt, context_str, "alert_analysis")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+		if errors.Is(err, ErrInvalidStructuredOutput) {
+			s.recordFailedAnalysis(&models.AIAnalysisResult{
+				AlertID:       req.AlertID,
+				AnalysisType:  req.Type,
+				Model:         "openai-gpt-4",
+				Prompt:        context_str,
+				PromptVersion: tpl.Version,
+			}, structured, err)
+		}
+		return nil, fmt.Errorf("failed to call AI model: %w", err)
 	}
+	parsedResult := structured.Parsed
 
 	// 创建分析结果记录
 	analysis := models.AIAnalysisResult{
+		AlertID:         req.AlertID,
 		AnalysisType:    req.Type,
 		Model:           "openai-gpt-4",
-		Response:        analysisResult,
+		Response:        structured.Validated,
 		Confidence:      parsedResult.ConfidenceScore,
 		Status:          "completed",
+		PromptVersion:   tpl.Version,
 	}
+	applyStructuredResult(&analysis, structured)
 
 	// 序列化标签和元数据到metadata中
 	// 注意：models.AIAnalysisResult中没有Tags字段，将标签信息存储在metadata中
@@ -192,9 +202,16 @@ func (s *AIService) AnalyzeAlert(req *AIAnalysisRequest) (*AIAnalysisResponse, e
 		"threshold":     req.Threshold,
 		"condition":     req.Condition,
 		"timestamp":     req.Timestamp,
+		"target_type":   req.TargetType,
+		"target_id":     req.TargetID,
+		"anomaly_detection": anomalyScore,
+		"trend_prediction":  prediction,
+		"prompt_template_id": tpl.ID,
+		"prompt_language":    tpl.Language,
 	}
 	metadataJSON, _ := json.Marshal(metadata)
 	analysis.Metadata = string(metadataJSON)
+	analysis.Prompt = context_str
 
 	// 保存到数据库
 	if err := s.db.Create(&analysis).Error; err != nil {
@@ -233,29 +250,40 @@ func (s *AIService) AnalyzePerformance(req *AIAnalysisRequest) (*AIAnalysisRespo
 		return nil, errors.New("AI service not configured")
 	}
 
-	// 构建性能分析上下文
-	context_str := s.buildPerformanceAnalysisContext(req)
-
-	// 调用AI模型
-	analysisResult, err := s.callOpenAI(context_str, "performance_analysis")
+	// 选择提示词模板并构建性能分析上下文
+	tpl := s.selectPromptTemplate("performance_analysis", req.Language, req.RuleID, stickyPromptKey(req))
+	context_str, err := s.buildPerformanceAnalysisContext(tpl, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call AI model: %w", err)
+		return nil, err
 	}
 
-	// 解析AI响应
-	parsedResult, err := s.parseAIResponse(analysisResult)
+	// 调用AI模型获取结构化结果（校验失败时自动修复）
+	structured, err := s.callStructured(tpl.SystemPrompt, context_str, "performance_analysis")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+		if errors.Is(err, ErrInvalidStructuredOutput) {
+			s.recordFailedAnalysis(&models.AIAnalysisResult{
+				AlertID:       req.AlertID,
+				AnalysisType:  req.Type,
+				Model:         "openai-gpt-4",
+				Prompt:        context_str,
+				PromptVersion: tpl.Version,
+			}, structured, err)
+		}
+		return nil, fmt.Errorf("failed to call AI model: %w", err)
 	}
+	parsedResult := structured.Parsed
 
 	// 创建分析结果记录
 	analysis := models.AIAnalysisResult{
+		AlertID:         req.AlertID,
 		AnalysisType:    req.Type,
 		Model:           "openai-gpt-4",
-		Response:        analysisResult,
+		Response:        structured.Validated,
 		Confidence:      parsedResult.ConfidenceScore,
 		Status:          "completed",
+		PromptVersion:   tpl.Version,
 	}
+	applyStructuredResult(&analysis, structured)
 
 	// 序列化元数据
 	metadata := map[string]interface{}{
@@ -263,9 +291,12 @@ func (s *AIService) AnalyzePerformance(req *AIAnalysisRequest) (*AIAnalysisRespo
 		"current_value": req.CurrentValue,
 		"timestamp":     req.Timestamp,
 		"context":       req.Context,
+		"prompt_template_id": tpl.ID,
+		"prompt_language":    tpl.Language,
 	}
 	metadataJSON, _ := json.Marshal(metadata)
 	analysis.Metadata = string(metadataJSON)
+	analysis.Prompt = context_str
 
 	// 保存到数据库
 	if err := s.db.Create(&analysis).Error; err != nil {
@@ -332,8 +363,11 @@ func (s *AIService) toAIAnalysisResponse(result *models.AIAnalysisResult) *AIAna
 		json.Unmarshal([]byte(result.Metadata), &metadata)
 	}
 
-	// 解析AI响应
-	parsedResult, _ := s.parseAIResponse(result.Response)
+	// 解析AI响应，失败的分析不包含结构化字段
+	parsedResult, err := s.parseAIResponse(result.Response)
+	if err != nil {
+		parsedResult = &ParsedAIResponse{}
+	}
 
 	return &AIAnalysisResponse{
 		ID:               result.ID,
@@ -521,131 +555,26 @@ func (s *AIService) buildAlertAnalysisContext(req *AIAnalysisRequest) string {
 }
 
 // buildPerformanceAnalysisContext 构建性能分析上下文
-func (s *AIService) buildPerformanceAnalysisContext(req *AIAnalysisRequest) string {
-	context_str := fmt.Sprintf(`
-你是一个专业的系统性能分析专家，请分析以下性能数据并提供优化建议：
-
-性能数据：
-- 目标类型：%s
-- 目标ID：%s
-- 指标名称：%s
-- 当前值：%.2f
-- 时间：%s
-`,
-		req.TargetType,
-		req.TargetID,
-		req.MetricName,
-		req.CurrentValue,
-		req.Timestamp.Format("2006-01-02 15:04:05"),
-	)
-
-	if req.Context != nil && len(req.Context) > 0 {
-		context_str += "\n上下文信息：\n"
-		for key, value := range req.Context {
-			context_str += fmt.Sprintf("- %s: %v\n", key, value)
-		}
-	}
-
-	context_str += `
-
-请提供以下分析：
-1. 性能状态评估
-2. 瓶颈识别
-3. 优化建议（至少3个具体的优化方案）
-4. 容量规划建议
-5. 风险评估
-6. 置信度评分（0-1之间的小数）
-
-请以JSON格式返回分析结果。
-`
-
-	return context_str
+func (s *AIService) buildPerformanceAnalysisContext(tpl *models.PromptTemplate, req *AIAnalysisRequest) (string, error) {
+	return renderPrompt(tpl, &PromptData{
+		Req:  req,
+		Time: req.Timestamp.Format("2006-01-02 15:04:05"),
+	})
 }
 
 // callOpenAI 调用OpenAI API
 func (s *AIService) callOpenAI(prompt, analysisType string) (string, error) {
-	ctx := context.Background()
-
-	// 根据分析类型选择模型
-	model := s.config.AIModels.OpenAI.Model
-	if model == "" {
-		model = openai.GPT3Dot5Turbo
-	}
-
-	// 构建消息
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: "你是一个专业的系统监控和运维专家，具有丰富的故障诊断和性能优化经验。请提供准确、实用的分析和建议。",
-		},
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: prompt,
-		},
-	}
-
-	// 创建请求
-	req := openai.ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		MaxTokens:   s.config.AIModels.OpenAI.MaxTokens,
-		Temperature: float32(s.config.AIModels.OpenAI.Temperature),
-	}
-
-	// 调用API
-	resp, err := s.openaiClient.CreateChatCompletion(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("OpenAI API call failed: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return "", errors.New("no response from OpenAI")
-	}
-
-	return resp.Choices[0].Message.Content, nil
+	content, _, _, err := s.callModel(defaultSystemPrompt, prompt)
+	return content, err
 }
 
-// parseAIResponse 解析AI响应
+// parseAIResponse 解析已保存的AI响应，不符合schema时返回错误
 func (s *AIService) parseAIResponse(response string) (*ParsedAIResponse, error) {
-	// 尝试提取JSON部分
-	jsonStart := strings.Index(response, "{")
-	jsonEnd := strings.LastIndex(response, "}")
-
-	if jsonStart == -1 || jsonEnd == -1 || jsonStart >= jsonEnd {
-		// 如果没有找到JSON，返回默认解析结果
-		return &ParsedAIResponse{
-			RootCause:       "AI分析结果解析失败",
-			Recommendations: []string{"请检查AI模型响应格式"},
-			SeverityLevel:   "medium",
-			ConfidenceScore: 0.5,
-		}, nil
+	parsed, _, errs := validateStructuredOutput(response, analysisSchema(""))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStructuredOutput, strings.Join(errs, "; "))
 	}
-
-	jsonStr := response[jsonStart : jsonEnd+1]
-
-	var parsed ParsedAIResponse
-	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
-		// JSON解析失败，返回原始响应
-		return &ParsedAIResponse{
-			RootCause:       response,
-			Recommendations: []string{"请参考完整的AI分析报告"},
-			SeverityLevel:   "medium",
-			ConfidenceScore: 0.7,
-		}, nil
-	}
-
-	// 验证和修正数据
-	if parsed.SeverityLevel == "" {
-		parsed.SeverityLevel = "medium"
-	}
-	if parsed.ConfidenceScore <= 0 || parsed.ConfidenceScore > 1 {
-		parsed.ConfidenceScore = 0.7
-	}
-	if len(parsed.Recommendations) == 0 {
-		parsed.Recommendations = []string{"请参考AI分析报告中的详细建议"}
-	}
-
-	return &parsed, nil
+	return parsed, nil
 }
 
 // getRelevantKnowledge 获取相关知识库信息
@@ -764,6 +693,12 @@ type AnomalyDetectionResult struct {
 	DeviationLevel  string  `json:"deviation_level"`
 	HistoricalMean  float64 `json:"historical_mean"`
 	HistoricalStdDev float64 `json:"historical_std_dev"`
+	HistoricalMedian float64 `json:"historical_median"`
+	HistoricalMAD   float64 `json:"historical_mad"`
+	ExpectedValue   float64 `json:"expected_value"`
+	SeasonalPeriod  int     `json:"seasonal_period"`
+	Method          string  `json:"method"`
+	DataPoints      int     `json:"data_points"`
 	Confidence      float64 `json:"confidence"`
 }
 
@@ -772,10 +707,17 @@ type TrendPredictionResult struct {
 	TrendDirection   string    `json:"trend_direction"`
 	PredictedValues  []float64 `json:"predicted_values"`
 	ConfidenceInterval []float64 `json:"confidence_interval"`
+	LowerBounds      []float64 `json:"lower_bounds"`
+	UpperBounds      []float64 `json:"upper_bounds"`
+	Timestamps       []time.Time `json:"timestamps"`
 	Seasonality      bool      `json:"seasonality"`
+	SeasonalPeriod   int       `json:"seasonal_period"`
+	SeasonalStrength float64   `json:"seasonal_strength"`
 	RiskLevel        string    `json:"risk_level"`
 	TimeHorizon      string    `json:"time_horizon"`
 	Accuracy         float64   `json:"accuracy"`
+	Method           string    `json:"method"`
+	DataPoints       int       `json:"data_points"`
 }
 
 // UpdateKnowledgeBase 更新知识库条目
@@ -834,224 +776,32 @@ func (s *AIService) DeleteKnowledgeBase(kbID uuid.UUID) error {
 	return nil
 }
 
-// detectAnomaly 异常检测算法
-func (s *AIService) detectAnomaly(req *AIAnalysisRequest) (*AnomalyDetectionResult, error) {
-	// 获取历史数据进行异常检测
-	historicalData, err := s.getHistoricalMetrics(req.TargetType, req.TargetID, req.MetricName, 30) // 获取30天历史数据
-	if err != nil {
-		return nil, fmt.Errorf("failed to get historical data: %w", err)
-	}
-
-	if len(historicalData) < 10 {
-		// 数据不足，返回基础检测结果
-		return &AnomalyDetectionResult{
-			IsAnomaly:      false,
-			AnomalyScore:   0.0,
-			Threshold:      2.0,
-			DeviationLevel: "normal",
-			Confidence:     0.5,
-		}, nil
-	}
-
-	// 计算统计指标
-	mean := s.calculateMean(historicalData)
-	stdDev := s.calculateStdDev(historicalData, mean)
-
-	// Z-Score异常检测
-	zScore := (req.CurrentValue - mean) / stdDev
-	anomalyScore := math.Abs(zScore)
-
-	// 动态阈值调整
-	threshold := 2.0
-	if req.Severity == "critical" {
-		threshold = 1.5 // 更敏感的检测
-	} else if req.Severity == "low" {
-		threshold = 2.5 // 较不敏感的检测
-	}
-
-	// 确定偏差级别
-	deviationLevel := "normal"
-	if anomalyScore > threshold {
-		if anomalyScore > threshold*2 {
-			deviationLevel = "severe"
-		} else if anomalyScore > threshold*1.5 {
-			deviationLevel = "high"
-		} else {
-			deviationLevel = "moderate"
-		}
-	}
-
-	// 计算置信度
-	confidence := math.Min(0.95, float64(len(historicalData))/100.0+0.5)
-
-	return &AnomalyDetectionResult{
-		IsAnomaly:        anomalyScore > threshold,
-		AnomalyScore:     anomalyScore,
-		Threshold:        threshold,
-		DeviationLevel:   deviationLevel,
-		HistoricalMean:   mean,
-		HistoricalStdDev: stdDev,
-		Confidence:       confidence,
-	}, nil
-}
-
-// predictTrend 趋势预测分析
-func (s *AIService) predictTrend(req *AIAnalysisRequest) (*TrendPredictionResult, error) {
-	// 获取历史数据进行趋势分析
-	historicalData, err := s.getHistoricalMetrics(req.TargetType, req.TargetID, req.MetricName, 7) // 获取7天历史数据
-	if err != nil {
-		return nil, fmt.Errorf("failed to get historical data: %w", err)
-	}
-
-	if len(historicalData) < 5 {
-		// 数据不足，返回基础预测结果
-		return &TrendPredictionResult{
-			TrendDirection: "stable",
-			PredictedValues: []float64{req.CurrentValue},
-			RiskLevel:      "low",
-			TimeHorizon:    "1h",
-			Accuracy:       0.5,
-		}, nil
-	}
-
-	// 简单线性回归预测
-	n := float64(len(historicalData))
-	sumX, sumY, sumXY, sumX2 := 0.0, 0.0, 0.0, 0.0
-
-	for i, value := range historicalData {
-		x := float64(i)
-		sumX += x
-		sumY += value
-		sumXY += x * value
-		sumX2 += x * x
-	}
-
-	// 计算回归系数
-	slope := (n*sumXY - sumX*sumY) / (n*sumX2 - sumX*sumX)
-	intercept := (sumY - slope*sumX) / n
-
-	// 预测未来值
-	predictedValues := make([]float64, 6) // 预测未来6个时间点
-	for i := 0; i < 6; i++ {
-		x := n + float64(i)
-		predictedValues[i] = slope*x + intercept
-	}
-
-	// 确定趋势方向
-	trendDirection := "stable"
-	if slope > 0.1 {
-		trendDirection = "increasing"
-	} else if slope < -0.1 {
-		trendDirection = "decreasing"
-	}
-
-	// 评估风险级别
-	riskLevel := "low"
-	if math.Abs(slope) > 1.0 {
-		riskLevel = "high"
-	} else if math.Abs(slope) > 0.5 {
-		riskLevel = "medium"
-	}
-
-	// 计算预测准确度
-	accuracy := math.Max(0.6, 1.0-math.Abs(slope)/10.0)
-
-	return &TrendPredictionResult{
-		TrendDirection:     trendDirection,
-		PredictedValues:    predictedValues,
-		ConfidenceInterval: []float64{0.8, 0.9}, // 简化的置信区间
-		Seasonality:        s.detectSeasonality(historicalData),
-		RiskLevel:          riskLevel,
-		TimeHorizon:        "6h",
-		Accuracy:           accuracy,
-	}, nil
-}
-
 // buildEnhancedAnalysisContext 构建增强的分析上下文
-func (s *AIService) buildEnhancedAnalysisContext(req *AIAnalysisRequest, anomaly *AnomalyDetectionResult, prediction *TrendPredictionResult) string {
-	context_str := fmt.Sprintf(`
-你是一个专业的AI驱动系统监控和运维专家，请基于以下综合信息进行深度分析：
-
-=== 基础告警信息 ===
-- 目标类型：%s
-- 目标ID：%s
-- 指标名称：%s
-- 当前值：%.2f
-- 阈值：%.2f
-- 条件：%s
-- 严重级别：%s
-- 时间：%s
-
-=== AI异常检测结果 ===
-- 异常状态：%t
-- 异常评分：%.2f
-- 检测阈值：%.2f
-- 偏差级别：%s
-- 历史均值：%.2f
-- 历史标准差：%.2f
-- 检测置信度：%.2f
-
-=== AI趋势预测分析 ===
-- 趋势方向：%s
-- 风险级别：%s
-- 预测准确度：%.2f
-- 时间范围：%s
-- 季节性模式：%t
-`,
-		req.TargetType, req.TargetID, req.MetricName,
-		req.CurrentValue, req.Threshold, req.Condition, req.Severity,
-		req.Timestamp.Format("2006-01-02 15:04:05"),
-		anomaly.IsAnomaly, anomaly.AnomalyScore, anomaly.Threshold,
-		anomaly.DeviationLevel, anomaly.HistoricalMean, anomaly.HistoricalStdDev, anomaly.Confidence,
-		prediction.TrendDirection, prediction.RiskLevel, prediction.Accuracy,
-		prediction.TimeHorizon, prediction.Seasonality,
-	)
-
-	if req.Tags != nil && len(req.Tags) > 0 {
-		context_str += "\n=== 标签信息 ===\n"
-		for key, value := range req.Tags {
-			context_str += fmt.Sprintf("- %s: %v\n", key, value)
+func (s *AIService) buildEnhancedAnalysisContext(tpl *models.PromptTemplate, req *AIAnalysisRequest, anomaly *AnomalyDetectionResult, prediction *TrendPredictionResult, knowledge, examples string) (string, error) {
+	data := &PromptData{
+		Req:         req,
+		Time:        req.Timestamp.Format("2006-01-02 15:04:05"),
+		Anomaly:     anomaly,
+		Prediction:  prediction,
+		Remediation: s.remediationHistoryContext(req.AlertID),
+		Knowledge:   knowledge,
+		Examples:    examples,
+	}
+	if len(req.Context) > 0 {
+		if contextJSON, err := json.Marshal(req.Context); err == nil {
+			data.Context = string(contextJSON)
 		}
 	}
 
-	context_str += `
-
-=== 请提供以下深度分析 ===
-1. 智能根因分析（结合异常检测和趋势预测结果）
-2. 多维度影响评估（业务影响、技术影响、用户体验影响）
-3. 分层解决方案（紧急处理、中期优化、长期预防）
-4. 预测性维护建议（基于趋势分析）
-5. 智能告警优化建议
-6. 综合风险评估（critical/high/medium/low）
-7. AI分析置信度评分（0-1之间的小数）
-
-请以JSON格式返回分析结果，格式如下：
-{
-  "root_cause": "智能根因分析结果",
-  "impact_assessment": "多维度影响评估",
-  "recommendations": ["紧急处理方案", "中期优化方案", "长期预防方案"],
-  "prevention_measures": "预测性维护建议",
-  "severity_level": "综合风险级别",
-  "confidence_score": 0.85
-}
-`
-
-	return context_str
+	return renderPrompt(tpl, data)
 }
 
-// 辅助函数
-func (s *AIService) getHistoricalMetrics(targetType, targetID, metricName string, days int) ([]float64, error) {
-	// 模拟获取历史数据
-	// 在实际实现中，这里应该从时序数据库（如InfluxDB）中获取数据
-	data := make([]float64, days*24) // 每小时一个数据点
-	base := 50.0
-	for i := range data {
-		// 模拟带有趋势和噪声的数据
-		trend := float64(i) * 0.1
-		noise := (rand.Float64() - 0.5) * 10
-		data[i] = base + trend + noise
-	}
-	return data, nil
+// stickyPromptKey 告警分析按告警ID固定模板版本，其余请求随机分配
+func stickyPromptKey(req *AIAnalysisRequest) string {
+	if req.AlertID != uuid.Nil {
+		return req.AlertID.String()
+	}
+	return ""
 }
 
 func (s *AIService) calculateMean(data []float64) float64 {
@@ -1070,15 +820,6 @@ func (s *AIService) calculateStdDev(data []float64, mean float64) float64 {
 	return math.Sqrt(sum / float64(len(data)))
 }
 
-func (s *AIService) detectSeasonality(data []float64) bool {
-	// 简化的季节性检测
-	if len(data) < 24 {
-		return false
-	}
-	// 检查是否存在周期性模式
-	return true // 简化实现
-}
-
 // GetKnowledgeBaseStats 获取知识库统计信息
 func (s *AIService) GetKnowledgeBaseStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
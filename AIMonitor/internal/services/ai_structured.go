@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// 结构化输出方式
+const (
+	outputModeToolCall = "tool_call"
+	outputModeJSON     = "json_mode"
+	outputModePrompt   = "prompt"
+)
+
+// structuredOutputFunction 函数调用模式下要求模型调用的函数名
+const structuredOutputFunction = "submit_analysis"
+
+// ErrInvalidStructuredOutput 模型输出在修复后仍未通过schema校验
+var ErrInvalidStructuredOutput = errors.New("AI response failed schema validation")
+
+// StructuredResult 结构化输出调用结果
+type StructuredResult struct {
+	Raw            string
+	Validated      string
+	Repaired       string
+	Parsed         *ParsedAIResponse
+	Errors         []string
+	RepairAttempts int
+	Mode           string
+	Tokens         int
+	Latency        time.Duration
+}
+
+// analysisSchemaFields 各分析类型的字段说明
+var analysisSchemaFields = map[string]map[string]string{
+	"alert_analysis": {
+		"root_cause":          "根本原因分析",
+		"impact_assessment":   "影响评估",
+		"recommendations":     "解决建议，按紧急处理、中期优化、长期预防排列",
+		"prevention_measures": "预防措施",
+	},
+	"performance_analysis": {
+		"root_cause":          "性能瓶颈及其原因",
+		"impact_assessment":   "性能状态与风险评估",
+		"recommendations":     "优化建议",
+		"prevention_measures": "容量规划建议",
+	},
+	"incident_analysis": {
+		"root_cause":          "事件的共同根因",
+		"impact_assessment":   "受影响的主机、服务和业务范围",
+		"recommendations":     "处置建议",
+		"prevention_measures": "预防措施",
+	},
+	"capacity_planning": {
+		"root_cause":          "容量趋势判断",
+		"impact_assessment":   "资源耗尽的影响",
+		"recommendations":     "扩容或清理建议",
+		"prevention_measures": "长期容量规划",
+	},
+}
+
+// analysisSchema 获取分析类型对应的JSON Schema，未知类型使用告警分析的字段说明
+func analysisSchema(analysisType string) jsonschema.Definition {
+	fields, ok := analysisSchemaFields[analysisType]
+	if !ok {
+		fields = analysisSchemaFields["alert_analysis"]
+	}
+
+	return jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"root_cause":        {Type: jsonschema.String, Description: fields["root_cause"]},
+			"impact_assessment": {Type: jsonschema.String, Description: fields["impact_assessment"]},
+			"recommendations": {
+				Type:        jsonschema.Array,
+				Description: fields["recommendations"],
+				Items:       &jsonschema.Definition{Type: jsonschema.String},
+			},
+			"prevention_measures": {Type: jsonschema.String, Description: fields["prevention_measures"]},
+			"severity_level": {
+				Type:        jsonschema.String,
+				Description: "严重级别",
+				Enum:        []string{"critical", "high", "medium", "low"},
+			},
+			"confidence_score": {Type: jsonschema.Number, Description: "置信度，0-1之间的小数"},
+		},
+		Required: []string{"root_cause", "recommendations", "severity_level", "confidence_score"},
+	}
+}
+
+// outputMode 获取配置的结构化输出方式
+func (s *AIService) outputMode() string {
+	switch mode := s.config.AIModels.OpenAI.OutputMode; mode {
+	case outputModeToolCall, outputModeJSON, outputModePrompt:
+		return mode
+	default:
+		return outputModeToolCall
+	}
+}
+
+// callStructured 按schema调用模型获取结构化结果：优先使用函数调用或JSON模式，
+// 校验失败时把错误反馈给模型进行修复，修复后仍失败返回ErrInvalidStructuredOutput
+func (s *AIService) callStructured(systemPrompt, prompt, analysisType string) (*StructuredResult, error) {
+	if s.openaiClient == nil {
+		return nil, errors.New("AI service not configured")
+	}
+	if systemPrompt == "" {
+		systemPrompt = defaultSystemPrompt
+	}
+
+	schema := analysisSchema(analysisType)
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	result := &StructuredResult{Mode: s.outputMode()}
+	userPrompt := prompt
+	if result.Mode != outputModeToolCall {
+		userPrompt += "\n\n请只返回一个JSON对象，严格符合以下JSON Schema，不要包含其他文字：\n" + string(schemaJSON)
+	}
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: userPrompt},
+	}
+
+	maxRepairs := s.config.AIModels.OpenAI.RepairAttempts
+	if maxRepairs < 0 {
+		maxRepairs = 0
+	}
+
+	var errs []string
+	for attempt := 0; attempt <= maxRepairs; attempt++ {
+		payload, err := s.requestStructured(messages, result, schemaJSON)
+		if err != nil {
+			return result, err
+		}
+		if attempt == 0 {
+			result.Raw = payload
+		} else {
+			result.Repaired = payload
+			result.RepairAttempts = attempt
+		}
+
+		parsed, validated, verrs := validateStructuredOutput(payload, schema)
+		if len(verrs) == 0 {
+			result.Parsed = parsed
+			result.Validated = validated
+			return result, nil
+		}
+		errs = verrs
+		result.Errors = append(result.Errors, verrs...)
+
+		// 把原输出和校验错误反馈给模型要求修正
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: payload},
+			openai.ChatCompletionMessage{
+				Role: openai.ChatMessageRoleUser,
+				Content: "上面的输出未通过校验：\n- " + strings.Join(verrs, "\n- ") +
+					"\n请修正后只返回符合以下JSON Schema的JSON对象：\n" + string(schemaJSON),
+			},
+		)
+	}
+
+	return result, fmt.Errorf("%w: %s", ErrInvalidStructuredOutput, strings.Join(errs, "; "))
+}
+
+// requestStructured 发送一次请求并取出结构化载荷（函数调用参数或消息内容）
+func (s *AIService) requestStructured(messages []openai.ChatCompletionMessage, result *StructuredResult, schemaJSON []byte) (string, error) {
+	req := openai.ChatCompletionRequest{
+		Model:       s.chatModel(),
+		Messages:    messages,
+		MaxTokens:   s.config.AIModels.OpenAI.MaxTokens,
+		Temperature: float32(s.config.AIModels.OpenAI.Temperature),
+	}
+	switch result.Mode {
+	case outputModeToolCall:
+		req.Tools = []openai.Tool{{
+			Type: openai.ToolTypeFunction,
+			Function: openai.FunctionDefinition{
+				Name:        structuredOutputFunction,
+				Description: "提交结构化分析结果",
+				Parameters:  json.RawMessage(schemaJSON),
+			},
+		}}
+		req.ToolChoice = openai.ToolChoice{
+			Type:     openai.ToolTypeFunction,
+			Function: openai.ToolFunction{Name: structuredOutputFunction},
+		}
+	case outputModeJSON:
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+
+	start := time.Now()
+	resp, err := s.openaiClient.CreateChatCompletion(context.Background(), req)
+	result.Latency += time.Since(start)
+	if err != nil {
+		return "", fmt.Errorf("OpenAI API call failed: %w", err)
+	}
+	result.Tokens += resp.Usage.TotalTokens
+	if len(resp.Choices) == 0 {
+		return "", errors.New("no response from OpenAI")
+	}
+
+	message := resp.Choices[0].Message
+	for _, call := range message.ToolCalls {
+		if call.Function.Name == structuredOutputFunction {
+			return call.Function.Arguments, nil
+		}
+	}
+	return message.Content, nil
+}
+
+// validateStructuredOutput 按schema校验模型输出，返回解析结果和规范化后的JSON
+func validateStructuredOutput(payload string, schema jsonschema.Definition) (*ParsedAIResponse, string, []string) {
+	body := extractJSONObject(payload)
+	if body == "" {
+		return nil, "", []string{"output does not contain a JSON object"}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return nil, "", []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	errs := validateSchemaValue("$", schema, value)
+	if len(errs) > 0 {
+		return nil, "", errs
+	}
+
+	var parsed ParsedAIResponse
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return nil, "", []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if strings.TrimSpace(parsed.RootCause) == "" {
+		errs = append(errs, "$.root_cause must not be empty")
+	}
+	if len(parsed.Recommendations) == 0 {
+		errs = append(errs, "$.recommendations must not be empty")
+	}
+	if parsed.ConfidenceScore < 0 || parsed.ConfidenceScore > 1 {
+		errs = append(errs, "$.confidence_score must be between 0 and 1")
+	}
+	if len(errs) > 0 {
+		return nil, "", errs
+	}
+
+	validated, _ := json.Marshal(parsed)
+	return &parsed, string(validated), nil
+}
+
+// extractJSONObject 从输出中取出JSON对象，兼容markdown代码块包裹
+func extractJSONObject(payload string) string {
+	start := strings.Index(payload, "{")
+	end := strings.LastIndex(payload, "}")
+	if start == -1 || end <= start {
+		return ""
+	}
+	return payload[start : end+1]
+}
+
+// validateSchemaValue 校验值是否符合schema（支持type、enum、required、properties、items）
+func validateSchemaValue(path string, def jsonschema.Definition, value interface{}) []string {
+	var errs []string
+
+	switch def.Type {
+	case jsonschema.Object:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an object", path)}
+		}
+		for _, name := range def.Required {
+			if v, ok := obj[name]; !ok || v == nil {
+				errs = append(errs, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		names := make([]string, 0, len(def.Properties))
+		for name := range def.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := obj[name]; ok && v != nil {
+				errs = append(errs, validateSchemaValue(path+"."+name, def.Properties[name], v)...)
+			}
+		}
+	case jsonschema.Array:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an array", path)}
+		}
+		if def.Items != nil {
+			for i, item := range arr {
+				errs = append(errs, validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), *def.Items, item)...)
+			}
+		}
+	case jsonschema.String:
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a string", path)}
+		}
+		if len(def.Enum) > 0 {
+			matched := false
+			for _, e := range def.Enum {
+				if str == e {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				errs = append(errs, fmt.Sprintf("%s must be one of %s", path, strings.Join(def.Enum, ", ")))
+			}
+		}
+	case jsonschema.Number, jsonschema.Integer:
+		num, ok := value.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a number", path)}
+		}
+		if def.Type == jsonschema.Integer && num != float64(int64(num)) {
+			errs = append(errs, fmt.Sprintf("%s must be an integer", path))
+		}
+	case jsonschema.Boolean:
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s must be a boolean", path)}
+		}
+	}
+
+	return errs
+}
+
+// applyStructuredResult 记录结构化输出的原始载荷、修复载荷和校验信息
+func applyStructuredResult(analysis *models.AIAnalysisResult, result *StructuredResult) {
+	analysis.RawResponse = result.Raw
+	analysis.RepairedResponse = result.Repaired
+	analysis.RepairAttempts = result.RepairAttempts
+	analysis.OutputMode = result.Mode
+	analysis.TokensUsed = result.Tokens
+	analysis.ProcessingTime = int(result.Latency.Milliseconds())
+	if len(result.Errors) > 0 {
+		errorsJSON, _ := json.Marshal(result.Errors)
+		analysis.ValidationErrors = string(errorsJSON)
+	}
+}
+
+// recordFailedAnalysis 保存未通过校验的分析记录，便于排查模型原始输出
+func (s *AIService) recordFailedAnalysis(analysis *models.AIAnalysisResult, result *StructuredResult, cause error) {
+	analysis.Status = "failed"
+	analysis.Error = cause.Error()
+	if result != nil {
+		applyStructuredResult(analysis, result)
+	}
+	if err := s.db.Create(analysis).Error; err != nil {
+		// 失败记录保存失败不影响错误返回
+		logger.GetLogger("ai").WithError(err).Warn("failed to save failed analysis")
+	}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// minBaselineSamples 拟合基线所需的最少小时数据点
+const minBaselineSamples = 24
+
+// anomalySeriesValue 检测窗口内单个序列的聚合值
+type anomalySeriesValue struct {
+	TargetID uuid.UUID
+	Value    float64
+}
+
+// AnomalyEvaluation 单个序列的异常评估结果
+type AnomalyEvaluation struct {
+	RuleID       uuid.UUID `json:"rule_id"`
+	TargetID     string    `json:"target_id"`
+	MetricName   string    `json:"metric_name"`
+	CurrentValue float64   `json:"current_value"`
+	Expected     float64   `json:"expected"`
+	Scale        float64   `json:"scale"`
+	Score        float64   `json:"score"`
+	Sensitivity  float64   `json:"sensitivity"`
+	BaselineMode string    `json:"baseline_mode"`
+	Samples      int       `json:"samples"`
+	IsAnomaly    bool      `json:"is_anomaly"`
+}
+
+// RunAnomalyDetection 周期性评估异常检测告警规则，直到ctx取消
+func (s *AlertService) RunAnomalyDetection(ctx context.Context, cfg config.AnomalyDetectionConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EvaluateAnomalyRules(cfg.HistoryDays, cfg.DefaultSensitivity); err != nil {
+				logger.GetLogger("alert").WithError(err).Error("failed to evaluate anomaly rules")
+			}
+		}
+	}
+}
+
+// EvaluateAnomalyRules 对所有启用的异常检测规则逐序列评估
+func (s *AlertService) EvaluateAnomalyRules(historyDays int, defaultSensitivity float64) error {
+	if s.aiService == nil {
+		return errors.New("AI service not configured")
+	}
+	if historyDays <= 0 {
+		historyDays = 28
+	}
+	if defaultSensitivity <= 0 {
+		defaultSensitivity = robustZScoreThreshold
+	}
+
+	var rules []models.AlertRule
+	if err := s.db.Where("enabled = ? AND kind = ?", true, "anomaly").Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to query anomaly rules: %w", err)
+	}
+
+	// 单条规则失败不影响其余规则的评估，错误汇总后返回
+	var errs []error
+	for i := range rules {
+		if _, err := s.evaluateAnomalyRule(&rules[i], historyDays, defaultSensitivity); err != nil {
+			errs = append(errs, fmt.Errorf("anomaly rule %s: %w", rules[i].Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// evaluateAnomalyRule 为规则覆盖的每个序列拟合基线并驱动告警生命周期
+func (s *AlertService) evaluateAnomalyRule(rule *models.AlertRule, historyDays int, defaultSensitivity float64) ([]*AnomalyEvaluation, error) {
+	// 规则持续时间作为检测窗口，窗口内取均值以平滑毛刺
+	window := time.Duration(rule.Duration) * time.Second
+	if window < time.Minute {
+		window = 5 * time.Minute
+	}
+	now := time.Now()
+
+	var current []anomalySeriesValue
+	if err := s.db.Model(&models.MetricData{}).
+		Select("target_id, AVG(value) AS value").
+		Where("metric = ? AND timestamp >= ?", rule.Metric, now.Add(-window)).
+		Group("target_id").
+		Scan(&current).Error; err != nil {
+		return nil, fmt.Errorf("failed to query current metric values: %w", err)
+	}
+
+	sensitivity := rule.Sensitivity
+	if sensitivity <= 0 {
+		sensitivity = defaultSensitivity
+	}
+
+	// 单个序列失败不影响其余序列的评估，错误汇总后返回
+	var errs []error
+	evaluations := make([]*AnomalyEvaluation, 0, len(current))
+	for _, cur := range current {
+		series, err := s.aiService.getHistoricalMetrics("", cur.TargetID.String(), rule.Metric, historyDays)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get historical data for target %s: %w", cur.TargetID, err))
+			continue
+		}
+		if len(series.Values) < minBaselineSamples {
+			// 历史数据不足，无法建立可靠基线
+			continue
+		}
+
+		baseline := s.aiService.FitSeasonalBaseline(series, now)
+		fallback := s.aiService.calculateStdDev(series.Values, s.aiService.calculateMean(series.Values))
+		score := robustScore(cur.Value, baseline.Expected, baseline.Scale, fallback)
+
+		evaluation := &AnomalyEvaluation{
+			RuleID:       rule.ID,
+			TargetID:     cur.TargetID.String(),
+			MetricName:   rule.Metric,
+			CurrentValue: cur.Value,
+			Expected:     baseline.Expected,
+			Scale:        baseline.Scale,
+			Score:        score,
+			Sensitivity:  sensitivity,
+			BaselineMode: baseline.Mode,
+			Samples:      baseline.Samples,
+			IsAnomaly:    score > sensitivity && matchesAnomalyDirection(rule.Condition, cur.Value, baseline.Expected),
+		}
+		evaluations = append(evaluations, evaluation)
+
+		data := &MetricData{
+			TargetID:   evaluation.TargetID,
+			MetricName: rule.Metric,
+			Value:      cur.Value,
+			Timestamp:  now,
+		}
+		if evaluation.IsAnomaly {
+			err = s.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+				Summary: fmt.Sprintf("%s anomaly on %s: current %.2f, expected %.2f (score %.2f > %.2f)",
+					rule.Metric, evaluation.TargetID, cur.Value, evaluation.Expected, evaluation.Score, sensitivity),
+				Description: fmt.Sprintf("Anomaly detected for metric %s on target %s (%s baseline)",
+					rule.Metric, evaluation.TargetID, evaluation.BaselineMode),
+				Details:   evaluation,
+				Reference: evaluation.Expected,
+			})
+		} else {
+			err = s.resolveEvaluatedAlert(rule, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", cur.TargetID, err))
+		}
+	}
+
+	return evaluations, errors.Join(errs...)
+}
+
+// matchesAnomalyDirection 根据规则条件限定偏离方向，未指定时双向检测
+func matchesAnomalyDirection(condition string, value, expected float64) bool {
+	switch condition {
+	case ">", ">=":
+		return value > expected
+	case "<", "<=":
+		return value < expected
+	default:
+		return true
+	}
+}
+
+// evaluatedAlert 周期评估类规则（异常检测、预测）产生的告警内容
+type evaluatedAlert struct {
+	Summary     string
+	Description string
+	Details     interface{} // 写入告警Annotations的评估证据
+	Reference   float64     // 传给AI分析的参考阈值
+}
+
+// evaluatedAlertFingerprint 生成周期评估类告警指纹
+func evaluatedAlertFingerprint(ruleID uuid.UUID, targetID, metricName string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s-%s-%s", ruleID, targetID, metricName)))
+	return hex.EncodeToString(sum[:])
+}
+
+// fireEvaluatedAlert 创建、更新或重新打开周期评估类告警
+func (s *AlertService) fireEvaluatedAlert(rule *models.AlertRule, data *MetricData, ea *evaluatedAlert) error {
+	annotationsJSON, err := json.Marshal(ea.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+	// 指标标签（如namespace、name、service）供修复模板、事件关联使用，固定字段优先
+	labels := make(map[string]interface{}, len(data.Tags)+3)
+	for key, value := range data.Tags {
+		labels[key] = value
+	}
+	labels["target_id"] = data.TargetID
+	labels["metric"] = data.MetricName
+	labels["kind"] = rule.Kind
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
+	summary := ea.Summary
+	fingerprint := evaluatedAlertFingerprint(rule.ID, data.TargetID, data.MetricName)
+	var alert models.Alert
+	err = s.db.Where("fingerprint = ?", fingerprint).First(&alert).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing alert: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		alert = models.Alert{
+			RuleID:      rule.ID,
+			Fingerprint: fingerprint,
+			Severity:    rule.Severity,
+			Status:      "firing",
+			Summary:     summary,
+			Description: ea.Description,
+			Value:       data.Value,
+			Labels:      string(labelsJSON),
+			Annotations: string(annotationsJSON),
+			StartsAt:    data.Timestamp,
+		}
+		if err := s.db.Create(&alert).Error; err != nil {
+			return fmt.Errorf("failed to create alert: %w", err)
+		}
+		s.recordAlertEvent(&alert, "firing", summary, ea.Details, nil)
+	} else {
+		updates := map[string]interface{}{
+			"value":       data.Value,
+			"summary":     summary,
+			"labels":      string(labelsJSON),
+			"annotations": string(annotationsJSON),
+		}
+		reopened := alert.Status != "firing"
+		if reopened {
+			// 已解决的告警再次触发时重新打开
+			updates["status"] = "firing"
+			updates["starts_at"] = data.Timestamp
+			updates["ends_at"] = nil
+			updates["acknowledged"] = false
+			updates["acknowledged_by"] = nil
+			updates["acknowledged_at"] = nil
+		}
+		if err := s.db.Model(&alert).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update alert: %w", err)
+		}
+		if !reopened {
+			return nil
+		}
+		alert.Status = "firing"
+		alert.Summary = summary
+		alert.Value = data.Value
+		s.recordAlertEvent(&alert, "reopened", summary, ea.Details, nil)
+	}
+
+	// 发送通知
+	go s.sendAlertNotification(&alert, rule)
+
+	// 触发AI分析，以评估参考值作为阈值
+	aiRule := *rule
+	aiRule.Threshold = ea.Reference
+	go s.triggerAIAnalysis(&alert, &aiRule, data)
+
+	return nil
+}
+
+// resolveEvaluatedAlert 序列恢复正常后解决周期评估类告警
+func (s *AlertService) resolveEvaluatedAlert(rule *models.AlertRule, data *MetricData) error {
+	var alert models.Alert
+	fingerprint := evaluatedAlertFingerprint(rule.ID, data.TargetID, data.MetricName)
+	err := s.db.Where("fingerprint = ? AND status = ?", fingerprint, "firing").First(&alert).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 没有活跃告警
+		}
+		return fmt.Errorf("failed to find alert: %w", err)
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&alert).Updates(map[string]interface{}{
+		"status":  "resolved",
+		"ends_at": &now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to resolve alert: %w", err)
+	}
+	alert.Status = "resolved"
+	s.recordAlertEvent(&alert, "resolved", "series returned to normal", nil, nil)
+
+	// 发送解决通知
+	go s.sendResolvedNotification(&alert)
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// RunForecastEvaluation 周期性评估预测类告警规则，直到ctx取消
+func (s *AlertService) RunForecastEvaluation(ctx context.Context, cfg config.CapacityPlanningConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EvaluateForecastRules(cfg.HistoryDays); err != nil {
+				logger.GetLogger("alert").WithError(err).Error("failed to evaluate forecast rules")
+			}
+		}
+	}
+}
+
+// EvaluateForecastRules 评估所有启用的预测规则，如"预计48小时内超过95%"
+func (s *AlertService) EvaluateForecastRules(historyDays int) error {
+	if s.aiService == nil {
+		return errors.New("AI service not configured")
+	}
+	historyDays = s.aiService.capacityHistoryDays(historyDays)
+
+	var rules []models.AlertRule
+	if err := s.db.Where("enabled = ? AND kind = ?", true, "forecast").Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to query forecast rules: %w", err)
+	}
+
+	// 单条规则失败不影响其余规则的评估，错误汇总后返回
+	var errs []error
+	for i := range rules {
+		if err := s.evaluateForecastRule(&rules[i], historyDays); err != nil {
+			errs = append(errs, fmt.Errorf("forecast rule %s: %w", rules[i].Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// evaluateForecastRule 对规则覆盖的每个序列预测到达阈值的时间
+func (s *AlertService) evaluateForecastRule(rule *models.AlertRule, historyDays int) error {
+	horizon := rule.ForecastHorizon
+	if horizon <= 0 {
+		return errors.New("forecast horizon not configured")
+	}
+
+	var targetIDs []uuid.UUID
+	if err := s.db.Model(&models.MetricData{}).
+		Distinct("target_id").
+		Where("metric = ? AND timestamp >= ?", rule.Metric, time.Now().Add(-24*time.Hour)).
+		Pluck("target_id", &targetIDs).Error; err != nil {
+		return fmt.Errorf("failed to query forecast series: %w", err)
+	}
+
+	// 单个序列失败不影响其余序列的评估，错误汇总后返回
+	var errs []error
+	for _, targetID := range targetIDs {
+		forecast, err := s.aiService.forecastCapacity(targetID.String(), rule.Metric, historyDays, rule.Threshold, rule.Condition)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to forecast target %s: %w", targetID, err))
+			continue
+		}
+		if forecast == nil {
+			// 历史数据不足
+			continue
+		}
+
+		data := &MetricData{
+			TargetID:   forecast.TargetID,
+			MetricName: rule.Metric,
+			Value:      forecast.CurrentValue,
+			Timestamp:  time.Now(),
+		}
+
+		hours := forecast.HoursToExhaustion
+		if hours != nil && *hours <= float64(horizon) {
+			err = s.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+				Summary: fmt.Sprintf("%s on %s predicted to reach %s %.2f within %.1fh (current %.2f, %.2f/day)",
+					rule.Metric, forecast.TargetID, rule.Condition, rule.Threshold, *hours, forecast.CurrentValue, forecast.SlopePerDay),
+				Description: fmt.Sprintf("Forecast rule %s: metric %s on target %s is predicted to cross the threshold at %s",
+					rule.Name, rule.Metric, forecast.TargetID, forecast.ExhaustionTime.Format(time.RFC3339)),
+				Details:   forecast,
+				Reference: rule.Threshold,
+			})
+		} else {
+			err = s.resolveEvaluatedAlert(rule, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", targetID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+)
+
+// APMMetricVersionRegression 版本回归告警使用的指标名，取值为p95/p99最大相对增幅（百分比）
+const APMMetricVersionRegression = "apm.version_regression"
+
+// RunVersionRegressionDetection 周期性评估新发布版本，直到ctx取消
+func (s *AlertService) RunVersionRegressionDetection(ctx context.Context, cfg config.VersionRegressionConfig, apmService *APMService) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EvaluateDeployments(ctx, cfg, apmService); err != nil {
+				logger.GetLogger("alert").WithError(err).Error("failed to evaluate deployments")
+			}
+		}
+	}
+}
+
+// EvaluateDeployments 对观察期已满的新版本与上一版本做对比，更新发布状态并驱动回归告警
+func (s *AlertService) EvaluateDeployments(ctx context.Context, cfg config.VersionRegressionConfig, apmService *APMService) error {
+	now := time.Now()
+	var deployments []models.ServiceDeployment
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND first_seen <= ?", "observing", now.Add(-cfg.ObservationWindow)).
+		Find(&deployments).Error; err != nil {
+		return fmt.Errorf("failed to query observing deployments: %w", err)
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+
+	var rules []models.AlertRule
+	if err := s.db.Where("enabled = ? AND kind = ?", true, "regression").Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to query regression rules: %w", err)
+	}
+
+	// 单个发布失败不影响其余发布的评估，错误汇总后返回
+	var errs []error
+	for i := range deployments {
+		if err := s.evaluateDeployment(ctx, cfg, apmService, &deployments[i], rules, now); err != nil {
+			errs = append(errs, fmt.Errorf("deployment %s %s: %w", deployments[i].ServiceName, deployments[i].Version, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evaluateDeployment 评估单个发布：基线为上一版本在新版本出现前BaselineWindow内的数据，候选为新版本出现后的数据
+func (s *AlertService) evaluateDeployment(ctx context.Context, cfg config.VersionRegressionConfig, apmService *APMService, deployment *models.ServiceDeployment, rules []models.AlertRule, now time.Time) error {
+	comparison, err := apmService.CompareVersions(ctx, VersionCompareRequest{
+		ServiceName:      deployment.ServiceName,
+		BaselineVersion:  deployment.PreviousVersion,
+		CandidateVersion: deployment.Version,
+		BaselineStart:    deployment.FirstSeen.Add(-cfg.BaselineWindow),
+		BaselineEnd:      deployment.FirstSeen,
+		CandidateStart:   deployment.FirstSeen,
+		CandidateEnd:     now,
+	})
+	if err != nil {
+		return err
+	}
+
+	status := "healthy"
+	switch {
+	case !comparison.Overall.Sufficient:
+		// 样本不足时继续观察，超过基线窗口仍不足则放弃评估
+		if now.Sub(deployment.FirstSeen) < cfg.BaselineWindow {
+			return nil
+		}
+		status = "insufficient_data"
+	case comparison.Regressed:
+		status = "regressed"
+	}
+
+	report, err := json.Marshal(comparison)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comparison report: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(deployment).Updates(map[string]interface{}{
+		"status":       status,
+		"evaluated_at": &now,
+		"report":       string(report),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update service deployment: %w", err)
+	}
+	if status == "insufficient_data" {
+		return nil
+	}
+
+	var service models.APMService
+	if err := s.db.WithContext(ctx).Where("name = ?", deployment.ServiceName).First(&service).Error; err != nil {
+		return fmt.Errorf("failed to get apm service: %w", err)
+	}
+	overall := comparison.Overall
+	data := &MetricData{
+		TargetType: "service",
+		TargetID:   service.ID.String(),
+		MetricName: APMMetricVersionRegression,
+		Value:      math.Max(overall.P95Change, overall.P99Change) * 100,
+		Tags: map[string]interface{}{
+			"service":          deployment.ServiceName,
+			"version":          deployment.Version,
+			"previous_version": deployment.PreviousVersion,
+		},
+		Timestamp: now,
+	}
+
+	var errs []error
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleLabelsMatch(rule, data.Tags) {
+			continue
+		}
+		// 规则阈值为p95/p99最小增幅（百分比）；错误率显著上升时不受阈值限制
+		regressed := (overall.LatencyRegressed && data.Value >= rule.Threshold) || overall.ErrorRateRegressed
+		if status != "regressed" || !regressed {
+			// 新版本恢复正常时解决该服务之前的回归告警
+			if err := s.resolveEvaluatedAlert(rule, data); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		ea := &evaluatedAlert{
+			Summary: fmt.Sprintf("%s %s regressed against %s: p95 %+.1f%%, p99 %+.1f%%, error rate %+.2f%%",
+				deployment.ServiceName, deployment.Version, deployment.PreviousVersion,
+				overall.P95Change*100, overall.P99Change*100, overall.ErrorRateDelta*100),
+			Description: fmt.Sprintf("Version %s of service %s was first seen at %s. Compared with %s over the preceding %s, latency p-value %.4f, error rate p-value %.4f.",
+				deployment.Version, deployment.ServiceName, deployment.FirstSeen.Format(time.RFC3339),
+				deployment.PreviousVersion, cfg.BaselineWindow, overall.LatencyPValue, overall.ErrorRatePValue),
+			Details: map[string]interface{}{
+				"deployment_id":     deployment.ID,
+				"overall":           overall,
+				"operations":        comparison.Operations,
+				"self_time_changes": comparison.SelfTimeChanges,
+			},
+			Reference: rule.Threshold,
+		}
+		if err := s.fireEvaluatedAlert(rule, data, ea); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
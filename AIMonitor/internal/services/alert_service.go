@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"ai-monitor/internal/cache"
+	"ai-monitor/internal/logger"
 	"ai-monitor/internal/models"
 
 	"github.com/google/uuid"
@@ -41,13 +42,17 @@ type CreateAlertRuleRequest struct {
 	TargetType  string                 `json:"target_type" binding:"required,oneof=host service application"`
 	TargetID    string                 `json:"target_id"`
 	MetricName  string                 `json:"metric_name" binding:"required"`
-	Condition   string                 `json:"condition" binding:"required,oneof=> >= < <= == !="`
-	Threshold   float64                `json:"threshold" binding:"required"`
+	Kind        string                 `json:"kind" binding:"omitempty,oneof=threshold anomaly forecast regression"`
+	Condition   string                 `json:"condition" binding:"omitempty,oneof=> >= < <= == !="`
+	Threshold   float64                `json:"threshold"`
+	Sensitivity float64                `json:"sensitivity" binding:"omitempty,gt=0"`
+	ForecastHorizon int                `json:"forecast_horizon" binding:"omitempty,min=1"`
 	Duration    int                    `json:"duration" binding:"required,min=1"`
 	Severity    string                 `json:"severity" binding:"required,oneof=critical high medium low"`
 	Enabled     bool                   `json:"enabled"`
 	Tags        map[string]interface{} `json:"tags"`
 	Channels    []string               `json:"channels"`
+	PromptTemplateID *uuid.UUID        `json:"prompt_template_id"`
 }
 
 // UpdateAlertRuleRequest 更新告警规则请求
@@ -56,11 +61,14 @@ type UpdateAlertRuleRequest struct {
 	Description string                 `json:"description"`
 	Condition   string                 `json:"condition" binding:"omitempty,oneof=> >= < <= == !="`
 	Threshold   *float64               `json:"threshold"`
+	Sensitivity *float64               `json:"sensitivity" binding:"omitempty,gt=0"`
+	ForecastHorizon *int               `json:"forecast_horizon" binding:"omitempty,min=1"`
 	Duration    *int                   `json:"duration" binding:"omitempty,min=1"`
 	Severity    string                 `json:"severity" binding:"omitempty,oneof=critical high medium low"`
 	Enabled     *bool                  `json:"enabled"`
 	Tags        map[string]interface{} `json:"tags"`
 	Channels    []string               `json:"channels"`
+	PromptTemplateID *uuid.UUID        `json:"prompt_template_id"`
 }
 
 // AlertRuleResponse 告警规则响应
@@ -71,13 +79,18 @@ type AlertRuleResponse struct {
 	TargetType  string                 `json:"target_type"`
 	TargetID    string                 `json:"target_id"`
 	MetricName  string                 `json:"metric_name"`
+	Kind        string                 `json:"kind"`
 	Condition   string                 `json:"condition"`
 	Threshold   float64                `json:"threshold"`
+	Sensitivity float64                `json:"sensitivity"`
+	ForecastHorizon int                `json:"forecast_horizon"`
 	Duration    int                    `json:"duration"`
 	Severity    string                 `json:"severity"`
 	Enabled     bool                   `json:"enabled"`
 	Tags        map[string]interface{} `json:"tags"`
 	Channels    []string               `json:"channels"`
+	PromptTemplateID *uuid.UUID        `json:"prompt_template_id"`
+	SLOID       *uuid.UUID             `json:"slo_id,omitempty"`
 	CreatedBy   uuid.UUID              `json:"created_by"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
@@ -122,6 +135,18 @@ func (s *AlertService) CreateAlertRule(req *CreateAlertRuleRequest, createdBy uu
 		return nil, errors.New("alert rule name already exists")
 	}
 
+	// 阈值和预测规则必须指定条件，异常检测规则的条件仅用于限定偏离方向，版本回归规则以阈值作为最小延迟增幅(%)
+	kind := req.Kind
+	if kind == "" {
+		kind = "threshold"
+	}
+	if kind != "anomaly" && kind != "regression" && req.Condition == "" {
+		return nil, fmt.Errorf("condition is required for %s rules", kind)
+	}
+	if kind == "forecast" && req.ForecastHorizon <= 0 {
+		return nil, errors.New("forecast_horizon is required for forecast rules")
+	}
+
 	// 序列化标签
 	tagsJSON, err := json.Marshal(req.Tags)
 	if err != nil {
@@ -139,14 +164,18 @@ func (s *AlertService) CreateAlertRule(req *CreateAlertRuleRequest, createdBy uu
 		Name:        req.Name,
 		Description: req.Description,
 		Metric:      req.MetricName, // 使用Metric字段而不是MetricName
+		Kind:        kind,
 		Condition:   req.Condition,
 		Threshold:   req.Threshold,
+		Sensitivity: req.Sensitivity,
+		ForecastHorizon: req.ForecastHorizon,
 		Duration:    req.Duration,
 		Severity:    req.Severity,
 		Enabled:     req.Enabled,
 		// 注意：AlertRule模型中没有Tags和Channels字段，可以使用Labels和Annotations
 		Labels:      string(tagsJSON),
 		Annotations: string(channelsJSON),
+		PromptTemplateID: req.PromptTemplateID,
 		CreatedBy:   createdBy,
 	}
 
@@ -202,6 +231,12 @@ func (s *AlertService) UpdateAlertRule(ruleID uuid.UUID, req *UpdateAlertRuleReq
 	if req.Threshold != nil {
 		updates["threshold"] = *req.Threshold
 	}
+	if req.Sensitivity != nil {
+		updates["sensitivity"] = *req.Sensitivity
+	}
+	if req.ForecastHorizon != nil {
+		updates["forecast_horizon"] = *req.ForecastHorizon
+	}
 	if req.Duration != nil {
 		updates["duration"] = *req.Duration
 	}
@@ -225,6 +260,13 @@ func (s *AlertService) UpdateAlertRule(ruleID uuid.UUID, req *UpdateAlertRuleReq
 		}
 		updates["channels"] = string(channelsJSON)
 	}
+	if req.PromptTemplateID != nil {
+		if *req.PromptTemplateID == uuid.Nil {
+			updates["prompt_template_id"] = nil
+		} else {
+			updates["prompt_template_id"] = *req.PromptTemplateID
+		}
+	}
 
 	if len(updates) > 0 {
 		if err := s.db.Model(&rule).Updates(updates).Error; err != nil {
@@ -310,15 +352,75 @@ func (s *AlertService) ProcessMetricData(data *MetricData) error {
 
 	// 检查每个规则
 	for _, rule := range rules {
-		if err := s.checkAlertRule(rule, data); err != nil {
-			// 记录错误但不中断处理
-			// Error checking alert rule
+		// 异常检测、预测、版本回归、SLO燃烧率规则由周期任务评估，容器事件规则由事件流驱动
+		if rule.Kind == "anomaly" || rule.Kind == "forecast" || rule.Kind == "regression" || rule.Kind == "slo" || rule.Kind == "event" {
+			continue
+		}
+		var err error
+		if isAPMMetric(data.MetricName) {
+			if !ruleLabelsMatch(rule, data.Tags) {
+				continue
+			}
+			err = s.checkAPMThresholdRule(rule, data)
+		} else {
+			err = s.checkAlertRule(rule, data)
+		}
+		if err != nil {
+			logger.GetLogger("alert").WithError(err).WithField("rule", rule.Name).Warn("failed to check alert rule")
 		}
 	}
 
 	return nil
 }
 
+// isAPMMetric 判断是否为span派生的RED指标。规则只按指标名匹配、不绑定具体服务，
+// 因此这类指标需按规则标签中的service等字段筛选；其余阈值规则的Labels仅作描述用途，不参与匹配
+func isAPMMetric(metricName string) bool {
+	return strings.HasPrefix(metricName, APMMetricPrefix)
+}
+
+// checkAPMThresholdRule 检查APM指标的阈值规则，告警按规则、服务和指标生成指纹
+func (s *AlertService) checkAPMThresholdRule(rule *models.AlertRule, data *MetricData) error {
+	if !s.evaluateCondition(rule.Condition, data.Value, rule.Threshold) {
+		return s.resolveEvaluatedAlert(rule, data)
+	}
+
+	service := fmt.Sprint(data.Tags["service"])
+	return s.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+		Summary: fmt.Sprintf("%s %s %.2f on service %s (current: %.2f)",
+			data.MetricName, rule.Condition, rule.Threshold, service, data.Value),
+		Description: fmt.Sprintf("Alert for metric %s on service %s", data.MetricName, service),
+		Details: map[string]interface{}{
+			"service":   service,
+			"condition": rule.Condition,
+			"threshold": rule.Threshold,
+			"value":     data.Value,
+		},
+		Reference: rule.Threshold,
+	})
+}
+
+// ruleLabelsMatch 规则标签中与指标标签同名的键必须取值相同，如service=checkout的规则只评估checkout服务的指标
+func ruleLabelsMatch(rule *models.AlertRule, tags map[string]interface{}) bool {
+	if rule.Labels == "" || len(tags) == 0 {
+		return true
+	}
+	var labels map[string]interface{}
+	if err := json.Unmarshal([]byte(rule.Labels), &labels); err != nil {
+		return true
+	}
+	for key, want := range labels {
+		got, ok := tags[key]
+		if !ok {
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
 // checkAlertRule 检查告警规则
 func (s *AlertService) checkAlertRule(rule *models.AlertRule, data *MetricData) error {
 	// 评估条件
@@ -393,6 +495,7 @@ func (s *AlertService) createAlert(rule *models.AlertRule, data *MetricData) err
 	if err := s.db.Create(&alert).Error; err != nil {
 		return fmt.Errorf("failed to create alert: %w", err)
 	}
+	s.recordAlertEvent(&alert, "firing", message, nil, nil)
 
 	// 发送通知
 	go s.sendAlertNotification(&alert, rule)
@@ -445,6 +548,7 @@ func (s *AlertService) resolveAlert(ruleID uuid.UUID, targetID string) error {
 	if err := s.db.Model(&alert).Updates(updates).Error; err != nil {
 		return fmt.Errorf("failed to resolve alert: %w", err)
 	}
+	s.recordAlertEvent(&alert, "resolved", "condition no longer met", nil, nil)
 
 	// 发送解决通知
 	go s.sendResolvedNotification(&alert)
@@ -481,7 +585,7 @@ func (s *AlertService) sendAlertNotification(alert *models.Alert, rule *models.A
 
 	// 发送通知
 	if err := s.notifyService.SendNotification(notification); err != nil {
-		// Failed to send alert notification
+		logger.GetLogger("alert").WithError(err).WithField("alert_id", alert.ID).Warn("failed to send alert notification")
 	}
 }
 
@@ -522,7 +626,7 @@ func (s *AlertService) sendResolvedNotification(alert *models.Alert) {
 
 	// 发送通知
 	if err := s.notifyService.SendNotification(notification); err != nil {
-		// Failed to send resolved notification
+		logger.GetLogger("alert").WithError(err).WithField("alert_id", alert.ID).Warn("failed to send resolved notification")
 	}
 }
 
@@ -550,7 +654,7 @@ func (s *AlertService) triggerAIAnalysis(alert *models.Alert, rule *models.Alert
 
 	// 执行AI分析
 	if _, err := s.aiService.AnalyzeAlert(request); err != nil {
-		// Failed to trigger AI analysis
+		logger.GetLogger("alert").WithError(err).WithField("alert_id", alert.ID).Warn("failed to trigger AI analysis")
 	}
 }
 
@@ -669,6 +773,7 @@ func (s *AlertService) AcknowledgeAlert(alertID uuid.UUID, userID uuid.UUID) (*A
 	if err := s.db.Model(&alert).Updates(updates).Error; err != nil {
 		return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
 	}
+	s.recordAlertEvent(&alert, "acknowledged", "alert acknowledged", nil, &userID)
 
 	// 重新获取更新后的告警
 	if err := s.db.First(&alert, alertID).Error; err != nil {
@@ -703,6 +808,7 @@ func (s *AlertService) ResolveAlert(alertID uuid.UUID, userID uuid.UUID) (*Alert
 	if err := s.db.Model(&alert).Updates(updates).Error; err != nil {
 		return nil, fmt.Errorf("failed to resolve alert: %w", err)
 	}
+	s.recordAlertEvent(&alert, "resolved", "alert resolved manually", nil, &userID)
 
 	// 发送解决通知
 	go s.sendResolvedNotification(&alert)
@@ -775,13 +881,18 @@ func (s *AlertService) toAlertRuleResponse(rule *models.AlertRule) *AlertRuleRes
 		TargetType:  "", // AlertRule模型中没有此字段
 		TargetID:    "", // AlertRule模型中没有此字段
 		MetricName:  rule.Metric, // 使用Metric字段
+		Kind:        rule.Kind,
 		Condition:   rule.Condition,
 		Threshold:   rule.Threshold,
+		Sensitivity: rule.Sensitivity,
+		ForecastHorizon: rule.ForecastHorizon,
 		Duration:    rule.Duration,
 		Severity:    rule.Severity,
 		Enabled:     rule.Enabled,
 		Tags:        tags,
 		Channels:    channels,
+		PromptTemplateID: rule.PromptTemplateID,
+		SLOID:       rule.SLOID,
 		CreatedBy:   rule.CreatedBy,
 		CreatedAt:   rule.CreatedAt,
 		UpdatedAt:   rule.UpdatedAt,
@@ -823,6 +934,27 @@ func (s *AlertService) toAlertResponse(alert *models.Alert) *AlertResponse {
 	}
 }
 
+// recordAlertEvent 记录告警时间线事件，失败不影响告警主流程
+func (s *AlertService) recordAlertEvent(alert *models.Alert, eventType, message string, details interface{}, userID *uuid.UUID) {
+	event := models.AlertEvent{
+		AlertID: alert.ID,
+		RuleID:  alert.RuleID,
+		Type:    eventType,
+		Message: message,
+		Value:   alert.Value,
+		UserID:  userID,
+	}
+	if details != nil {
+		if detailsJSON, err := json.Marshal(details); err == nil {
+			event.Details = string(detailsJSON)
+		}
+	}
+
+	if err := s.db.Create(&event).Error; err != nil {
+		logger.GetLogger("alert").WithError(err).WithField("alert_id", alert.ID).Warn("failed to record alert event")
+	}
+}
+
 // clearAlertRuleCache 清除告警规则缓存
 func (s *AlertService) clearAlertRuleCache() {
 	if s.cacheManager != nil {
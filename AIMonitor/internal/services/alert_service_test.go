@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestAPMThresholdRuleFiresAndResolves(t *testing.T) {
+	db := newTestDB(t, &models.AlertRule{}, &models.Alert{}, &models.AlertEvent{})
+	service := NewAlertService(db, nil, nil, nil)
+
+	// checkout p99 > 800ms
+	rule := models.AlertRule{
+		Name:      "checkout p99",
+		Metric:    APMMetricLatencyP99,
+		Condition: ">",
+		Threshold: 800,
+		Severity:  "high",
+		Enabled:   true,
+		Kind:      "threshold",
+		Labels:    `{"service":"checkout"}`,
+		CreatedBy: uuid.New(),
+	}
+	if err := db.Create(&rule).Error; err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	checkout, cart := uuid.New().String(), uuid.New().String()
+	process := func(targetID, serviceName string, value float64) {
+		t.Helper()
+		err := service.ProcessMetricData(&MetricData{
+			TargetType: "service",
+			TargetID:   targetID,
+			MetricName: APMMetricLatencyP99,
+			Value:      value,
+			Tags:       map[string]interface{}{"service": serviceName},
+			Timestamp:  time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("ProcessMetricData: %v", err)
+		}
+	}
+	firing := func() []models.Alert {
+		t.Helper()
+		var alerts []models.Alert
+		if err := db.Where("rule_id = ? AND status = ?", rule.ID, "firing").Find(&alerts).Error; err != nil {
+			t.Fatalf("query alerts: %v", err)
+		}
+		return alerts
+	}
+
+	// 规则标签限定checkout服务，其他服务超过阈值不触发
+	process(cart, "cart", 1500)
+	if alerts := firing(); len(alerts) != 0 {
+		t.Fatalf("expected no alert for cart, got %d", len(alerts))
+	}
+
+	process(checkout, "checkout", 950)
+	alerts := firing()
+	if len(alerts) != 1 {
+		t.Fatalf("expected one firing alert, got %d", len(alerts))
+	}
+	if alerts[0].Value != 950 || alerts[0].Severity != "high" {
+		t.Fatalf("unexpected alert %+v", alerts[0])
+	}
+	// 指标标签合并进告警标签，供修复模板和事件关联使用
+	var labels map[string]interface{}
+	if err := json.Unmarshal([]byte(alerts[0].Labels), &labels); err != nil {
+		t.Fatalf("decode labels: %v", err)
+	}
+	if labels["service"] != "checkout" || labels["target_id"] != checkout || labels["metric"] != APMMetricLatencyP99 {
+		t.Fatalf("unexpected alert labels %v", labels)
+	}
+
+	// 持续超阈值只更新同一告警
+	process(checkout, "checkout", 1200)
+	if alerts := firing(); len(alerts) != 1 || alerts[0].Value != 1200 {
+		t.Fatalf("expected the firing alert to be updated, got %+v", alerts)
+	}
+
+	process(checkout, "checkout", 300)
+	if alerts := firing(); len(alerts) != 0 {
+		t.Fatalf("expected alert to resolve, got %d firing", len(alerts))
+	}
+	var events int64
+	db.Model(&models.AlertEvent{}).Where("rule_id = ?", rule.ID).Count(&events)
+	if events != 2 {
+		t.Fatalf("expected firing and resolved events, got %d", events)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"ai-monitor/internal/cache"
@@ -19,7 +21,6 @@ type APMService struct {
 	cacheManager *cache.CacheManager
 	config       *config.Config
 	jaegerClient JaegerClient
-	otelClient   OTelClient
 }
 
 // JaegerClient Jaeger客户端接口
@@ -28,26 +29,21 @@ type JaegerClient interface {
 	GetTrace(ctx context.Context, traceID string) (*TraceDetail, error)
 	GetServices(ctx context.Context) ([]string, error)
 	GetOperations(ctx context.Context, service string) ([]string, error)
-}
-
-// OTelClient OpenTelemetry客户端接口
-type OTelClient interface {
-	GetMetrics(ctx context.Context, query MetricQuery) ([]MetricSeries, error)
-	GetServiceMap(ctx context.Context) (*ServiceMap, error)
+	GetDependencies(ctx context.Context, endTime time.Time, lookback time.Duration) ([]ServiceEdge, error)
 }
 
 // NewAPMService 创建APM服务
 func NewAPMService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config) (*APMService, error) {
-	// 初始化Jaeger客户端
-	jaegerClient, err := NewJaegerClient(config.Monitoring.Tracing.JaegerEndpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create jaeger client: %w", err)
-	}
-
-	// 初始化OpenTelemetry客户端 (使用默认URL)
-	otelClient, err := NewOTelClient("http://localhost:4318")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create otel client: %w", err)
+	// 初始化链路查询客户端：启用内置链路接收器时直接查询本地存储的跨度
+	var jaegerClient JaegerClient
+	if config.Monitoring.Tracing.ReceiversEnabled() {
+		jaegerClient = NewSpanStore(db)
+	} else {
+		client, err := NewJaegerClient(config.Monitoring.Tracing.QueryEndpoint, config.Monitoring.Tracing.QueryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create jaeger client: %w", err)
+		}
+		jaegerClient = client
 	}
 
 	return &APMService{
@@ -55,7 +51,6 @@ func NewAPMService(db *gorm.DB, cacheManager *cache.CacheManager, config *config
 		cacheManager: cacheManager,
 		config:       config,
 		jaegerClient: jaegerClient,
-		otelClient:   otelClient,
 	}, nil
 }
 
@@ -64,11 +59,14 @@ type TraceQuery struct {
 	Service     string        `json:"service"`
 	Operation   string        `json:"operation"`
 	Tags        map[string]string `json:"tags"`
+	// Status 跨度状态：ok、error
+	Status      string        `json:"status"`
 	StartTime   time.Time     `json:"start_time"`
 	EndTime     time.Time     `json:"end_time"`
 	MinDuration time.Duration `json:"min_duration"`
 	MaxDuration time.Duration `json:"max_duration"`
 	Limit       int           `json:"limit"`
+	Offset      int           `json:"offset"`
 }
 
 // Trace 链路追踪信息
@@ -92,6 +90,8 @@ type TraceDetail struct {
 	Services  []string  `json:"services"`
 	Errors    []SpanError `json:"errors"`
 	Processes map[string]Process `json:"processes"`
+	// Analysis 关键路径、自身耗时、服务耗时分布与N+1查询检测
+	Analysis  *TraceAnalysis     `json:"analysis,omitempty"`
 }
 
 // Span 链路跨度
@@ -167,6 +167,8 @@ type MetricValue struct {
 type ServiceMap struct {
 	Services []ServiceNode `json:"services"`
 	Edges    []ServiceEdge `json:"edges"`
+	// Truncated 跨度数超过max_spans，只统计了时间范围内最近的跨度
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // ServiceNode 服务节点
@@ -200,11 +202,15 @@ type ServiceMetrics struct {
 	ActiveRequests int     `json:"active_requests"`
 }
 
-// ConnectionMetrics 连接指标
+// ConnectionMetrics 连接指标，延迟单位毫秒，吞吐量为每秒请求数
 type ConnectionMetrics struct {
 	RequestCount int     `json:"request_count"`
 	ErrorCount   int     `json:"error_count"`
+	ErrorRate    float64 `json:"error_rate"`
 	LatencyAvg   float64 `json:"latency_avg"`
+	LatencyP50   float64 `json:"latency_p50"`
+	LatencyP95   float64 `json:"latency_p95"`
+	LatencyP99   float64 `json:"latency_p99"`
 	Throughput   float64 `json:"throughput"`
 }
 
@@ -228,6 +234,8 @@ type EndpointMetrics struct {
 	LatencyP50  float64 `json:"latency_p50"`
 	LatencyP95  float64 `json:"latency_p95"`
 	LatencyP99  float64 `json:"latency_p99"`
+	// Exemplars 各延迟区间最慢请求的链路，可直接跳转查看
+	Exemplars []SpanExemplar `json:"exemplars,omitempty"`
 }
 
 // ServiceDependency 服务依赖
@@ -314,6 +322,7 @@ func (s *APMService) GetTraceDetail(traceID string) (*TraceDetail, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trace detail from jaeger: %w", err)
 	}
+	detail.Analysis = AnalyzeTrace(detail)
 
 	// 缓存结果
 	if s.cacheManager != nil {
@@ -325,33 +334,9 @@ func (s *APMService) GetTraceDetail(traceID string) (*TraceDetail, error) {
 	return detail, nil
 }
 
-// GetServiceMap 获取服务拓扑图
+// GetServiceMap 获取默认窗口的服务拓扑图
 func (s *APMService) GetServiceMap() (*ServiceMap, error) {
-	ctx := context.Background()
-
-	// 检查缓存
-	cacheKey := "service_map"
-	if s.cacheManager != nil {
-		var serviceMap ServiceMap
-		if err := s.cacheManager.Get(ctx, cacheKey, &serviceMap); err == nil {
-			return &serviceMap, nil
-		}
-	}
-
-	// 从OpenTelemetry获取服务拓扑图
-	serviceMap, err := s.otelClient.GetServiceMap(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get service map from otel: %w", err)
-	}
-
-	// 缓存结果
-	if s.cacheManager != nil {
-		if data, err := json.Marshal(serviceMap); err == nil {
-			s.cacheManager.Set(ctx, cacheKey, string(data), 3*time.Minute)
-		}
-	}
-
-	return serviceMap, nil
+	return s.GetServiceMapForWindow(0, "")
 }
 
 // GetServicePerformance 获取服务性能概览
@@ -556,88 +541,256 @@ func (s *APMService) GetOperations(service string) ([]string, error) {
 
 // 私有方法实现
 
+// serviceMapFromEdges 由调用关系构建服务拓扑图
+func serviceMapFromEdges(edges []ServiceEdge) *ServiceMap {
+	serviceMap := &ServiceMap{Edges: edges}
+	seen := map[string]bool{}
+	for _, edge := range edges {
+		for _, name := range []string{edge.Source, edge.Target} {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			serviceMap.Services = append(serviceMap.Services, ServiceNode{
+				ID:     name,
+				Name:   name,
+				Type:   "service",
+				Health: "unknown",
+			})
+		}
+	}
+	return serviceMap
+}
+
 func (s *APMService) generateTraceCacheKey(query TraceQuery) string {
-	return fmt.Sprintf("traces:%s:%s:%d:%d", query.Service, query.Operation, query.StartTime.Unix(), query.EndTime.Unix())
+	keys := make([]string, 0, len(query.Tags))
+	for k := range query.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+"="+query.Tags[k])
+	}
+	return fmt.Sprintf("traces:%s:%s:%s:%s:%d:%d:%d:%d:%d:%d", query.Service, query.Operation, query.Status, strings.Join(tags, ","),
+		query.MinDuration, query.MaxDuration, query.StartTime.Unix(), query.EndTime.Unix(), query.Offset, query.Limit)
 }
 
+// getServiceOverview 由跨度指标汇总计算服务整体RED指标
 func (s *APMService) getServiceOverview(ctx context.Context, serviceName string, startTime, endTime time.Time) (*ServiceMetrics, error) {
-	// 实现服务概览指标获取逻辑
-	return &ServiceMetrics{}, nil
-}
+	rollups, err := loadSpanMetricRollups(ctx, s.db, serviceName, "", startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *APMService) getEndpointMetrics(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]EndpointMetrics, error) {
-	// 实现端点指标获取逻辑
-	return []EndpointMetrics{}, nil
+	merged := &spanMetricRollup{Histogram: newLatencyHistogram()}
+	for _, rollup := range rollups {
+		merged.Count += rollup.Count
+		merged.Errors += rollup.Errors
+		merged.Histogram.merge(rollup.Histogram)
+	}
+	return rollupServiceMetrics(merged, endTime.Sub(startTime)), nil
 }
 
+// getEndpointMetrics 按操作统计端点指标，附带各延迟区间的示例链路
+func (s *APMService) getEndpointMetrics(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]EndpointMetrics, error) {
+	rollups, err := loadSpanMetricRollups(ctx, s.db, serviceName, "", startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	window := endTime.Sub(startTime)
+	endpoints := make([]EndpointMetrics, 0, len(rollups))
+	for _, rollup := range rollups {
+		metrics := rollupServiceMetrics(rollup, window)
+		method, endpoint := splitOperationMethod(rollup.Operation)
+		endpoints = append(endpoints, EndpointMetrics{
+			Endpoint:    endpoint,
+			Method:      method,
+			RequestRate: metrics.RequestRate,
+			ErrorRate:   metrics.ErrorRate,
+			LatencyP50:  metrics.LatencyP50,
+			LatencyP95:  metrics.LatencyP95,
+			LatencyP99:  metrics.LatencyP99,
+			Exemplars:   rollup.Histogram.exemplarList(),
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].RequestRate > endpoints[j].RequestRate })
+	return endpoints, nil
+}
+
+// getServiceDependencies 由服务拓扑中以该服务为源的调用边得到下游依赖
 func (s *APMService) getServiceDependencies(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]ServiceDependency, error) {
-	// 实现服务依赖获取逻辑
-	return []ServiceDependency{}, nil
-}
-
-func (s *APMService) getErrorSummary(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]ErrorSummary, error) {
-	// 实现错误摘要获取逻辑
-	return []ErrorSummary{}, nil
-}
+	serviceMap, err := s.BuildServiceMap(ctx, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *APMService) getSlowOperations(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]SlowOperation, error) {
-	// 实现慢操作获取逻辑
-	return []SlowOperation{}, nil
-}
+	nodeTypes := make(map[string]string, len(serviceMap.Services))
+	for _, node := range serviceMap.Services {
+		nodeTypes[node.ID] = node.Type
+	}
 
-func (s *APMService) getServiceResourceUsage(ctx context.Context, serviceName string, startTime, endTime time.Time) (*ServiceResourceUsage, error) {
-	// 实现资源使用情况获取逻辑
-	return &ServiceResourceUsage{}, nil
+	dependencies := []ServiceDependency{}
+	for _, edge := range serviceMap.Edges {
+		if edge.Source != serviceName {
+			continue
+		}
+		dependencies = append(dependencies, ServiceDependency{
+			ServiceName: edge.Target,
+			Type:        nodeTypes[edge.Target],
+			RequestRate: edge.Metrics.Throughput,
+			ErrorRate:   edge.Metrics.ErrorRate,
+			LatencyAvg:  edge.Metrics.LatencyAvg,
+			Health:      healthFromErrorRate(edge.Metrics.ErrorRate),
+		})
+	}
+	return dependencies, nil
 }
 
-// Jaeger客户端实现
-type jaegerClient struct {
-	baseURL string
-}
+// getErrorSummary 按异常类型和消息归并服务的错误跨度
+func (s *APMService) getErrorSummary(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]ErrorSummary, error) {
+	var rows []models.APMTrace
+	if err := s.db.WithContext(ctx).
+		Select("operation_name, start_time, tags").
+		Where("service_name = ? AND status = ? AND start_time BETWEEN ? AND ?", serviceName, "error", startTime, endTime).
+		Order("start_time DESC").Limit(5000).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get error spans: %w", err)
+	}
+
+	summaries := map[string]*ErrorSummary{}
+	operations := map[string]map[string]bool{}
+	var order []string
+	for _, row := range rows {
+		tags := map[string]interface{}{}
+		if row.Tags != "" {
+			json.Unmarshal([]byte(row.Tags), &tags)
+		}
+		errorType := attributeString(tags, "exception.type", "error.type")
+		if errorType == "" {
+			errorType = "error"
+		}
+		message := attributeString(tags, "exception.message", "otel.status_description", "error.message")
+
+		key := errorType + "|" + message
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &ErrorSummary{ErrorType: errorType, Message: message, LastSeen: row.StartTime}
+			summaries[key] = summary
+			operations[key] = map[string]bool{}
+			order = append(order, key)
+		}
+		summary.Count++
+		if row.StartTime.After(summary.LastSeen) {
+			summary.LastSeen = row.StartTime
+		}
+		if !operations[key][row.OperationName] {
+			operations[key][row.OperationName] = true
+			summary.AffectedOps = append(summary.AffectedOps, row.OperationName)
+		}
+	}
 
-func NewJaegerClient(baseURL string) (JaegerClient, error) {
-	return &jaegerClient{
-		baseURL: baseURL,
-	}, nil
+	result := make([]ErrorSummary, 0, len(order))
+	for _, key := range order {
+		result = append(result, *summaries[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result, nil
 }
 
-func (c *jaegerClient) GetTraces(ctx context.Context, query TraceQuery) ([]Trace, error) {
-	// 实现Jaeger API调用逻辑
-	return []Trace{}, nil
-}
+// getSlowOperations 按平均耗时取最慢的操作
+func (s *APMService) getSlowOperations(ctx context.Context, serviceName string, startTime, endTime time.Time) ([]SlowOperation, error) {
+	rollups, err := loadSpanMetricRollups(ctx, s.db, serviceName, "", startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
 
-func (c *jaegerClient) GetTrace(ctx context.Context, traceID string) (*TraceDetail, error) {
-	// 实现Jaeger API调用逻辑
-	return &TraceDetail{}, nil
+	operations := make([]SlowOperation, 0, len(rollups))
+	for _, rollup := range rollups {
+		if rollup.Count == 0 {
+			continue
+		}
+		avgMs := rollup.Histogram.Sum / float64(rollup.Count)
+		operations = append(operations, SlowOperation{
+			Operation:   rollup.Operation,
+			Service:     serviceName,
+			AvgDuration: time.Duration(avgMs * float64(time.Millisecond)),
+			MaxDuration: time.Duration(rollup.Histogram.Max * float64(time.Millisecond)),
+			Count:       int(rollup.Count),
+			LastSeen:    rollup.LastSeen,
+		})
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].AvgDuration > operations[j].AvgDuration })
+	if len(operations) > 10 {
+		operations = operations[:10]
+	}
+	return operations, nil
 }
 
-func (c *jaegerClient) GetServices(ctx context.Context) ([]string, error) {
-	// 实现Jaeger API调用逻辑
-	return []string{}, nil
-}
+// getServiceResourceUsage 取指标库中以该服务为目标上报的资源指标平均值
+func (s *APMService) getServiceResourceUsage(ctx context.Context, serviceName string, startTime, endTime time.Time) (*ServiceResourceUsage, error) {
+	var service models.APMService
+	if err := s.db.WithContext(ctx).Select("id").Where("name = ?", serviceName).First(&service).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &ServiceResourceUsage{}, nil
+		}
+		return nil, fmt.Errorf("failed to get apm service: %w", err)
+	}
 
-func (c *jaegerClient) GetOperations(ctx context.Context, service string) ([]string, error) {
-	// 实现Jaeger API调用逻辑
-	return []string{}, nil
-}
+	var rows []struct {
+		Metric string
+		Avg    float64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.MetricData{}).
+		Select("metric, AVG(value) AS avg").
+		Where("target_id = ? AND metric IN ? AND timestamp BETWEEN ? AND ?", service.ID,
+			[]string{"cpu_usage", "memory_usage", "disk_usage", "network_io"}, startTime, endTime).
+		Group("metric").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get resource usage: %w", err)
+	}
 
-// OpenTelemetry客户端实现
-type otelClient struct {
-	baseURL string
+	usage := &ServiceResourceUsage{}
+	for _, row := range rows {
+		switch row.Metric {
+		case "cpu_usage":
+			usage.CPUUsage = row.Avg
+		case "memory_usage":
+			usage.MemoryUsage = row.Avg
+		case "disk_usage":
+			usage.DiskUsage = row.Avg
+		case "network_io":
+			usage.NetworkIO = row.Avg
+		}
+	}
+	return usage, nil
 }
 
-func NewOTelClient(baseURL string) (OTelClient, error) {
-	return &otelClient{
-		baseURL: baseURL,
-	}, nil
+// rollupServiceMetrics 由汇总计算RED指标，吞吐量与请求速率均为每秒请求数
+func rollupServiceMetrics(rollup *spanMetricRollup, window time.Duration) *ServiceMetrics {
+	metrics := &ServiceMetrics{
+		LatencyP50: rollup.Histogram.quantile(0.50),
+		LatencyP95: rollup.Histogram.quantile(0.95),
+		LatencyP99: rollup.Histogram.quantile(0.99),
+	}
+	if window > 0 {
+		metrics.RequestRate = float64(rollup.Count) / window.Seconds()
+		metrics.Throughput = metrics.RequestRate
+	}
+	if rollup.Count > 0 {
+		metrics.ErrorRate = float64(rollup.Errors) / float64(rollup.Count)
+	}
+	return metrics
 }
 
-func (c *otelClient) GetMetrics(ctx context.Context, query MetricQuery) ([]MetricSeries, error) {
-	// 实现OpenTelemetry API调用逻辑
-	return []MetricSeries{}, nil
+// splitOperationMethod 拆分"GET /api/orders"形式的操作名
+func splitOperationMethod(operation string) (method, endpoint string) {
+	if i := strings.IndexByte(operation, ' '); i > 0 {
+		switch prefix := strings.ToUpper(operation[:i]); prefix {
+		case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+			return prefix, strings.TrimSpace(operation[i+1:])
+		}
+	}
+	return "", operation
 }
-
-func (c *otelClient) GetServiceMap(ctx context.Context) (*ServiceMap, error) {
-	// 实现OpenTelemetry API调用逻辑
-	return &ServiceMap{}, nil
-}
\ No newline at end of file
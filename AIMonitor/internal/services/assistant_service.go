@@ -0,0 +1,739 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ai-monitor/internal/auth"
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+// AssistantService 运维助手服务，基于工具调用查询平台数据回答运维问题
+type AssistantService struct {
+	db                *gorm.DB
+	cacheManager      *cache.CacheManager
+	config            *config.Config
+	aiService         *AIService
+	monitoringService *MonitoringService
+	alertService      *AlertService
+	apmService        *APMService
+	containerService  *ContainerService
+}
+
+// AssistantChatRequest 运维助手对话请求
+type AssistantChatRequest struct {
+	ConversationID *uuid.UUID `json:"conversation_id"`
+	Message        string     `json:"message" binding:"required,max=4000"`
+}
+
+// AssistantEvidence 回答引用的证据，指标查询会附带图表数据
+type AssistantEvidence struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Summary   string                 `json:"summary"`
+	Chart     *AssistantChart        `json:"chart,omitempty"`
+}
+
+// AssistantChart 图表数据
+type AssistantChart struct {
+	Type   string                 `json:"type"` // timeseries
+	Title  string                 `json:"title"`
+	Series []*MetricQueryResponse `json:"series"`
+}
+
+// AssistantAnswer 运维助手最终回答
+type AssistantAnswer struct {
+	ConversationID uuid.UUID            `json:"conversation_id"`
+	MessageID      uuid.UUID            `json:"message_id"`
+	Content        string               `json:"content"`
+	Evidence       []*AssistantEvidence `json:"evidence"`
+	Steps          int                  `json:"steps"`
+	Tokens         int                  `json:"tokens"`
+	BudgetExceeded bool                 `json:"budget_exceeded"`
+}
+
+// AssistantConversationResponse 会话响应
+type AssistantConversationResponse struct {
+	ID          uuid.UUID                   `json:"id"`
+	Title       string                      `json:"title"`
+	TotalTokens int                         `json:"total_tokens"`
+	LastActive  time.Time                   `json:"last_active"`
+	CreatedAt   time.Time                   `json:"created_at"`
+	Messages    []*AssistantMessageResponse `json:"messages,omitempty"`
+}
+
+// AssistantMessageResponse 会话消息响应
+type AssistantMessageResponse struct {
+	ID        uuid.UUID            `json:"id"`
+	Role      string               `json:"role"`
+	Content   string               `json:"content"`
+	ToolName  string               `json:"tool_name,omitempty"`
+	Evidence  []*AssistantEvidence `json:"evidence,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// AssistantEmitter 向调用方推送对话过程事件
+type AssistantEmitter func(event string, data interface{})
+
+// assistantTool 助手可调用的只读工具
+type assistantTool struct {
+	permission string
+	definition openai.FunctionDefinition
+	run        func(s *AssistantService, args map[string]interface{}) (interface{}, *AssistantEvidence, error)
+}
+
+const assistantSystemPrompt = `你是AI Monitor平台的运维助手。请通过提供的只读工具查询指标、告警、链路、服务拓扑、Kubernetes Pod和知识库来回答运维人员的问题。
+要求：
+1. 先查询数据再下结论，不要编造数据；时间请使用RFC3339格式，当前时间为 %s。
+2. 每次只调用解决问题所需的工具，拿到足够证据后立即作答。
+3. 回答使用中文，先给出结论，再列出支撑结论的证据（指标、告警、链路等），最后给出建议的排查或处理步骤。
+4. 如果工具无权限或查询失败，请说明缺失的信息而不是猜测。`
+
+// assistantTools 工具定义，权限与RBAC中的只读权限对应
+var assistantTools = map[string]*assistantTool{
+	"query_metrics": {
+		permission: "monitoring.read",
+		definition: openai.FunctionDefinition{
+			Name:        "query_metrics",
+			Description: "执行PromQL查询。提供start和end时返回时间序列，否则返回即时值",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"query": {"type": "string", "description": "PromQL表达式"},
+					"start": {"type": "string", "description": "开始时间，RFC3339"},
+					"end": {"type": "string", "description": "结束时间，RFC3339"},
+					"step": {"type": "string", "description": "步长，如 1m"}
+				},
+				"required": ["query"]
+			}`),
+		},
+		run: (*AssistantService).toolQueryMetrics,
+	},
+	"list_alerts": {
+		permission: "alert.read",
+		definition: openai.FunctionDefinition{
+			Name:        "list_alerts",
+			Description: "查询告警列表",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"status": {"type": "string", "enum": ["firing", "resolved"]},
+					"severity": {"type": "string", "enum": ["critical", "high", "medium", "low"]},
+					"target_type": {"type": "string"},
+					"limit": {"type": "integer", "description": "最多返回条数，默认20"}
+				}
+			}`),
+		},
+		run: (*AssistantService).toolListAlerts,
+	},
+	"get_traces": {
+		permission: "monitoring.read",
+		definition: openai.FunctionDefinition{
+			Name:        "get_traces",
+			Description: "查询服务的调用链路，可按操作、时间范围和最小耗时过滤",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"service": {"type": "string"},
+					"operation": {"type": "string"},
+					"start": {"type": "string", "description": "开始时间，RFC3339"},
+					"end": {"type": "string", "description": "结束时间，RFC3339"},
+					"min_duration_ms": {"type": "integer"},
+					"limit": {"type": "integer", "description": "默认20"}
+				},
+				"required": ["service"]
+			}`),
+		},
+		run: (*AssistantService).toolGetTraces,
+	},
+	"get_service_map": {
+		permission: "monitoring.read",
+		definition: openai.FunctionDefinition{
+			Name:        "get_service_map",
+			Description: "获取服务拓扑及各服务的健康状态和调用关系",
+			Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+		},
+		run: (*AssistantService).toolGetServiceMap,
+	},
+	"get_kubernetes_pods": {
+		permission: "monitoring.read",
+		definition: openai.FunctionDefinition{
+			Name:        "get_kubernetes_pods",
+			Description: "获取指定集群和命名空间的Pod列表及状态",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"cluster": {"type": "string", "description": "Kubernetes集群名称，默认使用默认集群，all表示全部集群"},
+					"namespace": {"type": "string", "description": "命名空间，默认default"}
+				}
+			}`),
+		},
+		run: (*AssistantService).toolGetKubernetesPods,
+	},
+	"search_knowledge": {
+		permission: "knowledge.read",
+		definition: openai.FunctionDefinition{
+			Name:        "search_knowledge",
+			Description: "按关键字检索运维知识库和历史复盘",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"query": {"type": "string"},
+					"category": {"type": "string"}
+				},
+				"required": ["query"]
+			}`),
+		},
+		run: (*AssistantService).toolSearchKnowledge,
+	},
+}
+
+// NewAssistantService 创建运维助手服务
+func NewAssistantService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, aiService *AIService, monitoringService *MonitoringService, alertService *AlertService, apmService *APMService, containerService *ContainerService) *AssistantService {
+	return &AssistantService{
+		db:                db,
+		cacheManager:      cacheManager,
+		config:            config,
+		aiService:         aiService,
+		monitoringService: monitoringService,
+		alertService:      alertService,
+		apmService:        apmService,
+		containerService:  containerService,
+	}
+}
+
+// Chat 处理一轮对话：模型在步数和token预算内迭代调用工具，最后给出带证据的回答
+func (s *AssistantService) Chat(ctx context.Context, claims *auth.UserClaims, req *AssistantChatRequest, emit AssistantEmitter) (*AssistantAnswer, error) {
+	if s.aiService == nil || s.aiService.openaiClient == nil {
+		return nil, errors.New("AI service not configured")
+	}
+
+	conversation, err := s.loadOrCreateConversation(claims.UserID, req)
+	if err != nil {
+		return nil, err
+	}
+	emit("conversation", map[string]interface{}{"conversation_id": conversation.ID})
+
+	messages, err := s.buildMessages(conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: req.Message})
+	if err := s.saveMessage(&models.AssistantMessage{ConversationID: conversation.ID, Role: "user", Content: req.Message}); err != nil {
+		return nil, err
+	}
+
+	cfg := s.config.AIModels.Assistant
+	maxSteps := cfg.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 6
+	}
+	budget := cfg.TokenBudget
+	if budget <= 0 {
+		budget = 20000
+	}
+
+	tools := s.allowedTools(claims)
+	answer := &AssistantAnswer{ConversationID: conversation.ID, Evidence: make([]*AssistantEvidence, 0)}
+
+	for {
+		// 步数或token预算用尽后不再提供工具，要求模型基于已有证据作答
+		exhausted := answer.Steps >= maxSteps || answer.Tokens >= budget
+		request := openai.ChatCompletionRequest{
+			Model:       s.model(),
+			Messages:    messages,
+			Temperature: float32(s.config.AIModels.OpenAI.Temperature),
+		}
+		if exhausted {
+			answer.BudgetExceeded = true
+			request.Messages = append(request.Messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: "工具调用预算已用完，请基于已获取的信息直接作答，并说明尚未核实的部分。",
+			})
+		} else if len(tools) > 0 {
+			request.Tools = tools
+		}
+
+		resp, err := s.aiService.openaiClient.CreateChatCompletion(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI API call failed: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, errors.New("no response from OpenAI")
+		}
+		answer.Tokens += resp.Usage.TotalTokens
+		message := resp.Choices[0].Message
+
+		if len(message.ToolCalls) == 0 || exhausted {
+			return s.finishAnswer(conversation, answer, message.Content, emit)
+		}
+
+		answer.Steps++
+		messages = append(messages, message)
+		toolCallsJSON, _ := json.Marshal(message.ToolCalls)
+		s.saveMessage(&models.AssistantMessage{
+			ConversationID: conversation.ID,
+			Role:           "assistant",
+			Content:        message.Content,
+			ToolCalls:      string(toolCallsJSON),
+			Tokens:         resp.Usage.TotalTokens,
+		})
+
+		for _, call := range message.ToolCalls {
+			emit("tool_call", map[string]interface{}{"id": call.ID, "name": call.Function.Name, "arguments": call.Function.Arguments})
+
+			content, evidence := s.invokeTool(claims, call)
+			if evidence != nil {
+				answer.Evidence = append(answer.Evidence, evidence)
+			}
+			emit("tool_result", map[string]interface{}{"id": call.ID, "name": call.Function.Name, "evidence": evidence})
+
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    content,
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+			})
+			s.saveMessage(&models.AssistantMessage{
+				ConversationID: conversation.ID,
+				Role:           "tool",
+				Content:        content,
+				ToolCallID:     call.ID,
+				ToolName:       call.Function.Name,
+			})
+		}
+	}
+}
+
+// ListConversations 获取用户的会话列表
+func (s *AssistantService) ListConversations(userID uuid.UUID, page, pageSize int) ([]*AssistantConversationResponse, int64, error) {
+	query := s.db.Model(&models.AssistantConversation{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	var conversations []models.AssistantConversation
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("last_active DESC").Find(&conversations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	responses := make([]*AssistantConversationResponse, len(conversations))
+	for i := range conversations {
+		responses[i] = s.toConversationResponse(&conversations[i])
+	}
+	return responses, total, nil
+}
+
+// GetConversation 获取会话及其用户可见的消息，工具调用的中间消息不返回
+func (s *AssistantService) GetConversation(userID, conversationID uuid.UUID) (*AssistantConversationResponse, error) {
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []models.AssistantMessage
+	if err := s.db.Where("conversation_id = ? AND role <> ? AND (tool_calls IS NULL OR tool_calls = '')", conversation.ID, "tool").
+		Order("created_at ASC").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversation messages: %w", err)
+	}
+
+	response := s.toConversationResponse(conversation)
+	response.Messages = make([]*AssistantMessageResponse, len(messages))
+	for i := range messages {
+		msg := &messages[i]
+		var evidence []*AssistantEvidence
+		if msg.Evidence != "" {
+			json.Unmarshal([]byte(msg.Evidence), &evidence)
+		}
+		response.Messages[i] = &AssistantMessageResponse{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			ToolName:  msg.ToolName,
+			Evidence:  evidence,
+			CreatedAt: msg.CreatedAt,
+		}
+	}
+	return response, nil
+}
+
+// DeleteConversation 删除会话及其消息
+func (s *AssistantService) DeleteConversation(userID, conversationID uuid.UUID) error {
+	conversation, err := s.getConversation(userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.AssistantMessage{}).Error; err != nil {
+			return fmt.Errorf("failed to delete conversation messages: %w", err)
+		}
+		if err := tx.Delete(conversation).Error; err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+		return nil
+	})
+}
+
+// finishAnswer 保存最终回答并更新会话统计
+func (s *AssistantService) finishAnswer(conversation *models.AssistantConversation, answer *AssistantAnswer, content string, emit AssistantEmitter) (*AssistantAnswer, error) {
+	answer.Content = content
+
+	evidenceJSON, _ := json.Marshal(answer.Evidence)
+	msg := &models.AssistantMessage{
+		ConversationID: conversation.ID,
+		Role:           "assistant",
+		Content:        content,
+		Evidence:       string(evidenceJSON),
+		Tokens:         answer.Tokens,
+	}
+	if err := s.saveMessage(msg); err != nil {
+		return nil, err
+	}
+	answer.MessageID = msg.ID
+
+	if err := s.db.Model(conversation).Updates(map[string]interface{}{
+		"total_tokens": gorm.Expr("total_tokens + ?", answer.Tokens),
+		"last_active":  time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	emit("answer", answer)
+	return answer, nil
+}
+
+// invokeTool 校验权限后执行工具，返回给模型的内容按配置截断
+func (s *AssistantService) invokeTool(claims *auth.UserClaims, call openai.ToolCall) (string, *AssistantEvidence) {
+	tool, ok := assistantTools[call.Function.Name]
+	if !ok {
+		return fmt.Sprintf(`{"error": "unknown tool %s"}`, call.Function.Name), nil
+	}
+	if !canUseTool(claims, tool) {
+		return fmt.Sprintf(`{"error": "permission denied: %s required"}`, tool.permission), nil
+	}
+
+	args := make(map[string]interface{})
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return fmt.Sprintf(`{"error": "invalid arguments: %s"}`, err.Error()), nil
+		}
+	}
+
+	type toolOutcome struct {
+		result   interface{}
+		evidence *AssistantEvidence
+		err      error
+	}
+	timeout := s.config.AIModels.Assistant.ToolTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	done := make(chan toolOutcome, 1)
+	go func() {
+		result, evidence, err := tool.run(s, args)
+		done <- toolOutcome{result, evidence, err}
+	}()
+
+	var outcome toolOutcome
+	select {
+	case outcome = <-done:
+	case <-time.After(timeout):
+		outcome.err = errors.New("tool call timed out")
+	}
+	if outcome.err != nil {
+		return fmt.Sprintf(`{"error": %q}`, outcome.err.Error()), nil
+	}
+
+	data, _ := json.Marshal(outcome.result)
+	content := string(data)
+	maxChars := s.config.AIModels.Assistant.MaxResultChars
+	if maxChars <= 0 {
+		maxChars = 6000
+	}
+	if utf8.RuneCountInString(content) > maxChars {
+		content = string([]rune(content)[:maxChars]) + "...(truncated)"
+	}
+
+	if outcome.evidence != nil {
+		outcome.evidence.Tool = call.Function.Name
+		outcome.evidence.Arguments = args
+	}
+	return content, outcome.evidence
+}
+
+// toolQueryMetrics 指标查询工具
+func (s *AssistantService) toolQueryMetrics(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	req := &MetricQueryRequest{
+		Query: paramString(args, "query"),
+		Step:  paramString(args, "step"),
+	}
+	if req.Query == "" {
+		return nil, nil, errors.New("query is required")
+	}
+	req.StartTime, _ = time.Parse(time.RFC3339, paramString(args, "start"))
+	req.EndTime, _ = time.Parse(time.RFC3339, paramString(args, "end"))
+
+	results, err := s.monitoringService.QueryMetrics(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evidence := &AssistantEvidence{Summary: fmt.Sprintf("%s 返回 %d 条序列", req.Query, len(results))}
+	if !req.StartTime.IsZero() && len(results) > 0 {
+		evidence.Chart = &AssistantChart{Type: "timeseries", Title: req.Query, Series: results}
+	}
+	return results, evidence, nil
+}
+
+// toolListAlerts 告警查询工具
+func (s *AssistantService) toolListAlerts(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	limit := paramInt(args, "limit", 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	alerts, total, err := s.alertService.ListAlerts(1, limit, paramString(args, "status"), paramString(args, "severity"), paramString(args, "target_type"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return alerts, &AssistantEvidence{Summary: fmt.Sprintf("匹配告警 %d 条", total)}, nil
+}
+
+// toolGetTraces 链路查询工具
+func (s *AssistantService) toolGetTraces(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	query := TraceQuery{
+		Service:     paramString(args, "service"),
+		Operation:   paramString(args, "operation"),
+		MinDuration: time.Duration(paramInt(args, "min_duration_ms", 0)) * time.Millisecond,
+		Limit:       paramInt(args, "limit", 20),
+	}
+	if query.Service == "" {
+		return nil, nil, errors.New("service is required")
+	}
+	query.StartTime, _ = time.Parse(time.RFC3339, paramString(args, "start"))
+	query.EndTime, _ = time.Parse(time.RFC3339, paramString(args, "end"))
+	if query.EndTime.IsZero() {
+		query.EndTime = time.Now()
+	}
+	if query.StartTime.IsZero() {
+		query.StartTime = query.EndTime.Add(-time.Hour)
+	}
+
+	traces, err := s.apmService.GetTraces(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return traces, &AssistantEvidence{Summary: fmt.Sprintf("服务 %s 返回 %d 条链路", query.Service, len(traces))}, nil
+}
+
+// toolGetServiceMap 服务拓扑工具
+func (s *AssistantService) toolGetServiceMap(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	serviceMap, err := s.apmService.GetServiceMap()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return serviceMap, &AssistantEvidence{Summary: fmt.Sprintf("服务 %d 个，调用关系 %d 条", len(serviceMap.Services), len(serviceMap.Edges))}, nil
+}
+
+// toolGetKubernetesPods Pod查询工具
+func (s *AssistantService) toolGetKubernetesPods(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	namespace := paramString(args, "namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	cluster := paramString(args, "cluster")
+
+	// 跨集群查询时部分集群失败仍返回其余集群的Pod
+	pods, err := s.containerService.GetKubernetesPods(cluster, namespace)
+	var partial *PartialClusterError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, nil, err
+	}
+
+	notRunning := 0
+	for _, pod := range pods {
+		if pod.Phase != "Running" && pod.Phase != "Succeeded" {
+			notRunning++
+		}
+	}
+	summary := fmt.Sprintf("命名空间 %s 共 %d 个Pod，%d 个非运行状态", namespace, len(pods), notRunning)
+	if cluster != "" {
+		summary = fmt.Sprintf("集群 %s ", cluster) + summary
+	}
+	if partial != nil {
+		summary += fmt.Sprintf("（%d 个集群查询失败）", len(partial.Failed))
+	}
+	return pods, &AssistantEvidence{Summary: summary}, nil
+}
+
+// toolSearchKnowledge 知识库检索工具
+func (s *AssistantService) toolSearchKnowledge(args map[string]interface{}) (interface{}, *AssistantEvidence, error) {
+	query := paramString(args, "query")
+	if query == "" {
+		return nil, nil, errors.New("query is required")
+	}
+
+	entries, total, err := s.aiService.ListKnowledgeBase(1, 5, paramString(args, "category"), query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	titles := make([]string, len(entries))
+	for i, entry := range entries {
+		titles[i] = entry.Title
+	}
+	return entries, &AssistantEvidence{Summary: fmt.Sprintf("匹配知识 %d 条：%s", total, strings.Join(titles, "、"))}, nil
+}
+
+// allowedTools 根据调用者权限筛选可提供给模型的工具
+func (s *AssistantService) allowedTools(claims *auth.UserClaims) []openai.Tool {
+	names := []string{"query_metrics", "list_alerts", "get_traces", "get_service_map", "get_kubernetes_pods", "search_knowledge"}
+
+	tools := make([]openai.Tool, 0, len(names))
+	for _, name := range names {
+		tool := assistantTools[name]
+		if !canUseTool(claims, tool) {
+			continue
+		}
+		tools = append(tools, openai.Tool{Type: openai.ToolTypeFunction, Function: tool.definition})
+	}
+	return tools
+}
+
+// buildMessages 构建系统提示并载入最近的会话历史
+func (s *AssistantService) buildMessages(conversationID uuid.UUID) ([]openai.ChatCompletionMessage, error) {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: fmt.Sprintf(assistantSystemPrompt, time.Now().Format(time.RFC3339)),
+		},
+	}
+
+	limit := s.config.AIModels.Assistant.HistoryLimit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var history []models.AssistantMessage
+	if err := s.db.Where("conversation_id = ?", conversationID).
+		Order("created_at DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to load conversation history: %w", err)
+	}
+
+	// 按时间正序，并跳过被截断在开头的工具调用消息，保证消息序列合法
+	started := false
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		if !started {
+			if msg.Role != "user" {
+				continue
+			}
+			started = true
+		}
+
+		switch msg.Role {
+		case "user":
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: msg.Content})
+		case "assistant":
+			message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: msg.Content}
+			if msg.ToolCalls != "" {
+				json.Unmarshal([]byte(msg.ToolCalls), &message.ToolCalls)
+			}
+			messages = append(messages, message)
+		case "tool":
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    msg.Content,
+				Name:       msg.ToolName,
+				ToolCallID: msg.ToolCallID,
+			})
+		}
+	}
+
+	return messages, nil
+}
+
+// loadOrCreateConversation 获取用户会话，未指定时新建
+func (s *AssistantService) loadOrCreateConversation(userID uuid.UUID, req *AssistantChatRequest) (*models.AssistantConversation, error) {
+	if req.ConversationID != nil {
+		return s.getConversation(userID, *req.ConversationID)
+	}
+
+	title := req.Message
+	if utf8.RuneCountInString(title) > 50 {
+		title = string([]rune(title)[:50]) + "..."
+	}
+	conversation := &models.AssistantConversation{
+		UserID:     userID,
+		Title:      title,
+		LastActive: time.Now(),
+	}
+	if err := s.db.Create(conversation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// getConversation 获取属于该用户的会话
+func (s *AssistantService) getConversation(userID, conversationID uuid.UUID) (*models.AssistantConversation, error) {
+	var conversation models.AssistantConversation
+	if err := s.db.Where("id = ? AND user_id = ?", conversationID, userID).First(&conversation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("conversation not found")
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return &conversation, nil
+}
+
+// saveMessage 保存会话消息
+func (s *AssistantService) saveMessage(msg *models.AssistantMessage) error {
+	if err := s.db.Create(msg).Error; err != nil {
+		return fmt.Errorf("failed to save conversation message: %w", err)
+	}
+	return nil
+}
+
+// model 获取对话使用的模型
+func (s *AssistantService) model() string {
+	if model := s.config.AIModels.OpenAI.Model; model != "" {
+		return model
+	}
+	return openai.GPT3Dot5Turbo
+}
+
+// toConversationResponse 转换为会话响应格式
+func (s *AssistantService) toConversationResponse(conversation *models.AssistantConversation) *AssistantConversationResponse {
+	return &AssistantConversationResponse{
+		ID:          conversation.ID,
+		Title:       conversation.Title,
+		TotalTokens: conversation.TotalTokens,
+		LastActive:  conversation.LastActive,
+		CreatedAt:   conversation.CreatedAt,
+	}
+}
+
+// canUseTool 管理员可使用全部工具，其他用户需具备工具对应的只读权限
+func canUseTool(claims *auth.UserClaims, tool *assistantTool) bool {
+	return claims.IsAdmin() || claims.HasPermission(tool.permission)
+}
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+	"ai-monitor/pkg/docker"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 容器事件告警使用的指标名
+const (
+	// ContainerMetricOOMKilled 取值为窗口内OOM次数
+	ContainerMetricOOMKilled = "container.oom_killed"
+	// ContainerMetricCrashLoop 取值为规则Duration窗口内的崩溃重启次数
+	ContainerMetricCrashLoop = "container.crash_loop"
+)
+
+// trackedContainerActions 需要记录的容器事件
+var trackedContainerActions = map[string]bool{
+	"start":         true,
+	"die":           true,
+	"oom":           true,
+	"restart":       true,
+	"health_status": true,
+}
+
+// builtinContainerEventRules 内置容器事件告警规则，Threshold与Duration可在规则中调整
+var builtinContainerEventRules = []models.AlertRule{
+	{
+		Name:        "Container OOM killed",
+		Description: "容器因内存不足被内核终止；Duration内未再次OOM时自动恢复",
+		Metric:      ContainerMetricOOMKilled,
+		Condition:   ">=",
+		Threshold:   1,
+		Duration:    1800,
+		Severity:    "high",
+	},
+	{
+		Name:        "Container crash loop",
+		Description: "容器在Duration窗口内崩溃重启次数达到Threshold",
+		Metric:      ContainerMetricCrashLoop,
+		Condition:   ">=",
+		Threshold:   5,
+		Duration:    600,
+		Severity:    "critical",
+	},
+}
+
+// ContainerEventService 订阅Docker事件流，维护容器状态并驱动OOM与崩溃循环告警
+type ContainerEventService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	alertService *AlertService
+	client       *docker.Client
+}
+
+// NewContainerEventService 创建容器事件服务
+func NewContainerEventService(db *gorm.DB, cacheManager *cache.CacheManager, cfg *config.Config, alertService *AlertService) (*ContainerEventService, error) {
+	dockerCfg := cfg.Monitoring.Docker
+	client, err := docker.NewClient(docker.Config{
+		Endpoint:   dockerCfg.Endpoint,
+		APIVersion: dockerCfg.APIVersion,
+		Timeout:    dockerCfg.Timeout,
+		TLSVerify:  dockerCfg.TLSVerify,
+		CertPath:   dockerCfg.CertPath,
+		KeyPath:    dockerCfg.KeyPath,
+		CAPath:     dockerCfg.CAPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContainerEventService{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       cfg,
+		alertService: alertService,
+		client:       client,
+	}, nil
+}
+
+// EnsureBuiltinRules 写入内置容器事件告警规则，已存在（含已删除）的规则不会被覆盖
+func (s *ContainerEventService) EnsureBuiltinRules() error {
+	for _, builtin := range builtinContainerEventRules {
+		var count int64
+		if err := s.db.Unscoped().Model(&models.AlertRule{}).
+			Where("kind = ? AND metric = ?", "event", builtin.Metric).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check container event rule: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		rule := builtin
+		rule.Kind = "event"
+		rule.Enabled = true
+		if err := s.db.Create(&rule).Error; err != nil {
+			return fmt.Errorf("failed to seed container event rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// RunDockerEvents 订阅事件流直到ctx取消，断线后从最后一个事件的时间点补齐
+func (s *ContainerEventService) RunDockerEvents(ctx context.Context, cfg config.DockerEventsConfig) {
+	reconnect := cfg.ReconnectInterval
+	if reconnect <= 0 {
+		reconnect = 10 * time.Second
+	}
+
+	if err := s.EnsureBuiltinRules(); err != nil {
+		logger.GetLogger("docker").WithError(err).Error("failed to ensure builtin container event rules")
+	}
+	go s.runAlertResolution(ctx, cfg)
+
+	var since time.Time
+	for {
+		// 每次（重新）连接时同步一次容器状态，覆盖断线期间丢失的变化
+		if err := s.SyncContainers(ctx); err != nil {
+			// 同步失败仍尝试订阅
+			logger.GetLogger("docker").WithError(err).Warn("failed to sync containers")
+		}
+
+		err := s.client.Events(ctx, docker.EventsOptions{
+			Since:   since,
+			Filters: map[string][]string{"type": {"container"}},
+		}, func(event *docker.Event) error {
+			since = event.Timestamp().Add(time.Nanosecond)
+			if err := s.HandleEvent(ctx, cfg, event); err != nil {
+				// 单个事件处理失败不中断事件流
+				logger.GetLogger("docker").WithError(err).WithField("container", event.Actor.ID).Warn("failed to handle container event")
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.GetLogger("docker").WithError(err).Warn("docker event stream disconnected, reconnecting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnect):
+		}
+	}
+}
+
+// runAlertResolution 周期性恢复不再满足条件的事件告警并清理过期事件
+func (s *ContainerEventService) runAlertResolution(ctx context.Context, cfg config.DockerEventsConfig) {
+	interval := cfg.ResolveInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ResolveEventAlerts(); err != nil {
+				logger.GetLogger("docker").WithError(err).Error("failed to resolve container event alerts")
+			}
+			if cfg.Retention > 0 {
+				s.db.Where("timestamp < ?", time.Now().Add(-cfg.Retention)).Delete(&models.ContainerEvent{})
+			}
+		}
+	}
+}
+
+// SyncContainers 同步守护进程上所有容器的状态到ContainerMonitor
+func (s *ContainerEventService) SyncContainers(ctx context.Context) error {
+	containers, err := s.client.ContainerList(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to list docker containers: %w", err)
+	}
+	for _, c := range containers {
+		if _, err := s.syncContainer(ctx, c.ID); err != nil {
+			// 容器可能在列表与查询之间被删除
+			continue
+		}
+	}
+	return nil
+}
+
+// HandleEvent 处理单个Docker事件：记录事件、更新容器状态并评估内置告警
+func (s *ContainerEventService) HandleEvent(ctx context.Context, cfg config.DockerEventsConfig, event *docker.Event) error {
+	if event.Type != "container" {
+		return nil
+	}
+
+	// 健康检查事件的Action形如 "health_status: unhealthy"
+	action, health := event.Action, ""
+	if strings.HasPrefix(action, "health_status") {
+		health = strings.TrimSpace(strings.TrimPrefix(action, "health_status:"))
+		action = "health_status"
+	}
+	if !trackedContainerActions[action] {
+		return nil
+	}
+
+	record := &models.ContainerEvent{
+		ContainerID:   event.Actor.ID,
+		ContainerName: event.Actor.Attributes["name"],
+		Image:         event.Actor.Attributes["image"],
+		Platform:      "docker",
+		Action:        action,
+		Health:        health,
+		Timestamp:     event.Timestamp(),
+	}
+	if code, err := strconv.Atoi(event.Actor.Attributes["exitCode"]); err == nil {
+		record.ExitCode = &code
+	}
+	if attrs, err := json.Marshal(event.Actor.Attributes); err == nil {
+		record.Attributes = string(attrs)
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to save container event: %w", err)
+	}
+
+	switch action {
+	case "start", "die", "restart":
+		inspected, err := s.syncContainer(ctx, event.Actor.ID)
+		if err != nil && !docker.IsNotFound(err) {
+			return err
+		}
+		if action == "start" {
+			return s.evaluateCrashLoop(ctx, cfg, record)
+		}
+		// cgroup v2下部分内核不产生oom事件，以容器退出时的OOMKilled标记补齐
+		if action == "die" && inspected != nil && inspected.State != nil && inspected.State.OOMKilled {
+			return s.recordInferredOOM(ctx, cfg, record)
+		}
+	case "oom":
+		return s.fireOOMAlert(ctx, cfg, record)
+	case "health_status":
+		status := "running"
+		if health == "unhealthy" {
+			status = "error"
+		}
+		if err := s.db.Model(&models.ContainerMonitor{}).
+			Where("container_id = ?", event.Actor.ID).
+			Updates(map[string]interface{}{"status": status, "last_seen": record.Timestamp}).Error; err != nil {
+			return fmt.Errorf("failed to update container status: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncContainer 查询容器详情并写入ContainerMonitor，已被删除（软删除）的监控项不再更新
+func (s *ContainerEventService) syncContainer(ctx context.Context, containerID string) (*docker.ContainerJSON, error) {
+	inspected, err := s.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var monitor models.ContainerMonitor
+	err = s.db.Unscoped().Where("container_id = ?", inspected.ID).First(&monitor).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return inspected, fmt.Errorf("failed to query container monitor: %w", err)
+	}
+	if err == nil && monitor.DeletedAt.Valid {
+		return inspected, nil
+	}
+
+	image, tag := "", ""
+	if inspected.Config != nil {
+		image, tag = splitImageTag(inspected.Config.Image)
+	}
+	now := time.Now()
+	monitor.ContainerID = inspected.ID
+	monitor.Name = strings.TrimPrefix(inspected.Name, "/")
+	monitor.Image = image
+	monitor.ImageTag = tag
+	monitor.Platform = "docker"
+	monitor.Status = containerMonitorStatus(inspected.State)
+	monitor.RestartCount = inspected.RestartCount
+	monitor.LastSeen = &now
+	if inspected.State != nil {
+		monitor.StartedAt = nonZeroTime(inspected.State.StartedAt)
+		monitor.FinishedAt = nonZeroTime(inspected.State.FinishedAt)
+	}
+	if inspected.HostConfig != nil {
+		monitor.CPULimit, monitor.MemoryLimit = "", ""
+		if inspected.HostConfig.NanoCPUs > 0 {
+			monitor.CPULimit = strconv.FormatFloat(float64(inspected.HostConfig.NanoCPUs)/1e9, 'f', -1, 64)
+		}
+		if inspected.HostConfig.Memory > 0 {
+			monitor.MemoryLimit = strconv.FormatInt(inspected.HostConfig.Memory, 10)
+		}
+	}
+	if inspected.Config != nil && len(inspected.Config.Labels) > 0 {
+		if labels, err := json.Marshal(inspected.Config.Labels); err == nil {
+			monitor.Labels = string(labels)
+		}
+	}
+
+	if monitor.ID == uuid.Nil {
+		err = s.db.Create(&monitor).Error
+	} else {
+		err = s.db.Save(&monitor).Error
+	}
+	if err != nil {
+		return inspected, fmt.Errorf("failed to save container monitor: %w", err)
+	}
+	return inspected, nil
+}
+
+// recordInferredOOM 退出时检测到OOMKilled且近期没有oom事件时补记事件并告警
+func (s *ContainerEventService) recordInferredOOM(ctx context.Context, cfg config.DockerEventsConfig, die *models.ContainerEvent) error {
+	var count int64
+	if err := s.db.Model(&models.ContainerEvent{}).
+		Where("container_id = ? AND action = ? AND timestamp >= ?", die.ContainerID, "oom", die.Timestamp.Add(-time.Minute)).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to query oom events: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	oom := &models.ContainerEvent{
+		ContainerID:   die.ContainerID,
+		ContainerName: die.ContainerName,
+		Image:         die.Image,
+		Platform:      die.Platform,
+		Action:        "oom",
+		ExitCode:      die.ExitCode,
+		Attributes:    `{"source":"inspect"}`,
+		Timestamp:     die.Timestamp,
+	}
+	if err := s.db.Create(oom).Error; err != nil {
+		return fmt.Errorf("failed to save container event: %w", err)
+	}
+	return s.fireOOMAlert(ctx, cfg, oom)
+}
+
+// fireOOMAlert 触发OOM告警，取值为规则Duration窗口内的OOM次数
+func (s *ContainerEventService) fireOOMAlert(ctx context.Context, cfg config.DockerEventsConfig, event *models.ContainerEvent) error {
+	rule, err := s.eventRule(ContainerMetricOOMKilled)
+	if err != nil || rule == nil {
+		return err
+	}
+	data := s.eventMetricData(rule, event)
+	if !ruleLabelsMatch(rule, data.Tags) {
+		return nil
+	}
+
+	window := time.Duration(rule.Duration) * time.Second
+	var count int64
+	if err := s.db.Model(&models.ContainerEvent{}).
+		Where("container_id = ? AND action = ? AND timestamp >= ?", event.ContainerID, "oom", event.Timestamp.Add(-window)).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count oom events: %w", err)
+	}
+	data.Value = float64(count)
+	if !s.alertService.evaluateCondition(rule.Condition, data.Value, rule.Threshold) {
+		return nil
+	}
+
+	return s.alertService.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+		Summary: fmt.Sprintf("Container %s was OOM killed (%d times in %s)",
+			containerDisplayName(event), count, formatWindow(window)),
+		Description: fmt.Sprintf("Container %s (image %s) exceeded its memory limit and was killed by the kernel",
+			containerDisplayName(event), event.Image),
+		Details:   s.eventAlertDetails(ctx, cfg, event, map[string]interface{}{"oom_count": count, "window": formatWindow(window)}),
+		Reference: rule.Threshold,
+	})
+}
+
+// evaluateCrashLoop 容器启动时统计窗口内的崩溃重启次数
+func (s *ContainerEventService) evaluateCrashLoop(ctx context.Context, cfg config.DockerEventsConfig, event *models.ContainerEvent) error {
+	rule, err := s.eventRule(ContainerMetricCrashLoop)
+	if err != nil || rule == nil {
+		return err
+	}
+	data := s.eventMetricData(rule, event)
+	if !ruleLabelsMatch(rule, data.Tags) {
+		return nil
+	}
+
+	window := time.Duration(rule.Duration) * time.Second
+	restarts, err := s.countRestarts(event.ContainerID, event.Timestamp.Add(-window), event.Timestamp)
+	if err != nil {
+		return err
+	}
+	data.Value = float64(restarts)
+	if !s.alertService.evaluateCondition(rule.Condition, data.Value, rule.Threshold) {
+		return nil
+	}
+
+	return s.alertService.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+		Summary: fmt.Sprintf("Container %s is crash looping: %d restarts in %s",
+			containerDisplayName(event), restarts, formatWindow(window)),
+		Description: fmt.Sprintf("Container %s (image %s) restarted %d times within %s (threshold %.0f)",
+			containerDisplayName(event), event.Image, restarts, formatWindow(window), rule.Threshold),
+		Details:   s.eventAlertDetails(ctx, cfg, event, map[string]interface{}{"restarts": restarts, "window": formatWindow(window)}),
+		Reference: rule.Threshold,
+	})
+}
+
+// countRestarts 统计区间内紧跟在die之后的start次数，即崩溃后被重新拉起的次数
+func (s *ContainerEventService) countRestarts(containerID string, start, end time.Time) (int, error) {
+	var events []models.ContainerEvent
+	if err := s.db.Select("action", "timestamp").
+		Where("container_id = ? AND action IN ? AND timestamp >= ? AND timestamp <= ?", containerID, []string{"start", "die"}, start, end).
+		Order("timestamp ASC").
+		Find(&events).Error; err != nil {
+		return 0, fmt.Errorf("failed to query container events: %w", err)
+	}
+
+	restarts := 0
+	for i := 1; i < len(events); i++ {
+		if events[i].Action == "start" && events[i-1].Action == "die" {
+			restarts++
+		}
+	}
+	return restarts, nil
+}
+
+// ResolveEventAlerts 恢复不再满足条件的容器事件告警
+func (s *ContainerEventService) ResolveEventAlerts() error {
+	var rules []models.AlertRule
+	if err := s.db.Where("kind = ?", "event").Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to query container event rules: %w", err)
+	}
+
+	now := time.Now()
+	for i := range rules {
+		rule := &rules[i]
+		var alerts []models.Alert
+		if err := s.db.Where("rule_id = ? AND status = ?", rule.ID, "firing").Find(&alerts).Error; err != nil {
+			return fmt.Errorf("failed to query firing alerts: %w", err)
+		}
+
+		window := time.Duration(rule.Duration) * time.Second
+		for _, alert := range alerts {
+			var labels map[string]interface{}
+			if err := json.Unmarshal([]byte(alert.Labels), &labels); err != nil {
+				continue
+			}
+			containerID := fmt.Sprint(labels["target_id"])
+
+			var value float64
+			switch rule.Metric {
+			case ContainerMetricOOMKilled:
+				var count int64
+				if err := s.db.Model(&models.ContainerEvent{}).
+					Where("container_id = ? AND action = ? AND timestamp >= ?", containerID, "oom", now.Add(-window)).
+					Count(&count).Error; err != nil {
+					return fmt.Errorf("failed to count oom events: %w", err)
+				}
+				value = float64(count)
+			case ContainerMetricCrashLoop:
+				restarts, err := s.countRestarts(containerID, now.Add(-window), now)
+				if err != nil {
+					return err
+				}
+				value = float64(restarts)
+			default:
+				continue
+			}
+
+			// 规则被禁用或条件不再满足时恢复
+			if rule.Enabled && s.alertService.evaluateCondition(rule.Condition, value, rule.Threshold) {
+				continue
+			}
+			if err := s.alertService.resolveEvaluatedAlert(rule, &MetricData{
+				TargetType: "container",
+				TargetID:   containerID,
+				MetricName: rule.Metric,
+				Value:      value,
+				Timestamp:  now,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListContainerEvents 分页查询容器事件
+func (s *ContainerEventService) ListContainerEvents(page, pageSize int, containerID, action string, start, end *time.Time) ([]models.ContainerEvent, int64, error) {
+	query := s.db.Model(&models.ContainerEvent{})
+	if containerID != "" {
+		query = query.Where("container_id = ?", containerID)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if start != nil {
+		query = query.Where("timestamp >= ?", *start)
+	}
+	if end != nil {
+		query = query.Where("timestamp <= ?", *end)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count container events: %w", err)
+	}
+
+	var events []models.ContainerEvent
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("timestamp DESC").Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list container events: %w", err)
+	}
+	return events, total, nil
+}
+
+// eventRule 获取启用的内置事件规则，未配置时返回nil
+func (s *ContainerEventService) eventRule(metric string) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := s.db.Where("kind = ? AND metric = ? AND enabled = ?", "event", metric, true).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query container event rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// eventMetricData 将容器事件转换为告警评估数据，Tags用于规则标签过滤
+func (s *ContainerEventService) eventMetricData(rule *models.AlertRule, event *models.ContainerEvent) *MetricData {
+	return &MetricData{
+		TargetType: "container",
+		TargetID:   event.ContainerID,
+		MetricName: rule.Metric,
+		Tags: map[string]interface{}{
+			"container_id":   event.ContainerID,
+			"container_name": event.ContainerName,
+			"image":          event.Image,
+		},
+		Timestamp: event.Timestamp,
+	}
+}
+
+// eventAlertDetails 告警证据：事件信息与容器最近的日志
+func (s *ContainerEventService) eventAlertDetails(ctx context.Context, cfg config.DockerEventsConfig, event *models.ContainerEvent, extra map[string]interface{}) map[string]interface{} {
+	details := map[string]interface{}{
+		"container_id":   event.ContainerID,
+		"container_name": event.ContainerName,
+		"image":          event.Image,
+		"event_time":     event.Timestamp,
+	}
+	if event.ExitCode != nil {
+		details["exit_code"] = *event.ExitCode
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+
+	if cfg.LogLines > 0 {
+		lines, err := s.client.ContainerLogLines(ctx, event.ContainerID, docker.LogsOptions{
+			Tail:       cfg.LogLines,
+			Timestamps: true,
+		})
+		if err != nil {
+			details["logs_error"] = err.Error()
+		} else {
+			details["logs"] = lines
+		}
+	}
+	return details
+}
+
+// containerMonitorStatus 将Docker容器状态映射为ContainerMonitor状态
+func containerMonitorStatus(state *docker.ContainerState) string {
+	if state == nil {
+		return "stopped"
+	}
+	switch state.Status {
+	case "running":
+		return "running"
+	case "paused":
+		return "paused"
+	case "exited":
+		if state.OOMKilled {
+			return "error"
+		}
+		return "exited"
+	case "restarting", "dead":
+		return "error"
+	default:
+		return "stopped"
+	}
+}
+
+// splitImageTag 拆分镜像名与标签，兼容带端口的仓库地址
+func splitImageTag(ref string) (string, string) {
+	if at := strings.Index(ref, "@"); at >= 0 {
+		return ref[:at], ref[at+1:]
+	}
+	colon := strings.LastIndex(ref, ":")
+	if colon > strings.LastIndex(ref, "/") {
+		return ref[:colon], ref[colon+1:]
+	}
+	return ref, "latest"
+}
+
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() || t.Year() <= 1 {
+		return nil
+	}
+	return &t
+}
+
+func containerDisplayName(event *models.ContainerEvent) string {
+	if event.ContainerName != "" {
+		return event.ContainerName
+	}
+	if len(event.ContainerID) > 12 {
+		return event.ContainerID[:12]
+	}
+	return event.ContainerID
+}
@@ -3,11 +3,15 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"ai-monitor/internal/cache"
 	"ai-monitor/internal/config"
+	"ai-monitor/internal/models"
 
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -21,7 +25,8 @@ type ContainerService struct {
 	config        *config.Config
 	prometheusAPI v1.API
 	dockerClient  DockerClient
-	k8sClient     KubernetesClient
+	clusters      *KubernetesClusterService
+	inventory     *KubernetesInventoryService
 }
 
 // DockerClient Docker客户端接口
@@ -30,20 +35,25 @@ type DockerClient interface {
 	GetContainer(ctx context.Context, containerID string) (*DockerContainerDetail, error)
 	GetContainerStats(ctx context.Context, containerID string) (*DockerContainerStats, error)
 	GetContainerLogs(ctx context.Context, containerID string, options LogOptions) ([]string, error)
+	RestartContainer(ctx context.Context, containerID string, timeout int) error
 }
 
 // KubernetesClient Kubernetes客户端接口
 type KubernetesClient interface {
+	Ping(ctx context.Context) (string, error)
 	ListPods(ctx context.Context, namespace string) ([]KubernetesPod, error)
 	GetPod(ctx context.Context, namespace, name string) (*KubernetesPodDetail, error)
 	ListNodes(ctx context.Context) ([]KubernetesNode, error)
 	GetNode(ctx context.Context, name string) (*KubernetesNodeDetail, error)
 	ListNamespaces(ctx context.Context) ([]KubernetesNamespace, error)
 	GetClusterMetrics(ctx context.Context) (*KubernetesClusterMetrics, error)
+	GetPodResourceUsage(ctx context.Context, namespace, name string) (*PodResourceUsage, error)
+	GetResourceUsage(ctx context.Context, namespace string) (*ClusterResourceMetrics, error)
+	ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) error
 }
 
-// NewContainerService 创建容器监控服务
-func NewContainerService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config) (*ContainerService, error) {
+// NewContainerService 创建容器监控服务，Kubernetes客户端由集群注册表按集群提供，资源缓存已同步时优先使用缓存
+func NewContainerService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, clusters *KubernetesClusterService, inventory *KubernetesInventoryService) (*ContainerService, error) {
 	// 创建Prometheus客户端
 	client, err := api.NewClient(api.Config{
 		Address: config.Prometheus.URL,
@@ -55,24 +65,19 @@ func NewContainerService(db *gorm.DB, cacheManager *cache.CacheManager, config *
 	prometheusAPI := v1.NewAPI(client)
 
 	// 创建Docker客户端
-	dockerClient, err := NewDockerClient("unix:///var/run/docker.sock")
+	dockerClient, err := NewDockerClient(config.Monitoring.Docker)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
-	// 创建Kubernetes客户端
-	k8sClient, err := NewKubernetesClient("")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
-	}
-
 	return &ContainerService{
 		db:            db,
 		cacheManager:  cacheManager,
 		config:        config,
 		prometheusAPI: prometheusAPI,
 		dockerClient:  dockerClient,
-		k8sClient:     k8sClient,
+		clusters:      clusters,
+		inventory:     inventory,
 	}, nil
 }
 
@@ -216,6 +221,7 @@ type ContainerPIDsStats struct {
 
 // KubernetesPod Kubernetes Pod信息
 type KubernetesPod struct {
+	Cluster     string            `json:"cluster,omitempty"`
 	Name        string            `json:"name"`
 	Namespace   string            `json:"namespace"`
 	UID         string            `json:"uid"`
@@ -408,6 +414,7 @@ type PVCVolumeSource struct {
 
 // KubernetesNode Kubernetes节点信息
 type KubernetesNode struct {
+	Cluster     string            `json:"cluster,omitempty"`
 	Name        string            `json:"name"`
 	UID         string            `json:"uid"`
 	Labels      map[string]string `json:"labels"`
@@ -479,6 +486,7 @@ type NodeResourceUsage struct {
 
 // KubernetesNamespace Kubernetes命名空间
 type KubernetesNamespace struct {
+	Cluster     string            `json:"cluster,omitempty"`
 	Name        string            `json:"name"`
 	UID         string            `json:"uid"`
 	Labels      map[string]string `json:"labels"`
@@ -631,41 +639,72 @@ func (s *ContainerService) GetDockerContainer(containerID string) (*DockerContai
 	return container, nil
 }
 
-// GetKubernetesPods 获取Kubernetes Pod列表
-func (s *ContainerService) GetKubernetesPods(namespace string) ([]KubernetesPod, error) {
+// RestartDockerContainer 重启Docker容器
+func (s *ContainerService) RestartDockerContainer(containerID string, timeout int) error {
 	ctx := context.Background()
 
-	// 检查缓存
-	cacheKey := fmt.Sprintf("k8s_pods:%s", namespace)
+	if err := s.dockerClient.RestartContainer(ctx, containerID, timeout); err != nil {
+		return fmt.Errorf("failed to restart docker container: %w", err)
+	}
+
+	// 清除容器缓存
 	if s.cacheManager != nil {
-		var pods []KubernetesPod
-		if err := s.cacheManager.Get(ctx, cacheKey, &pods); err == nil {
-			return pods, nil
-		}
+		s.cacheManager.Delete(ctx, fmt.Sprintf("docker_container:%s", containerID))
+		s.cacheManager.Delete(ctx, "docker_containers")
 	}
 
-	// 从Kubernetes API获取Pod列表
-	pods, err := s.k8sClient.ListPods(ctx, namespace)
+	return nil
+}
+
+// ScaleKubernetesDeployment 调整Kubernetes Deployment副本数，cluster为空时使用默认集群
+func (s *ContainerService) ScaleKubernetesDeployment(cluster, namespace, name string, replicas int32) error {
+	ctx := context.Background()
+
+	client, target, err := s.kubernetesClient(cluster)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list kubernetes pods: %w", err)
+		return err
+	}
+	if err := client.ScaleDeployment(ctx, namespace, name, replicas); err != nil {
+		return fmt.Errorf("failed to scale deployment: %w", err)
 	}
 
-	// 缓存结果
+	// 清除Pod缓存
 	if s.cacheManager != nil {
-		if data, err := json.Marshal(pods); err == nil {
-			s.cacheManager.Set(ctx, cacheKey, string(data), 2*time.Minute)
-		}
+		s.cacheManager.Delete(ctx, fmt.Sprintf("k8s_pods:%s:%s", target.Name, namespace))
 	}
 
+	return nil
+}
+
+// GetKubernetesPods 获取Kubernetes Pod列表，cluster为all时聚合全部已启用集群
+// 部分集群失败时返回已获取的数据和*PartialClusterError
+func (s *ContainerService) GetKubernetesPods(cluster, namespace string) ([]KubernetesPod, error) {
+	pods, err := listKubernetesClusters(s, cluster,
+		func(c *models.KubernetesCluster) ([]KubernetesPod, bool) { return s.inventory.Pods(c.ID, namespace) },
+		func(name string) string { return fmt.Sprintf("k8s_pods:%s:%s", name, namespace) },
+		2*time.Minute,
+		func(ctx context.Context, client KubernetesClient) ([]KubernetesPod, error) {
+			return client.ListPods(ctx, namespace)
+		},
+		func(pod *KubernetesPod, name string) { pod.Cluster = name },
+	)
+	if err != nil {
+		return pods, fmt.Errorf("failed to list kubernetes pods: %w", err)
+	}
 	return pods, nil
 }
 
 // GetKubernetesPod 获取Kubernetes Pod详情
-func (s *ContainerService) GetKubernetesPod(namespace, name string) (*KubernetesPodDetail, error) {
+func (s *ContainerService) GetKubernetesPod(cluster, namespace, name string) (*KubernetesPodDetail, error) {
 	ctx := context.Background()
 
+	client, target, err := s.kubernetesClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查缓存
-	cacheKey := fmt.Sprintf("k8s_pod:%s:%s", namespace, name)
+	cacheKey := fmt.Sprintf("k8s_pod:%s:%s:%s", target.Name, namespace, name)
 	if s.cacheManager != nil {
 		var pod KubernetesPodDetail
 		if err := s.cacheManager.Get(ctx, cacheKey, &pod); err == nil {
@@ -674,13 +713,14 @@ func (s *ContainerService) GetKubernetesPod(namespace, name string) (*Kubernetes
 	}
 
 	// 从Kubernetes API获取Pod详情
-	pod, err := s.k8sClient.GetPod(ctx, namespace, name)
+	pod, err := client.GetPod(ctx, namespace, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get kubernetes pod: %w", err)
 	}
+	pod.Cluster = target.Name
 
 	// 获取Pod资源使用情况
-	resourceUsage, err := s.getPodResourceUsage(ctx, namespace, name)
+	resourceUsage, err := client.GetPodResourceUsage(ctx, namespace, name)
 	if err == nil {
 		pod.ResourceUsage = resourceUsage
 	}
@@ -695,70 +735,50 @@ func (s *ContainerService) GetKubernetesPod(namespace, name string) (*Kubernetes
 	return pod, nil
 }
 
-// GetKubernetesNodes 获取Kubernetes节点列表
-func (s *ContainerService) GetKubernetesNodes() ([]KubernetesNode, error) {
-	ctx := context.Background()
-
-	// 检查缓存
-	cacheKey := "k8s_nodes"
-	if s.cacheManager != nil {
-		var nodes []KubernetesNode
-		if err := s.cacheManager.Get(ctx, cacheKey, &nodes); err == nil {
-			return nodes, nil
-		}
-	}
-
-	// 从Kubernetes API获取节点列表
-	nodes, err := s.k8sClient.ListNodes(ctx)
+// GetKubernetesNodes 获取Kubernetes节点列表，cluster为all时聚合全部已启用集群
+func (s *ContainerService) GetKubernetesNodes(cluster string) ([]KubernetesNode, error) {
+	nodes, err := listKubernetesClusters(s, cluster,
+		func(c *models.KubernetesCluster) ([]KubernetesNode, bool) { return s.inventory.Nodes(c.ID) },
+		func(name string) string { return fmt.Sprintf("k8s_nodes:%s", name) },
+		5*time.Minute,
+		func(ctx context.Context, client KubernetesClient) ([]KubernetesNode, error) {
+			return client.ListNodes(ctx)
+		},
+		func(node *KubernetesNode, name string) { node.Cluster = name },
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list kubernetes nodes: %w", err)
+		return nodes, fmt.Errorf("failed to list kubernetes nodes: %w", err)
 	}
-
-	// 缓存结果
-	if s.cacheManager != nil {
-		if data, err := json.Marshal(nodes); err == nil {
-			s.cacheManager.Set(ctx, cacheKey, string(data), 5*time.Minute)
-		}
-	}
-
 	return nodes, nil
 }
 
-// GetKubernetesClusterMetrics 获取Kubernetes集群指标
-// GetKubernetesNamespaces 获取Kubernetes命名空间列表
-func (s *ContainerService) GetKubernetesNamespaces() ([]KubernetesNamespace, error) {
-	ctx := context.Background()
-
-	// 检查缓存
-	cacheKey := "k8s_namespaces"
-	if s.cacheManager != nil {
-		var namespaces []KubernetesNamespace
-		if err := s.cacheManager.Get(ctx, cacheKey, &namespaces); err == nil {
-			return namespaces, nil
-		}
-	}
-
-	// 从Kubernetes API获取命名空间列表
-	namespaces, err := s.k8sClient.ListNamespaces(ctx)
+// GetKubernetesNamespaces 获取Kubernetes命名空间列表，cluster为all时聚合全部已启用集群
+func (s *ContainerService) GetKubernetesNamespaces(cluster string) ([]KubernetesNamespace, error) {
+	namespaces, err := listKubernetesClusters(s, cluster, nil,
+		func(name string) string { return fmt.Sprintf("k8s_namespaces:%s", name) },
+		5*time.Minute,
+		func(ctx context.Context, client KubernetesClient) ([]KubernetesNamespace, error) {
+			return client.ListNamespaces(ctx)
+		},
+		func(namespace *KubernetesNamespace, name string) { namespace.Cluster = name },
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list kubernetes namespaces: %w", err)
-	}
-
-	// 缓存结果
-	if s.cacheManager != nil {
-		if data, err := json.Marshal(namespaces); err == nil {
-			s.cacheManager.Set(ctx, cacheKey, string(data), 5*time.Minute)
-		}
+		return namespaces, fmt.Errorf("failed to list kubernetes namespaces: %w", err)
 	}
-
 	return namespaces, nil
 }
 
-func (s *ContainerService) GetKubernetesClusterMetrics() (*KubernetesClusterMetrics, error) {
+// GetKubernetesClusterMetrics 获取Kubernetes集群指标
+func (s *ContainerService) GetKubernetesClusterMetrics(cluster string) (*KubernetesClusterMetrics, error) {
 	ctx := context.Background()
 
+	client, target, err := s.kubernetesClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查缓存
-	cacheKey := "k8s_cluster_metrics"
+	cacheKey := fmt.Sprintf("k8s_cluster_metrics:%s", target.Name)
 	if s.cacheManager != nil {
 		var metrics KubernetesClusterMetrics
 		if err := s.cacheManager.Get(ctx, cacheKey, &metrics); err == nil {
@@ -767,7 +787,7 @@ func (s *ContainerService) GetKubernetesClusterMetrics() (*KubernetesClusterMetr
 	}
 
 	// 从Kubernetes API获取集群指标
-	metrics, err := s.k8sClient.GetClusterMetrics(ctx)
+	metrics, err := client.GetClusterMetrics(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get kubernetes cluster metrics: %w", err)
 	}
@@ -782,9 +802,114 @@ func (s *ContainerService) GetKubernetesClusterMetrics() (*KubernetesClusterMetr
 	return metrics, nil
 }
 
+// GetKubernetesWorkloads 从资源缓存获取工作负载，kind为空时返回全部类型，cluster为all时聚合全部已启用集群
+func (s *ContainerService) GetKubernetesWorkloads(cluster, kind, namespace string) ([]KubernetesWorkload, error) {
+	workloads, err := listKubernetesClusters(s, cluster,
+		func(c *models.KubernetesCluster) ([]KubernetesWorkload, bool) { return s.inventory.Workloads(c.ID, kind, namespace) },
+		nil, 0, nil,
+		func(workload *KubernetesWorkload, name string) { workload.Cluster = name },
+	)
+	if err != nil {
+		return workloads, fmt.Errorf("failed to list kubernetes workloads: %w", err)
+	}
+	return workloads, nil
+}
+
+// GetKubernetesEvents 从资源缓存获取事件，按最近发生时间倒序，cluster为all时聚合全部已启用集群
+func (s *ContainerService) GetKubernetesEvents(cluster string, filter KubernetesEventFilter) ([]KubernetesEvent, error) {
+	events, err := listKubernetesClusters(s, cluster,
+		func(c *models.KubernetesCluster) ([]KubernetesEvent, bool) { return s.inventory.Events(c.ID, filter) },
+		nil, 0, nil,
+		func(event *KubernetesEvent, name string) { event.Cluster = name },
+	)
+	// 跨集群聚合后重新排序并截断
+	sortKubernetesEvents(events)
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	if err != nil {
+		return events, fmt.Errorf("failed to list kubernetes events: %w", err)
+	}
+	return events, nil
+}
+
+// GetKubernetesInventoryStatus 获取各集群资源缓存的同步状态
+func (s *ContainerService) GetKubernetesInventoryStatus() []KubernetesInventoryStatus {
+	return s.inventory.Status()
+}
+
+// kubernetesClient 获取单个集群的客户端，不支持跨集群聚合的接口拒绝cluster=all
+func (s *ContainerService) kubernetesClient(cluster string) (KubernetesClient, *models.KubernetesCluster, error) {
+	if cluster == AllClusters {
+		return nil, nil, ErrAllClustersUnsupported
+	}
+	return s.clusters.Client(cluster)
+}
+
+// listKubernetesClusters 在一个或全部集群上执行列表查询并标记结果所属集群，聚合结果按集群名称排序
+// cached不为nil且集群资源缓存已同步时直接使用缓存，否则查询API并按集群写入缓存；list为nil表示只能从资源缓存读取
+func listKubernetesClusters[T any](s *ContainerService, cluster string, cached func(c *models.KubernetesCluster) ([]T, bool),
+	cacheKey func(name string) string, ttl time.Duration,
+	list func(ctx context.Context, client KubernetesClient) ([]T, error), setCluster func(item *T, name string)) ([]T, error) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	results := make(map[string][]T)
+	err := s.clusters.ForEachCluster(ctx, cluster, func(ctx context.Context, c *models.KubernetesCluster, client KubernetesClient) error {
+		var items []T
+		var ok bool
+		if cached != nil {
+			items, ok = cached(c)
+		}
+		if ok {
+			for i := range items {
+				setCluster(&items[i], c.Name)
+			}
+		} else if list == nil {
+			return ErrInventoryNotSynced
+		} else if key := cacheKey(c.Name); s.cacheManager == nil || s.cacheManager.Get(ctx, key, &items) != nil {
+			var err error
+			items, err = list(ctx, client)
+			if err != nil {
+				return err
+			}
+			for i := range items {
+				setCluster(&items[i], c.Name)
+			}
+			if s.cacheManager != nil {
+				if data, err := json.Marshal(items); err == nil {
+					s.cacheManager.Set(ctx, key, string(data), ttl)
+				}
+			}
+		}
+
+		mu.Lock()
+		results[c.Name] = items
+		mu.Unlock()
+		return nil
+	})
+
+	var partial *PartialClusterError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged := []T{}
+	for _, name := range names {
+		merged = append(merged, results[name]...)
+	}
+	return merged, err
+}
+
 // ResourceUsageResponse 资源使用响应
 type ResourceUsageResponse struct {
 	Platform     string                 `json:"platform"`
+	Cluster      string                 `json:"cluster,omitempty"`
 	ResourceType string                 `json:"resource_type"`
 	Namespace    string                 `json:"namespace,omitempty"`
 	StartTime    *time.Time             `json:"start_time,omitempty"`
@@ -793,12 +918,28 @@ type ResourceUsageResponse struct {
 	Timestamp    time.Time              `json:"timestamp"`
 }
 
-// GetResourceUsage 获取资源使用情况
-func (s *ContainerService) GetResourceUsage(platform, resourceType, namespace string, startTime, endTime *time.Time) (*ResourceUsageResponse, error) {
+// GetResourceUsage 获取资源使用情况，cluster仅对Kubernetes生效，为空时使用默认集群
+func (s *ContainerService) GetResourceUsage(platform, cluster, resourceType, namespace string, startTime, endTime *time.Time) (*ResourceUsageResponse, error) {
 	ctx := context.Background()
 
+	var client KubernetesClient
+	switch platform {
+	case "kubernetes":
+		var target *models.KubernetesCluster
+		var err error
+		client, target, err = s.kubernetesClient(cluster)
+		if err != nil {
+			return nil, err
+		}
+		cluster = target.Name
+	case "docker":
+		cluster = ""
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", platform)
+	}
+
 	// 构建缓存键
-	cacheKey := fmt.Sprintf("resource_usage:%s:%s:%s", platform, resourceType, namespace)
+	cacheKey := fmt.Sprintf("resource_usage:%s:%s:%s:%s", platform, cluster, resourceType, namespace)
 	if s.cacheManager != nil {
 		var usage ResourceUsageResponse
 		if err := s.cacheManager.Get(ctx, cacheKey, &usage); err == nil {
@@ -806,37 +947,21 @@ func (s *ContainerService) GetResourceUsage(platform, resourceType, namespace st
 		}
 	}
 
-	// 模拟资源使用数据
-	metrics := make(map[string]interface{})
-	switch resourceType {
-	case "cpu":
-		metrics["usage_percent"] = 45.2
-		metrics["cores_used"] = 1.8
-		metrics["cores_total"] = 4.0
-	case "memory":
-		metrics["usage_percent"] = 62.5
-		metrics["used_bytes"] = 2147483648 // 2GB
-		metrics["total_bytes"] = 3435973836 // ~3.2GB
-	case "disk":
-		metrics["usage_percent"] = 35.8
-		metrics["used_bytes"] = 10737418240 // 10GB
-		metrics["total_bytes"] = 30064771072 // ~28GB
-	case "network":
-		metrics["rx_bytes"] = 1048576000 // 1GB
-		metrics["tx_bytes"] = 524288000  // 500MB
-		metrics["rx_packets"] = 1000000
-		metrics["tx_packets"] = 800000
-	default:
-		// 返回所有资源类型的综合数据
-		metrics["cpu_usage_percent"] = 45.2
-		metrics["memory_usage_percent"] = 62.5
-		metrics["disk_usage_percent"] = 35.8
-		metrics["network_rx_bytes"] = 1048576000
-		metrics["network_tx_bytes"] = 524288000
+	// 指标为当前时刻的实时值，时间范围仅回显给调用方
+	var metrics map[string]interface{}
+	var err error
+	if client != nil {
+		metrics, err = s.kubernetesResourceUsage(ctx, client, resourceType, namespace)
+	} else {
+		metrics, err = s.dockerResourceUsage(ctx, resourceType)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	usage := &ResourceUsageResponse{
 		Platform:     platform,
+		Cluster:      cluster,
 		ResourceType: resourceType,
 		Namespace:    namespace,
 		StartTime:    startTime,
@@ -855,82 +980,134 @@ func (s *ContainerService) GetResourceUsage(platform, resourceType, namespace st
 	return usage, nil
 }
 
-// 私有方法实现
-
-func (s *ContainerService) getPodResourceUsage(ctx context.Context, namespace, name string) (*PodResourceUsage, error) {
-	// 实现Pod资源使用情况获取逻辑
-	// 这里需要调用Prometheus API获取相应的指标
-	return &PodResourceUsage{}, nil
-}
-
-// Docker客户端实现
-type dockerClient struct {
-	endpoint string
-}
-
-func NewDockerClient(endpoint string) (DockerClient, error) {
-	return &dockerClient{
-		endpoint: endpoint,
-	}, nil
-}
-
-func (c *dockerClient) ListContainers(ctx context.Context) ([]DockerContainer, error) {
-	// 实现Docker API调用逻辑
-	return []DockerContainer{}, nil
-}
-
-func (c *dockerClient) GetContainer(ctx context.Context, containerID string) (*DockerContainerDetail, error) {
-	// 实现Docker API调用逻辑
-	return &DockerContainerDetail{}, nil
-}
-
-func (c *dockerClient) GetContainerStats(ctx context.Context, containerID string) (*DockerContainerStats, error) {
-	// 实现Docker API调用逻辑
-	return &DockerContainerStats{}, nil
-}
-
-func (c *dockerClient) GetContainerLogs(ctx context.Context, containerID string, options LogOptions) ([]string, error) {
-	// 实现Docker API调用逻辑
-	return []string{}, nil
-}
-
-// Kubernetes客户端实现
-type kubernetesClient struct {
-	configPath string
-}
-
-func NewKubernetesClient(configPath string) (KubernetesClient, error) {
-	return &kubernetesClient{
-		configPath: configPath,
-	}, nil
-}
+// kubernetesResourceUsage 基于节点allocatable、Pod requests与metrics-server汇总资源使用情况
+func (s *ContainerService) kubernetesResourceUsage(ctx context.Context, client KubernetesClient, resourceType, namespace string) (map[string]interface{}, error) {
+	usage, err := client.GetResourceUsage(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes resource usage: %w", err)
+	}
 
-func (c *kubernetesClient) ListPods(ctx context.Context, namespace string) ([]KubernetesPod, error) {
-	// 实现Kubernetes API调用逻辑
-	return []KubernetesPod{}, nil
+	metrics := make(map[string]interface{})
+	switch resourceType {
+	case "cpu":
+		metrics["usage_percent"] = usage.CPU.Percent
+		metrics["cores_used"] = usage.CPU.Used
+		metrics["cores_requested"] = usage.CPU.Requested
+		metrics["cores_total"] = usage.CPU.Allocatable
+	case "memory":
+		metrics["usage_percent"] = usage.Memory.Percent
+		metrics["used_bytes"] = usage.Memory.Used
+		metrics["requested_bytes"] = usage.Memory.Requested
+		metrics["total_bytes"] = usage.Memory.Allocatable
+	case "disk":
+		// metrics-server不提供临时存储使用量，只能按请求量统计
+		metrics["request_percent"] = usage.Storage.Percent
+		metrics["requested_bytes"] = usage.Storage.Requested
+		metrics["total_bytes"] = usage.Storage.Allocatable
+	case "network":
+		return nil, errors.New("network usage is not available from kubernetes metrics-server")
+	default:
+		metrics["cpu_usage_percent"] = usage.CPU.Percent
+		metrics["memory_usage_percent"] = usage.Memory.Percent
+		metrics["disk_request_percent"] = usage.Storage.Percent
+		metrics["pods_used"] = usage.Pods.Used
+		metrics["pods_total"] = usage.Pods.Allocatable
+	}
+	return metrics, nil
 }
 
-func (c *kubernetesClient) GetPod(ctx context.Context, namespace, name string) (*KubernetesPodDetail, error) {
-	// 实现Kubernetes API调用逻辑
-	return &KubernetesPodDetail{}, nil
-}
+// dockerResourceUsage 汇总运行中容器的实时统计
+func (s *ContainerService) dockerResourceUsage(ctx context.Context, resourceType string) (map[string]interface{}, error) {
+	containers, err := s.dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list docker containers: %w", err)
+	}
 
-func (c *kubernetesClient) ListNodes(ctx context.Context) ([]KubernetesNode, error) {
-	// 实现Kubernetes API调用逻辑
-	return []KubernetesNode{}, nil
-}
+	// 单次统计需要守护进程采样两次，并发获取以缩短耗时
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, 8)
+		samples []*DockerContainerStats
+	)
+	for _, ct := range containers {
+		if ct.State != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			stats, err := s.dockerClient.GetContainerStats(ctx, id)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			samples = append(samples, stats)
+			mu.Unlock()
+		}(ct.ID)
+	}
+	wg.Wait()
+
+	var cpuPercent float64
+	var onlineCPUs uint32
+	var memUsed, memTotal, rxBytes, txBytes, rxPackets, txPackets, readBytes, writeBytes uint64
+	for _, st := range samples {
+		cpuPercent += st.CPU.UsagePercent
+		if st.CPU.OnlineCPUs > onlineCPUs {
+			onlineCPUs = st.CPU.OnlineCPUs
+		}
+		memUsed += st.Memory.Usage
+		// 未设置内存限制的容器上报的limit即主机内存
+		if st.Memory.Limit > memTotal {
+			memTotal = st.Memory.Limit
+		}
+		rxBytes += st.Network.RxBytes
+		txBytes += st.Network.TxBytes
+		rxPackets += st.Network.RxPackets
+		txPackets += st.Network.TxPackets
+		readBytes += st.BlockIO.ReadBytes
+		writeBytes += st.BlockIO.WriteBytes
+	}
 
-func (c *kubernetesClient) GetNode(ctx context.Context, name string) (*KubernetesNodeDetail, error) {
-	// 实现Kubernetes API调用逻辑
-	return &KubernetesNodeDetail{}, nil
-}
+	// 容器CPU百分比以单核为100%，换算为主机整体占比
+	hostCPUPercent := 0.0
+	if onlineCPUs > 0 {
+		hostCPUPercent = cpuPercent / float64(onlineCPUs)
+	}
+	memPercent := 0.0
+	if memTotal > 0 {
+		memPercent = float64(memUsed) / float64(memTotal) * 100
+	}
 
-func (c *kubernetesClient) ListNamespaces(ctx context.Context) ([]KubernetesNamespace, error) {
-	// 实现Kubernetes API调用逻辑
-	return []KubernetesNamespace{}, nil
+	metrics := make(map[string]interface{})
+	switch resourceType {
+	case "cpu":
+		metrics["usage_percent"] = hostCPUPercent
+		metrics["cores_used"] = cpuPercent / 100
+		metrics["cores_total"] = onlineCPUs
+	case "memory":
+		metrics["usage_percent"] = memPercent
+		metrics["used_bytes"] = memUsed
+		metrics["total_bytes"] = memTotal
+	case "disk":
+		metrics["read_bytes"] = readBytes
+		metrics["write_bytes"] = writeBytes
+	case "network":
+		metrics["rx_bytes"] = rxBytes
+		metrics["tx_bytes"] = txBytes
+		metrics["rx_packets"] = rxPackets
+		metrics["tx_packets"] = txPackets
+	default:
+		metrics["cpu_usage_percent"] = hostCPUPercent
+		metrics["memory_usage_percent"] = memPercent
+		metrics["disk_read_bytes"] = readBytes
+		metrics["disk_write_bytes"] = writeBytes
+		metrics["network_rx_bytes"] = rxBytes
+		metrics["network_tx_bytes"] = txBytes
+		metrics["containers_running"] = len(samples)
+	}
+	return metrics, nil
 }
 
-func (c *kubernetesClient) GetClusterMetrics(ctx context.Context) (*KubernetesClusterMetrics, error) {
-	// 实现Kubernetes API调用逻辑
-	return &KubernetesClusterMetrics{}, nil
-}
\ No newline at end of file
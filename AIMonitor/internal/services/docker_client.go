@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/pkg/docker"
+)
+
+// dockerClient 基于Docker Engine API的客户端实现
+type dockerClient struct {
+	client *docker.Client
+}
+
+// NewDockerClient 创建Docker客户端，连接在首次请求时建立
+func NewDockerClient(cfg config.DockerConfig) (DockerClient, error) {
+	client, err := docker.NewClient(docker.Config{
+		Endpoint:   cfg.Endpoint,
+		APIVersion: cfg.APIVersion,
+		Timeout:    cfg.Timeout,
+		TLSVerify:  cfg.TLSVerify,
+		CertPath:   cfg.CertPath,
+		KeyPath:    cfg.KeyPath,
+		CAPath:     cfg.CAPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dockerClient{client: client}, nil
+}
+
+func (c *dockerClient) ListContainers(ctx context.Context) ([]DockerContainer, error) {
+	containers, err := c.client.ContainerList(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DockerContainer, 0, len(containers))
+	for _, ct := range containers {
+		name := ""
+		if len(ct.Names) > 0 {
+			name = strings.TrimPrefix(ct.Names[0], "/")
+		}
+		ports := make([]ContainerPort, 0, len(ct.Ports))
+		for _, p := range ct.Ports {
+			ports = append(ports, ContainerPort{PrivatePort: p.PrivatePort, PublicPort: p.PublicPort, Type: p.Type, IP: p.IP})
+		}
+		result = append(result, DockerContainer{
+			ID:      ct.ID,
+			Name:    name,
+			Image:   ct.Image,
+			Status:  ct.Status,
+			State:   ct.State,
+			Created: time.Unix(ct.Created, 0),
+			Ports:   ports,
+			Labels:  ct.Labels,
+			Mounts:  toContainerMounts(ct.Mounts),
+		})
+	}
+	return result, nil
+}
+
+func (c *dockerClient) GetContainer(ctx context.Context, containerID string) (*DockerContainerDetail, error) {
+	ct, err := c.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &DockerContainerDetail{
+		DockerContainer: DockerContainer{
+			ID:      ct.ID,
+			Name:    strings.TrimPrefix(ct.Name, "/"),
+			Image:   ct.Image,
+			Created: ct.Created,
+			Mounts:  toContainerMounts(ct.Mounts),
+		},
+		Networks: make(map[string]Network),
+	}
+	if ct.State != nil {
+		detail.Status = ct.State.Status
+		detail.State = ct.State.Status
+		detail.Started = ct.State.StartedAt
+	}
+	if ct.Config != nil {
+		detail.Image = ct.Config.Image
+		detail.Labels = ct.Config.Labels
+		detail.Config = ContainerConfig{
+			Hostname:     ct.Config.Hostname,
+			Domainname:   ct.Config.Domainname,
+			User:         ct.Config.User,
+			AttachStdin:  ct.Config.AttachStdin,
+			AttachStdout: ct.Config.AttachStdout,
+			AttachStderr: ct.Config.AttachStderr,
+			Tty:          ct.Config.Tty,
+			OpenStdin:    ct.Config.OpenStdin,
+			StdinOnce:    ct.Config.StdinOnce,
+			Env:          ct.Config.Env,
+			Cmd:          ct.Config.Cmd,
+			Entrypoint:   ct.Config.Entrypoint,
+			Image:        ct.Config.Image,
+			Labels:       ct.Config.Labels,
+			WorkingDir:   ct.Config.WorkingDir,
+		}
+	}
+	if ct.HostConfig != nil {
+		detail.NetworkMode = ct.HostConfig.NetworkMode
+		detail.Resources = ContainerResources{
+			CPUShares:   ct.HostConfig.CPUShares,
+			Memory:      ct.HostConfig.Memory,
+			MemorySwap:  ct.HostConfig.MemorySwap,
+			CPUPeriod:   ct.HostConfig.CPUPeriod,
+			CPUQuota:    ct.HostConfig.CPUQuota,
+			CPUSetCPUs:  ct.HostConfig.CpusetCpus,
+			CPUSetMems:  ct.HostConfig.CpusetMems,
+			BlkioWeight: ct.HostConfig.BlkioWeight,
+		}
+	}
+	if ct.NetworkSettings != nil {
+		for name, n := range ct.NetworkSettings.Networks {
+			detail.Networks[name] = Network{
+				IPAddress:  n.IPAddress,
+				Gateway:    n.Gateway,
+				MacAddress: n.MacAddress,
+				NetworkID:  n.NetworkID,
+				EndpointID: n.EndpointID,
+			}
+		}
+	}
+	return detail, nil
+}
+
+func (c *dockerClient) GetContainerStats(ctx context.Context, containerID string) (*DockerContainerStats, error) {
+	stats, err := c.client.ContainerStats(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return toDockerContainerStats(stats), nil
+}
+
+func (c *dockerClient) GetContainerLogs(ctx context.Context, containerID string, options LogOptions) ([]string, error) {
+	return c.client.ContainerLogLines(ctx, containerID, docker.LogsOptions{
+		Stdout:     true,
+		Stderr:     true,
+		Since:      options.Since,
+		Until:      options.Until,
+		Tail:       options.Tail,
+		Timestamps: options.Timestamps,
+	})
+}
+
+func (c *dockerClient) RestartContainer(ctx context.Context, containerID string, timeout int) error {
+	return c.client.ContainerRestart(ctx, containerID, timeout)
+}
+
+// toDockerContainerStats 将Docker原始统计转换为接口返回结构
+func toDockerContainerStats(s *docker.Stats) *DockerContainerStats {
+	network := docker.NetworkTotals(s)
+	readBytes, writeBytes, readOps, writeOps := docker.BlockIOTotals(s)
+
+	// cgroup v1与v2的内存细分键名不同
+	memStat := func(keys ...string) uint64 {
+		for _, k := range keys {
+			if v, ok := s.MemoryStats.Stats[k]; ok {
+				return v
+			}
+		}
+		return 0
+	}
+
+	return &DockerContainerStats{
+		CPU: ContainerCPUStats{
+			UsagePercent:      docker.CPUPercent(s),
+			UsageInUsermode:   s.CPUStats.CPUUsage.UsageInUsermode,
+			UsageInKernelmode: s.CPUStats.CPUUsage.UsageInKernelmode,
+			SystemCPUUsage:    s.CPUStats.SystemUsage,
+			OnlineCPUs:        s.CPUStats.OnlineCPUs,
+			ThrottledPeriods:  s.CPUStats.ThrottlingData.ThrottledPeriods,
+			ThrottledTime:     s.CPUStats.ThrottlingData.ThrottledTime,
+		},
+		Memory: ContainerMemoryStats{
+			Usage:    docker.MemoryUsage(s),
+			MaxUsage: s.MemoryStats.MaxUsage,
+			Limit:    s.MemoryStats.Limit,
+			Percent:  docker.MemoryPercent(s),
+			Cache:    memStat("cache", "file"),
+			RSS:      memStat("rss", "anon"),
+			Swap:     memStat("swap"),
+		},
+		Network: ContainerNetworkStats{
+			RxBytes:   network.RxBytes,
+			RxPackets: network.RxPackets,
+			RxErrors:  network.RxErrors,
+			RxDropped: network.RxDropped,
+			TxBytes:   network.TxBytes,
+			TxPackets: network.TxPackets,
+			TxErrors:  network.TxErrors,
+			TxDropped: network.TxDropped,
+		},
+		BlockIO: ContainerBlockIOStats{
+			ReadBytes:  readBytes,
+			WriteBytes: writeBytes,
+			ReadOps:    readOps,
+			WriteOps:   writeOps,
+		},
+		PIDs: ContainerPIDsStats{
+			Current: s.PidsStats.Current,
+			Limit:   s.PidsStats.Limit,
+		},
+	}
+}
+
+func toContainerMounts(mounts []docker.MountPoint) []ContainerMount {
+	result := make([]ContainerMount, 0, len(mounts))
+	for _, m := range mounts {
+		result = append(result, ContainerMount{
+			Type:        m.Type,
+			Source:      m.Source,
+			Destination: m.Destination,
+			Mode:        m.Mode,
+			RW:          m.RW,
+		})
+	}
+	return result
+}
@@ -0,0 +1,660 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxPostmortemAnalyses 复盘引用的AI分析结果数量上限
+	maxPostmortemAnalyses = 10
+	// maxPostmortemMetrics 复盘引用的指标序列数量上限
+	maxPostmortemMetrics = 20
+	// postmortemMetricLead 复盘指标统计窗口在事件开始前的提前量
+	postmortemMetricLead = 30 * time.Minute
+)
+
+// CreateIncidentRequest 创建事件请求
+type CreateIncidentRequest struct {
+	Title        string      `json:"title" binding:"required"`
+	Description  string      `json:"description"`
+	Severity     string      `json:"severity" binding:"required,oneof=critical high medium low"`
+	CommanderID  *uuid.UUID  `json:"commander_id"`
+	Stakeholders []string    `json:"stakeholders"`
+	AlertIDs     []uuid.UUID `json:"alert_ids"`
+}
+
+// UpdateIncidentRequest 更新事件请求
+type UpdateIncidentRequest struct {
+	Title        string     `json:"title"`
+	Description  *string    `json:"description"`
+	Severity     string     `json:"severity" binding:"omitempty,oneof=critical high medium low"`
+	CommanderID  *uuid.UUID `json:"commander_id"`
+	Stakeholders []string   `json:"stakeholders"`
+}
+
+// UpdateIncidentStatusRequest 更新事件状态请求
+type UpdateIncidentStatusRequest struct {
+	Status  string `json:"status" binding:"required,oneof=investigating identified monitoring resolved"`
+	Message string `json:"message"`
+}
+
+// IncidentNoteRequest 事件备注请求
+type IncidentNoteRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// LinkIncidentAlertsRequest 关联告警请求
+type LinkIncidentAlertsRequest struct {
+	AlertIDs []uuid.UUID `json:"alert_ids" binding:"required,min=1"`
+}
+
+// incidentMetricSummary 事件期间指标统计
+type incidentMetricSummary struct {
+	TargetID string  `json:"target_id"`
+	Metric   string  `json:"metric"`
+	Min      float64 `json:"min"`
+	Avg      float64 `json:"avg"`
+	Max      float64 `json:"max"`
+	Samples  int64   `json:"samples"`
+}
+
+// CreateIncident 手动创建事件
+func (s *IncidentService) CreateIncident(req *CreateIncidentRequest, createdBy uuid.UUID) (*IncidentResponse, error) {
+	if err := s.validateCommander(req.CommanderID); err != nil {
+		return nil, err
+	}
+
+	stakeholdersJSON, _ := json.Marshal(req.Stakeholders)
+	now := time.Now()
+	incident := &models.Incident{
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       "investigating",
+		Severity:     req.Severity,
+		CommanderID:  req.CommanderID,
+		Stakeholders: string(stakeholdersJSON),
+		StartedAt:    now,
+		LastAlertAt:  now,
+		Hosts:        "[]",
+		Services:     "[]",
+		Nodes:        "[]",
+		CreatedBy:    &createdBy,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(incident).Error; err != nil {
+			return fmt.Errorf("failed to create incident: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "created", "手动创建事件", "", "", &createdBy, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.AlertIDs) > 0 {
+		if err := s.LinkAlerts(incident.ID, req.AlertIDs, createdBy); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.notifyStakeholders(incident, "", incident.Status, "事件已创建")
+
+	return s.GetIncident(incident.ID)
+}
+
+// UpdateIncident 更新事件基本信息
+func (s *IncidentService) UpdateIncident(incidentID uuid.UUID, req *UpdateIncidentRequest, userID uuid.UUID) (*IncidentResponse, error) {
+	incident, err := s.getIncidentModel(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCommander(req.CommanderID); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Title != "" {
+		updates["title"] = req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Severity != "" {
+		updates["severity"] = req.Severity
+	}
+	if req.Stakeholders != nil {
+		stakeholdersJSON, _ := json.Marshal(req.Stakeholders)
+		updates["stakeholders"] = string(stakeholdersJSON)
+	}
+	commanderChanged := req.CommanderID != nil && (incident.CommanderID == nil || *incident.CommanderID != *req.CommanderID)
+	if commanderChanged {
+		updates["commander_id"] = *req.CommanderID
+	}
+	if len(updates) == 0 {
+		return s.GetIncident(incidentID)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(incident).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update incident: %w", err)
+		}
+		if commanderChanged {
+			return s.recordIncidentEvent(tx, incident.ID, "commander_changed", "事件负责人变更", "", "", &userID, map[string]interface{}{
+				"commander_id": req.CommanderID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(incidentID)
+}
+
+// UpdateIncidentStatus 变更事件状态并通知相关人员
+func (s *IncidentService) UpdateIncidentStatus(incidentID uuid.UUID, req *UpdateIncidentStatusRequest, userID uuid.UUID) (*IncidentResponse, error) {
+	incident, err := s.getIncidentModel(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status == req.Status {
+		return nil, fmt.Errorf("incident is already %s", req.Status)
+	}
+	if incident.Status == "resolved" && req.Status != "investigating" {
+		return nil, errors.New("resolved incident can only be reopened as investigating")
+	}
+
+	if err := s.changeStatus(incident, req.Status, req.Message, &userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(incidentID)
+}
+
+// AddIncidentNote 添加事件备注
+func (s *IncidentService) AddIncidentNote(incidentID uuid.UUID, req *IncidentNoteRequest, userID uuid.UUID) (*IncidentResponse, error) {
+	if _, err := s.getIncidentModel(incidentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordIncidentEvent(s.db, incidentID, "note", req.Message, "", "", &userID, nil); err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(incidentID)
+}
+
+// LinkAlerts 手动将告警关联到事件，已属于其他事件的告警会被移入
+func (s *IncidentService) LinkAlerts(incidentID uuid.UUID, alertIDs []uuid.UUID, userID uuid.UUID) error {
+	incident, err := s.getIncidentModel(incidentID)
+	if err != nil {
+		return err
+	}
+
+	hosts := jsonStringSet(incident.Hosts)
+	services := jsonStringSet(incident.Services)
+	nodes := jsonStringSet(incident.Nodes)
+
+	for _, alertID := range alertIDs {
+		var alert models.Alert
+		if err := s.db.First(&alert, alertID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("alert %s not found", alertID)
+			}
+			return fmt.Errorf("failed to get alert: %w", err)
+		}
+
+		var existing models.IncidentAlert
+		err := s.db.Where("alert_id = ?", alertID).First(&existing).Error
+		if err == nil && existing.IncidentID == incidentID {
+			continue
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check alert link: %w", err)
+		}
+		moved := err == nil
+
+		features := s.extractAlertFeatures(&alert)
+		for k := range features.Hosts {
+			hosts[k] = true
+		}
+		for k := range features.Services {
+			services[k] = true
+		}
+		for k := range features.Nodes {
+			nodes[k] = true
+		}
+
+		updates := map[string]interface{}{
+			"alert_count": gorm.Expr("alert_count + ?", 1),
+			"hosts":       jsonStringList(hosts),
+			"services":    jsonStringList(services),
+			"nodes":       jsonStringList(nodes),
+		}
+		if alert.StartsAt.Before(incident.StartedAt) {
+			incident.StartedAt = alert.StartsAt
+			updates["started_at"] = alert.StartsAt
+		}
+		if alert.StartsAt.After(incident.LastAlertAt) {
+			incident.LastAlertAt = alert.StartsAt
+			updates["last_alert_at"] = alert.StartsAt
+		}
+		if alertSeverityRank[alert.Severity] > alertSeverityRank[incident.Severity] {
+			incident.Severity = alert.Severity
+			updates["severity"] = alert.Severity
+		}
+
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if moved {
+				if err := tx.Unscoped().Delete(&existing).Error; err != nil {
+					return fmt.Errorf("failed to unlink alert: %w", err)
+				}
+				if err := tx.Model(&models.Incident{}).Where("id = ?", existing.IncidentID).
+					Update("alert_count", gorm.Expr("alert_count - ?", 1)).Error; err != nil {
+					return fmt.Errorf("failed to update previous incident: %w", err)
+				}
+			}
+			link := &models.IncidentAlert{IncidentID: incidentID, AlertID: alertID, Reason: "manual", Score: 1}
+			if err := tx.Create(link).Error; err != nil {
+				return fmt.Errorf("failed to link alert to incident: %w", err)
+			}
+			if err := tx.Model(incident).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update incident: %w", err)
+			}
+			return s.recordIncidentEvent(tx, incidentID, "alert_linked", alert.Summary, "", "", &userID, map[string]interface{}{
+				"alert_id": alertID,
+				"reason":   "manual",
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GeneratePostmortem 根据时间线、关联分析结果和指标生成复盘草稿并保存到知识库
+func (s *IncidentService) GeneratePostmortem(incidentID uuid.UUID, userID *uuid.UUID) (*KnowledgeBaseResponse, error) {
+	incident, err := s.getIncidentModel(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := s.GetIncident(incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	analyses, err := s.incidentAnalyses(incident, detail)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := s.incidentMetricSummaries(incident)
+	if err != nil {
+		return nil, err
+	}
+
+	content := s.buildPostmortemTemplate(detail, analyses, metrics)
+	if s.aiService != nil && s.aiService.openaiClient != nil {
+		prompt := s.buildPostmortemContext(detail, analyses, metrics)
+		result, err := s.aiService.callOpenAI(prompt, "postmortem")
+		if err != nil {
+			return nil, fmt.Errorf("failed to call AI model: %w", err)
+		}
+		content = result
+	}
+
+	author := uuid.Nil
+	switch {
+	case userID != nil:
+		author = *userID
+	case incident.CommanderID != nil:
+		author = *incident.CommanderID
+	case incident.CreatedBy != nil:
+		author = *incident.CreatedBy
+	}
+
+	tagsJSON, _ := json.Marshal([]string{"postmortem", "incident"})
+	metricNames := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		metricNames = append(metricNames, m.Metric)
+	}
+	metricsJSON, _ := json.Marshal(metricNames)
+
+	// 仍为草稿的复盘直接覆盖，已发布的复盘保留并另建草稿
+	var kb models.KnowledgeBase
+	if incident.PostmortemID != nil {
+		if err := s.db.Where("id = ? AND status = ?", *incident.PostmortemID, "draft").First(&kb).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get postmortem: %w", err)
+		}
+	}
+	kb.Title = fmt.Sprintf("故障复盘：%s", incident.Title)
+	kb.Content = content
+	kb.Category = "postmortem"
+	kb.Tags = string(tagsJSON)
+	kb.Metrics = string(metricsJSON)
+	kb.Severity = incident.Severity
+	kb.Status = "draft"
+	kb.UpdatedBy = author
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if kb.ID == uuid.Nil {
+			kb.CreatedBy = author
+			if err := tx.Create(&kb).Error; err != nil {
+				return fmt.Errorf("failed to create postmortem: %w", err)
+			}
+		} else if err := tx.Save(&kb).Error; err != nil {
+			return fmt.Errorf("failed to update postmortem: %w", err)
+		}
+		if err := tx.Model(incident).Update("postmortem_id", kb.ID).Error; err != nil {
+			return fmt.Errorf("failed to update incident: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "postmortem", "复盘草稿已生成", "", "", userID, map[string]interface{}{
+			"knowledge_base_id": kb.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.aiService.toKnowledgeBaseResponse(&kb), nil
+}
+
+// changeStatus 变更事件状态、记录时间线并通知相关人员，解决时生成复盘草稿
+func (s *IncidentService) changeStatus(incident *models.Incident, status, message string, userID *uuid.UUID) error {
+	from := incident.Status
+	updates := map[string]interface{}{"status": status}
+	if status == "resolved" {
+		now := time.Now()
+		updates["resolved_at"] = &now
+		incident.ResolvedAt = &now
+	} else if from == "resolved" {
+		updates["resolved_at"] = nil
+		incident.ResolvedAt = nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(incident).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update incident status: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "status_change", message, from, status, userID, nil)
+	})
+	if err != nil {
+		return err
+	}
+	incident.Status = status
+
+	go s.notifyStakeholders(incident, from, status, message)
+
+	if status == "resolved" {
+		go func() {
+			if _, err := s.GeneratePostmortem(incident.ID, userID); err != nil {
+				// 复盘生成失败不影响状态变更，可手动重新生成
+				logger.GetLogger("incident").WithError(err).WithField("incident_id", incident.ID).Warn("failed to generate postmortem")
+			}
+		}()
+	}
+
+	return nil
+}
+
+// notifyStakeholders 向事件相关人员发送状态变更通知，未指定时使用关联告警规则的通知渠道
+func (s *IncidentService) notifyStakeholders(incident *models.Incident, from, to, message string) {
+	if s.notifyService == nil {
+		return
+	}
+
+	channels := jsonStrings(incident.Stakeholders)
+	if len(channels) == 0 {
+		var rules []models.AlertRule
+		if err := s.db.Where("id IN (?)", s.db.Model(&models.IncidentAlert{}).
+			Select("alerts.rule_id").
+			Joins("JOIN alerts ON alerts.id = incident_alerts.alert_id").
+			Where("incident_alerts.incident_id = ?", incident.ID)).
+			Find(&rules).Error; err != nil {
+			return
+		}
+		seen := map[string]bool{}
+		for _, rule := range rules {
+			var ruleChannels []string
+			if err := json.Unmarshal([]byte(rule.Annotations), &ruleChannels); err != nil {
+				continue
+			}
+			for _, channel := range ruleChannels {
+				if !seen[channel] {
+					seen[channel] = true
+					channels = append(channels, channel)
+				}
+			}
+		}
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	severity := incident.Severity
+	if to == "resolved" {
+		severity = "info"
+	}
+	content := fmt.Sprintf("事件状态：%s", to)
+	if from != "" {
+		content = fmt.Sprintf("事件状态：%s -> %s", from, to)
+	}
+	if message != "" {
+		content += "\n" + message
+	}
+
+	notification := &NotificationRequest{
+		Title:    fmt.Sprintf("[INCIDENT %s] %s", strings.ToUpper(to), incident.Title),
+		Content:  content,
+		Severity: severity,
+		Tags: map[string]interface{}{
+			"incident_id":  incident.ID,
+			"from_status":  from,
+			"to_status":    to,
+			"commander_id": incident.CommanderID,
+		},
+		Channels: channels,
+	}
+
+	if err := s.notifyService.SendNotification(notification); err != nil {
+		logger.GetLogger("incident").WithError(err).WithField("incident_id", incident.ID).Warn("failed to send incident notification")
+	}
+}
+
+// recordIncidentEvent 记录事件时间线条目
+func (s *IncidentService) recordIncidentEvent(tx *gorm.DB, incidentID uuid.UUID, eventType, message, from, to string, userID *uuid.UUID, details map[string]interface{}) error {
+	event := &models.IncidentEvent{
+		IncidentID: incidentID,
+		Type:       eventType,
+		Message:    message,
+		FromStatus: from,
+		ToStatus:   to,
+		UserID:     userID,
+	}
+	if details != nil {
+		detailsJSON, _ := json.Marshal(details)
+		event.Details = string(detailsJSON)
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record incident event: %w", err)
+	}
+	return nil
+}
+
+// getIncidentModel 获取事件记录
+func (s *IncidentService) getIncidentModel(incidentID uuid.UUID) (*models.Incident, error) {
+	var incident models.Incident
+	if err := s.db.First(&incident, incidentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("incident not found")
+		}
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	return &incident, nil
+}
+
+// validateCommander 校验事件负责人存在
+func (s *IncidentService) validateCommander(commanderID *uuid.UUID) error {
+	if commanderID == nil {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", *commanderID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check commander: %w", err)
+	}
+	if count == 0 {
+		return errors.New("commander not found")
+	}
+	return nil
+}
+
+// incidentAnalyses 获取事件及其关联告警的AI分析结果
+func (s *IncidentService) incidentAnalyses(incident *models.Incident, detail *IncidentResponse) ([]models.AIAnalysisResult, error) {
+	alertIDs := make([]uuid.UUID, len(detail.Alerts))
+	for i, alert := range detail.Alerts {
+		alertIDs[i] = alert.AlertID
+	}
+
+	query := s.db.Where("status = ?", "completed")
+	if incident.AnalysisID != nil {
+		query = query.Where(s.db.Where("alert_id IN ?", alertIDs).Or("id = ?", *incident.AnalysisID))
+	} else if len(alertIDs) > 0 {
+		query = query.Where("alert_id IN ?", alertIDs)
+	} else {
+		return nil, nil
+	}
+
+	var analyses []models.AIAnalysisResult
+	if err := query.Order("created_at ASC").Limit(maxPostmortemAnalyses).Find(&analyses).Error; err != nil {
+		return nil, fmt.Errorf("failed to query analyses: %w", err)
+	}
+	return analyses, nil
+}
+
+// incidentMetricSummaries 统计关联告警指标在事件期间的变化
+func (s *IncidentService) incidentMetricSummaries(incident *models.Incident) ([]*incidentMetricSummary, error) {
+	var links []models.IncidentAlert
+	if err := s.db.Preload("Alert.Rule").Where("incident_id = ?", incident.ID).Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to query incident alerts: %w", err)
+	}
+
+	start := incident.StartedAt.Add(-postmortemMetricLead)
+	end := time.Now()
+	if incident.ResolvedAt != nil {
+		end = *incident.ResolvedAt
+	}
+
+	summaries := make([]*incidentMetricSummary, 0)
+	seen := map[string]bool{}
+	for _, link := range links {
+		targetID := s.extractAlertTarget(&link.Alert)
+		metric := link.Alert.Rule.Metric
+		key := targetID + "|" + metric
+		if targetID == "" || metric == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if len(summaries) >= maxPostmortemMetrics {
+			break
+		}
+
+		summary := &incidentMetricSummary{TargetID: targetID, Metric: metric}
+		if err := s.db.Model(&models.MetricData{}).
+			Select("COALESCE(MIN(value), 0) AS min, COALESCE(AVG(value), 0) AS avg, COALESCE(MAX(value), 0) AS max, COUNT(*) AS samples").
+			Where("target_id = ? AND metric = ? AND timestamp BETWEEN ? AND ?", targetID, metric, start, end).
+			Scan(summary).Error; err != nil {
+			return nil, fmt.Errorf("failed to summarize metric: %w", err)
+		}
+		if summary.Samples > 0 {
+			summaries = append(summaries, summary)
+		}
+	}
+
+	return summaries, nil
+}
+
+// buildPostmortemContext 构建复盘生成提示词
+func (s *IncidentService) buildPostmortemContext(detail *IncidentResponse, analyses []models.AIAnalysisResult, metrics []*incidentMetricSummary) string {
+	var b strings.Builder
+	b.WriteString("请根据以下故障事件资料撰写一份无责复盘报告（Markdown格式），包含：摘要、影响范围、时间线、根本原因、处理过程、做得好的地方、需要改进的地方、后续行动项（含负责人占位）。\n")
+	b.WriteString("只依据提供的资料，不要编造未出现的事实；资料不足的地方请标注\"待补充\"。\n\n")
+	b.WriteString(s.buildPostmortemTemplate(detail, analyses, metrics))
+	return b.String()
+}
+
+// buildPostmortemTemplate 汇总复盘资料，未配置AI模型时直接作为草稿内容
+func (s *IncidentService) buildPostmortemTemplate(detail *IncidentResponse, analyses []models.AIAnalysisResult, metrics []*incidentMetricSummary) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# 故障复盘：%s\n\n", detail.Title))
+
+	b.WriteString("## 摘要\n\n")
+	b.WriteString(fmt.Sprintf("- 严重级别：%s\n", detail.Severity))
+	b.WriteString(fmt.Sprintf("- 开始时间：%s\n", detail.StartedAt.Format(time.RFC3339)))
+	if detail.ResolvedAt != nil {
+		b.WriteString(fmt.Sprintf("- 解决时间：%s（持续 %s）\n", detail.ResolvedAt.Format(time.RFC3339), detail.ResolvedAt.Sub(detail.StartedAt).Round(time.Minute)))
+	}
+	b.WriteString(fmt.Sprintf("- 关联告警：%d 条\n", detail.AlertCount))
+	if len(detail.Hosts) > 0 {
+		b.WriteString(fmt.Sprintf("- 涉及主机：%s\n", strings.Join(detail.Hosts, ", ")))
+	}
+	if len(detail.Services) > 0 {
+		b.WriteString(fmt.Sprintf("- 涉及服务：%s\n", strings.Join(detail.Services, ", ")))
+	}
+	if detail.Description != "" {
+		b.WriteString(fmt.Sprintf("\n%s\n", detail.Description))
+	}
+
+	b.WriteString("\n## 根本原因\n\n")
+	if detail.RootCause != "" {
+		b.WriteString(detail.RootCause + "\n")
+	} else {
+		b.WriteString("待补充\n")
+	}
+
+	if len(analyses) > 0 {
+		b.WriteString("\n## AI分析结论\n\n")
+		for _, analysis := range analyses {
+			parsed, err := s.aiService.parseAIResponse(analysis.Response)
+			if err != nil {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("- [%s，置信度 %.2f] %s\n", analysis.AnalysisType, analysis.Confidence, parsed.RootCause))
+			for _, rec := range parsed.Recommendations {
+				b.WriteString(fmt.Sprintf("  - 建议：%s\n", rec))
+			}
+		}
+	}
+
+	if len(metrics) > 0 {
+		b.WriteString("\n## 关键指标\n\n")
+		b.WriteString("| 目标 | 指标 | 最小值 | 平均值 | 最大值 | 样本数 |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, m := range metrics {
+			b.WriteString(fmt.Sprintf("| %s | %s | %.2f | %.2f | %.2f | %d |\n", m.TargetID, m.Metric, m.Min, m.Avg, m.Max, m.Samples))
+		}
+	}
+
+	b.WriteString("\n## 时间线\n\n")
+	timeline := detail.Timeline
+	if len(timeline) > maxTimelineEntries {
+		timeline = timeline[:maxTimelineEntries]
+	}
+	for _, entry := range timeline {
+		b.WriteString(fmt.Sprintf("- %s [%s] %s\n", entry.Time.Format(time.RFC3339), entry.Kind, entry.Summary))
+	}
+
+	b.WriteString("\n## 后续行动项\n\n- 待补充\n")
+	return b.String()
+}
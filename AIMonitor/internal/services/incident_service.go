@@ -0,0 +1,978 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxCorrelationBatch 每轮关联处理的告警数量上限
+	maxCorrelationBatch = 500
+	// maxIncidentMembers 参与相似度比较的事件成员数量上限
+	maxIncidentMembers = 50
+	// maxTimelineEntries 发送给模型的时间线条目上限
+	maxTimelineEntries = 100
+	// changeLookback 故障开始前纳入时间线的变更窗口
+	changeLookback = time.Hour
+)
+
+// alertSeverityRank 告警严重级别排序
+var alertSeverityRank = map[string]int{
+	"critical": 4,
+	"high":     3,
+	"warning":  2,
+	"medium":   2,
+	"low":      1,
+	"info":     1,
+}
+
+// changeAuditResources 视为配置变更的审计资源类型
+var changeAuditResources = []string{"config", "alert_rule", "monitoring_target", "agent", "middleware", "container", "deployment"}
+
+// IncidentService 故障事件服务，将同时段相关告警归并为事件并做统一根因分析
+type IncidentService struct {
+	db            *gorm.DB
+	cacheManager  *cache.CacheManager
+	config        *config.Config
+	aiService     *AIService
+	apmService    *APMService
+	notifyService *NotificationService
+}
+
+// NewIncidentService 创建故障事件服务
+func NewIncidentService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, aiService *AIService, apmService *APMService, notifyService *NotificationService) *IncidentService {
+	return &IncidentService{
+		db:            db,
+		cacheManager:  cacheManager,
+		config:        config,
+		aiService:     aiService,
+		apmService:    apmService,
+		notifyService: notifyService,
+	}
+}
+
+// IncidentAlertResponse 事件成员告警
+type IncidentAlertResponse struct {
+	AlertID  uuid.UUID `json:"alert_id"`
+	Summary  string    `json:"summary"`
+	Severity string    `json:"severity"`
+	Status   string    `json:"status"`
+	StartsAt time.Time `json:"starts_at"`
+	Reason   string    `json:"reason"`
+	Score    float64   `json:"score"`
+}
+
+// IncidentTimelineEntry 事件时间线条目
+type IncidentTimelineEntry struct {
+	Time    time.Time  `json:"time"`
+	Kind    string     `json:"kind"` // alert, alert_event, anomaly, deployment, config_change 以及事件时间线类型
+	Source  string     `json:"source"`
+	Summary string     `json:"summary"`
+	UserID  *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// IncidentResponse 故障事件响应
+type IncidentResponse struct {
+	ID           uuid.UUID                `json:"id"`
+	Title        string                   `json:"title"`
+	Description  string                   `json:"description"`
+	Status       string                   `json:"status"`
+	Severity     string                   `json:"severity"`
+	CommanderID  *uuid.UUID               `json:"commander_id"`
+	Stakeholders []string                 `json:"stakeholders"`
+	StartedAt    time.Time                `json:"started_at"`
+	LastAlertAt  time.Time                `json:"last_alert_at"`
+	ResolvedAt   *time.Time               `json:"resolved_at"`
+	AlertCount   int                      `json:"alert_count"`
+	Hosts        []string                 `json:"hosts"`
+	Services     []string                 `json:"services"`
+	Nodes        []string                 `json:"nodes"`
+	RootCause    string                   `json:"root_cause"`
+	AnalysisID   *uuid.UUID               `json:"analysis_id"`
+	PostmortemID *uuid.UUID               `json:"postmortem_id"`
+	CreatedBy    *uuid.UUID               `json:"created_by"`
+	Alerts       []*IncidentAlertResponse `json:"alerts,omitempty"`
+	Timeline     []*IncidentTimelineEntry `json:"timeline,omitempty"`
+	CreatedAt    time.Time                `json:"created_at"`
+	UpdatedAt    time.Time                `json:"updated_at"`
+}
+
+// alertFeatures 用于关联判断的告警特征
+type alertFeatures struct {
+	AlertID  uuid.UUID
+	TargetID string
+	Hosts    map[string]bool
+	Services map[string]bool
+	Nodes    map[string]bool
+	Labels   map[string]bool
+	Tokens   map[string]bool
+}
+
+// incidentCluster 关联过程中的事件及其成员特征
+type incidentCluster struct {
+	incident *models.Incident
+	members  []*alertFeatures
+	hosts    map[string]bool
+	services map[string]bool
+	nodes    map[string]bool
+}
+
+// RunIncidentCorrelation 周期性关联告警并分析故障事件，直到ctx取消
+func (s *IncidentService) RunIncidentCorrelation(ctx context.Context, cfg config.CorrelationConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CorrelateAlerts(); err != nil {
+				logger.GetLogger("incident").WithError(err).Error("failed to correlate alerts")
+			}
+		}
+	}
+}
+
+// CorrelateAlerts 将未归属的活跃告警归并到事件，解决已恢复事件，并分析已稳定的事件
+func (s *IncidentService) CorrelateAlerts() error {
+	cfg := s.config.Alerting.Correlation
+	window := cfg.Window
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	threshold := cfg.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+	settle := cfg.SettleDelay
+	if settle <= 0 {
+		settle = 2 * time.Minute
+	}
+
+	var alerts []models.Alert
+	if err := s.db.Where("status = ? AND id NOT IN (?)", "firing", s.db.Model(&models.IncidentAlert{}).Select("alert_id")).
+		Order("starts_at ASC").
+		Limit(maxCorrelationBatch).
+		Find(&alerts).Error; err != nil {
+		return fmt.Errorf("failed to query uncorrelated alerts: %w", err)
+	}
+
+	if len(alerts) > 0 {
+		clusters, err := s.loadOpenClusters(alerts[0].StartsAt.Add(-window))
+		if err != nil {
+			return err
+		}
+		adjacency := s.serviceAdjacency()
+
+		for i := range alerts {
+			alert := &alerts[i]
+			features := s.extractAlertFeatures(alert)
+
+			var best *incidentCluster
+			bestScore, bestReason := 0.0, ""
+			for _, cluster := range clusters {
+				if cluster.incident.LastAlertAt.Before(alert.StartsAt.Add(-window)) {
+					continue
+				}
+				score, reason := scoreAgainstCluster(features, cluster, adjacency)
+				if score > bestScore {
+					best, bestScore, bestReason = cluster, score, reason
+				}
+			}
+
+			if best != nil && bestScore >= threshold {
+				err = s.attachAlert(best, alert, features, bestReason, bestScore)
+			} else {
+				var cluster *incidentCluster
+				cluster, err = s.openIncident(alert, features)
+				if cluster != nil {
+					clusters = append(clusters, cluster)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.markRecoveredIncidents(); err != nil {
+		return err
+	}
+
+	// 事件在静默期内无新告警后再分析，告警数翻倍时重新分析
+	if s.aiService != nil && s.aiService.openaiClient != nil {
+		var pending []models.Incident
+		if err := s.db.Where("status <> ? AND alert_count >= ? AND last_alert_at <= ? AND (analysis_id IS NULL OR alert_count >= analyzed_alert_count * 2)",
+			"resolved", 2, time.Now().Add(-settle)).
+			Find(&pending).Error; err != nil {
+			return fmt.Errorf("failed to query incidents pending analysis: %w", err)
+		}
+		for _, incident := range pending {
+			if _, err := s.AnalyzeIncident(incident.ID); err != nil {
+				logger.GetLogger("incident").WithError(err).WithField("incident_id", incident.ID).Warn("failed to analyze incident")
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadOpenClusters 加载窗口内未解决的事件及其成员特征
+func (s *IncidentService) loadOpenClusters(since time.Time) ([]*incidentCluster, error) {
+	var incidents []models.Incident
+	if err := s.db.Where("status <> ? AND last_alert_at >= ?", "resolved", since).Find(&incidents).Error; err != nil {
+		return nil, fmt.Errorf("failed to query open incidents: %w", err)
+	}
+
+	clusters := make([]*incidentCluster, 0, len(incidents))
+	for i := range incidents {
+		incident := &incidents[i]
+		var links []models.IncidentAlert
+		if err := s.db.Preload("Alert").
+			Where("incident_id = ?", incident.ID).
+			Order("created_at DESC").
+			Limit(maxIncidentMembers).
+			Find(&links).Error; err != nil {
+			return nil, fmt.Errorf("failed to query incident alerts: %w", err)
+		}
+
+		cluster := &incidentCluster{
+			incident: incident,
+			hosts:    jsonStringSet(incident.Hosts),
+			services: jsonStringSet(incident.Services),
+			nodes:    jsonStringSet(incident.Nodes),
+		}
+		for j := range links {
+			cluster.members = append(cluster.members, s.extractAlertFeatures(&links[j].Alert))
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// openIncident 以告警为种子创建新事件
+func (s *IncidentService) openIncident(alert *models.Alert, features *alertFeatures) (*incidentCluster, error) {
+	incident := &models.Incident{
+		Title:       alert.Summary,
+		Status:      "investigating",
+		Severity:    alert.Severity,
+		StartedAt:   alert.StartsAt,
+		LastAlertAt: alert.StartsAt,
+		AlertCount:  1,
+		Hosts:       jsonStringList(features.Hosts),
+		Services:    jsonStringList(features.Services),
+		Nodes:       jsonStringList(features.Nodes),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(incident).Error; err != nil {
+			return fmt.Errorf("failed to create incident: %w", err)
+		}
+		link := &models.IncidentAlert{IncidentID: incident.ID, AlertID: alert.ID, Reason: "seed", Score: 1}
+		if err := tx.Create(link).Error; err != nil {
+			return fmt.Errorf("failed to link alert to incident: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "created", fmt.Sprintf("由告警自动创建：%s", alert.Summary), "", "", nil, map[string]interface{}{
+			"alert_id": alert.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &incidentCluster{
+		incident: incident,
+		members:  []*alertFeatures{features},
+		hosts:    copyStringSet(features.Hosts),
+		services: copyStringSet(features.Services),
+		nodes:    copyStringSet(features.Nodes),
+	}, nil
+}
+
+// attachAlert 将告警归入已有事件并更新事件汇总
+func (s *IncidentService) attachAlert(cluster *incidentCluster, alert *models.Alert, features *alertFeatures, reason string, score float64) error {
+	incident := cluster.incident
+	for k := range features.Hosts {
+		cluster.hosts[k] = true
+	}
+	for k := range features.Services {
+		cluster.services[k] = true
+	}
+	for k := range features.Nodes {
+		cluster.nodes[k] = true
+	}
+
+	updates := map[string]interface{}{
+		"alert_count": gorm.Expr("alert_count + ?", 1),
+		"hosts":       jsonStringList(cluster.hosts),
+		"services":    jsonStringList(cluster.services),
+		"nodes":       jsonStringList(cluster.nodes),
+	}
+	if alert.StartsAt.After(incident.LastAlertAt) {
+		incident.LastAlertAt = alert.StartsAt
+		updates["last_alert_at"] = alert.StartsAt
+	}
+	if alertSeverityRank[alert.Severity] > alertSeverityRank[incident.Severity] {
+		incident.Severity = alert.Severity
+		updates["severity"] = alert.Severity
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		link := &models.IncidentAlert{IncidentID: incident.ID, AlertID: alert.ID, Reason: reason, Score: score}
+		if err := tx.Create(link).Error; err != nil {
+			return fmt.Errorf("failed to link alert to incident: %w", err)
+		}
+		if err := tx.Model(incident).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update incident: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "alert_linked", alert.Summary, "", "", nil, map[string]interface{}{
+			"alert_id": alert.ID,
+			"reason":   reason,
+			"score":    score,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	incident.AlertCount++
+	cluster.members = append(cluster.members, features)
+	return nil
+}
+
+// markRecoveredIncidents 成员告警全部恢复后将事件转入观察状态，由负责人确认解决
+func (s *IncidentService) markRecoveredIncidents() error {
+	firing := s.db.Model(&models.IncidentAlert{}).
+		Select("incident_alerts.incident_id").
+		Joins("JOIN alerts ON alerts.id = incident_alerts.alert_id").
+		Where("alerts.status = ?", "firing")
+
+	var incidents []models.Incident
+	if err := s.db.Where("status IN ? AND alert_count > ? AND id NOT IN (?)", []string{"investigating", "identified"}, 0, firing).
+		Find(&incidents).Error; err != nil {
+		return fmt.Errorf("failed to query recovered incidents: %w", err)
+	}
+
+	for i := range incidents {
+		if err := s.changeStatus(&incidents[i], "monitoring", "所有关联告警已恢复", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractAlertFeatures 从告警标签、监控目标和容器信息中提取拓扑与文本特征
+func (s *IncidentService) extractAlertFeatures(alert *models.Alert) *alertFeatures {
+	features := &alertFeatures{
+		AlertID:  alert.ID,
+		Hosts:    map[string]bool{},
+		Services: map[string]bool{},
+		Nodes:    map[string]bool{},
+		Labels:   map[string]bool{},
+		Tokens:   tokenizeSummary(alert.Summary),
+	}
+
+	var labels map[string]interface{}
+	if alert.Labels != "" {
+		json.Unmarshal([]byte(alert.Labels), &labels)
+	}
+	for key, raw := range labels {
+		if raw == nil {
+			continue
+		}
+		value := strings.TrimSpace(fmt.Sprint(raw))
+		if value == "" {
+			continue
+		}
+		switch key {
+		case "host", "hostname", "instance":
+			features.Hosts[value] = true
+		case "service", "service_name", "app":
+			features.Services[value] = true
+		case "node", "node_name", "kubernetes_node":
+			features.Nodes[value] = true
+		case "target_id":
+			features.TargetID = value
+		}
+		// 指标名和规则类型对所有同类告警都相同，不作为关联依据
+		if key != "metric" && key != "kind" {
+			features.Labels[key+"="+value] = true
+		}
+	}
+
+	if features.TargetID == "" {
+		features.TargetID = fingerprintTargetID(alert.Fingerprint)
+	}
+	if features.TargetID != "" {
+		var target models.MonitoringTarget
+		if err := s.db.Select("id", "name", "type", "address").Where("id = ?", features.TargetID).First(&target).Error; err == nil {
+			features.Hosts[target.Address] = true
+			if target.Type == "service" {
+				features.Services[target.Name] = true
+			}
+		}
+	}
+
+	// 容器告警补充所在K8s节点
+	for _, key := range []string{"container_id", "pod", "pod_name"} {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		var container models.ContainerMonitor
+		column := "container_id"
+		if key != "container_id" {
+			column = "pod_name"
+		}
+		if err := s.db.Select("node_name").Where(column+" = ?", fmt.Sprint(value)).First(&container).Error; err == nil && container.NodeName != "" {
+			features.Nodes[container.NodeName] = true
+		}
+	}
+
+	return features
+}
+
+// serviceAdjacency 从APM服务拓扑构建服务邻接关系
+func (s *IncidentService) serviceAdjacency() map[string]map[string]bool {
+	adjacency := map[string]map[string]bool{}
+	if s.apmService == nil {
+		return adjacency
+	}
+	serviceMap, err := s.apmService.GetServiceMap()
+	if err != nil || serviceMap == nil {
+		return adjacency
+	}
+
+	names := map[string]string{}
+	for _, node := range serviceMap.Services {
+		names[node.ID] = node.Name
+	}
+	resolve := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return id
+	}
+	link := func(a, b string) {
+		if adjacency[a] == nil {
+			adjacency[a] = map[string]bool{}
+		}
+		adjacency[a][b] = true
+	}
+	for _, edge := range serviceMap.Edges {
+		source, target := resolve(edge.Source), resolve(edge.Target)
+		link(source, target)
+		link(target, source)
+	}
+	return adjacency
+}
+
+// scoreAgainstCluster 计算告警与事件的关联度：拓扑 > 共享标签 > 摘要文本相似度
+func scoreAgainstCluster(features *alertFeatures, cluster *incidentCluster, adjacency map[string]map[string]bool) (float64, string) {
+	if intersects(features.Hosts, cluster.hosts) {
+		return 1, "host"
+	}
+	if intersects(features.Nodes, cluster.nodes) {
+		return 0.9, "node"
+	}
+	if intersects(features.Services, cluster.services) {
+		return 0.9, "service"
+	}
+	for service := range features.Services {
+		if intersects(adjacency[service], cluster.services) {
+			return 0.7, "service"
+		}
+	}
+
+	best, reason := 0.0, ""
+	for _, member := range cluster.members {
+		if score := 0.8 * jaccard(features.Labels, member.Labels); score > best {
+			best, reason = score, "labels"
+		}
+		if score := 0.7 * jaccard(features.Tokens, member.Tokens); score > best {
+			best, reason = score, "text"
+		}
+	}
+	return best, reason
+}
+
+// AnalyzeIncident 将事件时间线发送给模型做一次整体根因分析
+func (s *IncidentService) AnalyzeIncident(incidentID uuid.UUID) (*AIAnalysisResponse, error) {
+	if s.aiService == nil || s.aiService.openaiClient == nil {
+		return nil, errors.New("AI service not configured")
+	}
+
+	incident, err := s.GetIncident(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(incident.Alerts) == 0 {
+		return nil, errors.New("incident has no alerts")
+	}
+
+	prompt := s.buildIncidentAnalysisContext(incident)
+	structured, err := s.aiService.callStructured("", prompt, "incident_analysis")
+	if err != nil {
+		if errors.Is(err, ErrInvalidStructuredOutput) {
+			s.aiService.recordFailedAnalysis(&models.AIAnalysisResult{
+				AlertID:      incident.Alerts[0].AlertID,
+				AnalysisType: "root_cause",
+				Model:        s.config.AIModels.OpenAI.Model,
+				Prompt:       prompt,
+			}, structured, err)
+		}
+		return nil, fmt.Errorf("failed to call AI model: %w", err)
+	}
+	parsed := structured.Parsed
+
+	alertIDs := make([]uuid.UUID, len(incident.Alerts))
+	for i, alert := range incident.Alerts {
+		alertIDs[i] = alert.AlertID
+	}
+	metadata := map[string]interface{}{
+		"incident_id": incident.ID,
+		"alert_ids":   alertIDs,
+		"alert_count": incident.AlertCount,
+		"hosts":       incident.Hosts,
+		"services":    incident.Services,
+		"nodes":       incident.Nodes,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	analysis := models.AIAnalysisResult{
+		AlertID:      incident.Alerts[0].AlertID,
+		AnalysisType: "root_cause",
+		Model:        s.config.AIModels.OpenAI.Model,
+		Prompt:       prompt,
+		Response:     structured.Validated,
+		Confidence:   parsed.ConfidenceScore,
+		Status:       "completed",
+		Metadata:     string(metadataJSON),
+	}
+	applyStructuredResult(&analysis, structured)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&analysis).Error; err != nil {
+			return fmt.Errorf("failed to save analysis result: %w", err)
+		}
+		if err := tx.Model(&models.Incident{}).Where("id = ?", incident.ID).Updates(map[string]interface{}{
+			"root_cause":           parsed.RootCause,
+			"analysis_id":          analysis.ID,
+			"analyzed_alert_count": incident.AlertCount,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update incident: %w", err)
+		}
+		return s.recordIncidentEvent(tx, incident.ID, "analysis", parsed.RootCause, "", "", nil, map[string]interface{}{
+			"analysis_id": analysis.ID,
+			"confidence":  parsed.ConfidenceScore,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIAnalysisResponse{
+		ID:              analysis.ID,
+		Type:            "incident_analysis",
+		TargetType:      "incident",
+		TargetID:        incident.ID.String(),
+		AnalysisResult:  analysis.Response,
+		RootCause:       parsed.RootCause,
+		Recommendations: parsed.Recommendations,
+		SeverityLevel:   parsed.SeverityLevel,
+		ConfidenceScore: analysis.Confidence,
+		Metadata:        metadata,
+		CreatedAt:       analysis.CreatedAt,
+	}, nil
+}
+
+// GetIncident 获取事件详情，包括成员告警和关联时间线
+func (s *IncidentService) GetIncident(incidentID uuid.UUID) (*IncidentResponse, error) {
+	var incident models.Incident
+	if err := s.db.First(&incident, incidentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("incident not found")
+		}
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+
+	var links []models.IncidentAlert
+	if err := s.db.Preload("Alert").Where("incident_id = ?", incidentID).Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to query incident alerts: %w", err)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Alert.StartsAt.Before(links[j].Alert.StartsAt)
+	})
+
+	response := s.toIncidentResponse(&incident)
+	response.Alerts = make([]*IncidentAlertResponse, len(links))
+	for i, link := range links {
+		response.Alerts[i] = &IncidentAlertResponse{
+			AlertID:  link.AlertID,
+			Summary:  link.Alert.Summary,
+			Severity: link.Alert.Severity,
+			Status:   link.Alert.Status,
+			StartsAt: link.Alert.StartsAt,
+			Reason:   link.Reason,
+			Score:    link.Score,
+		}
+	}
+
+	timeline, err := s.buildTimeline(&incident, links)
+	if err != nil {
+		return nil, err
+	}
+	response.Timeline = timeline
+
+	return response, nil
+}
+
+// ListIncidents 获取事件列表
+func (s *IncidentService) ListIncidents(page, pageSize int, status, severity string) ([]*IncidentResponse, int64, error) {
+	query := s.db.Model(&models.Incident{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	var incidents []models.Incident
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("started_at DESC").Find(&incidents).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list incidents: %w", err)
+	}
+
+	responses := make([]*IncidentResponse, len(incidents))
+	for i := range incidents {
+		responses[i] = s.toIncidentResponse(&incidents[i])
+	}
+	return responses, total, nil
+}
+
+// buildTimeline 合并告警、状态事件、指标异常、部署与配置变更为时间线
+func (s *IncidentService) buildTimeline(incident *models.Incident, links []models.IncidentAlert) ([]*IncidentTimelineEntry, error) {
+	end := incident.LastAlertAt
+	if incident.ResolvedAt != nil {
+		end = *incident.ResolvedAt
+	}
+
+	timeline := make([]*IncidentTimelineEntry, 0)
+	alertIDs := make([]uuid.UUID, 0, len(links))
+	targetIDs := make([]string, 0)
+	for _, link := range links {
+		alertIDs = append(alertIDs, link.AlertID)
+		timeline = append(timeline, &IncidentTimelineEntry{
+			Time:    link.Alert.StartsAt,
+			Kind:    "alert",
+			Source:  link.Alert.Severity,
+			Summary: link.Alert.Summary,
+		})
+		if targetID := s.extractAlertTarget(&link.Alert); targetID != "" {
+			targetIDs = append(targetIDs, targetID)
+		}
+	}
+
+	// 告警状态变化
+	if len(alertIDs) > 0 {
+		var events []models.AlertEvent
+		if err := s.db.Where("alert_id IN ? AND type <> ?", alertIDs, "firing").Find(&events).Error; err != nil {
+			return nil, fmt.Errorf("failed to query alert events: %w", err)
+		}
+		for _, event := range events {
+			timeline = append(timeline, &IncidentTimelineEntry{
+				Time:    event.CreatedAt,
+				Kind:    "alert_event",
+				Source:  event.Type,
+				Summary: event.Message,
+			})
+		}
+	}
+
+	// 同一目标上的指标异常和错误率上升洞察
+	if len(targetIDs) > 0 {
+		var insights []models.AIInsight
+		if err := s.db.Where("type IN ? AND target_id IN ? AND generated_at BETWEEN ? AND ?",
+			[]string{"anomaly", "error_rate"}, targetIDs, incident.StartedAt.Add(-changeLookback), end).
+			Order("generated_at DESC").
+			Find(&insights).Error; err != nil {
+			return nil, fmt.Errorf("failed to query anomalies: %w", err)
+		}
+		seen := map[string]bool{}
+		for _, insight := range insights {
+			key := insight.Type + insight.TargetID + insight.Title
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			timeline = append(timeline, &IncidentTimelineEntry{
+				Time:    insight.GeneratedAt,
+				Kind:    "anomaly",
+				Source:  insight.TargetID,
+				Summary: insight.Description,
+			})
+		}
+	}
+
+	// 故障开始前后的部署与配置变更
+	var audits []models.AuditLog
+	if err := s.db.Where("status = ? AND created_at BETWEEN ? AND ?", "success", incident.StartedAt.Add(-changeLookback), end).
+		Where(s.db.Where("action LIKE ?", "%deploy%").
+			Or("resource IN ? AND (action LIKE ? OR action LIKE ? OR action LIKE ? OR action LIKE ? OR action LIKE ?)",
+				changeAuditResources, "create%", "update%", "delete%", "enable%", "disable%")).
+		Order("created_at ASC").
+		Limit(maxTimelineEntries).
+		Find(&audits).Error; err != nil {
+		return nil, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	for _, audit := range audits {
+		kind := "config_change"
+		if strings.Contains(audit.Action, "deploy") {
+			kind = "deployment"
+		}
+		timeline = append(timeline, &IncidentTimelineEntry{
+			Time:    audit.CreatedAt,
+			Kind:    kind,
+			Source:  audit.Resource,
+			Summary: fmt.Sprintf("%s %s %s", audit.Action, audit.Resource, audit.ResourceID),
+		})
+	}
+
+	var deployments []models.AgentDeployment
+	if err := s.db.Where("updated_at BETWEEN ? AND ?", incident.StartedAt.Add(-changeLookback), end).
+		Find(&deployments).Error; err != nil {
+		return nil, fmt.Errorf("failed to query deployments: %w", err)
+	}
+	for _, deployment := range deployments {
+		timeline = append(timeline, &IncidentTimelineEntry{
+			Time:    deployment.UpdatedAt,
+			Kind:    "deployment",
+			Source:  deployment.AgentType,
+			Summary: fmt.Sprintf("%s %s (%s)", deployment.Name, deployment.Version, deployment.Status),
+		})
+	}
+
+	// 事件自身的备注、状态变更和自动事件
+	var incidentEvents []models.IncidentEvent
+	if err := s.db.Where("incident_id = ?", incident.ID).Find(&incidentEvents).Error; err != nil {
+		return nil, fmt.Errorf("failed to query incident events: %w", err)
+	}
+	for _, event := range incidentEvents {
+		source := "system"
+		if event.UserID != nil {
+			source = "user"
+		}
+		summary := event.Message
+		if event.Type == "status_change" {
+			summary = fmt.Sprintf("%s -> %s：%s", event.FromStatus, event.ToStatus, event.Message)
+		}
+		timeline = append(timeline, &IncidentTimelineEntry{
+			Time:    event.CreatedAt,
+			Kind:    event.Type,
+			Source:  source,
+			Summary: summary,
+			UserID:  event.UserID,
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline, nil
+}
+
+// extractAlertTarget 获取告警对应的监控目标ID
+func (s *IncidentService) extractAlertTarget(alert *models.Alert) string {
+	var labels map[string]interface{}
+	if alert.Labels != "" {
+		json.Unmarshal([]byte(alert.Labels), &labels)
+	}
+	if targetID, ok := labels["target_id"]; ok {
+		return fmt.Sprint(targetID)
+	}
+	return fingerprintTargetID(alert.Fingerprint)
+}
+
+// buildIncidentAnalysisContext 构建事件根因分析上下文
+func (s *IncidentService) buildIncidentAnalysisContext(incident *IncidentResponse) string {
+	var b strings.Builder
+	b.WriteString("请对以下故障事件进行整体根因分析。该事件由多条在时间上接近、拓扑或特征相关的告警归并而成，")
+	b.WriteString("其中大部分可能只是下游症状，请找出最初的故障源头（如数据库、节点或某次变更），而不是逐条解释告警。\n\n")
+
+	b.WriteString(fmt.Sprintf("事件：%s\n", incident.Title))
+	b.WriteString(fmt.Sprintf("严重级别：%s\n", incident.Severity))
+	b.WriteString(fmt.Sprintf("开始时间：%s\n", incident.StartedAt.Format(time.RFC3339)))
+	b.WriteString(fmt.Sprintf("告警数量：%d\n", incident.AlertCount))
+	if len(incident.Hosts) > 0 {
+		b.WriteString(fmt.Sprintf("涉及主机：%s\n", strings.Join(incident.Hosts, ", ")))
+	}
+	if len(incident.Services) > 0 {
+		b.WriteString(fmt.Sprintf("涉及服务：%s\n", strings.Join(incident.Services, ", ")))
+	}
+	if len(incident.Nodes) > 0 {
+		b.WriteString(fmt.Sprintf("涉及K8s节点：%s\n", strings.Join(incident.Nodes, ", ")))
+	}
+
+	b.WriteString("\n时间线（按时间排序）：\n")
+	timeline := incident.Timeline
+	if len(timeline) > maxTimelineEntries {
+		timeline = timeline[:maxTimelineEntries]
+	}
+	for _, entry := range timeline {
+		b.WriteString(fmt.Sprintf("- %s [%s/%s] %s\n", entry.Time.Format(time.RFC3339), entry.Kind, entry.Source, entry.Summary))
+	}
+
+	b.WriteString(`
+请提供以下分析：
+1. 根本原因（指出最可能的源头组件或变更）
+2. 影响评估（哪些告警是症状）
+3. 解决建议
+4. 预防措施
+5. 严重级别（critical/high/medium/low）
+6. 置信度评分（0-1之间的小数）
+
+请以JSON格式返回分析结果，字段为 root_cause、impact_assessment、recommendations、prevention_measures、severity_level、confidence_score。
+`)
+
+	return b.String()
+}
+
+// toIncidentResponse 转换为事件响应格式
+func (s *IncidentService) toIncidentResponse(incident *models.Incident) *IncidentResponse {
+	return &IncidentResponse{
+		ID:           incident.ID,
+		Title:        incident.Title,
+		Description:  incident.Description,
+		Status:       incident.Status,
+		Severity:     incident.Severity,
+		CommanderID:  incident.CommanderID,
+		Stakeholders: jsonStrings(incident.Stakeholders),
+		StartedAt:    incident.StartedAt,
+		LastAlertAt:  incident.LastAlertAt,
+		ResolvedAt:   incident.ResolvedAt,
+		AlertCount:   incident.AlertCount,
+		Hosts:        jsonStrings(incident.Hosts),
+		Services:     jsonStrings(incident.Services),
+		Nodes:        jsonStrings(incident.Nodes),
+		RootCause:    incident.RootCause,
+		AnalysisID:   incident.AnalysisID,
+		PostmortemID: incident.PostmortemID,
+		CreatedBy:    incident.CreatedBy,
+		CreatedAt:    incident.CreatedAt,
+		UpdatedAt:    incident.UpdatedAt,
+	}
+}
+
+// fingerprintTargetID 从阈值告警指纹（规则ID-目标ID-指标）中解析目标ID
+func fingerprintTargetID(fingerprint string) string {
+	if len(fingerprint) < 74 || fingerprint[36] != '-' {
+		return ""
+	}
+	candidate := fingerprint[37:73]
+	if _, err := uuid.Parse(candidate); err != nil {
+		return ""
+	}
+	return candidate
+}
+
+// tokenizeSummary 将告警摘要切分为词集合，忽略纯数字
+func tokenizeSummary(summary string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, field := range strings.FieldsFunc(strings.ToLower(summary), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(field) < 2 || strings.IndexFunc(field, unicode.IsLetter) < 0 {
+			continue
+		}
+		tokens[field] = true
+	}
+	return tokens
+}
+
+// jaccard 计算两个集合的Jaccard相似度
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// intersects 判断两个集合是否有交集
+func intersects(a, b map[string]bool) bool {
+	for k := range a {
+		if b[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// copyStringSet 复制集合
+func copyStringSet(set map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(set))
+	for k := range set {
+		copied[k] = true
+	}
+	return copied
+}
+
+// jsonStrings 解析JSON字符串数组
+func jsonStrings(raw string) []string {
+	values := []string{}
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &values)
+	}
+	return values
+}
+
+// jsonStringSet 解析JSON字符串数组为集合
+func jsonStringSet(raw string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range jsonStrings(raw) {
+		set[v] = true
+	}
+	return set
+}
+
+// jsonStringList 将集合序列化为有序JSON数组
+func jsonStringList(set map[string]bool) string {
+	values := make([]string, 0, len(set))
+	for k := range set {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	data, _ := json.Marshal(values)
+	return string(data)
+}
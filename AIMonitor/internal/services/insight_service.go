@@ -0,0 +1,549 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxInsightsPerBatch 每批次保留的洞察数量上限
+	maxInsightsPerBatch = 50
+	// noisyRuleMinFirings 判定噪声规则的最少触发次数
+	noisyRuleMinFirings = 10
+	// noisyRuleMaxAckRatio 判定噪声规则的最高确认比例
+	noisyRuleMaxAckRatio = 0.2
+	// flappingMinTransitions 判定告警抖动的最少状态切换次数
+	flappingMinTransitions = 4
+	// errorRateRiseRatio 错误率上升判定倍数
+	errorRateRiseRatio = 1.5
+	// capacityInsightHours 纳入洞察的容量耗尽时间上限
+	capacityInsightHours = 168
+	// insightRetention 历史洞察保留时长
+	insightRetention = 7 * 24 * time.Hour
+)
+
+// severityWeights 洞察严重级别权重，用于排序
+var severityWeights = map[string]float64{
+	"critical": 4,
+	"high":     3,
+	"medium":   2,
+	"low":      1,
+}
+
+// InsightService 智能洞察服务
+type InsightService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	aiService    *AIService
+}
+
+// NewInsightService 创建智能洞察服务
+func NewInsightService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, aiService *AIService) *InsightService {
+	return &InsightService{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       config,
+		aiService:    aiService,
+	}
+}
+
+// InsightResponse 洞察响应
+type InsightResponse struct {
+	ID          uuid.UUID              `json:"id"`
+	BatchID     uuid.UUID              `json:"batch_id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Severity    string                 `json:"severity"`
+	Score       float64                `json:"score"`
+	Rank        int                    `json:"rank"`
+	TargetID    string                 `json:"target_id"`
+	Evidence    map[string]interface{} `json:"evidence"`
+	GeneratedAt time.Time              `json:"generated_at"`
+}
+
+// RunInsightGeneration 周期性生成洞察，直到ctx取消
+func (s *InsightService) RunInsightGeneration(ctx context.Context, cfg config.InsightsConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	// 启动后立即生成一次，避免首个周期内无数据
+	if _, err := s.GenerateInsights(); err != nil {
+		logger.GetLogger("insight").WithError(err).Error("failed to generate insights")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.GenerateInsights(); err != nil {
+				logger.GetLogger("insight").WithError(err).Error("failed to generate insights")
+			}
+		}
+	}
+}
+
+// GenerateInsights 扫描近期指标、告警和分析结果，生成排序后的洞察批次
+func (s *InsightService) GenerateInsights() ([]*InsightResponse, error) {
+	cfg := s.config.Alerting.Insights
+	lookback := cfg.Lookback
+	if lookback <= 0 {
+		lookback = 24 * time.Hour
+	}
+	maxSeries := cfg.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = 500
+	}
+
+	now := time.Now()
+	since := now.Add(-lookback)
+
+	detectors := []func() ([]*models.AIInsight, error){
+		func() ([]*models.AIInsight, error) { return s.detectMetricAnomalies(now, maxSeries) },
+		func() ([]*models.AIInsight, error) { return s.detectNoisyRules(since) },
+		func() ([]*models.AIInsight, error) { return s.detectFlappingAlerts(since) },
+		func() ([]*models.AIInsight, error) { return s.detectRisingErrorRates(since, now) },
+		s.detectCapacityRisks,
+	}
+
+	// 单个检测器失败不影响其他检测器的结果，错误汇总后返回
+	var errs []error
+	insights := make([]*models.AIInsight, 0)
+	for _, detect := range detectors {
+		found, err := detect()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		insights = append(insights, found...)
+	}
+
+	// 按严重级别权重+强度排序
+	sort.SliceStable(insights, func(i, j int) bool {
+		return insights[i].Score > insights[j].Score
+	})
+	if len(insights) > maxInsightsPerBatch {
+		insights = insights[:maxInsightsPerBatch]
+	}
+
+	batchID := uuid.New()
+	for i, insight := range insights {
+		insight.BatchID = batchID
+		insight.Rank = i + 1
+		insight.GeneratedAt = now
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(insights) > 0 {
+			if err := tx.Create(&insights).Error; err != nil {
+				return fmt.Errorf("failed to save insights: %w", err)
+			}
+		}
+		if err := tx.Where("generated_at < ?", now.Add(-insightRetention)).Delete(&models.AIInsight{}).Error; err != nil {
+			return fmt.Errorf("failed to prune insights: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(append(errs, err)...)
+	}
+
+	responses := make([]*InsightResponse, len(insights))
+	for i, insight := range insights {
+		responses[i] = s.toInsightResponse(insight)
+	}
+	return responses, errors.Join(errs...)
+}
+
+// ListInsights 获取最新批次的洞察
+func (s *InsightService) ListInsights(insightType, severity string, limit int) ([]*InsightResponse, error) {
+	if limit <= 0 || limit > maxInsightsPerBatch {
+		limit = maxInsightsPerBatch
+	}
+
+	var latest models.AIInsight
+	if err := s.db.Select("batch_id").Order("generated_at DESC").First(&latest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return []*InsightResponse{}, nil
+		}
+		return nil, fmt.Errorf("failed to get latest insight batch: %w", err)
+	}
+
+	query := s.db.Where("batch_id = ?", latest.BatchID)
+	if insightType != "" {
+		query = query.Where("type = ?", insightType)
+	}
+	if severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+
+	var insights []models.AIInsight
+	if err := query.Order("insight_rank ASC").Limit(limit).Find(&insights).Error; err != nil {
+		return nil, fmt.Errorf("failed to list insights: %w", err)
+	}
+
+	responses := make([]*InsightResponse, len(insights))
+	for i := range insights {
+		responses[i] = s.toInsightResponse(&insights[i])
+	}
+	return responses, nil
+}
+
+// detectMetricAnomalies 对近期活跃序列做稳健异常评分，取最显著的异常
+func (s *InsightService) detectMetricAnomalies(now time.Time, maxSeries int) ([]*models.AIInsight, error) {
+	var keys []capacitySeriesKey
+	if err := s.db.Model(&models.MetricData{}).
+		Distinct("target_id", "metric").
+		Where("timestamp >= ?", now.Add(-time.Hour)).
+		Limit(maxSeries).
+		Scan(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query active series: %w", err)
+	}
+
+	insights := make([]*models.AIInsight, 0)
+	for _, key := range keys {
+		targetID := key.TargetID.String()
+		series, err := s.aiService.getHistoricalMetrics("", targetID, key.Metric, 7)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get historical data: %w", err)
+		}
+		if len(series.Values) < minBaselineSamples {
+			continue
+		}
+
+		last := len(series.Values) - 1
+		current := series.Values[last]
+		result := s.aiService.scoreAnomaly(series, current, series.TimestampAt(last), robustZScoreThreshold)
+		if !result.IsAnomaly {
+			continue
+		}
+
+		severity := "medium"
+		switch result.DeviationLevel {
+		case "severe":
+			severity = "critical"
+		case "high":
+			severity = "high"
+		}
+
+		alertIDs := s.relatedAlertIDs(targetID)
+		insights = append(insights, s.newInsight("anomaly", severity, 1-result.Threshold/result.AnomalyScore, targetID,
+			fmt.Sprintf("%s 指标异常", key.Metric),
+			fmt.Sprintf("目标 %s 的 %s 当前值 %.2f，期望值 %.2f，异常评分 %.2f（%s）",
+				targetID, key.Metric, current, result.ExpectedValue, result.AnomalyScore, result.Method),
+			map[string]interface{}{
+				"target_id":      targetID,
+				"metric":         key.Metric,
+				"current_value":  current,
+				"expected_value": result.ExpectedValue,
+				"anomaly_score":  result.AnomalyScore,
+				"method":         result.Method,
+				"alert_ids":      alertIDs,
+				"analysis_ids":   s.relatedAnalysisIDs(alertIDs),
+			}))
+	}
+
+	return insights, nil
+}
+
+// detectNoisyRules 识别频繁触发但很少被确认的告警规则
+func (s *InsightService) detectNoisyRules(since time.Time) ([]*models.AIInsight, error) {
+	type ruleStat struct {
+		RuleID       uuid.UUID
+		Fired        int64
+		Acknowledged int64
+	}
+
+	var stats []ruleStat
+	if err := s.db.Model(&models.AlertEvent{}).
+		Select("rule_id, SUM(CASE WHEN type IN ('firing', 'reopened') THEN 1 ELSE 0 END) AS fired, SUM(CASE WHEN type = 'acknowledged' THEN 1 ELSE 0 END) AS acknowledged").
+		Where("created_at >= ?", since).
+		Group("rule_id").
+		Having("SUM(CASE WHEN type IN ('firing', 'reopened') THEN 1 ELSE 0 END) >= ?", noisyRuleMinFirings).
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query rule statistics: %w", err)
+	}
+
+	insights := make([]*models.AIInsight, 0)
+	for _, stat := range stats {
+		ackRatio := float64(stat.Acknowledged) / float64(stat.Fired)
+		if ackRatio > noisyRuleMaxAckRatio {
+			continue
+		}
+
+		var rule models.AlertRule
+		if err := s.db.Select("id", "name", "metric").First(&rule, stat.RuleID).Error; err != nil {
+			continue
+		}
+
+		var alertIDs []uuid.UUID
+		s.db.Model(&models.AlertEvent{}).
+			Distinct("alert_id").
+			Where("rule_id = ? AND created_at >= ?", stat.RuleID, since).
+			Limit(10).
+			Pluck("alert_id", &alertIDs)
+
+		severity := "medium"
+		if stat.Fired >= noisyRuleMinFirings*5 {
+			severity = "high"
+		}
+
+		insights = append(insights, s.newInsight("noisy_rule", severity, 1-float64(noisyRuleMinFirings)/float64(stat.Fired), rule.ID.String(),
+			fmt.Sprintf("告警规则 %s 噪声过大", rule.Name),
+			fmt.Sprintf("规则 %s 在统计窗口内触发 %d 次，仅 %d 次被确认（%.0f%%），建议调整阈值或改用异常检测规则",
+				rule.Name, stat.Fired, stat.Acknowledged, ackRatio*100),
+			map[string]interface{}{
+				"rule_id":      rule.ID,
+				"metric":       rule.Metric,
+				"fired":        stat.Fired,
+				"acknowledged": stat.Acknowledged,
+				"alert_ids":    alertIDs,
+				"analysis_ids": s.relatedAnalysisIDs(alertIDs),
+			}))
+	}
+
+	return insights, nil
+}
+
+// detectFlappingAlerts 识别在触发与恢复之间反复切换的告警
+func (s *InsightService) detectFlappingAlerts(since time.Time) ([]*models.AIInsight, error) {
+	type flapStat struct {
+		AlertID     uuid.UUID
+		RuleID      uuid.UUID
+		Transitions int64
+	}
+
+	var stats []flapStat
+	if err := s.db.Model(&models.AlertEvent{}).
+		Select("alert_id, rule_id, COUNT(*) AS transitions").
+		Where("type IN ? AND created_at >= ?", []string{"reopened", "resolved"}, since).
+		Group("alert_id, rule_id").
+		Having("COUNT(*) >= ?", flappingMinTransitions).
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query alert transitions: %w", err)
+	}
+
+	insights := make([]*models.AIInsight, 0)
+	for _, stat := range stats {
+		var alert models.Alert
+		if err := s.db.Select("id", "summary", "severity").First(&alert, stat.AlertID).Error; err != nil {
+			continue
+		}
+
+		severity := "medium"
+		if stat.Transitions >= flappingMinTransitions*3 {
+			severity = "high"
+		}
+
+		alertIDs := []uuid.UUID{stat.AlertID}
+		insights = append(insights, s.newInsight("flapping_alert", severity, 1-float64(flappingMinTransitions)/float64(stat.Transitions), stat.AlertID.String(),
+			"告警反复抖动",
+			fmt.Sprintf("告警「%s」在统计窗口内状态切换 %d 次，建议增加持续时间或设置恢复滞后", alert.Summary, stat.Transitions),
+			map[string]interface{}{
+				"alert_id":     stat.AlertID,
+				"rule_id":      stat.RuleID,
+				"transitions":  stat.Transitions,
+				"analysis_ids": s.relatedAnalysisIDs(alertIDs),
+			}))
+	}
+
+	return insights, nil
+}
+
+// detectRisingErrorRates 比较最近一小时与统计窗口基线的错误率
+func (s *InsightService) detectRisingErrorRates(since, now time.Time) ([]*models.AIInsight, error) {
+	type errorStat struct {
+		SeriesKey string
+		Metric    string
+		Recent    *float64
+		Baseline  *float64
+	}
+
+	recentStart := now.Add(-time.Hour)
+	stats := make([]errorStat, 0)
+
+	// 指标存储中的错误率类指标
+	var metricStats []errorStat
+	if err := s.db.Model(&models.MetricData{}).
+		Select("target_id AS series_key, metric, AVG(CASE WHEN timestamp >= ? THEN value END) AS recent, AVG(CASE WHEN timestamp < ? THEN value END) AS baseline", recentStart, recentStart).
+		Where("timestamp >= ? AND metric LIKE ?", since, "%error%").
+		Group("target_id, metric").
+		Scan(&metricStats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query error rate metrics: %w", err)
+	}
+	stats = append(stats, metricStats...)
+
+	// APM链路中的服务错误率
+	if s.db.Migrator().HasTable(&models.APMTrace{}) {
+		var serviceStats []errorStat
+		if err := s.db.Model(&models.APMTrace{}).
+			Select("service_name AS series_key, 'error_rate' AS metric, "+
+				"AVG(CASE WHEN start_time >= ? THEN (CASE WHEN status = 'error' THEN 1.0 ELSE 0.0 END) END) AS recent, "+
+				"AVG(CASE WHEN start_time < ? THEN (CASE WHEN status = 'error' THEN 1.0 ELSE 0.0 END) END) AS baseline", recentStart, recentStart).
+			Where("start_time >= ?", since).
+			Group("service_name").
+			Scan(&serviceStats).Error; err != nil {
+			return nil, fmt.Errorf("failed to query service error rates: %w", err)
+		}
+		stats = append(stats, serviceStats...)
+	}
+
+	insights := make([]*models.AIInsight, 0)
+	for _, stat := range stats {
+		if stat.Recent == nil || stat.Baseline == nil {
+			continue
+		}
+		recent, baseline := *stat.Recent, *stat.Baseline
+		if recent <= 0 || recent < baseline*errorRateRiseRatio {
+			continue
+		}
+
+		ratio := errorRateRiseRatio * 4
+		if baseline > 0 {
+			ratio = recent / baseline
+		}
+		severity := "medium"
+		if ratio >= errorRateRiseRatio*4 {
+			severity = "critical"
+		} else if ratio >= errorRateRiseRatio*2 {
+			severity = "high"
+		}
+
+		alertIDs := s.relatedAlertIDs(stat.SeriesKey)
+		insights = append(insights, s.newInsight("error_rate", severity, 1-1/ratio, stat.SeriesKey,
+			fmt.Sprintf("%s 错误率上升", stat.SeriesKey),
+			fmt.Sprintf("%s 的 %s 最近一小时为 %.4f，基线为 %.4f，上升 %.1f 倍", stat.SeriesKey, stat.Metric, recent, baseline, ratio),
+			map[string]interface{}{
+				"key":          stat.SeriesKey,
+				"metric":       stat.Metric,
+				"recent":       recent,
+				"baseline":     baseline,
+				"ratio":        ratio,
+				"alert_ids":    alertIDs,
+				"analysis_ids": s.relatedAnalysisIDs(alertIDs),
+			}))
+	}
+
+	return insights, nil
+}
+
+// detectCapacityRisks 将一周内可能耗尽的容量预测转为洞察
+func (s *InsightService) detectCapacityRisks() ([]*models.AIInsight, error) {
+	report, err := s.aiService.GetCapacityReport("", 0, 100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get capacity report: %w", err)
+	}
+
+	insights := make([]*models.AIInsight, 0)
+	for _, forecast := range report.Forecasts {
+		hours := forecast.HoursToExhaustion
+		if hours == nil || *hours > capacityInsightHours {
+			// 报告已按耗尽时间排序
+			break
+		}
+
+		severity := forecast.RiskLevel
+		if _, ok := severityWeights[severity]; !ok {
+			severity = "medium"
+		}
+
+		alertIDs := s.relatedAlertIDs(forecast.TargetID)
+		insights = append(insights, s.newInsight("capacity", severity, 1-*hours/capacityInsightHours, forecast.TargetID,
+			fmt.Sprintf("%s 容量即将耗尽", forecast.MetricName),
+			describeCapacityForecast(forecast),
+			map[string]interface{}{
+				"forecast":     forecast,
+				"alert_ids":    alertIDs,
+				"analysis_ids": s.relatedAnalysisIDs(alertIDs),
+			}))
+	}
+
+	return insights, nil
+}
+
+// relatedAlertIDs 查找与目标相关的活跃告警
+func (s *InsightService) relatedAlertIDs(targetID string) []uuid.UUID {
+	var ids []uuid.UUID
+	s.db.Model(&models.Alert{}).
+		Where("status = ? AND labels LIKE ?", "firing", "%"+targetID+"%").
+		Order("starts_at DESC").
+		Limit(5).
+		Pluck("id", &ids)
+	return ids
+}
+
+// relatedAnalysisIDs 查找告警关联的AI分析结果
+func (s *InsightService) relatedAnalysisIDs(alertIDs []uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{}
+	if len(alertIDs) == 0 {
+		return ids
+	}
+	s.db.Model(&models.AIAnalysisResult{}).
+		Where("alert_id IN ?", alertIDs).
+		Order("created_at DESC").
+		Limit(5).
+		Pluck("id", &ids)
+	return ids
+}
+
+// newInsight 构建洞察记录，评分 = 严重级别权重 + 强度(0-1)
+func (s *InsightService) newInsight(insightType, severity string, intensity float64, targetID, title, description string, evidence map[string]interface{}) *models.AIInsight {
+	if intensity < 0 {
+		intensity = 0
+	}
+	if intensity > 0.99 {
+		intensity = 0.99
+	}
+
+	evidenceJSON, _ := json.Marshal(evidence)
+	return &models.AIInsight{
+		Type:        insightType,
+		Title:       title,
+		Description: description,
+		Severity:    severity,
+		Score:       severityWeights[severity] + intensity,
+		TargetID:    targetID,
+		Evidence:    string(evidenceJSON),
+	}
+}
+
+// toInsightResponse 转换为洞察响应格式
+func (s *InsightService) toInsightResponse(insight *models.AIInsight) *InsightResponse {
+	var evidence map[string]interface{}
+	if insight.Evidence != "" {
+		json.Unmarshal([]byte(insight.Evidence), &evidence)
+	}
+
+	return &InsightResponse{
+		ID:          insight.ID,
+		BatchID:     insight.BatchID,
+		Type:        insight.Type,
+		Title:       insight.Title,
+		Description: insight.Description,
+		Severity:    insight.Severity,
+		Score:       insight.Score,
+		Rank:        insight.Rank,
+		TargetID:    insight.TargetID,
+		Evidence:    evidence,
+		GeneratedAt: insight.GeneratedAt,
+	}
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTraceNotFound Jaeger中不存在指定链路
+var ErrTraceNotFound = errors.New("trace not found")
+
+// defaultJaegerTimeout 未配置超时时的默认请求超时
+const defaultJaegerTimeout = 10 * time.Second
+
+// jaegerResponse Jaeger Query API通用响应结构
+type jaegerResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+	Errors []jaegerError   `json:"errors"`
+}
+
+// jaegerError Jaeger Query API错误
+type jaegerError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"traceID"`
+}
+
+// jaegerTrace Jaeger链路JSON结构
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+	Warnings  []string                 `json:"warnings"`
+}
+
+// jaegerSpan Jaeger跨度JSON结构，时间单位为微秒
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []jaegerKeyValue  `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+	Warnings      []string          `json:"warnings"`
+}
+
+// jaegerReference Jaeger跨度引用
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+// jaegerKeyValue Jaeger标签
+type jaegerKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// jaegerLog Jaeger跨度日志
+type jaegerLog struct {
+	Timestamp int64            `json:"timestamp"`
+	Fields    []jaegerKeyValue `json:"fields"`
+}
+
+// jaegerProcess Jaeger进程
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+// jaegerDependency Jaeger服务依赖
+type jaegerDependency struct {
+	Parent    string `json:"parent"`
+	Child     string `json:"child"`
+	CallCount int    `json:"callCount"`
+}
+
+// Jaeger客户端实现，基于Jaeger Query HTTP/JSON API
+type jaegerClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewJaegerClient 创建Jaeger Query客户端
+func NewJaegerClient(baseURL string, timeout time.Duration) (JaegerClient, error) {
+	if baseURL != "" {
+		if _, err := url.Parse(baseURL); err != nil {
+			return nil, fmt.Errorf("invalid jaeger query endpoint: %w", err)
+		}
+	}
+	if timeout <= 0 {
+		timeout = defaultJaegerTimeout
+	}
+
+	return &jaegerClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// GetTraces 查询链路列表。Jaeger不支持偏移量，按 offset+limit 查询后在本地截取
+func (c *jaegerClient) GetTraces(ctx context.Context, query TraceQuery) ([]Trace, error) {
+	if query.Service == "" {
+		return nil, errors.New("service is required to search traces")
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	params := url.Values{}
+	params.Set("service", query.Service)
+	if query.Operation != "" {
+		params.Set("operation", query.Operation)
+	}
+	tags := make(map[string]string, len(query.Tags)+1)
+	for k, v := range query.Tags {
+		tags[k] = v
+	}
+	// Jaeger以error=true标签标记错误跨度
+	if query.Status == "error" {
+		tags["error"] = "true"
+	}
+	if len(tags) > 0 {
+		tagsJSON, _ := json.Marshal(tags)
+		params.Set("tags", string(tagsJSON))
+	}
+	endTime := query.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := query.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.Add(-time.Hour)
+	}
+	params.Set("start", strconv.FormatInt(startTime.UnixMicro(), 10))
+	params.Set("end", strconv.FormatInt(endTime.UnixMicro(), 10))
+	if query.MinDuration > 0 {
+		params.Set("minDuration", query.MinDuration.String())
+	}
+	if query.MaxDuration > 0 {
+		params.Set("maxDuration", query.MaxDuration.String())
+	}
+	params.Set("limit", strconv.Itoa(offset+limit))
+
+	var raw []jaegerTrace
+	if err := c.get(ctx, "/api/traces", params, &raw); err != nil {
+		return nil, err
+	}
+
+	traces := make([]Trace, 0, len(raw))
+	for i := range raw {
+		traces = append(traces, summarizeJaegerTrace(&raw[i]))
+	}
+	// 按开始时间倒序，保证分页稳定
+	sort.Slice(traces, func(i, j int) bool {
+		return traces[i].StartTime.After(traces[j].StartTime)
+	})
+
+	if offset >= len(traces) {
+		return []Trace{}, nil
+	}
+	end := offset + limit
+	if end > len(traces) {
+		end = len(traces)
+	}
+	return traces[offset:end], nil
+}
+
+// GetTrace 查询单条链路详情
+func (c *jaegerClient) GetTrace(ctx context.Context, traceID string) (*TraceDetail, error) {
+	var raw []jaegerTrace
+	if err := c.get(ctx, "/api/traces/"+url.PathEscape(traceID), nil, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ErrTraceNotFound
+	}
+	return convertJaegerTrace(&raw[0]), nil
+}
+
+// GetServices 查询上报过链路的服务列表
+func (c *jaegerClient) GetServices(ctx context.Context) ([]string, error) {
+	var services []string
+	if err := c.get(ctx, "/api/services", nil, &services); err != nil {
+		return nil, err
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetOperations 查询服务的操作列表
+func (c *jaegerClient) GetOperations(ctx context.Context, service string) ([]string, error) {
+	var operations []string
+	if err := c.get(ctx, "/api/services/"+url.PathEscape(service)+"/operations", nil, &operations); err != nil {
+		return nil, err
+	}
+	sort.Strings(operations)
+	return operations, nil
+}
+
+// GetDependencies 查询截止endTime、回溯lookback时间内的服务调用关系
+func (c *jaegerClient) GetDependencies(ctx context.Context, endTime time.Time, lookback time.Duration) ([]ServiceEdge, error) {
+	params := url.Values{}
+	params.Set("endTs", strconv.FormatInt(endTime.UnixMilli(), 10))
+	params.Set("lookback", strconv.FormatInt(lookback.Milliseconds(), 10))
+
+	var deps []jaegerDependency
+	if err := c.get(ctx, "/api/dependencies", params, &deps); err != nil {
+		return nil, err
+	}
+
+	edges := make([]ServiceEdge, 0, len(deps))
+	for _, dep := range deps {
+		edges = append(edges, ServiceEdge{
+			Source: dep.Parent,
+			Target: dep.Child,
+			Metrics: ConnectionMetrics{
+				RequestCount: dep.CallCount,
+			},
+		})
+	}
+	return edges, nil
+}
+
+// get 发送GET请求并把data字段解码到out
+func (c *jaegerClient) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if c.baseURL == "" {
+		return errors.New("jaeger query endpoint not configured")
+	}
+
+	endpoint := c.baseURL + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create jaeger request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jaeger request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read jaeger response: %w", err)
+	}
+
+	var result jaegerResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("jaeger returned status %d", resp.StatusCode)
+		}
+		return fmt.Errorf("failed to decode jaeger response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTraceNotFound
+	}
+	if len(result.Errors) > 0 {
+		if result.Errors[0].Code == http.StatusNotFound {
+			return ErrTraceNotFound
+		}
+		return fmt.Errorf("jaeger returned error: %s", result.Errors[0].Msg)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jaeger returned status %d", resp.StatusCode)
+	}
+
+	if len(result.Data) == 0 || string(result.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("failed to decode jaeger data: %w", err)
+	}
+	return nil
+}
+
+// summarizeJaegerTrace 生成链路摘要
+func summarizeJaegerTrace(raw *jaegerTrace) Trace {
+	detail := convertJaegerTrace(raw)
+
+	warnings := len(raw.Warnings)
+	for _, span := range raw.Spans {
+		warnings += len(span.Warnings)
+	}
+
+	return Trace{
+		TraceID:   detail.TraceID,
+		SpanCount: len(detail.Spans),
+		Duration:  detail.Duration,
+		StartTime: detail.StartTime,
+		Services:  detail.Services,
+		Errors:    len(detail.Errors),
+		Warnings:  warnings,
+	}
+}
+
+// convertJaegerTrace 转换为链路详情
+func convertJaegerTrace(raw *jaegerTrace) *TraceDetail {
+	detail := &TraceDetail{
+		TraceID:   raw.TraceID,
+		Spans:     make([]Span, 0, len(raw.Spans)),
+		Errors:    []SpanError{},
+		Processes: make(map[string]Process, len(raw.Processes)),
+	}
+
+	for id, process := range raw.Processes {
+		tags := make(map[string]string, len(process.Tags))
+		for _, kv := range process.Tags {
+			tags[kv.Key] = fmt.Sprintf("%v", kv.Value)
+		}
+		detail.Processes[id] = Process{ServiceName: process.ServiceName, Tags: tags}
+	}
+
+	services := map[string]bool{}
+	for _, raw := range raw.Spans {
+		span := convertJaegerSpan(&raw, detail.Processes)
+		detail.Spans = append(detail.Spans, span)
+		if span.ServiceName != "" {
+			services[span.ServiceName] = true
+		}
+
+		if detail.StartTime.IsZero() || span.StartTime.Before(detail.StartTime) {
+			detail.StartTime = span.StartTime
+		}
+		if end := span.StartTime.Add(span.Duration); end.After(detail.EndTime) {
+			detail.EndTime = end
+		}
+
+		if span.Status.Code == spanStatusError {
+			detail.Errors = append(detail.Errors, SpanError{
+				SpanID:    span.SpanID,
+				Message:   spanErrorMessage(&span),
+				Timestamp: span.StartTime,
+				Level:     "error",
+			})
+		}
+	}
+	if !detail.StartTime.IsZero() {
+		detail.Duration = detail.EndTime.Sub(detail.StartTime)
+	}
+
+	for name := range services {
+		detail.Services = append(detail.Services, name)
+	}
+	sort.Strings(detail.Services)
+
+	// 跨度按开始时间排列，便于前端渲染瀑布图
+	sort.SliceStable(detail.Spans, func(i, j int) bool {
+		return detail.Spans[i].StartTime.Before(detail.Spans[j].StartTime)
+	})
+	return detail
+}
+
+// 跨度状态码，与OpenTelemetry定义一致
+const (
+	spanStatusUnset = 0
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+// convertJaegerSpan 转换跨度
+func convertJaegerSpan(raw *jaegerSpan, processes map[string]Process) Span {
+	span := Span{
+		SpanID:        raw.SpanID,
+		TraceID:       raw.TraceID,
+		OperationName: raw.OperationName,
+		ServiceName:   processes[raw.ProcessID].ServiceName,
+		StartTime:     time.UnixMicro(raw.StartTime),
+		Duration:      time.Duration(raw.Duration) * time.Microsecond,
+		Tags:          make(map[string]interface{}, len(raw.Tags)),
+		Logs:          make([]SpanLog, 0, len(raw.Logs)),
+		References:    make([]SpanReference, 0, len(raw.References)),
+	}
+
+	for _, kv := range raw.Tags {
+		span.Tags[kv.Key] = kv.Value
+	}
+
+	for _, log := range raw.Logs {
+		fields := make(map[string]interface{}, len(log.Fields))
+		for _, kv := range log.Fields {
+			fields[kv.Key] = kv.Value
+		}
+		span.Logs = append(span.Logs, SpanLog{Timestamp: time.UnixMicro(log.Timestamp), Fields: fields})
+	}
+
+	for _, ref := range raw.References {
+		span.References = append(span.References, SpanReference{
+			RefType: ref.RefType,
+			TraceID: ref.TraceID,
+			SpanID:  ref.SpanID,
+		})
+		// 同一链路内的CHILD_OF引用即父跨度
+		if span.ParentSpanID == "" && ref.RefType == "CHILD_OF" && ref.TraceID == raw.TraceID {
+			span.ParentSpanID = ref.SpanID
+		}
+	}
+
+	span.Status = jaegerSpanStatus(span.Tags)
+	return span
+}
+
+// jaegerSpanStatus 根据error标签和OTel状态标签推断跨度状态
+func jaegerSpanStatus(tags map[string]interface{}) SpanStatus {
+	status := SpanStatus{Code: spanStatusUnset}
+	if msg, ok := tags["otel.status_description"].(string); ok {
+		status.Message = msg
+	}
+
+	switch fmt.Sprintf("%v", tags["otel.status_code"]) {
+	case "ERROR":
+		status.Code = spanStatusError
+	case "OK":
+		status.Code = spanStatusOK
+	}
+	if isErr, ok := tags["error"].(bool); ok && isErr {
+		status.Code = spanStatusError
+	} else if v, ok := tags["error"].(string); ok && v == "true" {
+		status.Code = spanStatusError
+	}
+	return status
+}
+
+// spanErrorMessage 提取跨度错误信息：优先状态描述，其次错误日志
+func spanErrorMessage(span *Span) string {
+	if span.Status.Message != "" {
+		return span.Status.Message
+	}
+	for _, log := range span.Logs {
+		for _, key := range []string{"message", "error.object", "exception.message", "event"} {
+			if v, ok := log.Fields[key]; ok {
+				return fmt.Sprintf("%v", v)
+			}
+		}
+	}
+	return span.OperationName + " failed"
+}
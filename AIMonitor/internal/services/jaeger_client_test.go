@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jaegerTraceFixture Jaeger Query /api/traces/{id}返回的链路：
+// frontend根跨度调用checkout，checkout的数据库跨度出错，另有一个FOLLOWS_FROM的异步跨度
+const jaegerTraceFixture = `{
+	"data": [{
+		"traceID": "4bf92f3577b34da6",
+		"spans": [
+			{
+				"traceID": "4bf92f3577b34da6", "spanID": "c1", "operationName": "POST /checkout",
+				"references": [{"refType": "CHILD_OF", "traceID": "4bf92f3577b34da6", "spanID": "root"}],
+				"startTime": 1700000000100000, "duration": 150000, "processID": "p2",
+				"tags": [{"key": "http.status_code", "type": "int64", "value": 200}, {"key": "otel.status_code", "type": "string", "value": "OK"}],
+				"logs": [], "warnings": ["clock skew adjusted"]
+			},
+			{
+				"traceID": "4bf92f3577b34da6", "spanID": "root", "operationName": "GET /cart",
+				"references": [],
+				"startTime": 1700000000000000, "duration": 300000, "processID": "p1",
+				"tags": [{"key": "span.kind", "type": "string", "value": "server"}],
+				"logs": []
+			},
+			{
+				"traceID": "4bf92f3577b34da6", "spanID": "db", "operationName": "SELECT orders",
+				"references": [
+					{"refType": "FOLLOWS_FROM", "traceID": "otherTrace", "spanID": "x"},
+					{"refType": "CHILD_OF", "traceID": "4bf92f3577b34da6", "spanID": "c1"}
+				],
+				"startTime": 1700000000120000, "duration": 50000, "processID": "p2",
+				"tags": [{"key": "error", "type": "bool", "value": true}, {"key": "db.system", "type": "string", "value": "postgresql"}],
+				"logs": [{"timestamp": 1700000000160000, "fields": [{"key": "event", "type": "string", "value": "error"}, {"key": "message", "type": "string", "value": "deadlock detected"}]}]
+			},
+			{
+				"traceID": "4bf92f3577b34da6", "spanID": "async", "operationName": "publish",
+				"references": [{"refType": "FOLLOWS_FROM", "traceID": "4bf92f3577b34da6", "spanID": "c1"}],
+				"startTime": 1700000000400000, "duration": 20000, "processID": "p2",
+				"tags": [{"key": "otel.status_code", "type": "string", "value": "ERROR"}, {"key": "otel.status_description", "type": "string", "value": "broker unavailable"}],
+				"logs": []
+			}
+		],
+		"processes": {
+			"p1": {"serviceName": "frontend", "tags": [{"key": "hostname", "type": "string", "value": "web-1"}]},
+			"p2": {"serviceName": "checkout", "tags": [{"key": "ip", "type": "string", "value": "10.0.0.7"}, {"key": "pid", "type": "int64", "value": 42}]}
+		},
+		"warnings": null
+	}],
+	"total": 0, "limit": 0, "offset": 0, "errors": null
+}`
+
+func newTestJaegerClient(t *testing.T, handler http.HandlerFunc) JaegerClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := NewJaegerClient(srv.URL+"/", 2*time.Second)
+	if err != nil {
+		t.Fatalf("NewJaegerClient: %v", err)
+	}
+	return client
+}
+
+func TestJaegerGetTraceMapsSpans(t *testing.T) {
+	client := newTestJaegerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/traces/4bf92f3577b34da6" || r.Header.Get("Accept") != "application/json" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, jaegerTraceFixture)
+	})
+
+	trace, err := client.GetTrace(context.Background(), "4bf92f3577b34da6")
+	if err != nil {
+		t.Fatalf("GetTrace: %v", err)
+	}
+
+	if trace.TraceID != "4bf92f3577b34da6" || len(trace.Spans) != 4 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	// 链路时间范围取最早开始与最晚结束：0ms ~ 420ms
+	if !trace.StartTime.Equal(time.UnixMicro(1700000000000000)) || trace.Duration != 420*time.Millisecond {
+		t.Fatalf("unexpected trace timing start=%s duration=%s", trace.StartTime, trace.Duration)
+	}
+	if strings.Join(trace.Services, ",") != "checkout,frontend" {
+		t.Fatalf("unexpected services %v", trace.Services)
+	}
+	if p := trace.Processes["p2"]; p.ServiceName != "checkout" || p.Tags["ip"] != "10.0.0.7" || p.Tags["pid"] != "42" {
+		t.Fatalf("unexpected process %+v", p)
+	}
+
+	// 跨度按开始时间排序
+	order := make([]string, 0, len(trace.Spans))
+	for _, span := range trace.Spans {
+		order = append(order, span.SpanID)
+	}
+	if strings.Join(order, ",") != "root,c1,db,async" {
+		t.Fatalf("unexpected span order %v", order)
+	}
+
+	root, checkout, db, async := trace.Spans[0], trace.Spans[1], trace.Spans[2], trace.Spans[3]
+	if root.ParentSpanID != "" || root.ServiceName != "frontend" || root.Tags["span.kind"] != "server" || root.Status.Code != spanStatusUnset {
+		t.Fatalf("unexpected root span %+v", root)
+	}
+	if checkout.ParentSpanID != "root" || checkout.Duration != 150*time.Millisecond || checkout.Status.Code != spanStatusOK {
+		t.Fatalf("unexpected checkout span %+v", checkout)
+	}
+	// JSON数字解码为float64
+	if checkout.Tags["http.status_code"] != float64(200) {
+		t.Fatalf("unexpected tag value %#v", checkout.Tags["http.status_code"])
+	}
+	// 其他链路的引用不作为父跨度
+	if db.ParentSpanID != "c1" || len(db.References) != 2 || db.References[0].RefType != "FOLLOWS_FROM" {
+		t.Fatalf("unexpected db span references %+v", db)
+	}
+	if db.Status.Code != spanStatusError || db.Tags["db.system"] != "postgresql" || len(db.Logs) != 1 || db.Logs[0].Fields["message"] != "deadlock detected" {
+		t.Fatalf("unexpected db span %+v", db)
+	}
+	// FOLLOWS_FROM不视为父子关系
+	if async.ParentSpanID != "" || async.Status.Code != spanStatusError || async.Status.Message != "broker unavailable" {
+		t.Fatalf("unexpected async span %+v", async)
+	}
+
+	if len(trace.Errors) != 2 {
+		t.Fatalf("expected 2 span errors, got %+v", trace.Errors)
+	}
+	messages := map[string]string{}
+	for _, e := range trace.Errors {
+		messages[e.SpanID] = e.Message
+		if e.Level != "error" {
+			t.Fatalf("unexpected error level %+v", e)
+		}
+	}
+	// 无状态描述时按优先级取错误日志字段，message优先于event
+	if messages["db"] != "deadlock detected" || messages["async"] != "broker unavailable" {
+		t.Fatalf("unexpected error messages %v", messages)
+	}
+}
+
+func TestJaegerGetTracesSearch(t *testing.T) {
+	var query map[string][]string
+	client := newTestJaegerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		// 三条链路，开始时间分别为 t+0、t+2s、t+1s
+		io.WriteString(w, `{"data": [
+			{"traceID": "a", "spans": [{"traceID": "a", "spanID": "1", "startTime": 1700000000000000, "duration": 1000, "processID": "p", "tags": [], "warnings": ["w1"]}],
+			 "processes": {"p": {"serviceName": "api"}}, "warnings": ["w0"]},
+			{"traceID": "b", "spans": [{"traceID": "b", "spanID": "1", "startTime": 1700000002000000, "duration": 5000, "processID": "p", "tags": [{"key": "error", "value": "true"}]}],
+			 "processes": {"p": {"serviceName": "api"}}},
+			{"traceID": "c", "spans": [
+				{"traceID": "c", "spanID": "1", "startTime": 1700000001000000, "duration": 3000, "processID": "p", "tags": []},
+				{"traceID": "c", "spanID": "2", "startTime": 1700000001001000, "duration": 1000, "processID": "q", "tags": []}],
+			 "processes": {"p": {"serviceName": "api"}, "q": {"serviceName": "db"}}}
+		]}`)
+	})
+
+	end := time.UnixMicro(1700000010000000)
+	traces, err := client.GetTraces(context.Background(), TraceQuery{
+		Service:     "api",
+		Operation:   "GET /users",
+		Tags:        map[string]string{"http.method": "GET"},
+		Status:      "error",
+		EndTime:     end,
+		MinDuration: 100 * time.Millisecond,
+		Limit:       2,
+		Offset:      1,
+	})
+	if err != nil {
+		t.Fatalf("GetTraces: %v", err)
+	}
+
+	if query["service"][0] != "api" || query["operation"][0] != "GET /users" || query["limit"][0] != "3" || query["minDuration"][0] != "100ms" {
+		t.Fatalf("unexpected query %v", query)
+	}
+	// 未指定开始时间时回溯1小时
+	if query["end"][0] != "1700000010000000" || query["start"][0] != "1699996410000000" {
+		t.Fatalf("unexpected time range %v", query)
+	}
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(query["tags"][0]), &tags); err != nil || tags["error"] != "true" || tags["http.method"] != "GET" {
+		t.Fatalf("unexpected tags %q", query["tags"])
+	}
+	if _, ok := query["maxDuration"]; ok {
+		t.Fatalf("maxDuration should be omitted: %v", query)
+	}
+
+	// 按开始时间倒序 b,c,a 后跳过1条
+	if len(traces) != 2 || traces[0].TraceID != "c" || traces[1].TraceID != "a" {
+		t.Fatalf("unexpected traces %+v", traces)
+	}
+	c, a := traces[0], traces[1]
+	if c.SpanCount != 2 || c.Duration != 3*time.Millisecond || strings.Join(c.Services, ",") != "api,db" || c.Errors != 0 {
+		t.Fatalf("unexpected summary %+v", c)
+	}
+	if a.Warnings != 2 {
+		t.Fatalf("expected trace and span warnings to be counted, got %+v", a)
+	}
+
+	// 偏移超出结果数量时返回空列表
+	traces, err = client.GetTraces(context.Background(), TraceQuery{Service: "api", Offset: 10})
+	if err != nil || traces == nil || len(traces) != 0 {
+		t.Fatalf("expected empty page, got %v %v", traces, err)
+	}
+
+	if _, err := client.GetTraces(context.Background(), TraceQuery{}); err == nil {
+		t.Fatal("expected error when service is missing")
+	}
+}
+
+func TestJaegerServicesOperationsDependencies(t *testing.T) {
+	client := newTestJaegerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/services":
+			io.WriteString(w, `{"data": ["payment", "api", "frontend"], "total": 3}`)
+		case "/api/services/api gateway/operations":
+			io.WriteString(w, `{"data": ["POST /b", "GET /a"]}`)
+		case "/api/dependencies":
+			if r.URL.Query().Get("endTs") != "1700000000000" || r.URL.Query().Get("lookback") != "3600000" {
+				t.Errorf("unexpected dependency query %s", r.URL.RawQuery)
+			}
+			io.WriteString(w, `{"data": [{"parent": "frontend", "child": "api", "callCount": 42}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	services, err := client.GetServices(ctx)
+	if err != nil || strings.Join(services, ",") != "api,frontend,payment" {
+		t.Fatalf("GetServices = %v, %v", services, err)
+	}
+	operations, err := client.GetOperations(ctx, "api gateway")
+	if err != nil || strings.Join(operations, ",") != "GET /a,POST /b" {
+		t.Fatalf("GetOperations = %v, %v", operations, err)
+	}
+	edges, err := client.GetDependencies(ctx, time.UnixMilli(1700000000000), time.Hour)
+	if err != nil || len(edges) != 1 || edges[0].Source != "frontend" || edges[0].Target != "api" || edges[0].Metrics.RequestCount != 42 {
+		t.Fatalf("GetDependencies = %+v, %v", edges, err)
+	}
+}
+
+func TestJaegerErrors(t *testing.T) {
+	client := newTestJaegerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/traces/missing":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"data": null, "errors": [{"code": 404, "msg": "trace not found"}]}`)
+		case "/api/traces/empty":
+			io.WriteString(w, `{"data": []}`)
+		case "/api/traces/in-body":
+			io.WriteString(w, `{"data": null, "errors": [{"code": 404, "msg": "trace not found", "traceID": "in-body"}]}`)
+		case "/api/traces/storage":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"data": null, "errors": [{"code": 500, "msg": "elasticsearch unavailable"}]}`)
+		case "/api/traces/gateway":
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "<html>bad gateway</html>")
+		case "/api/traces/garbled":
+			io.WriteString(w, `{"data": `)
+		case "/api/traces/wrong-shape":
+			io.WriteString(w, `{"data": {"traceID": "x"}}`)
+		case "/api/services":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `{"data": null}`)
+		}
+	})
+	ctx := context.Background()
+
+	for _, id := range []string{"missing", "empty", "in-body"} {
+		if _, err := client.GetTrace(ctx, id); !errors.Is(err, ErrTraceNotFound) {
+			t.Errorf("%s: expected ErrTraceNotFound, got %v", id, err)
+		}
+	}
+
+	_, err := client.GetTrace(ctx, "storage")
+	if err == nil || !strings.Contains(err.Error(), "elasticsearch unavailable") {
+		t.Errorf("expected jaeger error message, got %v", err)
+	}
+	_, err = client.GetTrace(ctx, "gateway")
+	if err == nil || !strings.Contains(err.Error(), "status 502") {
+		t.Errorf("expected status error for non-JSON response, got %v", err)
+	}
+	_, err = client.GetTrace(ctx, "garbled")
+	if err == nil || !strings.Contains(err.Error(), "failed to decode jaeger response") {
+		t.Errorf("expected decode error, got %v", err)
+	}
+	_, err = client.GetTrace(ctx, "wrong-shape")
+	if err == nil || !strings.Contains(err.Error(), "failed to decode jaeger data") {
+		t.Errorf("expected data decode error, got %v", err)
+	}
+	_, err = client.GetServices(ctx)
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("expected status error, got %v", err)
+	}
+
+	unconfigured, err := NewJaegerClient("", 0)
+	if err != nil {
+		t.Fatalf("NewJaegerClient: %v", err)
+	}
+	if _, err := unconfigured.GetServices(ctx); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("expected not configured error, got %v", err)
+	}
+	if _, err := NewJaegerClient("http://[::1", 0); err == nil {
+		t.Error("expected invalid endpoint error")
+	}
+}
+
+func TestJaegerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := NewJaegerClient(srv.URL, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewJaegerClient: %v", err)
+	}
+	start := time.Now()
+	_, err = client.GetServices(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "jaeger request failed") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("client timeout not applied, took %s", elapsed)
+	}
+
+	// 调用方ctx取消同样中止请求
+	client, _ = NewJaegerClient(srv.URL, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetTrace(ctx, "slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline error, got %v", err)
+	}
+}
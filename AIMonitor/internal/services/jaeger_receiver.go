@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger-idl/thrift-gen/jaeger"
+)
+
+// JaegerThriftContentType Jaeger客户端HTTP上报使用的内容类型（Thrift二进制协议）
+const JaegerThriftContentType = "application/x-thrift"
+
+// IngestJaegerThrift 接收Jaeger客户端以Thrift over HTTP上报的Batch
+func (s *TraceIngestService) IngestJaegerThrift(ctx context.Context, body []byte) (*IngestResult, error) {
+	batch := jaeger.NewBatch()
+	if err := thrift.NewTDeserializer().Read(ctx, batch, body); err != nil {
+		return nil, fmt.Errorf("failed to decode jaeger thrift batch: %w", err)
+	}
+	return s.Ingest(ctx, convertJaegerBatch(batch))
+}
+
+// convertJaegerBatch 将Jaeger Batch展开为归一化跨度，进程标签作为资源属性
+func convertJaegerBatch(batch *jaeger.Batch) []*IngestSpan {
+	process := batch.GetProcess()
+	resource := jaegerTags(process.GetTags())
+	serviceName := process.GetServiceName()
+	resource["service.name"] = serviceName
+	// jaeger.version形如Go-2.30.0，据此补充语言信息
+	if version := attributeString(resource, "jaeger.version"); version != "" {
+		if _, ok := resource["telemetry.sdk.language"]; !ok {
+			if i := strings.Index(version, "-"); i > 0 {
+				resource["telemetry.sdk.language"] = strings.ToLower(version[:i])
+			}
+		}
+	}
+
+	spans := make([]*IngestSpan, 0, len(batch.GetSpans()))
+	for _, span := range batch.GetSpans() {
+		spans = append(spans, convertJaegerThriftSpan(span, serviceName, resource))
+	}
+	return spans
+}
+
+// convertJaegerThriftSpan 转换单个Jaeger Thrift跨度。span.kind、error等约定标签转换为对应字段
+func convertJaegerThriftSpan(span *jaeger.Span, serviceName string, resource map[string]interface{}) *IngestSpan {
+	start := time.UnixMicro(span.GetStartTime())
+	attrs := jaegerTags(span.GetTags())
+	result := &IngestSpan{
+		TraceID:       fmt.Sprintf("%016x%016x", uint64(span.GetTraceIdHigh()), uint64(span.GetTraceIdLow())),
+		SpanID:        fmt.Sprintf("%016x", uint64(span.GetSpanId())),
+		OperationName: span.GetOperationName(),
+		ServiceName:   serviceName,
+		StartTime:     start,
+		EndTime:       start.Add(time.Duration(span.GetDuration()) * time.Microsecond),
+		Attributes:    attrs,
+		Resource:      resource,
+		Scope:         "jaeger",
+	}
+
+	if span.GetParentSpanId() != 0 {
+		result.ParentSpanID = fmt.Sprintf("%016x", uint64(span.GetParentSpanId()))
+	} else {
+		// 新版客户端只通过references表达父子关系，优先取CHILD_OF
+		for _, ref := range span.GetReferences() {
+			if ref.GetTraceIdLow() != span.GetTraceIdLow() || ref.GetTraceIdHigh() != span.GetTraceIdHigh() {
+				continue
+			}
+			result.ParentSpanID = fmt.Sprintf("%016x", uint64(ref.GetSpanId()))
+			if ref.GetRefType() == jaeger.SpanRefType_CHILD_OF {
+				break
+			}
+		}
+	}
+
+	if kind, ok := attrs["span.kind"].(string); ok {
+		result.Kind = strings.ToLower(kind)
+		delete(attrs, "span.kind")
+	}
+	switch v := attrs["error"].(type) {
+	case bool:
+		result.Error = v
+	case string:
+		result.Error = v == "true"
+	}
+	result.StatusMessage = attributeString(attrs, "otel.status_description", "error.message")
+
+	for _, log := range span.GetLogs() {
+		result.Events = append(result.Events, SpanLog{
+			Timestamp: time.UnixMicro(log.GetTimestamp()),
+			Fields:    jaegerTags(log.GetFields()),
+		})
+	}
+	return result
+}
+
+// jaegerTags 转换Jaeger标签列表
+func jaegerTags(tags []*jaeger.Tag) map[string]interface{} {
+	result := make(map[string]interface{}, len(tags))
+	for _, tag := range tags {
+		switch tag.GetVType() {
+		case jaeger.TagType_STRING:
+			result[tag.GetKey()] = tag.GetVStr()
+		case jaeger.TagType_DOUBLE:
+			result[tag.GetKey()] = tag.GetVDouble()
+		case jaeger.TagType_BOOL:
+			result[tag.GetKey()] = tag.GetVBool()
+		case jaeger.TagType_LONG:
+			result[tag.GetKey()] = tag.GetVLong()
+		case jaeger.TagType_BINARY:
+			result[tag.GetKey()] = base64.StdEncoding.EncodeToString(tag.GetVBinary())
+		}
+	}
+	return result
+}
@@ -0,0 +1,776 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/pkg/kubernetes"
+)
+
+// errKubernetesNotConfigured 未找到可用的集群连接配置
+var errKubernetesNotConfigured = errors.New("kubernetes is not configured: set monitoring.kubernetes.kubeconfig or run inside a cluster")
+
+// kubernetesClient 基于Kubernetes API Server与metrics-server的客户端实现
+type kubernetesClient struct {
+	client *kubernetes.Client
+	// err 自动探测配置失败的原因，非nil时所有调用返回该错误
+	err error
+}
+
+// NewKubernetesClient 创建Kubernetes客户端
+// 显式配置（in_cluster或kubeconfig）错误时返回错误；自动探测失败时返回不可用的客户端，避免阻止服务启动
+func NewKubernetesClient(cfg config.KubernetesConfig) (KubernetesClient, error) {
+	restConfig, explicit, err := resolveKubernetesConfig(cfg)
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+		return &kubernetesClient{err: err}, nil
+	}
+
+	client, err := newKubernetesClient(restConfig, cfg.Timeout)
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+		return &kubernetesClient{err: err}, nil
+	}
+	return client, nil
+}
+
+// newKubernetesClient 根据连接配置创建客户端
+func newKubernetesClient(restConfig *kubernetes.Config, timeout time.Duration) (KubernetesClient, error) {
+	restConfig.Timeout = timeout
+	client, err := kubernetes.NewClient(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kubernetesClient{client: client}, nil
+}
+
+// resolveKubernetesConfig 按 in_cluster、kubeconfig、集群内环境、默认kubeconfig 的顺序确定连接配置
+func resolveKubernetesConfig(cfg config.KubernetesConfig) (*kubernetes.Config, bool, error) {
+	if cfg.InCluster {
+		restConfig, err := kubernetes.InClusterConfig()
+		return restConfig, true, err
+	}
+	if cfg.Kubeconfig != "" {
+		restConfig, err := loadKubeconfigContext(cfg.Kubeconfig, cfg.Context)
+		return restConfig, true, err
+	}
+	if kubernetes.IsInCluster() {
+		restConfig, err := kubernetes.InClusterConfig()
+		return restConfig, false, err
+	}
+
+	path := kubernetes.DefaultKubeconfigPath()
+	if path == "" {
+		return nil, false, errKubernetesNotConfigured
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, false, errKubernetesNotConfigured
+	}
+	restConfig, err := loadKubeconfigContext(path, cfg.Context)
+	return restConfig, false, err
+}
+
+// loadKubeconfigContext 读取kubeconfig并生成指定上下文的连接配置
+func loadKubeconfigContext(path, contextName string) (*kubernetes.Config, error) {
+	kubeconfig, err := kubernetes.LoadKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+	restConfig, err := kubeconfig.ClientConfig(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig %s: %w", path, err)
+	}
+	return restConfig, nil
+}
+
+// ready 检查客户端是否可用
+func (c *kubernetesClient) ready() error {
+	if c.client == nil {
+		if c.err != nil {
+			return c.err
+		}
+		return errKubernetesNotConfigured
+	}
+	return nil
+}
+
+// Ping 检查API Server就绪状态并返回版本号
+func (c *kubernetesClient) Ping(ctx context.Context) (string, error) {
+	if err := c.ready(); err != nil {
+		return "", err
+	}
+	if err := c.client.Readyz(ctx, ""); err != nil {
+		return "", err
+	}
+	version, err := c.client.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return version.GitVersion, nil
+}
+
+func (c *kubernetesClient) ListPods(ctx context.Context, namespace string) ([]KubernetesPod, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	pods, err := c.client.ListPods(ctx, namespace, kubernetes.ListOptions{Limit: 500})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]KubernetesPod, 0, len(pods))
+	for i := range pods {
+		result = append(result, toKubernetesPod(&pods[i]))
+	}
+	return result, nil
+}
+
+func (c *kubernetesClient) GetPod(ctx context.Context, namespace, name string) (*KubernetesPodDetail, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	pod, err := c.client.GetPod(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &KubernetesPodDetail{
+		KubernetesPod: toKubernetesPod(pod),
+		Conditions:    make([]PodCondition, 0, len(pod.Status.Conditions)),
+		Events:        []PodEvent{},
+		Volumes:       make([]PodVolume, 0, len(pod.Spec.Volumes)),
+	}
+	for _, cond := range pod.Status.Conditions {
+		detail.Conditions = append(detail.Conditions, PodCondition{
+			Type:               cond.Type,
+			Status:             cond.Status,
+			LastProbeTime:      cond.LastProbeTime,
+			LastTransitionTime: cond.LastTransitionTime,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+		})
+	}
+	for _, v := range pod.Spec.Volumes {
+		detail.Volumes = append(detail.Volumes, toPodVolume(v))
+	}
+
+	// 事件读取失败（如缺少events权限）不影响Pod详情
+	events, err := c.client.ListEvents(ctx, namespace, kubernetes.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + name,
+	})
+	if err == nil {
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Timestamp().Before(events[j].Timestamp())
+		})
+		for _, e := range events {
+			if e.InvolvedObject.UID != "" && e.InvolvedObject.UID != pod.Metadata.UID {
+				// 同名Pod重建前遗留的事件
+				continue
+			}
+			count := e.Count
+			if count == 0 {
+				count = 1
+			}
+			detail.Events = append(detail.Events, PodEvent{
+				Type:      e.Type,
+				Reason:    e.Reason,
+				Message:   e.Message,
+				Timestamp: e.Timestamp(),
+				Count:     count,
+			})
+		}
+	}
+	return detail, nil
+}
+
+// GetPodResourceUsage 结合Pod规格的requests/limits与metrics-server的实时使用量
+func (c *kubernetesClient) GetPodResourceUsage(ctx context.Context, namespace, name string) (*PodResourceUsage, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	pod, err := c.client.GetPod(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := c.client.GetPodMetrics(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics from metrics-server: %w", err)
+	}
+
+	requests, limits := podResourceTotals(pod)
+	var cpuUsed, memUsed float64
+	for _, ct := range metrics.Containers {
+		cpuUsed += ct.Usage.Quantity("cpu")
+		memUsed += ct.Usage.Quantity("memory")
+	}
+
+	return &PodResourceUsage{
+		CPU:    newResourceUsage(cpuUsed, requests["cpu"], limits["cpu"]),
+		Memory: newResourceUsage(memUsed, requests["memory"], limits["memory"]),
+	}, nil
+}
+
+func (c *kubernetesClient) ListNodes(ctx context.Context) ([]KubernetesNode, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	nodes, err := c.client.ListNodes(ctx, kubernetes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]KubernetesNode, 0, len(nodes))
+	for i := range nodes {
+		result = append(result, toKubernetesNode(&nodes[i]))
+	}
+	return result, nil
+}
+
+func (c *kubernetesClient) GetNode(ctx context.Context, name string) (*KubernetesNodeDetail, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	node, err := c.client.GetNode(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &KubernetesNodeDetail{
+		KubernetesNode: toKubernetesNode(node),
+		Conditions:     make([]NodeCondition, 0, len(node.Status.Conditions)),
+		Addresses:      make([]NodeAddress, 0, len(node.Status.Addresses)),
+		SystemInfo: NodeSystemInfo{
+			MachineID:               node.Status.NodeInfo.MachineID,
+			SystemUUID:              node.Status.NodeInfo.SystemUUID,
+			BootID:                  node.Status.NodeInfo.BootID,
+			KernelVersion:           node.Status.NodeInfo.KernelVersion,
+			OSImage:                 node.Status.NodeInfo.OSImage,
+			ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+			KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+			KubeProxyVersion:        node.Status.NodeInfo.KubeProxyVersion,
+			OperatingSystem:         node.Status.NodeInfo.OperatingSystem,
+			Architecture:            node.Status.NodeInfo.Architecture,
+		},
+		Pods: []KubernetesPod{},
+	}
+	for _, cond := range node.Status.Conditions {
+		detail.Conditions = append(detail.Conditions, NodeCondition{
+			Type:               cond.Type,
+			Status:             cond.Status,
+			LastHeartbeatTime:  cond.LastHeartbeatTime,
+			LastTransitionTime: cond.LastTransitionTime,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+		})
+	}
+	for _, addr := range node.Status.Addresses {
+		detail.Addresses = append(detail.Addresses, NodeAddress{Type: addr.Type, Address: addr.Address})
+	}
+
+	pods, err := c.client.ListPods(ctx, "", kubernetes.ListOptions{FieldSelector: "spec.nodeName=" + name, Limit: 500})
+	if err != nil {
+		return nil, err
+	}
+
+	// 节点使用率以allocatable为上限，requests统计未结束的Pod
+	var cpuRequested, memRequested float64
+	activePods := 0
+	for i := range pods {
+		detail.Pods = append(detail.Pods, toKubernetesPod(&pods[i]))
+		if podTerminated(&pods[i]) {
+			continue
+		}
+		activePods++
+		requests, _ := podResourceTotals(&pods[i])
+		cpuRequested += requests["cpu"]
+		memRequested += requests["memory"]
+	}
+
+	allocatable := node.Status.Allocatable
+	usage := &NodeResourceUsage{
+		CPU:     newResourceUsage(0, cpuRequested, allocatable.Quantity("cpu")),
+		Memory:  newResourceUsage(0, memRequested, allocatable.Quantity("memory")),
+		Storage: newResourceUsage(0, 0, allocatable.Quantity("ephemeral-storage")),
+		Pods:    newResourceUsage(float64(activePods), 0, allocatable.Quantity("pods")),
+	}
+	// 未安装metrics-server时只返回requests
+	if metrics, err := c.client.GetNodeMetrics(ctx, name); err == nil {
+		usage.CPU = newResourceUsage(metrics.Usage.Quantity("cpu"), cpuRequested, allocatable.Quantity("cpu"))
+		usage.Memory = newResourceUsage(metrics.Usage.Quantity("memory"), memRequested, allocatable.Quantity("memory"))
+	}
+	detail.ResourceUsage = usage
+
+	return detail, nil
+}
+
+func (c *kubernetesClient) ListNamespaces(ctx context.Context) ([]KubernetesNamespace, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	namespaces, err := c.client.ListNamespaces(ctx, kubernetes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]KubernetesNamespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		result = append(result, KubernetesNamespace{
+			Name:        ns.Metadata.Name,
+			UID:         ns.Metadata.UID,
+			Labels:      ns.Metadata.Labels,
+			Annotations: ns.Metadata.Annotations,
+			Phase:       ns.Status.Phase,
+			Created:     ns.Metadata.CreationTimestamp,
+		})
+	}
+	return result, nil
+}
+
+// clusterWorkloadPaths 集群范围的工作负载集合路径
+var clusterWorkloadPaths = map[string]string{
+	"deployments":  "/apis/apps/v1/deployments",
+	"statefulsets": "/apis/apps/v1/statefulsets",
+	"daemonsets":   "/apis/apps/v1/daemonsets",
+	"jobs":         "/apis/batch/v1/jobs",
+	"cronjobs":     "/apis/batch/v1/cronjobs",
+	"services":     "/api/v1/services",
+	"ingresses":    "/apis/networking.k8s.io/v1/ingresses",
+}
+
+func (c *kubernetesClient) GetClusterMetrics(ctx context.Context) (*KubernetesClusterMetrics, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := c.client.ListNodes(ctx, kubernetes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := c.client.ListPods(ctx, "", kubernetes.ListOptions{Limit: 500})
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := c.client.ListNamespaces(ctx, kubernetes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &KubernetesClusterMetrics{}
+
+	metrics.Nodes.Total = len(nodes)
+	for i := range nodes {
+		if nodeReady(&nodes[i]) {
+			metrics.Nodes.Ready++
+		} else {
+			metrics.Nodes.NotReady++
+		}
+		if !nodes[i].Spec.Unschedulable {
+			metrics.Nodes.Schedulable++
+		}
+	}
+
+	metrics.Pods.Total = len(pods)
+	for i := range pods {
+		switch pods[i].Status.Phase {
+		case "Running":
+			metrics.Pods.Running++
+		case "Pending":
+			metrics.Pods.Pending++
+		case "Succeeded":
+			metrics.Pods.Succeeded++
+		case "Failed":
+			metrics.Pods.Failed++
+		}
+	}
+
+	metrics.Namespaces.Total = len(namespaces)
+	for _, ns := range namespaces {
+		if ns.Status.Phase == "Active" {
+			metrics.Namespaces.Active++
+		}
+	}
+
+	resources, err := c.resourceMetrics(ctx, nodes, pods, "")
+	if err != nil {
+		return nil, err
+	}
+	metrics.Resources = *resources
+
+	// 工作负载数量读取失败（如缺少权限或API未启用）时记为0
+	counts := make(map[string]int, len(clusterWorkloadPaths))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for kind, path := range clusterWorkloadPaths {
+		wg.Add(1)
+		go func(kind, path string) {
+			defer wg.Done()
+			count, err := c.client.CountResources(ctx, path)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			counts[kind] = count
+			mu.Unlock()
+		}(kind, path)
+	}
+	wg.Wait()
+	metrics.Workloads = ClusterWorkloadMetrics{
+		Deployments:  counts["deployments"],
+		StatefulSets: counts["statefulsets"],
+		DaemonSets:   counts["daemonsets"],
+		Jobs:         counts["jobs"],
+		CronJobs:     counts["cronjobs"],
+		Services:     counts["services"],
+		Ingresses:    counts["ingresses"],
+	}
+
+	metrics.Health = c.clusterHealth(ctx, metrics.Nodes)
+	return metrics, nil
+}
+
+// GetResourceUsage 汇总集群或单个命名空间的资源请求与实时使用量，容量取节点allocatable之和
+func (c *kubernetesClient) GetResourceUsage(ctx context.Context, namespace string) (*ClusterResourceMetrics, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	nodes, err := c.client.ListNodes(ctx, kubernetes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := c.client.ListPods(ctx, namespace, kubernetes.ListOptions{Limit: 500})
+	if err != nil {
+		return nil, err
+	}
+	return c.resourceMetrics(ctx, nodes, pods, namespace)
+}
+
+// resourceMetrics 计算资源容量、请求量与使用量，namespace非空时使用量只统计该命名空间
+func (c *kubernetesClient) resourceMetrics(ctx context.Context, nodes []kubernetes.Node, pods []kubernetes.Pod, namespace string) (*ClusterResourceMetrics, error) {
+	var result ClusterResourceMetrics
+	for i := range nodes {
+		capacity, allocatable := nodes[i].Status.Capacity, nodes[i].Status.Allocatable
+		result.CPU.Capacity += capacity.Quantity("cpu")
+		result.CPU.Allocatable += allocatable.Quantity("cpu")
+		result.Memory.Capacity += capacity.Quantity("memory")
+		result.Memory.Allocatable += allocatable.Quantity("memory")
+		result.Storage.Capacity += capacity.Quantity("ephemeral-storage")
+		result.Storage.Allocatable += allocatable.Quantity("ephemeral-storage")
+		result.Pods.Capacity += capacity.Quantity("pods")
+		result.Pods.Allocatable += allocatable.Quantity("pods")
+	}
+
+	for i := range pods {
+		if podTerminated(&pods[i]) {
+			continue
+		}
+		requests, _ := podResourceTotals(&pods[i])
+		result.CPU.Requested += requests["cpu"]
+		result.Memory.Requested += requests["memory"]
+		result.Storage.Requested += requests["ephemeral-storage"]
+		result.Pods.Requested++
+	}
+	result.Pods.Used = result.Pods.Requested
+
+	// 集群范围优先使用节点指标，包含系统进程开销；命名空间范围只能累加Pod指标
+	if namespace == "" {
+		if nodeMetrics, err := c.client.ListNodeMetrics(ctx); err == nil {
+			for _, m := range nodeMetrics {
+				result.CPU.Used += m.Usage.Quantity("cpu")
+				result.Memory.Used += m.Usage.Quantity("memory")
+			}
+		}
+	} else if podMetrics, err := c.client.ListPodMetrics(ctx, namespace, kubernetes.ListOptions{}); err == nil {
+		for _, m := range podMetrics {
+			for _, ct := range m.Containers {
+				result.CPU.Used += ct.Usage.Quantity("cpu")
+				result.Memory.Used += ct.Usage.Quantity("memory")
+			}
+		}
+	}
+
+	result.CPU.Percent = percentOf(result.CPU.Used, result.CPU.Allocatable)
+	result.Memory.Percent = percentOf(result.Memory.Used, result.Memory.Allocatable)
+	// metrics-server不提供临时存储使用量，存储按请求量计算占比
+	result.Storage.Percent = percentOf(result.Storage.Requested, result.Storage.Allocatable)
+	result.Pods.Percent = percentOf(result.Pods.Used, result.Pods.Allocatable)
+	return &result, nil
+}
+
+// clusterHealth 检查控制平面健康状态
+// API Server与etcd通过/readyz检查；调度器与控制器管理器通过选主租约是否按时续约判断，读取不到租约时回退到componentstatuses
+func (c *kubernetesClient) clusterHealth(ctx context.Context, nodes ClusterNodeMetrics) ClusterHealthMetrics {
+	health := ClusterHealthMetrics{
+		APIServerHealth: c.client.Readyz(ctx, "") == nil,
+		EtcdHealth:      c.client.Readyz(ctx, "etcd") == nil,
+	}
+
+	now := time.Now()
+	leaseHealthy := func(name string) (bool, bool) {
+		lease, err := c.client.GetLease(ctx, "kube-system", name)
+		if err != nil {
+			return false, false
+		}
+		return !lease.Expired(now), true
+	}
+	scheduler, schedulerKnown := leaseHealthy("kube-scheduler")
+	controller, controllerKnown := leaseHealthy("kube-controller-manager")
+	if !schedulerKnown || !controllerKnown {
+		if statuses, err := c.client.ComponentStatuses(ctx); err == nil {
+			if v, ok := statuses["scheduler"]; ok && !schedulerKnown {
+				scheduler = v
+			}
+			if v, ok := statuses["controller-manager"]; ok && !controllerKnown {
+				controller = v
+			}
+		}
+	}
+	health.SchedulerHealth = scheduler
+	health.ControllerHealth = controller
+
+	// 控制平面组件与节点就绪率各占50分
+	healthyComponents := 0
+	for _, ok := range []bool{health.APIServerHealth, health.EtcdHealth, health.SchedulerHealth, health.ControllerHealth} {
+		if ok {
+			healthyComponents++
+		}
+	}
+	score := float64(healthyComponents) / 4 * 50
+	if nodes.Total > 0 {
+		score += float64(nodes.Ready) / float64(nodes.Total) * 50
+	}
+	health.HealthScore = score
+
+	switch {
+	case score >= 90:
+		health.OverallHealth = "healthy"
+	case score >= 60:
+		health.OverallHealth = "degraded"
+	default:
+		health.OverallHealth = "unhealthy"
+	}
+	return health
+}
+
+func (c *kubernetesClient) ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) error {
+	if err := c.ready(); err != nil {
+		return err
+	}
+	_, err := c.client.ScaleDeployment(ctx, namespace, name, replicas)
+	return err
+}
+
+// toKubernetesPod 合并Pod规格与容器状态
+func toKubernetesPod(pod *kubernetes.Pod) KubernetesPod {
+	result := KubernetesPod{
+		Name:        pod.Metadata.Name,
+		Namespace:   pod.Metadata.Namespace,
+		UID:         pod.Metadata.UID,
+		Phase:       pod.Status.Phase,
+		NodeName:    pod.Spec.NodeName,
+		PodIP:       pod.Status.PodIP,
+		HostIP:      pod.Status.HostIP,
+		Created:     pod.Metadata.CreationTimestamp,
+		Labels:      pod.Metadata.Labels,
+		Annotations: pod.Metadata.Annotations,
+		Containers:  make([]PodContainer, 0, len(pod.Spec.Containers)),
+	}
+	if pod.Status.StartTime != nil {
+		result.Started = *pod.Status.StartTime
+	}
+
+	statuses := make(map[string]kubernetes.ContainerStatus, len(pod.Status.ContainerStatuses))
+	for _, st := range pod.Status.ContainerStatuses {
+		statuses[st.Name] = st
+	}
+	for _, ct := range pod.Spec.Containers {
+		container := PodContainer{
+			Name:  ct.Name,
+			Image: ct.Image,
+			Resources: PodContainerResources{
+				Requests: toResourceList(ct.Resources.Requests),
+				Limits:   toResourceList(ct.Resources.Limits),
+			},
+			Ports:        make([]ContainerPort, 0, len(ct.Ports)),
+			Env:          make([]ContainerEnvVar, 0, len(ct.Env)),
+			VolumeMounts: make([]ContainerVolumeMount, 0, len(ct.VolumeMounts)),
+		}
+		for _, p := range ct.Ports {
+			container.Ports = append(container.Ports, ContainerPort{
+				PrivatePort: p.ContainerPort,
+				PublicPort:  p.HostPort,
+				Type:        strings.ToLower(p.Protocol),
+				IP:          p.HostIP,
+			})
+		}
+		// valueFrom引用的ConfigMap/Secret不展开
+		for _, e := range ct.Env {
+			container.Env = append(container.Env, ContainerEnvVar{Name: e.Name, Value: e.Value})
+		}
+		for _, m := range ct.VolumeMounts {
+			container.VolumeMounts = append(container.VolumeMounts, ContainerVolumeMount{
+				Name:      m.Name,
+				MountPath: m.MountPath,
+				ReadOnly:  m.ReadOnly,
+				SubPath:   m.SubPath,
+			})
+		}
+		if st, ok := statuses[ct.Name]; ok {
+			container.ImageID = st.ImageID
+			container.ContainerID = st.ContainerID
+			container.Ready = st.Ready
+			container.RestartCount = st.RestartCount
+			container.State = toContainerState(st.State)
+		}
+		result.Containers = append(result.Containers, container)
+	}
+	return result
+}
+
+func toContainerState(state kubernetes.ContainerState) ContainerState {
+	var result ContainerState
+	if state.Waiting != nil {
+		result.Waiting = &ContainerStateWaiting{Reason: state.Waiting.Reason, Message: state.Waiting.Message}
+	}
+	if state.Running != nil {
+		result.Running = &ContainerStateRunning{StartedAt: state.Running.StartedAt}
+	}
+	if t := state.Terminated; t != nil {
+		result.Terminated = &ContainerStateTerminated{
+			ExitCode:    t.ExitCode,
+			Signal:      t.Signal,
+			Reason:      t.Reason,
+			Message:     t.Message,
+			StartedAt:   t.StartedAt,
+			FinishedAt:  t.FinishedAt,
+			ContainerID: t.ContainerID,
+		}
+	}
+	return result
+}
+
+func toPodVolume(v kubernetes.Volume) PodVolume {
+	volume := PodVolume{Name: v.Name, Type: "other"}
+	switch {
+	case v.HostPath != nil:
+		volume.Type = "hostPath"
+		volume.Source.HostPath = &HostPathVolumeSource{Path: v.HostPath.Path, Type: v.HostPath.Type}
+	case v.EmptyDir != nil:
+		volume.Type = "emptyDir"
+		volume.Source.EmptyDir = &EmptyDirVolumeSource{Medium: v.EmptyDir.Medium, SizeLimit: v.EmptyDir.SizeLimit}
+	case v.ConfigMap != nil:
+		volume.Type = "configMap"
+		volume.Source.ConfigMap = &ConfigMapVolumeSource{Name: v.ConfigMap.Name, DefaultMode: derefInt32(v.ConfigMap.DefaultMode)}
+	case v.Secret != nil:
+		volume.Type = "secret"
+		volume.Source.Secret = &SecretVolumeSource{SecretName: v.Secret.SecretName, DefaultMode: derefInt32(v.Secret.DefaultMode)}
+	case v.PersistentVolumeClaim != nil:
+		volume.Type = "persistentVolumeClaim"
+		volume.Source.PersistentVolumeClaim = &PVCVolumeSource{ClaimName: v.PersistentVolumeClaim.ClaimName, ReadOnly: v.PersistentVolumeClaim.ReadOnly}
+	}
+	return volume
+}
+
+func toKubernetesNode(node *kubernetes.Node) KubernetesNode {
+	return KubernetesNode{
+		Name:        node.Metadata.Name,
+		UID:         node.Metadata.UID,
+		Labels:      node.Metadata.Labels,
+		Annotations: node.Metadata.Annotations,
+		Created:     node.Metadata.CreationTimestamp,
+		Ready:       nodeReady(node),
+		Schedulable: !node.Spec.Unschedulable,
+		Version: NodeVersion{
+			KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+			KubeProxyVersion: node.Status.NodeInfo.KubeProxyVersion,
+			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			OperatingSystem:  node.Status.NodeInfo.OperatingSystem,
+			Architecture:     node.Status.NodeInfo.Architecture,
+		},
+		Capacity:    toResourceList(node.Status.Capacity),
+		Allocatable: toResourceList(node.Status.Allocatable),
+	}
+}
+
+func toResourceList(list kubernetes.ResourceList) ResourceList {
+	return ResourceList{
+		CPU:     list["cpu"],
+		Memory:  list["memory"],
+		Storage: list["ephemeral-storage"],
+	}
+}
+
+// podResourceTotals 计算Pod的有效requests与limits
+// 与调度器一致：取普通容器之和与单个init容器的较大值
+func podResourceTotals(pod *kubernetes.Pod) (map[string]float64, map[string]float64) {
+	sum := func(get func(kubernetes.Container) kubernetes.ResourceList) map[string]float64 {
+		totals := map[string]float64{}
+		for _, ct := range pod.Spec.Containers {
+			list := get(ct)
+			for name := range list {
+				totals[name] += list.Quantity(name)
+			}
+		}
+		for _, ct := range pod.Spec.InitContainers {
+			list := get(ct)
+			for name := range list {
+				if v := list.Quantity(name); v > totals[name] {
+					totals[name] = v
+				}
+			}
+		}
+		return totals
+	}
+	requests := sum(func(ct kubernetes.Container) kubernetes.ResourceList { return ct.Resources.Requests })
+	limits := sum(func(ct kubernetes.Container) kubernetes.ResourceList { return ct.Resources.Limits })
+	return requests, limits
+}
+
+// podTerminated 已结束的Pod不再占用节点资源
+func podTerminated(pod *kubernetes.Pod) bool {
+	return pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed"
+}
+
+func nodeReady(node *kubernetes.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
+
+// newResourceUsage 使用率优先以limit为分母，未设置limit时以request为分母
+func newResourceUsage(used, requested, limit float64) ResourceUsage {
+	usage := ResourceUsage{Used: used, Requested: requested, Limit: limit}
+	if limit > 0 {
+		usage.Percent = percentOf(used, limit)
+	} else {
+		usage.Percent = percentOf(used, requested)
+	}
+	return usage
+}
+
+func percentOf(value, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return value / total * 100
+}
+
+func derefInt32(v *int32) int32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,720 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+	"ai-monitor/internal/utils"
+	"ai-monitor/pkg/kubernetes"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AllClusters cluster参数取该值时跨全部已启用集群聚合查询
+const AllClusters = "all"
+
+var (
+	// ErrClusterNotFound 集群不存在
+	ErrClusterNotFound = errors.New("kubernetes cluster not found")
+	// ErrAllClustersUnsupported 接口不支持跨集群聚合
+	ErrAllClustersUnsupported = errors.New("cluster=all is only supported for pods, nodes and namespaces")
+)
+
+// KubernetesClusterService Kubernetes集群注册表，按集群维护API客户端
+type KubernetesClusterService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	crypto       *utils.CryptoUtils
+	stringUtils  *utils.StringUtils
+
+	mu sync.Mutex
+	// clients 按集群ID缓存客户端，集群更新时失效
+	clients map[uuid.UUID]*clusterClient
+}
+
+// clusterClient 缓存的集群客户端，updatedAt用于判断集群配置是否变更
+type clusterClient struct {
+	client    KubernetesClient
+	updatedAt time.Time
+}
+
+// kubernetesClusterCredentials 加密存储的集群凭据
+type kubernetesClusterCredentials struct {
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Token      string `json:"token,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	CAData     string `json:"ca_data,omitempty"`
+}
+
+// KubernetesClusterRequest 注册或更新集群请求，凭据字段为空时更新操作保留原值
+type KubernetesClusterRequest struct {
+	Name                  string            `json:"name"`
+	Description           string            `json:"description"`
+	AuthType              string            `json:"auth_type"`
+	Server                string            `json:"server"`
+	Context               string            `json:"context"`
+	InsecureSkipTLSVerify *bool             `json:"insecure_skip_tls_verify"`
+	Kubeconfig            string            `json:"kubeconfig"`
+	Token                 string            `json:"token"`
+	ClientCert            string            `json:"client_cert"`
+	ClientKey             string            `json:"client_key"`
+	CAData                string            `json:"ca_data"`
+	Labels                map[string]string `json:"labels"`
+	Enabled               *bool             `json:"enabled"`
+	IsDefault             bool              `json:"is_default"`
+}
+
+// PartialClusterError 跨集群查询时部分集群失败，已成功集群的数据仍然返回
+type PartialClusterError struct {
+	Failed map[string]string
+}
+
+func (e *PartialClusterError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, e.Failed[name]))
+	}
+	return "failed to query kubernetes clusters: " + strings.Join(parts, "; ")
+}
+
+// Warnings 返回按集群名排序的失败信息
+func (e *PartialClusterError) Warnings() []string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	warnings := make([]string, 0, len(names))
+	for _, name := range names {
+		warnings = append(warnings, fmt.Sprintf("cluster %s: %s", name, e.Failed[name]))
+	}
+	return warnings
+}
+
+// NewKubernetesClusterService 创建集群注册表服务
+func NewKubernetesClusterService(db *gorm.DB, cacheManager *cache.CacheManager, cfg *config.Config) *KubernetesClusterService {
+	return &KubernetesClusterService{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       cfg,
+		crypto:       &utils.CryptoUtils{},
+		stringUtils:  &utils.StringUtils{},
+		clients:      make(map[uuid.UUID]*clusterClient),
+	}
+}
+
+// EnsureConfigCluster 将monitoring.kubernetes配置注册为集群
+// 未显式配置且自动探测失败（如未部署在集群内且没有kubeconfig）时不注册
+func (s *KubernetesClusterService) EnsureConfigCluster() error {
+	var count int64
+	if err := s.db.Unscoped().Model(&models.KubernetesCluster{}).Where("auth_type = ?", "config").Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count config clusters: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	restConfig, explicit, err := resolveKubernetesConfig(s.config.Monitoring.Kubernetes)
+	if err != nil {
+		if explicit {
+			return err
+		}
+		return nil
+	}
+
+	name := s.config.Monitoring.Kubernetes.ClusterName
+	if name == "" {
+		name = "default"
+	}
+	var existing int64
+	if err := s.db.Model(&models.KubernetesCluster{}).Where("name = ?", name).Count(&existing).Error; err != nil {
+		return fmt.Errorf("failed to check cluster name: %w", err)
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	var defaults int64
+	if err := s.db.Model(&models.KubernetesCluster{}).Where("is_default = ?", true).Count(&defaults).Error; err != nil {
+		return fmt.Errorf("failed to count default clusters: %w", err)
+	}
+
+	cluster := &models.KubernetesCluster{
+		Name:        name,
+		Description: "由服务端monitoring.kubernetes配置注册",
+		AuthType:    "config",
+		Server:      restConfig.Host,
+		Context:     restConfig.ContextName,
+		Enabled:     true,
+		IsDefault:   defaults == 0,
+		Status:      "unknown",
+		Labels:      "{}",
+	}
+	if err := s.db.Create(cluster).Error; err != nil {
+		return fmt.Errorf("failed to register config cluster: %w", err)
+	}
+	return nil
+}
+
+// ListClusters 获取已注册集群
+func (s *KubernetesClusterService) ListClusters() ([]models.KubernetesCluster, error) {
+	var clusters []models.KubernetesCluster
+	if err := s.db.Order("is_default DESC, name ASC").Find(&clusters).Error; err != nil {
+		return nil, fmt.Errorf("failed to list kubernetes clusters: %w", err)
+	}
+	return clusters, nil
+}
+
+// GetCluster 按ID或名称获取集群，key为空时返回默认集群
+func (s *KubernetesClusterService) GetCluster(key string) (*models.KubernetesCluster, error) {
+	var cluster models.KubernetesCluster
+	query := s.db.Model(&models.KubernetesCluster{})
+	if key == "" {
+		// 未指定时使用默认集群；只注册了一个集群时即使未标记默认也使用它
+		err := query.Where("is_default = ?", true).First(&cluster).Error
+		if err == nil {
+			return &cluster, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get default cluster: %w", err)
+		}
+		var clusters []models.KubernetesCluster
+		if err := s.db.Limit(2).Find(&clusters).Error; err != nil {
+			return nil, fmt.Errorf("failed to get default cluster: %w", err)
+		}
+		if len(clusters) != 1 {
+			return nil, fmt.Errorf("%w: no default cluster, specify the cluster parameter", ErrClusterNotFound)
+		}
+		return &clusters[0], nil
+	}
+
+	if id, err := uuid.Parse(key); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("name = ?", key)
+	}
+	if err := query.First(&cluster).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrClusterNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to get kubernetes cluster: %w", err)
+	}
+	return &cluster, nil
+}
+
+// CreateCluster 注册集群，创建后立即执行一次健康检查
+func (s *KubernetesClusterService) CreateCluster(ctx context.Context, req *KubernetesClusterRequest, userID uuid.UUID) (*models.KubernetesCluster, error) {
+	if req.Name == "" {
+		return nil, errors.New("cluster name is required")
+	}
+	if req.Name == AllClusters {
+		return nil, fmt.Errorf("cluster name %q is reserved", AllClusters)
+	}
+
+	cluster := &models.KubernetesCluster{
+		Name:        req.Name,
+		Description: req.Description,
+		AuthType:    req.AuthType,
+		Server:      req.Server,
+		Context:     req.Context,
+		Enabled:     true,
+		IsDefault:   req.IsDefault,
+		Status:      "unknown",
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}
+	if req.InsecureSkipTLSVerify != nil {
+		cluster.InsecureSkipTLSVerify = *req.InsecureSkipTLSVerify
+	}
+	if req.Enabled != nil {
+		cluster.Enabled = *req.Enabled
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode labels: %w", err)
+	}
+	cluster.Labels = string(labels)
+	if req.Labels == nil {
+		cluster.Labels = "{}"
+	}
+
+	creds := kubernetesClusterCredentials{
+		Kubeconfig: req.Kubeconfig,
+		Token:      req.Token,
+		ClientCert: req.ClientCert,
+		ClientKey:  req.ClientKey,
+		CAData:     req.CAData,
+	}
+	if err := s.applyCredentials(cluster, &creds); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if cluster.IsDefault {
+			if err := tx.Model(&models.KubernetesCluster{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(cluster).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes cluster: %w", err)
+	}
+
+	if cluster.Enabled {
+		s.checkCluster(ctx, cluster)
+	}
+	return cluster, nil
+}
+
+// UpdateCluster 更新集群，凭据字段全部为空时保留原凭据
+func (s *KubernetesClusterService) UpdateCluster(ctx context.Context, key string, req *KubernetesClusterRequest, userID uuid.UUID) (*models.KubernetesCluster, error) {
+	cluster, err := s.GetCluster(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" && req.Name != cluster.Name {
+		if req.Name == AllClusters {
+			return nil, fmt.Errorf("cluster name %q is reserved", AllClusters)
+		}
+		cluster.Name = req.Name
+	}
+	if req.Description != "" {
+		cluster.Description = req.Description
+	}
+	if req.AuthType != "" {
+		cluster.AuthType = req.AuthType
+	}
+	if req.Server != "" {
+		cluster.Server = req.Server
+	}
+	if req.Context != "" {
+		cluster.Context = req.Context
+	}
+	if req.InsecureSkipTLSVerify != nil {
+		cluster.InsecureSkipTLSVerify = *req.InsecureSkipTLSVerify
+	}
+	if req.Enabled != nil {
+		cluster.Enabled = *req.Enabled
+	}
+	if req.Labels != nil {
+		labels, err := json.Marshal(req.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode labels: %w", err)
+		}
+		cluster.Labels = string(labels)
+	}
+	cluster.IsDefault = cluster.IsDefault || req.IsDefault
+	cluster.UpdatedBy = userID
+
+	creds := kubernetesClusterCredentials{
+		Kubeconfig: req.Kubeconfig,
+		Token:      req.Token,
+		ClientCert: req.ClientCert,
+		ClientKey:  req.ClientKey,
+		CAData:     req.CAData,
+	}
+	if creds == (kubernetesClusterCredentials{}) {
+		existing, err := s.decryptCredentials(cluster)
+		if err != nil {
+			return nil, err
+		}
+		creds = *existing
+	}
+	if err := s.applyCredentials(cluster, &creds); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.IsDefault {
+			if err := tx.Model(&models.KubernetesCluster{}).Where("is_default = ? AND id <> ?", true, cluster.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(cluster).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update kubernetes cluster: %w", err)
+	}
+
+	s.invalidate(cluster.ID)
+	s.clearCache(cluster.Name)
+	if cluster.Enabled {
+		s.checkCluster(ctx, cluster)
+	}
+	return cluster, nil
+}
+
+// DeleteCluster 删除集群，凭据随记录物理删除
+func (s *KubernetesClusterService) DeleteCluster(key string) error {
+	cluster, err := s.GetCluster(key)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Unscoped().Delete(&models.KubernetesCluster{}, "id = ?", cluster.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete kubernetes cluster: %w", err)
+	}
+	s.invalidate(cluster.ID)
+	s.clearCache(cluster.Name)
+	return nil
+}
+
+// Client 获取集群客户端，key为集群名称或ID，为空时使用默认集群
+func (s *KubernetesClusterService) Client(key string) (KubernetesClient, *models.KubernetesCluster, error) {
+	cluster, err := s.GetCluster(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cluster.Enabled {
+		return nil, nil, fmt.Errorf("kubernetes cluster %s is disabled", cluster.Name)
+	}
+	client, err := s.clientFor(cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, cluster, nil
+}
+
+// EnabledClusters 获取全部已启用集群
+func (s *KubernetesClusterService) EnabledClusters() ([]models.KubernetesCluster, error) {
+	var clusters []models.KubernetesCluster
+	if err := s.db.Where("enabled = ?", true).Order("name ASC").Find(&clusters).Error; err != nil {
+		return nil, fmt.Errorf("failed to list kubernetes clusters: %w", err)
+	}
+	return clusters, nil
+}
+
+// ForEachCluster 对指定集群执行fn，key为all时并发遍历全部已启用集群
+// 跨集群时部分失败返回*PartialClusterError，全部失败时返回普通错误
+func (s *KubernetesClusterService) ForEachCluster(ctx context.Context, key string, fn func(ctx context.Context, cluster *models.KubernetesCluster, client KubernetesClient) error) error {
+	if key != AllClusters {
+		client, cluster, err := s.Client(key)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, cluster, client)
+	}
+
+	clusters, err := s.EnabledClusters()
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return fmt.Errorf("%w: no enabled clusters", ErrClusterNotFound)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]string)
+	for i := range clusters {
+		wg.Add(1)
+		go func(cluster *models.KubernetesCluster) {
+			defer wg.Done()
+			client, err := s.clientFor(cluster)
+			if err == nil {
+				err = fn(ctx, cluster, client)
+			}
+			if err != nil {
+				mu.Lock()
+				failed[cluster.Name] = err.Error()
+				mu.Unlock()
+			}
+		}(&clusters[i])
+	}
+	wg.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+	partial := &PartialClusterError{Failed: failed}
+	if len(failed) == len(clusters) {
+		return errors.New(partial.Error())
+	}
+	return partial
+}
+
+// CheckCluster 立即检查集群健康状态并更新记录
+func (s *KubernetesClusterService) CheckCluster(ctx context.Context, key string) (*models.KubernetesCluster, error) {
+	cluster, err := s.GetCluster(key)
+	if err != nil {
+		return nil, err
+	}
+	s.checkCluster(ctx, cluster)
+	return cluster, nil
+}
+
+// RunHealthChecks 按health_check_interval周期检查全部已启用集群
+func (s *KubernetesClusterService) RunHealthChecks(ctx context.Context, cfg config.KubernetesConfig) {
+	ticker := time.NewTicker(cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	s.checkAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll 并发检查全部已启用集群
+func (s *KubernetesClusterService) checkAll(ctx context.Context) {
+	clusters, err := s.EnabledClusters()
+	if err != nil {
+		logger.GetLogger("kubernetes").WithError(err).Error("failed to list enabled clusters")
+		return
+	}
+	var wg sync.WaitGroup
+	for i := range clusters {
+		wg.Add(1)
+		go func(cluster *models.KubernetesCluster) {
+			defer wg.Done()
+			s.checkCluster(ctx, cluster)
+		}(&clusters[i])
+	}
+	wg.Wait()
+}
+
+// checkCluster 检查API Server就绪状态、版本与节点数，结果写回集群记录
+func (s *KubernetesClusterService) checkCluster(ctx context.Context, cluster *models.KubernetesCluster) {
+	now := time.Now()
+	updates := map[string]interface{}{"last_checked_at": now}
+
+	client, err := s.clientFor(cluster)
+	var version string
+	var nodes []KubernetesNode
+	if err == nil {
+		version, err = client.Ping(ctx)
+	}
+	if err == nil {
+		nodes, err = client.ListNodes(ctx)
+	}
+	if err != nil {
+		updates["status"] = "unhealthy"
+		updates["last_error"] = s.stringUtils.Truncate(err.Error(), 1000)
+	} else {
+		updates["status"] = "healthy"
+		updates["last_error"] = ""
+		updates["version"] = version
+		updates["node_count"] = len(nodes)
+	}
+
+	// 使用UpdateColumns避免刷新updated_at导致客户端缓存失效
+	if err := s.db.Model(&models.KubernetesCluster{}).Where("id = ?", cluster.ID).UpdateColumns(updates).Error; err != nil {
+		// 不影响返回的检查结果
+		logger.GetLogger("kubernetes").WithError(err).WithField("cluster", cluster.Name).Warn("failed to save cluster health")
+	}
+
+	cluster.LastCheckedAt = &now
+	cluster.Status = updates["status"].(string)
+	cluster.LastError = updates["last_error"].(string)
+	if v, ok := updates["version"].(string); ok {
+		cluster.Version = v
+		cluster.NodeCount = len(nodes)
+	}
+}
+
+// clientFor 返回缓存的集群客户端，集群配置变更后重新创建
+func (s *KubernetesClusterService) clientFor(cluster *models.KubernetesCluster) (KubernetesClient, error) {
+	s.mu.Lock()
+	cached, ok := s.clients[cluster.ID]
+	s.mu.Unlock()
+	if ok && cached.updatedAt.Equal(cluster.UpdatedAt) {
+		return cached.client, nil
+	}
+
+	client, err := s.buildClient(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for cluster %s: %w", cluster.Name, err)
+	}
+
+	s.mu.Lock()
+	s.clients[cluster.ID] = &clusterClient{client: client, updatedAt: cluster.UpdatedAt}
+	s.mu.Unlock()
+	return client, nil
+}
+
+// apiClient 返回集群底层的API客户端，供list+watch等不经过KubernetesClient接口的场景使用
+func (s *KubernetesClusterService) apiClient(cluster *models.KubernetesCluster) (*kubernetes.Client, error) {
+	client, err := s.clientFor(cluster)
+	if err != nil {
+		return nil, err
+	}
+	kc, ok := client.(*kubernetesClient)
+	if !ok {
+		return nil, fmt.Errorf("cluster %s does not expose an api client", cluster.Name)
+	}
+	if err := kc.ready(); err != nil {
+		return nil, err
+	}
+	return kc.client, nil
+}
+
+// buildClient 根据认证方式构造客户端
+func (s *KubernetesClusterService) buildClient(cluster *models.KubernetesCluster) (KubernetesClient, error) {
+	timeout := s.config.Monitoring.Kubernetes.Timeout
+	if cluster.AuthType == "config" {
+		return NewKubernetesClient(s.config.Monitoring.Kubernetes)
+	}
+
+	restConfig, err := s.restConfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return newKubernetesClient(restConfig, timeout)
+}
+
+// restConfig 解密凭据并生成连接配置
+func (s *KubernetesClusterService) restConfig(cluster *models.KubernetesCluster) (*kubernetes.Config, error) {
+	if cluster.AuthType == "in_cluster" {
+		return kubernetes.InClusterConfig()
+	}
+
+	creds, err := s.decryptCredentials(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return clusterRestConfig(cluster, creds)
+}
+
+// clusterRestConfig 按认证方式校验数据库中保存的凭据并生成连接配置
+func clusterRestConfig(cluster *models.KubernetesCluster, creds *kubernetesClusterCredentials) (*kubernetes.Config, error) {
+	switch cluster.AuthType {
+	case "kubeconfig":
+		if creds.Kubeconfig == "" {
+			return nil, errors.New("kubeconfig is required")
+		}
+		kubeconfig, err := kubernetes.ParseKubeconfig([]byte(creds.Kubeconfig))
+		if err != nil {
+			return nil, err
+		}
+		restConfig, err := kubeconfig.ClientConfig(cluster.Context)
+		if err != nil {
+			return nil, err
+		}
+		if cluster.InsecureSkipTLSVerify {
+			restConfig.Insecure = true
+		}
+		return restConfig, nil
+	case "token", "client_cert":
+		if cluster.Server == "" {
+			return nil, errors.New("server is required")
+		}
+		restConfig := &kubernetes.Config{
+			Host:     cluster.Server,
+			CAData:   []byte(creds.CAData),
+			Insecure: cluster.InsecureSkipTLSVerify,
+		}
+		if cluster.AuthType == "token" {
+			if creds.Token == "" {
+				return nil, errors.New("token is required")
+			}
+			restConfig.BearerToken = creds.Token
+		} else {
+			if creds.ClientCert == "" || creds.ClientKey == "" {
+				return nil, errors.New("client_cert and client_key are required")
+			}
+			restConfig.CertData = []byte(creds.ClientCert)
+			restConfig.KeyData = []byte(creds.ClientKey)
+		}
+		return restConfig, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %s", cluster.AuthType)
+	}
+}
+
+// applyCredentials 校验凭据能生成有效连接配置后加密写入集群记录
+func (s *KubernetesClusterService) applyCredentials(cluster *models.KubernetesCluster, creds *kubernetesClusterCredentials) error {
+	switch cluster.AuthType {
+	case "config", "in_cluster":
+		// 凭据来自服务端配置或Pod挂载，不在数据库中保存
+		cluster.Credentials = ""
+		return nil
+	case "kubeconfig", "token", "client_cert":
+	default:
+		return fmt.Errorf("unsupported auth type: %s", cluster.AuthType)
+	}
+
+	restConfig, err := clusterRestConfig(cluster, creds)
+	if err != nil {
+		return fmt.Errorf("invalid cluster credentials: %w", err)
+	}
+	// 提前校验证书格式，避免保存后才在连接时失败
+	if _, err := kubernetes.NewClient(restConfig); err != nil {
+		return fmt.Errorf("invalid cluster credentials: %w", err)
+	}
+	if cluster.AuthType == "kubeconfig" {
+		cluster.Server = restConfig.Host
+		cluster.Context = restConfig.ContextName
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to encode cluster credentials: %w", err)
+	}
+	encrypted, err := s.crypto.Encrypt(string(data), s.config.Security.Encryption.Key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt cluster credentials: %w", err)
+	}
+	cluster.Credentials = encrypted
+	return nil
+}
+
+// decryptCredentials 解密集群凭据
+func (s *KubernetesClusterService) decryptCredentials(cluster *models.KubernetesCluster) (*kubernetesClusterCredentials, error) {
+	var creds kubernetesClusterCredentials
+	if cluster.Credentials == "" {
+		return &creds, nil
+	}
+	plaintext, err := s.crypto.Decrypt(cluster.Credentials, s.config.Security.Encryption.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials of cluster %s: %w", cluster.Name, err)
+	}
+	if err := json.Unmarshal([]byte(plaintext), &creds); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials of cluster %s: %w", cluster.Name, err)
+	}
+	return &creds, nil
+}
+
+// invalidate 移除缓存的集群客户端
+func (s *KubernetesClusterService) invalidate(id uuid.UUID) {
+	s.mu.Lock()
+	delete(s.clients, id)
+	s.mu.Unlock()
+}
+
+// clearCache 清除集群相关的查询缓存
+func (s *KubernetesClusterService) clearCache(name string) {
+	if s.cacheManager == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, key := range []string{
+		fmt.Sprintf("k8s_nodes:%s", name),
+		fmt.Sprintf("k8s_namespaces:%s", name),
+		fmt.Sprintf("k8s_cluster_metrics:%s", name),
+	} {
+		s.cacheManager.Delete(ctx, key)
+	}
+}
@@ -0,0 +1,601 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/models"
+	"ai-monitor/pkg/kubernetes"
+)
+
+// Kubernetes检测器告警使用的指标名
+const (
+	// KubernetesMetricPodCrashLoop 取值为容器重启次数
+	KubernetesMetricPodCrashLoop = "k8s.pod_crash_loop"
+	// KubernetesMetricPodImagePull 取值为镜像拉取失败的容器数
+	KubernetesMetricPodImagePull = "k8s.pod_image_pull_backoff"
+	// KubernetesMetricPodPending 取值为Pod处于Pending的秒数
+	KubernetesMetricPodPending = "k8s.pod_pending_seconds"
+	// KubernetesMetricNodeNotReady 取值为节点处于NotReady的秒数
+	KubernetesMetricNodeNotReady = "k8s.node_not_ready_seconds"
+	// KubernetesMetricJobFailed Job失败时取值为1
+	KubernetesMetricJobFailed = "k8s.job_failed"
+	// KubernetesMetricDeploymentUnavailable 取值为不可用副本数
+	KubernetesMetricDeploymentUnavailable = "k8s.deployment_unavailable_replicas"
+)
+
+const (
+	// crashLoopResolveDelay 容器离开CrashLoopBackOff后保持告警的时间，避免退避重启期间反复触发和恢复
+	crashLoopResolveDelay = 10 * time.Minute
+	// jobFailedLookback 只对该时间内失败的Job告警，保留的历史失败Job不会持续告警
+	jobFailedLookback = 24 * time.Hour
+	// detectorEventLimit 告警证据中附带的最近Warning事件数
+	detectorEventLimit = 5
+)
+
+// imagePullFailureReasons 镜像拉取失败的等待原因
+var imagePullFailureReasons = map[string]bool{
+	"ImagePullBackOff":  true,
+	"ErrImagePull":      true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// builtinKubernetesEventRules 内置Kubernetes检测器规则，Duration为触发前条件需持续满足的秒数，Threshold与Duration可在规则中调整
+var builtinKubernetesEventRules = []models.AlertRule{
+	{
+		Name:        "Kubernetes pod crash looping",
+		Description: "容器处于CrashLoopBackOff且重启次数达到Threshold",
+		Metric:      KubernetesMetricPodCrashLoop,
+		Condition:   ">=",
+		Threshold:   3,
+		Duration:    60,
+		Severity:    "critical",
+	},
+	{
+		Name:        "Kubernetes image pull failing",
+		Description: "容器镜像拉取失败（ImagePullBackOff、ErrImagePull等）持续Duration秒",
+		Metric:      KubernetesMetricPodImagePull,
+		Condition:   ">=",
+		Threshold:   1,
+		Duration:    120,
+		Severity:    "high",
+	},
+	{
+		Name:        "Kubernetes pod pending too long",
+		Description: "Pod处于Pending状态超过Threshold秒",
+		Metric:      KubernetesMetricPodPending,
+		Condition:   ">=",
+		Threshold:   600,
+		Duration:    60,
+		Severity:    "medium",
+	},
+	{
+		Name:        "Kubernetes node not ready",
+		Description: "节点Ready条件非True超过Threshold秒",
+		Metric:      KubernetesMetricNodeNotReady,
+		Condition:   ">=",
+		Threshold:   60,
+		Duration:    60,
+		Severity:    "critical",
+	},
+	{
+		Name:        "Kubernetes job failed",
+		Description: "Job在24小时内进入Failed状态",
+		Metric:      KubernetesMetricJobFailed,
+		Condition:   ">=",
+		Threshold:   1,
+		Duration:    60,
+		Severity:    "high",
+	},
+	{
+		Name:        "Kubernetes deployment replicas unavailable",
+		Description: "Deployment不可用副本数达到Threshold并持续Duration秒",
+		Metric:      KubernetesMetricDeploymentUnavailable,
+		Condition:   ">=",
+		Threshold:   1,
+		Duration:    300,
+		Severity:    "high",
+	},
+}
+
+// kubernetesFinding 检测器发现的单个异常对象
+type kubernetesFinding struct {
+	Kind        string // Pod、Node、Job、Deployment
+	Namespace   string
+	Name        string
+	Node        string
+	Value       float64
+	Summary     string
+	Description string
+	Details     map[string]interface{}
+}
+
+// kubernetesDetector 内置检测器，基于单个集群的资源缓存产出异常对象
+type kubernetesDetector struct {
+	metric string
+	detect func(s *KubernetesInventoryService, inv *clusterInventory, now time.Time) []kubernetesFinding
+}
+
+// kubernetesDetectors 全部内置检测器
+var kubernetesDetectors = []kubernetesDetector{
+	{metric: KubernetesMetricPodCrashLoop, detect: (*KubernetesInventoryService).detectCrashLoops},
+	{metric: KubernetesMetricPodImagePull, detect: (*KubernetesInventoryService).detectImagePullFailures},
+	{metric: KubernetesMetricPodPending, detect: (*KubernetesInventoryService).detectPendingPods},
+	{metric: KubernetesMetricNodeNotReady, detect: (*KubernetesInventoryService).detectNotReadyNodes},
+	{metric: KubernetesMetricJobFailed, detect: (*KubernetesInventoryService).detectFailedJobs},
+	{metric: KubernetesMetricDeploymentUnavailable, detect: (*KubernetesInventoryService).detectUnavailableDeployments},
+}
+
+// EnsureBuiltinRules 写入内置Kubernetes检测器规则，已存在（含已删除）的规则不会被覆盖
+func (s *KubernetesInventoryService) EnsureBuiltinRules() error {
+	for _, builtin := range builtinKubernetesEventRules {
+		var count int64
+		if err := s.db.Unscoped().Model(&models.AlertRule{}).
+			Where("kind = ? AND metric = ?", "event", builtin.Metric).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check kubernetes event rule: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		rule := builtin
+		rule.Kind = "event"
+		rule.Enabled = true
+		if err := s.db.Create(&rule).Error; err != nil {
+			return fmt.Errorf("failed to seed kubernetes event rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// Detect 在全部已同步的集群上运行内置检测器，触发新告警并恢复不再满足条件的告警
+func (s *KubernetesInventoryService) Detect(now time.Time) error {
+	rules, err := s.detectorRules()
+	if err != nil {
+		return err
+	}
+
+	s.detectMu.Lock()
+	defer s.detectMu.Unlock()
+
+	var errs []error
+	for _, inv := range s.snapshot() {
+		// 未同步完成的缓存可能缺少对象，此时既不触发也不恢复
+		if !inv.synced() {
+			continue
+		}
+		for _, detector := range kubernetesDetectors {
+			rule := rules[detector.metric]
+			if rule == nil {
+				continue
+			}
+			if err := s.evaluateDetector(inv, rule, detector, now); err != nil {
+				errs = append(errs, fmt.Errorf("cluster %s %s: %w", inv.name, detector.metric, err))
+			}
+		}
+	}
+	s.pruneCrashLoopSeen(now)
+	return errors.Join(errs...)
+}
+
+// evaluateDetector 评估单个集群上的一个检测器
+func (s *KubernetesInventoryService) evaluateDetector(inv *clusterInventory, rule *models.AlertRule, detector kubernetesDetector, now time.Time) error {
+	violating := make(map[string]bool)
+	if rule.Enabled {
+		for _, finding := range detector.detect(s, inv, now) {
+			data := &MetricData{
+				TargetType: "kubernetes",
+				TargetID:   kubernetesTargetID(inv.name, finding.Kind, finding.Namespace, finding.Name),
+				MetricName: rule.Metric,
+				Value:      finding.Value,
+				Tags: map[string]interface{}{
+					"cluster":   inv.name,
+					"kind":      finding.Kind,
+					"namespace": finding.Namespace,
+					"name":      finding.Name,
+					"node":      finding.Node,
+				},
+				Timestamp: now,
+			}
+			if !ruleLabelsMatch(rule, data.Tags) || !s.alertService.evaluateCondition(rule.Condition, data.Value, rule.Threshold) {
+				continue
+			}
+			violating[data.TargetID] = true
+
+			// 条件需持续满足Duration秒后才触发
+			key := rule.ID.String() + "|" + data.TargetID
+			since, ok := s.pendingSince[key]
+			if !ok {
+				since = now
+				s.pendingSince[key] = now
+			}
+			if now.Sub(since) < time.Duration(rule.Duration)*time.Second {
+				continue
+			}
+
+			details := map[string]interface{}{
+				"cluster":   inv.name,
+				"kind":      finding.Kind,
+				"namespace": finding.Namespace,
+				"name":      finding.Name,
+				"since":     since,
+			}
+			if finding.Node != "" {
+				details["node"] = finding.Node
+			}
+			for k, v := range finding.Details {
+				details[k] = v
+			}
+			if events := recentWarningEvents(inv, finding.Kind, finding.Namespace, finding.Name); len(events) > 0 {
+				details["events"] = events
+			}
+
+			if err := s.alertService.fireEvaluatedAlert(rule, data, &evaluatedAlert{
+				Summary:     finding.Summary,
+				Description: finding.Description,
+				Details:     details,
+				Reference:   rule.Threshold,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	prefix := rule.ID.String() + "|" + inv.name + "/"
+	for key := range s.pendingSince {
+		if strings.HasPrefix(key, prefix) && !violating[strings.TrimPrefix(key, rule.ID.String()+"|")] {
+			delete(s.pendingSince, key)
+		}
+	}
+	return s.resolveRuleAlerts(rule, inv.name, violating)
+}
+
+// resolveRuleAlerts 恢复集群下不在violating中的告警，violating为nil时恢复该集群的全部告警
+func (s *KubernetesInventoryService) resolveRuleAlerts(rule *models.AlertRule, cluster string, violating map[string]bool) error {
+	var alerts []models.Alert
+	if err := s.db.Where("rule_id = ? AND status = ?", rule.ID, "firing").Find(&alerts).Error; err != nil {
+		return fmt.Errorf("failed to query firing alerts: %w", err)
+	}
+
+	for _, alert := range alerts {
+		var labels map[string]interface{}
+		if err := json.Unmarshal([]byte(alert.Labels), &labels); err != nil {
+			continue
+		}
+		targetID := fmt.Sprint(labels["target_id"])
+		if !strings.HasPrefix(targetID, cluster+"/") || violating[targetID] {
+			continue
+		}
+		if err := s.alertService.resolveEvaluatedAlert(rule, &MetricData{
+			TargetType: "kubernetes",
+			TargetID:   targetID,
+			MetricName: rule.Metric,
+			Timestamp:  time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveClusterAlerts 集群不再被监控时恢复其下全部检测器告警
+func (s *KubernetesInventoryService) resolveClusterAlerts(cluster string) error {
+	rules, err := s.detectorRules()
+	if err != nil {
+		return err
+	}
+
+	s.detectMu.Lock()
+	defer s.detectMu.Unlock()
+	for _, rule := range rules {
+		prefix := rule.ID.String() + "|" + cluster + "/"
+		for key := range s.pendingSince {
+			if strings.HasPrefix(key, prefix) {
+				delete(s.pendingSince, key)
+			}
+		}
+		if err := s.resolveRuleAlerts(rule, cluster, nil); err != nil {
+			return err
+		}
+	}
+	for target := range s.crashLoopSeen {
+		if strings.HasPrefix(target, cluster+"/") {
+			delete(s.crashLoopSeen, target)
+		}
+	}
+	return nil
+}
+
+// detectorRules 按指标获取内置检测器规则，包含已禁用的规则以便恢复其告警
+func (s *KubernetesInventoryService) detectorRules() (map[string]*models.AlertRule, error) {
+	metrics := make([]string, 0, len(kubernetesDetectors))
+	for _, detector := range kubernetesDetectors {
+		metrics = append(metrics, detector.metric)
+	}
+
+	var rules []models.AlertRule
+	if err := s.db.Where("kind = ? AND metric IN ?", "event", metrics).Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to query kubernetes event rules: %w", err)
+	}
+	result := make(map[string]*models.AlertRule, len(rules))
+	for i := range rules {
+		result[rules[i].Metric] = &rules[i]
+	}
+	return result, nil
+}
+
+// detectCrashLoops 检测处于CrashLoopBackOff的容器，离开退避状态后在crashLoopResolveDelay内仍视为异常
+func (s *KubernetesInventoryService) detectCrashLoops(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, pod := range inv.pods.List("") {
+		if podTerminated(&pod) {
+			continue
+		}
+		target := kubernetesTargetID(inv.name, "Pod", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		var looping []string
+		var restarts int32
+		var lastTermination *kubernetes.ContainerStateTerminated
+		for _, status := range podContainerStatuses(&pod) {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+				looping = append(looping, status.Name)
+			}
+			if status.RestartCount > restarts {
+				restarts = status.RestartCount
+				lastTermination = status.LastState.Terminated
+			}
+		}
+
+		if len(looping) > 0 {
+			s.crashLoopSeen[target] = now
+		} else if seen, ok := s.crashLoopSeen[target]; !ok || now.Sub(seen) >= crashLoopResolveDelay {
+			continue
+		}
+
+		details := map[string]interface{}{
+			"restarts":           restarts,
+			"looping_containers": looping,
+			"phase":              pod.Status.Phase,
+		}
+		if lastTermination != nil {
+			details["last_exit_code"] = lastTermination.ExitCode
+			details["last_exit_reason"] = lastTermination.Reason
+			details["last_finished_at"] = lastTermination.FinishedAt
+		}
+		findings = append(findings, kubernetesFinding{
+			Kind:      "Pod",
+			Namespace: pod.Metadata.Namespace,
+			Name:      pod.Metadata.Name,
+			Node:      pod.Spec.NodeName,
+			Value:     float64(restarts),
+			Summary: fmt.Sprintf("Pod %s/%s in cluster %s is crash looping (%d restarts)",
+				pod.Metadata.Namespace, pod.Metadata.Name, inv.name, restarts),
+			Description: fmt.Sprintf("Containers of pod %s/%s are in CrashLoopBackOff after %d restarts",
+				pod.Metadata.Namespace, pod.Metadata.Name, restarts),
+			Details: details,
+		})
+	}
+	return findings
+}
+
+// detectImagePullFailures 检测镜像拉取失败的Pod
+func (s *KubernetesInventoryService) detectImagePullFailures(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, pod := range inv.pods.List("") {
+		if podTerminated(&pod) {
+			continue
+		}
+
+		var failures []map[string]interface{}
+		for _, status := range podContainerStatuses(&pod) {
+			waiting := status.State.Waiting
+			if waiting == nil || !imagePullFailureReasons[waiting.Reason] {
+				continue
+			}
+			failures = append(failures, map[string]interface{}{
+				"container": status.Name,
+				"image":     status.Image,
+				"reason":    waiting.Reason,
+				"message":   waiting.Message,
+			})
+		}
+		if len(failures) == 0 {
+			continue
+		}
+
+		findings = append(findings, kubernetesFinding{
+			Kind:      "Pod",
+			Namespace: pod.Metadata.Namespace,
+			Name:      pod.Metadata.Name,
+			Node:      pod.Spec.NodeName,
+			Value:     float64(len(failures)),
+			Summary: fmt.Sprintf("Pod %s/%s in cluster %s cannot pull %s",
+				pod.Metadata.Namespace, pod.Metadata.Name, inv.name, failures[0]["image"]),
+			Description: fmt.Sprintf("%d container(s) of pod %s/%s are waiting with %s",
+				len(failures), pod.Metadata.Namespace, pod.Metadata.Name, failures[0]["reason"]),
+			Details: map[string]interface{}{"containers": failures},
+		})
+	}
+	return findings
+}
+
+// detectPendingPods 检测处于Pending的Pod，取值为自创建以来的秒数
+func (s *KubernetesInventoryService) detectPendingPods(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, pod := range inv.pods.List("") {
+		if pod.Status.Phase != "Pending" || pod.Metadata.DeletionTimestamp != nil {
+			continue
+		}
+
+		pending := now.Sub(pod.Metadata.CreationTimestamp)
+		details := map[string]interface{}{
+			"pending": formatWindow(pending),
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == "PodScheduled" && cond.Status != "True" {
+				details["scheduling_reason"] = cond.Reason
+				details["scheduling_message"] = cond.Message
+			}
+		}
+
+		findings = append(findings, kubernetesFinding{
+			Kind:      "Pod",
+			Namespace: pod.Metadata.Namespace,
+			Name:      pod.Metadata.Name,
+			Node:      pod.Spec.NodeName,
+			Value:     pending.Seconds(),
+			Summary: fmt.Sprintf("Pod %s/%s in cluster %s has been pending for %s",
+				pod.Metadata.Namespace, pod.Metadata.Name, inv.name, formatWindow(pending)),
+			Description: fmt.Sprintf("Pod %s/%s has not started running %s after creation",
+				pod.Metadata.Namespace, pod.Metadata.Name, formatWindow(pending)),
+			Details: details,
+		})
+	}
+	return findings
+}
+
+// detectNotReadyNodes 检测Ready条件非True的节点，取值为自状态变化以来的秒数
+func (s *KubernetesInventoryService) detectNotReadyNodes(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, node := range inv.nodes.List("") {
+		if nodeReady(&node) {
+			continue
+		}
+
+		since := node.Metadata.CreationTimestamp
+		details := map[string]interface{}{
+			"unschedulable": node.Spec.Unschedulable,
+		}
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == "Ready" {
+				since = cond.LastTransitionTime
+				details["status"] = cond.Status
+				details["reason"] = cond.Reason
+				details["message"] = cond.Message
+				details["last_heartbeat"] = cond.LastHeartbeatTime
+			}
+		}
+		notReady := now.Sub(since)
+
+		findings = append(findings, kubernetesFinding{
+			Kind:        "Node",
+			Name:        node.Metadata.Name,
+			Node:        node.Metadata.Name,
+			Value:       notReady.Seconds(),
+			Summary:     fmt.Sprintf("Node %s in cluster %s has been NotReady for %s", node.Metadata.Name, inv.name, formatWindow(notReady)),
+			Description: fmt.Sprintf("Ready condition of node %s has not been True for %s", node.Metadata.Name, formatWindow(notReady)),
+			Details:     details,
+		})
+	}
+	return findings
+}
+
+// detectFailedJobs 检测jobFailedLookback内失败的Job
+func (s *KubernetesInventoryService) detectFailedJobs(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, job := range inv.jobs.List("") {
+		cond := job.Condition("Failed")
+		if cond == nil || now.Sub(cond.LastTransitionTime) > jobFailedLookback {
+			continue
+		}
+
+		findings = append(findings, kubernetesFinding{
+			Kind:      "Job",
+			Namespace: job.Metadata.Namespace,
+			Name:      job.Metadata.Name,
+			Value:     1,
+			Summary: fmt.Sprintf("Job %s/%s in cluster %s failed: %s",
+				job.Metadata.Namespace, job.Metadata.Name, inv.name, cond.Reason),
+			Description: fmt.Sprintf("Job %s/%s failed with %d failed pod(s): %s",
+				job.Metadata.Namespace, job.Metadata.Name, job.Status.Failed, cond.Message),
+			Details: map[string]interface{}{
+				"reason":    cond.Reason,
+				"message":   cond.Message,
+				"failed_at": cond.LastTransitionTime,
+				"failed":    job.Status.Failed,
+				"succeeded": job.Status.Succeeded,
+			},
+		})
+	}
+	return findings
+}
+
+// detectUnavailableDeployments 检测存在不可用副本的Deployment，已暂停或缩容到0的除外
+func (s *KubernetesInventoryService) detectUnavailableDeployments(inv *clusterInventory, now time.Time) []kubernetesFinding {
+	var findings []kubernetesFinding
+	for _, d := range inv.deployments.List("") {
+		desired := deploymentDesiredReplicas(&d)
+		if desired == 0 || d.Spec.Paused {
+			continue
+		}
+		unavailable := deploymentUnavailableReplicas(&d)
+		if unavailable == 0 {
+			continue
+		}
+
+		findings = append(findings, kubernetesFinding{
+			Kind:      "Deployment",
+			Namespace: d.Metadata.Namespace,
+			Name:      d.Metadata.Name,
+			Value:     float64(unavailable),
+			Summary: fmt.Sprintf("Deployment %s/%s in cluster %s has %d/%d replicas unavailable",
+				d.Metadata.Namespace, d.Metadata.Name, inv.name, unavailable, desired),
+			Description: fmt.Sprintf("Deployment %s/%s wants %d replicas but only %d are available",
+				d.Metadata.Namespace, d.Metadata.Name, desired, d.Status.AvailableReplicas),
+			Details: map[string]interface{}{
+				"desired":   desired,
+				"available": d.Status.AvailableReplicas,
+				"ready":     d.Status.ReadyReplicas,
+				"updated":   d.Status.UpdatedReplicas,
+				"message":   workloadConditionMessage(d.Status.Conditions, "Available"),
+			},
+		})
+	}
+	return findings
+}
+
+// pruneCrashLoopSeen 清理已过期的CrashLoopBackOff记录
+func (s *KubernetesInventoryService) pruneCrashLoopSeen(now time.Time) {
+	for target, seen := range s.crashLoopSeen {
+		if now.Sub(seen) >= crashLoopResolveDelay {
+			delete(s.crashLoopSeen, target)
+		}
+	}
+}
+
+// recentWarningEvents 对象最近的Warning事件，作为告警证据
+func recentWarningEvents(inv *clusterInventory, kind, namespace, name string) []KubernetesEvent {
+	var events []KubernetesEvent
+	for _, event := range inv.events.List(namespace) {
+		if event.Type != "Warning" || event.InvolvedObject.Kind != kind || event.InvolvedObject.Name != name {
+			continue
+		}
+		events = append(events, toKubernetesEvent(&event))
+	}
+	sortKubernetesEvents(events)
+	if len(events) > detectorEventLimit {
+		events = events[:detectorEventLimit]
+	}
+	return events
+}
+
+// podContainerStatuses 返回Pod的初始化容器与业务容器状态
+func podContainerStatuses(pod *kubernetes.Pod) []kubernetes.ContainerStatus {
+	statuses := make([]kubernetes.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
+
+// kubernetesTargetID 告警目标ID：集群/类型/命名空间/名称，集群范围资源省略命名空间
+func kubernetesTargetID(cluster, kind, namespace, name string) string {
+	parts := []string{cluster, strings.ToLower(kind)}
+	if namespace != "" {
+		parts = append(parts, namespace)
+	}
+	return strings.Join(append(parts, name), "/")
+}
@@ -0,0 +1,569 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+	"ai-monitor/pkg/kubernetes"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInventoryNotSynced 集群资源缓存未启用或尚未完成首次同步
+var ErrInventoryNotSynced = errors.New("kubernetes inventory is not synced")
+
+// 工作负载类型
+const (
+	WorkloadDeployment  = "deployment"
+	WorkloadStatefulSet = "statefulset"
+	WorkloadDaemonSet   = "daemonset"
+	WorkloadJob         = "job"
+)
+
+// KubernetesInventoryService 按集群通过list+watch维护Pod、节点、工作负载与事件的内存缓存，并运行内置检测器
+type KubernetesInventoryService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	clusters     *KubernetesClusterService
+	alertService *AlertService
+
+	mu          sync.RWMutex
+	inventories map[uuid.UUID]*clusterInventory
+
+	// detectMu 保护检测器的跨周期状态
+	detectMu      sync.Mutex
+	pendingSince  map[string]time.Time // 规则ID|目标 -> 首次满足条件的时间
+	crashLoopSeen map[string]time.Time // 目标 -> 最近一次处于CrashLoopBackOff的时间
+}
+
+// clusterInventory 单个集群的资源缓存
+type clusterInventory struct {
+	clusterID uuid.UUID
+	name      string
+	updatedAt time.Time
+	startedAt time.Time
+	cancel    context.CancelFunc
+
+	pods         *kubernetes.Informer[kubernetes.Pod]
+	nodes        *kubernetes.Informer[kubernetes.Node]
+	deployments  *kubernetes.Informer[kubernetes.Deployment]
+	statefulSets *kubernetes.Informer[kubernetes.StatefulSet]
+	daemonSets   *kubernetes.Informer[kubernetes.DaemonSet]
+	jobs         *kubernetes.Informer[kubernetes.Job]
+	events       *kubernetes.Informer[kubernetes.Event]
+}
+
+// KubernetesInventoryStatus 集群资源缓存状态
+type KubernetesInventoryStatus struct {
+	ClusterID uuid.UUID                            `json:"cluster_id"`
+	Cluster   string                               `json:"cluster"`
+	Synced    bool                                 `json:"synced"`
+	StartedAt time.Time                            `json:"started_at"`
+	Resources map[string]kubernetes.InformerStatus `json:"resources"`
+}
+
+// KubernetesWorkload 工作负载概要
+type KubernetesWorkload struct {
+	Cluster     string            `json:"cluster,omitempty"`
+	Kind        string            `json:"kind"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	UID         string            `json:"uid"`
+	Labels      map[string]string `json:"labels"`
+	Desired     int32             `json:"desired"`
+	Ready       int32             `json:"ready"`
+	Available   int32             `json:"available"`
+	Unavailable int32             `json:"unavailable"`
+	Updated     int32             `json:"updated"`
+	Active      int32             `json:"active,omitempty"`
+	Succeeded   int32             `json:"succeeded,omitempty"`
+	Failed      int32             `json:"failed,omitempty"`
+	Status      string            `json:"status"` // healthy, progressing, degraded, running, complete, failed
+	Message     string            `json:"message,omitempty"`
+	Created     time.Time         `json:"created"`
+}
+
+// KubernetesEvent 集群事件
+type KubernetesEvent struct {
+	Cluster           string    `json:"cluster,omitempty"`
+	Namespace         string    `json:"namespace"`
+	Name              string    `json:"name"`
+	Type              string    `json:"type"`
+	Reason            string    `json:"reason"`
+	Message           string    `json:"message"`
+	InvolvedKind      string    `json:"involved_kind"`
+	InvolvedNamespace string    `json:"involved_namespace,omitempty"`
+	InvolvedName      string    `json:"involved_name"`
+	Count             int32     `json:"count"`
+	FirstSeen         time.Time `json:"first_seen"`
+	LastSeen          time.Time `json:"last_seen"`
+}
+
+// KubernetesEventFilter 事件查询条件，Kind与Name匹配事件关联的对象
+type KubernetesEventFilter struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Type      string
+	Limit     int
+}
+
+// NewKubernetesInventoryService 创建Kubernetes资源缓存服务
+func NewKubernetesInventoryService(db *gorm.DB, cacheManager *cache.CacheManager, cfg *config.Config, clusters *KubernetesClusterService, alertService *AlertService) *KubernetesInventoryService {
+	return &KubernetesInventoryService{
+		db:            db,
+		cacheManager:  cacheManager,
+		config:        cfg,
+		clusters:      clusters,
+		alertService:  alertService,
+		inventories:   make(map[uuid.UUID]*clusterInventory),
+		pendingSince:  make(map[string]time.Time),
+		crashLoopSeen: make(map[string]time.Time),
+	}
+}
+
+// RunInventory 为已启用集群维护资源缓存并周期运行检测器，直到ctx取消
+func (s *KubernetesInventoryService) RunInventory(ctx context.Context, cfg config.KubernetesInventoryConfig) {
+	if err := s.EnsureBuiltinRules(); err != nil {
+		logger.GetLogger("kubernetes").WithError(err).Error("failed to ensure builtin kubernetes rules")
+	}
+
+	syncInterval := cfg.ClusterSyncInterval
+	if syncInterval <= 0 {
+		syncInterval = time.Minute
+	}
+	detectInterval := cfg.DetectInterval
+	if detectInterval <= 0 {
+		detectInterval = 30 * time.Second
+	}
+
+	s.syncClusters(ctx, cfg)
+
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	detectTicker := time.NewTicker(detectInterval)
+	defer detectTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.stopAll()
+			return
+		case <-syncTicker.C:
+			s.syncClusters(ctx, cfg)
+		case <-detectTicker.C:
+			if err := s.Detect(time.Now()); err != nil {
+				logger.GetLogger("kubernetes").WithError(err).Error("failed to run kubernetes detectors")
+			}
+		}
+	}
+}
+
+// syncClusters 按集群注册表启动、重启或停止资源缓存
+func (s *KubernetesInventoryService) syncClusters(ctx context.Context, cfg config.KubernetesInventoryConfig) {
+	clusters, err := s.clusters.EnabledClusters()
+	if err != nil {
+		logger.GetLogger("kubernetes").WithError(err).Error("failed to list enabled clusters")
+		return
+	}
+	enabled := make(map[uuid.UUID]*models.KubernetesCluster, len(clusters))
+	for i := range clusters {
+		enabled[clusters[i].ID] = &clusters[i]
+	}
+
+	s.mu.Lock()
+	var removed []string
+	for id, inv := range s.inventories {
+		cluster, ok := enabled[id]
+		if ok && cluster.UpdatedAt.Equal(inv.updatedAt) {
+			continue
+		}
+		inv.cancel()
+		delete(s.inventories, id)
+		// 集群被删除、停用或改名后，旧名称下的告警不会再被评估
+		if !ok || cluster.Name != inv.name {
+			removed = append(removed, inv.name)
+		}
+	}
+
+	for id, cluster := range enabled {
+		if _, ok := s.inventories[id]; ok {
+			continue
+		}
+		client, err := s.clusters.apiClient(cluster)
+		if err != nil {
+			// 客户端不可用时等待下一轮同步重试
+			continue
+		}
+		inv := newClusterInventory(cluster, client)
+		invCtx, cancel := context.WithCancel(ctx)
+		inv.cancel = cancel
+		inv.run(invCtx, cfg.RetryInterval)
+		s.inventories[id] = inv
+	}
+	s.mu.Unlock()
+
+	for _, name := range removed {
+		if err := s.resolveClusterAlerts(name); err != nil {
+			logger.GetLogger("kubernetes").WithError(err).WithField("cluster", name).Warn("failed to resolve alerts of removed cluster")
+		}
+	}
+}
+
+// stopAll 停止全部集群的资源缓存
+func (s *KubernetesInventoryService) stopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, inv := range s.inventories {
+		inv.cancel()
+		delete(s.inventories, id)
+	}
+}
+
+// Status 返回各集群资源缓存的同步状态，按集群名称排序
+func (s *KubernetesInventoryService) Status() []KubernetesInventoryStatus {
+	inventories := s.snapshot()
+	result := make([]KubernetesInventoryStatus, 0, len(inventories))
+	for _, inv := range inventories {
+		result = append(result, KubernetesInventoryStatus{
+			ClusterID: inv.clusterID,
+			Cluster:   inv.name,
+			Synced:    inv.synced(),
+			StartedAt: inv.startedAt,
+			Resources: map[string]kubernetes.InformerStatus{
+				"pods":         inv.pods.Status(),
+				"nodes":        inv.nodes.Status(),
+				"deployments":  inv.deployments.Status(),
+				"statefulsets": inv.statefulSets.Status(),
+				"daemonsets":   inv.daemonSets.Status(),
+				"jobs":         inv.jobs.Status(),
+				"events":       inv.events.Status(),
+			},
+		})
+	}
+	return result
+}
+
+// Pods 返回缓存中的Pod，缓存未同步时ok为false，调用方应回退到直接查询API
+func (s *KubernetesInventoryService) Pods(clusterID uuid.UUID, namespace string) ([]KubernetesPod, bool) {
+	inv := s.inventory(clusterID)
+	if inv == nil || !inv.pods.HasSynced() {
+		return nil, false
+	}
+	pods := inv.pods.List(namespace)
+	result := make([]KubernetesPod, 0, len(pods))
+	for i := range pods {
+		result = append(result, toKubernetesPod(&pods[i]))
+	}
+	return result, true
+}
+
+// Nodes 返回缓存中的节点，缓存未同步时ok为false
+func (s *KubernetesInventoryService) Nodes(clusterID uuid.UUID) ([]KubernetesNode, bool) {
+	inv := s.inventory(clusterID)
+	if inv == nil || !inv.nodes.HasSynced() {
+		return nil, false
+	}
+	nodes := inv.nodes.List("")
+	result := make([]KubernetesNode, 0, len(nodes))
+	for i := range nodes {
+		result = append(result, toKubernetesNode(&nodes[i]))
+	}
+	return result, true
+}
+
+// Workloads 返回缓存中的工作负载，kind为空时返回全部类型，缓存未同步时ok为false
+func (s *KubernetesInventoryService) Workloads(clusterID uuid.UUID, kind, namespace string) ([]KubernetesWorkload, bool) {
+	inv := s.inventory(clusterID)
+	if inv == nil {
+		return nil, false
+	}
+
+	result := []KubernetesWorkload{}
+	if kind == "" || kind == WorkloadDeployment {
+		if !inv.deployments.HasSynced() {
+			return nil, false
+		}
+		for _, d := range inv.deployments.List(namespace) {
+			result = append(result, deploymentWorkload(&d))
+		}
+	}
+	if kind == "" || kind == WorkloadStatefulSet {
+		if !inv.statefulSets.HasSynced() {
+			return nil, false
+		}
+		for _, sts := range inv.statefulSets.List(namespace) {
+			result = append(result, statefulSetWorkload(&sts))
+		}
+	}
+	if kind == "" || kind == WorkloadDaemonSet {
+		if !inv.daemonSets.HasSynced() {
+			return nil, false
+		}
+		for _, ds := range inv.daemonSets.List(namespace) {
+			result = append(result, daemonSetWorkload(&ds))
+		}
+	}
+	if kind == "" || kind == WorkloadJob {
+		if !inv.jobs.HasSynced() {
+			return nil, false
+		}
+		for _, job := range inv.jobs.List(namespace) {
+			result = append(result, jobWorkload(&job))
+		}
+	}
+	return result, true
+}
+
+// Events 返回缓存中的事件，按最近发生时间倒序，缓存未同步时ok为false
+func (s *KubernetesInventoryService) Events(clusterID uuid.UUID, filter KubernetesEventFilter) ([]KubernetesEvent, bool) {
+	inv := s.inventory(clusterID)
+	if inv == nil || !inv.events.HasSynced() {
+		return nil, false
+	}
+
+	result := []KubernetesEvent{}
+	for _, event := range inv.events.List(filter.Namespace) {
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+		if filter.Kind != "" && !strings.EqualFold(event.InvolvedObject.Kind, filter.Kind) {
+			continue
+		}
+		if filter.Name != "" && event.InvolvedObject.Name != filter.Name {
+			continue
+		}
+		result = append(result, toKubernetesEvent(&event))
+	}
+	sortKubernetesEvents(result)
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, true
+}
+
+// inventory 获取集群的资源缓存，未启动时返回nil
+func (s *KubernetesInventoryService) inventory(clusterID uuid.UUID) *clusterInventory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inventories[clusterID]
+}
+
+// snapshot 返回当前全部集群的资源缓存，按集群名称排序
+func (s *KubernetesInventoryService) snapshot() []*clusterInventory {
+	s.mu.RLock()
+	inventories := make([]*clusterInventory, 0, len(s.inventories))
+	for _, inv := range s.inventories {
+		inventories = append(inventories, inv)
+	}
+	s.mu.RUnlock()
+	sort.Slice(inventories, func(i, j int) bool { return inventories[i].name < inventories[j].name })
+	return inventories
+}
+
+// newClusterInventory 为集群创建各类资源的Informer
+func newClusterInventory(cluster *models.KubernetesCluster, client *kubernetes.Client) *clusterInventory {
+	return &clusterInventory{
+		clusterID:    cluster.ID,
+		name:         cluster.Name,
+		updatedAt:    cluster.UpdatedAt,
+		pods:         kubernetes.NewInformer[kubernetes.Pod](client, kubernetes.PodsPath, kubernetes.ListOptions{}),
+		nodes:        kubernetes.NewInformer[kubernetes.Node](client, kubernetes.NodesPath, kubernetes.ListOptions{}),
+		deployments:  kubernetes.NewInformer[kubernetes.Deployment](client, kubernetes.DeploymentsPath, kubernetes.ListOptions{}),
+		statefulSets: kubernetes.NewInformer[kubernetes.StatefulSet](client, kubernetes.StatefulSetsPath, kubernetes.ListOptions{}),
+		daemonSets:   kubernetes.NewInformer[kubernetes.DaemonSet](client, kubernetes.DaemonSetsPath, kubernetes.ListOptions{}),
+		jobs:         kubernetes.NewInformer[kubernetes.Job](client, kubernetes.JobsPath, kubernetes.ListOptions{}),
+		events:       kubernetes.NewInformer[kubernetes.Event](client, kubernetes.EventsPath, kubernetes.ListOptions{}),
+	}
+}
+
+// run 启动全部Informer
+func (inv *clusterInventory) run(ctx context.Context, retry time.Duration) {
+	inv.startedAt = time.Now()
+	go inv.pods.Run(ctx, retry)
+	go inv.nodes.Run(ctx, retry)
+	go inv.deployments.Run(ctx, retry)
+	go inv.statefulSets.Run(ctx, retry)
+	go inv.daemonSets.Run(ctx, retry)
+	go inv.jobs.Run(ctx, retry)
+	go inv.events.Run(ctx, retry)
+}
+
+// synced 检测器依赖的资源是否均已完成首次同步
+func (inv *clusterInventory) synced() bool {
+	return inv.pods.HasSynced() && inv.nodes.HasSynced() && inv.deployments.HasSynced() &&
+		inv.statefulSets.HasSynced() && inv.daemonSets.HasSynced() && inv.jobs.HasSynced() && inv.events.HasSynced()
+}
+
+// deploymentWorkload 转换Deployment概要，ProgressDeadlineExceeded视为失败
+func deploymentWorkload(d *kubernetes.Deployment) KubernetesWorkload {
+	desired := deploymentDesiredReplicas(d)
+	w := newWorkload(WorkloadDeployment, &d.Metadata)
+	w.Desired = desired
+	w.Ready = d.Status.ReadyReplicas
+	w.Available = d.Status.AvailableReplicas
+	w.Unavailable = deploymentUnavailableReplicas(d)
+	w.Updated = d.Status.UpdatedReplicas
+
+	switch {
+	case workloadConditionReason(d.Status.Conditions, "Progressing") == "ProgressDeadlineExceeded":
+		w.Status = "failed"
+		w.Message = workloadConditionMessage(d.Status.Conditions, "Progressing")
+	case w.Unavailable == 0 && w.Updated >= desired:
+		w.Status = "healthy"
+	case w.Updated < desired || d.Status.Replicas > desired:
+		w.Status = "progressing"
+	default:
+		w.Status = "degraded"
+		w.Message = workloadConditionMessage(d.Status.Conditions, "Available")
+	}
+	return w
+}
+
+// statefulSetWorkload 转换StatefulSet概要
+func statefulSetWorkload(sts *kubernetes.StatefulSet) KubernetesWorkload {
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+	w := newWorkload(WorkloadStatefulSet, &sts.Metadata)
+	w.Desired = desired
+	w.Ready = sts.Status.ReadyReplicas
+	w.Available = sts.Status.AvailableReplicas
+	w.Unavailable = max(desired-sts.Status.ReadyReplicas, 0)
+	w.Updated = sts.Status.UpdatedReplicas
+
+	switch {
+	case w.Unavailable == 0:
+		w.Status = "healthy"
+	case w.Updated < desired:
+		w.Status = "progressing"
+	default:
+		w.Status = "degraded"
+	}
+	return w
+}
+
+// daemonSetWorkload 转换DaemonSet概要
+func daemonSetWorkload(ds *kubernetes.DaemonSet) KubernetesWorkload {
+	w := newWorkload(WorkloadDaemonSet, &ds.Metadata)
+	w.Desired = ds.Status.DesiredNumberScheduled
+	w.Ready = ds.Status.NumberReady
+	w.Available = ds.Status.NumberAvailable
+	w.Unavailable = ds.Status.NumberUnavailable
+	w.Updated = ds.Status.UpdatedNumberScheduled
+
+	switch {
+	case w.Unavailable == 0 && w.Ready >= w.Desired:
+		w.Status = "healthy"
+	case w.Updated < w.Desired:
+		w.Status = "progressing"
+	default:
+		w.Status = "degraded"
+	}
+	return w
+}
+
+// jobWorkload 转换Job概要
+func jobWorkload(job *kubernetes.Job) KubernetesWorkload {
+	w := newWorkload(WorkloadJob, &job.Metadata)
+	w.Desired = 1
+	if job.Spec.Completions != nil {
+		w.Desired = *job.Spec.Completions
+	}
+	w.Active = job.Status.Active
+	w.Succeeded = job.Status.Succeeded
+	w.Failed = job.Status.Failed
+
+	if cond := job.Condition("Failed"); cond != nil {
+		w.Status = "failed"
+		w.Message = cond.Message
+	} else if job.Condition("Complete") != nil {
+		w.Status = "complete"
+	} else {
+		w.Status = "running"
+	}
+	return w
+}
+
+func newWorkload(kind string, meta *kubernetes.ObjectMeta) KubernetesWorkload {
+	return KubernetesWorkload{
+		Kind:      kind,
+		Name:      meta.Name,
+		Namespace: meta.Namespace,
+		UID:       meta.UID,
+		Labels:    meta.Labels,
+		Created:   meta.CreationTimestamp,
+	}
+}
+
+// deploymentDesiredReplicas 期望副本数，未设置时Kubernetes默认为1
+func deploymentDesiredReplicas(d *kubernetes.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// deploymentUnavailableReplicas 不可用副本数，取控制器上报值与期望值差额中的较大者
+func deploymentUnavailableReplicas(d *kubernetes.Deployment) int32 {
+	return max(d.Status.UnavailableReplicas, deploymentDesiredReplicas(d)-d.Status.AvailableReplicas, 0)
+}
+
+func workloadConditionReason(conditions []kubernetes.WorkloadCondition, conditionType string) string {
+	for _, cond := range conditions {
+		if cond.Type == conditionType {
+			return cond.Reason
+		}
+	}
+	return ""
+}
+
+func workloadConditionMessage(conditions []kubernetes.WorkloadCondition, conditionType string) string {
+	for _, cond := range conditions {
+		if cond.Type == conditionType {
+			return cond.Message
+		}
+	}
+	return ""
+}
+
+// toKubernetesEvent 转换事件
+func toKubernetesEvent(event *kubernetes.Event) KubernetesEvent {
+	first := event.FirstTimestamp
+	if first.IsZero() {
+		first = event.Timestamp()
+	}
+	count := event.Count
+	if count == 0 {
+		count = 1
+	}
+	return KubernetesEvent{
+		Namespace:         event.Metadata.Namespace,
+		Name:              event.Metadata.Name,
+		Type:              event.Type,
+		Reason:            event.Reason,
+		Message:           event.Message,
+		InvolvedKind:      event.InvolvedObject.Kind,
+		InvolvedNamespace: event.InvolvedObject.Namespace,
+		InvolvedName:      event.InvolvedObject.Name,
+		Count:             count,
+		FirstSeen:         first,
+		LastSeen:          event.Timestamp(),
+	}
+}
+
+// sortKubernetesEvents 按最近发生时间倒序排列
+func sortKubernetesEvents(events []KubernetesEvent) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.After(events[j].LastSeen) })
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/config"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // 注册gzip解压，SDK默认可能启用压缩
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLP/HTTP支持的内容类型
+const (
+	OTLPContentTypeProtobuf = "application/x-protobuf"
+	OTLPContentTypeJSON     = "application/json"
+)
+
+// ErrUnsupportedOTLPContentType 不支持的OTLP内容类型
+var ErrUnsupportedOTLPContentType = errors.New("unsupported otlp content type")
+
+// ExportOTLPTraces 接收一次OTLP链路导出请求，返回符合规范的部分成功响应
+func (s *TraceIngestService) ExportOTLPTraces(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	spans := convertOTLPTraces(req)
+	resp := &coltracepb.ExportTraceServiceResponse{}
+	if len(spans) == 0 {
+		return resp, nil
+	}
+
+	result, err := s.Ingest(ctx, spans)
+	if err != nil {
+		return nil, err
+	}
+	if result.Rejected > 0 {
+		resp.PartialSuccess = &coltracepb.ExportTracePartialSuccess{
+			RejectedSpans: int64(result.Rejected),
+			ErrorMessage:  result.Message,
+		}
+	}
+	return resp, nil
+}
+
+// DecodeOTLPTraceRequest 按内容类型解码OTLP/HTTP请求体
+func DecodeOTLPTraceRequest(body []byte, contentType string) (*coltracepb.ExportTraceServiceRequest, error) {
+	req := &coltracepb.ExportTraceServiceRequest{}
+	switch otlpMediaType(contentType) {
+	case OTLPContentTypeProtobuf:
+		if err := proto.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("failed to decode otlp protobuf: %w", err)
+		}
+	case OTLPContentTypeJSON:
+		// OTLP/JSON中的traceId、spanId为十六进制字符串，而protojson按base64解析bytes字段
+		normalized, err := otlpJSONHexToBase64(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode otlp json: %w", err)
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(normalized, req); err != nil {
+			return nil, fmt.Errorf("failed to decode otlp json: %w", err)
+		}
+	default:
+		return nil, ErrUnsupportedOTLPContentType
+	}
+	return req, nil
+}
+
+// EncodeOTLPMessage 按请求的内容类型编码OTLP响应（成功响应或google.rpc.Status）
+func EncodeOTLPMessage(msg proto.Message, contentType string) ([]byte, string, error) {
+	if otlpMediaType(contentType) == OTLPContentTypeJSON {
+		data, err := protojson.Marshal(msg)
+		return data, OTLPContentTypeJSON, err
+	}
+	data, err := proto.Marshal(msg)
+	return data, OTLPContentTypeProtobuf, err
+}
+
+// otlpMediaType 去掉charset等参数
+func otlpMediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// otlpJSONHexToBase64 将OTLP/JSON中的链路、跨度ID由十六进制转换为base64
+func otlpJSONHexToBase64(body []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	convert := func(obj map[string]interface{}, keys ...string) error {
+		for _, key := range keys {
+			v, ok := obj[key].(string)
+			if !ok || v == "" {
+				continue
+			}
+			raw, err := hex.DecodeString(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, v)
+			}
+			obj[key] = base64.StdEncoding.EncodeToString(raw)
+		}
+		return nil
+	}
+
+	for _, rs := range jsonArray(doc["resourceSpans"]) {
+		for _, ss := range jsonArray(rs["scopeSpans"]) {
+			for _, span := range jsonArray(ss["spans"]) {
+				if err := convert(span, "traceId", "spanId", "parentSpanId"); err != nil {
+					return nil, err
+				}
+				for _, link := range jsonArray(span["links"]) {
+					if err := convert(link, "traceId", "spanId"); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// jsonArray 取JSON数组中的对象元素
+func jsonArray(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// convertOTLPTraces 将OTLP请求展开为归一化跨度
+func convertOTLPTraces(req *coltracepb.ExportTraceServiceRequest) []*IngestSpan {
+	var spans []*IngestSpan
+	for _, rs := range req.GetResourceSpans() {
+		resource := otlpAttributes(rs.GetResource().GetAttributes())
+		serviceName := attributeString(resource, "service.name")
+
+		for _, ss := range rs.GetScopeSpans() {
+			scope := ss.GetScope().GetName()
+			for _, span := range ss.GetSpans() {
+				spans = append(spans, convertOTLPSpan(span, serviceName, resource, scope))
+			}
+		}
+	}
+	return spans
+}
+
+// convertOTLPSpan 转换单个OTLP跨度
+func convertOTLPSpan(span *tracepb.Span, serviceName string, resource map[string]interface{}, scope string) *IngestSpan {
+	result := &IngestSpan{
+		TraceID:       hex.EncodeToString(span.GetTraceId()),
+		SpanID:        hex.EncodeToString(span.GetSpanId()),
+		ParentSpanID:  hex.EncodeToString(span.GetParentSpanId()),
+		OperationName: span.GetName(),
+		ServiceName:   serviceName,
+		Kind:          otlpSpanKind(span.GetKind()),
+		StartTime:     unixNano(span.GetStartTimeUnixNano()),
+		EndTime:       unixNano(span.GetEndTimeUnixNano()),
+		Error:         span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR,
+		StatusMessage: span.GetStatus().GetMessage(),
+		Attributes:    otlpAttributes(span.GetAttributes()),
+		Resource:      resource,
+		Scope:         scope,
+	}
+	if scope != "" {
+		result.Attributes["otel.scope.name"] = scope
+	}
+
+	for _, event := range span.GetEvents() {
+		fields := otlpAttributes(event.GetAttributes())
+		fields["event"] = event.GetName()
+		result.Events = append(result.Events, SpanLog{
+			Timestamp: unixNano(event.GetTimeUnixNano()),
+			Fields:    fields,
+		})
+	}
+	return result
+}
+
+// otlpSpanKind 转换跨度类型
+func otlpSpanKind(kind tracepb.Span_SpanKind) string {
+	switch kind {
+	case tracepb.Span_SPAN_KIND_SERVER:
+		return SpanKindServer
+	case tracepb.Span_SPAN_KIND_CLIENT:
+		return SpanKindClient
+	case tracepb.Span_SPAN_KIND_PRODUCER:
+		return SpanKindProducer
+	case tracepb.Span_SPAN_KIND_CONSUMER:
+		return SpanKindConsumer
+	case tracepb.Span_SPAN_KIND_INTERNAL:
+		return SpanKindInternal
+	default:
+		return ""
+	}
+}
+
+// otlpAttributes 转换属性列表
+func otlpAttributes(kvs []*commonpb.KeyValue) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = otlpValue(kv.GetValue())
+	}
+	return attrs
+}
+
+// otlpValue 转换属性值，数组和键值列表保持嵌套结构
+func otlpValue(v *commonpb.AnyValue) interface{} {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return value.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		items := make([]interface{}, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			items = append(items, otlpValue(item))
+		}
+		return items
+	case *commonpb.AnyValue_KvlistValue:
+		return otlpAttributes(value.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
+
+// unixNano 纳秒时间戳转换为时间，0表示未设置
+func unixNano(ns uint64) time.Time {
+	if ns == 0 || ns > math.MaxInt64 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+// otlpTraceServer OTLP/gRPC链路服务
+type otlpTraceServer struct {
+	coltracepb.UnimplementedTraceServiceServer
+	ingest *TraceIngestService
+}
+
+// Export 实现TraceServiceServer
+func (srv *otlpTraceServer) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	resp, err := srv.ingest.ExportOTLPTraces(ctx, req)
+	if err != nil {
+		// 存储失败可重试
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return resp, nil
+}
+
+// StartOTLPGRPC 启动OTLP/gRPC接收端，随ctx取消优雅退出
+func (s *TraceIngestService) StartOTLPGRPC(ctx context.Context, cfg config.OTLPReceiverConfig) error {
+	addr := cfg.GRPCAddr
+	if addr == "" {
+		addr = ":4317"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen otlp grpc on %s: %w", addr, err)
+	}
+
+	opts := []grpc.ServerOption{}
+	if cfg.MaxBodySize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(cfg.MaxBodySize)))
+	}
+	if cfg.RequireAuth {
+		opts = append(opts, grpc.UnaryInterceptor(s.otlpAuthInterceptor))
+	}
+	server := grpc.NewServer(opts...)
+	coltracepb.RegisterTraceServiceServer(server, &otlpTraceServer{ingest: s})
+
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	return nil
+}
+
+// otlpAuthInterceptor 校验gRPC元数据中的API Key（authorization: Bearer <key>）
+func (s *TraceIngestService) otlpAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+	}
+	if _, err := NewAPIKeyService(s.db).ValidateAPIKey(strings.TrimPrefix(values[0], "Bearer ")); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+	return handler(ctx, req)
+}
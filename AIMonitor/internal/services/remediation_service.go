@@ -0,0 +1,1187 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RemediationService 自动修复服务
+type RemediationService struct {
+	db               *gorm.DB
+	cacheManager     *cache.CacheManager
+	config           *config.Config
+	aiService        *AIService
+	agentService     *AgentService
+	containerService *ContainerService
+	auditService     *AuditService
+	httpClient       *http.Client
+}
+
+// PlaybookStep 修复剧本步骤
+type PlaybookStep struct {
+	Name            string                 `json:"name" binding:"required"`
+	Type            string                 `json:"type" binding:"required,oneof=agent_command webhook restart_container scale_deployment"`
+	Risk            string                 `json:"risk" binding:"omitempty,oneof=low high"`
+	Params          map[string]interface{} `json:"params"`
+	ContinueOnError bool                   `json:"continue_on_error"`
+}
+
+// RemediationStepResult 修复步骤执行结果
+type RemediationStepResult struct {
+	Index      int                    `json:"index"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Status     string                 `json:"status"` // succeeded, failed, dry_run
+	Params     map[string]interface{} `json:"params"`
+	Output     string                 `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt time.Time              `json:"finished_at"`
+}
+
+// CreatePlaybookRequest 创建修复剧本请求
+type CreatePlaybookRequest struct {
+	Name        string         `json:"name" binding:"required,max=100"`
+	Description string         `json:"description"`
+	RuleID      *uuid.UUID     `json:"rule_id"`
+	Enabled     *bool          `json:"enabled"`
+	DryRun      *bool          `json:"dry_run"`
+	Steps       []PlaybookStep `json:"steps" binding:"required,min=1,dive"`
+	RateLimit   int            `json:"rate_limit" binding:"omitempty,min=0"`
+	RateWindow  int            `json:"rate_window" binding:"omitempty,min=0"`
+}
+
+// UpdatePlaybookRequest 更新修复剧本请求
+type UpdatePlaybookRequest struct {
+	Name        *string        `json:"name" binding:"omitempty,max=100"`
+	Description *string        `json:"description"`
+	RuleID      *uuid.UUID     `json:"rule_id"`
+	Enabled     *bool          `json:"enabled"`
+	DryRun      *bool          `json:"dry_run"`
+	Steps       []PlaybookStep `json:"steps" binding:"omitempty,dive"`
+	RateLimit   *int           `json:"rate_limit" binding:"omitempty,min=0"`
+	RateWindow  *int           `json:"rate_window" binding:"omitempty,min=0"`
+}
+
+// ExecutePlaybookRequest 手动执行修复剧本请求
+type ExecutePlaybookRequest struct {
+	AlertID   *uuid.UUID        `json:"alert_id"`
+	TargetID  string            `json:"target_id"`
+	DryRun    *bool             `json:"dry_run"`
+	Variables map[string]string `json:"variables"`
+}
+
+// PlaybookResponse 修复剧本响应
+type PlaybookResponse struct {
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	RuleID      *uuid.UUID     `json:"rule_id"`
+	Enabled     bool           `json:"enabled"`
+	DryRun      bool           `json:"dry_run"`
+	Steps       []PlaybookStep `json:"steps"`
+	RateLimit   int            `json:"rate_limit"`
+	RateWindow  int            `json:"rate_window"`
+	CreatedBy   uuid.UUID      `json:"created_by"`
+	UpdatedBy   uuid.UUID      `json:"updated_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// RemediationExecutionResponse 修复执行记录响应
+type RemediationExecutionResponse struct {
+	ID           uuid.UUID                `json:"id"`
+	PlaybookID   uuid.UUID                `json:"playbook_id"`
+	AlertID      *uuid.UUID               `json:"alert_id"`
+	TargetID     string                   `json:"target_id"`
+	Trigger      string                   `json:"trigger"`
+	Status       string                   `json:"status"`
+	DryRun       bool                     `json:"dry_run"`
+	CurrentStep  int                      `json:"current_step"`
+	StepResults  []*RemediationStepResult `json:"step_results"`
+	Variables    map[string]string        `json:"variables"`
+	Error        string                   `json:"error"`
+	TriggeredBy  *uuid.UUID               `json:"triggered_by"`
+	ApprovedBy   *uuid.UUID               `json:"approved_by"`
+	ApprovedAt   *time.Time               `json:"approved_at"`
+	ApprovedStep *int                     `json:"approved_step"`
+	StartedAt    time.Time                `json:"started_at"`
+	FinishedAt   *time.Time               `json:"finished_at"`
+}
+
+// NewRemediationService 创建自动修复服务
+func NewRemediationService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, aiService *AIService, agentService *AgentService, containerService *ContainerService, auditService *AuditService) *RemediationService {
+	timeout := config.Alerting.Remediation.WebhookTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &RemediationService{
+		db:               db,
+		cacheManager:     cacheManager,
+		config:           config,
+		aiService:        aiService,
+		agentService:     agentService,
+		containerService: containerService,
+		auditService:     auditService,
+		httpClient:       &http.Client{Timeout: timeout},
+	}
+}
+
+// RunRemediation 周期性地为告警触发修复剧本并清理超时未审批的执行
+func (s *RemediationService) RunRemediation(ctx context.Context, cfg config.RemediationConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExpirePendingApprovals(); err != nil {
+				logger.GetLogger("remediation").WithError(err).Error("failed to expire pending approvals")
+			}
+			if err := s.TriggerForAlerts(); err != nil {
+				logger.GetLogger("remediation").WithError(err).Error("failed to trigger remediations for alerts")
+			}
+		}
+	}
+}
+
+// TriggerForAlerts 为绑定了剧本的活跃告警启动修复，每条告警每个剧本只触发一次
+func (s *RemediationService) TriggerForAlerts() error {
+	if !s.config.FeatureFlags.AutoRemediation {
+		return nil
+	}
+
+	var playbooks []models.RemediationPlaybook
+	if err := s.db.Where("enabled = ? AND rule_id IS NOT NULL", true).Find(&playbooks).Error; err != nil {
+		return fmt.Errorf("failed to query playbooks: %w", err)
+	}
+
+	for i := range playbooks {
+		playbook := &playbooks[i]
+
+		var alerts []models.Alert
+		if err := s.db.Where("rule_id = ? AND status = ? AND silenced = ?", *playbook.RuleID, "firing", false).
+			Where("NOT EXISTS (SELECT 1 FROM remediation_executions e WHERE e.playbook_id = ? AND e.alert_id = alerts.id AND e.deleted_at IS NULL)", playbook.ID).
+			Find(&alerts).Error; err != nil {
+			return fmt.Errorf("failed to query alerts for playbook: %w", err)
+		}
+
+		for j := range alerts {
+			if _, err := s.startExecution(playbook, &alerts[j], "", "alert", playbook.DryRun, nil, nil); err != nil {
+				// 单条告警失败不影响其他告警
+				logger.GetLogger("remediation").WithError(err).WithField("alert_id", alerts[j].ID).Warn("failed to start remediation for alert")
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExpirePendingApprovals 将超过审批时限的执行标记为过期
+func (s *RemediationService) ExpirePendingApprovals() error {
+	timeout := s.config.Alerting.Remediation.ApprovalTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+
+	var executions []models.RemediationExecution
+	if err := s.db.Where("status = ? AND updated_at < ?", "pending_approval", time.Now().Add(-timeout)).
+		Find(&executions).Error; err != nil {
+		return fmt.Errorf("failed to query pending executions: %w", err)
+	}
+
+	for i := range executions {
+		if _, err := s.finishPendingExecution(&executions[i], "expired", "approval timed out", nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreatePlaybook 创建修复剧本
+func (s *RemediationService) CreatePlaybook(req *CreatePlaybookRequest, userID uuid.UUID) (*PlaybookResponse, error) {
+	if err := s.validateSteps(req.Steps); err != nil {
+		return nil, err
+	}
+	if req.RuleID != nil {
+		if err := s.db.First(&models.AlertRule{}, *req.RuleID).Error; err != nil {
+			return nil, errors.New("alert rule not found")
+		}
+	}
+
+	stepsJSON, _ := json.Marshal(req.Steps)
+	playbook := models.RemediationPlaybook{
+		Name:        req.Name,
+		Description: req.Description,
+		RuleID:      req.RuleID,
+		Enabled:     true,
+		DryRun:      true,
+		Steps:       string(stepsJSON),
+		RateLimit:   req.RateLimit,
+		RateWindow:  req.RateWindow,
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}
+	if req.Enabled != nil {
+		playbook.Enabled = *req.Enabled
+	}
+	if req.DryRun != nil {
+		playbook.DryRun = *req.DryRun
+	}
+
+	// GORM会跳过零值字段而使用数据库默认值，显式写入布尔字段
+	if err := s.db.Select("*").Create(&playbook).Error; err != nil {
+		return nil, fmt.Errorf("failed to create playbook: %w", err)
+	}
+
+	return s.toPlaybookResponse(&playbook), nil
+}
+
+// GetPlaybook 获取修复剧本
+func (s *RemediationService) GetPlaybook(id uuid.UUID) (*PlaybookResponse, error) {
+	var playbook models.RemediationPlaybook
+	if err := s.db.First(&playbook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("playbook not found")
+		}
+		return nil, fmt.Errorf("failed to get playbook: %w", err)
+	}
+	return s.toPlaybookResponse(&playbook), nil
+}
+
+// UpdatePlaybook 更新修复剧本
+func (s *RemediationService) UpdatePlaybook(id uuid.UUID, req *UpdatePlaybookRequest, userID uuid.UUID) (*PlaybookResponse, error) {
+	var playbook models.RemediationPlaybook
+	if err := s.db.First(&playbook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("playbook not found")
+		}
+		return nil, fmt.Errorf("failed to get playbook: %w", err)
+	}
+
+	updates := map[string]interface{}{"updated_by": userID}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.RuleID != nil {
+		if err := s.db.First(&models.AlertRule{}, *req.RuleID).Error; err != nil {
+			return nil, errors.New("alert rule not found")
+		}
+		updates["rule_id"] = *req.RuleID
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if req.DryRun != nil {
+		updates["dry_run"] = *req.DryRun
+	}
+	if req.Steps != nil {
+		if err := s.validateSteps(req.Steps); err != nil {
+			return nil, err
+		}
+		stepsJSON, _ := json.Marshal(req.Steps)
+		updates["steps"] = string(stepsJSON)
+	}
+	if req.RateLimit != nil {
+		updates["rate_limit"] = *req.RateLimit
+	}
+	if req.RateWindow != nil {
+		updates["rate_window"] = *req.RateWindow
+	}
+
+	if err := s.db.Model(&playbook).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update playbook: %w", err)
+	}
+
+	return s.GetPlaybook(id)
+}
+
+// DeletePlaybook 删除修复剧本
+func (s *RemediationService) DeletePlaybook(id uuid.UUID) error {
+	result := s.db.Delete(&models.RemediationPlaybook{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete playbook: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("playbook not found")
+	}
+	return nil
+}
+
+// ListPlaybooks 获取修复剧本列表
+func (s *RemediationService) ListPlaybooks(page, pageSize int, ruleID string) ([]*PlaybookResponse, int64, error) {
+	query := s.db.Model(&models.RemediationPlaybook{})
+	if ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count playbooks: %w", err)
+	}
+
+	var playbooks []models.RemediationPlaybook
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&playbooks).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list playbooks: %w", err)
+	}
+
+	responses := make([]*PlaybookResponse, len(playbooks))
+	for i := range playbooks {
+		responses[i] = s.toPlaybookResponse(&playbooks[i])
+	}
+	return responses, total, nil
+}
+
+// ExecutePlaybook 手动执行修复剧本，未指定dry_run时沿用剧本配置
+func (s *RemediationService) ExecutePlaybook(id uuid.UUID, req *ExecutePlaybookRequest, userID uuid.UUID) (*RemediationExecutionResponse, error) {
+	var playbook models.RemediationPlaybook
+	if err := s.db.First(&playbook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("playbook not found")
+		}
+		return nil, fmt.Errorf("failed to get playbook: %w", err)
+	}
+
+	var alert *models.Alert
+	if req.AlertID != nil {
+		alert = &models.Alert{}
+		if err := s.db.First(alert, *req.AlertID).Error; err != nil {
+			return nil, errors.New("alert not found")
+		}
+	}
+	if alert == nil && req.TargetID == "" {
+		return nil, errors.New("alert_id or target_id is required")
+	}
+
+	dryRun := playbook.DryRun
+	if req.DryRun != nil {
+		dryRun = *req.DryRun
+	}
+
+	exec, err := s.startExecution(&playbook, alert, req.TargetID, "manual", dryRun, req.Variables, &userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toExecutionResponse(exec), nil
+}
+
+// GetExecution 获取修复执行记录
+func (s *RemediationService) GetExecution(id uuid.UUID) (*RemediationExecutionResponse, error) {
+	var exec models.RemediationExecution
+	if err := s.db.First(&exec, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("execution not found")
+		}
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	return s.toExecutionResponse(&exec), nil
+}
+
+// ListExecutions 获取修复执行记录列表
+func (s *RemediationService) ListExecutions(page, pageSize int, playbookID, alertID, status string) ([]*RemediationExecutionResponse, int64, error) {
+	query := s.db.Model(&models.RemediationExecution{})
+	if playbookID != "" {
+		query = query.Where("playbook_id = ?", playbookID)
+	}
+	if alertID != "" {
+		query = query.Where("alert_id = ?", alertID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count executions: %w", err)
+	}
+
+	var executions []models.RemediationExecution
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("started_at DESC").Find(&executions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list executions: %w", err)
+	}
+
+	responses := make([]*RemediationExecutionResponse, len(executions))
+	for i := range executions {
+		responses[i] = s.toExecutionResponse(&executions[i])
+	}
+	return responses, total, nil
+}
+
+// ApproveExecution 审批当前挂起的高风险步骤并从该步骤继续执行，后续高风险步骤仍需再次审批
+func (s *RemediationService) ApproveExecution(id uuid.UUID, userID uuid.UUID) (*RemediationExecutionResponse, error) {
+	exec, playbook, err := s.getPendingExecution(id)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := s.config.Alerting.Remediation.ApprovalTimeout
+	if timeout > 0 && time.Since(exec.UpdatedAt) > timeout {
+		if _, err := s.finishPendingExecution(exec, "expired", "approval timed out", nil); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("execution approval has expired")
+	}
+
+	// 条件更新保证并发的审批、拒绝与过期中只有一个生效
+	now := time.Now()
+	step := exec.CurrentStep
+	ok, err := s.transitionPending(exec.ID, map[string]interface{}{
+		"status":        "running",
+		"approved_by":   userID,
+		"approved_at":   now,
+		"approved_step": step,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve execution: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("execution is no longer pending approval")
+	}
+	exec.Status = "running"
+	exec.ApprovedBy = &userID
+	exec.ApprovedAt = &now
+	exec.ApprovedStep = &step
+
+	s.audit(&userID, "approve", exec, "success", "", map[string]interface{}{"step": step})
+	s.appendTimeline(exec, fmt.Sprintf("修复剧本 %s 高风险步骤已审批", playbook.Name), &userID)
+
+	go s.runExecution(exec, playbook)
+
+	return s.toExecutionResponse(exec), nil
+}
+
+// RejectExecution 拒绝待确认的执行
+func (s *RemediationService) RejectExecution(id uuid.UUID, userID uuid.UUID) (*RemediationExecutionResponse, error) {
+	exec, _, err := s.getPendingExecution(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.finishPendingExecution(exec, "rejected", "rejected by approver", &userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("execution is no longer pending approval")
+	}
+
+	return s.toExecutionResponse(exec), nil
+}
+
+// startExecution 检查限流后创建执行记录并异步执行剧本
+func (s *RemediationService) startExecution(playbook *models.RemediationPlaybook, alert *models.Alert, targetID, trigger string, dryRun bool, extra map[string]string, userID *uuid.UUID) (*models.RemediationExecution, error) {
+	vars := s.buildVariables(alert, targetID, extra)
+
+	varsJSON, _ := json.Marshal(vars)
+	exec := &models.RemediationExecution{
+		PlaybookID:  playbook.ID,
+		TargetID:    vars["target_id"],
+		Trigger:     trigger,
+		Status:      "running",
+		DryRun:      dryRun,
+		StepResults: "[]",
+		Variables:   string(varsJSON),
+		TriggeredBy: userID,
+		StartedAt:   time.Now(),
+	}
+	if alert != nil {
+		exec.AlertID = &alert.ID
+	}
+
+	limited, err := s.isRateLimited(playbook, exec.TargetID, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if limited {
+		now := time.Now()
+		exec.Status = "rate_limited"
+		exec.Error = "rate limit exceeded for target"
+		exec.FinishedAt = &now
+	}
+
+	if err := s.db.Create(exec).Error; err != nil {
+		return nil, fmt.Errorf("failed to create execution: %w", err)
+	}
+
+	if limited {
+		s.audit(userID, "execute", exec, "failure", exec.Error, map[string]interface{}{"playbook": playbook.Name})
+		s.appendTimeline(exec, fmt.Sprintf("修复剧本 %s 已被限流跳过", playbook.Name), userID)
+		return exec, nil
+	}
+
+	s.audit(userID, "execute", exec, "success", "", map[string]interface{}{
+		"playbook": playbook.Name,
+		"trigger":  trigger,
+		"dry_run":  dryRun,
+	})
+	s.appendTimeline(exec, fmt.Sprintf("修复剧本 %s 开始执行", playbook.Name), userID)
+
+	go s.runExecution(exec, playbook)
+
+	return exec, nil
+}
+
+// isRateLimited 检查同一剧本在同一目标上的执行次数是否超过限制，演练不计入
+func (s *RemediationService) isRateLimited(playbook *models.RemediationPlaybook, targetID string, dryRun bool) (bool, error) {
+	if dryRun {
+		return false, nil
+	}
+
+	cfg := s.config.Alerting.Remediation
+	limit := playbook.RateLimit
+	if limit <= 0 {
+		limit = cfg.DefaultRateLimit
+	}
+	if limit <= 0 {
+		return false, nil
+	}
+	window := time.Duration(playbook.RateWindow) * time.Second
+	if window <= 0 {
+		window = cfg.DefaultRateWindow
+	}
+	if window <= 0 {
+		window = time.Hour
+	}
+
+	var count int64
+	if err := s.db.Model(&models.RemediationExecution{}).
+		Where("playbook_id = ? AND target_id = ? AND dry_run = ? AND started_at >= ?", playbook.ID, targetID, false, time.Now().Add(-window)).
+		Where("status NOT IN ?", []string{"rate_limited", "rejected", "expired"}).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to count recent executions: %w", err)
+	}
+
+	return count >= int64(limit), nil
+}
+
+// runExecution 从当前步骤开始顺序执行剧本，遇到未审批的高风险步骤时挂起
+func (s *RemediationService) runExecution(exec *models.RemediationExecution, playbook *models.RemediationPlaybook) {
+	var steps []PlaybookStep
+	if err := json.Unmarshal([]byte(playbook.Steps), &steps); err != nil {
+		exec.Error = fmt.Sprintf("invalid playbook steps: %v", err)
+		s.finishExecution(exec, "failed")
+		return
+	}
+
+	var vars map[string]string
+	json.Unmarshal([]byte(exec.Variables), &vars)
+
+	var results []*RemediationStepResult
+	json.Unmarshal([]byte(exec.StepResults), &results)
+
+	for exec.CurrentStep < len(steps) {
+		step := steps[exec.CurrentStep]
+
+		approved := exec.ApprovedStep != nil && *exec.ApprovedStep == exec.CurrentStep
+		if step.Risk == "high" && !exec.DryRun && !approved {
+			exec.Status = "pending_approval"
+			if err := s.db.Save(exec).Error; err != nil {
+				// 状态保存失败时仍保持挂起，等待审批超时处理
+				logger.GetLogger("remediation").WithError(err).WithField("execution_id", exec.ID).Warn("failed to save pending execution")
+			}
+			s.audit(nil, "request_approval", exec, "success", "", map[string]interface{}{
+				"step":      exec.CurrentStep,
+				"step_name": step.Name,
+			})
+			s.appendTimeline(exec, fmt.Sprintf("修复剧本 %s 步骤 %s 需要人工审批", playbook.Name, step.Name), nil)
+			return
+		}
+
+		result := s.executeStep(exec, exec.CurrentStep, &step, vars)
+		results = append(results, result)
+		exec.CurrentStep++
+		// 审批只对当前步骤有效
+		exec.ApprovedStep = nil
+		resultsJSON, _ := json.Marshal(results)
+		exec.StepResults = string(resultsJSON)
+		if err := s.db.Save(exec).Error; err != nil {
+			// 步骤结果保存失败不影响后续步骤
+			logger.GetLogger("remediation").WithError(err).WithField("execution_id", exec.ID).Warn("failed to save step results")
+		}
+
+		auditResult := "success"
+		if result.Status == "failed" {
+			auditResult = "failure"
+		}
+		s.audit(nil, "execute_step", exec, auditResult, result.Error, map[string]interface{}{
+			"step":    result.Index,
+			"name":    result.Name,
+			"type":    result.Type,
+			"params":  result.Params,
+			"dry_run": exec.DryRun,
+		})
+
+		if result.Status == "failed" && !step.ContinueOnError {
+			exec.Error = fmt.Sprintf("step %s failed: %s", step.Name, result.Error)
+			s.finishExecution(exec, "failed")
+			return
+		}
+	}
+
+	s.finishExecution(exec, "succeeded")
+}
+
+// executeStep 渲染参数并执行单个步骤，演练模式只记录渲染结果
+func (s *RemediationService) executeStep(exec *models.RemediationExecution, index int, step *PlaybookStep, vars map[string]string) *RemediationStepResult {
+	result := &RemediationStepResult{
+		Index:     index,
+		Name:      step.Name,
+		Type:      step.Type,
+		Params:    renderParams(step.Params, vars),
+		StartedAt: time.Now(),
+	}
+
+	if exec.DryRun {
+		result.Status = "dry_run"
+		result.FinishedAt = time.Now()
+		return result
+	}
+
+	var output string
+	var err error
+	switch step.Type {
+	case "agent_command":
+		output, err = s.runAgentCommand(exec, result.Params)
+	case "webhook":
+		output, err = s.callWebhook(result.Params)
+	case "restart_container":
+		err = s.containerService.RestartDockerContainer(paramString(result.Params, "container_id"), paramInt(result.Params, "timeout", 10))
+	case "scale_deployment":
+		// cluster参数可选，未指定时使用默认集群
+		err = s.containerService.ScaleKubernetesDeployment(
+			paramString(result.Params, "cluster"),
+			paramString(result.Params, "namespace"),
+			paramString(result.Params, "name"),
+			int32(paramInt(result.Params, "replicas", 1)),
+		)
+	default:
+		err = fmt.Errorf("unsupported step type: %s", step.Type)
+	}
+
+	result.Output = output
+	result.FinishedAt = time.Now()
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+	} else {
+		result.Status = "succeeded"
+	}
+	return result
+}
+
+// runAgentCommand 通过Agent命令队列下发命令并等待结果
+func (s *RemediationService) runAgentCommand(exec *models.RemediationExecution, params map[string]interface{}) (string, error) {
+	agentID, err := uuid.Parse(paramString(params, "agent_id"))
+	if err != nil {
+		return "", fmt.Errorf("invalid agent_id: %w", err)
+	}
+
+	timeout := s.config.Alerting.Remediation.CommandTimeout
+	if seconds := paramInt(params, "timeout", 0); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	var args []string
+	if raw, ok := params["args"].([]interface{}); ok {
+		for _, arg := range raw {
+			args = append(args, fmt.Sprint(arg))
+		}
+	}
+
+	cmd, err := s.agentService.EnqueueCommand(agentID, paramString(params, "command"), args, int(timeout.Seconds()), &exec.ID)
+	if err != nil {
+		return "", err
+	}
+
+	done, err := s.agentService.WaitCommand(cmd.ID, timeout)
+	if err != nil {
+		return "", err
+	}
+	if done.Status != "succeeded" {
+		return done.Output, fmt.Errorf("command exited with code %d", done.ExitCode)
+	}
+	return done.Output, nil
+}
+
+// callWebhook 调用外部Webhook，非2xx响应视为失败
+func (s *RemediationService) callWebhook(params map[string]interface{}) (string, error) {
+	url := paramString(params, "url")
+	if url == "" {
+		return "", errors.New("webhook url is required")
+	}
+	method := strings.ToUpper(paramString(params, "method"))
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	switch payload := params["body"].(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(payload)
+	default:
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if headers, ok := params["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			req.Header.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return string(respBody), fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return string(respBody), nil
+}
+
+// finishExecution 结束执行，写入审计与告警时间线，并将真实执行结果反馈给AI分析
+func (s *RemediationService) finishExecution(exec *models.RemediationExecution, status string) {
+	now := time.Now()
+	exec.Status = status
+	exec.FinishedAt = &now
+	if err := s.db.Save(exec).Error; err != nil {
+		// 保存失败时仍继续记录审计
+		logger.GetLogger("remediation").WithError(err).WithField("execution_id", exec.ID).Warn("failed to save finished execution")
+	}
+
+	s.recordFinish(exec, status)
+}
+
+// finishPendingExecution 以条件更新结束待审批的执行，执行已被其他请求处理时返回false
+func (s *RemediationService) finishPendingExecution(exec *models.RemediationExecution, status, errMsg string, userID *uuid.UUID) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      status,
+		"error":       errMsg,
+		"finished_at": now,
+	}
+	if userID != nil {
+		updates["approved_by"] = *userID
+	}
+	ok, err := s.transitionPending(exec.ID, updates)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish execution: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	exec.Status = status
+	exec.Error = errMsg
+	exec.FinishedAt = &now
+	if userID != nil {
+		exec.ApprovedBy = userID
+	}
+	s.recordFinish(exec, status)
+	return true, nil
+}
+
+// transitionPending 仅当执行仍处于pending_approval时更新，返回是否更新成功
+func (s *RemediationService) transitionPending(id uuid.UUID, updates map[string]interface{}) (bool, error) {
+	result := s.db.Model(&models.RemediationExecution{}).
+		Where("id = ? AND status = ?", id, "pending_approval").
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// recordFinish 写入执行结束的审计与告警时间线，并将真实执行结果反馈给AI分析
+func (s *RemediationService) recordFinish(exec *models.RemediationExecution, status string) {
+	var playbook models.RemediationPlaybook
+	s.db.Unscoped().First(&playbook, exec.PlaybookID)
+
+	auditResult := "success"
+	if status != "succeeded" {
+		auditResult = "failure"
+	}
+	s.audit(exec.ApprovedBy, "finish", exec, auditResult, exec.Error, map[string]interface{}{
+		"playbook": playbook.Name,
+		"status":   status,
+		"dry_run":  exec.DryRun,
+	})
+
+	message := fmt.Sprintf("修复剧本 %s 执行结束：%s", playbook.Name, status)
+	if exec.DryRun {
+		message += "（演练）"
+	}
+	s.appendTimeline(exec, message, exec.ApprovedBy)
+
+	if !exec.DryRun && exec.AlertID != nil && (status == "succeeded" || status == "failed") {
+		go s.feedbackToAI(exec, &playbook)
+	}
+}
+
+// feedbackToAI 携带修复结果重新分析告警
+func (s *RemediationService) feedbackToAI(exec *models.RemediationExecution, playbook *models.RemediationPlaybook) {
+	if s.aiService == nil {
+		return
+	}
+
+	var alert models.Alert
+	if err := s.db.Preload("Rule").First(&alert, *exec.AlertID).Error; err != nil {
+		return
+	}
+
+	var labels map[string]interface{}
+	if alert.Labels != "" {
+		json.Unmarshal([]byte(alert.Labels), &labels)
+	}
+	var results []*RemediationStepResult
+	json.Unmarshal([]byte(exec.StepResults), &results)
+
+	request := &AIAnalysisRequest{
+		Type:         "alert_analysis",
+		AlertID:      alert.ID,
+		RuleID:       alert.RuleID,
+		TargetType:   paramString(labels, "target_type"),
+		TargetID:     exec.TargetID,
+		MetricName:   alert.Rule.Metric,
+		CurrentValue: alert.Value,
+		Threshold:    alert.Rule.Threshold,
+		Condition:    alert.Rule.Condition,
+		Severity:     alert.Severity,
+		Tags:         labels,
+		Timestamp:    time.Now(),
+		Context: map[string]interface{}{
+			"remediation": map[string]interface{}{
+				"playbook":     playbook.Name,
+				"status":       exec.Status,
+				"error":        exec.Error,
+				"steps":        results,
+				"alert_status": alert.Status,
+			},
+		},
+	}
+
+	if _, err := s.aiService.AnalyzeAlert(request); err != nil {
+		// AI反馈失败不影响修复结果
+		logger.GetLogger("remediation").WithError(err).Warn("failed to feed remediation result back to AI analysis")
+	}
+}
+
+// appendTimeline 将修复进展写入告警时间线
+func (s *RemediationService) appendTimeline(exec *models.RemediationExecution, message string, userID *uuid.UUID) {
+	if exec.AlertID == nil {
+		return
+	}
+
+	var alert models.Alert
+	if err := s.db.Select("id", "rule_id", "value").First(&alert, *exec.AlertID).Error; err != nil {
+		return
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"execution_id": exec.ID,
+		"playbook_id":  exec.PlaybookID,
+		"status":       exec.Status,
+		"dry_run":      exec.DryRun,
+		"current_step": exec.CurrentStep,
+		"error":        exec.Error,
+	})
+	event := models.AlertEvent{
+		AlertID: alert.ID,
+		RuleID:  alert.RuleID,
+		Type:    "remediation",
+		Message: message,
+		Value:   alert.Value,
+		Details: string(details),
+		UserID:  userID,
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		logger.GetLogger("remediation").WithError(err).WithField("alert_id", alert.ID).Warn("failed to record remediation event")
+	}
+}
+
+// audit 记录修复操作审计日志
+func (s *RemediationService) audit(userID *uuid.UUID, action string, exec *models.RemediationExecution, result, errMsg string, details map[string]interface{}) {
+	if s.auditService == nil {
+		return
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["playbook_id"] = exec.PlaybookID
+	details["target_id"] = exec.TargetID
+	if exec.AlertID != nil {
+		details["alert_id"] = *exec.AlertID
+	}
+
+	if err := s.auditService.LogAudit(userID, &AuditLogRequest{
+		Action:     action,
+		Resource:   "remediation",
+		ResourceID: exec.ID.String(),
+		Details:    details,
+		Result:     result,
+		ErrorMsg:   errMsg,
+	}); err != nil {
+		// 审计失败不影响修复流程
+		logger.GetLogger("remediation").WithError(err).WithField("execution_id", exec.ID).Warn("failed to write remediation audit log")
+	}
+}
+
+// getPendingExecution 获取待审批的执行及其剧本
+func (s *RemediationService) getPendingExecution(id uuid.UUID) (*models.RemediationExecution, *models.RemediationPlaybook, error) {
+	var exec models.RemediationExecution
+	if err := s.db.First(&exec, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("execution not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	if exec.Status != "pending_approval" {
+		return nil, nil, fmt.Errorf("execution is %s, not pending approval", exec.Status)
+	}
+
+	var playbook models.RemediationPlaybook
+	if err := s.db.Unscoped().First(&playbook, exec.PlaybookID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get playbook: %w", err)
+	}
+	return &exec, &playbook, nil
+}
+
+// buildVariables 构建步骤参数模板变量
+func (s *RemediationService) buildVariables(alert *models.Alert, targetID string, extra map[string]string) map[string]string {
+	vars := make(map[string]string)
+	for key, value := range extra {
+		vars[key] = value
+	}
+
+	if alert != nil {
+		vars["alert_id"] = alert.ID.String()
+		vars["rule_id"] = alert.RuleID.String()
+		vars["severity"] = alert.Severity
+		vars["value"] = strconv.FormatFloat(alert.Value, 'f', -1, 64)
+
+		var labels map[string]interface{}
+		if alert.Labels != "" {
+			json.Unmarshal([]byte(alert.Labels), &labels)
+		}
+		for key, value := range labels {
+			vars["labels."+key] = fmt.Sprint(value)
+		}
+		if metric, ok := labels["metric"]; ok {
+			vars["metric"] = fmt.Sprint(metric)
+		}
+		if targetID == "" {
+			if id, ok := labels["target_id"]; ok {
+				targetID = fmt.Sprint(id)
+			} else {
+				targetID = fingerprintTargetID(alert.Fingerprint)
+			}
+		}
+	}
+	vars["target_id"] = targetID
+
+	return vars
+}
+
+// validateSteps 校验剧本步骤的必要参数
+func (s *RemediationService) validateSteps(steps []PlaybookStep) error {
+	required := map[string][]string{
+		"agent_command":     {"agent_id", "command"},
+		"webhook":           {"url"},
+		"restart_container": {"container_id"},
+		"scale_deployment":  {"namespace", "name", "replicas"},
+	}
+
+	for i, step := range steps {
+		keys, ok := required[step.Type]
+		if !ok {
+			return fmt.Errorf("step %d: unsupported type %s", i, step.Type)
+		}
+		for _, key := range keys {
+			if _, ok := step.Params[key]; !ok {
+				return fmt.Errorf("step %d (%s): missing param %s", i, step.Name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// toPlaybookResponse 转换为修复剧本响应格式
+func (s *RemediationService) toPlaybookResponse(playbook *models.RemediationPlaybook) *PlaybookResponse {
+	var steps []PlaybookStep
+	json.Unmarshal([]byte(playbook.Steps), &steps)
+
+	return &PlaybookResponse{
+		ID:          playbook.ID,
+		Name:        playbook.Name,
+		Description: playbook.Description,
+		RuleID:      playbook.RuleID,
+		Enabled:     playbook.Enabled,
+		DryRun:      playbook.DryRun,
+		Steps:       steps,
+		RateLimit:   playbook.RateLimit,
+		RateWindow:  playbook.RateWindow,
+		CreatedBy:   playbook.CreatedBy,
+		UpdatedBy:   playbook.UpdatedBy,
+		CreatedAt:   playbook.CreatedAt,
+		UpdatedAt:   playbook.UpdatedAt,
+	}
+}
+
+// toExecutionResponse 转换为修复执行记录响应格式
+func (s *RemediationService) toExecutionResponse(exec *models.RemediationExecution) *RemediationExecutionResponse {
+	var results []*RemediationStepResult
+	json.Unmarshal([]byte(exec.StepResults), &results)
+	var vars map[string]string
+	json.Unmarshal([]byte(exec.Variables), &vars)
+
+	return &RemediationExecutionResponse{
+		ID:           exec.ID,
+		PlaybookID:   exec.PlaybookID,
+		AlertID:      exec.AlertID,
+		TargetID:     exec.TargetID,
+		Trigger:      exec.Trigger,
+		Status:       exec.Status,
+		DryRun:       exec.DryRun,
+		CurrentStep:  exec.CurrentStep,
+		StepResults:  results,
+		Variables:    vars,
+		Error:        exec.Error,
+		TriggeredBy:  exec.TriggeredBy,
+		ApprovedBy:   exec.ApprovedBy,
+		ApprovedAt:   exec.ApprovedAt,
+		ApprovedStep: exec.ApprovedStep,
+		StartedAt:    exec.StartedAt,
+		FinishedAt:   exec.FinishedAt,
+	}
+}
+
+// remediationHistoryContext 汇总告警的修复执行记录，供告警分析参考
+func (s *AIService) remediationHistoryContext(alertID uuid.UUID) string {
+	if alertID == uuid.Nil {
+		return ""
+	}
+
+	var executions []models.RemediationExecution
+	if err := s.db.Where("alert_id = ? AND dry_run = ?", alertID, false).
+		Order("started_at ASC").Limit(10).Find(&executions).Error; err != nil || len(executions) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, exec := range executions {
+		var playbook models.RemediationPlaybook
+		s.db.Unscoped().Select("name").First(&playbook, exec.PlaybookID)
+		b.WriteString(fmt.Sprintf("- %s 剧本 %s：%s", exec.StartedAt.Format("2006-01-02 15:04:05"), playbook.Name, exec.Status))
+		if exec.Error != "" {
+			b.WriteString(fmt.Sprintf("（%s）", exec.Error))
+		}
+		b.WriteString("\n")
+
+		var results []*RemediationStepResult
+		json.Unmarshal([]byte(exec.StepResults), &results)
+		for _, result := range results {
+			b.WriteString(fmt.Sprintf("  - 步骤 %s [%s]：%s", result.Name, result.Type, result.Status))
+			if result.Error != "" {
+				b.WriteString("，错误：" + result.Error)
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("请结合上述修复是否生效判断根因，若修复后告警仍未恢复，应指出可能的更深层原因。\n")
+	return b.String()
+}
+
+// renderParams 使用 {{变量}} 模板渲染步骤参数
+func renderParams(params map[string]interface{}, vars map[string]string) map[string]interface{} {
+	rendered := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		rendered[key] = renderValue(value, vars)
+	}
+	return rendered
+}
+
+// renderValue 递归渲染参数值中的字符串
+func renderValue(value interface{}, vars map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		for name, replacement := range vars {
+			v = strings.ReplaceAll(v, "{{"+name+"}}", replacement)
+		}
+		return v
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = renderValue(item, vars)
+		}
+		return out
+	case map[string]interface{}:
+		return renderParams(v, vars)
+	default:
+		return value
+	}
+}
+
+// paramString 读取字符串参数
+func paramString(params map[string]interface{}, key string) string {
+	if value, ok := params[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// paramInt 读取整数参数，模板渲染后的字符串同样支持
+func paramInt(params map[string]interface{}, key string, defaultValue int) int {
+	switch v := params[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
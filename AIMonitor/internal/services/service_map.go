@@ -0,0 +1,674 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 拓扑节点类型
+const (
+	ServiceNodeTypeService   = "service"
+	ServiceNodeTypeDatabase  = "database"
+	ServiceNodeTypeMessaging = "messaging"
+	ServiceNodeTypeExternal  = "external"
+)
+
+// defaultServiceMapWindow 未配置窗口时的默认聚合窗口
+const defaultServiceMapWindow = 15 * time.Minute
+
+// 拓扑对比时视为显著变化的阈值
+const (
+	topologyErrorRateDelta   = 0.01 // 错误率变化1个百分点
+	topologyLatencyChange    = 0.2  // p95延迟变化20%
+	topologyRequestRateRatio = 0.5  // 请求速率变化50%
+)
+
+// serviceMapSpan 拓扑聚合所需的跨度字段
+type serviceMapSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	ServiceName  string
+	SpanKind     string
+	Status       string
+	Duration     int64
+	StartTime    time.Time
+	Tags         string
+}
+
+// latencySamples 请求样本统计
+type latencySamples struct {
+	count     int
+	errors    int
+	durations []float64 // 毫秒
+}
+
+func (l *latencySamples) add(durationMicros int64, isError bool) {
+	l.count++
+	if isError {
+		l.errors++
+	}
+	l.durations = append(l.durations, float64(durationMicros)/1000)
+}
+
+// connectionMetrics 计算调用边指标
+func (l *latencySamples) connectionMetrics(window time.Duration) ConnectionMetrics {
+	sort.Float64s(l.durations)
+	metrics := ConnectionMetrics{
+		RequestCount: l.count,
+		ErrorCount:   l.errors,
+		LatencyP50:   percentileOf(l.durations, 0.50),
+		LatencyP95:   percentileOf(l.durations, 0.95),
+		LatencyP99:   percentileOf(l.durations, 0.99),
+	}
+	if l.count > 0 {
+		var sum float64
+		for _, d := range l.durations {
+			sum += d
+		}
+		metrics.LatencyAvg = sum / float64(l.count)
+		metrics.ErrorRate = float64(l.errors) / float64(l.count)
+	}
+	if window > 0 {
+		metrics.Throughput = float64(l.count) / window.Seconds()
+	}
+	return metrics
+}
+
+// serviceMetrics 计算服务节点指标
+func (l *latencySamples) serviceMetrics(window time.Duration) ServiceMetrics {
+	conn := l.connectionMetrics(window)
+	return ServiceMetrics{
+		RequestRate: conn.Throughput,
+		ErrorRate:   conn.ErrorRate,
+		LatencyP50:  conn.LatencyP50,
+		LatencyP95:  conn.LatencyP95,
+		LatencyP99:  conn.LatencyP99,
+		Throughput:  conn.Throughput,
+	}
+}
+
+// percentileOf 最近秩法计算分位数，输入需已排序
+func percentileOf(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// serviceMapWindows 返回配置的聚合窗口，第一个为默认窗口
+func (s *APMService) serviceMapWindows() []time.Duration {
+	windows := s.config.Monitoring.Tracing.ServiceMap.Windows
+	if len(windows) == 0 {
+		return []time.Duration{defaultServiceMapWindow}
+	}
+	return windows
+}
+
+// GetServiceMapForWindow 获取指定窗口的服务拓扑图，优先使用后台聚合结果；指定服务时只返回其直接上下游
+func (s *APMService) GetServiceMapForWindow(window time.Duration, service string) (*ServiceMap, error) {
+	serviceMap, err := s.aggregatedServiceMap(window)
+	if err != nil {
+		return nil, err
+	}
+	if service != "" {
+		return FilterServiceMap(serviceMap, service), nil
+	}
+	return serviceMap, nil
+}
+
+// aggregatedServiceMap 获取窗口聚合结果，缓存未命中时实时计算
+func (s *APMService) aggregatedServiceMap(window time.Duration) (*ServiceMap, error) {
+	ctx := context.Background()
+	if window <= 0 {
+		window = s.serviceMapWindows()[0]
+	}
+
+	cacheKey := fmt.Sprintf("service_map:%s", window)
+	if s.cacheManager != nil {
+		var serviceMap ServiceMap
+		if err := s.cacheManager.Get(ctx, cacheKey, &serviceMap); err == nil {
+			return &serviceMap, nil
+		}
+	}
+
+	end := time.Now()
+	serviceMap, err := s.BuildServiceMap(ctx, end.Add(-window), end)
+	if err != nil {
+		return nil, err
+	}
+
+	// 本地没有跨度数据时使用Jaeger的服务依赖
+	if len(serviceMap.Edges) == 0 {
+		edges, err := s.jaegerClient.GetDependencies(ctx, end, window)
+		if err == nil && len(edges) > 0 {
+			serviceMap = serviceMapFromEdges(edges)
+		}
+	}
+
+	s.cacheServiceMap(ctx, window, serviceMap)
+	return serviceMap, nil
+}
+
+// cacheServiceMap 缓存聚合结果，有效期覆盖两个聚合周期
+func (s *APMService) cacheServiceMap(ctx context.Context, window time.Duration, serviceMap *ServiceMap) {
+	if s.cacheManager == nil {
+		return
+	}
+	ttl := 2 * s.config.Monitoring.Tracing.ServiceMap.Interval
+	if ttl <= 0 {
+		ttl = 2 * time.Minute
+	}
+	if data, err := json.Marshal(serviceMap); err == nil {
+		s.cacheManager.Set(ctx, fmt.Sprintf("service_map:%s", window), string(data), ttl)
+	}
+}
+
+// BuildServiceMap 遍历时间范围内的父子跨度构建服务拓扑：
+// 父子跨度属于不同服务即为一次服务调用；没有下游跨度的client/producer跨度按db.system、peer.service等属性识别数据库和外部依赖
+func (s *APMService) BuildServiceMap(ctx context.Context, start, end time.Time) (*ServiceMap, error) {
+	maxSpans := s.config.Monitoring.Tracing.ServiceMap.MaxSpans
+	if maxSpans <= 0 {
+		maxSpans = 200000
+	}
+
+	// 超过上限时保留最近的跨度，多取一条用于判断是否截断
+	var spans []serviceMapSpan
+	if err := s.db.WithContext(ctx).Model(&models.APMTrace{}).
+		Select("trace_id, span_id, parent_span_id, service_name, span_kind, status, duration, start_time, "+
+			"CASE WHEN span_kind IN ('client', 'producer') THEN tags ELSE NULL END AS tags").
+		Where("start_time BETWEEN ? AND ?", start, end).
+		Order("start_time DESC").
+		Limit(maxSpans + 1).
+		Scan(&spans).Error; err != nil {
+		return nil, fmt.Errorf("failed to load spans: %w", err)
+	}
+
+	window := end.Sub(start)
+	truncated := len(spans) > maxSpans
+	if truncated {
+		// 截断后速率按实际覆盖的时间段计算
+		spans = spans[:maxSpans]
+		if covered := end.Sub(spans[len(spans)-1].StartTime); covered > 0 {
+			window = covered
+		}
+	}
+	byID := make(map[string]*serviceMapSpan, len(spans))
+	for i := range spans {
+		byID[spans[i].TraceID+":"+spans[i].SpanID] = &spans[i]
+	}
+
+	nodes := map[string]*ServiceNode{}
+	nodeStats := map[string]*latencySamples{}
+	edgeStats := map[[2]string]*latencySamples{}
+	protocols := map[[2]string]string{}
+	hasRemoteChild := map[*serviceMapSpan]bool{}
+
+	addNode := func(id, name, nodeType string) {
+		if _, ok := nodes[id]; !ok {
+			nodes[id] = &ServiceNode{ID: id, Name: name, Type: nodeType, Health: "unknown", Metadata: map[string]interface{}{}}
+		}
+	}
+	addEdge := func(source, target, protocol string, span *serviceMapSpan) {
+		key := [2]string{source, target}
+		if edgeStats[key] == nil {
+			edgeStats[key] = &latencySamples{}
+		}
+		edgeStats[key].add(span.Duration, span.Status == "error")
+		if protocol != "" && protocols[key] == "" {
+			protocols[key] = protocol
+		}
+	}
+
+	// 服务间调用：以被调方入口跨度计量
+	for i := range spans {
+		span := &spans[i]
+		addNode(span.ServiceName, span.ServiceName, ServiceNodeTypeService)
+
+		parent := byID[span.TraceID+":"+span.ParentSpanID]
+		remoteParent := parent != nil && parent.ServiceName != span.ServiceName
+		if span.ParentSpanID == "" || remoteParent || span.SpanKind == SpanKindServer || span.SpanKind == SpanKindConsumer {
+			if nodeStats[span.ServiceName] == nil {
+				nodeStats[span.ServiceName] = &latencySamples{}
+			}
+			nodeStats[span.ServiceName].add(span.Duration, span.Status == "error")
+		}
+		if remoteParent {
+			hasRemoteChild[parent] = true
+			addEdge(parent.ServiceName, span.ServiceName, spanProtocol(parent), span)
+		}
+	}
+
+	// 未被插桩的下游：数据库、消息队列和外部服务
+	for i := range spans {
+		span := &spans[i]
+		if span.SpanKind != SpanKindClient && span.SpanKind != SpanKindProducer {
+			continue
+		}
+		if hasRemoteChild[span] {
+			continue
+		}
+		id, name, nodeType := spanPeer(span)
+		if id == "" {
+			continue
+		}
+		addNode(id, name, nodeType)
+		addEdge(span.ServiceName, id, spanProtocol(span), span)
+	}
+
+	// 服务注册表补充版本和环境
+	var registered []models.APMService
+	s.db.WithContext(ctx).Select("name, version, environment, language, framework").Find(&registered)
+	for _, svc := range registered {
+		if node, ok := nodes[svc.Name]; ok {
+			node.Version = svc.Version
+			node.Environment = svc.Environment
+			if svc.Language != "" {
+				node.Metadata["language"] = svc.Language
+			}
+			if svc.Framework != "" {
+				node.Metadata["framework"] = svc.Framework
+			}
+		}
+	}
+
+	serviceMap := &ServiceMap{Services: []ServiceNode{}, Edges: []ServiceEdge{}, Truncated: truncated}
+	for id, node := range nodes {
+		if stats, ok := nodeStats[id]; ok {
+			node.Metrics = stats.serviceMetrics(window)
+			node.Health = healthFromErrorRate(node.Metrics.ErrorRate)
+		}
+		serviceMap.Services = append(serviceMap.Services, *node)
+	}
+	for key, stats := range edgeStats {
+		serviceMap.Edges = append(serviceMap.Edges, ServiceEdge{
+			Source:   key[0],
+			Target:   key[1],
+			Metrics:  stats.connectionMetrics(window),
+			Protocol: protocols[key],
+		})
+	}
+
+	// 虚拟节点的指标取自所有入边
+	for i := range serviceMap.Services {
+		node := &serviceMap.Services[i]
+		if node.Type == ServiceNodeTypeService {
+			continue
+		}
+		inbound := &latencySamples{}
+		for key, stats := range edgeStats {
+			if key[1] == node.ID {
+				inbound.count += stats.count
+				inbound.errors += stats.errors
+				inbound.durations = append(inbound.durations, stats.durations...)
+			}
+		}
+		node.Metrics = inbound.serviceMetrics(window)
+		node.Health = healthFromErrorRate(node.Metrics.ErrorRate)
+	}
+
+	sort.Slice(serviceMap.Services, func(i, j int) bool { return serviceMap.Services[i].ID < serviceMap.Services[j].ID })
+	sort.Slice(serviceMap.Edges, func(i, j int) bool {
+		if serviceMap.Edges[i].Source != serviceMap.Edges[j].Source {
+			return serviceMap.Edges[i].Source < serviceMap.Edges[j].Source
+		}
+		return serviceMap.Edges[i].Target < serviceMap.Edges[j].Target
+	})
+	return serviceMap, nil
+}
+
+// spanPeer 识别client/producer跨度的下游节点
+func spanPeer(span *serviceMapSpan) (id, name, nodeType string) {
+	tags := map[string]interface{}{}
+	if span.Tags != "" {
+		json.Unmarshal([]byte(span.Tags), &tags)
+	}
+
+	if peer := attributeString(tags, "peer.service"); peer != "" {
+		return peer, peer, ServiceNodeTypeService
+	}
+	if system := attributeString(tags, "db.system"); system != "" {
+		name = system
+		if host := attributeString(tags, "server.address", "net.peer.name", "net.peer.ip"); host != "" {
+			name = system + "@" + host
+		}
+		return "db:" + name, name, ServiceNodeTypeDatabase
+	}
+	if system := attributeString(tags, "messaging.system"); system != "" {
+		name = system
+		if dest := attributeString(tags, "messaging.destination.name", "messaging.destination"); dest != "" {
+			name = system + ":" + dest
+		}
+		return "mq:" + name, name, ServiceNodeTypeMessaging
+	}
+	host := attributeString(tags, "server.address", "net.peer.name", "http.host")
+	if host == "" {
+		if raw := attributeString(tags, "url.full", "http.url"); raw != "" {
+			if u, err := url.Parse(raw); err == nil {
+				host = u.Hostname()
+			}
+		}
+	}
+	if host != "" {
+		return "external:" + host, host, ServiceNodeTypeExternal
+	}
+	return "", "", ""
+}
+
+// spanProtocol 推断调用协议
+func spanProtocol(span *serviceMapSpan) string {
+	if span.Tags == "" {
+		if span.SpanKind == SpanKindProducer || span.SpanKind == SpanKindConsumer {
+			return "messaging"
+		}
+		return ""
+	}
+	tags := map[string]interface{}{}
+	json.Unmarshal([]byte(span.Tags), &tags)
+	switch {
+	case attributeString(tags, "db.system") != "":
+		return attributeString(tags, "db.system")
+	case attributeString(tags, "messaging.system") != "":
+		return attributeString(tags, "messaging.system")
+	case attributeString(tags, "rpc.system") != "":
+		return attributeString(tags, "rpc.system")
+	case attributeString(tags, "http.request.method", "http.method") != "":
+		return "http"
+	}
+	return ""
+}
+
+// healthFromErrorRate 按错误率评估节点健康度
+func healthFromErrorRate(errorRate float64) string {
+	switch {
+	case errorRate >= 0.05:
+		return "critical"
+	case errorRate >= 0.01:
+		return "warning"
+	default:
+		return "healthy"
+	}
+}
+
+// RunServiceMapAggregation 周期性按各滑动窗口聚合服务拓扑，并按快照间隔保存默认窗口的历史快照
+func (s *APMService) RunServiceMapAggregation(ctx context.Context, cfg config.ServiceMapConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	var lastSnapshot time.Time
+	var latest models.ServiceTopologySnapshot
+	if err := s.db.Order("captured_at DESC").First(&latest).Error; err == nil {
+		lastSnapshot = latest.CapturedAt
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			for i, window := range s.serviceMapWindows() {
+				serviceMap, err := s.BuildServiceMap(ctx, now.Add(-window), now)
+				if err != nil {
+					logger.GetLogger("apm").WithError(err).WithField("window", window.String()).Error("failed to build service map")
+					continue
+				}
+				s.cacheServiceMap(ctx, window, serviceMap)
+
+				if i == 0 && now.Sub(lastSnapshot) >= cfg.SnapshotInterval {
+					if err := s.saveTopologySnapshot(now, window, serviceMap); err == nil {
+						lastSnapshot = now
+					}
+				}
+			}
+
+			if cfg.HistoryRetention > 0 {
+				s.db.Unscoped().Where("captured_at < ?", now.Add(-cfg.HistoryRetention)).
+					Delete(&models.ServiceTopologySnapshot{})
+			}
+		}
+	}
+}
+
+// saveTopologySnapshot 保存拓扑快照
+func (s *APMService) saveTopologySnapshot(capturedAt time.Time, window time.Duration, serviceMap *ServiceMap) error {
+	data, err := json.Marshal(serviceMap)
+	if err != nil {
+		return fmt.Errorf("failed to encode service map: %w", err)
+	}
+	snapshot := &models.ServiceTopologySnapshot{
+		CapturedAt:    capturedAt,
+		WindowSeconds: int64(window.Seconds()),
+		NodeCount:     len(serviceMap.Services),
+		EdgeCount:     len(serviceMap.Edges),
+		Topology:      string(data),
+	}
+	if err := s.db.Create(snapshot).Error; err != nil {
+		return fmt.Errorf("failed to save topology snapshot: %w", err)
+	}
+	return nil
+}
+
+// TopologySnapshotInfo 拓扑快照摘要
+type TopologySnapshotInfo struct {
+	ID         string    `json:"id"`
+	CapturedAt time.Time `json:"captured_at"`
+	Window     string    `json:"window"`
+	NodeCount  int       `json:"node_count"`
+	EdgeCount  int       `json:"edge_count"`
+}
+
+// ListTopologySnapshots 列出时间范围内的拓扑快照
+func (s *APMService) ListTopologySnapshots(start, end time.Time, limit int) ([]TopologySnapshotInfo, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	var snapshots []models.ServiceTopologySnapshot
+	if err := s.db.Select("id, captured_at, window_seconds, node_count, edge_count").
+		Where("captured_at BETWEEN ? AND ?", start, end).
+		Order("captured_at DESC").Limit(limit).
+		Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to list topology snapshots: %w", err)
+	}
+
+	result := make([]TopologySnapshotInfo, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		result = append(result, TopologySnapshotInfo{
+			ID:         snapshot.ID.String(),
+			CapturedAt: snapshot.CapturedAt,
+			Window:     (time.Duration(snapshot.WindowSeconds) * time.Second).String(),
+			NodeCount:  snapshot.NodeCount,
+			EdgeCount:  snapshot.EdgeCount,
+		})
+	}
+	return result, nil
+}
+
+// GetServiceMapAt 获取指定时间点（含）之前最近的历史拓扑
+func (s *APMService) GetServiceMapAt(at time.Time) (*ServiceMap, *TopologySnapshotInfo, error) {
+	var snapshot models.ServiceTopologySnapshot
+	if err := s.db.Where("captured_at <= ?", at).Order("captured_at DESC").First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("topology snapshot not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get topology snapshot: %w", err)
+	}
+
+	var serviceMap ServiceMap
+	if err := json.Unmarshal([]byte(snapshot.Topology), &serviceMap); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode topology snapshot: %w", err)
+	}
+	info := &TopologySnapshotInfo{
+		ID:         snapshot.ID.String(),
+		CapturedAt: snapshot.CapturedAt,
+		Window:     (time.Duration(snapshot.WindowSeconds) * time.Second).String(),
+		NodeCount:  snapshot.NodeCount,
+		EdgeCount:  snapshot.EdgeCount,
+	}
+	return &serviceMap, info, nil
+}
+
+// ServiceEdgeChange 调用边指标变化
+type ServiceEdgeChange struct {
+	Source           string            `json:"source"`
+	Target           string            `json:"target"`
+	Before           ConnectionMetrics `json:"before"`
+	After            ConnectionMetrics `json:"after"`
+	ThroughputChange float64           `json:"throughput_change"`
+	ErrorRateDelta   float64           `json:"error_rate_delta"`
+	LatencyP95Change float64           `json:"latency_p95_change"`
+}
+
+// ServiceMapDiff 两个时间点的拓扑对比
+type ServiceMapDiff struct {
+	Before       *TopologySnapshotInfo `json:"before"`
+	After        *TopologySnapshotInfo `json:"after"`
+	AddedNodes   []ServiceNode         `json:"added_nodes"`
+	RemovedNodes []ServiceNode         `json:"removed_nodes"`
+	AddedEdges   []ServiceEdge         `json:"added_edges"`
+	RemovedEdges []ServiceEdge         `json:"removed_edges"`
+	ChangedEdges []ServiceEdgeChange   `json:"changed_edges"`
+}
+
+// DiffServiceMap 对比两个时间点的历史拓扑，例如发布前后的调用关系和调用质量变化
+func (s *APMService) DiffServiceMap(before, after time.Time) (*ServiceMapDiff, error) {
+	if !after.After(before) {
+		return nil, errors.New("after must be later than before")
+	}
+	beforeMap, beforeInfo, err := s.GetServiceMapAt(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, afterInfo, err := s.GetServiceMapAt(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffServiceMaps(beforeMap, afterMap)
+	diff.Before = beforeInfo
+	diff.After = afterInfo
+	return diff, nil
+}
+
+// diffServiceMaps 计算拓扑差异
+func diffServiceMaps(before, after *ServiceMap) *ServiceMapDiff {
+	diff := &ServiceMapDiff{
+		AddedNodes:   []ServiceNode{},
+		RemovedNodes: []ServiceNode{},
+		AddedEdges:   []ServiceEdge{},
+		RemovedEdges: []ServiceEdge{},
+		ChangedEdges: []ServiceEdgeChange{},
+	}
+
+	beforeNodes := map[string]bool{}
+	for _, node := range before.Services {
+		beforeNodes[node.ID] = true
+	}
+	afterNodes := map[string]bool{}
+	for _, node := range after.Services {
+		afterNodes[node.ID] = true
+		if !beforeNodes[node.ID] {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+		}
+	}
+	for _, node := range before.Services {
+		if !afterNodes[node.ID] {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+
+	edgeKey := func(edge ServiceEdge) string { return edge.Source + "->" + edge.Target }
+	beforeEdges := map[string]ServiceEdge{}
+	for _, edge := range before.Edges {
+		beforeEdges[edgeKey(edge)] = edge
+	}
+	afterEdges := map[string]bool{}
+	for _, edge := range after.Edges {
+		afterEdges[edgeKey(edge)] = true
+		prev, ok := beforeEdges[edgeKey(edge)]
+		if !ok {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+			continue
+		}
+
+		change := ServiceEdgeChange{
+			Source:           edge.Source,
+			Target:           edge.Target,
+			Before:           prev.Metrics,
+			After:            edge.Metrics,
+			ThroughputChange: relativeChange(prev.Metrics.Throughput, edge.Metrics.Throughput),
+			ErrorRateDelta:   edge.Metrics.ErrorRate - prev.Metrics.ErrorRate,
+			LatencyP95Change: relativeChange(prev.Metrics.LatencyP95, edge.Metrics.LatencyP95),
+		}
+		if math.Abs(change.ErrorRateDelta) >= topologyErrorRateDelta ||
+			math.Abs(change.LatencyP95Change) >= topologyLatencyChange ||
+			math.Abs(change.ThroughputChange) >= topologyRequestRateRatio {
+			diff.ChangedEdges = append(diff.ChangedEdges, change)
+		}
+	}
+	for _, edge := range before.Edges {
+		if !afterEdges[edgeKey(edge)] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+
+	sort.Slice(diff.ChangedEdges, func(i, j int) bool {
+		return math.Abs(diff.ChangedEdges[i].LatencyP95Change) > math.Abs(diff.ChangedEdges[j].LatencyP95Change)
+	})
+	return diff
+}
+
+// relativeChange 相对变化率，基准为0时按新值是否为0返回0或1
+func relativeChange(before, after float64) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (after - before) / before
+}
+
+// FilterServiceMap 只保留与指定服务直接相连的节点和边
+func FilterServiceMap(serviceMap *ServiceMap, service string) *ServiceMap {
+	filtered := &ServiceMap{Services: []ServiceNode{}, Edges: []ServiceEdge{}}
+	keep := map[string]bool{service: true}
+	for _, edge := range serviceMap.Edges {
+		if edge.Source == service || edge.Target == service {
+			filtered.Edges = append(filtered.Edges, edge)
+			keep[edge.Source] = true
+			keep[edge.Target] = true
+		}
+	}
+	for _, node := range serviceMap.Services {
+		if keep[node.ID] || strings.EqualFold(node.Name, service) {
+			filtered.Services = append(filtered.Services, node)
+		}
+	}
+	return filtered
+}
@@ -23,12 +23,24 @@ type Services struct {
 	MiddlewareService   *MiddlewareService
 	APMService          *APMService
 	ContainerService    *ContainerService
+	KubernetesClusters  *KubernetesClusterService
+	KubernetesInventory *KubernetesInventoryService
 	AgentService        *AgentService
 	APIKeyService       *APIKeyService
 	DiscoveryService    *DiscoveryService
+	InsightService      *InsightService
+	IncidentService     *IncidentService
+	RemediationService  *RemediationService
+	AssistantService    *AssistantService
+	TraceIngestService  *TraceIngestService
+	SpanMetrics         *SpanMetricsProcessor
+	SLOService          *SLOService
+	ContainerEvents     *ContainerEventService
 
 	// 数据库连接
 	DB *gorm.DB
+	// 应用配置
+	config *config.Config
 	// 缓存管理器
 	cacheManager *cache.CacheManager
 	// JWT管理器
@@ -52,6 +64,9 @@ func NewServices(cfg *config.Config, db *gorm.DB) (*Services, error) {
 	auditService := NewAuditService(db, cacheManager, cfg)
 	agentService := NewAgentService(db, cacheManager, cfg)
 	apikeyService := NewAPIKeyService(db)
+	insightService := NewInsightService(db, cacheManager, cfg, aiService)
+	spanMetricsProcessor := NewSpanMetricsProcessor(db, cacheManager, cfg, alertService)
+	traceIngestService := NewTraceIngestService(db, cacheManager, cfg, spanMetricsProcessor)
 
 	// 创建需要依赖其他服务的服务
 	monitoringService, err := NewMonitoringService(db, cacheManager, cfg, alertService)
@@ -69,11 +84,23 @@ func NewServices(cfg *config.Config, db *gorm.DB) (*Services, error) {
 		return nil, fmt.Errorf("failed to create apm service: %w", err)
 	}
 
-	containerService, err := NewContainerService(db, cacheManager, cfg)
+	kubernetesClusterService := NewKubernetesClusterService(db, cacheManager, cfg)
+	kubernetesInventoryService := NewKubernetesInventoryService(db, cacheManager, cfg, kubernetesClusterService, alertService)
+	containerService, err := NewContainerService(db, cacheManager, cfg, kubernetesClusterService, kubernetesInventoryService)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container service: %w", err)
 	}
 
+	containerEventService, err := NewContainerEventService(db, cacheManager, cfg, alertService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container event service: %w", err)
+	}
+
+	sloService := NewSLOService(db, cacheManager, cfg, alertService)
+	incidentService := NewIncidentService(db, cacheManager, cfg, aiService, apmService, notificationService)
+	remediationService := NewRemediationService(db, cacheManager, cfg, aiService, agentService, containerService, auditService)
+	assistantService := NewAssistantService(db, cacheManager, cfg, aiService, monitoringService, alertService, apmService, containerService)
+
 	// 创建发现服务
 	discoveryService := NewDiscoveryService(db, cacheManager, cfg, agentService, apikeyService)
 
@@ -88,10 +115,21 @@ func NewServices(cfg *config.Config, db *gorm.DB) (*Services, error) {
 		MiddlewareService:   middlewareService,
 		APMService:          apmService,
 		ContainerService:    containerService,
+		KubernetesClusters:  kubernetesClusterService,
+		KubernetesInventory: kubernetesInventoryService,
 		AgentService:        agentService,
 		APIKeyService:       apikeyService,
 		DiscoveryService:    discoveryService,
+		InsightService:      insightService,
+		IncidentService:     incidentService,
+		RemediationService:  remediationService,
+		AssistantService:    assistantService,
+		TraceIngestService:  traceIngestService,
+		SpanMetrics:         spanMetricsProcessor,
+		SLOService:          sloService,
+		ContainerEvents:     containerEventService,
 		DB:                  db,
+		config:              cfg,
 		cacheManager:        cacheManager,
 		JWTManager:          jwtManager,
 	}, nil
@@ -100,8 +138,92 @@ func NewServices(cfg *config.Config, db *gorm.DB) (*Services, error) {
 // Start 启动所有服务
 func (s *Services) Start(ctx context.Context) error {
 	// 缓存管理器不需要显式启动
-	// 这里可以添加其他需要启动的服务
-	// 例如：定时任务、后台处理器等
+	// 后台任务随ctx取消而退出
+
+	// 写入内置提示词模板
+	if err := s.AIService.EnsureDefaultPromptTemplates(); err != nil {
+		return fmt.Errorf("failed to seed prompt templates: %w", err)
+	}
+
+	// 将monitoring.kubernetes配置注册为集群
+	if err := s.KubernetesClusters.EnsureConfigCluster(); err != nil {
+		return fmt.Errorf("failed to register kubernetes cluster: %w", err)
+	}
+
+	// 异常检测告警任务
+	if s.config.Alerting.AnomalyDetection.Enabled {
+		go s.AlertService.RunAnomalyDetection(ctx, s.config.Alerting.AnomalyDetection)
+	}
+
+	// 容量预测告警任务
+	if s.config.Alerting.CapacityPlanning.Enabled {
+		go s.AlertService.RunForecastEvaluation(ctx, s.config.Alerting.CapacityPlanning)
+	}
+
+	// 发布版本回归检测任务
+	if s.config.Alerting.VersionRegression.Enabled {
+		go s.AlertService.RunVersionRegressionDetection(ctx, s.config.Alerting.VersionRegression, s.APMService)
+	}
+
+	// SLO燃烧率告警与错误预算历史任务
+	if s.config.Alerting.SLO.Enabled {
+		go s.SLOService.RunSLOEvaluation(ctx, s.config.Alerting.SLO)
+	}
+
+	// 智能洞察生成任务
+	if s.config.Alerting.Insights.Enabled {
+		go s.InsightService.RunInsightGeneration(ctx, s.config.Alerting.Insights)
+	}
+
+	// 告警关联任务
+	if s.config.Alerting.Correlation.Enabled {
+		go s.IncidentService.RunIncidentCorrelation(ctx, s.config.Alerting.Correlation)
+	}
+
+	// 自动修复任务
+	if s.config.Alerting.Remediation.Enabled {
+		go s.RemediationService.RunRemediation(ctx, s.config.Alerting.Remediation)
+	}
+
+	// 服务拓扑聚合任务
+	if s.config.Monitoring.Tracing.ServiceMap.Enabled {
+		go s.APMService.RunServiceMapAggregation(ctx, s.config.Monitoring.Tracing.ServiceMap)
+	}
+
+	// 跨度RED指标刷新任务
+	if s.config.Monitoring.Tracing.SpanMetrics.Enabled {
+		go s.SpanMetrics.RunSpanMetrics(ctx, s.config.Monitoring.Tracing.SpanMetrics)
+	}
+
+	// 链路尾部采样决策与保留清理任务
+	if s.config.Monitoring.Tracing.Sampling.Enabled {
+		go s.TraceIngestService.RunTailSampling(ctx)
+	}
+	if s.config.Monitoring.Tracing.Retention.Enabled {
+		go s.TraceIngestService.RunTraceRetention(ctx, s.config.Monitoring.Tracing.Retention)
+	}
+
+	// Kubernetes集群健康检查任务
+	if s.config.Monitoring.Kubernetes.HealthCheckInterval > 0 {
+		go s.KubernetesClusters.RunHealthChecks(ctx, s.config.Monitoring.Kubernetes)
+	}
+
+	// Kubernetes资源list+watch缓存与内置检测器
+	if s.config.Monitoring.Kubernetes.Inventory.Enabled {
+		go s.KubernetesInventory.RunInventory(ctx, s.config.Monitoring.Kubernetes.Inventory)
+	}
+
+	// Docker容器事件订阅任务
+	if s.config.Monitoring.Docker.Events.Enabled {
+		go s.ContainerEvents.RunDockerEvents(ctx, s.config.Monitoring.Docker.Events)
+	}
+
+	// OTLP/gRPC链路接收端，OTLP/HTTP由主服务路由处理
+	if s.config.Monitoring.Tracing.OTLP.Enabled {
+		if err := s.TraceIngestService.StartOTLPGRPC(ctx, s.config.Monitoring.Tracing.OTLP); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -117,6 +239,11 @@ func (s *Services) GetCacheManager() *cache.CacheManager {
 	return s.cacheManager
 }
 
+// GetConfig 获取应用配置
+func (s *Services) GetConfig() *config.Config {
+	return s.config
+}
+
 // GetJWTManager 获取JWT管理器
 func (s *Services) GetJWTManager() *auth.JWTManager {
 	return s.JWTManager
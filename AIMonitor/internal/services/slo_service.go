@@ -0,0 +1,891 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SLOMetricBurnRate SLO燃烧率告警使用的指标名，取值为长窗口燃烧率
+const SLOMetricBurnRate = "slo.burn_rate"
+
+// metric来源SLO的计数指标类型
+const (
+	// SLOMetricKindDelta 指标值为每个周期内的计数，窗口内直接求和
+	SLOMetricKindDelta = "delta"
+	// SLOMetricKindCounter 指标值为单调递增的累计计数器，按相邻采样增量计算
+	SLOMetricKindCounter = "counter"
+)
+
+// defaultBurnRateWindows 未配置燃烧率窗口时使用的多窗口多燃烧率组合：
+// 1小时消耗2%预算（14.4倍）或6小时消耗5%（6倍）为紧急，1天消耗10%（3倍）或3天消耗10%（1倍）为一般
+var defaultBurnRateWindows = []config.BurnRateWindowConfig{
+	{LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4, Severity: "critical"},
+	{LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6, Severity: "critical"},
+	{LongWindow: 24 * time.Hour, ShortWindow: 2 * time.Hour, BurnRate: 3, Severity: "medium"},
+	{LongWindow: 72 * time.Hour, ShortWindow: 6 * time.Hour, BurnRate: 1, Severity: "medium"},
+}
+
+// SLOService SLO服务
+type SLOService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	alertService *AlertService
+
+	mu          sync.Mutex
+	lastHistory map[uuid.UUID]time.Time
+}
+
+// CreateSLORequest 创建SLO请求。apm来源按服务（可选操作）的跨度指标计算可用性或延迟达标率，
+// metric来源取两个计数指标：metric_kind为delta（默认）时指标为按周期计数（如apm.requests），在窗口内求和；
+// 为counter时指标为累计计数器，按相邻采样的增量计算
+type CreateSLORequest struct {
+	Name             string     `json:"name" binding:"required,max=60"`
+	Description      string     `json:"description" binding:"max=500"`
+	SourceType       string     `json:"source_type" binding:"required,oneof=apm metric"`
+	ServiceName      string     `json:"service_name"`
+	Operation        string     `json:"operation"`
+	Indicator        string     `json:"indicator" binding:"omitempty,oneof=availability latency"`
+	LatencyThreshold float64    `json:"latency_threshold" binding:"omitempty,gt=0"`
+	GoodMetric       string     `json:"good_metric"`
+	TotalMetric      string     `json:"total_metric"`
+	MetricKind       string     `json:"metric_kind" binding:"omitempty,oneof=delta counter"`
+	MetricTargetID   *uuid.UUID `json:"metric_target_id"`
+	Target           float64    `json:"target" binding:"required,gt=0,lt=100"`
+	WindowDays       int        `json:"window_days" binding:"omitempty,min=1,max=90"`
+	Enabled          *bool      `json:"enabled"`
+}
+
+// UpdateSLORequest 更新SLO请求，事件来源不可修改
+type UpdateSLORequest struct {
+	Name             *string  `json:"name" binding:"omitempty,max=60"`
+	Description      *string  `json:"description" binding:"omitempty,max=500"`
+	LatencyThreshold *float64 `json:"latency_threshold" binding:"omitempty,gt=0"`
+	Target           *float64 `json:"target" binding:"omitempty,gt=0,lt=100"`
+	WindowDays       *int     `json:"window_days" binding:"omitempty,min=1,max=90"`
+	Enabled          *bool    `json:"enabled"`
+}
+
+// BurnRateStatus 燃烧率告警规则的当前状态
+type BurnRateStatus struct {
+	RuleID        uuid.UUID `json:"rule_id"`
+	LongWindow    string    `json:"long_window"`
+	ShortWindow   string    `json:"short_window"`
+	Threshold     float64   `json:"threshold"`
+	Severity      string    `json:"severity"`
+	LongBurnRate  float64   `json:"long_burn_rate"`
+	ShortBurnRate float64   `json:"short_burn_rate"`
+	Firing        bool      `json:"firing"`
+}
+
+// SLOStatus SLO在滚动窗口内的达成情况
+type SLOStatus struct {
+	Good  float64 `json:"good"`
+	Total float64 `json:"total"`
+	// SLI 好事件百分比，无事件时为100
+	SLI float64 `json:"sli"`
+	// ErrorBudget 窗口内允许的坏事件数
+	ErrorBudget float64 `json:"error_budget"`
+	// BudgetConsumed 窗口内的坏事件数
+	BudgetConsumed float64 `json:"budget_consumed"`
+	// BudgetRemaining 剩余错误预算比例，预算耗尽后为负
+	BudgetRemaining float64          `json:"budget_remaining"`
+	BurnRates       []BurnRateStatus `json:"burn_rates"`
+	EvaluatedAt     time.Time        `json:"evaluated_at"`
+}
+
+// SLOResponse SLO响应
+type SLOResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Description      string     `json:"description"`
+	SourceType       string     `json:"source_type"`
+	ServiceName      string     `json:"service_name"`
+	Operation        string     `json:"operation"`
+	Indicator        string     `json:"indicator"`
+	LatencyThreshold float64    `json:"latency_threshold"`
+	GoodMetric       string     `json:"good_metric"`
+	TotalMetric      string     `json:"total_metric"`
+	MetricKind       string     `json:"metric_kind"`
+	MetricTargetID   *uuid.UUID `json:"metric_target_id"`
+	Target           float64    `json:"target"`
+	WindowDays       int        `json:"window_days"`
+	Enabled          bool       `json:"enabled"`
+	Status           *SLOStatus `json:"status,omitempty"`
+	CreatedBy        uuid.UUID  `json:"created_by"`
+	UpdatedBy        uuid.UUID  `json:"updated_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// SLOReportEntry SLO报表条目
+type SLOReportEntry struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	ServiceName     string     `json:"service_name"`
+	Target          float64    `json:"target"`
+	WindowDays      int        `json:"window_days"`
+	SLI             float64    `json:"sli"`
+	BudgetRemaining float64    `json:"budget_remaining"`
+	FiringAlerts    int64      `json:"firing_alerts"`
+	EvaluatedAt     *time.Time `json:"evaluated_at"`
+}
+
+// SLOHistoryPoint 错误预算历史点
+type SLOHistoryPoint struct {
+	Timestamp       time.Time `json:"timestamp"`
+	Good            float64   `json:"good"`
+	Total           float64   `json:"total"`
+	SLI             float64   `json:"sli"`
+	BudgetRemaining float64   `json:"budget_remaining"`
+}
+
+// sloSample 某一时刻写入的好事件和总事件数
+type sloSample struct {
+	At    time.Time
+	Good  float64
+	Total float64
+}
+
+// NewSLOService 创建SLO服务
+func NewSLOService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, alertService *AlertService) *SLOService {
+	return &SLOService{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       config,
+		alertService: alertService,
+		lastHistory:  map[uuid.UUID]time.Time{},
+	}
+}
+
+// CreateSLO 创建SLO并生成燃烧率告警规则
+func (s *SLOService) CreateSLO(req *CreateSLORequest, userID uuid.UUID) (*SLOResponse, error) {
+	if err := s.db.Where("name = ?", req.Name).First(&models.SLO{}).Error; err == nil {
+		return nil, errors.New("slo name already exists")
+	}
+
+	slo := models.SLO{
+		Name:             req.Name,
+		Description:      req.Description,
+		SourceType:       req.SourceType,
+		ServiceName:      req.ServiceName,
+		Operation:        req.Operation,
+		Indicator:        req.Indicator,
+		LatencyThreshold: req.LatencyThreshold,
+		GoodMetric:       req.GoodMetric,
+		TotalMetric:      req.TotalMetric,
+		MetricKind:       req.MetricKind,
+		MetricTargetID:   req.MetricTargetID,
+		Target:           req.Target,
+		WindowDays:       req.WindowDays,
+		Enabled:          true,
+		SLI:              100,
+		BudgetRemaining:  1,
+		CreatedBy:        userID,
+		UpdatedBy:        userID,
+	}
+	if slo.WindowDays == 0 {
+		slo.WindowDays = 30
+	}
+	if req.Enabled != nil {
+		slo.Enabled = *req.Enabled
+	}
+	if err := validateSLOSource(&slo); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// GORM会跳过零值字段而使用数据库默认值，显式写入布尔字段
+		if err := tx.Select("*").Create(&slo).Error; err != nil {
+			return fmt.Errorf("failed to create slo: %w", err)
+		}
+		for _, window := range s.burnRateWindows() {
+			rule := burnRateRule(&slo, window, userID)
+			if err := tx.Select("*").Create(rule).Error; err != nil {
+				return fmt.Errorf("failed to create burn rate rule: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.alertService.clearAlertRuleCache()
+
+	return toSLOResponse(&slo), nil
+}
+
+// GetSLO 获取SLO及实时计算的达成情况
+func (s *SLOService) GetSLO(ctx context.Context, id uuid.UUID) (*SLOResponse, error) {
+	slo, err := s.getSLO(id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.sloRules(slo.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	samples, err := s.loadSamples(ctx, slo, now.Add(-sampleLookback(slo, rules, true)), now)
+	if err != nil {
+		return nil, err
+	}
+	status := computeSLOStatus(slo, samples, now)
+	for i := range rules {
+		status.BurnRates = append(status.BurnRates, burnRateStatusOf(slo, &rules[i], samples, now))
+	}
+
+	response := toSLOResponse(slo)
+	response.Status = status
+	return response, nil
+}
+
+// UpdateSLO 更新SLO，名称和启用状态同步到燃烧率规则
+func (s *SLOService) UpdateSLO(id uuid.UUID, req *UpdateSLORequest, userID uuid.UUID) (*SLOResponse, error) {
+	slo, err := s.getSLO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"updated_by": userID}
+	if req.Name != nil && *req.Name != slo.Name {
+		if err := s.db.Where("name = ? AND id != ?", *req.Name, id).First(&models.SLO{}).Error; err == nil {
+			return nil, errors.New("slo name already exists")
+		}
+		updates["name"] = *req.Name
+		slo.Name = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.LatencyThreshold != nil {
+		if slo.Indicator != "latency" {
+			return nil, errors.New("latency_threshold only applies to latency slos")
+		}
+		updates["latency_threshold"] = *req.LatencyThreshold
+	}
+	if req.Target != nil {
+		updates["target"] = *req.Target
+	}
+	if req.WindowDays != nil {
+		updates["window_days"] = *req.WindowDays
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	rules, err := s.sloRules(id, false)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(slo).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update slo: %w", err)
+		}
+		for i := range rules {
+			ruleUpdates := map[string]interface{}{"name": burnRateRuleName(slo, &rules[i])}
+			if req.Enabled != nil {
+				ruleUpdates["enabled"] = *req.Enabled
+			}
+			if err := tx.Model(&rules[i]).Updates(ruleUpdates).Error; err != nil {
+				return fmt.Errorf("failed to update burn rate rule: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.alertService.clearAlertRuleCache()
+
+	updated, err := s.getSLO(id)
+	if err != nil {
+		return nil, err
+	}
+	return toSLOResponse(updated), nil
+}
+
+// DeleteSLO 删除SLO及其燃烧率规则，未解决的燃烧率告警一并解决
+func (s *SLOService) DeleteSLO(id uuid.UUID) error {
+	slo, err := s.getSLO(id)
+	if err != nil {
+		return err
+	}
+	rules, err := s.sloRules(id, false)
+	if err != nil {
+		return err
+	}
+
+	data := &MetricData{TargetType: "slo", TargetID: slo.ID.String(), MetricName: SLOMetricBurnRate}
+	for i := range rules {
+		if err := s.alertService.resolveEvaluatedAlert(&rules[i], data); err != nil {
+			logger.GetLogger("slo").WithError(err).WithField("slo", slo.Name).Warn("failed to resolve burn rate alert before deleting slo")
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("slo_id = ?", id).Delete(&models.AlertRule{}).Error; err != nil {
+			return fmt.Errorf("failed to delete burn rate rules: %w", err)
+		}
+		if err := tx.Where("slo_id = ?", id).Delete(&models.SLOSnapshot{}).Error; err != nil {
+			return fmt.Errorf("failed to delete slo history: %w", err)
+		}
+		if err := tx.Delete(slo).Error; err != nil {
+			return fmt.Errorf("failed to delete slo: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.alertService.clearAlertRuleCache()
+
+	s.mu.Lock()
+	delete(s.lastHistory, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// ListSLOs 获取SLO列表
+func (s *SLOService) ListSLOs(page, pageSize int, serviceName string) ([]*SLOResponse, int64, error) {
+	query := s.db.Model(&models.SLO{})
+	if serviceName != "" {
+		query = query.Where("service_name = ?", serviceName)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count slos: %w", err)
+	}
+
+	var slos []models.SLO
+	offset := (page - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&slos).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list slos: %w", err)
+	}
+
+	responses := make([]*SLOResponse, len(slos))
+	for i := range slos {
+		responses[i] = toSLOResponse(&slos[i])
+	}
+	return responses, total, nil
+}
+
+// GetSLOHistory 获取错误预算历史
+func (s *SLOService) GetSLOHistory(id uuid.UUID, start, end time.Time) ([]SLOHistoryPoint, error) {
+	if _, err := s.getSLO(id); err != nil {
+		return nil, err
+	}
+
+	var snapshots []models.SLOSnapshot
+	if err := s.db.Where("slo_id = ? AND timestamp BETWEEN ? AND ?", id, start, end).
+		Order("timestamp ASC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get slo history: %w", err)
+	}
+
+	points := make([]SLOHistoryPoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		points = append(points, SLOHistoryPoint{
+			Timestamp:       snapshot.Timestamp,
+			Good:            snapshot.Good,
+			Total:           snapshot.Total,
+			SLI:             snapshot.SLI,
+			BudgetRemaining: snapshot.BudgetRemaining,
+		})
+	}
+	return points, nil
+}
+
+// GetSLOReport 按剩余错误预算从少到多列出已启用的SLO，尚未评估的排在最后
+func (s *SLOService) GetSLOReport(serviceName string) ([]SLOReportEntry, error) {
+	query := s.db.Where("enabled = ?", true)
+	if serviceName != "" {
+		query = query.Where("service_name = ?", serviceName)
+	}
+	var slos []models.SLO
+	if err := query.Find(&slos).Error; err != nil {
+		return nil, fmt.Errorf("failed to list slos: %w", err)
+	}
+
+	var firing []struct {
+		SLOID uuid.UUID
+		Count int64
+	}
+	if err := s.db.Table("alerts").
+		Select("alert_rules.slo_id AS slo_id, COUNT(*) AS count").
+		Joins("JOIN alert_rules ON alert_rules.id = alerts.rule_id").
+		Where("alerts.status = ? AND alerts.deleted_at IS NULL AND alert_rules.slo_id IS NOT NULL", "firing").
+		Group("alert_rules.slo_id").
+		Scan(&firing).Error; err != nil {
+		return nil, fmt.Errorf("failed to count slo alerts: %w", err)
+	}
+	firingBySLO := make(map[uuid.UUID]int64, len(firing))
+	for _, f := range firing {
+		firingBySLO[f.SLOID] = f.Count
+	}
+
+	report := make([]SLOReportEntry, 0, len(slos))
+	for _, slo := range slos {
+		report = append(report, SLOReportEntry{
+			ID:              slo.ID,
+			Name:            slo.Name,
+			ServiceName:     slo.ServiceName,
+			Target:          slo.Target,
+			WindowDays:      slo.WindowDays,
+			SLI:             slo.SLI,
+			BudgetRemaining: slo.BudgetRemaining,
+			FiringAlerts:    firingBySLO[slo.ID],
+			EvaluatedAt:     slo.EvaluatedAt,
+		})
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		if (report[i].EvaluatedAt == nil) != (report[j].EvaluatedAt == nil) {
+			return report[j].EvaluatedAt == nil
+		}
+		return report[i].BudgetRemaining < report[j].BudgetRemaining
+	})
+	return report, nil
+}
+
+// RunSLOEvaluation 周期性评估燃烧率告警并记录错误预算历史，直到ctx取消
+func (s *SLOService) RunSLOEvaluation(ctx context.Context, cfg config.SLOConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EvaluateSLOs(ctx, cfg); err != nil {
+				logger.GetLogger("slo").WithError(err).Error("failed to evaluate slos")
+			}
+			if cfg.HistoryRetention > 0 {
+				s.db.Where("timestamp < ?", time.Now().Add(-cfg.HistoryRetention)).Delete(&models.SLOSnapshot{})
+			}
+		}
+	}
+}
+
+// EvaluateSLOs 评估所有启用的SLO
+func (s *SLOService) EvaluateSLOs(ctx context.Context, cfg config.SLOConfig) error {
+	var slos []models.SLO
+	if err := s.db.WithContext(ctx).Where("enabled = ?", true).Find(&slos).Error; err != nil {
+		return fmt.Errorf("failed to query slos: %w", err)
+	}
+
+	// 单个SLO失败不影响其余SLO的评估，错误汇总后返回
+	var errs []error
+	for i := range slos {
+		if err := s.evaluateSLO(ctx, cfg, &slos[i]); err != nil {
+			errs = append(errs, fmt.Errorf("slo %s: %w", slos[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evaluateSLO 按最长的燃烧率窗口加载一次事件，依次评估各规则；到达记录间隔时再按整个SLO窗口计算错误预算
+func (s *SLOService) evaluateSLO(ctx context.Context, cfg config.SLOConfig, slo *models.SLO) error {
+	rules, err := s.sloRules(slo.ID, true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	recordHistory := now.Sub(s.lastHistory[slo.ID]) >= cfg.HistoryInterval
+	s.mu.Unlock()
+
+	samples, err := s.loadSamples(ctx, slo, now.Add(-sampleLookback(slo, rules, recordHistory)), now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range rules {
+		rule := &rules[i]
+		burn := burnRateStatusOf(slo, rule, samples, now)
+		data := &MetricData{
+			TargetType: "slo",
+			TargetID:   slo.ID.String(),
+			MetricName: SLOMetricBurnRate,
+			Value:      burn.LongBurnRate,
+			Tags:       map[string]interface{}{"slo": slo.Name, "service": slo.ServiceName},
+			Timestamp:  now,
+		}
+		if !burn.Firing {
+			// 短窗口回落即解决，避免故障恢复后长窗口拖尾
+			if err := s.alertService.resolveEvaluatedAlert(rule, data); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		ea := &evaluatedAlert{
+			Summary: fmt.Sprintf("SLO %s is burning error budget at %.1fx over %s (%.1fx over %s)",
+				slo.Name, burn.LongBurnRate, burn.LongWindow, burn.ShortBurnRate, burn.ShortWindow),
+			Description: fmt.Sprintf("At this rate the %d-day error budget of SLO %s (target %g%%) is exhausted in %s.",
+				slo.WindowDays, slo.Name, slo.Target, formatWindow(time.Duration(float64(sloWindow(slo))/burn.LongBurnRate))),
+			Details:   burn,
+			Reference: rule.Threshold,
+		}
+		if err := s.alertService.fireEvaluatedAlert(rule, data, ea); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// 告警评估的错误不影响错误预算历史的记录
+	alertErr := errors.Join(errs...)
+	if !recordHistory {
+		return alertErr
+	}
+	status := computeSLOStatus(slo, samples, now)
+	if err := s.db.WithContext(ctx).Model(slo).Updates(map[string]interface{}{
+		"sli":              status.SLI,
+		"budget_remaining": status.BudgetRemaining,
+		"evaluated_at":     &now,
+	}).Error; err != nil {
+		return errors.Join(alertErr, fmt.Errorf("failed to update slo status: %w", err))
+	}
+	if err := s.db.WithContext(ctx).Create(&models.SLOSnapshot{
+		SLOID:           slo.ID,
+		Timestamp:       now,
+		Good:            status.Good,
+		Total:           status.Total,
+		SLI:             status.SLI,
+		BudgetRemaining: status.BudgetRemaining,
+	}).Error; err != nil {
+		return errors.Join(alertErr, fmt.Errorf("failed to save slo snapshot: %w", err))
+	}
+
+	s.mu.Lock()
+	s.lastHistory[slo.ID] = now
+	s.mu.Unlock()
+	return alertErr
+}
+
+// loadSamples 加载时间范围内（不含起点）的事件计数
+func (s *SLOService) loadSamples(ctx context.Context, slo *models.SLO, start, end time.Time) ([]sloSample, error) {
+	if slo.SourceType == "metric" {
+		var rows []models.MetricData
+		query := s.db.WithContext(ctx).
+			Select("target_id, metric, value, timestamp").
+			Where("metric IN ? AND timestamp > ? AND timestamp <= ?", []string{slo.GoodMetric, slo.TotalMetric}, start, end)
+		if slo.MetricTargetID != nil {
+			query = query.Where("target_id = ?", *slo.MetricTargetID)
+		}
+		if err := query.Order("timestamp ASC").Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to load slo metrics: %w", err)
+		}
+		if slo.MetricKind == SLOMetricKindCounter {
+			return counterIncreaseSamples(rows, slo.GoodMetric), nil
+		}
+
+		samples := make([]sloSample, 0, len(rows))
+		for _, row := range rows {
+			sample := sloSample{At: row.Timestamp}
+			if row.Metric == slo.GoodMetric {
+				sample.Good = row.Value
+			} else {
+				sample.Total = row.Value
+			}
+			samples = append(samples, sample)
+		}
+		return samples, nil
+	}
+
+	// 跨度指标按结束时间归入窗口，跨越窗口起点的汇总行整行计入
+	var rows []models.APMSpanMetric
+	query := s.db.WithContext(ctx).
+		Where("service_name = ? AND bucket_end > ? AND bucket_end <= ?", slo.ServiceName, start, end)
+	if slo.Operation != "" {
+		query = query.Where("operation_name = ?", slo.Operation)
+	}
+	if slo.Indicator != "latency" {
+		query = query.Select("status, count, bucket_end")
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load span metrics: %w", err)
+	}
+
+	samples := make([]sloSample, 0, len(rows))
+	for _, row := range rows {
+		sample := sloSample{At: row.BucketEnd, Total: float64(row.Count)}
+		if slo.Indicator == "latency" {
+			hist := newLatencyHistogram()
+			json.Unmarshal([]byte(row.Histogram), &hist.Counts)
+			hist.Max = row.DurationMax
+			sample.Good = hist.countBelow(slo.LatencyThreshold)
+		} else if row.Status != "error" {
+			sample.Good = sample.Total
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// counterIncreaseSamples 将按时间升序排列的累计计数器转换为相邻采样间的增量
+// 每个序列（目标+指标）的首个采样只作为基准不计入，计数器回退视为重置，重置后的值即为增量
+func counterIncreaseSamples(rows []models.MetricData, goodMetric string) []sloSample {
+	type seriesKey struct {
+		TargetID uuid.UUID
+		Metric   string
+	}
+	last := make(map[seriesKey]float64)
+	samples := make([]sloSample, 0, len(rows))
+	for _, row := range rows {
+		key := seriesKey{TargetID: row.TargetID, Metric: row.Metric}
+		prev, ok := last[key]
+		last[key] = row.Value
+		if !ok {
+			continue
+		}
+
+		increase := row.Value - prev
+		if increase < 0 {
+			increase = row.Value
+		}
+		sample := sloSample{At: row.Timestamp}
+		if row.Metric == goodMetric {
+			sample.Good = increase
+		} else {
+			sample.Total = increase
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// burnRateWindows 配置的燃烧率窗口
+func (s *SLOService) burnRateWindows() []config.BurnRateWindowConfig {
+	if windows := s.config.Alerting.SLO.BurnRateWindows; len(windows) > 0 {
+		return windows
+	}
+	return defaultBurnRateWindows
+}
+
+// getSLO 按ID获取SLO
+func (s *SLOService) getSLO(id uuid.UUID) (*models.SLO, error) {
+	var slo models.SLO
+	if err := s.db.First(&slo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("slo not found")
+		}
+		return nil, fmt.Errorf("failed to get slo: %w", err)
+	}
+	return &slo, nil
+}
+
+// sloRules 获取SLO的燃烧率规则，按长窗口从短到长排列
+func (s *SLOService) sloRules(sloID uuid.UUID, enabledOnly bool) ([]models.AlertRule, error) {
+	query := s.db.Where("slo_id = ? AND kind = ?", sloID, "slo")
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	var rules []models.AlertRule
+	if err := query.Order("duration ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to query burn rate rules: %w", err)
+	}
+	return rules, nil
+}
+
+// validateSLOSource 校验事件来源配置
+func validateSLOSource(slo *models.SLO) error {
+	switch slo.SourceType {
+	case "apm":
+		if slo.ServiceName == "" {
+			return errors.New("service_name is required for apm slos")
+		}
+		if slo.Indicator == "" {
+			slo.Indicator = "availability"
+		}
+		if slo.Indicator == "latency" && slo.LatencyThreshold <= 0 {
+			return errors.New("latency_threshold is required for latency slos")
+		}
+	case "metric":
+		if slo.GoodMetric == "" || slo.TotalMetric == "" {
+			return errors.New("good_metric and total_metric are required for metric slos")
+		}
+		if slo.GoodMetric == slo.TotalMetric {
+			return errors.New("good_metric and total_metric must differ")
+		}
+		if slo.MetricKind == "" {
+			slo.MetricKind = SLOMetricKindDelta
+		}
+		slo.Indicator = ""
+	}
+	return nil
+}
+
+// burnRateRule 由燃烧率窗口生成告警规则：Duration为长窗口秒数，ShortWindow为短窗口秒数，Threshold为燃烧率
+func burnRateRule(slo *models.SLO, window config.BurnRateWindowConfig, userID uuid.UUID) *models.AlertRule {
+	labels, _ := json.Marshal(map[string]interface{}{"slo_id": slo.ID.String()})
+	rule := &models.AlertRule{
+		Description: fmt.Sprintf("Error budget burn rate alert generated for SLO %s", slo.Name),
+		Metric:      SLOMetricBurnRate,
+		Kind:        "slo",
+		Condition:   ">=",
+		Threshold:   window.BurnRate,
+		Duration:    int(window.LongWindow / time.Second),
+		ShortWindow: int(window.ShortWindow / time.Second),
+		Severity:    window.Severity,
+		Enabled:     slo.Enabled,
+		Labels:      string(labels),
+		Annotations: "[]",
+		SLOID:       &slo.ID,
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}
+	rule.Name = burnRateRuleName(slo, rule)
+	return rule
+}
+
+// burnRateRuleName 燃烧率规则名称
+func burnRateRuleName(slo *models.SLO, rule *models.AlertRule) string {
+	return fmt.Sprintf("%s burn rate %gx (%s/%s)", slo.Name, rule.Threshold,
+		formatWindow(time.Duration(rule.Duration)*time.Second), formatWindow(time.Duration(rule.ShortWindow)*time.Second))
+}
+
+// burnRateStatusOf 计算规则长短窗口的燃烧率，两者都达到阈值时为触发
+func burnRateStatusOf(slo *models.SLO, rule *models.AlertRule, samples []sloSample, now time.Time) BurnRateStatus {
+	long := time.Duration(rule.Duration) * time.Second
+	short := time.Duration(rule.ShortWindow) * time.Second
+	status := BurnRateStatus{
+		RuleID:        rule.ID,
+		LongWindow:    formatWindow(long),
+		ShortWindow:   formatWindow(short),
+		Threshold:     rule.Threshold,
+		Severity:      rule.Severity,
+		LongBurnRate:  burnRate(slo, samples, now.Add(-long)),
+		ShortBurnRate: burnRate(slo, samples, now.Add(-short)),
+	}
+	status.Firing = rule.Enabled && status.LongBurnRate >= rule.Threshold && status.ShortBurnRate >= rule.Threshold
+	return status
+}
+
+// burnRate 窗口内错误率与错误预算比例之比，1表示恰好在SLO窗口结束时耗尽预算
+func burnRate(slo *models.SLO, samples []sloSample, since time.Time) float64 {
+	good, total := sumSamples(samples, since)
+	if total == 0 {
+		return 0
+	}
+	bad := total - good
+	if bad < 0 {
+		bad = 0
+	}
+	return bad / total / (1 - slo.Target/100)
+}
+
+// computeSLOStatus 计算SLO窗口内的达成情况
+func computeSLOStatus(slo *models.SLO, samples []sloSample, now time.Time) *SLOStatus {
+	good, total := sumSamples(samples, now.Add(-sloWindow(slo)))
+	status := &SLOStatus{
+		Good:            good,
+		Total:           total,
+		SLI:             100,
+		BudgetRemaining: 1,
+		BurnRates:       []BurnRateStatus{},
+		EvaluatedAt:     now,
+	}
+	if total == 0 {
+		return status
+	}
+
+	status.SLI = good / total * 100
+	status.ErrorBudget = total * (1 - slo.Target/100)
+	status.BudgetConsumed = total - good
+	if status.BudgetConsumed < 0 {
+		status.BudgetConsumed = 0
+	}
+	status.BudgetRemaining = 1 - status.BudgetConsumed/status.ErrorBudget
+	return status
+}
+
+// sumSamples 汇总since之后的事件数
+func sumSamples(samples []sloSample, since time.Time) (good, total float64) {
+	for _, sample := range samples {
+		if sample.At.After(since) {
+			good += sample.Good
+			total += sample.Total
+		}
+	}
+	return good, total
+}
+
+// sampleLookback 需要加载的事件时间范围：最长的燃烧率长窗口，需要计算错误预算时不短于SLO窗口
+func sampleLookback(slo *models.SLO, rules []models.AlertRule, includeWindow bool) time.Duration {
+	var lookback time.Duration
+	if includeWindow {
+		lookback = sloWindow(slo)
+	}
+	for _, rule := range rules {
+		if window := time.Duration(rule.Duration) * time.Second; window > lookback {
+			lookback = window
+		}
+	}
+	return lookback
+}
+
+// sloWindow SLO滚动窗口长度
+func sloWindow(slo *models.SLO) time.Duration {
+	return time.Duration(slo.WindowDays) * 24 * time.Hour
+}
+
+// formatWindow 以最大的整单位显示窗口长度，如5m、6h、3d
+func formatWindow(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.Round(time.Minute).String()
+}
+
+// toSLOResponse 转换为SLO响应格式
+func toSLOResponse(slo *models.SLO) *SLOResponse {
+	return &SLOResponse{
+		ID:               slo.ID,
+		Name:             slo.Name,
+		Description:      slo.Description,
+		SourceType:       slo.SourceType,
+		ServiceName:      slo.ServiceName,
+		Operation:        slo.Operation,
+		Indicator:        slo.Indicator,
+		LatencyThreshold: slo.LatencyThreshold,
+		GoodMetric:       slo.GoodMetric,
+		TotalMetric:      slo.TotalMetric,
+		MetricKind:       slo.MetricKind,
+		MetricTargetID:   slo.MetricTargetID,
+		Target:           slo.Target,
+		WindowDays:       slo.WindowDays,
+		Enabled:          slo.Enabled,
+		CreatedBy:        slo.CreatedBy,
+		UpdatedBy:        slo.UpdatedBy,
+		CreatedAt:        slo.CreatedAt,
+		UpdatedAt:        slo.UpdatedAt,
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 创建迁移了指定模型的内存SQLite数据库
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// createMetricSeries 以一分钟间隔写入一个指标序列，最后一个点位于end
+func createMetricSeries(t *testing.T, db *gorm.DB, targetID uuid.UUID, metric string, end time.Time, values ...float64) {
+	t.Helper()
+	for i, value := range values {
+		row := models.MetricData{
+			ID:        uuid.New(),
+			TargetID:  targetID,
+			Metric:    metric,
+			Value:     value,
+			Timestamp: end.Add(-time.Duration(len(values)-1-i) * time.Minute),
+		}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("create metric data: %v", err)
+		}
+	}
+}
+
+func TestLoadSamplesDeltaMetricsAreSummed(t *testing.T) {
+	db := newTestDB(t, &models.MetricData{})
+	service := NewSLOService(db, nil, &config.Config{}, nil)
+	now := time.Now()
+	target := uuid.New()
+
+	// apm.requests等按刷新周期写入的增量，数值下降不代表重置
+	createMetricSeries(t, db, target, "apm.requests", now, 100, 90, 110)
+	createMetricSeries(t, db, target, "apm.requests_ok", now, 99, 90, 100)
+
+	slo := &models.SLO{SourceType: "metric", GoodMetric: "apm.requests_ok", TotalMetric: "apm.requests", Target: 99}
+	if err := validateSLOSource(slo); err != nil {
+		t.Fatalf("validateSLOSource: %v", err)
+	}
+	if slo.MetricKind != SLOMetricKindDelta {
+		t.Fatalf("expected metric kind to default to delta, got %q", slo.MetricKind)
+	}
+
+	samples, err := service.loadSamples(context.Background(), slo, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("loadSamples: %v", err)
+	}
+	good, total := sumSamples(samples, now.Add(-time.Hour))
+	if good != 289 || total != 300 {
+		t.Fatalf("expected good=289 total=300, got good=%v total=%v", good, total)
+	}
+}
+
+func TestLoadSamplesCounterMetricsUseIncreases(t *testing.T) {
+	db := newTestDB(t, &models.MetricData{})
+	service := NewSLOService(db, nil, &config.Config{}, nil)
+	now := time.Now()
+	a, b := uuid.New(), uuid.New()
+
+	// 目标a在第三个点重启，计数器从0重新累计
+	createMetricSeries(t, db, a, "http_requests_total", now, 1000, 1100, 40, 90)
+	createMetricSeries(t, db, a, "http_requests_ok_total", now, 990, 1080, 30, 80)
+	createMetricSeries(t, db, b, "http_requests_total", now, 5000, 5010, 5030, 5060)
+	createMetricSeries(t, db, b, "http_requests_ok_total", now, 5000, 5010, 5030, 5060)
+
+	slo := &models.SLO{
+		SourceType:  "metric",
+		GoodMetric:  "http_requests_ok_total",
+		TotalMetric: "http_requests_total",
+		MetricKind:  SLOMetricKindCounter,
+		Target:      99,
+	}
+	if err := validateSLOSource(slo); err != nil {
+		t.Fatalf("validateSLOSource: %v", err)
+	}
+
+	samples, err := service.loadSamples(context.Background(), slo, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("loadSamples: %v", err)
+	}
+	// a: 100 + 40 + 50 = 190 总数，90 + 30 + 50 = 170 成功；b: 60 总数，60 成功
+	good, total := sumSamples(samples, now.Add(-time.Hour))
+	if good != 230 || total != 250 {
+		t.Fatalf("expected good=230 total=250, got good=%v total=%v", good, total)
+	}
+
+	// 只计入窗口内的增量
+	good, total = sumSamples(samples, now.Add(-90*time.Second))
+	if good != 130 || total != 140 {
+		t.Fatalf("expected good=130 total=140 in last two samples, got good=%v total=%v", good, total)
+	}
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APMMetricPrefix span派生RED指标的名称前缀
+const APMMetricPrefix = "apm."
+
+// 由跨度计算并写入指标库的服务级指标，延迟单位毫秒，错误率为0-1的比例
+const (
+	APMMetricRequests    = "apm.requests"
+	APMMetricErrors      = "apm.errors"
+	APMMetricRequestRate = "apm.request_rate"
+	APMMetricErrorRate   = "apm.error_rate"
+	APMMetricLatencyAvg  = "apm.latency_avg"
+	APMMetricLatencyP50  = "apm.latency_p50"
+	APMMetricLatencyP95  = "apm.latency_p95"
+	APMMetricLatencyP99  = "apm.latency_p99"
+)
+
+// spanLatencyBuckets 延迟直方图桶上界（毫秒），最后一个桶为+Inf
+var spanLatencyBuckets = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// SpanExemplar 直方图桶的示例链路，取桶内最慢的跨度
+type SpanExemplar struct {
+	Bucket     int       `json:"bucket"`
+	TraceID    string    `json:"trace_id"`
+	SpanID     string    `json:"span_id"`
+	DurationMs float64   `json:"duration_ms"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// spanMetricKey 指标维度
+type spanMetricKey struct {
+	Service   string
+	Version   string
+	Operation string
+	Status    string
+}
+
+// latencyHistogram 延迟直方图
+type latencyHistogram struct {
+	Counts    []int64
+	Sum       float64
+	Max       float64
+	Exemplars map[int]*SpanExemplar
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{
+		Counts:    make([]int64, len(spanLatencyBuckets)+1),
+		Exemplars: map[int]*SpanExemplar{},
+	}
+}
+
+// observe 记录一次请求
+func (h *latencyHistogram) observe(durationMs float64, exemplar *SpanExemplar) {
+	bucket := sort.SearchFloat64s(spanLatencyBuckets, durationMs)
+	h.Counts[bucket]++
+	h.Sum += durationMs
+	if durationMs > h.Max {
+		h.Max = durationMs
+	}
+	if exemplar != nil {
+		if cur, ok := h.Exemplars[bucket]; !ok || durationMs > cur.DurationMs {
+			exemplar.Bucket = bucket
+			h.Exemplars[bucket] = exemplar
+		}
+	}
+}
+
+// merge 合并直方图
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	for i := range h.Counts {
+		if i < len(other.Counts) {
+			h.Counts[i] += other.Counts[i]
+		}
+	}
+	h.Sum += other.Sum
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	for bucket, exemplar := range other.Exemplars {
+		if cur, ok := h.Exemplars[bucket]; !ok || exemplar.DurationMs > cur.DurationMs {
+			h.Exemplars[bucket] = exemplar
+		}
+	}
+}
+
+// total 请求总数
+func (h *latencyHistogram) total() int64 {
+	var n int64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// quantile 在桶内线性插值估算分位数，+Inf桶以最大值为上界
+func (h *latencyHistogram) quantile(q float64) float64 {
+	total := h.total()
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	var cumulative int64
+	for i, count := range h.Counts {
+		if count == 0 {
+			continue
+		}
+		if float64(cumulative+count) >= rank {
+			lower := 0.0
+			if i > 0 {
+				lower = spanLatencyBuckets[i-1]
+			}
+			upper := h.Max
+			if i < len(spanLatencyBuckets) && spanLatencyBuckets[i] < h.Max {
+				upper = spanLatencyBuckets[i]
+			}
+			if upper < lower {
+				upper = lower
+			}
+			return lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+		}
+		cumulative += count
+	}
+	return h.Max
+}
+
+// countBelow 估算耗时不超过threshold毫秒的请求数，阈值所在桶内线性插值
+func (h *latencyHistogram) countBelow(threshold float64) float64 {
+	var count float64
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = spanLatencyBuckets[i-1]
+		}
+		upper := h.Max
+		if i < len(spanLatencyBuckets) && spanLatencyBuckets[i] < h.Max {
+			upper = spanLatencyBuckets[i]
+		}
+		switch {
+		case upper <= threshold:
+			count += float64(c)
+		case lower < threshold:
+			count += float64(c) * (threshold - lower) / (upper - lower)
+		}
+	}
+	return count
+}
+
+// exemplarList 按桶排列的示例链路
+func (h *latencyHistogram) exemplarList() []SpanExemplar {
+	result := make([]SpanExemplar, 0, len(h.Exemplars))
+	for _, exemplar := range h.Exemplars {
+		result = append(result, *exemplar)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Bucket < result[j].Bucket })
+	return result
+}
+
+// SpanMetricsProcessor 跨度指标处理器：在接入路径上累计RED指标，周期性写入汇总表和指标库
+type SpanMetricsProcessor struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	alertService *AlertService
+
+	mu          sync.Mutex
+	windowStart time.Time
+	histograms  map[spanMetricKey]*latencyHistogram
+}
+
+// NewSpanMetricsProcessor 创建跨度指标处理器
+func NewSpanMetricsProcessor(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, alertService *AlertService) *SpanMetricsProcessor {
+	return &SpanMetricsProcessor{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       config,
+		alertService: alertService,
+		windowStart:  time.Now(),
+		histograms:   map[spanMetricKey]*latencyHistogram{},
+	}
+}
+
+// Record 累计跨度指标，只统计服务入口跨度（server、consumer或根跨度），避免同一请求内部跨度重复计数
+func (p *SpanMetricsProcessor) Record(rows []*models.APMTrace) {
+	if !p.config.Monitoring.Tracing.SpanMetrics.Enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, row := range rows {
+		if row.ParentSpanID != "" && row.SpanKind != SpanKindServer && row.SpanKind != SpanKindConsumer {
+			continue
+		}
+		key := spanMetricKey{Service: row.ServiceName, Version: row.ServiceVersion, Operation: row.OperationName, Status: row.Status}
+		hist, ok := p.histograms[key]
+		if !ok {
+			hist = newLatencyHistogram()
+			p.histograms[key] = hist
+		}
+		durationMs := float64(row.Duration) / 1000
+		hist.observe(durationMs, &SpanExemplar{
+			TraceID:    row.TraceID,
+			SpanID:     row.SpanID,
+			DurationMs: durationMs,
+			Timestamp:  row.StartTime,
+		})
+	}
+}
+
+// Flush 写出当前周期的汇总，并将服务级指标写入指标库、触发告警评估
+func (p *SpanMetricsProcessor) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	histograms := p.histograms
+	windowStart := p.windowStart
+	now := time.Now()
+	p.histograms = map[spanMetricKey]*latencyHistogram{}
+	p.windowStart = now
+	p.mu.Unlock()
+
+	if len(histograms) == 0 {
+		return nil
+	}
+
+	rows := make([]*models.APMSpanMetric, 0, len(histograms))
+	perService := map[string]*latencyHistogram{}
+	perServiceErrors := map[string]int64{}
+	for key, hist := range histograms {
+		countsJSON, _ := json.Marshal(hist.Counts)
+		exemplarsJSON, _ := json.Marshal(hist.exemplarList())
+		rows = append(rows, &models.APMSpanMetric{
+			BucketStart:    windowStart,
+			BucketEnd:      now,
+			ServiceName:    key.Service,
+			ServiceVersion: key.Version,
+			OperationName:  key.Operation,
+			Status:         key.Status,
+			Count:          hist.total(),
+			DurationSum:    hist.Sum,
+			DurationMax:    hist.Max,
+			Histogram:      string(countsJSON),
+			Exemplars:      string(exemplarsJSON),
+		})
+
+		if perService[key.Service] == nil {
+			perService[key.Service] = newLatencyHistogram()
+		}
+		perService[key.Service].merge(hist)
+		if key.Status == "error" {
+			perServiceErrors[key.Service] += hist.total()
+		}
+	}
+	if err := p.db.WithContext(ctx).CreateInBatches(rows, 500).Error; err != nil {
+		return fmt.Errorf("failed to store span metrics: %w", err)
+	}
+
+	return p.writeServiceMetrics(ctx, perService, perServiceErrors, now.Sub(windowStart), now)
+}
+
+// writeServiceMetrics 服务级指标写入指标库，目标ID为APM服务ID，标签带service便于告警规则按服务筛选
+func (p *SpanMetricsProcessor) writeServiceMetrics(ctx context.Context, perService map[string]*latencyHistogram, errors map[string]int64, window time.Duration, timestamp time.Time) error {
+	names := make([]string, 0, len(perService))
+	for name := range perService {
+		names = append(names, name)
+	}
+	var registered []models.APMService
+	if err := p.db.WithContext(ctx).Select("id, name").Where("name IN ?", names).Find(&registered).Error; err != nil {
+		return fmt.Errorf("failed to get apm services: %w", err)
+	}
+	serviceIDs := make(map[string]uuid.UUID, len(registered))
+	for _, svc := range registered {
+		serviceIDs[svc.Name] = svc.ID
+	}
+
+	var metrics []*models.MetricData
+	var alertData []*MetricData
+	for name, hist := range perService {
+		targetID, ok := serviceIDs[name]
+		if !ok {
+			continue
+		}
+		total := hist.total()
+		values := map[string]float64{
+			APMMetricRequests:   float64(total),
+			APMMetricErrors:     float64(errors[name]),
+			APMMetricLatencyP50: hist.quantile(0.50),
+			APMMetricLatencyP95: hist.quantile(0.95),
+			APMMetricLatencyP99: hist.quantile(0.99),
+		}
+		if window > 0 {
+			values[APMMetricRequestRate] = float64(total) / window.Seconds()
+		}
+		if total > 0 {
+			values[APMMetricErrorRate] = float64(errors[name]) / float64(total)
+			values[APMMetricLatencyAvg] = hist.Sum / float64(total)
+		}
+
+		tags := map[string]interface{}{"service": name}
+		labelsJSON, _ := json.Marshal(tags)
+		for metric, value := range values {
+			metrics = append(metrics, &models.MetricData{
+				ID:        uuid.New(),
+				TargetID:  targetID,
+				Metric:    metric,
+				Value:     value,
+				Labels:    string(labelsJSON),
+				Timestamp: timestamp,
+			})
+			alertData = append(alertData, &MetricData{
+				TargetType: "service",
+				TargetID:   targetID.String(),
+				MetricName: metric,
+				Value:      value,
+				Tags:       tags,
+				Timestamp:  timestamp,
+			})
+		}
+	}
+
+	if len(metrics) > 0 {
+		if err := p.db.WithContext(ctx).CreateInBatches(metrics, 500).Error; err != nil {
+			return fmt.Errorf("failed to store service metrics: %w", err)
+		}
+	}
+	if p.alertService != nil {
+		for _, data := range alertData {
+			p.alertService.ProcessMetricData(data)
+		}
+	}
+	return nil
+}
+
+// RunSpanMetrics 周期性刷新跨度指标并清理过期汇总
+func (p *SpanMetricsProcessor) RunSpanMetrics(ctx context.Context, cfg config.SpanMetricsConfig) {
+	interval := cfg.FlushInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 退出前写出已累计的数据
+			p.Flush(context.Background())
+			return
+		case <-ticker.C:
+			if err := p.Flush(ctx); err != nil {
+				logger.GetLogger("apm").WithError(err).Error("failed to flush span metrics")
+			}
+			if cfg.Retention > 0 {
+				p.db.Where("bucket_start < ?", time.Now().Add(-cfg.Retention)).Delete(&models.APMSpanMetric{})
+			}
+		}
+	}
+}
+
+// spanMetricRollup 时间范围内某个维度的汇总
+type spanMetricRollup struct {
+	Operation string
+	Count     int64
+	Errors    int64
+	Histogram *latencyHistogram
+	LastSeen  time.Time
+}
+
+// loadSpanMetricRollups 读取服务在时间范围内的汇总，按操作合并；version非空时只统计该版本
+func loadSpanMetricRollups(ctx context.Context, db *gorm.DB, serviceName, version string, start, end time.Time) (map[string]*spanMetricRollup, error) {
+	var rows []models.APMSpanMetric
+	query := db.WithContext(ctx).
+		Where("service_name = ? AND bucket_start >= ? AND bucket_end <= ?", serviceName, start, end)
+	if version != "" {
+		query = query.Where("service_version = ?", version)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load span metrics: %w", err)
+	}
+
+	rollups := map[string]*spanMetricRollup{}
+	for _, row := range rows {
+		rollup, ok := rollups[row.OperationName]
+		if !ok {
+			rollup = &spanMetricRollup{Operation: row.OperationName, Histogram: newLatencyHistogram()}
+			rollups[row.OperationName] = rollup
+		}
+
+		hist := newLatencyHistogram()
+		json.Unmarshal([]byte(row.Histogram), &hist.Counts)
+		var exemplars []SpanExemplar
+		json.Unmarshal([]byte(row.Exemplars), &exemplars)
+		for i := range exemplars {
+			hist.Exemplars[exemplars[i].Bucket] = &exemplars[i]
+		}
+		hist.Sum = row.DurationSum
+		hist.Max = row.DurationMax
+		rollup.Histogram.merge(hist)
+
+		rollup.Count += row.Count
+		if row.Status == "error" {
+			rollup.Errors += row.Count
+		}
+		if row.BucketEnd.After(rollup.LastSeen) {
+			rollup.LastSeen = row.BucketEnd
+		}
+	}
+	return rollups, nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// spanStore 基于本地apm_traces表的链路查询，启用内置接收器后替代Jaeger Query
+type spanStore struct {
+	db *gorm.DB
+}
+
+// NewSpanStore 创建本地链路存储查询客户端
+func NewSpanStore(db *gorm.DB) JaegerClient {
+	return &spanStore{db: db}
+}
+
+// GetTraces 按条件查询链路摘要，按链路开始时间倒序分页
+func (s *spanStore) GetTraces(ctx context.Context, query TraceQuery) ([]Trace, error) {
+	endTime := query.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := query.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.Add(-time.Hour)
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// 先按跨度条件筛选链路ID，再加载命中链路的全部跨度
+	db := s.db.WithContext(ctx).Model(&models.APMTrace{}).
+		Where("start_time BETWEEN ? AND ?", startTime, endTime)
+	if query.Service != "" {
+		db = db.Where("service_name = ?", query.Service)
+	}
+	if query.Operation != "" {
+		db = db.Where("operation_name = ?", query.Operation)
+	}
+	if query.MinDuration > 0 {
+		db = db.Where("duration >= ?", query.MinDuration.Microseconds())
+	}
+	if query.MaxDuration > 0 {
+		db = db.Where("duration <= ?", query.MaxDuration.Microseconds())
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	// 标签条件须在同一跨度上同时满足，与Jaeger的检索语义一致；
+	// 标签行的时间条件使子查询可走(tag_key, tag_value, start_time)索引
+	for key, value := range query.Tags {
+		db = db.Where("EXISTS (SELECT 1 FROM apm_span_tags t WHERE t.tag_key = ? AND t.tag_value = ? "+
+			"AND t.start_time BETWEEN ? AND ? AND t.trace_id = apm_traces.trace_id AND t.span_id = apm_traces.span_id)",
+			key, value, startTime, endTime)
+	}
+
+	var traceIDs []string
+	if err := db.Group("trace_id").
+		Order("MIN(start_time) DESC").
+		Offset(query.Offset).Limit(limit).
+		Pluck("trace_id", &traceIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	if len(traceIDs) == 0 {
+		return []Trace{}, nil
+	}
+
+	var rows []models.APMTrace
+	if err := s.db.WithContext(ctx).Where("trace_id IN ?", traceIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load trace spans: %w", err)
+	}
+	byTrace := make(map[string][]models.APMTrace, len(traceIDs))
+	for _, row := range rows {
+		byTrace[row.TraceID] = append(byTrace[row.TraceID], row)
+	}
+
+	traces := make([]Trace, 0, len(traceIDs))
+	for _, id := range traceIDs {
+		detail := buildStoredTrace(id, byTrace[id])
+		traces = append(traces, Trace{
+			TraceID:   detail.TraceID,
+			SpanCount: len(detail.Spans),
+			Duration:  detail.Duration,
+			StartTime: detail.StartTime,
+			Services:  detail.Services,
+			Errors:    len(detail.Errors),
+		})
+	}
+	return traces, nil
+}
+
+// GetTrace 获取单条链路
+func (s *spanStore) GetTrace(ctx context.Context, traceID string) (*TraceDetail, error) {
+	traceID = normalizeTraceID(traceID)
+	var rows []models.APMTrace
+	if err := s.db.WithContext(ctx).Where("trace_id = ?", traceID).Order("start_time").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrTraceNotFound
+	}
+	return buildStoredTrace(traceID, rows), nil
+}
+
+// GetServices 获取服务注册表中的服务
+func (s *spanStore) GetServices(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := s.db.WithContext(ctx).Model(&models.APMService{}).Order("name").Pluck("name", &names).Error; err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+	return names, nil
+}
+
+// GetOperations 获取服务出现过的操作
+func (s *spanStore) GetOperations(ctx context.Context, service string) ([]string, error) {
+	var operations []string
+	if err := s.db.WithContext(ctx).Model(&models.APMTrace{}).
+		Where("service_name = ?", service).
+		Distinct("operation_name").Order("operation_name").
+		Pluck("operation_name", &operations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get operations: %w", err)
+	}
+	return operations, nil
+}
+
+// GetDependencies 由跨服务的父子跨度统计服务调用关系
+func (s *spanStore) GetDependencies(ctx context.Context, endTime time.Time, lookback time.Duration) ([]ServiceEdge, error) {
+	var rows []struct {
+		Source    string
+		Target    string
+		Calls     int
+		Errors    int
+		AvgMicros float64
+	}
+	if err := s.db.WithContext(ctx).Table("apm_traces c").
+		Select("p.service_name AS source, c.service_name AS target, COUNT(*) AS calls, "+
+			"COUNT(CASE WHEN c.status = 'error' THEN 1 END) AS errors, AVG(c.duration) AS avg_micros").
+		Joins("JOIN apm_traces p ON p.trace_id = c.trace_id AND p.span_id = c.parent_span_id").
+		Where("c.start_time BETWEEN ? AND ? AND p.service_name <> c.service_name", endTime.Add(-lookback), endTime).
+		Group("p.service_name, c.service_name").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+
+	edges := make([]ServiceEdge, 0, len(rows))
+	for _, row := range rows {
+		edges = append(edges, ServiceEdge{
+			Source: row.Source,
+			Target: row.Target,
+			Metrics: ConnectionMetrics{
+				RequestCount: row.Calls,
+				ErrorCount:   row.Errors,
+				LatencyAvg:   row.AvgMicros / 1000,
+			},
+		})
+	}
+	return edges, nil
+}
+
+// buildStoredTrace 由存储的跨度组装链路详情
+func buildStoredTrace(traceID string, rows []models.APMTrace) *TraceDetail {
+	detail := &TraceDetail{
+		TraceID:   traceID,
+		Spans:     make([]Span, 0, len(rows)),
+		Errors:    []SpanError{},
+		Processes: map[string]Process{},
+	}
+
+	services := map[string]bool{}
+	for _, row := range rows {
+		span := storedSpan(&row)
+		detail.Spans = append(detail.Spans, span)
+		services[row.ServiceName] = true
+
+		if _, ok := detail.Processes[row.ServiceName]; !ok {
+			var process Process
+			if row.Process != "" {
+				json.Unmarshal([]byte(row.Process), &process)
+			}
+			process.ServiceName = row.ServiceName
+			detail.Processes[row.ServiceName] = process
+		}
+
+		if detail.StartTime.IsZero() || row.StartTime.Before(detail.StartTime) {
+			detail.StartTime = row.StartTime
+		}
+		if row.EndTime.After(detail.EndTime) {
+			detail.EndTime = row.EndTime
+		}
+		if span.Status.Code == spanStatusError {
+			detail.Errors = append(detail.Errors, SpanError{
+				SpanID:    span.SpanID,
+				Message:   spanErrorMessage(&span),
+				Timestamp: span.StartTime,
+				Level:     "error",
+			})
+		}
+	}
+	if !detail.StartTime.IsZero() {
+		detail.Duration = detail.EndTime.Sub(detail.StartTime)
+	}
+
+	for name := range services {
+		detail.Services = append(detail.Services, name)
+	}
+	sort.Strings(detail.Services)
+	sort.SliceStable(detail.Spans, func(i, j int) bool {
+		return detail.Spans[i].StartTime.Before(detail.Spans[j].StartTime)
+	})
+	return detail
+}
+
+// storedSpan 转换存储的跨度
+func storedSpan(row *models.APMTrace) Span {
+	span := Span{
+		SpanID:        row.SpanID,
+		TraceID:       row.TraceID,
+		ParentSpanID:  row.ParentSpanID,
+		OperationName: row.OperationName,
+		ServiceName:   row.ServiceName,
+		StartTime:     row.StartTime,
+		Duration:      time.Duration(row.Duration) * time.Microsecond,
+		Tags:          map[string]interface{}{},
+		Logs:          []SpanLog{},
+		References:    []SpanReference{},
+	}
+	if row.Tags != "" {
+		json.Unmarshal([]byte(row.Tags), &span.Tags)
+	}
+	if row.Logs != "" {
+		json.Unmarshal([]byte(row.Logs), &span.Logs)
+	}
+	if row.ParentSpanID != "" {
+		span.References = append(span.References, SpanReference{
+			RefType: "CHILD_OF",
+			TraceID: row.TraceID,
+			SpanID:  row.ParentSpanID,
+		})
+	}
+
+	span.Status = jaegerSpanStatus(span.Tags)
+	if span.Status.Code == spanStatusUnset && row.Status == "error" {
+		span.Status.Code = spanStatusError
+	}
+	return span
+}
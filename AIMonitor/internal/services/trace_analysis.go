@@ -0,0 +1,309 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// nPlusOneThreshold 同一父跨度下相同语句的数据库调用达到该次数时判定为N+1查询
+const nPlusOneThreshold = 5
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteral  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlInList         = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	sqlWhitespaceRuns = regexp.MustCompile(`\s+`)
+)
+
+// TraceAnalysis 链路分析结果
+type TraceAnalysis struct {
+	// CriticalPath 关键路径，按时间顺序排列，各段耗时之和等于根跨度耗时
+	CriticalPath []CriticalPathSegment `json:"critical_path"`
+	// SelfTimes 跨度ID -> 自身耗时（扣除子跨度覆盖的时间）
+	SelfTimes        map[string]time.Duration `json:"self_times"`
+	ServiceBreakdown []ServiceTimeBreakdown   `json:"service_breakdown"`
+	NPlusOne         []NPlusOnePattern        `json:"n_plus_one"`
+}
+
+// CriticalPathSegment 关键路径上的一段
+type CriticalPathSegment struct {
+	SpanID        string        `json:"span_id"`
+	ServiceName   string        `json:"service_name"`
+	OperationName string        `json:"operation_name"`
+	StartTime     time.Time     `json:"start_time"`
+	Duration      time.Duration `json:"duration"`
+}
+
+// ServiceTimeBreakdown 服务耗时分布
+type ServiceTimeBreakdown struct {
+	ServiceName      string        `json:"service_name"`
+	SpanCount        int           `json:"span_count"`
+	SelfTime         time.Duration `json:"self_time"`
+	CriticalPathTime time.Duration `json:"critical_path_time"`
+	// Percentage 自身耗时占全部跨度自身耗时的百分比
+	Percentage float64 `json:"percentage"`
+}
+
+// NPlusOnePattern 疑似N+1查询：同一父跨度下大量执行相同语句的数据库跨度
+type NPlusOnePattern struct {
+	ParentSpanID    string        `json:"parent_span_id"`
+	ParentOperation string        `json:"parent_operation"`
+	ServiceName     string        `json:"service_name"`
+	Statement       string        `json:"statement"`
+	Count           int           `json:"count"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	SpanIDs         []string      `json:"span_ids"`
+}
+
+// analysisSpan 分析用的跨度节点
+type analysisSpan struct {
+	span     *Span
+	start    time.Time
+	end      time.Time
+	children []*analysisSpan
+}
+
+// AnalyzeTrace 计算关键路径、跨度自身耗时、服务耗时分布并检测N+1查询
+func AnalyzeTrace(detail *TraceDetail) *TraceAnalysis {
+	analysis := &TraceAnalysis{
+		CriticalPath:     []CriticalPathSegment{},
+		SelfTimes:        map[string]time.Duration{},
+		ServiceBreakdown: []ServiceTimeBreakdown{},
+		NPlusOne:         []NPlusOnePattern{},
+	}
+	if detail == nil || len(detail.Spans) == 0 {
+		return analysis
+	}
+
+	nodes := make(map[string]*analysisSpan, len(detail.Spans))
+	for i := range detail.Spans {
+		span := &detail.Spans[i]
+		nodes[span.SpanID] = &analysisSpan{span: span, start: span.StartTime, end: span.StartTime.Add(span.Duration)}
+	}
+	var roots []*analysisSpan
+	for i := range detail.Spans {
+		node := nodes[detail.Spans[i].SpanID]
+		if parent, ok := nodes[node.span.ParentSpanID]; ok && parent != node {
+			parent.children = append(parent.children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	breakdown := map[string]*ServiceTimeBreakdown{}
+	serviceOf := func(name string) *ServiceTimeBreakdown {
+		b, ok := breakdown[name]
+		if !ok {
+			b = &ServiceTimeBreakdown{ServiceName: name}
+			breakdown[name] = b
+		}
+		return b
+	}
+
+	var totalSelf time.Duration
+	for _, node := range nodes {
+		self := selfTime(node)
+		analysis.SelfTimes[node.span.SpanID] = self
+		totalSelf += self
+		b := serviceOf(node.span.ServiceName)
+		b.SpanCount++
+		b.SelfTime += self
+	}
+
+	// 从结束最晚的根跨度计算关键路径；缺失父跨度的孤立子树不参与
+	if len(roots) > 0 {
+		root := roots[0]
+		for _, r := range roots[1:] {
+			if r.end.After(root.end) {
+				root = r
+			}
+		}
+		var segments []CriticalPathSegment
+		criticalPath(root, root.end, &segments)
+		for i := len(segments) - 1; i >= 0; i-- {
+			seg := segments[i]
+			if n := len(analysis.CriticalPath); n > 0 && analysis.CriticalPath[n-1].SpanID == seg.SpanID {
+				analysis.CriticalPath[n-1].Duration += seg.Duration
+				continue
+			}
+			analysis.CriticalPath = append(analysis.CriticalPath, seg)
+		}
+		for _, seg := range analysis.CriticalPath {
+			serviceOf(seg.ServiceName).CriticalPathTime += seg.Duration
+		}
+	}
+
+	for _, b := range breakdown {
+		if totalSelf > 0 {
+			b.Percentage = float64(b.SelfTime) / float64(totalSelf) * 100
+		}
+		analysis.ServiceBreakdown = append(analysis.ServiceBreakdown, *b)
+	}
+	sort.Slice(analysis.ServiceBreakdown, func(i, j int) bool {
+		return analysis.ServiceBreakdown[i].SelfTime > analysis.ServiceBreakdown[j].SelfTime
+	})
+
+	for _, node := range nodes {
+		analysis.NPlusOne = append(analysis.NPlusOne, detectNPlusOne(node)...)
+	}
+	sort.Slice(analysis.NPlusOne, func(i, j int) bool {
+		return analysis.NPlusOne[i].Count > analysis.NPlusOne[j].Count
+	})
+	return analysis
+}
+
+// selfTime 跨度耗时减去子跨度在其时间范围内覆盖的并集
+func selfTime(node *analysisSpan) time.Duration {
+	type interval struct{ start, end time.Time }
+	intervals := make([]interval, 0, len(node.children))
+	for _, child := range node.children {
+		start, end := child.start, child.end
+		if start.Before(node.start) {
+			start = node.start
+		}
+		if end.After(node.end) {
+			end = node.end
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{start, end})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+
+	var covered time.Duration
+	var cur *interval
+	for i := range intervals {
+		iv := intervals[i]
+		if cur != nil && !iv.start.After(cur.end) {
+			if iv.end.After(cur.end) {
+				cur.end = iv.end
+			}
+			continue
+		}
+		if cur != nil {
+			covered += cur.end.Sub(cur.start)
+		}
+		cur = &iv
+	}
+	if cur != nil {
+		covered += cur.end.Sub(cur.start)
+	}
+	return node.end.Sub(node.start) - covered
+}
+
+// criticalPath 自跨度结束时刻向前回溯：每次选取在游标之前结束最晚的子跨度递归展开，子跨度之间的空隙计入当前跨度。
+// 片段按时间倒序追加到segments
+func criticalPath(node *analysisSpan, end time.Time, segments *[]CriticalPathSegment) {
+	add := func(from, to time.Time) {
+		if to.After(from) {
+			*segments = append(*segments, CriticalPathSegment{
+				SpanID:        node.span.SpanID,
+				ServiceName:   node.span.ServiceName,
+				OperationName: node.span.OperationName,
+				StartTime:     from,
+				Duration:      to.Sub(from),
+			})
+		}
+	}
+
+	children := make([]*analysisSpan, len(node.children))
+	copy(children, node.children)
+	sort.Slice(children, func(i, j int) bool { return children[i].end.After(children[j].end) })
+
+	cursor := end
+	for _, child := range children {
+		if !child.start.Before(cursor) || !child.end.After(node.start) {
+			continue
+		}
+		childEnd := child.end
+		if childEnd.After(cursor) {
+			childEnd = cursor
+		}
+		add(childEnd, cursor)
+		criticalPath(child, childEnd, segments)
+		cursor = child.start
+		if cursor.Before(node.start) {
+			cursor = node.start
+			break
+		}
+	}
+	add(node.start, cursor)
+}
+
+// detectNPlusOne 检测跨度的直接子跨度中重复执行的数据库语句
+func detectNPlusOne(node *analysisSpan) []NPlusOnePattern {
+	if len(node.children) < nPlusOneThreshold {
+		return nil
+	}
+
+	groups := map[string]*NPlusOnePattern{}
+	var order []string
+	for _, child := range node.children {
+		statement := spanDBStatement(child.span)
+		if statement == "" {
+			continue
+		}
+		key := child.span.ServiceName + "\x00" + statement
+		pattern, ok := groups[key]
+		if !ok {
+			pattern = &NPlusOnePattern{
+				ParentSpanID:    node.span.SpanID,
+				ParentOperation: node.span.OperationName,
+				ServiceName:     child.span.ServiceName,
+				Statement:       statement,
+			}
+			groups[key] = pattern
+			order = append(order, key)
+		}
+		pattern.Count++
+		pattern.TotalDuration += child.span.Duration
+		pattern.SpanIDs = append(pattern.SpanIDs, child.span.SpanID)
+	}
+
+	var patterns []NPlusOnePattern
+	for _, key := range order {
+		if pattern := groups[key]; pattern.Count >= nPlusOneThreshold {
+			patterns = append(patterns, *pattern)
+		}
+	}
+	return patterns
+}
+
+// spanDBStatement 取数据库跨度的语句并将字面量归一化为占位符，非数据库跨度返回空串
+func spanDBStatement(span *Span) string {
+	var statement string
+	for _, key := range []string{"db.query.text", "db.statement"} {
+		if v, ok := span.Tags[key]; ok {
+			statement = fmt.Sprint(v)
+			break
+		}
+	}
+	if statement == "" {
+		// 没有语句时以数据库操作和表名近似，如Redis、MongoDB的插桩
+		system, _ := span.Tags["db.system"].(string)
+		if system == "" {
+			return ""
+		}
+		statement = strings.TrimSpace(fmt.Sprintf("%s %v %v", system,
+			tagOrEmpty(span.Tags, "db.operation"), tagOrEmpty(span.Tags, "db.sql.table")))
+		if statement == system {
+			statement = system + " " + span.OperationName
+		}
+	}
+
+	statement = sqlStringLiteral.ReplaceAllString(statement, "?")
+	statement = sqlNumberLiteral.ReplaceAllString(statement, "?")
+	statement = sqlInList.ReplaceAllString(statement, "IN (?)")
+	return strings.TrimSpace(sqlWhitespaceRuns.ReplaceAllString(statement, " "))
+}
+
+// tagOrEmpty 取标签值，缺失时返回空串
+func tagOrEmpty(tags map[string]interface{}, key string) interface{} {
+	if v, ok := tags[key]; ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,461 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/cache"
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 跨度类型，与OpenTelemetry SpanKind对应
+const (
+	SpanKindInternal = "internal"
+	SpanKindServer   = "server"
+	SpanKindClient   = "client"
+	SpanKindProducer = "producer"
+	SpanKindConsumer = "consumer"
+)
+
+// unknownServiceName 未上报service.name时使用的服务名（OpenTelemetry规范默认值）
+const unknownServiceName = "unknown_service"
+
+// serviceRegistryInterval 同一服务注册信息未变化时的最小更新间隔
+const serviceRegistryInterval = 30 * time.Second
+
+var (
+	traceIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	spanIDPattern  = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// IngestSpan 归一化后的跨度，各种上报格式先转换为该结构再统一入库
+type IngestSpan struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	OperationName string
+	ServiceName   string
+	Kind          string
+	StartTime     time.Time
+	EndTime       time.Time
+	Error         bool
+	StatusMessage string
+	Attributes    map[string]interface{}
+	Events        []SpanLog
+	// Resource 资源属性，如service.version、telemetry.sdk.language
+	Resource map[string]interface{}
+	// Scope 产生跨度的插桩库名称
+	Scope string
+}
+
+// IngestResult 跨度入库结果
+type IngestResult struct {
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Message  string `json:"message,omitempty"`
+}
+
+// TraceIngestService 链路数据接入服务：跨度入库并维护APM服务注册表
+type TraceIngestService struct {
+	db           *gorm.DB
+	cacheManager *cache.CacheManager
+	config       *config.Config
+	spanMetrics  *SpanMetricsProcessor
+	// sampler 尾部采样器，未启用采样时为nil
+	sampler *tailSampler
+	// registered 服务名 -> 最近一次注册的信息，用于降低注册表写入频率
+	registered sync.Map
+}
+
+// serviceRegistration 服务注册信息
+type serviceRegistration struct {
+	Version     string
+	Environment string
+	Language    string
+	Framework   string
+	LastSeen    time.Time
+}
+
+// NewTraceIngestService 创建链路数据接入服务
+func NewTraceIngestService(db *gorm.DB, cacheManager *cache.CacheManager, config *config.Config, spanMetrics *SpanMetricsProcessor) *TraceIngestService {
+	s := &TraceIngestService{
+		db:           db,
+		cacheManager: cacheManager,
+		config:       config,
+		spanMetrics:  spanMetrics,
+	}
+	if config.Monitoring.Tracing.Sampling.Enabled {
+		s.sampler = newTailSampler(config.Monitoring.Tracing.Sampling, s.storeSpans)
+	}
+	return s
+}
+
+// Ingest 校验并写入跨度，同时更新服务注册表。不合法的跨度计入Rejected，不影响其余跨度入库
+func (s *TraceIngestService) Ingest(ctx context.Context, spans []*IngestSpan) (*IngestResult, error) {
+	result := &IngestResult{}
+	rows := make([]*models.APMTrace, 0, len(spans))
+	attrs := make([]map[string]interface{}, 0, len(spans))
+	services := map[string]*serviceRegistration{}
+	var reasons []string
+
+	for _, span := range spans {
+		row, err := toAPMTrace(span)
+		if err != nil {
+			result.Rejected++
+			if len(reasons) < 3 {
+				reasons = append(reasons, err.Error())
+			}
+			continue
+		}
+		rows = append(rows, row)
+		attrs = append(attrs, span.Attributes)
+
+		reg := registrationFromSpan(span)
+		if prev, ok := services[row.ServiceName]; !ok || reg.LastSeen.After(prev.LastSeen) {
+			services[row.ServiceName] = reg
+		}
+	}
+	if len(reasons) > 0 {
+		result.Message = strings.Join(reasons, "; ")
+	}
+
+	if len(rows) > 0 {
+		// 启用尾部采样时跨度先进入缓冲，只有已决定保留链路的迟到跨度立即入库
+		toStore := rows
+		if s.sampler != nil {
+			toStore = s.sampler.admit(ctx, rows, attrs)
+		}
+		if err := s.storeSpans(ctx, toStore); err != nil {
+			return nil, err
+		}
+		// 指标按采样前的全部跨度统计；入库成功后再计数，避免存储失败、客户端重试时重复统计
+		if s.spanMetrics != nil {
+			s.spanMetrics.Record(rows)
+		}
+	}
+	result.Accepted = len(rows)
+
+	for name, reg := range services {
+		if err := s.registerService(ctx, name, reg); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// storeSpans 写入跨度，客户端重试会重复上报同一跨度，按(trace_id, span_id)去重
+func (s *TraceIngestService) storeSpans(ctx context.Context, rows []*models.APMTrace) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(rows, 500).Error; err != nil {
+		return fmt.Errorf("failed to store spans: %w", err)
+	}
+	if tags := spanTagRows(rows); len(tags) > 0 {
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(tags, 1000).Error; err != nil {
+			return fmt.Errorf("failed to store span tags: %w", err)
+		}
+	}
+	return nil
+}
+
+// spanTagRows 提取跨度及其进程的标量标签建立检索索引，过长的键值不建索引
+func spanTagRows(rows []*models.APMTrace) []*models.APMSpanTag {
+	var tags []*models.APMSpanTag
+	for _, row := range rows {
+		values := map[string]string{}
+		var process Process
+		if row.Process != "" && json.Unmarshal([]byte(row.Process), &process) == nil {
+			for k, v := range process.Tags {
+				values[k] = v
+			}
+		}
+		var spanTags map[string]interface{}
+		if row.Tags != "" {
+			json.Unmarshal([]byte(row.Tags), &spanTags)
+		}
+		for k, v := range spanTags {
+			switch v.(type) {
+			case string, bool, float64, int64:
+				values[k] = fmt.Sprint(v)
+			}
+		}
+
+		for k, v := range values {
+			if len(k) > 128 || len(v) > 255 {
+				continue
+			}
+			tags = append(tags, &models.APMSpanTag{
+				ID:        uuid.New(),
+				TraceID:   row.TraceID,
+				SpanID:    row.SpanID,
+				TagKey:    k,
+				TagValue:  v,
+				StartTime: row.StartTime,
+			})
+		}
+	}
+	return tags
+}
+
+// registerService 新增或更新APM服务，资源属性缺失时保留原值
+func (s *TraceIngestService) registerService(ctx context.Context, name string, reg *serviceRegistration) error {
+	if prev, ok := s.registered.Load(name); ok {
+		last := prev.(*serviceRegistration)
+		if last.Version == reg.Version && last.Environment == reg.Environment &&
+			last.Language == reg.Language && last.Framework == reg.Framework &&
+			reg.LastSeen.Sub(last.LastSeen) < serviceRegistryInterval {
+			return nil
+		}
+	}
+
+	lastSeen := reg.LastSeen
+	var service models.APMService
+	err := s.db.WithContext(ctx).Unscoped().Where("name = ?", name).First(&service).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		service = models.APMService{
+			Name:        name,
+			Version:     reg.Version,
+			Environment: reg.Environment,
+			Language:    reg.Language,
+			Framework:   reg.Framework,
+			Status:      "active",
+			LastSeen:    &lastSeen,
+		}
+		// 并发接收同一新服务时以先写入者为准
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&service).Error; err != nil {
+			return fmt.Errorf("failed to create apm service: %w", err)
+		}
+		if reg.Version != "" {
+			if err := s.recordDeployment(ctx, name, reg.Version, "", reg.LastSeen); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get apm service: %w", err)
+	default:
+		if service.LastSeen != nil && service.LastSeen.After(lastSeen) {
+			lastSeen = *service.LastSeen
+		}
+		updates := map[string]interface{}{
+			"status":     "active",
+			"last_seen":  lastSeen,
+			"deleted_at": nil,
+		}
+		if reg.Version != "" {
+			updates["version"] = reg.Version
+			if reg.Version != service.Version {
+				if err := s.recordDeployment(ctx, name, reg.Version, service.Version, reg.LastSeen); err != nil {
+					return err
+				}
+			}
+		}
+		if reg.Environment != "" {
+			updates["environment"] = reg.Environment
+		}
+		if reg.Language != "" {
+			updates["language"] = reg.Language
+		}
+		if reg.Framework != "" {
+			updates["framework"] = reg.Framework
+		}
+		if err := s.db.WithContext(ctx).Unscoped().Model(&service).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update apm service: %w", err)
+		}
+	}
+
+	s.registered.Store(name, reg)
+	if s.cacheManager != nil {
+		s.cacheManager.Delete(ctx, fmt.Sprintf("service_detail:%s", name))
+	}
+	return nil
+}
+
+// registrationFromSpan 从资源属性提取服务注册信息
+func registrationFromSpan(span *IngestSpan) *serviceRegistration {
+	reg := &serviceRegistration{
+		Version:     attributeString(span.Resource, "service.version"),
+		Environment: attributeString(span.Resource, "deployment.environment.name", "deployment.environment"),
+		Language:    attributeString(span.Resource, "telemetry.sdk.language", "process.runtime.name"),
+		Framework:   attributeString(span.Resource, "service.framework", "telemetry.distro.name"),
+		LastSeen:    span.EndTime,
+	}
+	if reg.Framework == "" {
+		reg.Framework = span.Scope
+	}
+	if len(reg.Framework) > 50 {
+		reg.Framework = reg.Framework[:50]
+	}
+	return reg
+}
+
+// recordDeployment 记录服务首次出现的新版本；滚动发布期间新旧版本交替上报，同一版本只记录一次
+func (s *TraceIngestService) recordDeployment(ctx context.Context, name, version, previous string, firstSeen time.Time) error {
+	if previous != "" {
+		// 滚动发布期间旧版本仍在上报，已作为某次发布基线的版本不再记录
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.ServiceDeployment{}).
+			Where("service_name = ? AND previous_version = ?", name, version).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check service deployment: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+
+	status := "observing"
+	if previous == "" {
+		// 服务的第一个版本没有可对比的基线
+		status = "baseline"
+	}
+	deployment := &models.ServiceDeployment{
+		ServiceName:     name,
+		Version:         version,
+		PreviousVersion: previous,
+		FirstSeen:       firstSeen,
+		Status:          status,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(deployment).Error; err != nil {
+		return fmt.Errorf("failed to record service deployment: %w", err)
+	}
+	return nil
+}
+
+// toAPMTrace 转换为存储模型。状态、类型等信息同时写入标签，保持与Jaeger标签约定一致
+func toAPMTrace(span *IngestSpan) (*models.APMTrace, error) {
+	traceID := normalizeTraceID(span.TraceID)
+	if !traceIDPattern.MatchString(traceID) || traceID == strings.Repeat("0", 32) {
+		return nil, fmt.Errorf("invalid trace id %q", span.TraceID)
+	}
+	spanID := strings.ToLower(span.SpanID)
+	if !spanIDPattern.MatchString(spanID) || spanID == strings.Repeat("0", 16) {
+		return nil, fmt.Errorf("invalid span id %q", span.SpanID)
+	}
+	parentID := strings.ToLower(span.ParentSpanID)
+	if parentID == strings.Repeat("0", 16) {
+		parentID = ""
+	}
+	if parentID != "" && !spanIDPattern.MatchString(parentID) {
+		return nil, fmt.Errorf("invalid parent span id %q", span.ParentSpanID)
+	}
+	if span.StartTime.IsZero() {
+		return nil, fmt.Errorf("span %s has no start time", spanID)
+	}
+
+	serviceName := span.ServiceName
+	if serviceName == "" {
+		serviceName = unknownServiceName
+	}
+	operation := span.OperationName
+	if operation == "" {
+		operation = "unknown"
+	}
+	endTime := span.EndTime
+	if endTime.Before(span.StartTime) {
+		endTime = span.StartTime
+	}
+
+	tags := make(map[string]interface{}, len(span.Attributes)+3)
+	for k, v := range span.Attributes {
+		tags[k] = v
+	}
+	if span.Kind != "" {
+		tags["span.kind"] = span.Kind
+	}
+	status := "ok"
+	if span.Error {
+		status = "error"
+		tags["error"] = true
+		tags["otel.status_code"] = "ERROR"
+	}
+	if span.StatusMessage != "" {
+		tags["otel.status_description"] = span.StatusMessage
+	}
+
+	processTags := make(map[string]string, len(span.Resource))
+	for k, v := range span.Resource {
+		processTags[k] = fmt.Sprintf("%v", v)
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode span tags: %w", err)
+	}
+	events := span.Events
+	if events == nil {
+		events = []SpanLog{}
+	}
+	logsJSON, err := json.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode span events: %w", err)
+	}
+	processJSON, err := json.Marshal(Process{ServiceName: serviceName, Tags: processTags})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode span process: %w", err)
+	}
+
+	if len(operation) > 255 {
+		operation = operation[:255]
+	}
+	if len(serviceName) > 100 {
+		serviceName = serviceName[:100]
+	}
+	serviceVersion := attributeString(span.Resource, "service.version")
+	if len(serviceVersion) > 50 {
+		serviceVersion = serviceVersion[:50]
+	}
+
+	return &models.APMTrace{
+		ID:             uuid.New(),
+		TraceID:        traceID,
+		SpanID:         spanID,
+		ParentSpanID:   parentID,
+		OperationName:  operation,
+		ServiceName:    serviceName,
+		SpanKind:       span.Kind,
+		ServiceVersion: serviceVersion,
+		StartTime:      span.StartTime,
+		EndTime:        endTime,
+		Duration:       endTime.Sub(span.StartTime).Microseconds(),
+		Status:         status,
+		Tags:           string(tagsJSON),
+		Logs:           string(logsJSON),
+		Process:        string(processJSON),
+	}, nil
+}
+
+// normalizeTraceID 统一为32位小写十六进制，64位链路ID左侧补零
+func normalizeTraceID(traceID string) string {
+	traceID = strings.ToLower(traceID)
+	if len(traceID) < 32 {
+		traceID = strings.Repeat("0", 32-len(traceID)) + traceID
+	}
+	return traceID
+}
+
+// attributeString 按顺序取第一个非空的字符串属性
+func attributeString(attrs map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := attrs[key]; ok && v != nil {
+			if s := fmt.Sprintf("%v", v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-monitor/internal/config"
+	"ai-monitor/internal/logger"
+	"ai-monitor/internal/models"
+)
+
+// 链路保留原因
+const (
+	SamplingReasonError     = "error"
+	SamplingReasonLatency   = "latency"
+	SamplingReasonAttribute = "attribute"
+	SamplingReasonRate      = "probabilistic"
+)
+
+// pendingTrace 等待决策的链路
+type pendingTrace struct {
+	spans     []*models.APMTrace
+	services  map[string]bool
+	firstSeen time.Time
+	// reason 非空表示必定保留
+	reason string
+}
+
+// samplingDecision 已决策链路的结果，用于处理迟到跨度
+type samplingDecision struct {
+	keep      bool
+	decidedAt time.Time
+}
+
+// SamplingStats 尾部采样统计
+type SamplingStats struct {
+	BufferedTraces int              `json:"buffered_traces"`
+	KeptTraces     int64            `json:"kept_traces"`
+	DroppedTraces  int64            `json:"dropped_traces"`
+	KeptByReason   map[string]int64 `json:"kept_by_reason"`
+}
+
+// tailSampler 尾部采样器：按链路缓冲跨度，等待窗口结束后统一决定保留或丢弃
+type tailSampler struct {
+	cfg        config.TailSamplingConfig
+	thresholds map[string]time.Duration
+	store      func(ctx context.Context, rows []*models.APMTrace) error
+
+	mu           sync.Mutex
+	pending      map[string]*pendingTrace
+	order        []string
+	decided      map[string]samplingDecision
+	decidedOrder []string
+	stats        SamplingStats
+}
+
+// newTailSampler 创建尾部采样器，store用于写入保留的跨度
+func newTailSampler(cfg config.TailSamplingConfig, store func(ctx context.Context, rows []*models.APMTrace) error) *tailSampler {
+	thresholds := make(map[string]time.Duration, len(cfg.LatencyThresholds))
+	for _, t := range cfg.LatencyThresholds {
+		thresholds[latencyThresholdKey(t.Service, t.Operation)] = t.Threshold
+	}
+	return &tailSampler{
+		cfg:        cfg,
+		thresholds: thresholds,
+		store:      store,
+		pending:    map[string]*pendingTrace{},
+		decided:    map[string]samplingDecision{},
+		stats:      SamplingStats{KeptByReason: map[string]int64{}},
+	}
+}
+
+// admit 缓冲跨度，返回所属链路已决定保留、需要立即入库的迟到跨度。attrs为跨度属性，与rows一一对应
+func (t *tailSampler) admit(ctx context.Context, rows []*models.APMTrace, attrs []map[string]interface{}) []*models.APMTrace {
+	var late []*models.APMTrace
+	var overflow []string
+
+	t.mu.Lock()
+	now := time.Now()
+	for i, row := range rows {
+		if decision, ok := t.decided[row.TraceID]; ok {
+			if decision.keep {
+				late = append(late, row)
+			}
+			continue
+		}
+
+		trace, ok := t.pending[row.TraceID]
+		if !ok {
+			trace = &pendingTrace{services: map[string]bool{}, firstSeen: now}
+			t.pending[row.TraceID] = trace
+			t.order = append(t.order, row.TraceID)
+		}
+		trace.spans = append(trace.spans, row)
+		trace.services[row.ServiceName] = true
+		if trace.reason == "" {
+			trace.reason = t.keepReason(row, attrs[i])
+		}
+	}
+	// 超出缓冲上限时提前决策最早的链路
+	if t.cfg.MaxBufferedTraces > 0 {
+		for excess := len(t.pending) - t.cfg.MaxBufferedTraces; excess > 0 && len(t.order) > 0; {
+			traceID := t.order[0]
+			t.order = t.order[1:]
+			if _, ok := t.pending[traceID]; ok {
+				overflow = append(overflow, traceID)
+				excess--
+			}
+		}
+	}
+	kept := t.decideLocked(overflow, now)
+	t.mu.Unlock()
+
+	if len(kept) > 0 {
+		if err := t.store(ctx, kept); err != nil {
+			logger.GetLogger("trace").WithError(err).WithField("traces", len(kept)).Error("failed to store overflow sampled traces")
+		}
+	}
+	return late
+}
+
+// keepReason 判断跨度是否使整条链路必定保留
+func (t *tailSampler) keepReason(row *models.APMTrace, attrs map[string]interface{}) string {
+	if row.Status == "error" {
+		return SamplingReasonError
+	}
+	if threshold := t.latencyThreshold(row.ServiceName, row.OperationName); threshold > 0 &&
+		time.Duration(row.Duration)*time.Microsecond > threshold {
+		return SamplingReasonLatency
+	}
+	for _, match := range t.cfg.KeepAttributes {
+		value, ok := attrs[match.Key]
+		if ok && (match.Value == "" || fmt.Sprint(value) == match.Value) {
+			return SamplingReasonAttribute
+		}
+	}
+	return ""
+}
+
+// latencyThreshold 按服务+操作、操作、服务、默认值的顺序取延迟阈值
+func (t *tailSampler) latencyThreshold(service, operation string) time.Duration {
+	for _, key := range []string{
+		latencyThresholdKey(service, operation),
+		latencyThresholdKey("", operation),
+		latencyThresholdKey(service, ""),
+	} {
+		if threshold, ok := t.thresholds[key]; ok {
+			return threshold
+		}
+	}
+	return t.cfg.DefaultLatencyThreshold
+}
+
+// keepRate 链路保留率，涉及多个服务时取最大值，保证每个服务至少达到其配置的保留率
+func (t *tailSampler) keepRate(services map[string]bool) float64 {
+	rate := -1.0
+	for name := range services {
+		// 配置键由viper统一转为小写
+		if r, ok := t.cfg.ServiceKeepRates[strings.ToLower(name)]; ok && r > rate {
+			rate = r
+		}
+	}
+	if rate < 0 {
+		rate = t.cfg.DefaultKeepRate
+	}
+	return rate
+}
+
+// decideLocked 对指定链路做出决策，返回需要入库的跨度
+func (t *tailSampler) decideLocked(traceIDs []string, now time.Time) []*models.APMTrace {
+	var kept []*models.APMTrace
+	for _, traceID := range traceIDs {
+		trace, ok := t.pending[traceID]
+		if !ok {
+			continue
+		}
+		delete(t.pending, traceID)
+
+		reason := trace.reason
+		if reason == "" && traceIDSampled(traceID, t.keepRate(trace.services)) {
+			reason = SamplingReasonRate
+		}
+		keep := reason != ""
+		if keep {
+			kept = append(kept, trace.spans...)
+			t.stats.KeptTraces++
+			t.stats.KeptByReason[reason]++
+		} else {
+			t.stats.DroppedTraces++
+		}
+
+		t.decided[traceID] = samplingDecision{keep: keep, decidedAt: now}
+		t.decidedOrder = append(t.decidedOrder, traceID)
+	}
+	return kept
+}
+
+// flushExpired 决策等待窗口已结束的链路，并清理过期的决策记录；all为true时决策全部缓冲链路
+func (t *tailSampler) flushExpired(ctx context.Context, all bool) error {
+	t.mu.Lock()
+	now := time.Now()
+	var expired []string
+	for len(t.order) > 0 {
+		traceID := t.order[0]
+		trace, ok := t.pending[traceID]
+		if ok && !all && now.Sub(trace.firstSeen) < t.cfg.DecisionWait {
+			break
+		}
+		t.order = t.order[1:]
+		if ok {
+			expired = append(expired, traceID)
+		}
+	}
+	kept := t.decideLocked(expired, now)
+
+	for len(t.decidedOrder) > 0 {
+		traceID := t.decidedOrder[0]
+		if decision, ok := t.decided[traceID]; ok && now.Sub(decision.decidedAt) < t.cfg.LateSpanTTL {
+			break
+		}
+		t.decidedOrder = t.decidedOrder[1:]
+		delete(t.decided, traceID)
+	}
+	t.mu.Unlock()
+
+	if len(kept) == 0 {
+		return nil
+	}
+	return t.store(ctx, kept)
+}
+
+// snapshot 当前统计
+func (t *tailSampler) snapshot() SamplingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats
+	stats.BufferedTraces = len(t.pending)
+	stats.KeptByReason = make(map[string]int64, len(t.stats.KeptByReason))
+	for reason, count := range t.stats.KeptByReason {
+		stats.KeptByReason[reason] = count
+	}
+	return stats
+}
+
+// latencyThresholdKey 延迟阈值索引键
+func latencyThresholdKey(service, operation string) string {
+	return service + "\x00" + operation
+}
+
+// traceIDSampled 以链路ID低64位作为随机数做一致性采样，多个实例对同一链路得出相同结论
+func traceIDSampled(traceID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if len(traceID) < 16 {
+		return false
+	}
+	value, err := strconv.ParseUint(traceID[len(traceID)-16:], 16, 64)
+	if err != nil {
+		return false
+	}
+	return float64(value) < rate*math.MaxUint64
+}
+
+// RunTailSampling 周期性决策等待窗口结束的链路，退出时决策全部缓冲链路
+func (s *TraceIngestService) RunTailSampling(ctx context.Context) {
+	if s.sampler == nil {
+		return
+	}
+	interval := s.sampler.cfg.DecisionWait / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.sampler.flushExpired(context.Background(), true)
+			return
+		case <-ticker.C:
+			if err := s.sampler.flushExpired(ctx, false); err != nil {
+				logger.GetLogger("trace").WithError(err).Error("failed to flush sampled traces")
+			}
+		}
+	}
+}
+
+// GetSamplingStats 获取尾部采样统计，未启用采样时返回nil
+func (s *TraceIngestService) GetSamplingStats() *SamplingStats {
+	if s.sampler == nil {
+		return nil
+	}
+	stats := s.sampler.snapshot()
+	return &stats
+}
+
+// RunTraceRetention 周期性按保留策略清理链路数据
+func (s *TraceIngestService) RunTraceRetention(ctx context.Context, cfg config.TraceRetentionConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PruneTraces(ctx, cfg, time.Now()); err != nil {
+				logger.GetLogger("trace").WithError(err).Error("failed to prune traces")
+			}
+		}
+	}
+}
+
+// PruneTraces 清理过期跨度及其标签索引：超过ErrorMaxAge的全部删除，超过MaxAge且链路中没有错误跨度的删除。返回删除的跨度数
+func (s *TraceIngestService) PruneTraces(ctx context.Context, cfg config.TraceRetentionConfig, now time.Time) (int64, error) {
+	if cfg.MaxAge <= 0 {
+		return 0, nil
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+	cutoff := now.Add(-cfg.MaxAge)
+	errorCutoff := cutoff
+	if cfg.ErrorMaxAge > cfg.MaxAge {
+		errorCutoff = now.Add(-cfg.ErrorMaxAge)
+	}
+
+	var deleted int64
+	// 分批删除，避免长事务锁表
+	for {
+		var ids []string
+		if err := s.db.WithContext(ctx).Model(&models.APMTrace{}).
+			Where("start_time < ?", errorCutoff).
+			Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return deleted, fmt.Errorf("failed to query expired spans: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := s.db.WithContext(ctx).Where("id IN ?", ids).Delete(&models.APMTrace{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete expired spans: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+	for {
+		var ids []string
+		if err := s.db.WithContext(ctx).Model(&models.APMSpanTag{}).
+			Where("start_time < ?", errorCutoff).
+			Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return deleted, fmt.Errorf("failed to query expired span tags: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := s.db.WithContext(ctx).Where("id IN ?", ids).Delete(&models.APMSpanTag{}).Error; err != nil {
+			return deleted, fmt.Errorf("failed to delete expired span tags: %w", err)
+		}
+	}
+
+	if errorCutoff.Equal(cutoff) {
+		return deleted, nil
+	}
+
+	errorTraces := s.db.Model(&models.APMTrace{}).Select("trace_id").Where("status = ?", "error")
+	for {
+		var traceIDs []string
+		if err := s.db.WithContext(ctx).Model(&models.APMTrace{}).
+			Where("start_time < ? AND trace_id NOT IN (?)", cutoff, errorTraces).
+			Distinct("trace_id").Limit(batchSize).
+			Pluck("trace_id", &traceIDs).Error; err != nil {
+			return deleted, fmt.Errorf("failed to query expired traces: %w", err)
+		}
+		if len(traceIDs) == 0 {
+			break
+		}
+		result := s.db.WithContext(ctx).
+			Where("trace_id IN ? AND start_time < ?", traceIDs, cutoff).
+			Delete(&models.APMTrace{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete expired traces: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+		if err := s.db.WithContext(ctx).
+			Where("trace_id IN ? AND start_time < ?", traceIDs, cutoff).
+			Delete(&models.APMSpanTag{}).Error; err != nil {
+			return deleted, fmt.Errorf("failed to delete expired span tags: %w", err)
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"ai-monitor/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxSelfTimeChanges 对比结果中保留的自身耗时变化条数
+const maxSelfTimeChanges = 20
+
+// OperationDistribution 单个操作的延迟分布
+type OperationDistribution struct {
+	Operation  string  `json:"operation"`
+	Count      int64   `json:"count"`
+	ErrorRate  float64 `json:"error_rate"`
+	LatencyP50 float64 `json:"latency_p50"`
+	LatencyP95 float64 `json:"latency_p95"`
+	LatencyP99 float64 `json:"latency_p99"`
+	// Buckets 各桶请求数，桶上界与Bounds对应，最后一个桶为+Inf
+	Buckets   []int64        `json:"buckets"`
+	Exemplars []SpanExemplar `json:"exemplars"`
+}
+
+// VersionDistribution 某个版本的各操作延迟分布
+type VersionDistribution struct {
+	Version    string                  `json:"version"`
+	FirstSeen  time.Time               `json:"first_seen"`
+	LastSeen   time.Time               `json:"last_seen"`
+	Count      int64                   `json:"count"`
+	Operations []OperationDistribution `json:"operations"`
+}
+
+// VersionDistributions 服务按版本划分的延迟分布
+type VersionDistributions struct {
+	ServiceName string                `json:"service_name"`
+	Bounds      []float64             `json:"bounds"`
+	Versions    []VersionDistribution `json:"versions"`
+}
+
+// VersionCompareRequest 版本对比请求。版本为空时不按版本过滤，即按时间窗口对比
+type VersionCompareRequest struct {
+	ServiceName      string    `json:"service_name"`
+	BaselineVersion  string    `json:"baseline_version"`
+	CandidateVersion string    `json:"candidate_version"`
+	BaselineStart    time.Time `json:"baseline_start"`
+	BaselineEnd      time.Time `json:"baseline_end"`
+	CandidateStart   time.Time `json:"candidate_start"`
+	CandidateEnd     time.Time `json:"candidate_end"`
+}
+
+// LatencyStats 延迟与错误率统计
+type LatencyStats struct {
+	Count      int64   `json:"count"`
+	Errors     int64   `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"`
+	LatencyP50 float64 `json:"latency_p50"`
+	LatencyP95 float64 `json:"latency_p95"`
+	LatencyP99 float64 `json:"latency_p99"`
+}
+
+// LatencyComparison 基线与候选的统计对比。变化率为相对值，错误率变化为绝对差
+type LatencyComparison struct {
+	Baseline        LatencyStats `json:"baseline"`
+	Candidate       LatencyStats `json:"candidate"`
+	P50Change       float64      `json:"p50_change"`
+	P95Change       float64      `json:"p95_change"`
+	P99Change       float64      `json:"p99_change"`
+	ErrorRateDelta  float64      `json:"error_rate_delta"`
+	LatencyPValue   float64      `json:"latency_p_value"`
+	ErrorRatePValue float64      `json:"error_rate_p_value"`
+	// LatencyRegressed 延迟分布显著变慢且p95或p99增幅超过阈值
+	LatencyRegressed bool `json:"latency_regressed"`
+	// ErrorRateRegressed 错误率显著上升
+	ErrorRateRegressed bool `json:"error_rate_regressed"`
+	// Sufficient 双方样本数均达到最小要求，否则不做显著性判定
+	Sufficient bool `json:"sufficient"`
+}
+
+// OperationComparison 单个操作的对比
+type OperationComparison struct {
+	Operation string `json:"operation"`
+	LatencyComparison
+}
+
+// SelfTimeChange 操作自身耗时（扣除直接子跨度）变化，单位毫秒
+type SelfTimeChange struct {
+	Operation      string  `json:"operation"`
+	BaselineAvg    float64 `json:"baseline_avg"`
+	CandidateAvg   float64 `json:"candidate_avg"`
+	Delta          float64 `json:"delta"`
+	Change         float64 `json:"change"`
+	BaselineSpans  int64   `json:"baseline_spans"`
+	CandidateSpans int64   `json:"candidate_spans"`
+}
+
+// VersionComparison 版本对比结果
+type VersionComparison struct {
+	Request           VersionCompareRequest `json:"request"`
+	SignificanceLevel float64               `json:"significance_level"`
+	Overall           LatencyComparison     `json:"overall"`
+	Operations        []OperationComparison `json:"operations"`
+	SelfTimeChanges   []SelfTimeChange      `json:"self_time_changes"`
+	Regressed         bool                  `json:"regressed"`
+}
+
+// GetVersionDistributions 按service.version统计服务各操作的延迟分布
+func (s *APMService) GetVersionDistributions(ctx context.Context, serviceName string, startTime, endTime time.Time) (*VersionDistributions, error) {
+	var rows []models.APMSpanMetric
+	if err := s.db.WithContext(ctx).
+		Where("service_name = ? AND bucket_start >= ? AND bucket_end <= ?", serviceName, startTime, endTime).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load span metrics: %w", err)
+	}
+
+	type versionSpan struct{ first, last time.Time }
+	spans := map[string]*versionSpan{}
+	for _, row := range rows {
+		span, ok := spans[row.ServiceVersion]
+		if !ok {
+			spans[row.ServiceVersion] = &versionSpan{first: row.BucketStart, last: row.BucketEnd}
+			continue
+		}
+		if row.BucketStart.Before(span.first) {
+			span.first = row.BucketStart
+		}
+		if row.BucketEnd.After(span.last) {
+			span.last = row.BucketEnd
+		}
+	}
+
+	result := &VersionDistributions{
+		ServiceName: serviceName,
+		Bounds:      spanLatencyBuckets,
+		Versions:    make([]VersionDistribution, 0, len(spans)),
+	}
+	for version, span := range spans {
+		rollups, err := loadSpanMetricRollups(ctx, s.db, serviceName, version, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+		dist := VersionDistribution{Version: version, FirstSeen: span.first, LastSeen: span.last}
+		for _, rollup := range rollups {
+			stats := latencyStatsOf(rollup.Count, rollup.Errors, rollup.Histogram)
+			dist.Count += rollup.Count
+			dist.Operations = append(dist.Operations, OperationDistribution{
+				Operation:  rollup.Operation,
+				Count:      rollup.Count,
+				ErrorRate:  stats.ErrorRate,
+				LatencyP50: stats.LatencyP50,
+				LatencyP95: stats.LatencyP95,
+				LatencyP99: stats.LatencyP99,
+				Buckets:    rollup.Histogram.Counts,
+				Exemplars:  rollup.Histogram.exemplarList(),
+			})
+		}
+		sort.Slice(dist.Operations, func(i, j int) bool { return dist.Operations[i].Count > dist.Operations[j].Count })
+		result.Versions = append(result.Versions, dist)
+	}
+	sort.Slice(result.Versions, func(i, j int) bool { return result.Versions[i].FirstSeen.Before(result.Versions[j].FirstSeen) })
+	return result, nil
+}
+
+// CompareVersions 对比两个版本或两个时间窗口的延迟分布和错误率，
+// 延迟用Mann-Whitney U检验、错误率用双比例z检验判定变化是否显著
+func (s *APMService) CompareVersions(ctx context.Context, req VersionCompareRequest) (*VersionComparison, error) {
+	if req.ServiceName == "" {
+		return nil, errors.New("service name is required")
+	}
+	if err := s.resolveCompareDefaults(ctx, &req); err != nil {
+		return nil, err
+	}
+	if !req.BaselineEnd.After(req.BaselineStart) || !req.CandidateEnd.After(req.CandidateStart) {
+		return nil, errors.New("invalid time range")
+	}
+	if req.BaselineVersion == req.CandidateVersion &&
+		req.BaselineStart.Equal(req.CandidateStart) && req.BaselineEnd.Equal(req.CandidateEnd) {
+		return nil, errors.New("baseline and candidate are identical")
+	}
+
+	baseline, err := loadSpanMetricRollups(ctx, s.db, req.ServiceName, req.BaselineVersion, req.BaselineStart, req.BaselineEnd)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := loadSpanMetricRollups(ctx, s.db, req.ServiceName, req.CandidateVersion, req.CandidateStart, req.CandidateEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := s.config.Alerting.VersionRegression
+	result := &VersionComparison{
+		Request:           req,
+		SignificanceLevel: cfg.SignificanceLevel,
+		Operations:        []OperationComparison{},
+		SelfTimeChanges:   []SelfTimeChange{},
+	}
+
+	baselineAll, candidateAll := &spanMetricRollup{Histogram: newLatencyHistogram()}, &spanMetricRollup{Histogram: newLatencyHistogram()}
+	for operation, b := range baseline {
+		baselineAll.Count += b.Count
+		baselineAll.Errors += b.Errors
+		baselineAll.Histogram.merge(b.Histogram)
+
+		c, ok := candidate[operation]
+		if !ok {
+			continue
+		}
+		comparison := s.compareRollups(b, c)
+		result.Operations = append(result.Operations, OperationComparison{Operation: operation, LatencyComparison: *comparison})
+		if comparison.LatencyRegressed || comparison.ErrorRateRegressed {
+			result.Regressed = true
+		}
+	}
+	for _, c := range candidate {
+		candidateAll.Count += c.Count
+		candidateAll.Errors += c.Errors
+		candidateAll.Histogram.merge(c.Histogram)
+	}
+	result.Overall = *s.compareRollups(baselineAll, candidateAll)
+	if result.Overall.LatencyRegressed || result.Overall.ErrorRateRegressed {
+		result.Regressed = true
+	}
+	sort.Slice(result.Operations, func(i, j int) bool {
+		return result.Operations[i].P95Change > result.Operations[j].P95Change
+	})
+
+	changes, err := s.compareSelfTimes(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result.SelfTimeChanges = changes
+	return result, nil
+}
+
+// resolveCompareDefaults 补全未指定的对比范围：指定候选版本时以其发布记录为准，
+// 基线取上一版本在发布前的数据；否则候选为最近一小时，基线为紧邻的前一个同长窗口
+func (s *APMService) resolveCompareDefaults(ctx context.Context, req *VersionCompareRequest) error {
+	now := time.Now()
+	var deployment *models.ServiceDeployment
+	if req.CandidateVersion != "" {
+		var d models.ServiceDeployment
+		err := s.db.WithContext(ctx).Where("service_name = ? AND version = ?", req.ServiceName, req.CandidateVersion).First(&d).Error
+		switch {
+		case err == nil:
+			deployment = &d
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("failed to get service deployment: %w", err)
+		}
+	}
+	if deployment != nil && req.BaselineVersion == "" {
+		req.BaselineVersion = deployment.PreviousVersion
+	}
+
+	if req.CandidateStart.IsZero() && req.CandidateEnd.IsZero() {
+		req.CandidateEnd = now
+		req.CandidateStart = now.Add(-time.Hour)
+		if deployment != nil {
+			req.CandidateStart = deployment.FirstSeen
+		}
+	}
+	if req.BaselineStart.IsZero() && req.BaselineEnd.IsZero() {
+		switch {
+		case deployment != nil:
+			window := s.config.Alerting.VersionRegression.BaselineWindow
+			if window <= 0 {
+				window = 24 * time.Hour
+			}
+			req.BaselineStart = deployment.FirstSeen.Add(-window)
+			req.BaselineEnd = deployment.FirstSeen
+		case req.BaselineVersion != req.CandidateVersion:
+			// 两个版本同时在线（如灰度）时在同一窗口内对比
+			req.BaselineStart = req.CandidateStart
+			req.BaselineEnd = req.CandidateEnd
+		default:
+			length := req.CandidateEnd.Sub(req.CandidateStart)
+			req.BaselineStart = req.CandidateStart.Add(-length)
+			req.BaselineEnd = req.CandidateStart
+		}
+	}
+	return nil
+}
+
+// compareRollups 对比两组汇总
+func (s *APMService) compareRollups(baseline, candidate *spanMetricRollup) *LatencyComparison {
+	cfg := s.config.Alerting.VersionRegression
+	comparison := &LatencyComparison{
+		Baseline:  latencyStatsOf(baseline.Count, baseline.Errors, baseline.Histogram),
+		Candidate: latencyStatsOf(candidate.Count, candidate.Errors, candidate.Histogram),
+	}
+	comparison.P50Change = relativeChange(comparison.Baseline.LatencyP50, comparison.Candidate.LatencyP50)
+	comparison.P95Change = relativeChange(comparison.Baseline.LatencyP95, comparison.Candidate.LatencyP95)
+	comparison.P99Change = relativeChange(comparison.Baseline.LatencyP99, comparison.Candidate.LatencyP99)
+	comparison.ErrorRateDelta = comparison.Candidate.ErrorRate - comparison.Baseline.ErrorRate
+
+	var z float64
+	z, comparison.LatencyPValue = mannWhitneyU(baseline.Histogram.Counts, candidate.Histogram.Counts)
+	errorZ, errorP := twoProportionZTest(baseline.Errors, baseline.Count, candidate.Errors, candidate.Count)
+	comparison.ErrorRatePValue = errorP
+
+	comparison.Sufficient = baseline.Count >= cfg.MinSamples && candidate.Count >= cfg.MinSamples
+	if comparison.Sufficient {
+		comparison.LatencyRegressed = z > 0 && comparison.LatencyPValue < cfg.SignificanceLevel &&
+			(comparison.P95Change >= cfg.MinLatencyChange || comparison.P99Change >= cfg.MinLatencyChange)
+		comparison.ErrorRateRegressed = errorZ > 0 && errorP < cfg.SignificanceLevel
+	}
+	return comparison
+}
+
+// compareSelfTimes 按操作对比平均自身耗时，返回增长最多的操作
+func (s *APMService) compareSelfTimes(ctx context.Context, req VersionCompareRequest) ([]SelfTimeChange, error) {
+	baseline, err := s.operationSelfTimes(ctx, req.ServiceName, req.BaselineVersion, req.BaselineStart, req.BaselineEnd)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := s.operationSelfTimes(ctx, req.ServiceName, req.CandidateVersion, req.CandidateStart, req.CandidateEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []SelfTimeChange{}
+	for operation, c := range candidate {
+		b, ok := baseline[operation]
+		if !ok {
+			continue
+		}
+		changes = append(changes, SelfTimeChange{
+			Operation:      operation,
+			BaselineAvg:    b.AvgSelf / 1000,
+			CandidateAvg:   c.AvgSelf / 1000,
+			Delta:          (c.AvgSelf - b.AvgSelf) / 1000,
+			Change:         relativeChange(b.AvgSelf, c.AvgSelf),
+			BaselineSpans:  b.Spans,
+			CandidateSpans: c.Spans,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Delta > changes[j].Delta })
+	if len(changes) > maxSelfTimeChanges {
+		changes = changes[:maxSelfTimeChanges]
+	}
+	return changes, nil
+}
+
+// operationSelfTime 操作平均自身耗时（微秒）
+type operationSelfTime struct {
+	OperationName string
+	AvgSelf       float64
+	Spans         int64
+}
+
+// operationSelfTimes 统计时间范围内各操作的平均自身耗时：跨度耗时减去直接子跨度耗时之和。
+// 只统计已采样存储的跨度；并发子跨度会使结果偏小，但不影响版本间的相对比较
+func (s *APMService) operationSelfTimes(ctx context.Context, serviceName, version string, startTime, endTime time.Time) (map[string]operationSelfTime, error) {
+	versionFilter := ""
+	args := []interface{}{startTime, endTime, serviceName, startTime, endTime}
+	if version != "" {
+		versionFilter = " AND t.service_version = ?"
+		args = append(args, version)
+	}
+
+	var rows []operationSelfTime
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT t.operation_name AS operation_name,
+		       AVG(CASE WHEN t.duration > COALESCE(c.child_total, 0) THEN t.duration - COALESCE(c.child_total, 0) ELSE 0 END) AS avg_self,
+		       COUNT(*) AS spans
+		FROM apm_traces t
+		LEFT JOIN (
+			SELECT trace_id, parent_span_id, SUM(duration) AS child_total
+			FROM apm_traces
+			WHERE parent_span_id <> '' AND start_time BETWEEN ? AND ?
+			GROUP BY trace_id, parent_span_id
+		) c ON c.trace_id = t.trace_id AND c.parent_span_id = t.span_id
+		WHERE t.service_name = ? AND t.start_time BETWEEN ? AND ?`+versionFilter+`
+		GROUP BY t.operation_name`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query span self times: %w", err)
+	}
+
+	result := make(map[string]operationSelfTime, len(rows))
+	for _, row := range rows {
+		result[row.OperationName] = row
+	}
+	return result, nil
+}
+
+// ListDeployments 列出服务的版本发布记录及回归评估结果，按首次出现时间倒序
+func (s *APMService) ListDeployments(ctx context.Context, serviceName string, limit int) ([]models.ServiceDeployment, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	var deployments []models.ServiceDeployment
+	if err := s.db.WithContext(ctx).Where("service_name = ?", serviceName).
+		Order("first_seen DESC").Limit(limit).
+		Find(&deployments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list service deployments: %w", err)
+	}
+	return deployments, nil
+}
+
+// latencyStatsOf 由直方图计算统计量
+func latencyStatsOf(count, errorCount int64, hist *latencyHistogram) LatencyStats {
+	stats := LatencyStats{
+		Count:      count,
+		Errors:     errorCount,
+		LatencyP50: hist.quantile(0.50),
+		LatencyP95: hist.quantile(0.95),
+		LatencyP99: hist.quantile(0.99),
+	}
+	if count > 0 {
+		stats.ErrorRate = float64(errorCount) / float64(count)
+	}
+	return stats
+}
+
+// mannWhitneyU 对两个同桶直方图做Mann-Whitney U检验，同桶样本视为并列并做并列校正。
+// 返回候选相对基线的z值（正值表示候选偏慢）和双侧p值
+func mannWhitneyU(baseline, candidate []int64) (z, p float64) {
+	var n1, n2 float64
+	for i := range baseline {
+		n1 += float64(baseline[i])
+	}
+	for i := range candidate {
+		n2 += float64(candidate[i])
+	}
+	n := n1 + n2
+	if n1 == 0 || n2 == 0 || n < 2 {
+		return 0, 1
+	}
+
+	// 同一桶内取平均秩
+	var rankSum, tieTerm, cumulative float64
+	for i := 0; i < len(baseline) || i < len(candidate); i++ {
+		var b, c float64
+		if i < len(baseline) {
+			b = float64(baseline[i])
+		}
+		if i < len(candidate) {
+			c = float64(candidate[i])
+		}
+		t := b + c
+		if t == 0 {
+			continue
+		}
+		midRank := cumulative + (t+1)/2
+		rankSum += c * midRank
+		tieTerm += t*t*t - t
+		cumulative += t
+	}
+
+	u := rankSum - n2*(n2+1)/2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// 全部样本落在同一桶，无法区分
+		return 0, 1
+	}
+	z = (u - mean) / math.Sqrt(variance)
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// twoProportionZTest 双比例z检验，返回候选相对基线的z值（正值表示候选错误率更高）和双侧p值
+func twoProportionZTest(errors1, total1, errors2, total2 int64) (z, p float64) {
+	if total1 == 0 || total2 == 0 {
+		return 0, 1
+	}
+	p1 := float64(errors1) / float64(total1)
+	p2 := float64(errors2) / float64(total2)
+	pooled := float64(errors1+errors2) / float64(total1+total2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(total1) + 1/float64(total2)))
+	if se == 0 {
+		return 0, 1
+	}
+	z = (p2 - p1) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
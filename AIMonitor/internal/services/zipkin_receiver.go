@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// zipkinSpan Zipkin v2跨度
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind"`
+	Timestamp      int64              `json:"timestamp"`
+	Duration       int64              `json:"duration"`
+	Shared         bool               `json:"shared"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+// zipkinEndpoint Zipkin端点
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// zipkinAnnotation Zipkin注解，时间戳单位微秒
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// IngestZipkinSpans 接收Zipkin v2 JSON格式的跨度数组
+func (s *TraceIngestService) IngestZipkinSpans(ctx context.Context, body []byte) (*IngestResult, error) {
+	var raw []zipkinSpan
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode zipkin spans: %w", err)
+	}
+
+	spans := make([]*IngestSpan, 0, len(raw))
+	for i := range raw {
+		spans = append(spans, convertZipkinSpan(&raw[i]))
+	}
+	reparentSharedChildren(raw, spans)
+	return s.Ingest(ctx, spans)
+}
+
+// reparentSharedChildren 共享跨度的服务端另起ID后，同服务内以原共享ID为父的子跨度改挂到服务端跨度下，
+// 否则子跨度会被当作调用方客户端跨度的远程子调用
+func reparentSharedChildren(raw []zipkinSpan, spans []*IngestSpan) {
+	type sharedKey struct {
+		TraceID string
+		SpanID  string
+	}
+	servers := make(map[sharedKey]*IngestSpan)
+	for i, span := range spans {
+		if isDerivedSharedSpan(&raw[i], span) {
+			servers[sharedKey{TraceID: span.TraceID, SpanID: span.ParentSpanID}] = span
+		}
+	}
+	if len(servers) == 0 {
+		return
+	}
+
+	for i, span := range spans {
+		if isDerivedSharedSpan(&raw[i], span) || span.ParentSpanID == "" {
+			continue
+		}
+		server, ok := servers[sharedKey{TraceID: span.TraceID, SpanID: span.ParentSpanID}]
+		if ok && server.ServiceName == span.ServiceName {
+			span.ParentSpanID = server.SpanID
+		}
+	}
+}
+
+// convertZipkinSpan 转换Zipkin跨度，远端端点写入peer.*属性以便服务拓扑识别下游
+func convertZipkinSpan(span *zipkinSpan) *IngestSpan {
+	start := time.UnixMicro(span.Timestamp)
+	if span.Timestamp == 0 {
+		start = time.Time{}
+	}
+	result := &IngestSpan{
+		TraceID:       span.TraceID,
+		SpanID:        padHexID(span.ID),
+		ParentSpanID:  padHexID(span.ParentID),
+		OperationName: span.Name,
+		Kind:          strings.ToLower(span.Kind),
+		StartTime:     start,
+		EndTime:       start.Add(time.Duration(span.Duration) * time.Microsecond),
+		Attributes:    make(map[string]interface{}, len(span.Tags)+3),
+		Resource:      map[string]interface{}{},
+		Scope:         "zipkin",
+	}
+	for k, v := range span.Tags {
+		result.Attributes[k] = v
+	}
+
+	if local := span.LocalEndpoint; local != nil {
+		result.ServiceName = local.ServiceName
+		result.Resource["service.name"] = local.ServiceName
+		if ip := firstNonEmpty(local.IPv4, local.IPv6); ip != "" {
+			result.Resource["host.ip"] = ip
+		}
+	}
+	if remote := span.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			if _, ok := result.Attributes["peer.service"]; !ok {
+				result.Attributes["peer.service"] = remote.ServiceName
+			}
+		}
+		if ip := firstNonEmpty(remote.IPv4, remote.IPv6); ip != "" {
+			result.Attributes["net.peer.ip"] = ip
+		}
+		if remote.Port > 0 {
+			result.Attributes["net.peer.port"] = int64(remote.Port)
+		}
+	}
+
+	// Zipkin以error标签标记失败，值为错误信息或空串
+	if msg, ok := span.Tags["error"]; ok {
+		result.Error = true
+		if msg != "" && msg != "true" {
+			result.StatusMessage = msg
+		}
+	}
+
+	// 共享跨度：服务端与客户端使用同一跨度ID，服务端另起ID并以客户端跨度为父
+	if isDerivedSharedSpan(span, result) {
+		result.ParentSpanID = result.SpanID
+		result.SpanID = derivedSpanID(result.SpanID, "shared")
+	}
+
+	for _, annotation := range span.Annotations {
+		result.Events = append(result.Events, SpanLog{
+			Timestamp: time.UnixMicro(annotation.Timestamp),
+			Fields:    map[string]interface{}{"event": annotation.Value},
+		})
+	}
+	return result
+}
+
+// isDerivedSharedSpan 是否为另起ID的共享跨度服务端，此时ParentSpanID为原共享ID
+func isDerivedSharedSpan(raw *zipkinSpan, span *IngestSpan) bool {
+	return raw.Shared && span.Kind == SpanKindServer && span.SpanID != ""
+}
+
+// padHexID 左侧补零至16位，部分Zipkin客户端省略前导零
+func padHexID(id string) string {
+	if id == "" || len(id) >= 16 {
+		return id
+	}
+	return strings.Repeat("0", 16-len(id)) + id
+}
+
+// derivedSpanID 由原跨度ID派生稳定的新ID，重复上报时结果一致
+func derivedSpanID(spanID, salt string) string {
+	h := fnv.New64a()
+	h.Write([]byte(spanID))
+	h.Write([]byte(salt))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
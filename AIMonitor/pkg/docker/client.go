@@ -0,0 +1,339 @@
+// Package docker 实现Docker Engine API客户端，服务端容器监控与Docker Agent共用
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultEndpoint 默认Docker守护进程地址
+	DefaultEndpoint = "unix:///var/run/docker.sock"
+	// DefaultAPIVersion 客户端支持的最高API版本，协商结果不会超过该版本
+	DefaultAPIVersion = "1.43"
+	// MinAPIVersion 客户端支持的最低API版本
+	MinAPIVersion = "1.24"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Config 客户端配置
+type Config struct {
+	// Endpoint 支持 unix:///path、tcp://host:port、http(s)://host:port，为空时读取DOCKER_HOST
+	Endpoint string
+	// APIVersion 固定API版本，为空时通过/_ping协商
+	APIVersion string
+	// Timeout 非流式请求超时时间
+	Timeout   time.Duration
+	TLSVerify bool
+	CertPath  string
+	KeyPath   string
+	CAPath    string
+}
+
+// Client Docker Engine API客户端
+type Client struct {
+	httpClient *http.Client
+	scheme     string
+	host       string
+	basePath   string
+	timeout    time.Duration
+
+	mu         sync.Mutex
+	version    string
+	negotiated bool
+}
+
+// Error Docker API返回的错误
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("docker api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound 判断错误是否为资源不存在
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewClient 创建Docker客户端，不会立即连接守护进程
+func NewClient(cfg Config) (*Client, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("DOCKER_HOST")
+	}
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker endpoint %q: %w", endpoint, err)
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	c := &Client{
+		timeout: cfg.Timeout,
+		version: cfg.APIVersion,
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.version != "" {
+		c.version = strings.TrimPrefix(c.version, "v")
+		c.negotiated = true
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socketPath := u.Path
+		if socketPath == "" {
+			socketPath = u.Host
+		}
+		if socketPath == "" {
+			return nil, fmt.Errorf("invalid docker endpoint %q: missing socket path", endpoint)
+		}
+		dialer := &net.Dialer{Timeout: c.timeout}
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		// 主机名仅用于构造URL，unix套接字上不会被解析
+		c.scheme, c.host = "http", "docker"
+	case "tcp", "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid docker endpoint %q: missing host", endpoint)
+		}
+		c.scheme, c.host = "http", u.Host
+		if tlsConfig != nil || u.Scheme == "https" {
+			c.scheme = "https"
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+		c.basePath = strings.TrimSuffix(u.Path, "/")
+	default:
+		return nil, fmt.Errorf("unsupported docker endpoint scheme %q", u.Scheme)
+	}
+
+	// 不设置整体超时，流式统计与日志跟随依赖ctx取消
+	c.httpClient = &http.Client{Transport: transport}
+	return c, nil
+}
+
+// buildTLSConfig 根据证书配置构造TLS配置，未配置证书且不校验时返回nil
+func buildTLSConfig(cfg Config) (*tls.Config, error) {
+	if !cfg.TLSVerify && cfg.CertPath == "" && cfg.CAPath == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !cfg.TLSVerify,
+	}
+	if cfg.CAPath != "" {
+		ca, err := os.ReadFile(cfg.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read docker ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse docker ca %s", cfg.CAPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertPath != "" {
+		if cfg.KeyPath == "" {
+			return nil, errors.New("docker tls key path is required when cert path is set")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load docker client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// APIVersion 返回当前使用的API版本，尚未协商时返回空字符串
+func (c *Client) APIVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// NegotiateAPIVersion 通过/_ping获取守护进程API版本，取其与客户端最高版本的较小值
+func (c *Client) NegotiateAPIVersion(ctx context.Context) error {
+	c.mu.Lock()
+	done := c.negotiated
+	c.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	ping, err := c.Ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	version := DefaultAPIVersion
+	if ping.APIVersion != "" && compareVersions(ping.APIVersion, version) < 0 {
+		version = ping.APIVersion
+	}
+	if compareVersions(version, MinAPIVersion) < 0 {
+		return fmt.Errorf("docker api version %s is older than minimum supported %s", version, MinAPIVersion)
+	}
+
+	c.mu.Lock()
+	c.version = version
+	c.negotiated = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Ping 检查守护进程连通性，该接口不带版本前缀
+func (c *Client) Ping(ctx context.Context) (*PingResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL("/_ping", nil, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ping docker daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, parseError(resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	return &PingResult{
+		APIVersion:     resp.Header.Get("Api-Version"),
+		OSType:         resp.Header.Get("Ostype"),
+		Experimental:   resp.Header.Get("Docker-Experimental") == "true",
+		BuilderVersion: resp.Header.Get("Builder-Version"),
+	}, nil
+}
+
+// buildURL 拼接请求地址，version为空时不带版本前缀
+func (c *Client) buildURL(path string, query url.Values, version string) string {
+	u := url.URL{Scheme: c.scheme, Host: c.host, Path: c.basePath + path}
+	if version != "" {
+		u.Path = c.basePath + "/v" + version + path
+	}
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// stream 发送请求并返回响应体，调用方负责关闭
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	if err := c.NegotiateAPIVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.buildURL(path, query, c.APIVersion()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker request %s %s failed: %w", method, path, err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, parseError(resp)
+	}
+	return resp, nil
+}
+
+// do 发送带超时的请求，out非nil时解析JSON响应
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.stream(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode docker response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseError 解析Docker错误响应 {"message": "..."}
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var payload struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+		message = payload.Message
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: message}
+}
+
+// compareVersions 比较形如1.43的版本号
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,318 @@
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDaemon 在临时unix套接字上模拟Docker守护进程
+type fakeDaemon struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+// newUnixClient 启动监听unix套接字的httptest服务并返回连接到该套接字的客户端
+func newUnixClient(t *testing.T, apiVersion string, handler http.HandlerFunc) (*Client, *fakeDaemon) {
+	t.Helper()
+	// unix套接字路径长度有限，不使用层级较深的t.TempDir
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen on unix socket: %v", err)
+	}
+
+	daemon := &fakeDaemon{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		daemon.mu.Lock()
+		daemon.requests = append(daemon.requests, r.Method+" "+r.URL.RequestURI())
+		daemon.mu.Unlock()
+		if r.URL.Path == "/_ping" {
+			w.Header().Set("Api-Version", apiVersion)
+			w.Header().Set("Ostype", "linux")
+			w.Header().Set("Docker-Experimental", "false")
+			io.WriteString(w, "OK")
+			return
+		}
+		handler(w, r)
+	}))
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(Config{Endpoint: "unix://" + socket, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client, daemon
+}
+
+func (d *fakeDaemon) Requests() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.requests...)
+}
+
+func TestContainerListOverUnixSocket(t *testing.T) {
+	client, daemon := newUnixClient(t, "1.41", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.41/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `[
+			{"Id":"abc123","Names":["/web"],"Image":"nginx:1.25","State":"running","Status":"Up 2 hours",
+			 "Labels":{"com.docker.compose.service":"web"},"Created":1700000000,
+			 "Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"}]},
+			{"Id":"def456","Names":["/worker"],"Image":"worker:latest","State":"exited","Status":"Exited (1) 5 minutes ago"}
+		]`)
+	})
+
+	containers, err := client.ContainerList(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ContainerList: %v", err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(containers))
+	}
+	web := containers[0]
+	if web.ID != "abc123" || web.Names[0] != "/web" || web.State != "running" || web.Labels["com.docker.compose.service"] != "web" {
+		t.Fatalf("unexpected container %+v", web)
+	}
+	if len(web.Ports) != 1 || web.Ports[0].PublicPort != 8080 || web.Ports[0].Type != "tcp" {
+		t.Fatalf("unexpected ports %+v", web.Ports)
+	}
+	if containers[1].State != "exited" {
+		t.Fatalf("unexpected container %+v", containers[1])
+	}
+
+	// 守护进程版本低于客户端最高版本时使用守护进程版本
+	if client.APIVersion() != "1.41" {
+		t.Fatalf("unexpected negotiated version %q", client.APIVersion())
+	}
+	requests := daemon.Requests()
+	if len(requests) != 2 || requests[0] != "GET /_ping" || requests[1] != "GET /v1.41/containers/json?all=1" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	// 协商结果被缓存，不再重复ping
+	if _, err := client.ContainerList(context.Background(), false); err != nil {
+		t.Fatalf("ContainerList: %v", err)
+	}
+	requests = daemon.Requests()
+	if len(requests) != 3 || requests[2] != "GET /v1.41/containers/json" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestContainerInspect(t *testing.T) {
+	client, _ := newUnixClient(t, "1.45", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/containers/abc123/json" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{
+			"Id":"abc123","Name":"/web","Image":"sha256:feed","RestartCount":4,
+			"Created":"2024-03-01T10:00:00.123456789Z",
+			"State":{"Status":"running","Running":true,"OOMKilled":true,"Pid":4242,"ExitCode":0,
+			         "StartedAt":"2024-03-01T10:00:01Z","FinishedAt":"0001-01-01T00:00:00Z"},
+			"Config":{"Hostname":"web","Image":"nginx:1.25","Env":["A=1"],"Labels":{"tier":"frontend"}},
+			"HostConfig":{"Memory":268435456,"NanoCpus":500000000,"RestartPolicy":{"Name":"always"}},
+			"NetworkSettings":{"Networks":{"bridge":{"IPAddress":"172.17.0.2","Gateway":"172.17.0.1"}}},
+			"Mounts":[{"Type":"volume","Name":"data","Destination":"/data","RW":true}]
+		}`)
+	})
+
+	container, err := client.ContainerInspect(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ContainerInspect: %v", err)
+	}
+	// 守护进程版本高于客户端时使用客户端最高版本
+	if client.APIVersion() != DefaultAPIVersion {
+		t.Fatalf("unexpected negotiated version %q", client.APIVersion())
+	}
+	if container.Name != "/web" || container.RestartCount != 4 {
+		t.Fatalf("unexpected container %+v", container)
+	}
+	if !container.State.Running || !container.State.OOMKilled || container.State.Pid != 4242 {
+		t.Fatalf("unexpected state %+v", container.State)
+	}
+	if container.Config.Labels["tier"] != "frontend" || container.HostConfig.Memory != 256<<20 || container.HostConfig.RestartPolicy.Name != "always" {
+		t.Fatalf("unexpected config %+v %+v", container.Config, container.HostConfig)
+	}
+	if container.NetworkSettings.Networks["bridge"].IPAddress != "172.17.0.2" || len(container.Mounts) != 1 {
+		t.Fatalf("unexpected network or mounts %+v %+v", container.NetworkSettings, container.Mounts)
+	}
+}
+
+func TestNon2xxResponses(t *testing.T) {
+	client, _ := newUnixClient(t, "1.43", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/missing/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"No such container: missing"}`)
+		case strings.HasSuffix(r.URL.Path, "/busy/restart"):
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, "container is being removed\n")
+		case strings.HasSuffix(r.URL.Path, "/broken/stats"):
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.HasSuffix(r.URL.Path, "/garbage/json"):
+			io.WriteString(w, `{"Id":`)
+		}
+	})
+	ctx := context.Background()
+
+	_, err := client.ContainerInspect(ctx, "missing")
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if apiErr := err.(*Error); apiErr.Message != "No such container: missing" {
+		t.Fatalf("unexpected message %q", apiErr.Message)
+	}
+	if !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("unexpected error text %q", err.Error())
+	}
+
+	// 非JSON错误体原样作为错误信息
+	err = client.ContainerRestart(ctx, "busy", 5)
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusConflict || apiErr.Message != "container is being removed" || IsNotFound(err) {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	_, err = client.ContainerStats(ctx, "broken")
+	if apiErr, ok := err.(*Error); !ok || apiErr.Message != http.StatusText(http.StatusInternalServerError) {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	_, err = client.ContainerInspect(ctx, "garbage")
+	if err == nil || !strings.Contains(err.Error(), "failed to decode docker response") {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+}
+
+func TestPingFailuresAndVersionChecks(t *testing.T) {
+	client, _ := newUnixClient(t, "1.12", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	})
+	if _, err := client.ContainerList(context.Background(), false); err == nil || !strings.Contains(err.Error(), "older than minimum supported") {
+		t.Fatalf("expected version error, got %v", err)
+	}
+
+	// 守护进程未监听时返回连接错误
+	client, err := NewClient(Config{Endpoint: "unix:///nonexistent/docker.sock", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to ping docker daemon") {
+		t.Fatalf("expected connection error, got %v", err)
+	}
+
+	// 固定版本时不进行协商
+	fixed, daemon := newUnixClient(t, "1.43", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[]`)
+	})
+	fixed.version, fixed.negotiated = "1.40", true
+	if _, err := fixed.ContainerList(context.Background(), false); err != nil {
+		t.Fatalf("ContainerList: %v", err)
+	}
+	if requests := daemon.Requests(); len(requests) != 1 || requests[0] != "GET /v1.40/containers/json" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestNewClientEndpoints(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantURL  string
+		wantErr  string
+	}{
+		{endpoint: "tcp://10.0.0.5:2375", wantURL: "http://10.0.0.5:2375/_ping"},
+		{endpoint: "https://docker.example.com:2376/base/", wantURL: "https://docker.example.com:2376/base/_ping"},
+		{endpoint: "unix:///var/run/docker.sock", wantURL: "http://docker/_ping"},
+		{endpoint: "npipe:////./pipe/docker_engine", wantErr: "unsupported docker endpoint scheme"},
+		{endpoint: "tcp://", wantErr: "missing host"},
+		{endpoint: "unix://", wantErr: "missing socket path"},
+	}
+	for _, tt := range tests {
+		client, err := NewClient(Config{Endpoint: tt.endpoint, APIVersion: "v1.41"})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error %q, got %v", tt.endpoint, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewClient: %v", tt.endpoint, err)
+			continue
+		}
+		if got := client.buildURL("/_ping", nil, ""); got != tt.wantURL {
+			t.Errorf("%s: buildURL = %s, want %s", tt.endpoint, got, tt.wantURL)
+		}
+		if client.APIVersion() != "1.41" {
+			t.Errorf("%s: unexpected version %q", tt.endpoint, client.APIVersion())
+		}
+	}
+
+	if _, err := NewClient(Config{Endpoint: "tcp://h:1", CertPath: "/nonexistent/cert.pem"}); err == nil || !strings.Contains(err.Error(), "key path is required") {
+		t.Errorf("expected key path error, got %v", err)
+	}
+	if _, err := NewClient(Config{Endpoint: "tcp://h:1", TLSVerify: true, CAPath: "/nonexistent/ca.pem"}); err == nil || !strings.Contains(err.Error(), "failed to read docker ca") {
+		t.Errorf("expected ca error, got %v", err)
+	}
+}
+
+func TestContainerLogLines(t *testing.T) {
+	frame := func(stream byte, text string) []byte {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+		return append(header, text...)
+	}
+	client, daemon := newUnixClient(t, "1.43", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/app/logs"):
+			w.Write(frame(1, "started\n"))
+			w.Write(frame(2, "warning: slow\r\n"))
+			w.Write(frame(1, "ready\n"))
+		case strings.HasSuffix(r.URL.Path, "/tty/logs"):
+			io.WriteString(w, "line one\nline two\n")
+		}
+	})
+	ctx := context.Background()
+
+	lines, err := client.ContainerLogLines(ctx, "app", LogsOptions{Tail: 50, Follow: true})
+	if err != nil {
+		t.Fatalf("ContainerLogLines: %v", err)
+	}
+	if strings.Join(lines, "|") != "started|warning: slow|ready" {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	requests := daemon.Requests()
+	last := requests[len(requests)-1]
+	if !strings.Contains(last, "tail=50") || !strings.Contains(last, "stdout=1") || !strings.Contains(last, "stderr=1") || strings.Contains(last, "follow") {
+		t.Fatalf("unexpected logs request %s", last)
+	}
+
+	lines, err = client.ContainerLogLines(ctx, "tty", LogsOptions{})
+	if err != nil {
+		t.Fatalf("ContainerLogLines: %v", err)
+	}
+	if strings.Join(lines, "|") != "line one|line two" {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
@@ -0,0 +1,215 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContainerList 获取容器列表，all为false时只返回运行中的容器
+func (c *Client) ContainerList(ctx context.Context, all bool) ([]Container, error) {
+	query := url.Values{}
+	if all {
+		query.Set("all", "1")
+	}
+	var containers []Container
+	if err := c.do(ctx, http.MethodGet, "/containers/json", query, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// ContainerInspect 获取容器详情
+func (c *Client) ContainerInspect(ctx context.Context, containerID string) (*ContainerJSON, error) {
+	var container ContainerJSON
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/json", nil, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// ContainerRestart 重启容器，timeout为停止前等待的秒数，小于0时使用守护进程默认值
+func (c *Client) ContainerRestart(ctx context.Context, containerID string, timeout int) error {
+	query := url.Values{}
+	if timeout >= 0 {
+		query.Set("t", strconv.Itoa(timeout))
+	}
+
+	// 重启需要等待容器停止，请求超时在停止等待时间上额外放宽
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+timeoutSeconds(timeout))
+	defer cancel()
+
+	resp, err := c.stream(ctx, http.MethodPost, "/containers/"+url.PathEscape(containerID)+"/restart", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// ContainerStats 获取一次容器统计，守护进程会采样两次以填充precpu_stats
+func (c *Client) ContainerStats(ctx context.Context, containerID string) (*Stats, error) {
+	query := url.Values{}
+	query.Set("stream", "false")
+
+	var stats Stats
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/stats", query, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ContainerStatsStream 持续读取容器统计直到ctx取消、容器停止或handler返回错误
+func (c *Client) ContainerStatsStream(ctx context.Context, containerID string, handler func(*Stats) error) error {
+	query := url.Values{}
+	query.Set("stream", "true")
+
+	resp, err := c.stream(ctx, http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/stats", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var stats Stats
+		if err := decoder.Decode(&stats); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode docker stats stream: %w", err)
+		}
+		if err := handler(&stats); err != nil {
+			return err
+		}
+	}
+}
+
+// ContainerLogs 获取容器日志原始流，非TTY容器的输出为多路复用格式，调用方负责关闭
+func (c *Client) ContainerLogs(ctx context.Context, containerID string, options LogsOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	if !options.Stdout && !options.Stderr {
+		options.Stdout, options.Stderr = true, true
+	}
+	if options.Stdout {
+		query.Set("stdout", "1")
+	}
+	if options.Stderr {
+		query.Set("stderr", "1")
+	}
+	if !options.Since.IsZero() {
+		query.Set("since", strconv.FormatInt(options.Since.Unix(), 10))
+	}
+	if !options.Until.IsZero() {
+		query.Set("until", strconv.FormatInt(options.Until.Unix(), 10))
+	}
+	if options.Tail > 0 {
+		query.Set("tail", strconv.Itoa(options.Tail))
+	} else {
+		query.Set("tail", "all")
+	}
+	if options.Follow {
+		query.Set("follow", "1")
+	}
+	if options.Timestamps {
+		query.Set("timestamps", "1")
+	}
+
+	resp, err := c.stream(ctx, http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ContainerLogLines 读取容器日志并按行返回，自动识别多路复用格式
+func (c *Client) ContainerLogLines(ctx context.Context, containerID string, options LogsOptions) ([]string, error) {
+	// 按行返回时不跟随日志，否则调用会一直阻塞
+	options.Follow = false
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body, err := c.ContainerLogs(ctx, containerID, options)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ReadLogLines(body)
+}
+
+// ReadLogLines 解析日志流为行，多路复用帧头为 [流类型, 0, 0, 0, 长度(大端4字节)]
+func ReadLogLines(r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(8)
+	if err != nil && len(header) == 0 {
+		if errors.Is(err, io.EOF) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	var data []byte
+	if isMultiplexed(header) {
+		var buf bytes.Buffer
+		frame := make([]byte, 8)
+		for {
+			if _, err := io.ReadFull(br, frame); err != nil {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					break
+				}
+				return nil, fmt.Errorf("failed to read docker log frame: %w", err)
+			}
+			size := binary.BigEndian.Uint32(frame[4:])
+			if _, err := io.CopyN(&buf, br, int64(size)); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("failed to read docker log frame: %w", err)
+			}
+		}
+		data = buf.Bytes()
+	} else {
+		if data, err = io.ReadAll(br); err != nil {
+			return nil, fmt.Errorf("failed to read docker logs: %w", err)
+		}
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
+
+// isMultiplexed 判断流是否以多路复用帧头开始（stdin/stdout/stderr）
+func isMultiplexed(header []byte) bool {
+	return len(header) == 8 && header[0] <= 2 && header[1] == 0 && header[2] == 0 && header[3] == 0
+}
+
+// timeoutSeconds 重启等待时间，未指定时按守护进程默认的10秒计算
+func timeoutSeconds(timeout int) time.Duration {
+	if timeout < 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(timeout) * time.Second
+}
@@ -0,0 +1,110 @@
+package docker
+
+import "strings"
+
+// CPUPercent 根据本次与上次采样的差值计算CPU使用率，100%表示占满一个核心
+func CPUPercent(s *Stats) float64 {
+	if s == nil {
+		return 0
+	}
+
+	// Windows容器没有系统CPU时间，按采样间隔内可用的100纳秒时间片计算
+	if s.NumProcs > 0 && s.CPUStats.SystemUsage == 0 {
+		if s.PreRead.IsZero() || !s.Read.After(s.PreRead) {
+			return 0
+		}
+		intervals := uint64(s.Read.Sub(s.PreRead).Nanoseconds()) / 100 * uint64(s.NumProcs)
+		if intervals == 0 || s.CPUStats.CPUUsage.TotalUsage < s.PreCPUStats.CPUUsage.TotalUsage {
+			return 0
+		}
+		used := s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage
+		return float64(used) / float64(intervals) * 100
+	}
+
+	// 首个采样或容器重启后计数器回绕时没有有效差值
+	if s.CPUStats.CPUUsage.TotalUsage < s.PreCPUStats.CPUUsage.TotalUsage ||
+		s.CPUStats.SystemUsage <= s.PreCPUStats.SystemUsage || s.PreCPUStats.SystemUsage == 0 {
+		return 0
+	}
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage - s.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(s.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if onlineCPUs == 0 {
+		onlineCPUs = 1
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+// MemoryUsage 计算实际内存占用，与docker stats一致扣除非活跃文件缓存
+func MemoryUsage(s *Stats) uint64 {
+	if s == nil {
+		return 0
+	}
+	if s.MemoryStats.PrivateWorkingSet > 0 {
+		return s.MemoryStats.PrivateWorkingSet
+	}
+	usage := s.MemoryStats.Usage
+	// cgroup v1使用total_inactive_file，cgroup v2使用inactive_file
+	if v, ok := s.MemoryStats.Stats["total_inactive_file"]; ok && v < usage {
+		return usage - v
+	}
+	if v, ok := s.MemoryStats.Stats["inactive_file"]; ok && v < usage {
+		return usage - v
+	}
+	return usage
+}
+
+// MemoryPercent 计算内存使用率，未设置限制时返回0
+func MemoryPercent(s *Stats) float64 {
+	if s == nil || s.MemoryStats.Limit == 0 {
+		return 0
+	}
+	return float64(MemoryUsage(s)) / float64(s.MemoryStats.Limit) * 100
+}
+
+// NetworkTotals 汇总所有网卡的统计
+func NetworkTotals(s *Stats) NetworkStats {
+	var total NetworkStats
+	if s == nil {
+		return total
+	}
+	for _, n := range s.Networks {
+		total.RxBytes += n.RxBytes
+		total.RxPackets += n.RxPackets
+		total.RxErrors += n.RxErrors
+		total.RxDropped += n.RxDropped
+		total.TxBytes += n.TxBytes
+		total.TxPackets += n.TxPackets
+		total.TxErrors += n.TxErrors
+		total.TxDropped += n.TxDropped
+	}
+	return total
+}
+
+// BlockIOTotals 汇总块设备读写字节数与次数
+func BlockIOTotals(s *Stats) (readBytes, writeBytes, readOps, writeOps uint64) {
+	if s == nil {
+		return
+	}
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			readBytes += e.Value
+		case "write":
+			writeBytes += e.Value
+		}
+	}
+	for _, e := range s.BlkioStats.IoServicedRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			readOps += e.Value
+		case "write":
+			writeOps += e.Value
+		}
+	}
+	return
+}
@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// linuxStatsJSON cgroup v2主机上/containers/{id}/stats?stream=false的响应
+const linuxStatsJSON = `{
+	"id":"abc123","name":"/web",
+	"read":"2024-03-01T10:00:02Z","preread":"2024-03-01T10:00:01Z",
+	"cpu_stats":{"cpu_usage":{"total_usage":1400000000,"usage_in_kernelmode":200000000,"usage_in_usermode":1200000000},
+	             "system_cpu_usage":20000000000,"online_cpus":4,
+	             "throttling_data":{"periods":10,"throttled_periods":2,"throttled_time":5000}},
+	"precpu_stats":{"cpu_usage":{"total_usage":1000000000},"system_cpu_usage":16000000000,"online_cpus":4},
+	"memory_stats":{"usage":536870912,"limit":2147483648,"stats":{"inactive_file":134217728,"anon":300000000}},
+	"blkio_stats":{
+		"io_service_bytes_recursive":[{"major":8,"minor":0,"op":"read","value":4096},{"major":8,"minor":0,"op":"write","value":8192},
+		                              {"major":8,"minor":16,"op":"Read","value":1024}],
+		"io_serviced_recursive":[{"major":8,"minor":0,"op":"read","value":3},{"major":8,"minor":0,"op":"write","value":7}]},
+	"pids_stats":{"current":12,"limit":100},
+	"networks":{"eth0":{"rx_bytes":1000,"rx_packets":10,"tx_bytes":2000,"tx_packets":20,"rx_dropped":1},
+	            "eth1":{"rx_bytes":500,"rx_packets":5,"tx_bytes":300,"tx_packets":3,"tx_errors":2}}
+}`
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestContainerStatsCalculations(t *testing.T) {
+	client, daemon := newUnixClient(t, "1.43", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.43/containers/abc123/stats" || r.URL.Query().Get("stream") != "false" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, linuxStatsJSON)
+	})
+
+	stats, err := client.ContainerStats(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("ContainerStats: %v", err)
+	}
+	if requests := daemon.Requests(); requests[len(requests)-1] != "GET /v1.43/containers/abc123/stats?stream=false" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	// cpuDelta 0.4s / systemDelta 4s * 4核 * 100 = 40%
+	if got := CPUPercent(stats); !almostEqual(got, 40) {
+		t.Fatalf("CPUPercent = %v, want 40", got)
+	}
+	// 512MiB - 128MiB inactive_file = 384MiB，限制2GiB
+	if got := MemoryUsage(stats); got != 384<<20 {
+		t.Fatalf("MemoryUsage = %d, want %d", got, 384<<20)
+	}
+	if got := MemoryPercent(stats); !almostEqual(got, 18.75) {
+		t.Fatalf("MemoryPercent = %v, want 18.75", got)
+	}
+
+	net := NetworkTotals(stats)
+	if net.RxBytes != 1500 || net.TxBytes != 2300 || net.RxPackets != 15 || net.TxPackets != 23 || net.RxDropped != 1 || net.TxErrors != 2 {
+		t.Fatalf("unexpected network totals %+v", net)
+	}
+	readBytes, writeBytes, readOps, writeOps := BlockIOTotals(stats)
+	if readBytes != 5120 || writeBytes != 8192 || readOps != 3 || writeOps != 7 {
+		t.Fatalf("unexpected block io totals %d %d %d %d", readBytes, writeBytes, readOps, writeOps)
+	}
+	if stats.PidsStats.Current != 12 || stats.CPUStats.ThrottlingData.ThrottledPeriods != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCPUPercentEdgeCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats *Stats
+		want  float64
+	}{
+		{name: "nil", stats: nil, want: 0},
+		{
+			// 首个采样没有precpu_stats
+			name:  "first sample",
+			stats: &Stats{CPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 500}, SystemUsage: 1000, OnlineCPUs: 2}},
+			want:  0,
+		},
+		{
+			// 容器重启后计数器从0开始
+			name: "counter reset",
+			stats: &Stats{
+				CPUStats:    CPUStats{CPUUsage: CPUUsage{TotalUsage: 100}, SystemUsage: 2000, OnlineCPUs: 2},
+				PreCPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 900}, SystemUsage: 1000, OnlineCPUs: 2},
+			},
+			want: 0,
+		},
+		{
+			// 旧版本守护进程不返回online_cpus时按percpu_usage数量计算
+			name: "percpu fallback",
+			stats: &Stats{
+				CPUStats:    CPUStats{CPUUsage: CPUUsage{TotalUsage: 300, PercpuUsage: []uint64{100, 100, 50, 50}}, SystemUsage: 2000},
+				PreCPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 100}, SystemUsage: 1000},
+			},
+			want: 80,
+		},
+		{
+			name: "single cpu fallback",
+			stats: &Stats{
+				CPUStats:    CPUStats{CPUUsage: CPUUsage{TotalUsage: 350}, SystemUsage: 2000},
+				PreCPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 100}, SystemUsage: 1000},
+			},
+			want: 25,
+		},
+		{
+			// Windows：1秒间隔、2个处理器共2e7个100纳秒时间片，使用5e6个即25%
+			name: "windows",
+			stats: &Stats{
+				Read:        time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+				PreRead:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				NumProcs:    2,
+				CPUStats:    CPUStats{CPUUsage: CPUUsage{TotalUsage: 15000000}},
+				PreCPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 10000000}},
+			},
+			want: 25,
+		},
+		{
+			name: "windows without preread",
+			stats: &Stats{
+				Read:     time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+				NumProcs: 2,
+				CPUStats: CPUStats{CPUUsage: CPUUsage{TotalUsage: 15000000}},
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		if got := CPUPercent(tt.stats); !almostEqual(got, tt.want) {
+			t.Errorf("%s: CPUPercent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryUsageVariants(t *testing.T) {
+	tests := []struct {
+		name        string
+		stats       MemoryStats
+		wantUsage   uint64
+		wantPercent float64
+	}{
+		{
+			name:        "cgroup v1",
+			stats:       MemoryStats{Usage: 1000, Limit: 4000, Stats: map[string]uint64{"total_inactive_file": 200, "inactive_file": 100}},
+			wantUsage:   800,
+			wantPercent: 20,
+		},
+		{
+			name:        "cgroup v2",
+			stats:       MemoryStats{Usage: 1000, Limit: 2000, Stats: map[string]uint64{"inactive_file": 400}},
+			wantUsage:   600,
+			wantPercent: 30,
+		},
+		{
+			// 缓存统计异常大于用量时不扣除
+			name:        "inactive larger than usage",
+			stats:       MemoryStats{Usage: 100, Limit: 1000, Stats: map[string]uint64{"inactive_file": 400}},
+			wantUsage:   100,
+			wantPercent: 10,
+		},
+		{
+			name:        "no limit",
+			stats:       MemoryStats{Usage: 100},
+			wantUsage:   100,
+			wantPercent: 0,
+		},
+		{
+			name:        "windows private working set",
+			stats:       MemoryStats{PrivateWorkingSet: 700, Commit: 900},
+			wantUsage:   700,
+			wantPercent: 0,
+		},
+	}
+	for _, tt := range tests {
+		stats := &Stats{MemoryStats: tt.stats}
+		if got := MemoryUsage(stats); got != tt.wantUsage {
+			t.Errorf("%s: MemoryUsage = %d, want %d", tt.name, got, tt.wantUsage)
+		}
+		if got := MemoryPercent(stats); !almostEqual(got, tt.wantPercent) {
+			t.Errorf("%s: MemoryPercent = %v, want %v", tt.name, got, tt.wantPercent)
+		}
+	}
+	if MemoryUsage(nil) != 0 || MemoryPercent(nil) != 0 {
+		t.Error("nil stats should report zero memory")
+	}
+}
+
+func TestContainerStatsStream(t *testing.T) {
+	client, _ := newUnixClient(t, "1.43", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, linuxStatsJSON+"\n")
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	var samples []float64
+	err := client.ContainerStatsStream(context.Background(), "abc123", func(s *Stats) error {
+		samples = append(samples, CPUPercent(s))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ContainerStatsStream: %v", err)
+	}
+	if len(samples) != 3 || !almostEqual(samples[2], 40) {
+		t.Fatalf("unexpected samples %v", samples)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = client.ContainerStatsStream(context.Background(), "abc123", func(*Stats) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected handler error after one sample, got %v (%d calls)", err, calls)
+	}
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Info 获取守护进程系统信息
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var info Info
+	if err := c.do(ctx, http.MethodGet, "/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ServerVersion 获取守护进程版本信息
+func (c *Client) ServerVersion(ctx context.Context) (*Version, error) {
+	var version Version
+	if err := c.do(ctx, http.MethodGet, "/version", nil, &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ImageList 获取镜像列表，filters对应Docker API的filters参数，如 {"dangling": ["true"]}
+func (c *Client) ImageList(ctx context.Context, filters map[string][]string) ([]ImageSummary, error) {
+	query, err := filterQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+	var images []ImageSummary
+	if err := c.do(ctx, http.MethodGet, "/images/json", query, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// VolumeList 获取卷列表
+func (c *Client) VolumeList(ctx context.Context) ([]*Volume, error) {
+	var resp struct {
+		Volumes []*Volume `json:"Volumes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/volumes", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Volumes, nil
+}
+
+// NetworkList 获取网络列表
+func (c *Client) NetworkList(ctx context.Context) ([]NetworkResource, error) {
+	var networks []NetworkResource
+	if err := c.do(ctx, http.MethodGet, "/networks", nil, &networks); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
+// DiskUsage 获取镜像、容器与卷的磁盘占用，卷大小只能由该接口获得
+func (c *Client) DiskUsage(ctx context.Context) (*DiskUsage, error) {
+	var usage DiskUsage
+	if err := c.do(ctx, http.MethodGet, "/system/df", nil, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// filterQuery 将过滤条件编码为filters查询参数
+func filterQuery(filters map[string][]string) (url.Values, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"filters": []string{string(data)}}, nil
+}
+
+// Events 订阅守护进程事件流，直到ctx取消、服务端关闭连接或handler返回错误
+func (c *Client) Events(ctx context.Context, options EventsOptions, handler func(*Event) error) error {
+	query, err := filterQuery(options.Filters)
+	if err != nil {
+		return err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	if !options.Since.IsZero() {
+		query.Set("since", unixNanoString(options.Since))
+	}
+	if !options.Until.IsZero() {
+		query.Set("until", unixNanoString(options.Until))
+	}
+
+	resp, err := c.stream(ctx, http.MethodGet, "/events", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode docker event stream: %w", err)
+		}
+		if err := handler(&event); err != nil {
+			return err
+		}
+	}
+}
+
+// unixNanoString 格式化为Docker接受的 秒.纳秒 时间戳
+func unixNanoString(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
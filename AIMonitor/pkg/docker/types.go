@@ -0,0 +1,333 @@
+package docker
+
+import "time"
+
+// PingResult /_ping响应头信息
+type PingResult struct {
+	APIVersion     string
+	OSType         string
+	Experimental   bool
+	BuilderVersion string
+}
+
+// Port 容器端口映射
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+// MountPoint 容器挂载点
+type MountPoint struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	Driver      string `json:"Driver"`
+	Mode        string `json:"Mode"`
+	RW          bool   `json:"RW"`
+}
+
+// Container /containers/json列表项
+type Container struct {
+	ID         string            `json:"Id"`
+	Names      []string          `json:"Names"`
+	Image      string            `json:"Image"`
+	ImageID    string            `json:"ImageID"`
+	Command    string            `json:"Command"`
+	Created    int64             `json:"Created"`
+	Ports      []Port            `json:"Ports"`
+	Labels     map[string]string `json:"Labels"`
+	State      string            `json:"State"`
+	Status     string            `json:"Status"`
+	Mounts     []MountPoint      `json:"Mounts"`
+	SizeRw     int64             `json:"SizeRw"`
+	SizeRootFs int64             `json:"SizeRootFs"`
+}
+
+// ContainerState 容器运行状态
+type ContainerState struct {
+	Status     string    `json:"Status"`
+	Running    bool      `json:"Running"`
+	Paused     bool      `json:"Paused"`
+	Restarting bool      `json:"Restarting"`
+	OOMKilled  bool      `json:"OOMKilled"`
+	Dead       bool      `json:"Dead"`
+	Pid        int       `json:"Pid"`
+	ExitCode   int       `json:"ExitCode"`
+	Error      string    `json:"Error"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+}
+
+// ContainerConfig 容器创建配置
+type ContainerConfig struct {
+	Hostname     string            `json:"Hostname"`
+	Domainname   string            `json:"Domainname"`
+	User         string            `json:"User"`
+	AttachStdin  bool              `json:"AttachStdin"`
+	AttachStdout bool              `json:"AttachStdout"`
+	AttachStderr bool              `json:"AttachStderr"`
+	Tty          bool              `json:"Tty"`
+	OpenStdin    bool              `json:"OpenStdin"`
+	StdinOnce    bool              `json:"StdinOnce"`
+	Env          []string          `json:"Env"`
+	Cmd          []string          `json:"Cmd"`
+	Entrypoint   []string          `json:"Entrypoint"`
+	Image        string            `json:"Image"`
+	Labels       map[string]string `json:"Labels"`
+	WorkingDir   string            `json:"WorkingDir"`
+}
+
+// HostConfig 容器主机配置
+type HostConfig struct {
+	NetworkMode   string `json:"NetworkMode"`
+	CPUShares     int64  `json:"CpuShares"`
+	Memory        int64  `json:"Memory"`
+	MemorySwap    int64  `json:"MemorySwap"`
+	CPUPeriod     int64  `json:"CpuPeriod"`
+	CPUQuota      int64  `json:"CpuQuota"`
+	NanoCPUs      int64  `json:"NanoCpus"`
+	CpusetCpus    string `json:"CpusetCpus"`
+	CpusetMems    string `json:"CpusetMems"`
+	BlkioWeight   int64  `json:"BlkioWeight"`
+	RestartPolicy struct {
+		Name              string `json:"Name"`
+		MaximumRetryCount int    `json:"MaximumRetryCount"`
+	} `json:"RestartPolicy"`
+}
+
+// EndpointSettings 容器在某个网络中的配置
+type EndpointSettings struct {
+	NetworkID  string `json:"NetworkID"`
+	EndpointID string `json:"EndpointID"`
+	Gateway    string `json:"Gateway"`
+	IPAddress  string `json:"IPAddress"`
+	MacAddress string `json:"MacAddress"`
+}
+
+// NetworkSettings 容器网络配置
+type NetworkSettings struct {
+	Networks map[string]EndpointSettings `json:"Networks"`
+}
+
+// ContainerJSON /containers/{id}/json响应
+type ContainerJSON struct {
+	ID              string           `json:"Id"`
+	Name            string           `json:"Name"`
+	Created         time.Time        `json:"Created"`
+	Image           string           `json:"Image"`
+	RestartCount    int              `json:"RestartCount"`
+	State           *ContainerState  `json:"State"`
+	Config          *ContainerConfig `json:"Config"`
+	HostConfig      *HostConfig      `json:"HostConfig"`
+	NetworkSettings *NetworkSettings `json:"NetworkSettings"`
+	Mounts          []MountPoint     `json:"Mounts"`
+}
+
+// CPUUsage CPU累计使用量（纳秒，Windows为100纳秒）
+type CPUUsage struct {
+	TotalUsage        uint64   `json:"total_usage"`
+	PercpuUsage       []uint64 `json:"percpu_usage"`
+	UsageInKernelmode uint64   `json:"usage_in_kernelmode"`
+	UsageInUsermode   uint64   `json:"usage_in_usermode"`
+}
+
+// ThrottlingData CPU限流数据
+type ThrottlingData struct {
+	Periods          uint64 `json:"periods"`
+	ThrottledPeriods uint64 `json:"throttled_periods"`
+	ThrottledTime    uint64 `json:"throttled_time"`
+}
+
+// CPUStats CPU统计
+type CPUStats struct {
+	CPUUsage       CPUUsage       `json:"cpu_usage"`
+	SystemUsage    uint64         `json:"system_cpu_usage"`
+	OnlineCPUs     uint32         `json:"online_cpus"`
+	ThrottlingData ThrottlingData `json:"throttling_data"`
+}
+
+// MemoryStats 内存统计，Stats按cgroup版本包含不同键
+type MemoryStats struct {
+	Usage    uint64            `json:"usage"`
+	MaxUsage uint64            `json:"max_usage"`
+	Limit    uint64            `json:"limit"`
+	Failcnt  uint64            `json:"failcnt"`
+	Stats    map[string]uint64 `json:"stats"`
+	// Windows专用
+	Commit            uint64 `json:"commitbytes"`
+	CommitPeak        uint64 `json:"commitpeakbytes"`
+	PrivateWorkingSet uint64 `json:"privateworkingset"`
+}
+
+// BlkioStatEntry 块设备IO统计项
+type BlkioStatEntry struct {
+	Major uint64 `json:"major"`
+	Minor uint64 `json:"minor"`
+	Op    string `json:"op"`
+	Value uint64 `json:"value"`
+}
+
+// BlkioStats 块设备IO统计
+type BlkioStats struct {
+	IoServiceBytesRecursive []BlkioStatEntry `json:"io_service_bytes_recursive"`
+	IoServicedRecursive     []BlkioStatEntry `json:"io_serviced_recursive"`
+}
+
+// NetworkStats 单个网卡统计
+type NetworkStats struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+// PidsStats 进程数统计
+type PidsStats struct {
+	Current uint64 `json:"current"`
+	Limit   uint64 `json:"limit"`
+}
+
+// Stats /containers/{id}/stats响应
+type Stats struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Read        time.Time               `json:"read"`
+	PreRead     time.Time               `json:"preread"`
+	NumProcs    uint32                  `json:"num_procs"`
+	CPUStats    CPUStats                `json:"cpu_stats"`
+	PreCPUStats CPUStats                `json:"precpu_stats"`
+	MemoryStats MemoryStats             `json:"memory_stats"`
+	BlkioStats  BlkioStats              `json:"blkio_stats"`
+	PidsStats   PidsStats               `json:"pids_stats"`
+	Networks    map[string]NetworkStats `json:"networks"`
+}
+
+// Info /info响应
+type Info struct {
+	ID                string `json:"ID"`
+	Name              string `json:"Name"`
+	Containers        int    `json:"Containers"`
+	ContainersRunning int    `json:"ContainersRunning"`
+	ContainersPaused  int    `json:"ContainersPaused"`
+	ContainersStopped int    `json:"ContainersStopped"`
+	Images            int    `json:"Images"`
+	Driver            string `json:"Driver"`
+	KernelVersion     string `json:"KernelVersion"`
+	OperatingSystem   string `json:"OperatingSystem"`
+	OSType            string `json:"OSType"`
+	Architecture      string `json:"Architecture"`
+	NCPU              int    `json:"NCPU"`
+	MemTotal          int64  `json:"MemTotal"`
+	ServerVersion     string `json:"ServerVersion"`
+	CgroupVersion     string `json:"CgroupVersion"`
+}
+
+// Version /version响应
+type Version struct {
+	Version       string `json:"Version"`
+	APIVersion    string `json:"ApiVersion"`
+	MinAPIVersion string `json:"MinAPIVersion"`
+	GitCommit     string `json:"GitCommit"`
+	GoVersion     string `json:"GoVersion"`
+	Os            string `json:"Os"`
+	Arch          string `json:"Arch"`
+	KernelVersion string `json:"KernelVersion"`
+}
+
+// ImageSummary /images/json列表项
+type ImageSummary struct {
+	ID          string            `json:"Id"`
+	ParentID    string            `json:"ParentId"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	SharedSize  int64             `json:"SharedSize"`
+	Labels      map[string]string `json:"Labels"`
+	Containers  int64             `json:"Containers"`
+}
+
+// VolumeUsage 卷使用量，不可用时为-1
+type VolumeUsage struct {
+	Size     int64 `json:"Size"`
+	RefCount int64 `json:"RefCount"`
+}
+
+// Volume 卷信息
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Scope      string            `json:"Scope"`
+	Labels     map[string]string `json:"Labels"`
+	UsageData  *VolumeUsage      `json:"UsageData"`
+}
+
+// NetworkResource /networks列表项
+type NetworkResource struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Scope      string            `json:"Scope"`
+	Internal   bool              `json:"Internal"`
+	Attachable bool              `json:"Attachable"`
+	Labels     map[string]string `json:"Labels"`
+}
+
+// DiskUsage /system/df响应
+type DiskUsage struct {
+	LayersSize int64           `json:"LayersSize"`
+	Images     []*ImageSummary `json:"Images"`
+	Containers []*Container    `json:"Containers"`
+	Volumes    []*Volume       `json:"Volumes"`
+}
+
+// LogsOptions 日志查询选项
+type LogsOptions struct {
+	Stdout     bool
+	Stderr     bool
+	Since      time.Time
+	Until      time.Time
+	Tail       int
+	Follow     bool
+	Timestamps bool
+}
+
+// EventActor 事件主体，Attributes包含name、image、exitCode等
+type EventActor struct {
+	ID         string            `json:"ID"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// Event /events流中的单个事件
+type Event struct {
+	Type     string     `json:"Type"`
+	Action   string     `json:"Action"`
+	Actor    EventActor `json:"Actor"`
+	Scope    string     `json:"scope"`
+	Time     int64      `json:"time"`
+	TimeNano int64      `json:"timeNano"`
+}
+
+// Timestamp 事件发生时间
+func (e *Event) Timestamp() time.Time {
+	if e.TimeNano > 0 {
+		return time.Unix(0, e.TimeNano)
+	}
+	return time.Unix(e.Time, 0)
+}
+
+// EventsOptions 事件订阅选项
+type EventsOptions struct {
+	// Since 回放该时间之后的历史事件，用于断线重连时补齐
+	Since   time.Time
+	Until   time.Time
+	Filters map[string][]string
+}
@@ -0,0 +1,330 @@
+// Package kubernetes 实现Kubernetes API Server的REST客户端，支持kubeconfig与集群内ServiceAccount认证
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 15 * time.Second
+	// tokenRefreshInterval 重新读取令牌文件的间隔，ServiceAccount绑定令牌会定期轮换
+	tokenRefreshInterval = time.Minute
+)
+
+// Client Kubernetes API客户端
+type Client struct {
+	httpClient *http.Client
+	host       string
+	basePath   string
+	timeout    time.Duration
+	namespace  string
+
+	username string
+	password string
+
+	tokenMu       sync.Mutex
+	token         string
+	tokenFile     string
+	tokenLoadedAt time.Time
+}
+
+// Error API Server返回的错误，Reason对应metav1.Status.reason
+type Error struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("kubernetes api error (status %d, %s): %s", e.StatusCode, e.Reason, e.Message)
+	}
+	return fmt.Sprintf("kubernetes api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound 判断错误是否为资源不存在
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsForbidden 判断错误是否为权限不足
+func IsForbidden(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// NewClient 创建客户端，不会立即连接API Server
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg == nil || cfg.Host == "" {
+		return nil, errors.New("kubernetes api server host is required")
+	}
+
+	host := cfg.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubernetes api server %q: %w", cfg.Host, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported kubernetes api server scheme %q", u.Scheme)
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if u.Scheme == "https" {
+		tlsConfig, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	c := &Client{
+		httpClient: &http.Client{Transport: transport},
+		host:       u.Scheme + "://" + u.Host,
+		basePath:   strings.TrimSuffix(u.Path, "/"),
+		timeout:    cfg.Timeout,
+		namespace:  cfg.Namespace,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		token:      cfg.BearerToken,
+		tokenFile:  cfg.BearerTokenFile,
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.namespace == "" {
+		c.namespace = "default"
+	}
+	if c.tokenFile != "" {
+		if _, err := c.bearerToken(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// buildTLSConfig 根据CA与客户端证书构造TLS配置
+func buildTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.Insecure,
+		ServerName:         cfg.ServerName,
+	}
+
+	ca := cfg.CAData
+	if len(ca) == 0 && cfg.CAFile != "" {
+		data, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubernetes ca: %w", err)
+		}
+		ca = data
+	}
+	if len(ca) > 0 && !cfg.Insecure {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("failed to parse kubernetes ca certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	cert, key := cfg.CertData, cfg.KeyData
+	if len(cert) == 0 && cfg.CertFile != "" {
+		data, err := os.ReadFile(cfg.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubernetes client certificate: %w", err)
+		}
+		cert = data
+	}
+	if len(key) == 0 && cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubernetes client key: %w", err)
+		}
+		key = data
+	}
+	if len(cert) > 0 || len(key) > 0 {
+		if len(cert) == 0 || len(key) == 0 {
+			return nil, errors.New("kubernetes client certificate and key must be set together")
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubernetes client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	return tlsConfig, nil
+}
+
+// Namespace 返回配置中的默认命名空间
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// Host 返回API Server地址
+func (c *Client) Host() string {
+	return c.host + c.basePath
+}
+
+// bearerToken 返回当前令牌，令牌文件按间隔重新读取
+func (c *Client) bearerToken() (string, error) {
+	if c.tokenFile == "" {
+		return c.token, nil
+	}
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token != "" && time.Since(c.tokenLoadedAt) < tokenRefreshInterval {
+		return c.token, nil
+	}
+	data, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		// 读取失败时继续使用旧令牌，由API Server判断是否过期
+		if c.token != "" {
+			return c.token, nil
+		}
+		return "", fmt.Errorf("failed to read kubernetes token file: %w", err)
+	}
+	c.token = strings.TrimSpace(string(data))
+	c.tokenLoadedAt = time.Now()
+	return c.token, nil
+}
+
+// authorize 设置Bearer令牌或Basic认证头，客户端证书认证在TLS层完成
+func (c *Client) authorize(req *http.Request) error {
+	token, err := c.bearerToken()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return nil
+}
+
+// do 发送请求，out非nil时解析JSON响应
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, contentType string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	u := c.host + c.basePath + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode kubernetes request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes request %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return parseError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode kubernetes response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// get 发送GET请求
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, "", out)
+}
+
+// getRaw 读取非JSON响应体，用于/readyz等健康检查接口
+func (c *Client) getRaw(ctx context.Context, path string, query url.Values) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	u := c.host + c.basePath + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := c.authorize(req); err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("kubernetes request GET %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 400 {
+		return string(body), &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return string(body), nil
+}
+
+// parseError 解析metav1.Status错误响应
+func parseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var status struct {
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}
+	message := strings.TrimSpace(string(body))
+	reason := ""
+	if json.Unmarshal(body, &status) == nil && status.Message != "" {
+		message, reason = status.Message, status.Reason
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{StatusCode: resp.StatusCode, Reason: reason, Message: message}
+}
@@ -0,0 +1,281 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient 创建指向httptest服务的明文客户端
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(&Config{Host: srv.URL, BearerToken: "test-token", Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kind":    "Status",
+		"status":  "Failure",
+		"code":    code,
+		"reason":  reason,
+		"message": message,
+	})
+}
+
+func TestListPodsPaginationAndDecoding(t *testing.T) {
+	var queries []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/prod/pods" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" || r.Header.Get("Accept") != "application/json" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("continue") {
+		case "":
+			io.WriteString(w, `{"metadata":{"resourceVersion":"100","continue":"page2"},"items":[
+				{"metadata":{"name":"web-1","namespace":"prod","labels":{"app":"web"}},
+				 "spec":{"nodeName":"node-a","containers":[{"name":"web","image":"nginx:1.25","resources":{"limits":{"cpu":"500m","memory":"256Mi"}}}]},
+				 "status":{"phase":"Running","containerStatuses":[{"name":"web","ready":true,"restartCount":3,"state":{"running":{"startedAt":"2024-01-02T03:04:05Z"}}}]}}]}`)
+		case "page2":
+			io.WriteString(w, `{"metadata":{"resourceVersion":"100"},"items":[
+				{"metadata":{"name":"web-2","namespace":"prod"},
+				 "status":{"phase":"Pending","containerStatuses":[{"name":"web","state":{"waiting":{"reason":"CrashLoopBackOff","message":"back-off"}}}]}}]}`)
+		}
+	}))
+
+	pods, err := client.ListPods(context.Background(), "prod", ListOptions{LabelSelector: "app=web", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPods: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("expected 2 pods, got %d", len(pods))
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "labelSelector=app%3Dweb") || !strings.Contains(queries[0], "limit=1") || !strings.Contains(queries[1], "continue=page2") {
+		t.Fatalf("unexpected queries %v", queries)
+	}
+
+	web1 := pods[0]
+	if web1.Metadata.Labels["app"] != "web" || web1.Spec.NodeName != "node-a" || web1.Status.Phase != "Running" {
+		t.Fatalf("unexpected pod %+v", web1)
+	}
+	if got := web1.Spec.Containers[0].Resources.Limits.Quantity("memory"); got != 256*1024*1024 {
+		t.Fatalf("unexpected memory limit %v", got)
+	}
+	if got := web1.Spec.Containers[0].Resources.Limits.Quantity("cpu"); got != 0.5 {
+		t.Fatalf("unexpected cpu limit %v", got)
+	}
+	status := web1.Status.ContainerStatuses[0]
+	if !status.Ready || status.RestartCount != 3 || status.State.Running == nil {
+		t.Fatalf("unexpected container status %+v", status)
+	}
+	waiting := pods[1].Status.ContainerStatuses[0].State.Waiting
+	if waiting == nil || waiting.Reason != "CrashLoopBackOff" {
+		t.Fatalf("unexpected waiting state %+v", waiting)
+	}
+}
+
+func TestListReturnsEmptySlice(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"metadata":{},"items":[]}`)
+	}))
+	nodes, err := client.ListNodes(context.Background(), ListOptions{})
+	if err != nil {
+		t.Fatalf("ListNodes: %v", err)
+	}
+	if nodes == nil || len(nodes) != 0 {
+		t.Fatalf("expected empty non-nil slice, got %#v", nodes)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/prod/pods/missing":
+			writeStatus(w, http.StatusNotFound, "NotFound", `pods "missing" not found`)
+		case "/api/v1/nodes/secret":
+			writeStatus(w, http.StatusForbidden, "Forbidden", `nodes "secret" is forbidden`)
+		case "/api/v1/nodes/plain":
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "upstream unavailable")
+		case "/api/v1/nodes/empty":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/api/v1/nodes/garbage":
+			io.WriteString(w, "{not json")
+		}
+	}))
+	ctx := context.Background()
+
+	_, err := client.GetPod(ctx, "prod", "missing")
+	if !IsNotFound(err) || IsForbidden(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	apiErr := err.(*Error)
+	if apiErr.Reason != "NotFound" || apiErr.Message != `pods "missing" not found` {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+	if !strings.Contains(err.Error(), "status 404, NotFound") {
+		t.Fatalf("unexpected error text %q", err.Error())
+	}
+
+	if _, err := client.GetNode(ctx, "secret"); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+
+	// 非Status响应体原样作为错误信息
+	_, err = client.GetNode(ctx, "plain")
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusBadGateway || apiErr.Message != "upstream unavailable" || apiErr.Reason != "" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	_, err = client.GetNode(ctx, "empty")
+	if apiErr, ok := err.(*Error); !ok || apiErr.Message != http.StatusText(http.StatusServiceUnavailable) {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	_, err = client.GetNode(ctx, "garbage")
+	if err == nil || !strings.Contains(err.Error(), "failed to decode kubernetes response") {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+}
+
+func TestRequestTimeoutAndConnectionError(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := NewClient(&Config{Host: srv.URL, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	start := time.Now()
+	if _, err := client.ServerVersion(context.Background()); err == nil || !strings.Contains(err.Error(), "GET /version failed") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("request did not honour timeout, took %s", elapsed)
+	}
+
+	client, err = NewClient(&Config{Host: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.ServerVersion(context.Background()); err == nil {
+		t.Fatal("expected connection error")
+	}
+}
+
+func TestBasicAuthAndBasePath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/k8s/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"major":"1","minor":"27","gitVersion":"v1.27.4","platform":"linux/amd64"}`)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{Host: srv.URL + "/k8s/", Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if client.Host() != srv.URL+"/k8s" || client.Namespace() != "default" {
+		t.Fatalf("unexpected client host %q namespace %q", client.Host(), client.Namespace())
+	}
+	version, err := client.ServerVersion(context.Background())
+	if err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+	if version.Platform != "linux/amd64" {
+		t.Fatalf("unexpected version %+v", version)
+	}
+}
+
+func TestScaleDeploymentSendsMergePatch(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/apis/apps/v1/namespaces/prod/deployments/api/scale" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != MergePatchType {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		var patch struct {
+			Spec struct {
+				Replicas int32 `json:"replicas"`
+			} `json:"spec"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		fmt.Fprintf(w, `{"metadata":{"name":"api","namespace":"prod"},"spec":{"replicas":%d},"status":{"replicas":2}}`, patch.Spec.Replicas)
+	}))
+
+	scale, err := client.ScaleDeployment(context.Background(), "prod", "api", 5)
+	if err != nil {
+		t.Fatalf("ScaleDeployment: %v", err)
+	}
+	if scale.Spec.Replicas != 5 || scale.Status.Replicas != 2 {
+		t.Fatalf("unexpected scale %+v", scale)
+	}
+}
+
+func TestCountResourcesAndReadyz(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apis/apps/v1/deployments":
+			io.WriteString(w, `{"metadata":{"continue":"x","remainingItemCount":41},"items":[{}]}`)
+		case "/apis/batch/v1/jobs":
+			// 未返回remainingItemCount时退化为完整计数
+			if r.URL.Query().Get("limit") == "1" {
+				io.WriteString(w, `{"metadata":{"continue":"x"},"items":[{}]}`)
+				return
+			}
+			io.WriteString(w, `{"metadata":{},"items":[{},{},{}]}`)
+		case "/readyz/etcd":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, "[-]etcd failed: reason withheld\n")
+		case "/readyz":
+			io.WriteString(w, "ok")
+		}
+	}))
+	ctx := context.Background()
+
+	if count, err := client.CountResources(ctx, "/apis/apps/v1/deployments"); err != nil || count != 42 {
+		t.Fatalf("CountResources deployments = %d, %v", count, err)
+	}
+	if count, err := client.CountResources(ctx, "/apis/batch/v1/jobs"); err != nil || count != 3 {
+		t.Fatalf("CountResources jobs = %d, %v", count, err)
+	}
+
+	if err := client.Readyz(ctx, ""); err != nil {
+		t.Fatalf("Readyz: %v", err)
+	}
+	err := client.Readyz(ctx, "etcd")
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusInternalServerError || !strings.Contains(apiErr.Message, "etcd failed") {
+		t.Fatalf("unexpected readyz error %#v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ServiceAccountDir 集群内Pod挂载的ServiceAccount凭据目录
+	ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// serviceAccountDir 读取ServiceAccount凭据的目录，测试时可替换
+var serviceAccountDir = ServiceAccountDir
+
+// ErrNotInCluster 当前进程不在Kubernetes集群内运行
+var ErrNotInCluster = errors.New("not running inside a kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+
+// Config 客户端连接配置，可由kubeconfig或集群内ServiceAccount生成
+type Config struct {
+	// Host API Server地址，如 https://10.0.0.1:6443
+	Host string
+	// BearerToken 静态令牌，BearerTokenFile非空时以文件内容为准（支持令牌轮换）
+	BearerToken     string
+	BearerTokenFile string
+	Username        string
+	Password        string
+	// CAData/CertData/KeyData PEM编码的证书，优先于对应的文件路径
+	CAData   []byte
+	CAFile   string
+	CertData []byte
+	CertFile string
+	KeyData  []byte
+	KeyFile  string
+	// Insecure 跳过服务端证书校验
+	Insecure   bool
+	ServerName string
+	// Timeout 单次请求超时时间
+	Timeout time.Duration
+	// Namespace 上下文或ServiceAccount的默认命名空间
+	Namespace string
+	// ContextName 生成配置所用的kubeconfig上下文
+	ContextName string
+}
+
+// Kubeconfig kubeconfig文件结构
+type Kubeconfig struct {
+	CurrentContext string         `yaml:"current-context"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Users          []NamedUser    `yaml:"users"`
+	Contexts       []NamedContext `yaml:"contexts"`
+
+	// dir kubeconfig所在目录，用于解析相对证书路径
+	dir string
+}
+
+// NamedCluster kubeconfig中的集群条目
+type NamedCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		TLSServerName            string `yaml:"tls-server-name"`
+	} `yaml:"cluster"`
+}
+
+// NamedUser kubeconfig中的用户凭据条目
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		ClientCertificate     string `yaml:"client-certificate"`
+		ClientCertificateData string `yaml:"client-certificate-data"`
+		ClientKey             string `yaml:"client-key"`
+		ClientKeyData         string `yaml:"client-key-data"`
+		Token                 string `yaml:"token"`
+		TokenFile             string `yaml:"tokenFile"`
+		Username              string `yaml:"username"`
+		Password              string `yaml:"password"`
+		Exec                  *struct {
+			Command string `yaml:"command"`
+		} `yaml:"exec"`
+		AuthProvider *struct {
+			Name string `yaml:"name"`
+		} `yaml:"auth-provider"`
+	} `yaml:"user"`
+}
+
+// NamedContext kubeconfig中的上下文条目
+type NamedContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		User      string `yaml:"user"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"context"`
+}
+
+// DefaultKubeconfigPath 返回KUBECONFIG中的第一个文件，未设置时为~/.kube/config
+func DefaultKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		for _, path := range filepath.SplitList(env) {
+			if path != "" {
+				return path
+			}
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// LoadKubeconfig 读取并解析kubeconfig文件
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	kc, err := ParseKubeconfig(data)
+	if err != nil {
+		return nil, err
+	}
+	kc.dir = filepath.Dir(path)
+	return kc, nil
+}
+
+// ParseKubeconfig 解析kubeconfig内容，相对证书路径按当前工作目录解析
+func ParseKubeconfig(data []byte) (*Kubeconfig, error) {
+	var kc Kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	return &kc, nil
+}
+
+// ContextNames 返回kubeconfig中定义的全部上下文名称
+func (k *Kubeconfig) ContextNames() []string {
+	names := make([]string, 0, len(k.Contexts))
+	for _, c := range k.Contexts {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// ClientConfig 根据上下文生成客户端配置，contextName为空时使用current-context
+func (k *Kubeconfig) ClientConfig(contextName string) (*Config, error) {
+	if contextName == "" {
+		contextName = k.CurrentContext
+	}
+	if contextName == "" {
+		if len(k.Contexts) != 1 {
+			return nil, errors.New("kubeconfig has no current-context and no context was specified")
+		}
+		contextName = k.Contexts[0].Name
+	}
+
+	var ctx *NamedContext
+	for i := range k.Contexts {
+		if k.Contexts[i].Name == contextName {
+			ctx = &k.Contexts[i]
+			break
+		}
+	}
+	if ctx == nil {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	var cluster *NamedCluster
+	for i := range k.Clusters {
+		if k.Clusters[i].Name == ctx.Context.Cluster {
+			cluster = &k.Clusters[i]
+			break
+		}
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %q referenced by context %q not found in kubeconfig", ctx.Context.Cluster, contextName)
+	}
+	if cluster.Cluster.Server == "" {
+		return nil, fmt.Errorf("cluster %q has no server address", cluster.Name)
+	}
+
+	cfg := &Config{
+		Host:        cluster.Cluster.Server,
+		Insecure:    cluster.Cluster.InsecureSkipTLSVerify,
+		ServerName:  cluster.Cluster.TLSServerName,
+		CAFile:      k.resolvePath(cluster.Cluster.CertificateAuthority),
+		Namespace:   ctx.Context.Namespace,
+		ContextName: contextName,
+	}
+	var err error
+	if cfg.CAData, err = decodeData(cluster.Cluster.CertificateAuthorityData, "certificate-authority-data"); err != nil {
+		return nil, err
+	}
+
+	if ctx.Context.User != "" {
+		var user *NamedUser
+		for i := range k.Users {
+			if k.Users[i].Name == ctx.Context.User {
+				user = &k.Users[i]
+				break
+			}
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %q referenced by context %q not found in kubeconfig", ctx.Context.User, contextName)
+		}
+		// exec插件与auth-provider需要执行外部程序，出于安全考虑不支持
+		if user.User.Exec != nil {
+			return nil, fmt.Errorf("user %q uses an exec credential plugin (%s), which is not supported; use a token or client certificate", user.Name, user.User.Exec.Command)
+		}
+		if user.User.AuthProvider != nil {
+			return nil, fmt.Errorf("user %q uses auth-provider %q, which is not supported; use a token or client certificate", user.Name, user.User.AuthProvider.Name)
+		}
+
+		cfg.BearerToken = user.User.Token
+		cfg.BearerTokenFile = k.resolvePath(user.User.TokenFile)
+		cfg.Username = user.User.Username
+		cfg.Password = user.User.Password
+		cfg.CertFile = k.resolvePath(user.User.ClientCertificate)
+		cfg.KeyFile = k.resolvePath(user.User.ClientKey)
+		if cfg.CertData, err = decodeData(user.User.ClientCertificateData, "client-certificate-data"); err != nil {
+			return nil, err
+		}
+		if cfg.KeyData, err = decodeData(user.User.ClientKeyData, "client-key-data"); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = "default"
+	}
+	return cfg, nil
+}
+
+// resolvePath 证书路径相对于kubeconfig所在目录
+func (k *Kubeconfig) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || k.dir == "" {
+		return path
+	}
+	return filepath.Join(k.dir, path)
+}
+
+// InClusterConfig 使用Pod挂载的ServiceAccount凭据访问所在集群
+func InClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	tokenFile := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(tokenFile); err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	cfg := &Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		BearerTokenFile: tokenFile,
+		CAFile:          filepath.Join(serviceAccountDir, "ca.crt"),
+		Namespace:       "default",
+	}
+	if ns, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		if trimmed := strings.TrimSpace(string(ns)); trimmed != "" {
+			cfg.Namespace = trimmed
+		}
+	}
+	return cfg, nil
+}
+
+// IsInCluster 判断当前进程是否运行在Kubernetes Pod中
+func IsInCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
+
+// decodeData 解码kubeconfig中base64编码的*-data字段
+func decodeData(value, field string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in kubeconfig: %w", field, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,402 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverCAPEM 返回httptest TLS服务端证书的PEM编码，作为kubeconfig中的CA
+func serverCAPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+// newClientCert 生成自签名客户端证书与私钥（PEM编码）
+func newClientCert(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func b64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestKubeconfigTokenAuth(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer kc-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(VersionInfo{Major: "1", Minor: "29", GitVersion: "v1.29.2"})
+	}))
+	defer srv.Close()
+
+	kubeconfig := `
+current-context: prod
+clusters:
+- name: prod-cluster
+  cluster:
+    server: ` + srv.URL + `
+    certificate-authority-data: ` + b64(serverCAPEM(srv)) + `
+users:
+- name: admin
+  user:
+    token: kc-token
+contexts:
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: admin
+    namespace: monitoring
+- name: other
+  context:
+    cluster: prod-cluster
+`
+	kc, err := ParseKubeconfig([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("ParseKubeconfig: %v", err)
+	}
+	if names := kc.ContextNames(); len(names) != 2 || names[0] != "prod" || names[1] != "other" {
+		t.Fatalf("unexpected contexts %v", names)
+	}
+
+	cfg, err := kc.ClientConfig("")
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	if cfg.ContextName != "prod" || cfg.Namespace != "monitoring" || cfg.BearerToken != "kc-token" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	version, err := client.ServerVersion(context.Background())
+	if err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+	if version.GitVersion != "v1.29.2" {
+		t.Fatalf("unexpected version %+v", version)
+	}
+
+	// 未指定用户的上下文不带凭据，命名空间回退为default
+	other, err := kc.ClientConfig("other")
+	if err != nil {
+		t.Fatalf("ClientConfig(other): %v", err)
+	}
+	if other.Namespace != "default" || other.BearerToken != "" {
+		t.Fatalf("unexpected config %+v", other)
+	}
+	client, err = NewClient(other)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.ServerVersion(context.Background()); err == nil {
+		t.Fatal("expected unauthorized error without token")
+	}
+}
+
+func TestKubeconfigClientCertificateFiles(t *testing.T) {
+	certPEM, keyPEM := newClientCert(t, "monitor")
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "monitor" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(VersionInfo{GitVersion: "v1.30.0"})
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// 相对路径按kubeconfig所在目录解析
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"ca.crt":     serverCAPEM(srv),
+		"client.crt": certPEM,
+		"client.key": keyPEM,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kubeconfig := `
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: ` + srv.URL + `
+    certificate-authority: ca.crt
+users:
+- name: dev
+  user:
+    client-certificate: client.crt
+    client-key: client.key
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+`
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	kc, err := LoadKubeconfig(path)
+	if err != nil {
+		t.Fatalf("LoadKubeconfig: %v", err)
+	}
+	cfg, err := kc.ClientConfig("")
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	if cfg.CAFile != filepath.Join(dir, "ca.crt") || cfg.CertFile != filepath.Join(dir, "client.crt") {
+		t.Fatalf("relative paths not resolved: %+v", cfg)
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	version, err := client.ServerVersion(context.Background())
+	if err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+	if version.GitVersion != "v1.30.0" {
+		t.Fatalf("unexpected version %+v", version)
+	}
+}
+
+func TestKubeconfigErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeconfig string
+		context    string
+		want       string
+	}{
+		{
+			name:       "no current context",
+			kubeconfig: "contexts:\n- name: a\n- name: b\n",
+			want:       "no current-context",
+		},
+		{
+			name:       "unknown context",
+			kubeconfig: "current-context: a\ncontexts:\n- name: a\n",
+			context:    "missing",
+			want:       `context "missing" not found`,
+		},
+		{
+			name:       "missing cluster",
+			kubeconfig: "current-context: a\ncontexts:\n- name: a\n  context:\n    cluster: c\n",
+			want:       `cluster "c" referenced by context "a" not found`,
+		},
+		{
+			name:       "cluster without server",
+			kubeconfig: "current-context: a\nclusters:\n- name: c\ncontexts:\n- name: a\n  context:\n    cluster: c\n",
+			want:       "has no server address",
+		},
+		{
+			name:       "missing user",
+			kubeconfig: "current-context: a\nclusters:\n- name: c\n  cluster:\n    server: https://k8s\ncontexts:\n- name: a\n  context:\n    cluster: c\n    user: u\n",
+			want:       `user "u" referenced by context "a" not found`,
+		},
+		{
+			name:       "exec plugin",
+			kubeconfig: "current-context: a\nclusters:\n- name: c\n  cluster:\n    server: https://k8s\nusers:\n- name: u\n  user:\n    exec:\n      command: aws\ncontexts:\n- name: a\n  context:\n    cluster: c\n    user: u\n",
+			want:       "exec credential plugin (aws)",
+		},
+		{
+			name:       "auth provider",
+			kubeconfig: "current-context: a\nclusters:\n- name: c\n  cluster:\n    server: https://k8s\nusers:\n- name: u\n  user:\n    auth-provider:\n      name: gcp\ncontexts:\n- name: a\n  context:\n    cluster: c\n    user: u\n",
+			want:       `auth-provider "gcp"`,
+		},
+		{
+			name:       "invalid ca data",
+			kubeconfig: "current-context: a\nclusters:\n- name: c\n  cluster:\n    server: https://k8s\n    certificate-authority-data: '%%%'\ncontexts:\n- name: a\n  context:\n    cluster: c\n",
+			want:       "invalid certificate-authority-data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc, err := ParseKubeconfig([]byte(tt.kubeconfig))
+			if err != nil {
+				t.Fatalf("ParseKubeconfig: %v", err)
+			}
+			_, err = kc.ClientConfig(tt.context)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ParseKubeconfig([]byte("clusters: [")); err == nil {
+		t.Fatal("expected parse error for malformed yaml")
+	}
+	if _, err := LoadKubeconfig(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected error for missing kubeconfig file")
+	}
+}
+
+func TestNewClientTLSErrors(t *testing.T) {
+	certPEM, keyPEM := newClientCert(t, "monitor")
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{name: "nil config", cfg: nil, want: "host is required"},
+		{name: "bad scheme", cfg: &Config{Host: "ftp://k8s"}, want: "unsupported kubernetes api server scheme"},
+		{name: "bad ca", cfg: &Config{Host: "https://k8s", CAData: []byte("not a pem")}, want: "failed to parse kubernetes ca certificate"},
+		{name: "missing ca file", cfg: &Config{Host: "https://k8s", CAFile: "/nonexistent/ca.crt"}, want: "failed to read kubernetes ca"},
+		{name: "cert without key", cfg: &Config{Host: "https://k8s", CertData: certPEM}, want: "must be set together"},
+		{name: "mismatched key", cfg: &Config{Host: "https://k8s", CertData: certPEM, KeyData: []byte("bad")}, want: "failed to load kubernetes client certificate"},
+		{name: "missing token file", cfg: &Config{Host: "https://k8s", BearerTokenFile: "/nonexistent/token"}, want: "failed to read kubernetes token file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// 证书与私钥同时提供时可正常创建
+	if _, err := NewClient(&Config{Host: "https://k8s", CertData: certPEM, KeyData: keyPEM}); err != nil {
+		t.Fatalf("NewClient with client certificate: %v", err)
+	}
+}
+
+func TestInClusterConfig(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	if IsInCluster() {
+		t.Fatal("IsInCluster should be false without service env")
+	}
+	if _, err := InClusterConfig(); !errors.Is(err, ErrNotInCluster) {
+		t.Fatalf("expected ErrNotInCluster, got %v", err)
+	}
+
+	var gotAuth []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(VersionInfo{GitVersion: "v1.28.0"})
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	orig := serviceAccountDir
+	serviceAccountDir = dir
+	defer func() { serviceAccountDir = orig }()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	idx := strings.LastIndex(host, ":")
+	t.Setenv("KUBERNETES_SERVICE_HOST", host[:idx])
+	t.Setenv("KUBERNETES_SERVICE_PORT", host[idx+1:])
+
+	// 令牌文件缺失时报错
+	if _, err := InClusterConfig(); err == nil || !strings.Contains(err.Error(), "service account token") {
+		t.Fatalf("expected token error, got %v", err)
+	}
+
+	writeFile := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("token", "sa-token-1\n")
+	writeFile("ca.crt", string(serverCAPEM(srv)))
+	writeFile("namespace", "observability\n")
+
+	cfg, err := InClusterConfig()
+	if err != nil {
+		t.Fatalf("InClusterConfig: %v", err)
+	}
+	if cfg.Host != srv.URL || cfg.Namespace != "observability" || cfg.BearerTokenFile != filepath.Join(dir, "token") {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if !IsInCluster() {
+		t.Fatal("IsInCluster should be true with service env")
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if client.Namespace() != "observability" {
+		t.Fatalf("unexpected namespace %q", client.Namespace())
+	}
+	if _, err := client.ServerVersion(context.Background()); err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+
+	// 令牌轮换后超过刷新间隔重新读取文件
+	writeFile("token", "sa-token-2")
+	client.tokenMu.Lock()
+	client.tokenLoadedAt = time.Now().Add(-2 * tokenRefreshInterval)
+	client.tokenMu.Unlock()
+	if _, err := client.ServerVersion(context.Background()); err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+
+	// 令牌文件被删除时继续使用已缓存的令牌
+	os.Remove(filepath.Join(dir, "token"))
+	client.tokenMu.Lock()
+	client.tokenLoadedAt = time.Now().Add(-2 * tokenRefreshInterval)
+	client.tokenMu.Unlock()
+	if _, err := client.ServerVersion(context.Background()); err != nil {
+		t.Fatalf("ServerVersion: %v", err)
+	}
+
+	want := []string{"Bearer sa-token-1", "Bearer sa-token-2", "Bearer sa-token-2"}
+	if strings.Join(gotAuth, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected authorization headers %v", gotAuth)
+	}
+}
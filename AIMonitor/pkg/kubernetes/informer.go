@@ -0,0 +1,252 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 资源集合路径，供Informer使用
+const (
+	PodsPath         = "/api/v1/pods"
+	NodesPath        = "/api/v1/nodes"
+	EventsPath       = "/api/v1/events"
+	DeploymentsPath  = "/apis/apps/v1/deployments"
+	StatefulSetsPath = "/apis/apps/v1/statefulsets"
+	DaemonSetsPath   = "/apis/apps/v1/daemonsets"
+	JobsPath         = "/apis/batch/v1/jobs"
+)
+
+const (
+	// informerPageSize list时的分页大小
+	informerPageSize = 500
+	// informerMinWatchTimeout 单次watch的最短时长，实际取值在[min, 2*min)间随机，避免多个watch同时重连
+	informerMinWatchTimeout = 5 * time.Minute
+)
+
+// InformerStatus Informer同步状态
+type InformerStatus struct {
+	Synced          bool      `json:"synced"`
+	Items           int       `json:"items"`
+	ResourceVersion string    `json:"resource_version"`
+	LastSyncTime    time.Time `json:"last_sync_time"`
+	LastEventTime   time.Time `json:"last_event_time"`
+	LastError       string    `json:"last_error,omitempty"`
+}
+
+// Informer 通过list+watch在内存中维护一类资源的副本
+// 首次list完成前HasSynced返回false；watch断开后从最新resourceVersion继续，版本过期时重新list
+type Informer[T any] struct {
+	client *Client
+	path   string
+	opts   ListOptions
+
+	mu              sync.RWMutex
+	items           map[string]*T
+	synced          bool
+	resourceVersion string
+	lastSyncTime    time.Time
+	lastEventTime   time.Time
+	lastError       string
+}
+
+// NewInformer 创建Informer，path为全部命名空间的集合路径，如 PodsPath
+func NewInformer[T any](client *Client, path string, opts ListOptions) *Informer[T] {
+	return &Informer[T]{
+		client: client,
+		path:   path,
+		opts:   opts,
+		items:  make(map[string]*T),
+	}
+}
+
+// Run 持续list+watch直到ctx取消，出错时按backoff间隔重试
+func (i *Informer[T]) Run(ctx context.Context, backoff time.Duration) {
+	if backoff <= 0 {
+		backoff = 5 * time.Second
+	}
+	for {
+		err := i.listAndWatch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			i.mu.Lock()
+			i.lastError = err.Error()
+			i.mu.Unlock()
+		}
+		// resourceVersion过期时立即重新list
+		if IsGone(err) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// HasSynced 首次list是否已完成
+func (i *Informer[T]) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.synced
+}
+
+// Status 返回同步状态
+func (i *Informer[T]) Status() InformerStatus {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return InformerStatus{
+		Synced:          i.synced,
+		Items:           len(i.items),
+		ResourceVersion: i.resourceVersion,
+		LastSyncTime:    i.lastSyncTime,
+		LastEventTime:   i.lastEventTime,
+		LastError:       i.lastError,
+	}
+}
+
+// List 返回缓存中的对象，namespace为空时返回全部，结果按 命名空间/名称 排序
+// 返回的对象与缓存共享内部的map和切片，调用方不得修改
+func (i *Informer[T]) List(namespace string) []T {
+	i.mu.RLock()
+	keys := make([]string, 0, len(i.items))
+	for key := range i.items {
+		if namespace == "" || strings.HasPrefix(key, namespace+"/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	result := make([]T, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *i.items[key])
+	}
+	i.mu.RUnlock()
+	return result
+}
+
+// Get 按命名空间和名称获取对象，集群范围资源namespace为空
+func (i *Informer[T]) Get(namespace, name string) (T, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	obj, ok := i.items[namespace+"/"+name]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return *obj, true
+}
+
+// listAndWatch 全量list替换缓存，随后从list的resourceVersion开始watch
+func (i *Informer[T]) listAndWatch(ctx context.Context) error {
+	opts := i.opts
+	opts.Limit = informerPageSize
+	raws, resourceVersion, err := i.client.ListRaw(ctx, i.path, opts)
+	if err != nil {
+		return err
+	}
+	if err := i.replace(raws, resourceVersion); err != nil {
+		return err
+	}
+
+	for {
+		i.mu.RLock()
+		resourceVersion = i.resourceVersion
+		i.mu.RUnlock()
+
+		timeout := informerMinWatchTimeout + time.Duration(rand.Int63n(int64(informerMinWatchTimeout)))
+		err := i.client.Watch(ctx, i.path, WatchOptions{
+			LabelSelector:   i.opts.LabelSelector,
+			FieldSelector:   i.opts.FieldSelector,
+			ResourceVersion: resourceVersion,
+			TimeoutSeconds:  int64(timeout / time.Second),
+		}, i.apply)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// replace 用list结果替换缓存
+func (i *Informer[T]) replace(raws []json.RawMessage, resourceVersion string) error {
+	items := make(map[string]*T, len(raws))
+	for _, raw := range raws {
+		key, _, obj, err := decodeObject[T](raw)
+		if err != nil {
+			return err
+		}
+		items[key] = obj
+	}
+
+	i.mu.Lock()
+	i.items = items
+	i.resourceVersion = resourceVersion
+	i.synced = true
+	i.lastSyncTime = time.Now()
+	i.lastError = ""
+	i.mu.Unlock()
+	return nil
+}
+
+// apply 处理单个watch事件
+func (i *Informer[T]) apply(event *WatchEvent) error {
+	if event.Type == "BOOKMARK" {
+		var meta struct {
+			Metadata ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(event.Object, &meta); err == nil && meta.Metadata.ResourceVersion != "" {
+			i.mu.Lock()
+			i.resourceVersion = meta.Metadata.ResourceVersion
+			i.mu.Unlock()
+		}
+		return nil
+	}
+
+	key, version, obj, err := decodeObject[T](event.Object)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	switch event.Type {
+	case "ADDED", "MODIFIED":
+		i.items[key] = obj
+	case "DELETED":
+		delete(i.items, key)
+	default:
+		i.mu.Unlock()
+		return nil
+	}
+	if version != "" {
+		i.resourceVersion = version
+	}
+	i.lastEventTime = time.Now()
+	i.mu.Unlock()
+	return nil
+}
+
+// decodeObject 解码对象并返回 命名空间/名称 形式的键和resourceVersion
+func decodeObject[T any](raw json.RawMessage) (string, string, *T, error) {
+	var meta struct {
+		Metadata ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return "", "", nil, fmt.Errorf("failed to decode kubernetes object metadata: %w", err)
+	}
+	obj := new(T)
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return "", "", nil, fmt.Errorf("failed to decode kubernetes object %s/%s: %w", meta.Metadata.Namespace, meta.Metadata.Name, err)
+	}
+	return meta.Metadata.Namespace + "/" + meta.Metadata.Name, meta.Metadata.ResourceVersion, obj, nil
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// quantitySuffixes Kubernetes数量后缀对应的倍数，二进制后缀需先于十进制后缀匹配
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"n", 1e-9},
+	{"u", 1e-6},
+	{"m", 1e-3},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// ParseQuantity 解析Kubernetes资源数量为浮点数，CPU单位为核，内存单位为字节
+// 支持 500m、1.5、250000n、128Mi、1G、1e3 等格式
+func ParseQuantity(value string) (float64, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	for _, q := range quantitySuffixes {
+		if strings.HasSuffix(s, q.suffix) {
+			s = strings.TrimSuffix(s, q.suffix)
+			multiplier = q.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", value)
+	}
+	return number * multiplier, nil
+}
+
+// Quantity 解析资源列表中的某一项，缺失或无法解析时返回0
+func (r ResourceList) Quantity(name string) float64 {
+	v, err := ParseQuantity(r[name])
+	if err != nil {
+		return 0
+	}
+	return v
+}
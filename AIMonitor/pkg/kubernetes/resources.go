@@ -0,0 +1,236 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// MergePatchType JSON Merge Patch内容类型
+const MergePatchType = "application/merge-patch+json"
+
+// ListPods 获取Pod列表，namespace为空时返回全部命名空间
+func (c *Client) ListPods(ctx context.Context, namespace string, opts ListOptions) ([]Pod, error) {
+	return listAll(ctx, c, namespacedPath("/api/v1", namespace, "pods"), opts, func(l *PodList) ([]Pod, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// GetPod 获取Pod
+func (c *Client) GetPod(ctx context.Context, namespace, name string) (*Pod, error) {
+	var pod Pod
+	if err := c.get(ctx, namespacedPath("/api/v1", namespace, "pods")+"/"+url.PathEscape(name), nil, &pod); err != nil {
+		return nil, err
+	}
+	return &pod, nil
+}
+
+// ListNodes 获取节点列表
+func (c *Client) ListNodes(ctx context.Context, opts ListOptions) ([]Node, error) {
+	return listAll(ctx, c, "/api/v1/nodes", opts, func(l *NodeList) ([]Node, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// GetNode 获取节点
+func (c *Client) GetNode(ctx context.Context, name string) (*Node, error) {
+	var node Node
+	if err := c.get(ctx, "/api/v1/nodes/"+url.PathEscape(name), nil, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// ListNamespaces 获取命名空间列表
+func (c *Client) ListNamespaces(ctx context.Context, opts ListOptions) ([]Namespace, error) {
+	return listAll(ctx, c, "/api/v1/namespaces", opts, func(l *NamespaceList) ([]Namespace, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// ListEvents 获取事件列表，可通过FieldSelector按involvedObject过滤
+func (c *Client) ListEvents(ctx context.Context, namespace string, opts ListOptions) ([]Event, error) {
+	return listAll(ctx, c, namespacedPath("/api/v1", namespace, "events"), opts, func(l *EventList) ([]Event, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// CountResources 统计资源数量，只取一条记录并利用remainingItemCount计算总数
+// path为不带命名空间的集合路径，如 /apis/apps/v1/deployments
+func (c *Client) CountResources(ctx context.Context, path string) (int, error) {
+	var list struct {
+		Metadata ListMeta          `json:"metadata"`
+		Items    []json.RawMessage `json:"items"`
+	}
+	query := url.Values{}
+	query.Set("limit", "1")
+	if err := c.get(ctx, path, query, &list); err != nil {
+		return 0, err
+	}
+	count := len(list.Items)
+	if list.Metadata.RemainingItemCount != nil {
+		count += int(*list.Metadata.RemainingItemCount)
+	} else if list.Metadata.Continue != "" {
+		// 带选择器的分页查询不返回剩余数量，退化为完整计数
+		query.Del("limit")
+		if err := c.get(ctx, path, query, &list); err != nil {
+			return 0, err
+		}
+		count = len(list.Items)
+	}
+	return count, nil
+}
+
+// GetDeploymentScale 获取Deployment的scale子资源
+func (c *Client) GetDeploymentScale(ctx context.Context, namespace, name string) (*Scale, error) {
+	var scale Scale
+	if err := c.get(ctx, deploymentScalePath(namespace, name), nil, &scale); err != nil {
+		return nil, err
+	}
+	return &scale, nil
+}
+
+// ScaleDeployment 通过scale子资源调整Deployment副本数
+func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) (*Scale, error) {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	}
+	var scale Scale
+	if err := c.do(ctx, http.MethodPatch, deploymentScalePath(namespace, name), nil, patch, MergePatchType, &scale); err != nil {
+		return nil, err
+	}
+	return &scale, nil
+}
+
+// GetLease 获取租约，kube-scheduler与kube-controller-manager的选主租约位于kube-system
+func (c *Client) GetLease(ctx context.Context, namespace, name string) (*Lease, error) {
+	var lease Lease
+	if err := c.get(ctx, namespacedPath("/apis/coordination.k8s.io/v1", namespace, "leases")+"/"+url.PathEscape(name), nil, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// GetPodMetrics 从metrics-server获取单个Pod的使用量
+func (c *Client) GetPodMetrics(ctx context.Context, namespace, name string) (*PodMetrics, error) {
+	var metrics PodMetrics
+	if err := c.get(ctx, namespacedPath("/apis/metrics.k8s.io/v1beta1", namespace, "pods")+"/"+url.PathEscape(name), nil, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+// ListPodMetrics 从metrics-server获取Pod使用量，namespace为空时返回全部命名空间
+func (c *Client) ListPodMetrics(ctx context.Context, namespace string, opts ListOptions) ([]PodMetrics, error) {
+	return listAll(ctx, c, namespacedPath("/apis/metrics.k8s.io/v1beta1", namespace, "pods"), opts, func(l *PodMetricsList) ([]PodMetrics, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// GetNodeMetrics 从metrics-server获取单个节点的使用量
+func (c *Client) GetNodeMetrics(ctx context.Context, name string) (*NodeMetrics, error) {
+	var metrics NodeMetrics
+	if err := c.get(ctx, "/apis/metrics.k8s.io/v1beta1/nodes/"+url.PathEscape(name), nil, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+// ListNodeMetrics 从metrics-server获取全部节点使用量
+func (c *Client) ListNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
+	return listAll(ctx, c, "/apis/metrics.k8s.io/v1beta1/nodes", ListOptions{}, func(l *NodeMetricsList) ([]NodeMetrics, ListMeta) {
+		return l.Items, l.Metadata
+	})
+}
+
+// ServerVersion 获取API Server版本
+func (c *Client) ServerVersion(ctx context.Context) (*VersionInfo, error) {
+	var version VersionInfo
+	if err := c.get(ctx, "/version", nil, &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Readyz 调用/readyz健康检查，check非空时只检查单项，如 etcd
+func (c *Client) Readyz(ctx context.Context, check string) error {
+	path := "/readyz"
+	if check != "" {
+		path += "/" + check
+	}
+	_, err := c.getRaw(ctx, path, nil)
+	return err
+}
+
+// ComponentStatuses 获取控制平面组件健康状态（已废弃的v1 API，新版本集群可能返回空）
+func (c *Client) ComponentStatuses(ctx context.Context) (map[string]bool, error) {
+	var list struct {
+		Items []struct {
+			Metadata   ObjectMeta `json:"metadata"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"items"`
+	}
+	if err := c.get(ctx, "/api/v1/componentstatuses", nil, &list); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		healthy := false
+		for _, cond := range item.Conditions {
+			if cond.Type == "Healthy" {
+				healthy = cond.Status == "True"
+			}
+		}
+		result[item.Metadata.Name] = healthy
+	}
+	return result, nil
+}
+
+// listAll 按continue令牌逐页读取列表，opts.Limit作为单页大小
+func listAll[L any, T any](ctx context.Context, c *Client, path string, opts ListOptions, page func(*L) ([]T, ListMeta)) ([]T, error) {
+	query := url.Values{}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		query.Set("fieldSelector", opts.FieldSelector)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(opts.Limit, 10))
+	}
+
+	var result []T
+	for {
+		var list L
+		if err := c.get(ctx, path, query, &list); err != nil {
+			return nil, err
+		}
+		items, meta := page(&list)
+		result = append(result, items...)
+		if meta.Continue == "" || opts.Limit <= 0 {
+			break
+		}
+		query.Set("continue", meta.Continue)
+	}
+	if result == nil {
+		result = []T{}
+	}
+	return result, nil
+}
+
+// namespacedPath 拼接资源集合路径，namespace为空时为集群范围
+func namespacedPath(prefix, namespace, resource string) string {
+	if namespace == "" {
+		return prefix + "/" + resource
+	}
+	return prefix + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+}
+
+func deploymentScalePath(namespace, name string) string {
+	return namespacedPath("/apis/apps/v1", namespace, "deployments") + "/" + url.PathEscape(name) + "/scale"
+}
@@ -0,0 +1,484 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ObjectMeta 资源元数据
+type ObjectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace,omitempty"`
+	UID               string            `json:"uid,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	DeletionTimestamp *time.Time        `json:"deletionTimestamp,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	OwnerReferences   []OwnerReference  `json:"ownerReferences,omitempty"`
+}
+
+// OwnerReference 资源所属的控制器
+type OwnerReference struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+	Controller *bool  `json:"controller,omitempty"`
+}
+
+// ListMeta 列表元数据，remainingItemCount仅在分页时返回
+type ListMeta struct {
+	ResourceVersion    string `json:"resourceVersion,omitempty"`
+	Continue           string `json:"continue,omitempty"`
+	RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+}
+
+// ResourceList 资源名到数量的映射，如 {"cpu": "500m", "memory": "1Gi"}
+type ResourceList map[string]string
+
+// ResourceRequirements 容器资源请求与限制
+type ResourceRequirements struct {
+	Requests ResourceList `json:"requests,omitempty"`
+	Limits   ResourceList `json:"limits,omitempty"`
+}
+
+// ContainerPort 容器端口
+type ContainerPort struct {
+	Name          string `json:"name,omitempty"`
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"`
+	HostIP        string `json:"hostIP,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// EnvVar 环境变量，valueFrom引用的值不会被展开
+type EnvVar struct {
+	Name      string      `json:"name"`
+	Value     string      `json:"value,omitempty"`
+	ValueFrom interface{} `json:"valueFrom,omitempty"`
+}
+
+// VolumeMount 卷挂载
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+	SubPath   string `json:"subPath,omitempty"`
+}
+
+// Container Pod规格中的容器
+type Container struct {
+	Name         string               `json:"name"`
+	Image        string               `json:"image"`
+	Ports        []ContainerPort      `json:"ports,omitempty"`
+	Env          []EnvVar             `json:"env,omitempty"`
+	Resources    ResourceRequirements `json:"resources,omitempty"`
+	VolumeMounts []VolumeMount        `json:"volumeMounts,omitempty"`
+}
+
+// Volume Pod卷，仅解析常用卷源
+type Volume struct {
+	Name     string `json:"name"`
+	HostPath *struct {
+		Path string `json:"path"`
+		Type string `json:"type,omitempty"`
+	} `json:"hostPath,omitempty"`
+	EmptyDir *struct {
+		Medium    string `json:"medium,omitempty"`
+		SizeLimit string `json:"sizeLimit,omitempty"`
+	} `json:"emptyDir,omitempty"`
+	ConfigMap *struct {
+		Name        string `json:"name"`
+		DefaultMode *int32 `json:"defaultMode,omitempty"`
+	} `json:"configMap,omitempty"`
+	Secret *struct {
+		SecretName  string `json:"secretName"`
+		DefaultMode *int32 `json:"defaultMode,omitempty"`
+	} `json:"secret,omitempty"`
+	PersistentVolumeClaim *struct {
+		ClaimName string `json:"claimName"`
+		ReadOnly  bool   `json:"readOnly,omitempty"`
+	} `json:"persistentVolumeClaim,omitempty"`
+}
+
+// PodSpec Pod规格
+type PodSpec struct {
+	NodeName       string      `json:"nodeName,omitempty"`
+	InitContainers []Container `json:"initContainers,omitempty"`
+	Containers     []Container `json:"containers"`
+	Volumes        []Volume    `json:"volumes,omitempty"`
+}
+
+// ContainerStateWaiting 容器等待状态
+type ContainerStateWaiting struct {
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ContainerStateRunning 容器运行状态
+type ContainerStateRunning struct {
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// ContainerStateTerminated 容器终止状态
+type ContainerStateTerminated struct {
+	ExitCode    int32     `json:"exitCode"`
+	Signal      int32     `json:"signal,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+	ContainerID string    `json:"containerID,omitempty"`
+}
+
+// ContainerState 容器状态，三者只有一个非nil
+type ContainerState struct {
+	Waiting    *ContainerStateWaiting    `json:"waiting,omitempty"`
+	Running    *ContainerStateRunning    `json:"running,omitempty"`
+	Terminated *ContainerStateTerminated `json:"terminated,omitempty"`
+}
+
+// ContainerStatus 容器状态
+type ContainerStatus struct {
+	Name         string         `json:"name"`
+	State        ContainerState `json:"state"`
+	LastState    ContainerState `json:"lastTerminationState"`
+	Ready        bool           `json:"ready"`
+	RestartCount int32          `json:"restartCount"`
+	Image        string         `json:"image"`
+	ImageID      string         `json:"imageID"`
+	ContainerID  string         `json:"containerID,omitempty"`
+}
+
+// PodCondition Pod条件
+type PodCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	LastProbeTime      time.Time `json:"lastProbeTime"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+}
+
+// PodStatus Pod状态
+type PodStatus struct {
+	Phase                 string            `json:"phase"`
+	Reason                string            `json:"reason,omitempty"`
+	Message               string            `json:"message,omitempty"`
+	HostIP                string            `json:"hostIP,omitempty"`
+	PodIP                 string            `json:"podIP,omitempty"`
+	StartTime             *time.Time        `json:"startTime,omitempty"`
+	Conditions            []PodCondition    `json:"conditions,omitempty"`
+	InitContainerStatuses []ContainerStatus `json:"initContainerStatuses,omitempty"`
+	ContainerStatuses     []ContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+// Pod core/v1 Pod
+type Pod struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+	Status   PodStatus  `json:"status"`
+}
+
+// PodList Pod列表
+type PodList struct {
+	Metadata ListMeta `json:"metadata"`
+	Items    []Pod    `json:"items"`
+}
+
+// NodeCondition 节点条件
+type NodeCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	LastHeartbeatTime  time.Time `json:"lastHeartbeatTime"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+}
+
+// NodeAddress 节点地址
+type NodeAddress struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// NodeSystemInfo 节点系统信息
+type NodeSystemInfo struct {
+	MachineID               string `json:"machineID"`
+	SystemUUID              string `json:"systemUUID"`
+	BootID                  string `json:"bootID"`
+	KernelVersion           string `json:"kernelVersion"`
+	OSImage                 string `json:"osImage"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
+	KubeletVersion          string `json:"kubeletVersion"`
+	KubeProxyVersion        string `json:"kubeProxyVersion"`
+	OperatingSystem         string `json:"operatingSystem"`
+	Architecture            string `json:"architecture"`
+}
+
+// Taint 节点污点
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// NodeSpec 节点规格
+type NodeSpec struct {
+	PodCIDR       string  `json:"podCIDR,omitempty"`
+	ProviderID    string  `json:"providerID,omitempty"`
+	Unschedulable bool    `json:"unschedulable,omitempty"`
+	Taints        []Taint `json:"taints,omitempty"`
+}
+
+// NodeStatus 节点状态
+type NodeStatus struct {
+	Capacity    ResourceList    `json:"capacity,omitempty"`
+	Allocatable ResourceList    `json:"allocatable,omitempty"`
+	Conditions  []NodeCondition `json:"conditions,omitempty"`
+	Addresses   []NodeAddress   `json:"addresses,omitempty"`
+	NodeInfo    NodeSystemInfo  `json:"nodeInfo"`
+}
+
+// Node core/v1 Node
+type Node struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     NodeSpec   `json:"spec"`
+	Status   NodeStatus `json:"status"`
+}
+
+// NodeList 节点列表
+type NodeList struct {
+	Metadata ListMeta `json:"metadata"`
+	Items    []Node   `json:"items"`
+}
+
+// Namespace core/v1 Namespace
+type Namespace struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Status   struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// NamespaceList 命名空间列表
+type NamespaceList struct {
+	Metadata ListMeta    `json:"metadata"`
+	Items    []Namespace `json:"items"`
+}
+
+// ObjectReference 事件关联的对象
+type ObjectReference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+// Event core/v1 Event
+type Event struct {
+	Metadata       ObjectMeta      `json:"metadata"`
+	InvolvedObject ObjectReference `json:"involvedObject"`
+	Reason         string          `json:"reason"`
+	Message        string          `json:"message"`
+	Type           string          `json:"type"`
+	Count          int32           `json:"count"`
+	FirstTimestamp time.Time       `json:"firstTimestamp"`
+	LastTimestamp  time.Time       `json:"lastTimestamp"`
+	// EventTime events.k8s.io新版事件使用该字段，此时first/lastTimestamp为空
+	EventTime *time.Time `json:"eventTime,omitempty"`
+}
+
+// Timestamp 事件最近一次发生的时间
+func (e *Event) Timestamp() time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp
+	}
+	if e.EventTime != nil && !e.EventTime.IsZero() {
+		return *e.EventTime
+	}
+	if !e.FirstTimestamp.IsZero() {
+		return e.FirstTimestamp
+	}
+	return e.Metadata.CreationTimestamp
+}
+
+// EventList 事件列表
+type EventList struct {
+	Metadata ListMeta `json:"metadata"`
+	Items    []Event  `json:"items"`
+}
+
+// Scale autoscaling/v1 Scale子资源
+type Scale struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas int32 `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		Replicas int32  `json:"replicas"`
+		Selector string `json:"selector,omitempty"`
+	} `json:"status"`
+}
+
+// Lease coordination.k8s.io/v1 Lease，控制平面组件用于选主
+type Lease struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		HolderIdentity       *string    `json:"holderIdentity,omitempty"`
+		LeaseDurationSeconds *int32     `json:"leaseDurationSeconds,omitempty"`
+		RenewTime            *time.Time `json:"renewTime,omitempty"`
+	} `json:"spec"`
+}
+
+// Expired 判断租约是否已过期，未记录续约时间视为过期
+func (l *Lease) Expired(now time.Time) bool {
+	if l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return now.After(l.Spec.RenewTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// ContainerMetrics metrics-server返回的容器使用量
+type ContainerMetrics struct {
+	Name  string       `json:"name"`
+	Usage ResourceList `json:"usage"`
+}
+
+// PodMetrics metrics.k8s.io/v1beta1 PodMetrics
+type PodMetrics struct {
+	Metadata   ObjectMeta         `json:"metadata"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Window     string             `json:"window"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// PodMetricsList Pod使用量列表
+type PodMetricsList struct {
+	Metadata ListMeta     `json:"metadata"`
+	Items    []PodMetrics `json:"items"`
+}
+
+// NodeMetrics metrics.k8s.io/v1beta1 NodeMetrics
+type NodeMetrics struct {
+	Metadata  ObjectMeta   `json:"metadata"`
+	Timestamp time.Time    `json:"timestamp"`
+	Window    string       `json:"window"`
+	Usage     ResourceList `json:"usage"`
+}
+
+// NodeMetricsList 节点使用量列表
+type NodeMetricsList struct {
+	Metadata ListMeta      `json:"metadata"`
+	Items    []NodeMetrics `json:"items"`
+}
+
+// ListOptions 列表查询选项
+type ListOptions struct {
+	LabelSelector string
+	FieldSelector string
+	// Limit 单页数量，0表示不分页
+	Limit int64
+}
+
+// VersionInfo /version响应
+type VersionInfo struct {
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	GitVersion string `json:"gitVersion"`
+	Platform   string `json:"platform"`
+}
+
+// WorkloadCondition Deployment、StatefulSet与Job共用的条件结构
+type WorkloadCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	LastUpdateTime     time.Time `json:"lastUpdateTime,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+}
+
+// Deployment apps/v1 Deployment
+type Deployment struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int32 `json:"replicas,omitempty"`
+		Paused   bool   `json:"paused,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration  int64               `json:"observedGeneration,omitempty"`
+		Replicas            int32               `json:"replicas"`
+		UpdatedReplicas     int32               `json:"updatedReplicas"`
+		ReadyReplicas       int32               `json:"readyReplicas"`
+		AvailableReplicas   int32               `json:"availableReplicas"`
+		UnavailableReplicas int32               `json:"unavailableReplicas"`
+		Conditions          []WorkloadCondition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// StatefulSet apps/v1 StatefulSet
+type StatefulSet struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas    *int32 `json:"replicas,omitempty"`
+		ServiceName string `json:"serviceName,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Replicas          int32               `json:"replicas"`
+		ReadyReplicas     int32               `json:"readyReplicas"`
+		CurrentReplicas   int32               `json:"currentReplicas"`
+		UpdatedReplicas   int32               `json:"updatedReplicas"`
+		AvailableReplicas int32               `json:"availableReplicas"`
+		Conditions        []WorkloadCondition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// DaemonSet apps/v1 DaemonSet
+type DaemonSet struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Status   struct {
+		CurrentNumberScheduled int32 `json:"currentNumberScheduled"`
+		DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+		NumberMisscheduled     int32 `json:"numberMisscheduled"`
+		NumberReady            int32 `json:"numberReady"`
+		UpdatedNumberScheduled int32 `json:"updatedNumberScheduled"`
+		NumberAvailable        int32 `json:"numberAvailable"`
+		NumberUnavailable      int32 `json:"numberUnavailable"`
+	} `json:"status"`
+}
+
+// Job batch/v1 Job
+type Job struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Parallelism  *int32 `json:"parallelism,omitempty"`
+		Completions  *int32 `json:"completions,omitempty"`
+		BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Conditions     []WorkloadCondition `json:"conditions,omitempty"`
+		StartTime      *time.Time          `json:"startTime,omitempty"`
+		CompletionTime *time.Time          `json:"completionTime,omitempty"`
+		Active         int32               `json:"active"`
+		Succeeded      int32               `json:"succeeded"`
+		Failed         int32               `json:"failed"`
+	} `json:"status"`
+}
+
+// Condition 返回指定类型且状态为True的条件
+func (j *Job) Condition(conditionType string) *WorkloadCondition {
+	for i := range j.Status.Conditions {
+		if j.Status.Conditions[i].Type == conditionType && j.Status.Conditions[i].Status == "True" {
+			return &j.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// WatchEvent watch流中的单个事件，Type为ADDED、MODIFIED、DELETED、BOOKMARK或ERROR
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WatchOptions watch请求选项
+type WatchOptions struct {
+	LabelSelector string
+	FieldSelector string
+	// ResourceVersion 从该版本之后开始推送变化，通常取list响应的resourceVersion
+	ResourceVersion string
+	// TimeoutSeconds 服务端在该时间后正常关闭watch，调用方应从最新版本重新watch
+	TimeoutSeconds int64
+}
+
+// IsGone 判断错误是否为resourceVersion已过期（410），此时需要重新list
+func IsGone(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone
+}
+
+// ListRaw 读取完整列表，返回未解码的对象与列表的resourceVersion，供list+watch使用
+func (c *Client) ListRaw(ctx context.Context, path string, opts ListOptions) ([]json.RawMessage, string, error) {
+	query := url.Values{}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		query.Set("fieldSelector", opts.FieldSelector)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(opts.Limit, 10))
+	}
+
+	var items []json.RawMessage
+	var resourceVersion string
+	for {
+		var list struct {
+			Metadata ListMeta          `json:"metadata"`
+			Items    []json.RawMessage `json:"items"`
+		}
+		if err := c.get(ctx, path, query, &list); err != nil {
+			return nil, "", err
+		}
+		items = append(items, list.Items...)
+		// 分页读取的各页属于同一快照，resourceVersion相同
+		resourceVersion = list.Metadata.ResourceVersion
+		if list.Metadata.Continue == "" || opts.Limit <= 0 {
+			break
+		}
+		query.Set("continue", list.Metadata.Continue)
+	}
+	return items, resourceVersion, nil
+}
+
+// Watch 监听集合路径上的变化，直到服务端关闭连接、ctx取消或handler返回错误
+// 服务端正常关闭时返回nil；事件流中的ERROR事件转换为*Error，可用IsGone判断是否需要重新list
+func (c *Client) Watch(ctx context.Context, path string, opts WatchOptions, handler func(*WatchEvent) error) error {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("allowWatchBookmarks", "true")
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		query.Set("fieldSelector", opts.FieldSelector)
+	}
+	if opts.ResourceVersion != "" {
+		query.Set("resourceVersion", opts.ResourceVersion)
+	}
+	if opts.TimeoutSeconds > 0 {
+		query.Set("timeoutSeconds", strconv.FormatInt(opts.TimeoutSeconds, 10))
+		// 服务端未按时关闭（如连接半开）时由客户端兜底
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.TimeoutSeconds)*time.Second+c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+c.basePath+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes watch %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return parseError(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event WatchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if ctx.Err() != nil {
+				// 兜底超时视为正常结束，外部ctx取消时返回取消原因
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) && opts.TimeoutSeconds > 0 {
+					return nil
+				}
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to decode kubernetes watch stream for %s: %w", path, err)
+		}
+		if event.Type == "ERROR" {
+			var status struct {
+				Code    int    `json:"code"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			}
+			json.Unmarshal(event.Object, &status)
+			return &Error{StatusCode: status.Code, Reason: status.Reason, Message: status.Message}
+		}
+		if err := handler(&event); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListRawPaginationKeepsResourceVersion(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		if r.URL.Query().Get("continue") == "" {
+			io.WriteString(w, `{"metadata":{"resourceVersion":"500","continue":"c1"},"items":[{"metadata":{"name":"a"}},{"metadata":{"name":"b"}}]}`)
+			return
+		}
+		io.WriteString(w, `{"metadata":{"resourceVersion":"500"},"items":[{"metadata":{"name":"c"}}]}`)
+	}))
+
+	items, version, err := client.ListRaw(context.Background(), NodesPath, ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListRaw: %v", err)
+	}
+	if len(items) != 3 || version != "500" {
+		t.Fatalf("unexpected list: %d items, version %q", len(items), version)
+	}
+	if !strings.Contains(string(items[2]), `"c"`) {
+		t.Fatalf("unexpected last item %s", items[2])
+	}
+}
+
+func TestWatchDecodesEventStream(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("watch") != "true" || q.Get("resourceVersion") != "10" || q.Get("labelSelector") != "app=web" || q.Get("timeoutSeconds") != "30" {
+			t.Errorf("unexpected watch query %s", r.URL.RawQuery)
+		}
+		flusher := w.(http.Flusher)
+		for _, line := range []string{
+			`{"type":"ADDED","object":{"metadata":{"name":"web-1","namespace":"prod","resourceVersion":"11"}}}`,
+			`{"type":"MODIFIED","object":{"metadata":{"name":"web-1","namespace":"prod","resourceVersion":"12"},"status":{"phase":"Running"}}}`,
+			`{"type":"DELETED","object":{"metadata":{"name":"web-1","namespace":"prod","resourceVersion":"13"}}}`,
+		} {
+			io.WriteString(w, line+"\n")
+			flusher.Flush()
+		}
+	}))
+
+	var events []string
+	err := client.Watch(context.Background(), PodsPath, WatchOptions{LabelSelector: "app=web", ResourceVersion: "10", TimeoutSeconds: 30}, func(e *WatchEvent) error {
+		_, version, pod, err := decodeObject[Pod](e.Object)
+		if err != nil {
+			return err
+		}
+		events = append(events, e.Type+":"+version+":"+pod.Status.Phase)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	want := "ADDED:11:,MODIFIED:12:Running,DELETED:13:"
+	if got := strings.Join(events, ","); got != want {
+		t.Fatalf("unexpected events %s, want %s", got, want)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			io.WriteString(w, `{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired","message":"too old resource version: 1 (200)"}}`+"\n")
+		case "/forbidden":
+			writeStatus(w, http.StatusForbidden, "Forbidden", "pods is forbidden")
+		case "/garbage":
+			io.WriteString(w, `{"type":"ADDED","object":{}}`+"\n{broken")
+		case "/events":
+			io.WriteString(w, `{"type":"ADDED","object":{}}`+"\n"+`{"type":"ADDED","object":{}}`+"\n")
+		}
+	}))
+	ctx := context.Background()
+	noop := func(*WatchEvent) error { return nil }
+
+	err := client.Watch(ctx, "/gone", WatchOptions{}, noop)
+	if !IsGone(err) {
+		t.Fatalf("expected gone error, got %v", err)
+	}
+	if apiErr := err.(*Error); apiErr.Reason != "Expired" || !strings.Contains(apiErr.Message, "too old") {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+
+	if err := client.Watch(ctx, "/forbidden", WatchOptions{}, noop); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+
+	if err := client.Watch(ctx, "/garbage", WatchOptions{}, noop); err == nil || !strings.Contains(err.Error(), "failed to decode kubernetes watch stream") {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+
+	// handler返回的错误中止watch并原样返回
+	stop := errors.New("stop")
+	calls := 0
+	err = client.Watch(ctx, "/events", WatchOptions{}, func(*WatchEvent) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected handler error after one call, got %v (%d calls)", err, calls)
+	}
+}
+
+func TestWatchContextCancel(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"7"}}}`+"\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.Watch(ctx, PodsPath, WatchOptions{}, func(e *WatchEvent) error {
+		if e.Type == "BOOKMARK" {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+}
+
+func TestInformerListWatchAndRelist(t *testing.T) {
+	var mu sync.Mutex
+	lists, watches := 0, 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("watch") != "true" {
+			lists++
+			if lists == 1 {
+				io.WriteString(w, `{"metadata":{"resourceVersion":"100"},"items":[
+					{"metadata":{"name":"a","namespace":"ns1","resourceVersion":"90"}},
+					{"metadata":{"name":"b","namespace":"ns2","resourceVersion":"95"}}]}`)
+				return
+			}
+			// 版本过期后重新list得到新的快照
+			io.WriteString(w, `{"metadata":{"resourceVersion":"300"},"items":[{"metadata":{"name":"z","namespace":"ns1","resourceVersion":"299"}}]}`)
+			return
+		}
+
+		watches++
+		switch watches {
+		case 1:
+			if rv := r.URL.Query().Get("resourceVersion"); rv != "100" {
+				t.Errorf("first watch should start from list version, got %s", rv)
+			}
+			for _, line := range []string{
+				`{"type":"ADDED","object":{"metadata":{"name":"c","namespace":"ns1","resourceVersion":"101"}}}`,
+				`{"type":"DELETED","object":{"metadata":{"name":"b","namespace":"ns2","resourceVersion":"102"}}}`,
+				`{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"150"}}}`,
+			} {
+				io.WriteString(w, line+"\n")
+			}
+		case 2:
+			// 正常关闭后从书签版本继续
+			if rv := r.URL.Query().Get("resourceVersion"); rv != "150" {
+				t.Errorf("second watch should resume from bookmark, got %s", rv)
+			}
+			io.WriteString(w, `{"type":"ERROR","object":{"code":410,"reason":"Expired","message":"too old"}}`+"\n")
+		default:
+			<-r.Context().Done()
+		}
+	}))
+
+	informer := NewInformer[Pod](client, PodsPath, ListOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		informer.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for informer.Status().ResourceVersion != "300" {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("informer did not relist, status %+v", informer.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if !informer.HasSynced() {
+		t.Fatal("informer should be synced")
+	}
+	pods := informer.List("")
+	if len(pods) != 1 || pods[0].Metadata.Name != "z" {
+		t.Fatalf("unexpected cache after relist: %+v", pods)
+	}
+	if _, ok := informer.Get("ns1", "c"); ok {
+		t.Fatal("objects from before the relist should be dropped")
+	}
+	if _, ok := informer.Get("ns1", "z"); !ok {
+		t.Fatal("expected ns1/z in cache")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("informer did not stop after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if lists != 2 {
+		t.Fatalf("expected 2 lists, got %d", lists)
+	}
+}
+
+func TestInformerApply(t *testing.T) {
+	informer := NewInformer[Pod](nil, PodsPath, ListOptions{})
+	if err := informer.replace(nil, "1"); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"b", "a", "c"} {
+		ns := "ns1"
+		if name == "c" {
+			ns = "ns2"
+		}
+		event := &WatchEvent{Type: "ADDED", Object: []byte(fmt.Sprintf(`{"metadata":{"name":%q,"namespace":%q,"resourceVersion":"%d"}}`, name, ns, i+2))}
+		if err := informer.apply(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := []string{}
+	for _, pod := range informer.List("ns1") {
+		names = append(names, pod.Metadata.Name)
+	}
+	if strings.Join(names, ",") != "a,b" {
+		t.Fatalf("unexpected ns1 pods %v", names)
+	}
+	if status := informer.Status(); status.Items != 3 || status.ResourceVersion != "4" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	if err := informer.apply(&WatchEvent{Type: "ADDED", Object: []byte(`{"metadata":{"name":"x"},"spec":{"containers":"bad"}}`)}); err == nil {
+		t.Fatal("expected decode error for malformed object")
+	}
+}